@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pdo-tools/pkg/export"
+)
+
+func init() {
+	commands["catalog"] = runCatalog
+}
+
+// runCatalog writes an HTML or PDF catalog of the PDO files in directories.
+func runCatalog(args []string) error {
+	flags := flag.NewFlagSet("catalog", flag.ExitOnError)
+	output := flags.String("output", "catalog.html", "Output file, HTML or PDF by extension")
+	flags.StringVar(output, "o", "catalog.html", "Short for -output")
+	format := flags.String("format", "", "Catalog format (html, pdf), by default the one of the -output extension")
+	title := flags.String("title", "", "Catalog title (default the name of the first directory)")
+	size := flags.Int("size", export.DefaultThumbnailSize, "Length in pixels of the longer side of rendered previews")
+	common := addCommonFlags(flags)
+	flags.Usage = func() {
+		fmt.Println("Usage: pdo-tools catalog [options] <dir|file.pdo> ...")
+		fmt.Println("Lists the PDO files in the directories, with preview, author, scale, page count and difficulty.")
+		flags.PrintDefaults()
+	}
+	parseInterspersed(flags, args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+	}
+	if *format != "html" && *format != "pdf" {
+		return withExit(exitUsage, fmt.Errorf("unknown catalog format %q, must be html or pdf", *format))
+	}
+	if *size <= 0 || *size > 4096 {
+		return withExit(exitUsage, fmt.Errorf("preview size %d out of range 1-4096", *size))
+	}
+	if *title == "" {
+		*title = filepath.Base(filepath.Clean(flags.Arg(0)))
+	}
+
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	files, err := findPDOs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var entries []export.CatalogEntry
+	for _, file := range files {
+		parser, err := parseInput(file, opts)
+		if err != nil {
+			logger.Warn("skipping file", "err", err)
+			continue
+		}
+		entries = append(entries, export.NewCatalogEntry(modelName(file), parser.PDO, *size))
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if *format == "pdf" {
+		err = export.WriteCatalogPDF(f, *title, entries, export.Options{Logger: logger})
+	} else {
+		err = export.WriteCatalogHTML(f, *title, entries)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote a catalog of %d models to %s\n", len(entries), *output)
+	if skipped := len(files) - len(entries); skipped > 0 {
+		return withExit(exitPartial, fmt.Errorf("%d of %d files failed to parse", skipped, len(files)))
+	}
+	return nil
+}
+
+// findPDOs lists the PDO files named on the command line and those in the
+// directories named, searched recursively in lexical order.
+func findPDOs(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p == path && !d.IsDir() || !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".pdo") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["duplicates"] = runDuplicates
+}
+
+// runDuplicates lists the parts that have the same shape as an earlier one.
+func runDuplicates(args []string) error {
+	fs := flag.NewFlagSet("duplicates", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the groups as JSON")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools duplicates [options] <file.pdo>")
+		fmt.Println("Lists parts that are identical but for their place and turn on the page.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	parser, err := parseInput(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	p := parser.PDO
+
+	groups := p.DuplicateParts()
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if groups == nil {
+			groups = []pdo.DuplicateGroup{}
+		}
+		return enc.Encode(groups)
+	}
+	for _, g := range groups {
+		fmt.Printf("part %d %q: cut %d\n", g.Original, partName(p, g.Original), len(g.Copies)+1)
+		for _, c := range g.Copies {
+			fmt.Printf("  part %d %q turned %.1f degrees\n", c.Part, partName(p, c.Part), c.Angle*180/math.Pi)
+		}
+	}
+	if len(groups) == 0 {
+		fmt.Printf("%s: no duplicate parts\n", fs.Arg(0))
+	}
+	return nil
+}
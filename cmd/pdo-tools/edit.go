@@ -0,0 +1,324 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pdo-tools/pkg/export"
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["rename-parts"] = runRenameParts
+	commands["renumber-edges"] = runRenumberEdges
+	commands["fix-size"] = runFixSize
+	commands["relayout"] = runRelayout
+	commands["recompress"] = runRecompress
+	commands["set-version"] = runSetVersion
+	commands["designer"] = runDesigner
+}
+
+// editFlags are the flags of commands that modify a PDO and write a new one.
+type editFlags struct {
+	*commonFlags
+	output     *string
+	fixSize    *bool
+	thumbnail  *bool
+	recompress *bool
+	version    *int
+	designerID *string
+	key        *string
+	clearIDs   *bool
+	repair     *bool
+}
+
+func addEditFlags(fs *flag.FlagSet) *editFlags {
+	return &editFlags{
+		commonFlags: addCommonFlags(fs),
+		output:      fs.String("output", "", "Output PDO file (default <input>_edited.pdo)"),
+		fixSize:     fs.Bool("fix-size", false, "Recompute the assembled size, origin and unfold scale before saving"),
+		thumbnail:   fs.Bool("thumbnail", false, "Embed a preview of the first page"),
+		recompress:  fs.Bool("recompress", false, "Deflate textures again at the best level, without changing pixels"),
+		version:     fs.Int("pdo-version", 0, "Write the file as PDO version 4, 5 or 6 (default the input's)"),
+		designerID:  fs.String("designer-id", "", "Replace the designer ID"),
+		key:         fs.String("key", "", "Replace the license key"),
+		clearIDs:    fs.Bool("clear-ids", false, "Clear the designer ID and key (before -designer-id and -key)"),
+		repair:      fs.Bool("repair-lines", false, "Point lines whose vertices don't match their faces at the edge or nearest vertices"),
+	}
+}
+
+// load parses the input file named on the command line.
+func (e *editFlags) load(fs *flag.FlagSet, logger *slog.Logger) (*pdo.PDO, error) {
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	opts, err := e.parseOptions(logger)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := parseInput(fs.Arg(0), opts)
+	if err != nil {
+		return nil, err
+	}
+	return parser.PDO, nil
+}
+
+// save writes the edited PDO, refusing to overwrite the input.
+func (e *editFlags) save(fs *flag.FlagSet, p *pdo.PDO) error {
+	input := fs.Arg(0)
+	output := *e.output
+	if output == "" {
+		output = strings.TrimSuffix(input, filepath.Ext(input)) + "_edited.pdo"
+	}
+	if filepath.Clean(output) == filepath.Clean(input) {
+		return fmt.Errorf("refusing to overwrite the input file %s", input)
+	}
+	if *e.repair {
+		repaired, unresolved := p.RepairLines()
+		fmt.Printf("Repaired %d lines, %d left unresolved\n", repaired, unresolved)
+	}
+	if *e.fixSize {
+		fixSize(p)
+	}
+	if *e.thumbnail {
+		if err := embedThumbnail(p, export.DefaultThumbnailSize); err != nil {
+			return err
+		}
+	}
+	if *e.version != 0 {
+		warnings, err := p.SetVersion(int32(*e.version))
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			e.logger().Warn(w, "version", *e.version)
+		}
+	}
+	if *e.clearIDs {
+		p.Header.DesignerID, p.Header.Key = "", ""
+	}
+	if *e.designerID != "" {
+		if err := p.SetDesignerID(*e.designerID); err != nil {
+			return err
+		}
+	}
+	if *e.key != "" {
+		if err := p.SetKey(*e.key); err != nil {
+			return err
+		}
+	}
+	if *e.recompress {
+		before, after, err := p.RecompressTextures()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Textures %d -> %d bytes\n", before, after)
+	}
+	if err := pdo.WriteFile(output, p); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	fmt.Printf("Wrote %s\n", output)
+	return nil
+}
+
+// runRenameParts names unnamed parts after their object.
+func runRenameParts(args []string) error {
+	fs := flag.NewFlagSet("rename-parts", flag.ExitOnError)
+	overwrite := fs.Bool("overwrite", false, "Also rename parts that already have a name")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools rename-parts [options] <file.pdo>")
+		fmt.Println("Names parts <object>_partNN and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	logger := edit.logger()
+	p, err := edit.load(fs, logger)
+	if err != nil {
+		return err
+	}
+
+	if p.Header.Version == pdo.PDO_V4 {
+		// Version 4 files have no part names, store them as version 5.
+		p.Header.Version = pdo.PDO_V5
+		logger.Info("upgrading version 4 file to version 5 to store part names")
+	}
+	n := p.NameParts(*overwrite)
+	fmt.Printf("Named %d of %d parts\n", n, len(p.Parts))
+	return edit.save(fs, p)
+}
+
+// runRenumberEdges reorders edge IDs to run sequentially over the layout.
+func runRenumberEdges(args []string) error {
+	fs := flag.NewFlagSet("renumber-edges", flag.ExitOnError)
+	order := fs.String("order", "part", "Numbering order (part, position)")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools renumber-edges [options] <file.pdo>")
+		fmt.Println("Numbers cut edges part by part or page by page and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var edgeOrder pdo.EdgeOrder
+	switch *order {
+	case "part":
+		edgeOrder = pdo.EdgesByPart
+	case "position":
+		edgeOrder = pdo.EdgesByPosition
+	default:
+		return fmt.Errorf("unknown edge order %q", *order)
+	}
+
+	logger := edit.logger()
+	p, err := edit.load(fs, logger)
+	if err != nil {
+		return err
+	}
+	p.RenumberEdges(edgeOrder)
+	return edit.save(fs, p)
+}
+
+// runFixSize brings the stored size of a model in line with its geometry.
+func runFixSize(args []string) error {
+	fs := flag.NewFlagSet("fix-size", flag.ExitOnError)
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools fix-size [options] <file.pdo>")
+		fmt.Println("Recomputes the assembled height, origin and unfold scale and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	*edit.fixSize = true
+	return edit.save(fs, p)
+}
+
+// runRecompress shrinks the stored textures without changing them.
+func runRecompress(args []string) error {
+	fs := flag.NewFlagSet("recompress", flag.ExitOnError)
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools recompress [options] <file.pdo>")
+		fmt.Println("Deflates the textures again at the best level and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	*edit.recompress = true
+	return edit.save(fs, p)
+}
+
+// runSetVersion converts a PDO to the layout of another version.
+func runSetVersion(args []string) error {
+	fs := flag.NewFlagSet("set-version", flag.ExitOnError)
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools set-version [options] -pdo-version N <file.pdo>")
+		fmt.Println("Writes a new PDO file for the given version, dropping what it can't store.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+	if *edit.version == 0 {
+		fs.Usage()
+		return fmt.Errorf("no -pdo-version given")
+	}
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Version %d -> %d\n", p.Header.Version, *edit.version)
+	return edit.save(fs, p)
+}
+
+// runDesigner prints the designer ID and key, or changes them.
+func runDesigner(args []string) error {
+	fs := flag.NewFlagSet("designer", flag.ExitOnError)
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools designer [options] <file.pdo> [-designer-id id] [-key key] [-clear-ids]")
+		fmt.Println("Prints the designer ID and key, or changes them and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Designer ID: %q\n", p.Header.DesignerID)
+	fmt.Printf("Key:         %q\n", p.Header.Key)
+	if *edit.designerID == "" && *edit.key == "" && !*edit.clearIDs {
+		return nil
+	}
+	return edit.save(fs, p)
+}
+
+// runRelayout re-flows the parts onto the pages of the current settings.
+func runRelayout(args []string) error {
+	fs := flag.NewFlagSet("relayout", flag.ExitOnError)
+	rotate := fs.String("rotate", "none", "Turn parts to pack them tighter (none, 90, free)")
+	spacing := fs.Float64("spacing", pdo.DefaultLayoutSpacing, "Gap between parts in mm")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools relayout [options] <file.pdo>")
+		fmt.Println("Packs the parts onto the pages of the print settings and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	rotation, err := parseRotation(*rotate)
+	if err != nil {
+		return err
+	}
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	n := p.Relayout(pdo.LayoutOptions{Spacing: *spacing, Rotate: rotation})
+	fmt.Printf("Laid out %d parts on %d pages\n", len(p.Parts), n)
+	return edit.save(fs, p)
+}
+
+// parseRotation converts a -rotate flag value.
+func parseRotation(s string) (pdo.Rotation, error) {
+	switch s {
+	case "none":
+		return pdo.RotateNone, nil
+	case "90":
+		return pdo.RotateQuarter, nil
+	case "free":
+		return pdo.RotateFree, nil
+	}
+	return 0, fmt.Errorf("unknown rotation %q", s)
+}
+
+// fixSize updates the size fields and reports what changed.
+func fixSize(p *pdo.PDO) {
+	c := p.FixSize()
+	if !c.HeaderMismatch() && !c.ScaleMismatch() {
+		fmt.Println("Size already matches the model")
+		return
+	}
+	if c.HeaderMismatch() {
+		fmt.Printf("Assembled height %g -> %g\n", c.HeaderHeight, p.Header.AssembledHeight)
+	}
+	if c.ScaleMismatch() {
+		fmt.Printf("Unfold scale %g -> %g\n", c.Scale, p.Unfold.Scale)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Exit codes, so wrapper scripts can tell failures apart without parsing
+// messages.
+const (
+	exitFailure = 1 // Anything not covered below, e.g. files that can't be written
+	exitUsage   = 2 // Invalid flags or arguments, like the flag package exits with
+	exitParse   = 3 // The input isn't a readable PDO file
+	exitVersion = 4 // The PDO format version isn't supported
+	exitLocked  = 5 // The file's lock flags refuse the operation
+	exitExport  = 6 // The file parsed but exporting it failed
+	exitPartial = 7 // Some outputs were written and others failed, or -strict saw warnings
+)
+
+// exitKinds names the exit codes in -error-json output.
+var exitKinds = map[int]string{
+	exitFailure: "failure",
+	exitUsage:   "usage",
+	exitParse:   "parse",
+	exitVersion: "unsupported-version",
+	exitLocked:  "locked",
+	exitExport:  "export",
+	exitPartial: "partial",
+}
+
+// errorJSON is set by -error-json.
+var errorJSON bool
+
+// exitError attaches an exit code to an error.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExit makes err exit the command with code.
+func withExit(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code for err: the version and lock categories of
+// the pdo package, a code attached with withExit, other parse error
+// categories, and def for anything else.
+func exitCode(err error, def int) int {
+	var ee *exitError
+	switch {
+	case errors.Is(err, pdo.ErrUnsupportedVersion):
+		return exitVersion
+	case errors.Is(err, pdo.ErrLocked):
+		return exitLocked
+	case errors.As(err, &ee):
+		return ee.code
+	case errors.Is(err, pdo.ErrInvalidMagic), errors.Is(err, pdo.ErrTruncated), errors.Is(err, pdo.ErrLimitExceeded):
+		return exitParse
+	}
+	return def
+}
+
+// reportError writes a failure to stderr: logged with msg and attrs, or
+// with -error-json as one JSON object.
+func reportError(logger *slog.Logger, code int, msg string, err error, attrs ...any) {
+	if !errorJSON {
+		logger.Error(msg, append(attrs, "err", err)...)
+		return
+	}
+	writeErrorJSON(code, fmt.Errorf("%s: %w", msg, err))
+}
+
+// writeErrorJSON writes the -error-json object of a failure.
+func writeErrorJSON(code int, err error) {
+	json.NewEncoder(os.Stderr).Encode(struct {
+		Error string `json:"error"`
+		Kind  string `json:"kind"`
+		Code  int    `json:"code"`
+	}{err.Error(), exitKinds[code], code})
+}
+
+// fail reports the error a command returned and exits with its code.
+func fail(err error) {
+	code := exitCode(err, exitFailure)
+	if errorJSON {
+		writeErrorJSON(code, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+// parseInput parses a command's input file, tagging failures as parse
+// errors.
+func parseInput(input string, opts pdo.Options) (*pdo.Parser, error) {
+	parser, err := pdo.ParseFileWithOptions(input, opts)
+	if err != nil {
+		return nil, withExit(exitParse, fmt.Errorf("failed to parse %s: %w", input, err))
+	}
+	return parser, nil
+}
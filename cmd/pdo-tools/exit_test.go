@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "cube.pdo", pdotest.Cube(pdotest.Options{}))
+	locked := pdotest.Cube(pdotest.Options{Texture: true})
+	locked.Header.TexLock = pdo.TexLockDeny
+	writeModel(t, dir, "locked.pdo", locked)
+	mesh := pdotest.Cube(pdotest.Options{})
+	mesh.Parts, mesh.TextBlocks = nil, nil
+	writeModel(t, dir, "mesh.pdo", mesh)
+	if err := os.WriteFile(filepath.Join(dir, "text.pdo"), []byte("not a pdo file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	future := []byte(readFile(t, filepath.Join(dir, "cube.pdo")))
+	binary.LittleEndian.PutUint32(future[len(pdo.FileMagic):], 9)
+	if err := os.WriteFile(filepath.Join(dir, "future.pdo"), future, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"cube.pdo"}, 0},
+		{[]string{}, exitUsage},
+		{[]string{"cube.pdo", "-format", "gif"}, exitUsage},
+		{[]string{"text.pdo"}, exitParse},
+		{[]string{"extract-text", "text.pdo"}, exitParse},
+		{[]string{"future.pdo"}, exitVersion},
+		{[]string{"locked.pdo", "-dump-textures"}, exitLocked},
+		{[]string{"mesh.pdo", "-format", "pdf"}, exitExport},
+		{[]string{"cube.pdo", "-strict", "-attribution="}, exitPartial},
+		{[]string{"cube.pdo", "-o", "missing/cube.svg"}, exitFailure},
+	}
+	for _, tt := range tests {
+		if r := pdoTools(t, dir, tt.args...); r.code != tt.want {
+			t.Errorf("%q: exit code %d, want %d: %s", tt.args, r.code, tt.want, r.stderr)
+		}
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, "cube.pdo", pdotest.Cube(pdotest.Options{}))
+	if err := os.WriteFile(filepath.Join(dir, "text.pdo"), []byte("not a pdo file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		kind string
+		code int
+	}{
+		{[]string{"text.pdo", "-error-json"}, "parse", exitParse},
+		{[]string{"extract-text", "-error-json", "text.pdo"}, "parse", exitParse},
+		{[]string{"cube.pdo", "-error-json", "-flaps", "round"}, "usage", exitUsage},
+		{[]string{"cube.pdo", "-error-json", "-strict", "-attribution="}, "partial", exitPartial},
+	}
+	for _, tt := range tests {
+		r := pdoTools(t, dir, tt.args...)
+		// The error is the last line, after the warnings summary of -strict.
+		lines := strings.Split(strings.TrimSuffix(r.stderr, "\n"), "\n")
+		var got struct {
+			Error string
+			Kind  string
+			Code  int
+		}
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &got); err != nil {
+			t.Errorf("%q: %v in stderr:\n%s", tt.args, err, r.stderr)
+			continue
+		}
+		if got.Kind != tt.kind || got.Code != tt.code || r.code != tt.code || got.Error == "" {
+			t.Errorf("%q: exit code %d, error %+v, want %s %d", tt.args, r.code, got, tt.kind, tt.code)
+		}
+	}
+}
@@ -0,0 +1,761 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pdo-tools/pkg/export"
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["convert"] = runConvert
+}
+
+// runConvert is the export command under a name, for -formats.
+func runConvert(args []string) error {
+	runExport(args)
+	return nil
+}
+
+// infoFormat is the -formats name of the info command's JSON report.
+const infoFormat = "info-json"
+
+// runExport converts a PDO file into one of the registered output formats.
+// It is the default command.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("pdo-tools", flag.ExitOnError)
+	output := fs.String("output", "", "Output file path")
+	fs.StringVar(output, "o", "", "Short for -output")
+	format := fs.String("format", "", "Output format ("+strings.Join(export.Names(), ", ")+"), by default the one of the -output extension, else svg")
+	listFormats := fs.Bool("list-formats", false, "List the output formats with their file extensions and exit")
+	dryRun := fs.Bool("dry-run", false, "Run the whole export but discard the output, reporting timing and warnings")
+	strict := fs.Bool("strict", false, "Exit with an error when parsing or exporting logged warnings")
+	formats := fs.String("formats", "", "Comma-separated output formats, exported concurrently from one parse; "+infoFormat+" writes the info report")
+	dumpTextures := fs.Bool("dump-textures", false, "Dump textures to PNG files")
+	force := fs.Bool("force", false, "Dump textures, or write them next to 3D models, though the file's texture lock forbids it")
+	grouping := fs.String("group", "object", "Grouping of 3D output (object, part)")
+	smoothAngle := fs.Float64("smooth", 0, "Smooth 3D normals across edges up to this angle in degrees (0 = flat)")
+	nameMap := fs.String("name-map", "", "Write original to exported name mappings to this file")
+	nUp := fs.Int("nup", 1, "Template pages per PDF sheet side (1, 2)")
+	booklet := fs.Bool("booklet", false, "Order PDF pages for a saddle-stitched booklet (2-up)")
+	duplex := fs.Bool("duplex", false, "Mirror the gutter on back sides for double-sided printing")
+	gutter := fs.Float64("gutter", 0, "Extra inner margin in mm for binding")
+	flapStyle := fs.String("flaps", "auto", "Glue flap style (auto, triangle, trapezoid, none)")
+	flapHeight := fs.Float64("flap-height", 0, "Regenerate all flaps with this height in mm (0 = keep)")
+	flapAngle := fs.Float64("flap-angle", 45, "Side angle in degrees of regenerated flaps")
+	moveFlaps := fs.String("move-flap", "", "Move flaps to the other side of these edges: comma-separated edge IDs, [object:]edge")
+	materialLayers := fs.Bool("material-layers", false, "Put the lines of each material on their own SVG layer")
+	partColors := fs.String("part-colors", "none", "Tint each part and add a color legend (none, outline, fill)")
+	duplicates := fs.String("duplicates", "keep", "Draw SVG parts of the same shape in full, as <use> instances of one, or once with a count (keep, use, count)")
+	pageFrames := fs.Bool("page-frames", false, "Lay out SVG content on a grid of framed pages like the printout")
+	precision := fs.Int("precision", export.DefaultPrecision, "Decimal places of SVG coordinates")
+	compactPaths := fs.Bool("compact-paths", false, "Join SVG lines into paths with relative commands")
+	pdfConformance := fs.String("pdf-standard", "none", "PDF standard to follow (none, pdfa-2b, pdfx-4)")
+	outputProfile := fs.String("icc", "", "ICC profile of the PDF output intent (required for pdfx-4)")
+	textures := fs.Bool("textures", false, "Draw face textures in PDF and SVG")
+	faceFill := fs.String("face-fill", "auto", "Paint PDF and SVG faces with textures or material colors (auto, texture, color); implies -textures unless auto")
+	cmyk := fs.Bool("cmyk", false, "Embed PDF textures as CMYK images (use with -icc)")
+	dither := fs.String("dither", "none", "Dither PDF textures (none, ordered, floyd-steinberg)")
+	ditherLevels := fs.Int("dither-levels", 2, "Levels per color channel of dithered textures")
+	gray := fs.Bool("gray", false, "Convert PDF textures to grayscale")
+	workers := fs.Int("workers", 0, "Goroutines preparing PDF textures and parts (default the number of CPUs)")
+	textToPath := fs.Bool("text-to-path", false, "Convert SVG text blocks to outlines")
+	stampText := fs.String("stamp-text", "", "Footer text for each page, with {name}, {url}, {page} and {pages} placeholders")
+	stampURL := fs.String("stamp-url", "", "URL to print as a QR code on each page")
+	stampQRSize := fs.Float64("stamp-qr-size", export.DefaultQRSize, "QR code size in mm")
+	attribution := fs.String("attribution", "", "Credit or license line stamped small on every page and recorded in the metadata, with an {author} placeholder; empty (-attribution=) uses the author")
+	watermark := fs.String("watermark", "", "Text laid diagonally across each PDF and SVG page, e.g. PREVIEW")
+	watermarkImage := fs.String("watermark-image", "", "PNG or JPEG image laid diagonally across each PDF and SVG page")
+	watermarkOpacity := fs.Float64("watermark-opacity", export.DefaultWatermarkOpacity, "Opacity of the watermark, from 0 to 1")
+	paper := fs.String("paper", "", "Print on another paper size (A4, A3, Letter, ...), re-flowing the parts")
+	orientation := fs.String("orientation", "", "Print in portrait or landscape, re-flowing the parts")
+	guides := addGuideFlags(fs)
+	flipY := fs.Bool("flip-y", false, "Mirror the layout vertically in SVG and DXF (SVG Y points down, DXF up by default)")
+	outlineOffset := fs.Float64("outline-offset", 0, "Draw an outline this many mm around each part, for weeding and kiss-cut stickers")
+	edgeLines := fs.Bool("edge-lines", false, "Draw 2D formats with lines derived from the model's edges, for files with damaged line lists")
+	perforate := fs.String("perforate", "off", "Cut fold lines as dashes or end ticks for cutters without scoring (off, dash, ticks)")
+	perforateDash := fs.Float64("perforate-dash", export.DefaultPerforationDash, "Length in mm of perforation cuts and ticks")
+	perforateGap := fs.Float64("perforate-gap", export.DefaultPerforationGap, "Uncut length in mm between perforation cuts")
+	cutSetting := fs.String("cut", "", "DXF cut settings, e.g. tool=1,pressure=30,passes=1")
+	scoreSetting := fs.String("score", "", "DXF fold line settings, e.g. tool=2,pressure=10,passes=2")
+	poster := fs.Bool("poster", false, "Split parts larger than a page across several PDF pages")
+	posterOverlap := fs.Float64("poster-overlap", export.DefaultPosterOverlap, "Width in mm of the glue strip shared by poster tiles")
+	units := fs.String("units", "", "Unit of SVG sizes and 3D coordinates (mm, cm, in, pt; default per format)")
+	origin := fs.Bool("origin", false, "Move the stored model origin to 0,0,0 in 3D exports")
+	center := fs.Bool("center", false, "Move the bounding box center to 0,0,0 in 3D exports")
+	ground := fs.Bool("ground", false, "Sit the model on Z=0 in 3D exports")
+	transform := fs.String("transform", "", "Scale, rotate (degrees) and move 3D exports, e.g. s=2,rx=90,tz=10")
+	titlePages := fs.Bool("title-pages", false, "Start every model of a PDF merged from several files with a title page")
+	explode := fs.Bool("explode", false, "Write one 3D file per part, moved apart along its normal")
+	explodeDistance := fs.Float64("explode-distance", export.DefaultExplodeDistance, "Distance in mm exploded parts are moved apart")
+	perPart := fs.Bool("per-part", false, "Write one 2D file (SVG, DXF, ...) per part, named after the part")
+	stringInfo := fs.Bool("string-info", false, "Report the string shift and multi-byte flag used to decode strings")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools [options] <file.pdo>")
+		fmt.Println("       pdo-tools convert <a.pdo> <b.pdo> ... -format pdf -o pack.pdf [options]")
+		fmt.Println("       pdo-tools convert <file.pdo> -formats svg,pdf,info-json [options]")
+		fmt.Printf("       pdo-tools <command> [options] <file.pdo>  (commands: %s)\n", strings.Join(commandNames(), ", "))
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	warnings := &export.Warnings{}
+	logger := slog.New(warnings.Handler(common.logger().Handler()))
+	// run exists once the options are checked, fail removes its scratch
+	// directory.
+	var run *exportRun
+	fail := func(code int, msg string, err error, attrs ...any) {
+		code = exitCode(err, code)
+		reportError(logger, code, msg, err, attrs...)
+		run.exit(code)
+	}
+
+	if *listFormats {
+		printFormats()
+		return
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	inputFile := fs.Arg(0)
+
+	// The extension of -output names the base of -formats outputs, not a format.
+	formatOutput := *output
+	if *formats != "" {
+		formatOutput = ""
+	}
+	exporter, err := outputFormat(*format, formatOutput)
+	if err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+
+	// Several input files are merged into one PDF.
+	pack := fs.Args()[1:]
+	if len(pack) > 0 {
+		if exporter.Name() != "pdf" || *formats != "" {
+			fail(exitUsage, "invalid options", fmt.Errorf("several input files only merge into one PDF, not %s", exporter.Name()))
+		}
+		if *explode || *perPart || *nameMap != "" || *moveFlaps != "" || *dumpTextures {
+			fail(exitUsage, "invalid options", fmt.Errorf("-explode, -per-part, -name-map, -move-flap and -dump-textures take a single input file"))
+		}
+	}
+
+	var multi []string
+	if *formats != "" {
+		if multi, err = parseFormats(*formats); err != nil {
+			fail(exitUsage, "invalid options", err)
+		}
+		if *explode || *perPart || *nameMap != "" {
+			fail(exitUsage, "invalid options", fmt.Errorf("-formats can't be combined with -explode, -per-part or -name-map"))
+		}
+	}
+	switch {
+	case *explode && *perPart:
+		fail(exitUsage, "invalid options", fmt.Errorf("-explode and -per-part can't be combined"))
+	case *explode && !export.IsMesh(exporter):
+		fail(exitUsage, "invalid options", fmt.Errorf("-explode needs a 3D format, not %s", exporter.Name()))
+	case *perPart && export.IsMesh(exporter):
+		fail(exitUsage, "invalid options", fmt.Errorf("-per-part needs a 2D format, not %s", exporter.Name()))
+	}
+
+	// Outputs of -formats share the base name of -output or the input.
+	base := strings.TrimSuffix(inputFile, filepath.Ext(inputFile))
+	if *output != "" {
+		base = strings.TrimSuffix(*output, filepath.Ext(*output))
+	}
+
+	// Determine output filename if not specified
+	if *output == "" {
+		*output = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + exporter.Extensions()[0]
+	}
+
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+
+	exportOpts := export.Options{Logger: logger, SmoothAngle: *smoothAngle, TextToPath: *textToPath, MaterialLayers: *materialLayers, PageFrames: *pageFrames}
+	exportOpts.Precision, exportOpts.CompactPaths = *precision, *compactPaths
+	if *precision < 1 || *precision > 10 {
+		fail(exitUsage, "invalid options", fmt.Errorf("precision %d out of range 1-10", *precision))
+	}
+	exportOpts.Textures, exportOpts.CMYK = *textures || *cmyk || *dither != "none" || *gray || *faceFill != "auto", *cmyk
+	exportOpts.Dither = export.Dither{Levels: *ditherLevels, Gray: *gray}
+	exportOpts.Workers = *workers
+	exportOpts.IgnoreTextureLock = *force
+	if *ditherLevels < 2 || *ditherLevels > 256 {
+		fail(exitUsage, "invalid options", fmt.Errorf("dither levels %d out of range 2-256", *ditherLevels))
+	}
+	exportOpts.Imposition = export.Imposition{Booklet: *booklet, Duplex: *duplex, Gutter: *gutter}
+	exportOpts.Title = modelName(inputFile)
+	if len(pack) > 0 {
+		exportOpts.Title = modelName(*output)
+	}
+	exportOpts.Stamp = export.Stamp{
+		Text:   *stampText,
+		Name:   exportOpts.Title,
+		URL:    *stampURL,
+		QRSize: *stampQRSize,
+	}
+	exportOpts.Watermark = export.Watermark{Text: *watermark, Opacity: *watermarkOpacity}
+	if *watermarkOpacity <= 0 || *watermarkOpacity > 1 {
+		fail(exitUsage, "invalid options", fmt.Errorf("watermark opacity %g out of range 0-1", *watermarkOpacity))
+	}
+	if *watermarkImage != "" {
+		if exportOpts.Watermark.Image, err = os.ReadFile(*watermarkImage); err != nil {
+			fail(exitUsage, "failed to read watermark image", err)
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "attribution" {
+			exportOpts.Stamp.Attribution = cmp.Or(*attribution, "{author}")
+		}
+	})
+	if exportOpts.Imposition.NUp, err = export.ParseNUp(*nUp); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.Grouping, err = export.ParseGrouping(*grouping); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.FlapStyle, err = export.ParseFlapStyle(*flapStyle); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.PartColoring, err = export.ParsePartColoring(*partColors); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.Duplicates, err = export.ParseDuplicates(*duplicates); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.Dither.Mode, err = export.ParseDitherMode(*dither); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.FaceFill, err = export.ParseFaceFill(*faceFill); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.Units, err = export.ParseUnits(*units); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.Guides, err = guides.guides(); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if *outlineOffset < 0 {
+		fail(exitUsage, "invalid options", fmt.Errorf("invalid outline offset %g", *outlineOffset))
+	}
+	exportOpts.OutlineOffset = *outlineOffset
+	exportOpts.FlipY = *flipY
+	exportOpts.EdgeLines = *edgeLines
+	if exportOpts.Perforation.Mode, err = export.ParsePerforationMode(*perforate); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if *perforateDash <= 0 || *perforateGap <= 0 {
+		fail(exitUsage, "invalid options", fmt.Errorf("invalid perforation %g/%g mm", *perforateDash, *perforateGap))
+	}
+	exportOpts.Perforation.Dash, exportOpts.Perforation.Gap = *perforateDash, *perforateGap
+	if err = export.ParseCutterSetting(*cutSetting, &exportOpts.Scoring.Cut); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if err = export.ParseCutterSetting(*scoreSetting, &exportOpts.Scoring.Score); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if *posterOverlap <= 0 {
+		fail(exitUsage, "invalid options", fmt.Errorf("invalid poster overlap %g", *posterOverlap))
+	}
+	exportOpts.Poster = export.Poster{Enabled: *poster, Overlap: *posterOverlap}
+	exportOpts.Placement = export.Placement{Origin: *origin, Center: *center, Ground: *ground}
+	if err = export.ParseTransform(*transform, &exportOpts.Placement); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if exportOpts.PDFConformance, err = export.ParsePDFConformance(*pdfConformance); err != nil {
+		fail(exitUsage, "invalid options", err)
+	}
+	if *outputProfile != "" {
+		if exportOpts.OutputProfile, err = os.ReadFile(*outputProfile); err != nil {
+			fail(exitFailure, "failed to read ICC profile", err, "file", *outputProfile)
+		}
+	}
+
+	if run, err = newExportRun(*dryRun); err != nil {
+		fail(exitFailure, "failed to create scratch directory", err)
+	}
+	defer run.close()
+	*output, base = run.path(*output), run.path(base)
+
+	start := time.Now()
+	parser, err := pdo.ParseFileWithOptions(inputFile, opts)
+	if err != nil {
+		fail(exitParse, "failed to parse file", err, "file", inputFile)
+	}
+	pdoFile := parser.PDO
+	models := []export.PDFModel{{Title: modelName(inputFile), PDO: pdoFile}}
+	for _, input := range pack {
+		parser, err := pdo.ParseFileWithOptions(input, opts)
+		if err != nil {
+			fail(exitParse, "failed to parse file", err, "file", input)
+		}
+		models = append(models, export.PDFModel{Title: modelName(input), PDO: parser.PDO})
+	}
+	run.parsed = time.Since(start)
+	if strings.Contains(exportOpts.Stamp.Attribution, "{author}") && !slices.ContainsFunc(models, func(m export.PDFModel) bool {
+		return strings.TrimSpace(m.PDO.Settings.AuthorName) != ""
+	}) {
+		logger.Warn("the file names no author, leaving the attribution out")
+	}
+
+	if *moveFlaps != "" {
+		edges, err := parseEdgeRefs(*moveFlaps)
+		if err != nil {
+			fail(exitUsage, "invalid options", err)
+		}
+		for _, e := range edges {
+			if err := pdoFile.MoveFlap(e.object, e.edge); err != nil {
+				fail(exitUsage, "failed to move flap", err)
+			}
+		}
+	}
+
+	for _, m := range models {
+		p := m.PDO
+		if *paper != "" || *orientation != "" {
+			before := p.PageDims()
+			for name, value := range map[string]string{"pageType": *paper, "orientation": *orientation} {
+				if value == "" {
+					continue
+				}
+				if err := p.Settings.Set(name, value); err != nil {
+					fail(exitUsage, "invalid options", err)
+				}
+			}
+			if p.PageDims() != before {
+				pages := p.Relayout(pdo.LayoutOptions{})
+				logger.Info("re-flowed parts onto the new pages", "model", m.Title, "pages", pages)
+			}
+		}
+
+		if *flapHeight > 0 {
+			lowered := p.ResizeFlaps(*flapHeight, *flapAngle*math.Pi/180)
+			if lowered > 0 {
+				logger.Info("lowered flaps that would overlap their part", "model", m.Title, "flaps", lowered)
+			}
+		}
+	}
+
+	if *stringInfo {
+		si := parser.Strings
+		fmt.Printf("Header string shift: %d, multi-byte chars: %d\n", si.HeaderShift, si.HeaderMultiByte)
+		fmt.Printf("Decoding with shift: %d, multi-byte: %t, byte-wise shift: %t, recovered: %t\n",
+			si.Shift, si.MultiByte, si.ByteWiseShift, si.Recovered)
+		fmt.Printf("Designer: %q, locale: %q, codepage: %q\n",
+			pdoFile.Header.DesignerID, pdoFile.Header.Locale, pdoFile.Header.Codepage)
+	}
+
+	if *dumpTextures {
+		if err := pdoFile.CheckTextureLock(); err != nil && !*force {
+			fail(exitLocked, "refusing to dump textures", fmt.Errorf("%w; -force dumps them anyway, if you have the designer's permission", err))
+		}
+		for i, mat := range pdoFile.TexturedMaterials() {
+			img, err := mat.Texture.GetImage()
+			if err != nil {
+				logger.Warn("failed to decode texture", "material", mat.Name, "err", err)
+				continue
+			}
+
+			texName := run.path(fmt.Sprintf("%s_tex%d.png", strings.TrimSuffix(inputFile, ".pdo"), i))
+			f, err := os.Create(texName)
+			if err != nil {
+				logger.Warn("failed to create texture file", "path", texName, "err", err)
+				continue
+			}
+
+			if err := png.Encode(f, img); err != nil {
+				logger.Warn("failed to encode texture", "path", texName, "err", err)
+			}
+			f.Close()
+			if !run.dryRun() {
+				fmt.Printf("Extracted material '%s' texture to %s\n", mat.Name, texName)
+			}
+		}
+	}
+
+	start = time.Now()
+	defer func() {
+		run.report(time.Since(start), warnings.Len())
+		printWarnings(warnings)
+		if *strict && warnings.Len() > 0 {
+			if errorJSON {
+				writeErrorJSON(exitPartial, fmt.Errorf("-strict: %d warnings", warnings.Len()))
+			}
+			run.exit(exitPartial)
+		}
+	}()
+	if len(multi) > 0 {
+		if err := exportFormats(run, pdoFile, inputFile, multi, base, exportOpts); err != nil {
+			fail(exitExport, "failed to export", err)
+		}
+		return
+	}
+
+	if *explode {
+		if err := exportParts(run, exporter, export.ExplodeParts(pdoFile, *explodeDistance), *output, exportOpts); err != nil {
+			fail(exitExport, "failed to export", err, "format", exporter.Name())
+		}
+		return
+	}
+	if *perPart {
+		if err := exportPerPart(run, exporter, pdoFile, *output, exportOpts); err != nil {
+			fail(exitExport, "failed to export", err, "format", exporter.Name())
+		}
+		return
+	}
+
+	for _, m := range models {
+		if err := export.CheckUnfold(exporter, m.PDO); err != nil {
+			fail(exitExport, "failed to export", err, "format", exporter.Name(), "model", m.Title)
+		}
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		fail(exitFailure, "failed to create output file", err, "file", *output)
+	}
+	defer f.Close()
+
+	if *nameMap != "" {
+		nm, err := os.Create(run.path(*nameMap))
+		if err != nil {
+			fail(exitFailure, "failed to create name map file", err, "file", *nameMap)
+		}
+		defer nm.Close()
+		exportOpts.NameMap = nm
+	}
+
+	if len(pack) > 0 {
+		if err := export.ExportPDFPack(export.PDFPack{Models: models, TitlePages: *titlePages}, f, exportOpts); err != nil {
+			fail(exitExport, "failed to export", err, "format", exporter.Name())
+		}
+		run.exported(*output)
+		return
+	}
+
+	target := export.Target{W: f, Path: *output}
+	if err := exporter.Export(context.Background(), pdoFile, target, exportOpts); err != nil {
+		fail(exitExport, "failed to export", err, "format", exporter.Name())
+	}
+	run.exported(*output)
+}
+
+// modelName names a model after its file, without directory and extension.
+func modelName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// printWarnings summarizes the logged warnings on stderr.
+func printWarnings(w *export.Warnings) {
+	if w.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%d warnings:\n", w.Len())
+	for _, c := range w.Summary() {
+		fmt.Fprintf(os.Stderr, "  %d× %s\n", c.Count, c.Message)
+	}
+}
+
+// exportRun tracks the files an export writes. A dry run writes them to a
+// scratch directory removed afterwards.
+type exportRun struct {
+	scratch string // "" when writing for real
+	parsed  time.Duration
+
+	mu    sync.Mutex
+	files []string
+}
+
+func newExportRun(dryRun bool) (*exportRun, error) {
+	run := &exportRun{}
+	if dryRun {
+		dir, err := os.MkdirTemp("", "pdo-tools-dry-run-")
+		if err != nil {
+			return nil, err
+		}
+		run.scratch = dir
+	}
+	return run, nil
+}
+
+// path returns where to write the file at path.
+func (r *exportRun) path(path string) string {
+	if !r.dryRun() {
+		return path
+	}
+	return filepath.Join(r.scratch, filepath.Base(path))
+}
+
+// exported records a written file.
+func (r *exportRun) exported(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files = append(r.files, path)
+	if !r.dryRun() {
+		fmt.Printf("Exported to %s\n", path)
+	}
+}
+
+// report prints the summary of a dry run.
+func (r *exportRun) report(exporting time.Duration, warnings int) {
+	if !r.dryRun() {
+		return
+	}
+	var size int64
+	entries, _ := os.ReadDir(r.scratch)
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil {
+			size += info.Size()
+		}
+	}
+	fmt.Printf("Dry run: parsed in %v, exported %d files (%d bytes) in %v, %d warnings, nothing written\n",
+		r.parsed.Round(time.Millisecond), len(entries), size, exporting.Round(time.Millisecond), warnings)
+}
+
+func (r *exportRun) dryRun() bool { return r.scratch != "" }
+
+func (r *exportRun) close() {
+	if r != nil && r.scratch != "" {
+		os.RemoveAll(r.scratch)
+	}
+}
+
+// exit removes the scratch directory, which deferred calls would miss, and
+// exits.
+func (r *exportRun) exit(code int) {
+	r.close()
+	os.Exit(code)
+}
+
+// outputFormat returns the exporter named by -format or, without one, the
+// exporter of the -output file extension, svg when there is no output path.
+func outputFormat(format, output string) (export.Exporter, error) {
+	if format == "" && output != "" {
+		ext := filepath.Ext(output)
+		if e, ok := export.ForExtension(ext); ok {
+			return e, nil
+		}
+		if strings.EqualFold(ext, ".png") {
+			return nil, fmt.Errorf("no format writes %s files, render the pages to PNG with the render-pages command", ext)
+		}
+		if ext != "" {
+			return nil, fmt.Errorf("no format writes %s files, choose one with -format (available: %s)", ext, strings.Join(export.Names(), ", "))
+		}
+	}
+	if format == "" {
+		format = "svg"
+	}
+	e, ok := export.Lookup(format)
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q (available: %s)", format, strings.Join(export.Names(), ", "))
+	}
+	return e, nil
+}
+
+// printFormats lists the output formats for -list-formats.
+func printFormats() {
+	for _, e := range export.Exporters() {
+		kind := "template"
+		if export.IsMesh(e) {
+			kind = "3D model"
+		}
+		fmt.Printf("%-9s %-10s %s\n", e.Name(), strings.Join(e.Extensions(), " "), kind)
+	}
+	fmt.Printf("%-9s %-10s %s\n", infoFormat, ".info.json", "info report, -formats only")
+}
+
+// parseFormats splits a -formats list and checks every format exists.
+func parseFormats(s string) ([]string, error) {
+	var formats []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.Contains(formats, name) {
+			continue
+		}
+		if _, ok := export.Lookup(name); !ok && name != infoFormat {
+			return nil, fmt.Errorf("unknown output format %q (available: %s, %s)", name, strings.Join(export.Names(), ", "), infoFormat)
+		}
+		formats = append(formats, name)
+	}
+	return formats, nil
+}
+
+// exportFormats writes the PDO in several formats at once, <base><ext> each.
+// Every exporter works on its own copy of the model. When only some of the
+// formats fail, the error exits with exitPartial.
+func exportFormats(run *exportRun, p *pdo.PDO, inputFile string, formats []string, base string, opts export.Options) error {
+	errs := make([]error, len(formats))
+	var wg sync.WaitGroup
+	for i, name := range formats {
+		wg.Go(func() {
+			errs[i] = exportFormat(run, p.Clone(), inputFile, name, base, opts)
+		})
+	}
+	wg.Wait()
+	err := errors.Join(errs...)
+	if err != nil && slices.Contains(errs, nil) {
+		return withExit(exitPartial, err)
+	}
+	return err
+}
+
+func exportFormat(run *exportRun, p *pdo.PDO, inputFile, name, base string, opts export.Options) error {
+	if name == infoFormat {
+		path := base + ".info.json"
+		data, err := json.MarshalIndent(newInfoReport(inputFile, p, pdo.Paper{}), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+		run.exported(path)
+		return nil
+	}
+
+	exporter, _ := export.Lookup(name)
+	if err := export.CheckUnfold(exporter, p); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	path := base + exporter.Extensions()[0]
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = exporter.Export(context.Background(), p, export.Target{W: f, Path: path}, opts)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	run.exported(path)
+	return nil
+}
+
+// exportPerPart writes each part of the unfold of p to its own file, see
+// exportParts.
+func exportPerPart(run *exportRun, exporter export.Exporter, p *pdo.PDO, output string, opts export.Options) error {
+	if err := export.CheckUnfold(exporter, p); err != nil {
+		return err
+	}
+	return exportParts(run, exporter, export.SplitParts(p), output, opts)
+}
+
+// exportParts writes each part as its own file named after the output,
+// <output>_NN_<part name>.
+func exportParts(run *exportRun, exporter export.Exporter, parts []export.ExplodedPart, output string, opts export.Options) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("no unfolded parts to export")
+	}
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	for _, part := range parts {
+		path := fmt.Sprintf("%s_%02d%s", base, part.Index, ext)
+		if part.Name != "" {
+			path = fmt.Sprintf("%s_%02d_%s%s", base, part.Index, safeFileName(part.Name), ext)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = exporter.Export(context.Background(), part.PDO, export.Target{W: f, Path: path}, opts)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("part %d: %w", part.Index, err)
+		}
+		run.exported(path)
+	}
+	return nil
+}
+
+// guideFlags are the page guide flags of the 2D formats.
+type guideFlags struct {
+	margins    *bool
+	grid       *float64
+	background *string
+}
+
+func addGuideFlags(fs *flag.FlagSet) *guideFlags {
+	return &guideFlags{
+		margins:    fs.Bool("margins", false, "Outline the printable area inside the page margins"),
+		grid:       fs.Float64("grid", 0, "Draw a light grid with this spacing in mm over the printable area"),
+		background: fs.String("background", "", "Paper color as #rrggbb"),
+	}
+}
+
+func (g *guideFlags) guides() (export.Guides, error) {
+	guides := export.Guides{Margins: *g.margins, Grid: *g.grid}
+	if *g.grid < 0 {
+		return guides, fmt.Errorf("invalid grid spacing %g", *g.grid)
+	}
+	if *g.background != "" {
+		c, err := export.ParseColor(*g.background)
+		if err != nil {
+			return guides, err
+		}
+		guides.Background = &c
+	}
+	return guides, nil
+}
+
+// safeFileName replaces characters that aren't allowed in file names.
+func safeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// edgeRef is an edge selected on the command line. edge is 0-based, the
+// user gives the 1-based edge ID printed on the template.
+type edgeRef struct {
+	object, edge int
+}
+
+// parseEdgeRefs parses a comma-separated list of "[object:]edge" references.
+// Without an object the first object is used.
+func parseEdgeRefs(s string) ([]edgeRef, error) {
+	var refs []edgeRef
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		obj, edge := "0", field
+		if before, after, ok := strings.Cut(field, ":"); ok {
+			obj, edge = before, after
+		}
+		o, err := strconv.Atoi(obj)
+		if err != nil || o < 0 {
+			return nil, fmt.Errorf("invalid object index in edge %q", field)
+		}
+		e, err := strconv.Atoi(edge)
+		if err != nil || e < 1 {
+			return nil, fmt.Errorf("invalid edge ID in edge %q", field)
+		}
+		refs = append(refs, edgeRef{object: o, edge: e - 1})
+	}
+	return refs, nil
+}
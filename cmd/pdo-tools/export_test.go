@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/export"
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestConvertFormats(t *testing.T) {
+	dir := t.TempDir()
+	input := writeModel(t, dir, "cube.pdo", pdotest.Cube(pdotest.Options{}))
+	if err := os.Mkdir(filepath.Join(dir, "out"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The -output extension names no format, repeated formats are written once.
+	r := pdoTools(t, dir, "convert", input, "-formats", "svg, OBJ,info-json,svg", "-o", "out/model.pdf")
+	if r.code != 0 {
+		t.Fatalf("exit code %d: %s", r.code, r.stderr)
+	}
+	for _, name := range []string{"model.svg", "model.obj", "model.mtl", "model.info.json"} {
+		if _, err := os.Stat(filepath.Join(dir, "out", name)); err != nil {
+			t.Error(err)
+		}
+	}
+	if n := strings.Count(r.stdout, "Exported to "); n != 3 {
+		t.Errorf("%d outputs reported, want 3:\n%s", n, r.stdout)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "model.pdf")); err == nil {
+		t.Error("wrote the -output file itself")
+	}
+	var info infoReport
+	if err := json.Unmarshal([]byte(readFile(t, filepath.Join(dir, "out", "model.info.json"))), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.File != input || info.Stats.Faces != 6 {
+		t.Errorf("info report for %q with %d faces", info.File, info.Stats.Faces)
+	}
+
+	// Without -output the outputs are named after the input. A model
+	// without an unfold exports its 3D formats only.
+	mesh := pdotest.Cube(pdotest.Options{})
+	mesh.Parts, mesh.TextBlocks = nil, nil
+	input = writeModel(t, dir, "model.pdo", mesh)
+	r = pdoTools(t, dir, "convert", input, "-formats", "obj,svg")
+	if r.code != exitPartial || !strings.Contains(r.stderr, "svg: ") {
+		t.Errorf("partial export: exit code %d: %s", r.code, r.stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "model.obj")); err != nil {
+		t.Error(err)
+	}
+	if r = pdoTools(t, dir, "convert", input, "-formats", "svg"); r.code != exitExport {
+		t.Errorf("failed export: exit code %d: %s", r.code, r.stderr)
+	}
+
+	for _, args := range [][]string{
+		{"-formats", "svg,gif"},
+		{"-formats", "svg,pdf", "-per-part"},
+	} {
+		if r := pdoTools(t, dir, append([]string{"convert", "cube.pdo"}, args...)...); r.code != exitUsage {
+			t.Errorf("%q: exit code %d, want %d", args, r.code, exitUsage)
+		}
+	}
+}
+
+func TestExport_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	input := writeModel(t, dir, "cube.pdo", pdotest.Cube(pdotest.Options{Texture: true}))
+	mesh := pdotest.Cube(pdotest.Options{})
+	mesh.Parts, mesh.TextBlocks = nil, nil
+	writeModel(t, dir, "mesh.pdo", mesh)
+	// The scratch directories go here, to check they are removed.
+	scratch := t.TempDir()
+	t.Setenv("TMPDIR", scratch)
+
+	// written lists the files besides the inputs and scratch directories
+	// left behind.
+	written := func() []string {
+		t.Helper()
+		var files []string
+		for _, d := range []string{dir, scratch} {
+			entries, err := os.ReadDir(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if !strings.HasSuffix(e.Name(), ".pdo") {
+					files = append(files, e.Name())
+				}
+			}
+		}
+		return files
+	}
+
+	for _, tt := range []struct {
+		args  []string
+		files int
+	}{
+		{[]string{"-format", "pdf"}, 1},
+		{[]string{"-formats", "svg,obj", "-dump-textures"}, 4},
+	} {
+		r := pdoTools(t, dir, append([]string{"convert", input, "-dry-run"}, tt.args...)...)
+		if r.code != 0 {
+			t.Fatalf("%q: exit code %d: %s", tt.args, r.code, r.stderr)
+		}
+		if !strings.HasPrefix(r.stdout, "Dry run: parsed in ") || !strings.Contains(r.stdout, fmt.Sprintf("exported %d files (", tt.files)) ||
+			!strings.HasSuffix(r.stdout, " warnings, nothing written\n") {
+			t.Errorf("%q: output %q", tt.args, r.stdout)
+		}
+		if files := written(); len(files) != 0 {
+			t.Errorf("%q: wrote %v", tt.args, files)
+		}
+	}
+
+	// Failures exit with their code and still clean up.
+	if r := pdoTools(t, dir, "mesh.pdo", "-dry-run"); r.code != exitExport {
+		t.Errorf("failed export: exit code %d: %s", r.code, r.stderr)
+	}
+	if files := written(); len(files) != 0 {
+		t.Errorf("failed export wrote %v", files)
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		format, output string
+		want           string // Exporter name, or a part of the error
+	}{
+		{"", "", "svg"},
+		{"", "model", "svg"},
+		{"", "out/model.PDF", "pdf"},
+		{"", "model.ps", "eps"},
+		{"", "model.wrl", "vrml"},
+		{"obj", "model.svg", "obj"},
+		{"", "model.png", "render-pages command"},
+		{"", "model.gif", "no format writes .gif files, choose one with -format"},
+		{"gif", "", `unknown output format "gif"`},
+	}
+	for _, tt := range tests {
+		e, err := outputFormat(tt.format, tt.output)
+		switch {
+		case err != nil && !strings.Contains(err.Error(), tt.want):
+			t.Errorf("outputFormat(%q, %q): %v, want %s", tt.format, tt.output, err, tt.want)
+		case err == nil && e.Name() != tt.want:
+			t.Errorf("outputFormat(%q, %q) = %s, want %s", tt.format, tt.output, e.Name(), tt.want)
+		}
+	}
+
+	dir := t.TempDir()
+	input := writeModel(t, dir, "cube.pdo", pdotest.Cube(pdotest.Options{}))
+	if r := pdoTools(t, dir, input, "-o", "cube.dxf"); r.code != 0 || !strings.HasPrefix(readFile(t, filepath.Join(dir, "cube.dxf")), "999\n") {
+		t.Errorf("-o cube.dxf: exit code %d, %s", r.code, r.stderr)
+	}
+	if r := pdoTools(t, dir, input, "-o", "cube.gif"); r.code != exitUsage {
+		t.Errorf("-o cube.gif: exit code %d, want %d", r.code, exitUsage)
+	}
+}
+
+func TestListFormats(t *testing.T) {
+	r := pdoTools(t, t.TempDir(), "-list-formats")
+	if r.code != 0 {
+		t.Fatalf("exit code %d: %s", r.code, r.stderr)
+	}
+	lines := strings.Split(strings.TrimSuffix(r.stdout, "\n"), "\n")
+	if len(lines) != len(export.Exporters())+1 {
+		t.Errorf("%d formats listed:\n%s", len(lines), r.stdout)
+	}
+	for _, want := range []string{"eps .eps .ps template", "obj .obj 3D model", "info-json .info.json info report, -formats only"} {
+		if !slices.ContainsFunc(lines, func(l string) bool { return strings.Join(strings.Fields(l), " ") == want }) {
+			t.Errorf("no line %q in:\n%s", want, r.stdout)
+		}
+	}
+}
+
+func TestExport_PerPart(t *testing.T) {
+	dir := t.TempDir()
+	input := writeModel(t, dir, "cube.pdo", pdotest.Cube(pdotest.Options{Pages: 2}))
+	r := pdoTools(t, dir, input, "-per-part", "-o", "part.dxf")
+	if r.code != 0 {
+		t.Fatalf("exit code %d: %s", r.code, r.stderr)
+	}
+	for _, name := range []string{"part_00_cube 1.dxf", "part_01_cube 2.dxf"} {
+		if !strings.Contains(readFile(t, filepath.Join(dir, name)), "\nLINE\n") {
+			t.Errorf("%s has no lines", name)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-per-part", "-format", "obj"},
+		{"-per-part", "-explode", "-format", "obj"},
+		{"-per-part", "-formats", "svg,dxf"},
+	} {
+		if r := pdoTools(t, dir, append([]string{input}, args...)...); r.code != exitUsage {
+			t.Errorf("%q: exit code %d, want %d", args, r.code, exitUsage)
+		}
+	}
+}
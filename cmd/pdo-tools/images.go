@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["extract-images"] = runExtractImages
+	commands["insert-image"] = runInsertImage
+}
+
+// imageEntry describes an extracted page image in the sidecar JSON.
+type imageEntry struct {
+	Index       int     `json:"index"`
+	File        string  `json:"file"`
+	PageColumn  int     `json:"page_column"`
+	PageRow     int     `json:"page_row"`
+	X           float64 `json:"x_mm"`
+	Y           float64 `json:"y_mm"`
+	Width       float64 `json:"width_mm"`
+	Height      float64 `json:"height_mm"`
+	PixelWidth  int32   `json:"width_px"`
+	PixelHeight int32   `json:"height_px"`
+}
+
+// runExtractImages writes the pictures placed on the pages (not the
+// material textures) to PNG files, with their placement in a JSON file.
+func runExtractImages(args []string) error {
+	fs := flag.NewFlagSet("extract-images", flag.ExitOnError)
+	output := fs.String("output", "", "Output file prefix (default: the input name)")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools extract-images [options] <file.pdo>")
+		fmt.Println("Writes page images to <prefix>_imageN.png and their placement to <prefix>_images.json.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	input := fs.Arg(0)
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	parser, err := parseInput(input, opts)
+	if err != nil {
+		return err
+	}
+	p := parser.PDO
+	if len(p.Images) == 0 {
+		fmt.Println("File has no page images")
+		return nil
+	}
+
+	prefix := *output
+	if prefix == "" {
+		prefix = strings.TrimSuffix(input, filepath.Ext(input))
+	}
+	dims := p.PageDims()
+	entries := []imageEntry{}
+	for i := range p.Images {
+		pi := &p.Images[i]
+		img, err := pi.Texture.GetImage()
+		if err != nil {
+			logger.Warn("failed to decode image", "image", i+1, "err", err)
+			continue
+		}
+		name := fmt.Sprintf("%s_image%d.png", prefix, i+1)
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		err = png.Encode(f, img)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		bb := pi.BoundingBox
+		col, row := gridPage(dims, bb)
+		entries = append(entries, imageEntry{
+			Index: i, File: filepath.Base(name), PageColumn: col, PageRow: row,
+			X: round3(bb.Left), Y: round3(bb.Top), Width: round3(bb.Width), Height: round3(bb.Height),
+			PixelWidth: pi.Texture.Width, PixelHeight: pi.Texture.Height,
+		})
+		fmt.Printf("Wrote %s\n", name)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	sidecar := prefix + "_images.json"
+	if err := os.WriteFile(sidecar, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sidecar, err)
+	}
+	fmt.Printf("Wrote %s\n", sidecar)
+	return nil
+}
+
+// runInsertImage places a PNG or JPEG picture on a page.
+func runInsertImage(args []string) error {
+	fs := flag.NewFlagSet("insert-image", flag.ExitOnError)
+	imagePath := fs.String("image", "", "PNG or JPEG file to place")
+	page := fs.String("page", "1,1", "Page column and row, from 1")
+	x := fs.Float64("x", 0, "Distance in mm from the left of the printable area")
+	y := fs.Float64("y", 0, "Distance in mm from the top of the printable area")
+	width := fs.Float64("width", 0, "Width in mm (default: from the height, or 50)")
+	height := fs.Float64("height", 0, "Height in mm (default: keeps the aspect ratio)")
+	under := fs.Bool("under", false, "Draw the image beneath the parts")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools insert-image -image <logo.png> [options] <file.pdo>")
+		fmt.Println("Places a picture on a page and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	if *imagePath == "" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	var col, row int
+	if _, err := fmt.Sscanf(*page, "%d,%d", &col, &row); err != nil || col < 1 || row < 1 {
+		return fmt.Errorf("invalid page %q, want column,row", *page)
+	}
+	if *width < 0 || *height < 0 {
+		return errors.New("image size must be positive")
+	}
+
+	f, err := os.Open(*imagePath)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", *imagePath, err)
+	}
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	w, h := *width, *height
+	aspect := float64(img.Bounds().Dy()) / float64(img.Bounds().Dx())
+	switch {
+	case w == 0 && h == 0:
+		w = 50
+		h = w * aspect
+	case w == 0:
+		w = h / aspect
+	case h == 0:
+		h = w * aspect
+	}
+	dims := p.PageDims()
+	bb := pdo.Rect{
+		Left:  float64(col-1)*dims.ClippedWidth + *x,
+		Top:   float64(row-1)*dims.ClippedHeight + *y,
+		Width: w, Height: h,
+	}
+	if *x+w > dims.ClippedWidth || *y+h > dims.ClippedHeight {
+		edit.logger().Warn("image reaches past the printable area", "width_mm", w, "height_mm", h)
+	}
+	if err := p.AddImage(img, bb, !*under); err != nil {
+		return err
+	}
+	fmt.Printf("Placed %dx%d image at %g, %g mm, %g x %g mm\n", img.Bounds().Dx(), img.Bounds().Dy(), bb.Left, bb.Top, w, h)
+	return edit.save(fs, p)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestExtractImages(t *testing.T) {
+	dir := t.TempDir()
+	p := pdotest.Cube(pdotest.Options{Pages: 2})
+	input := writeModel(t, dir, "plain.pdo", p)
+	if r := pdoTools(t, dir, "extract-images", input); r.code != 0 || r.stdout != "File has no page images\n" {
+		t.Errorf("without images: exit code %d, output %q", r.code, r.stdout)
+	}
+
+	dims := p.PageDims()
+	logo := image.NewRGBA(image.Rect(0, 0, 6, 3))
+	logo.Set(1, 2, color.RGBA{R: 255, A: 255})
+	for _, bb := range []pdo.Rect{{Left: 5, Top: 5, Width: 30, Height: 15}, {Left: dims.ClippedWidth + 10, Top: 40, Width: 12, Height: 6}} {
+		if err := p.AddImage(logo, bb, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input = writeModel(t, dir, "cube.pdo", p)
+	if err := os.Mkdir(filepath.Join(dir, "out"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r := pdoTools(t, dir, "extract-images", "-output", "out/logo", input)
+	if r.code != 0 {
+		t.Fatalf("exit code %d: %s", r.code, r.stderr)
+	}
+	if !strings.Contains(r.stdout, "Wrote out/logo_image2.png\n") || !strings.HasSuffix(r.stdout, "Wrote out/logo_images.json\n") {
+		t.Errorf("output %q", r.stdout)
+	}
+
+	var entries []imageEntry
+	if err := json.Unmarshal([]byte(readFile(t, filepath.Join(dir, "out", "logo_images.json"))), &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := imageEntry{Index: 1, File: "logo_image2.png", PageColumn: 2, PageRow: 1, X: round3(dims.ClippedWidth + 10), Y: 40, Width: 12, Height: 6, PixelWidth: 6, PixelHeight: 3}
+	if len(entries) != 2 || entries[1] != want {
+		t.Fatalf("entries %+v, want the second %+v", entries, want)
+	}
+	f, err := os.Open(filepath.Join(dir, "out", entries[1].File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != logo.Bounds() || color.RGBAModel.Convert(img.At(1, 2)) != logo.At(1, 2) {
+		t.Errorf("extracted image differs from the placed one")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pdo-tools/pkg/importer"
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["import"] = runImport
+}
+
+// runImport converts a glTF or Collada model into a PDO without an unfolding.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	output := fs.String("output", "", "Output PDO file (default <input>.pdo)")
+	format := fs.String("format", "", "Input format (default from the file extension)")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools import [options] <model.gltf|model.glb|model.dae>")
+		fmt.Println("Converts a 3D model into a PDO file to unfold in Pepakura Designer.")
+		var names []string
+		for _, imp := range importer.Importers() {
+			names = append(names, imp.Name())
+		}
+		fmt.Printf("Formats: %s\n", strings.Join(names, ", "))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	input := fs.Arg(0)
+
+	var imp importer.Importer
+	var ok bool
+	if *format != "" {
+		imp, ok = importer.Lookup(*format)
+	} else {
+		imp, ok = importer.ForExtension(filepath.Ext(input))
+	}
+	if !ok {
+		return fmt.Errorf("no importer for %s", input)
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(input, filepath.Ext(input)) + ".pdo"
+	}
+	if filepath.Clean(out) == filepath.Clean(input) {
+		return fmt.Errorf("refusing to overwrite the input file %s", input)
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	p, err := imp.Import(context.Background(), importer.Source{R: f, Path: input}, importer.Options{Logger: common.logger()})
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", input, err)
+	}
+	if err := pdo.WriteFile(out, p); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	faces := 0
+	for _, obj := range p.Objects {
+		faces += len(obj.Faces)
+	}
+	fmt.Printf("Wrote %s (%d objects, %d faces, %d materials)\n", out, len(p.Objects), faces, len(p.Materials))
+	return nil
+}
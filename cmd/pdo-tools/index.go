@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"pdo-tools/pkg/index"
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["index"] = runIndex
+	commands["search"] = runSearch
+	commands["duplicate-files"] = runDuplicateFiles
+}
+
+// defaultIndex is the index file of index and search without -index.
+const defaultIndex = "pdo-index.json"
+
+// runIndex writes the metadata of the PDO files in directories to an index
+// file for search. Files unchanged since the last run keep their entries.
+func runIndex(args []string) error {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	indexFile := flags.String("index", defaultIndex, "Index file to write")
+	rebuild := flags.Bool("rebuild", false, "Parse every file again, even those unchanged since the index was written")
+	common := addCommonFlags(flags)
+	flags.Usage = func() {
+		fmt.Println("Usage: pdo-tools index [options] <dir|file.pdo> ...")
+		fmt.Println("Indexes author, designer ID, comment, text, object names and statistics of the PDO files for search.")
+		flags.PrintDefaults()
+	}
+	parseInterspersed(flags, args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	files, err := findPDOs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	previous := map[string]index.Entry{}
+	if f, err := os.Open(*indexFile); err == nil && !*rebuild {
+		old, err := index.Read(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w (use -rebuild to replace it)", *indexFile, err)
+		}
+		for _, e := range old.Entries {
+			previous[e.File] = e
+		}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	ix := &index.Index{Updated: time.Now().UTC()}
+	var parsed, failed int
+	ix.Entries, parsed, failed = indexFiles(files, previous, opts, logger)
+
+	f, err := os.Create(*indexFile)
+	if err != nil {
+		return err
+	}
+	err = ix.Write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Indexed %d models (%d parsed, %d unchanged) in %s\n", len(ix.Entries), parsed, len(ix.Entries)-parsed, *indexFile)
+	if failed > 0 {
+		return withExit(exitPartial, fmt.Errorf("%d of %d files failed to index", failed, len(files)))
+	}
+	return nil
+}
+
+// indexFiles indexes PDO files, reusing the previous entries of those of
+// the same size and modification time. Files that can't be read or parsed
+// are logged and counted as failed.
+func indexFiles(files []string, previous map[string]index.Entry, opts pdo.Options, logger *slog.Logger) (entries []index.Entry, parsed, failed int) {
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			logger.Warn("skipping file", "err", err)
+			failed++
+			continue
+		}
+		if e, ok := previous[file]; ok && e.Size == info.Size() && e.ModTime.Equal(info.ModTime()) {
+			entries = append(entries, e)
+			continue
+		}
+		hash, err := fileHash(file)
+		if err != nil {
+			logger.Warn("skipping file", "err", err)
+			failed++
+			continue
+		}
+		parser, err := parseInput(file, opts)
+		if err != nil {
+			logger.Warn("skipping file", "err", err)
+			failed++
+			continue
+		}
+		e := index.NewEntry(file, parser.PDO)
+		e.Size, e.ModTime, e.Hash = info.Size(), info.ModTime(), hash
+		entries = append(entries, e)
+		parsed++
+	}
+	return entries, parsed, failed
+}
+
+// fileHash returns the SHA-256 of a file in hex.
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// runSearch lists the indexed models matching a query.
+func runSearch(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	input := flags.String("index", defaultIndex, "Index file written by the index command")
+	jsonOutput := flags.Bool("json", false, "Print the matching entries as JSON")
+	flags.Usage = func() {
+		fmt.Println("Usage: pdo-tools search [options] <term> ...")
+		fmt.Println("Lists the indexed models matching all terms, each one of:")
+		fmt.Println("  words         in the file name, author, designer, comment, text or object names")
+		fmt.Println("  field:words   in one of file, author, designer, comment, text, object or level")
+		fmt.Println("  field<number  with <, <=, >, >= or = on parts, faces, pages, score, scale or version")
+		fmt.Println("Words match case-insensitively; quote terms with spaces.")
+		flags.PrintDefaults()
+	}
+	parseInterspersed(flags, args)
+
+	query, err := index.ParseQuery(flags.Args())
+	if err != nil {
+		return withExit(exitUsage, err)
+	}
+	f, err := os.Open(*input)
+	if err != nil {
+		return err
+	}
+	ix, err := index.Read(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	matches := ix.Search(query)
+
+	if *jsonOutput {
+		entries := make([]*index.Entry, len(matches))
+		for i, m := range matches {
+			entries[i] = m.Entry
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	for _, m := range matches {
+		e := m.Entry
+		details := []string{fmt.Sprintf("%d parts", e.Stats.Parts), fmt.Sprintf("%d pages", e.Pages()), e.Difficulty.Level}
+		if e.Author != "" {
+			details = append([]string{e.Author}, details...)
+		}
+		fmt.Printf("%s (%s)\n", e.File, strings.Join(details, ", "))
+		for _, s := range m.Snippets {
+			fmt.Printf("    %s\n", s)
+		}
+	}
+	fmt.Printf("%d of %d models match\n", len(matches), len(ix.Entries))
+	return nil
+}
+
+// runDuplicateFiles lists the files holding copies of the same model.
+func runDuplicateFiles(args []string) error {
+	flags := flag.NewFlagSet("duplicate-files", flag.ExitOnError)
+	indexFile := flags.String("index", defaultIndex, "Index file written by the index command, read without files or directories")
+	jsonOutput := flags.Bool("json", false, "Print the groups as JSON")
+	common := addCommonFlags(flags)
+	flags.Usage = func() {
+		fmt.Println("Usage: pdo-tools duplicate-files [options] [<dir|file.pdo> ...]")
+		fmt.Println("Groups the files holding the same model, by file hash, fingerprint, shape, textures and size,")
+		fmt.Println("or by author, comment and object names for edited copies.")
+		flags.PrintDefaults()
+	}
+	parseInterspersed(flags, args)
+
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	var entries []index.Entry
+	failed := 0
+	if flags.NArg() == 0 {
+		f, err := os.Open(*indexFile)
+		if err != nil {
+			return err
+		}
+		ix, err := index.Read(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		entries = ix.Entries
+	} else {
+		files, err := findPDOs(flags.Args())
+		if err != nil {
+			return err
+		}
+		entries, _, failed = indexFiles(files, nil, opts, logger)
+	}
+	groups := index.Duplicates(entries)
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(groups); err != nil {
+			return err
+		}
+	} else {
+		copies := 0
+		for _, g := range groups {
+			fmt.Printf("%s:\n", g.Kind)
+			for _, d := range g.Files {
+				details := []string{fmt.Sprintf("%.1f mm", d.Extent)}
+				if d.Author != "" {
+					details = append(details, d.Author)
+				}
+				if d.SameAs != "" {
+					details = append(details, "same bytes as "+d.SameAs)
+				}
+				fmt.Printf("    %s (%s)\n", d.File, strings.Join(details, ", "))
+			}
+			copies += len(g.Files) - 1
+		}
+		fmt.Printf("%d groups, %d copies among %d models\n", len(groups), copies, len(entries))
+	}
+	if failed > 0 {
+		return withExit(exitPartial, fmt.Errorf("%d files failed to parse", failed))
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["info"] = runInfo
+}
+
+// infoReport is the machine-readable output of the info command.
+type infoReport struct {
+	File     string
+	Version  int32
+	Designer string
+	Key      string
+	Locale   string
+	Codepage string
+	Author   string
+	Comment  string
+	Scale    float64
+	// Fingerprint is the pdo.Fingerprint of the model.
+	Fingerprint string
+	// TexturesLocked is set when the texture lock forbids taking the
+	// textures out.
+	TexturesLocked bool
+	Stats          pdo.Statistics
+	Size           pdo.SizeCheck
+	Paper          pdo.PaperEstimate
+	Difficulty     pdo.Difficulty
+}
+
+// runInfo prints metadata and statistics of a PDO file.
+func runInfo(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	unitName := fs.String("units", "auto", "Lengths shown in mm or in, auto follows the file's locale (JSON is always in mm)")
+	paper := addPaperFlags(fs)
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools info [options] <file.pdo>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	stock, err := paper.paper()
+	if err != nil {
+		return err
+	}
+
+	parser, err := parseInput(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	p := parser.PDO
+
+	report := newInfoReport(fs.Arg(0), p, stock)
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	u, err := parseUnit(*unitName, p.Header.LocaleInches())
+	if err != nil {
+		return err
+	}
+	printInfo(os.Stdout, report, u)
+	return nil
+}
+
+func newInfoReport(file string, p *pdo.PDO, paper pdo.Paper) infoReport {
+	return infoReport{
+		File:           file,
+		Version:        p.Header.Version,
+		Designer:       p.Header.DesignerID,
+		Key:            p.Header.Key,
+		Locale:         p.Header.Locale,
+		Codepage:       p.Header.Codepage,
+		Author:         p.Settings.AuthorName,
+		Comment:        p.Settings.Comment,
+		Scale:          p.Unfold.Scale,
+		Fingerprint:    pdo.Fingerprint(p),
+		TexturesLocked: p.CheckTextureLock() != nil,
+		Stats:          pdo.Stats(p),
+		Size:           p.CheckSize(),
+		Paper:          p.EstimatePaper(paper),
+		Difficulty:     p.Difficulty(),
+	}
+}
+
+func printInfo(w io.Writer, r infoReport, u lengthUnit) {
+	s := r.Stats
+	fmt.Fprintf(w, "File:          %s\n", r.File)
+	fmt.Fprintf(w, "Version:       %d (%s)\n", r.Version, r.Designer)
+	if r.Key != "" {
+		fmt.Fprintf(w, "Key:           %s\n", r.Key)
+	}
+	fmt.Fprintf(w, "Locale:        %s, codepage %s\n", r.Locale, r.Codepage)
+	if r.Author != "" {
+		fmt.Fprintf(w, "Author:        %s\n", r.Author)
+	}
+	if r.Comment != "" {
+		fmt.Fprintf(w, "Comment:       %s\n", r.Comment)
+	}
+	fmt.Fprintf(w, "Unfold scale:  %g\n", r.Scale)
+	fmt.Fprintf(w, "Fingerprint:   %s\n", r.Fingerprint)
+	fmt.Fprintf(w, "Objects:       %d (%d vertices, %d faces, %d edges)\n", s.Objects, s.Vertices, s.Faces, s.Edges)
+	fmt.Fprintf(w, "Materials:     %d (%d textured)\n", s.Materials, s.TexturedMaterials)
+	if r.TexturesLocked {
+		fmt.Fprintf(w, "Texture lock:  the designer forbids taking the textures out\n")
+	}
+	fmt.Fprintf(w, "Parts:         %d (%d lines)\n", s.Parts, s.Lines)
+	fmt.Fprintf(w, "Text blocks:   %d\n", s.TextBlocks)
+	fmt.Fprintf(w, "Images:        %d\n", s.Images)
+	fmt.Fprintf(w, "Surface area:  %.2f (model units²)\n", s.SurfaceArea)
+	fmt.Fprintf(w, "Template area: %.2f %s²\n", u.area(s.TemplateArea), u.name)
+	fmt.Fprintf(w, "Model bounds:  (%.2f, %.2f, %.2f) - (%.2f, %.2f, %.2f)\n",
+		s.ModelBounds.Min.X, s.ModelBounds.Min.Y, s.ModelBounds.Min.Z,
+		s.ModelBounds.Max.X, s.ModelBounds.Max.Y, s.ModelBounds.Max.Z)
+	fmt.Fprintf(w, "Layout bounds: %.2f x %.2f %s at (%.2f, %.2f)\n",
+		u.len(s.LayoutBounds.Width), u.len(s.LayoutBounds.Height), u.name, u.len(s.LayoutBounds.Left), u.len(s.LayoutBounds.Top))
+	fmt.Fprintf(w, "Real height:   %.1f %s (header %g units, model %g units)\n", u.len(r.Size.RealHeight()), u.name, r.Size.HeaderHeight, r.Size.ModelHeight)
+	if r.Size.ScaleMismatch() {
+		fmt.Fprintf(w, "               parts are unfolded at scale %g, not %g\n", r.Size.LayoutScale, r.Size.Scale)
+	}
+	fmt.Fprintf(w, "Pages:         %d x %d\n", s.PagesX, s.PagesY)
+	fmt.Fprintf(w, "Textures:      %d bytes decoded, %d bytes stored\n", s.TextureBytes, s.CompressedTextureBytes)
+	e := r.Paper
+	if u.name == "mm" {
+		fmt.Fprintf(w, "Paper:         %.0f cm² of parts, %.0f cm² of flaps, %.1f g at %.0f g/m²\n",
+			e.Area/100, e.FlapArea/100, e.Weight, e.Grammage)
+	} else {
+		fmt.Fprintf(w, "Paper:         %.1f %s² of parts, %.1f %s² of flaps, %.1f g at %.0f g/m²\n",
+			u.area(e.Area), u.name, u.area(e.FlapArea), u.name, e.Weight, e.Grammage)
+	}
+	fmt.Fprintf(w, "Sheets:        %d (%.1f g", e.Sheets, e.SheetWeight)
+	if e.StackHeight > 0 {
+		fmt.Fprintf(w, ", %.2f %s stack", u.len(e.StackHeight), u.name)
+	}
+	fmt.Fprintln(w, ")")
+	d := r.Difficulty
+	fmt.Fprintf(w, "Difficulty:    %s (score %.1f: %d parts, %d flaps, %d folds, faces %.2f %s² on average, smallest part %.2f %s)\n",
+		d.Level, d.Score, d.Parts, d.Flaps, d.Folds, u.area(d.AverageFaceArea), u.name, u.len(d.SmallestPart), u.name)
+}
+
+// lengthUnit converts lengths in mm for display.
+type lengthUnit struct {
+	name string
+	mm   float64 // Size of the unit in mm
+}
+
+func (u lengthUnit) len(mm float64) float64   { return mm / u.mm }
+func (u lengthUnit) area(mm2 float64) float64 { return mm2 / (u.mm * u.mm) }
+
+// parseUnit converts a -units flag value, "auto" picking inches when the
+// file's locale does.
+func parseUnit(s string, inches bool) (lengthUnit, error) {
+	switch s {
+	case "auto":
+		if inches {
+			return lengthUnit{"in", 25.4}, nil
+		}
+		return lengthUnit{"mm", 1}, nil
+	case "mm":
+		return lengthUnit{"mm", 1}, nil
+	case "in":
+		return lengthUnit{"in", 25.4}, nil
+	}
+	return lengthUnit{}, fmt.Errorf("unknown units %q", s)
+}
+
+// paperFlags are the flags describing the paper a model is printed on.
+type paperFlags struct {
+	grammage  *float64
+	thickness *float64
+}
+
+func addPaperFlags(fs *flag.FlagSet) *paperFlags {
+	return &paperFlags{
+		grammage:  fs.Float64("gsm", 0, fmt.Sprintf("Paper weight in g/m² for weight estimates (default from -thickness, else %d)", pdo.DefaultGrammage)),
+		thickness: fs.Float64("thickness", 0, "Paper thickness in mm for weight and stack estimates"),
+	}
+}
+
+func (f *paperFlags) paper() (pdo.Paper, error) {
+	if *f.grammage < 0 || *f.thickness < 0 {
+		return pdo.Paper{}, fmt.Errorf("invalid paper: %g g/m², %g mm", *f.grammage, *f.thickness)
+	}
+	return pdo.Paper{Grammage: *f.grammage, Thickness: *f.thickness}, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["joints"] = runJoints
+}
+
+// runJoints lists the edges where faces meet, with their parts, lengths and
+// fold angles.
+func runJoints(args []string) error {
+	fs := flag.NewFlagSet("joints", flag.ExitOnError)
+	format := fs.String("format", "csv", "Report format (csv, json)")
+	output := fs.String("output", "", "Write the report to this file instead of stdout")
+	cutsOnly := fs.Bool("cuts", false, "Only list cut edges glued between parts")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools joints [options] <file.pdo>")
+		fmt.Println("Lists every edge shared by two faces: parts, edge ID, lengths and fold angle.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown report format %q", *format)
+	}
+
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	parser, err := parseInput(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	p := parser.PDO
+
+	joints := p.Joints()
+	if *cutsOnly {
+		cuts := joints[:0]
+		for _, j := range joints {
+			if j.Kind == pdo.JointCut {
+				cuts = append(cuts, j)
+			}
+		}
+		joints = cuts
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(joints)
+	}
+	return writeJointsCSV(w, p, joints)
+}
+
+func writeJointsCSV(w io.Writer, p *pdo.PDO, joints []pdo.Joint) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"object", "edge_id", "kind", "part_a", "part_a_name", "face_a", "part_b", "part_b_name", "face_b",
+		"length_mm", "template_length_a_mm", "template_length_b_mm", "fold_angle"})
+	mm := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	for _, j := range joints {
+		cw.Write([]string{
+			strconv.Itoa(j.Object), strconv.Itoa(j.Edge + 1), j.Kind.String(),
+			strconv.Itoa(j.PartA), partName(p, j.PartA), strconv.Itoa(j.FaceA),
+			strconv.Itoa(j.PartB), partName(p, j.PartB), strconv.Itoa(j.FaceB),
+			mm(j.Length), mm(j.LengthA), mm(j.LengthB), strconv.FormatFloat(j.FoldAngle, 'f', 2, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// partName returns the name of a part, empty when it has none.
+func partName(p *pdo.PDO, i int) string {
+	if i < 0 || i >= len(p.Parts) {
+		return ""
+	}
+	return p.Parts[i].Name
+}
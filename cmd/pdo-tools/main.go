@@ -3,108 +3,116 @@ package main
 import (
 	"flag"
 	"fmt"
-	"image/png"
+	"log/slog"
 	"os"
-	"path/filepath"
-	"strings"
+	"sort"
+	"strconv"
 
-	"pdo-tools/pkg/export"
 	"pdo-tools/pkg/pdo"
 )
 
-func main() {
-	output := flag.String("output", "", "Output file path")
-	format := flag.String("format", "svg", "Output format (svg, pdf, obj)")
-	dumpTextures := flag.Bool("dump-textures", false, "Dump textures to PNG files")
-	flag.Parse()
+// commands maps subcommand names to their implementations. Without a known
+// subcommand the arguments are handled by the export command.
+var commands = map[string]func(args []string) error{}
 
-	args := flag.Args()
-	if len(args) < 1 {
-		fmt.Println("Usage: pdo-tools [options] <file.pdo>")
-		flag.PrintDefaults()
-		os.Exit(1)
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fail(err)
+			}
+			return
+		}
 	}
+	runExport(os.Args[1:])
+}
 
-	inputFile := args[0]
-
-	// Determine format from output filename if manually specified
-	if *output != "" && *format == "svg" {
-		ext := strings.ToLower(filepath.Ext(*output))
-		switch ext {
-		case ".pdf":
-			*format = "pdf"
-		case ".obj":
-			*format = "obj"
-		}
+// commandNames returns the registered subcommands in alphabetical order.
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	// Determine output filename if not specified
-	if *output == "" {
-		ext := ".svg"
-		switch *format {
-		case "pdf":
-			ext = ".pdf"
-		case "obj":
-			ext = ".obj"
+// parseInterspersed parses flags given before, between and after the
+// positional arguments, which stay available from fs.Args.
+func parseInterspersed(fs *flag.FlagSet, args []string) {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			break
 		}
-		*output = strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + ext
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
+	fs.Parse(append([]string{"--"}, positional...))
+}
 
-	pdoFile, err := pdo.ParseFile(inputFile)
-	if err != nil {
-		fmt.Printf("Error parsing file: %v\n", err)
-		os.Exit(1)
-	}
+// commonFlags are the logging and string decoding flags shared by all commands.
+type commonFlags struct {
+	stringShift    *int
+	multiByte      *string
+	recoverStrings *bool
+	verbose        *bool
+	quiet          *bool
+	logJSON        *bool
+}
 
-	if *dumpTextures {
-		for i, mat := range pdoFile.Materials {
-			if !mat.HasTexture {
-				continue
-			}
-			img, err := mat.Texture.GetImage()
-			if err != nil {
-				fmt.Printf("Error decoding texture for material %s: %v\n", mat.Name, err)
-				continue
-			}
+func addCommonFlags(fs *flag.FlagSet) *commonFlags {
+	// Read by fail once the command has returned.
+	fs.BoolVar(&errorJSON, "error-json", false, "Report a failure on stderr as a JSON object with its exit code")
+	return &commonFlags{
+		stringShift:    fs.Int("string-shift", -1, "Override the string character shift (0-255)"),
+		multiByte:      fs.String("multibyte", "", "Override the multi-byte strings flag (true, false)"),
+		recoverStrings: fs.Bool("recover-strings", false, "Try all string shifts when strings decode to garbage"),
+		verbose:        fs.Bool("verbose", false, "Log debug diagnostics"),
+		quiet:          fs.Bool("quiet", false, "Only log errors"),
+		logJSON:        fs.Bool("log-json", false, "Write log messages as JSON"),
+	}
+}
 
-			texName := fmt.Sprintf("%s_tex%d.png", strings.TrimSuffix(inputFile, ".pdo"), i)
-			f, err := os.Create(texName)
-			if err != nil {
-				fmt.Printf("Error creating texture file %s: %v\n", texName, err)
-				continue
-			}
+func (c *commonFlags) logger() *slog.Logger {
+	return newLogger(*c.verbose, *c.quiet, *c.logJSON)
+}
 
-			if err := png.Encode(f, img); err != nil {
-				fmt.Printf("Error encoding png %s: %v\n", texName, err)
-			}
-			f.Close()
-			fmt.Printf("Extracted material '%s' texture to %s\n", mat.Name, texName)
+// parseOptions builds parser options from the flags.
+func (c *commonFlags) parseOptions(logger *slog.Logger) (pdo.Options, error) {
+	opts := pdo.Options{RecoverStrings: *c.recoverStrings, Logger: logger}
+	if *c.stringShift >= 0 {
+		if *c.stringShift > 255 {
+			return opts, fmt.Errorf("invalid string shift %d, must be 0-255", *c.stringShift)
 		}
+		shift := byte(*c.stringShift)
+		opts.StringShift = &shift
 	}
-
-	f, err := os.Create(*output)
-	if err != nil {
-		fmt.Printf("Error creating output file: %v\n", err)
-		os.Exit(1)
+	if *c.multiByte != "" {
+		mb, err := strconv.ParseBool(*c.multiByte)
+		if err != nil {
+			return opts, fmt.Errorf("invalid multibyte value %q: %w", *c.multiByte, err)
+		}
+		opts.MultiByte = &mb
 	}
-	defer f.Close()
+	return opts, nil
+}
 
-	if *format == "pdf" {
-		if err := export.ExportPDF(pdoFile, f); err != nil {
-			fmt.Printf("Error exporting PDF: %v\n", err)
-			os.Exit(1)
-		}
-	} else if *format == "obj" {
-		if err := export.ExportOBJ(pdoFile, f, *output); err != nil {
-			fmt.Printf("Error exporting OBJ: %v\n", err)
-			os.Exit(1)
-		}
-	} else {
-		if err := export.ExportSVG(pdoFile, f); err != nil {
-			fmt.Printf("Error exporting SVG: %v\n", err)
-			os.Exit(1)
-		}
+// newLogger builds the CLI logger writing to stderr.
+func newLogger(verbose, quiet, jsonOutput bool) *slog.Logger {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	if quiet {
+		level = slog.LevelError
 	}
 
-	fmt.Printf("Exported to %s\n", *output)
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if jsonOutput {
+		return slog.New(slog.NewJSONHandler(os.Stderr, handlerOpts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, handlerOpts))
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// TestMain runs pdo-tools instead of the tests when pdoTools starts the
+// test binary, so tests see the output and exit code of whole commands.
+func TestMain(m *testing.M) {
+	if os.Getenv("PDO_TOOLS_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// result is the output and exit code of a pdo-tools run.
+type result struct {
+	stdout, stderr string
+	code           int
+}
+
+// pdoTools runs pdo-tools with args in dir.
+func pdoTools(t *testing.T, dir string, args ...string) result {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PDO_TOOLS_MAIN=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return result{stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()}
+}
+
+// writeModel writes p to name in dir and returns the file name.
+func writeModel(t *testing.T, dir, name string, p *pdo.PDO) string {
+	t.Helper()
+	if err := pdo.WriteFile(filepath.Join(dir, name), p); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// readFile returns the contents of a file the command wrote.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"pdo-tools/pkg/export"
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["materials"] = runMaterials
+}
+
+// runMaterials lists the materials, or recolors them and writes a new PDO.
+func runMaterials(args []string) error {
+	fs := flag.NewFlagSet("materials", flag.ExitOnError)
+	var colors settingValues
+	fs.Var(&colors, "set-material-color", "Recolor a material, name-or-index=#rrggbb (repeatable)")
+	target := fs.String("target", "both", "Colors to change (both, 2d, 3d)")
+	jsonOutput := fs.Bool("json", false, "Print the material usage as JSON")
+	prune := fs.Bool("prune", false, "Remove unused materials, vertices and edges and write a new PDO file")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools materials [options] <file.pdo> [-set-material-color name=#rrggbb ...]")
+		fmt.Println("Lists the materials with the faces and parts using them, or recolors them and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	var colorTarget pdo.ColorTarget
+	switch *target {
+	case "both":
+		colorTarget = pdo.ColorBoth
+	case "2d":
+		colorTarget = pdo.Color2DOnly
+	case "3d":
+		colorTarget = pdo.Color3DOnly
+	default:
+		return fmt.Errorf("unknown color target %q", *target)
+	}
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	if len(colors) == 0 && !*prune {
+		usage := p.MaterialUsage()
+		if *jsonOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(usage)
+		}
+		if len(p.Materials) == 0 {
+			fmt.Println("File has no materials")
+		}
+		unused := 0
+		for i, m := range p.Materials {
+			texture := ""
+			if m.HasTexture {
+				texture = fmt.Sprintf("  texture %dx%d, %.0f mm² textured", m.Texture.Width, m.Texture.Height, usage[i].TexturedArea)
+			}
+			use := "unused"
+			if u := &usage[i]; u.Used() {
+				use = fmt.Sprintf("%d faces on parts %s", u.Faces, joinInts(u.Parts))
+			} else {
+				unused++
+			}
+			fmt.Printf("%3d  %-24q 2D %s  3D %s%s  %s\n", i, m.Name, colorHex([3]float32(m.Color2DRGBA[:3])), colorHex(m.Color3D.Model.RGB()), texture, use)
+		}
+		if unused > 0 {
+			fmt.Printf("%d unused materials, -prune removes them\n", unused)
+		}
+		return nil
+	}
+
+	for _, kv := range colors {
+		// Material names may contain "=", colors don't.
+		i := strings.LastIndex(kv, "=")
+		ref, value := kv[:i], kv[i+1:]
+		m, err := p.MaterialIndex(ref)
+		if err != nil {
+			return err
+		}
+		c, err := export.ParseColor(value)
+		if err != nil {
+			return err
+		}
+		mat := &p.Materials[m]
+		mat.SetColor([3]float32{float32(c[0]) / 255, float32(c[1]) / 255, float32(c[2]) / 255}, colorTarget)
+		fmt.Printf("Material %d %q: %s\n", m, mat.Name, strings.ToLower(value))
+		if mat.HasTexture && colorTarget != pdo.Color3DOnly {
+			fmt.Printf("Material %d %q has a texture, the color only shows when printing without textures\n", m, mat.Name)
+		}
+	}
+	if *prune {
+		n := p.Prune()
+		fmt.Printf("Pruned %d materials, %d vertices, %d edges\n", n.Materials, n.Vertices, n.Edges)
+	}
+	return edit.save(fs, p)
+}
+
+// joinInts formats a list of numbers as "1, 2, 3".
+func joinInts(v []int) string {
+	s := make([]string, len(v))
+	for i, n := range v {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// colorHex formats RGB components 0-1 as #rrggbb.
+func colorHex(c [3]float32) string {
+	b := func(v float32) uint8 { return uint8(max(0, min(1, v))*255 + 0.5) }
+	return fmt.Sprintf("#%02x%02x%02x", b(c[0]), b(c[1]), b(c[2]))
+}
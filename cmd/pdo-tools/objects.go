@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	commands["ls-objects"] = runListObjects
+}
+
+// objectNames collects repeated object name-or-index flags.
+type objectNames []string
+
+func (s *objectNames) String() string { return strings.Join(*s, ",") }
+
+func (s *objectNames) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// objectReport is a row of the ls-objects command.
+type objectReport struct {
+	Index    int
+	Name     string
+	Vertices int
+	Faces    int
+	Parts    int
+	Visible  bool
+}
+
+// runListObjects lists the objects, or shows and hides them and writes a
+// new PDO.
+func runListObjects(args []string) error {
+	fs := flag.NewFlagSet("ls-objects", flag.ExitOnError)
+	var show, hide objectNames
+	fs.Var(&show, "show", "Make an object visible, by name or index (repeatable)")
+	fs.Var(&hide, "hide", "Hide an object in the 3D view, by name or index (repeatable)")
+	jsonOutput := fs.Bool("json", false, "Print the objects as JSON")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools ls-objects [options] <file.pdo> [-show name ...] [-hide name ...]")
+		fmt.Println("Lists the objects, or shows and hides them and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	if len(show) > 0 || len(hide) > 0 {
+		for _, change := range []struct {
+			refs    objectNames
+			visible uint8
+		}{{show, 1}, {hide, 0}} {
+			for _, ref := range change.refs {
+				i, err := p.ObjectIndex(ref)
+				if err != nil {
+					return err
+				}
+				p.Objects[i].Visible = change.visible
+				fmt.Printf("Object %d %q: %s\n", i, p.Objects[i].Name, visibility(change.visible != 0))
+			}
+		}
+		return edit.save(fs, p)
+	}
+
+	objects := make([]objectReport, len(p.Objects))
+	for i, o := range p.Objects {
+		objects[i] = objectReport{Index: i, Name: o.Name, Vertices: len(o.Vertices), Faces: len(o.Faces), Visible: o.Visible != 0}
+	}
+	for _, part := range p.Parts {
+		if i := int(part.ObjectIndex); i >= 0 && i < len(objects) {
+			objects[i].Parts++
+		}
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(objects)
+	}
+	if len(objects) == 0 {
+		fmt.Println("File has no objects")
+	}
+	for _, o := range objects {
+		fmt.Printf("%3d  %-24q %6d vertices %6d faces %3d parts  %s\n", o.Index, o.Name, o.Vertices, o.Faces, o.Parts, visibility(o.Visible))
+	}
+	return nil
+}
+
+func visibility(visible bool) string {
+	if visible {
+		return "visible"
+	}
+	return "hidden"
+}
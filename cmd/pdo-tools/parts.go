@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["parts"] = runParts
+}
+
+// partReport is a row of the parts command.
+type partReport struct {
+	pdo.PartPaper
+	Page [2]int
+	// Width and Height are the size of the part with its flaps in mm.
+	Width, Height float64
+}
+
+// runParts lists the parts with their page, size, paper area and weight.
+func runParts(args []string) error {
+	fs := flag.NewFlagSet("parts", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the parts as JSON")
+	paper := addPaperFlags(fs)
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools parts [options] <file.pdo>")
+		fmt.Println("Lists every part with its page, size, paper area and estimated weight.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	stock, err := paper.paper()
+	if err != nil {
+		return err
+	}
+	parser, err := parseInput(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+	p := parser.PDO
+
+	pages := p.PartPages(p.PageDims())
+	parts := make([]partReport, len(p.Parts))
+	for i := range p.Parts {
+		bb := p.PartBounds(i)
+		parts[i] = partReport{PartPaper: p.PartPaper(i, stock), Page: pages[i], Width: bb.Width, Height: bb.Height}
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(parts)
+	}
+	for _, r := range parts {
+		fmt.Printf("%4d %-20q page %d,%d  %6.1f x %6.1f mm  %8.1f cm² + %6.1f cm² flaps  %6.2f g\n",
+			r.Part, r.Name, r.Page[0]+1, r.Page[1]+1, r.Width, r.Height, r.Area/100, r.FlapArea/100, r.Weight)
+	}
+	e := p.EstimatePaper(stock)
+	fmt.Printf("total: %.1f cm² + %.1f cm² flaps, %.1f g at %.0f g/m² on %d sheets\n",
+		e.Area/100, e.FlapArea/100, e.Weight, e.Grammage, e.Sheets)
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["check-printable"] = runCheckPrintable
+}
+
+// runCheckPrintable reports the parts a printer would cut off at the page
+// edges, and with -nudge moves them inward and writes a new PDO file.
+func runCheckPrintable(args []string) error {
+	fs := flag.NewFlagSet("check-printable", flag.ExitOnError)
+	printer := fs.String("printer", "inkjet", "Printer preset (borderless, inkjet, laser) or unprintable margins in mm: all, vertical,horizontal or top,right,bottom,left")
+	nudge := fs.Bool("nudge", false, "Move the parts that fit inside the printable area and write a new PDO file")
+	jsonOutput := fs.Bool("json", false, "Print the parts as JSON")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools check-printable [options] <file.pdo>")
+		fmt.Println("Lists the parts reaching into the unprintable margin of a printer, or of the page settings.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	area, err := pdo.ParsePrintableArea(*printer)
+	if err != nil {
+		return err
+	}
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+
+	parts := p.CheckPrintable(area)
+	if *nudge {
+		if n := p.NudgePrintable(area); n > 0 {
+			fmt.Printf("Moved %d parts inside the printable area\n", n)
+			if err := edit.save(fs, p); err != nil {
+				return err
+			}
+			parts = p.CheckPrintable(area)
+		}
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if parts == nil {
+			parts = []pdo.Unprintable{}
+		}
+		if err := enc.Encode(parts); err != nil {
+			return err
+		}
+	} else {
+		for _, u := range parts {
+			fmt.Printf("part %d %q on page %d,%d: %d lines outside, beyond top %.1f right %.1f bottom %.1f left %.1f mm",
+				u.Part, u.Name, u.Page[0]+1, u.Page[1]+1, u.Lines, u.Top, u.Right, u.Bottom, u.Left)
+			if !u.Fits {
+				fmt.Print(", larger than the printable area")
+			}
+			fmt.Println()
+		}
+		if len(parts) == 0 {
+			fmt.Printf("%s: every part is printable with margins %s mm\n", fs.Arg(0), area)
+		}
+	}
+	if len(parts) > 0 {
+		return fmt.Errorf("%s: %d parts reach beyond the printable area", fs.Arg(0), len(parts))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["remove-object"] = runRemoveObject
+	commands["remove-part"] = runRemovePart
+}
+
+// runRemoveObject deletes objects with their parts and prunes what they
+// leave unused.
+func runRemoveObject(args []string) error {
+	fs := flag.NewFlagSet("remove-object", flag.ExitOnError)
+	var refs objectNames
+	fs.Var(&refs, "object", "Object to remove, by name or index (repeatable)")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools remove-object [options] -object name <file.pdo>")
+		fmt.Println("Removes objects and their parts, prunes unused materials, vertices and edges, and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+	if len(refs) == 0 {
+		fs.Usage()
+		return fmt.Errorf("no object to remove")
+	}
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	return removeEach(fs, edit, p, refs, p.ObjectIndex, func(i int) (string, error) {
+		name := p.Objects[i].Name
+		return fmt.Sprintf("object %d %q", i, name), p.RemoveObject(i)
+	})
+}
+
+// runRemovePart deletes parts with their faces and prunes what they leave
+// unused.
+func runRemovePart(args []string) error {
+	fs := flag.NewFlagSet("remove-part", flag.ExitOnError)
+	var refs objectNames
+	fs.Var(&refs, "part", "Part to remove, by name or index (repeatable)")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools remove-part [options] -part name <file.pdo>")
+		fmt.Println("Removes parts and their faces, prunes unused materials, vertices and edges, and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+	if len(refs) == 0 {
+		fs.Usage()
+		return fmt.Errorf("no part to remove")
+	}
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	return removeEach(fs, edit, p, refs, p.PartIndex, func(i int) (string, error) {
+		name := p.Parts[i].Name
+		return fmt.Sprintf("part %d %q", i, name), p.RemovePart(i)
+	})
+}
+
+// removeEach resolves all refs before removing anything, so that indices
+// refer to the file as loaded, then removes, prunes and saves.
+func removeEach(fs *flag.FlagSet, edit *editFlags, p *pdo.PDO, refs []string, index func(string) (int, error), remove func(int) (string, error)) error {
+	var indices []int
+	for _, ref := range refs {
+		i, err := index(ref)
+		if err != nil {
+			return err
+		}
+		indices = append(indices, i)
+	}
+	// Remove from the back, earlier indices stay valid.
+	slices.Sort(indices)
+	indices = slices.Compact(indices)
+	for _, i := range slices.Backward(indices) {
+		what, err := remove(i)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", what)
+	}
+	n := p.Prune()
+	fmt.Printf("Pruned %d materials, %d vertices, %d edges\n", n.Materials, n.Vertices, n.Edges)
+	return edit.save(fs, p)
+}
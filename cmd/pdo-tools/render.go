@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pdo-tools/pkg/export"
+)
+
+func init() {
+	commands["render-pages"] = runRenderPages
+}
+
+// runRenderPages writes a PNG image of every page, or one contact sheet.
+func runRenderPages(args []string) error {
+	fs := flag.NewFlagSet("render-pages", flag.ExitOnError)
+	output := fs.String("output", "", "Output file prefix (default: the input name)")
+	dpi := fs.Float64("dpi", export.DefaultRenderDPI, "Resolution in dots per inch")
+	textures := fs.Bool("textures", true, "Draw face textures and material colors")
+	faceFill := fs.String("face-fill", "auto", "Paint faces with textures or material colors (auto, texture, color)")
+	contactSheet := fs.Bool("contact-sheet", false, "Write all pages into one <prefix>_pages.png")
+	columns := fs.Int("columns", 4, "Pages per row of the contact sheet")
+	guideFlags := addGuideFlags(fs)
+	outlineOffset := fs.Float64("outline-offset", 0, "Draw an outline this many mm around each part")
+	poster := fs.Bool("poster", false, "Split parts larger than a page across several pages")
+	posterOverlap := fs.Float64("poster-overlap", export.DefaultPosterOverlap, "Width in mm of the glue strip shared by poster tiles")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools render-pages [options] <file.pdo>")
+		fmt.Println("Renders each page to <prefix>_pN.png.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	if *dpi <= 0 || *dpi > 600 {
+		return fmt.Errorf("resolution %g out of range 1-600 dpi", *dpi)
+	}
+	if *outlineOffset < 0 {
+		return fmt.Errorf("invalid outline offset %g", *outlineOffset)
+	}
+	if *posterOverlap <= 0 {
+		return fmt.Errorf("invalid poster overlap %g", *posterOverlap)
+	}
+
+	guides, err := guideFlags.guides()
+	if err != nil {
+		return err
+	}
+	fill, err := export.ParseFaceFill(*faceFill)
+	if err != nil {
+		return err
+	}
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	input := fs.Arg(0)
+	parser, err := parseInput(input, opts)
+	if err != nil {
+		return err
+	}
+	if !parser.PDO.HasUnfold() {
+		return fmt.Errorf("%s: %w, unfold it in Pepakura Designer and save it again", input, export.ErrNoUnfold)
+	}
+
+	prefix := *output
+	if prefix == "" {
+		prefix = strings.TrimSuffix(input, filepath.Ext(input))
+	}
+	pages := export.RenderPages(parser.PDO, *dpi, export.Options{Logger: logger, Textures: *textures, FaceFill: fill, Guides: guides, OutlineOffset: *outlineOffset,
+		Poster: export.Poster{Enabled: *poster, Overlap: *posterOverlap}})
+	if len(pages) == 0 {
+		logger.Warn("no pages to render")
+		return nil
+	}
+
+	if *contactSheet {
+		return writePNG(prefix+"_pages.png", export.ContactSheet(pages, *columns, 8))
+	}
+	for i, img := range pages {
+		if err := writePNG(fmt.Sprintf("%s_p%d.png", prefix, i+1), img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/service"
+)
+
+func init() {
+	commands["serve"] = runServe
+}
+
+// runServe runs the conversion service until interrupted.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	workers := fs.Int("workers", 0, "Conversions run at once (default the number of CPUs)")
+	queue := fs.Int("queue", service.DefaultQueueSize, "Jobs that can wait for a worker")
+	timeout := fs.Duration("timeout", service.DefaultTimeout, "Time limit of a single job")
+	maxUpload := fs.Int64("max-upload", service.DefaultMaxUpload, "Largest accepted upload in bytes")
+	retention := fs.Duration("retention", service.DefaultRetention, "How long finished jobs are kept")
+	maxResults := fs.Int("max-results", service.DefaultMaxResults, "Finished jobs kept at most")
+	maxResultBytes := fs.Int64("max-result-bytes", service.DefaultMaxResultBytes, "Total size of the results kept at most in bytes")
+	maxAlloc := fs.Int64("max-alloc", pdo.DefaultLimits.MaxAlloc, "Largest allocation for parsing an upload in bytes")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools serve [options]")
+		fmt.Println("Runs an HTTP service converting uploaded PDO files in a job queue.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	logger := common.logger()
+	parseOpts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	parseOpts.Limits = pdo.DefaultLimits
+	parseOpts.Limits.MaxAlloc = *maxAlloc
+	srv := service.New(service.Config{
+		Workers:        *workers,
+		QueueSize:      *queue,
+		Timeout:        *timeout,
+		MaxUpload:      *maxUpload,
+		Retention:      *retention,
+		MaxResults:     *maxResults,
+		MaxResultBytes: *maxResultBytes,
+		Parse:          parseOpts,
+		Logger:         logger,
+	})
+	defer srv.Close()
+
+	httpSrv := &http.Server{Addr: *addr, Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpSrv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("serving", "addr", *addr)
+	if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["settings"] = runSettings
+}
+
+// settingValues collects repeated -set name=value flags.
+type settingValues []string
+
+func (s *settingValues) String() string { return strings.Join(*s, ",") }
+
+func (s *settingValues) Set(v string) error {
+	if !strings.Contains(v, "=") {
+		return fmt.Errorf("want name=value, got %q", v)
+	}
+	*s = append(*s, v)
+	return nil
+}
+
+// runSettings prints the print settings, or changes them and writes a new PDO.
+func runSettings(args []string) error {
+	fs := flag.NewFlagSet("settings", flag.ExitOnError)
+	var sets settingValues
+	fs.Var(&sets, "set", "Change a setting, name=value (repeatable)")
+	keepLayout := fs.Bool("keep-layout", false, "Leave the parts where they are when the page size or margins change")
+	rotate := fs.String("rotate", "none", "Turn parts when re-flowing them (none, 90, free)")
+	edit := addEditFlags(fs)
+	fs.StringVar(edit.output, "o", "", "Short for -output")
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools settings [options] <file.pdo> [-set name=value ...]")
+		fmt.Println("Prints the print settings, or changes them and writes a new PDO file.")
+		fmt.Printf("Settings: %s\n", strings.Join(pdo.SettingNames(), ", "))
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	rotation, err := parseRotation(*rotate)
+	if err != nil {
+		return err
+	}
+	logger := edit.logger()
+	p, err := edit.load(fs, logger)
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		for _, name := range pdo.SettingNames() {
+			v, _ := p.Settings.Get(name)
+			fmt.Printf("%-26s %s\n", name, v)
+		}
+		return nil
+	}
+
+	before := p.PageDims()
+	for _, kv := range sets {
+		name, value, _ := strings.Cut(kv, "=")
+		old, err := p.Settings.Get(name)
+		if err != nil {
+			return err
+		}
+		if err := p.Settings.Set(name, value); err != nil {
+			return err
+		}
+		v, _ := p.Settings.Get(name)
+		fmt.Printf("%s: %s -> %s\n", strings.TrimSpace(name), old, v)
+	}
+	if p.PageDims() != before && !*keepLayout {
+		fmt.Printf("Re-flowed parts onto %d pages\n", p.Relayout(pdo.LayoutOptions{Rotate: rotation}))
+	}
+	if p.Header.Version == pdo.PDO_V4 && (p.Settings.AuthorName != "" || p.Settings.Comment != "") {
+		logger.Warn("version 4 files have no author name and comment, they are not saved")
+	}
+	return edit.save(fs, p)
+}
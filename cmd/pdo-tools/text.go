@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["extract-text"] = runExtractText
+	commands["translate-text"] = runTranslateText
+}
+
+// textEntry is a text block as listed by extract-text. Pages are numbered
+// by their column and row in the layout grid, from 1.
+type textEntry struct {
+	Index      int      `json:"index"`
+	PageColumn int      `json:"page_column"`
+	PageRow    int      `json:"page_row"`
+	X          float64  `json:"x_mm"`
+	Y          float64  `json:"y_mm"`
+	Width      float64  `json:"width_mm"`
+	Height     float64  `json:"height_mm"`
+	Font       string   `json:"font"`
+	FontSize   int32    `json:"font_size"`
+	Color      string   `json:"color"`
+	Lines      []string `json:"lines"`
+}
+
+// textEntries lists the text blocks of a PDO with their place on the pages.
+func textEntries(p *pdo.PDO) []textEntry {
+	dims := p.PageDims()
+	entries := make([]textEntry, len(p.TextBlocks))
+	for i, tb := range p.TextBlocks {
+		bb := tb.BoundingBox
+		c := tb.Color
+		col, row := gridPage(dims, bb)
+		entries[i] = textEntry{
+			Index:      i,
+			PageColumn: col,
+			PageRow:    row,
+			X:          round3(bb.Left),
+			Y:          round3(bb.Top),
+			Width:      round3(bb.Width),
+			Height:     round3(bb.Height),
+			Font:       tb.FontName,
+			FontSize:   tb.FontSize,
+			Color:      fmt.Sprintf("#%02x%02x%02x", c&0xFF, c>>8&0xFF, c>>16&0xFF),
+			Lines:      tb.Lines,
+		}
+		if entries[i].Lines == nil {
+			entries[i].Lines = []string{}
+		}
+	}
+	return entries
+}
+
+// gridPage returns the column and row from 1 of the page a box starts on.
+func gridPage(dims pdo.PageDims, bb pdo.Rect) (col, row int) {
+	return int(math.Floor(bb.Left/dims.ClippedWidth)) + 1, int(math.Floor(bb.Top/dims.ClippedHeight)) + 1
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1e3) / 1e3
+}
+
+// runExtractText dumps the text blocks of a PDO, for reading or translating
+// the instructions of a template without Pepakura.
+func runExtractText(args []string) error {
+	fs := flag.NewFlagSet("extract-text", flag.ExitOnError)
+	format := fs.String("format", "text", "Output format (text, json, markdown)")
+	output := fs.String("output", "", "Write the text to this file instead of stdout")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools extract-text [options] <file.pdo>")
+		fmt.Println("Lists every text block with its page, position, font and color.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	write, ok := map[string]func(io.Writer, []textEntry) error{
+		"text":     writeTextPlain,
+		"json":     writeTextJSON,
+		"markdown": writeTextMarkdown,
+	}[*format]
+	if !ok {
+		return fmt.Errorf("unknown text format %q", *format)
+	}
+
+	opts, err := common.parseOptions(common.logger())
+	if err != nil {
+		return err
+	}
+	parser, err := parseInput(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return write(w, textEntries(parser.PDO))
+}
+
+// textPlace describes where a text block is and how it looks.
+func textPlace(e textEntry) string {
+	return fmt.Sprintf("page column %d, row %d at %g, %g mm; %s %d pt, %s",
+		e.PageColumn, e.PageRow, e.X, e.Y, e.Font, e.FontSize, e.Color)
+}
+
+func writeTextPlain(w io.Writer, entries []textEntry) error {
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "[%d] %s\n", e.Index, textPlace(e))
+		for _, line := range e.Lines {
+			fmt.Fprintln(w, line)
+		}
+	}
+	return nil
+}
+
+func writeTextJSON(w io.Writer, entries []textEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+func writeTextMarkdown(w io.Writer, entries []textEntry) error {
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "## Text block %d\n\n*%s*\n\n", e.Index, textPlace(e))
+		// Hard line breaks keep the lines of the block apart.
+		fmt.Fprintln(w, strings.Join(e.Lines, "  \n"))
+	}
+	return nil
+}
+
+// runTranslateText replaces text block lines with those of an edited
+// extract-text JSON file. Blocks missing from the file keep their text.
+func runTranslateText(args []string) error {
+	fs := flag.NewFlagSet("translate-text", flag.ExitOnError)
+	translations := fs.String("apply-translations", "", "JSON file in the format of extract-text -format json")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools translate-text -apply-translations <text.json> [options] <file.pdo>")
+		fmt.Println("Replaces the lines of text blocks and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	parseInterspersed(fs, args)
+
+	if *translations == "" {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	data, err := os.ReadFile(*translations)
+	if err != nil {
+		return err
+	}
+	var entries []textEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to read %s: %w", *translations, err)
+	}
+
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	changed := 0
+	for _, e := range entries {
+		if e.Index >= 0 && e.Index < len(p.TextBlocks) && slices.Equal(p.TextBlocks[e.Index].Lines, e.Lines) {
+			continue
+		}
+		if err := p.SetText(e.Index, e.Lines); err != nil {
+			return err
+		}
+		changed++
+	}
+	fmt.Printf("Replaced the text of %d of %d blocks\n", changed, len(p.TextBlocks))
+	return edit.save(fs, p)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestExtractText(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{Pages: 2})
+	dims := p.PageDims()
+	p.TextBlocks = append(p.TextBlocks, pdo.TextBlock{
+		BoundingBox: pdo.Rect{Left: dims.ClippedWidth + 10, Top: 20, Width: 50, Height: 10},
+		Color:       0x0000FF,
+		FontSize:    9,
+		FontName:    "Arial",
+		Lines:       []string{"Step 2", "Fold the lid."},
+	})
+	dir := t.TempDir()
+	input := writeModel(t, dir, "cube.pdo", p)
+
+	r := pdoTools(t, dir, "extract-text", "-format", "json", input)
+	if r.code != 0 {
+		t.Fatalf("exit code %d: %s", r.code, r.stderr)
+	}
+	var entries []textEntry
+	if err := json.Unmarshal([]byte(r.stdout), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d text blocks, want 2", len(entries))
+	}
+	if e := entries[0]; e.PageColumn != 1 || e.PageRow != 1 || !slices.Equal(e.Lines, []string{"Glue the flaps inside."}) {
+		t.Errorf("first block %+v", e)
+	}
+	want := textEntry{Index: 1, PageColumn: 2, PageRow: 1, X: round3(dims.ClippedWidth + 10), Y: 20, Width: 50, Height: 10,
+		Font: "Arial", FontSize: 9, Color: "#ff0000", Lines: []string{"Step 2", "Fold the lid."}}
+	if !reflect.DeepEqual(entries[1], want) {
+		t.Errorf("second block %+v, want %+v", entries[1], want)
+	}
+
+	r = pdoTools(t, dir, "extract-text", input)
+	if !strings.HasPrefix(r.stdout, "[0] page column 1, row 1") || !strings.Contains(r.stdout, "\n\n[1] page column 2, row 1") ||
+		!strings.Contains(r.stdout, "9 pt, #ff0000\nStep 2\nFold the lid.\n") {
+		t.Errorf("text output:\n%s", r.stdout)
+	}
+
+	r = pdoTools(t, dir, "extract-text", "-format", "markdown", "-output", "text.md", input)
+	md := readFile(t, filepath.Join(dir, "text.md"))
+	if r.stdout != "" || !strings.Contains(md, "## Text block 1\n\n*page column 2") || !strings.Contains(md, "Step 2  \nFold the lid.\n") {
+		t.Errorf("markdown output:\n%s", md)
+	}
+
+	if r = pdoTools(t, dir, "extract-text", "-format", "yaml", input); r.code == 0 || !strings.Contains(r.stderr, `unknown text format "yaml"`) {
+		t.Errorf("unknown format: exit code %d, %s", r.code, r.stderr)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pdo-tools/pkg/export"
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["thumbnail"] = runThumbnail
+	commands["extract-thumbnail"] = runExtractThumbnail
+}
+
+// runThumbnail embeds a preview image into a PDO, or removes it.
+func runThumbnail(args []string) error {
+	fs := flag.NewFlagSet("thumbnail", flag.ExitOnError)
+	size := fs.Int("size", export.DefaultThumbnailSize, "Length in pixels of the longer side")
+	remove := fs.Bool("remove", false, "Remove the preview instead")
+	edit := addEditFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools thumbnail [options] <file.pdo>")
+		fmt.Println("Embeds a preview of the first page and writes a new PDO file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *size <= 0 || *size > 4096 {
+		return fmt.Errorf("thumbnail size %d out of range 1-4096", *size)
+	}
+	p, err := edit.load(fs, edit.logger())
+	if err != nil {
+		return err
+	}
+	if *remove {
+		if p.Thumbnail == nil {
+			fmt.Println("File has no preview")
+		}
+		p.Thumbnail = nil
+	} else if err := embedThumbnail(p, *size); err != nil {
+		return err
+	}
+	return edit.save(fs, p)
+}
+
+// embedThumbnail stores a PNG preview of the first page in the PDO.
+func embedThumbnail(p *pdo.PDO, size int) error {
+	img := export.Thumbnail(p, size, export.Options{Textures: true})
+	if img == nil {
+		return errors.New("no pages to preview")
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("failed to encode preview: %w", err)
+	}
+	p.Thumbnail = buf.Bytes()
+	fmt.Printf("Embedded %dx%d preview\n", img.Bounds().Dx(), img.Bounds().Dy())
+	return nil
+}
+
+// runExtractThumbnail writes the embedded preview of a PDO to a PNG file.
+func runExtractThumbnail(args []string) error {
+	fs := flag.NewFlagSet("extract-thumbnail", flag.ExitOnError)
+	output := fs.String("output", "", "Output PNG file (default <input>_thumb.png)")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools extract-thumbnail [options] <file.pdo>")
+		fmt.Println("Writes the preview embedded by the thumbnail command to a PNG file.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	input := fs.Arg(0)
+	opts, err := common.parseOptions(common.logger())
+	if err != nil {
+		return err
+	}
+	parser, err := parseInput(input, opts)
+	if err != nil {
+		return err
+	}
+	if parser.PDO.Thumbnail == nil {
+		return fmt.Errorf("%s has no embedded preview", input)
+	}
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(input, filepath.Ext(input)) + "_thumb.png"
+	}
+	if err := os.WriteFile(out, parser.PDO.Thumbnail, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	fmt.Printf("Wrote %s\n", out)
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func init() {
+	commands["validate"] = runValidate
+}
+
+// runValidate checks a PDO file for problems and fails when it finds errors.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Print the issues as JSON")
+	common := addCommonFlags(fs)
+	fs.Usage = func() {
+		fmt.Println("Usage: pdo-tools validate [options] <file.pdo>")
+		fmt.Println("Checks the model and layout for problems that make it unbuildable.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+
+	logger := common.logger()
+	opts, err := common.parseOptions(logger)
+	if err != nil {
+		return err
+	}
+	parser, err := parseInput(fs.Arg(0), opts)
+	if err != nil {
+		return err
+	}
+
+	issues := pdo.Validate(parser.PDO)
+	errs := 0
+	for _, is := range issues {
+		if is.Severity == pdo.SeverityError {
+			errs++
+		}
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if issues == nil {
+			issues = []pdo.Issue{}
+		}
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		for _, is := range issues {
+			fmt.Printf("%s: %s: object %d: %s\n", is.Severity, is.Rule, is.Object, is.Message)
+		}
+		if len(issues) == 0 {
+			fmt.Printf("%s: no problems found\n", fs.Arg(0))
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%s: %d errors, %d warnings", fs.Arg(0), errs, len(issues)-errs)
+	}
+	return nil
+}
@@ -4,5 +4,6 @@ go 1.25.5
 
 require (
 	github.com/go-pdf/fpdf v0.9.0
+	golang.org/x/image v0.25.0
 	golang.org/x/text v0.32.0
 )
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// ExportAMF writes the assembled model as an AMF file in mm (or inches)
+// with one volume per material, colored like the material. Faces of textured
+// materials carry the texture color at their center, which is enough for
+// multi-color print previews.
+func ExportAMF(p *pdo.PDO, w io.Writer, opts Options) error {
+	unit := "millimeter"
+	switch opts.Units {
+	case UnitsInch:
+		unit = "inch"
+	case UnitsCM, UnitsPoint:
+		// AMF has no such unit, the file states its coordinates are mm.
+		opts.logger().Warn("AMF doesn't support the unit, writing mm", "units", opts.Units)
+		opts.Units = UnitsMM
+	}
+	num := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 32)
+	}
+	textures := map[int32]image.Image{}
+	for i := range p.Materials {
+		mat := &p.Materials[i]
+		if !mat.HasTexture {
+			continue
+		}
+		img, err := mat.Texture.GetImage()
+		if err != nil {
+			opts.logger().Warn("failed to decode texture", "material", mat.Name, "err", err)
+			continue
+		}
+		textures[int32(i)] = img
+	}
+
+	used := map[int32]bool{}
+	place := opts.placement(p, false)
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintf(w, "<amf unit=\"%s\" version=\"1.1\">\n", unit)
+	fmt.Fprintln(w, ` <metadata type="producer">pdo-tools</metadata>`)
+	writeXMLMetadata(w, " <metadata type=\"%s\">%s</metadata>\n", opts.metadata(p))
+	for i, obj := range p.Objects {
+		fmt.Fprintf(w, " <object id=\"%d\">\n", i)
+		fmt.Fprintf(w, "  <metadata type=\"name\">%s</metadata>\n", xmlEscape(obj.Name))
+		fmt.Fprintln(w, "  <mesh>\n   <vertices>")
+		for _, v := range place.vertices(obj) {
+			fmt.Fprintf(w, "    <vertex><coordinates><x>%s</x><y>%s</y><z>%s</z></coordinates></vertex>\n",
+				num(v.X), num(v.Y), num(v.Z))
+		}
+		fmt.Fprintln(w, "   </vertices>")
+
+		// Volumes in order of first use of their material.
+		var order []int32
+		byMaterial := map[int32][]*pdo.Face{}
+		for fi := range obj.Faces {
+			face := &obj.Faces[fi]
+			if len(face.Vertices) < 3 {
+				continue
+			}
+			m := face.MaterialIndex
+			if m < 0 || int(m) >= len(p.Materials) {
+				m = -1
+			}
+			if _, ok := byMaterial[m]; !ok {
+				order = append(order, m)
+				used[m] = true
+			}
+			byMaterial[m] = append(byMaterial[m], face)
+		}
+		for _, m := range order {
+			fmt.Fprintf(w, "   <volume materialid=\"%d\">\n", amfMaterialID(m))
+			tex := textures[m]
+			for _, face := range byMaterial[m] {
+				color := ""
+				if tex != nil {
+					r, g, b := faceTextureColor(tex, face)
+					color = fmt.Sprintf("<color><r>%s</r><g>%s</g><b>%s</b></color>", num(r), num(g), num(b))
+				}
+				loop := outwardLoop(obj, face)
+				for k := 1; k+1 < len(loop); k++ {
+					fmt.Fprintf(w, "    <triangle>%s<v1>%d</v1><v2>%d</v2><v3>%d</v3></triangle>\n", color, loop[0], loop[k], loop[k+1])
+				}
+			}
+			fmt.Fprintln(w, "   </volume>")
+		}
+		fmt.Fprintln(w, "  </mesh>\n </object>")
+	}
+
+	// Material IDs start at 1, 0 is reserved. Faces without a material use a light gray one.
+	for m := int32(-1); int(m) < len(p.Materials); m++ {
+		if !used[m] {
+			continue
+		}
+		r, g, b := float32(0.8), float32(0.8), float32(0.8)
+		if m >= 0 {
+			c := p.Materials[m].Color3D.Model
+			r, g, b = c.R, c.G, c.B
+		}
+		fmt.Fprintf(w, " <material id=\"%d\">\n  <metadata type=\"name\">%s</metadata>\n", amfMaterialID(m), xmlEscape(materialName(p, m)))
+		fmt.Fprintf(w, "  <color><r>%s</r><g>%s</g><b>%s</b></color>\n </material>\n", num(float64(r)), num(float64(g)), num(float64(b)))
+	}
+	_, err := fmt.Fprintln(w, "</amf>")
+	return err
+}
+
+// amfMaterialID maps material indexes to AMF IDs, -1 (no material) to 1.
+func amfMaterialID(m int32) int32 {
+	if m < 0 {
+		return 1
+	}
+	return m + 2
+}
+
+// faceTextureColor samples a texture at the center of a face's texture
+// coordinates, as components in [0, 1].
+func faceTextureColor(tex image.Image, face *pdo.Face) (r, g, b float64) {
+	var u, v float64
+	for _, fv := range face.Vertices {
+		u += fv.U
+		v += fv.V
+	}
+	n := float64(len(face.Vertices))
+	bounds := tex.Bounds()
+	x := wrap(int(u/n*float64(bounds.Dx())), bounds.Dx())
+	y := wrap(int(v/n*float64(bounds.Dy())), bounds.Dy())
+	cr, cg, cb, _ := tex.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+	return float64(cr) / 0xffff, float64(cg) / 0xffff, float64(cb) / 0xffff
+}
+
+type amfExporter struct{}
+
+func (amfExporter) Name() string         { return "amf" }
+func (amfExporter) Extensions() []string { return []string{".amf"} }
+func (amfExporter) Mesh() bool           { return true }
+func (amfExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ExportAMF(p, t.W, opts)
+}
+
+func init() {
+	Register(amfExporter{})
+}
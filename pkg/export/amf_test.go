@@ -0,0 +1,52 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestExportAMF(t *testing.T) {
+	p := texturedCone(t)
+	var buf bytes.Buffer
+	if err := ExportAMF(p, &buf, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	type color struct{ R, G, B float64 }
+	var doc struct {
+		Unit    string `xml:"unit,attr"`
+		Objects []struct {
+			Volumes []struct {
+				MaterialID int `xml:"materialid,attr"`
+				Triangles  []struct {
+					Color *color `xml:"color"`
+				} `xml:"triangle"`
+			} `xml:"mesh>volume"`
+		} `xml:"object"`
+		Materials []struct {
+			ID int `xml:"id,attr"`
+		} `xml:"material"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Unit != "millimeter" || len(doc.Objects) != len(p.Objects) || len(doc.Materials) != 1 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+
+	wantTriangles := 0
+	for _, face := range p.Objects[0].Faces {
+		wantTriangles += len(face.Vertices) - 2
+	}
+	vol := doc.Objects[0].Volumes[0]
+	if vol.MaterialID != doc.Materials[0].ID || len(vol.Triangles) != wantTriangles {
+		t.Errorf("volume of material %d with %d triangles, want %d with %d", vol.MaterialID, len(vol.Triangles), doc.Materials[0].ID, wantTriangles)
+	}
+	// Every face shows one of the texture's colors.
+	for _, tri := range vol.Triangles {
+		if c := tri.Color; c == nil || (c.R != 0 && c.R != 1) || (c.G != 0 && c.G != 1) || (c.B != 0 && c.B != 1) {
+			t.Fatalf("triangle color %+v", tri.Color)
+		}
+	}
+}
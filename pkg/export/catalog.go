@@ -0,0 +1,191 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image/png"
+	"io"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// CatalogEntry describes one model in a catalog.
+type CatalogEntry struct {
+	Name   string
+	Author string
+	// Scale is the unfold scale, mm per model unit.
+	Scale float64
+	// Pages is the number of printed pages.
+	Pages      int
+	Difficulty pdo.Difficulty
+	// Thumbnail is a PNG preview, nil when the model has no pages.
+	Thumbnail []byte
+}
+
+// NewCatalogEntry describes a model for a catalog. The preview is the one
+// embedded in the file or else the first page rendered size pixels long.
+func NewCatalogEntry(name string, p *pdo.PDO, size int) CatalogEntry {
+	e := CatalogEntry{
+		Name:       name,
+		Author:     p.Settings.AuthorName,
+		Scale:      p.Unfold.Scale,
+		Pages:      len(layoutPages(p, p.PageDims())),
+		Difficulty: p.Difficulty(),
+		Thumbnail:  p.Thumbnail,
+	}
+	if e.Thumbnail == nil {
+		if img := Thumbnail(p, size, Options{Textures: true}); img != nil {
+			var buf bytes.Buffer
+			if png.Encode(&buf, img) == nil {
+				e.Thumbnail = buf.Bytes()
+			}
+		}
+	}
+	return e
+}
+
+// difficultyLine sums up the difficulty of an entry in one line.
+func (e CatalogEntry) difficultyLine() string {
+	d := e.Difficulty
+	return fmt.Sprintf("%s (%.1f): %d parts, %d flaps, %d folds", d.Level, d.Score, d.Parts, d.Flaps, d.Folds)
+}
+
+var catalogHTML = template.Must(template.New("catalog").Funcs(template.FuncMap{
+	"thumbnail": func(data []byte) template.URL {
+		return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(data))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.models { display: grid; grid-template-columns: repeat(auto-fill, minmax(16em, 1fr)); gap: 1.5em; }
+.model { border: 1px solid #ccc; padding: 1em; }
+.model img { width: 100%; height: 12em; object-fit: contain; background: #f4f4f4; }
+.model h2 { font-size: 1.1em; margin: 0.5em 0; }
+.model dl { display: grid; grid-template-columns: auto 1fr; gap: 0.2em 0.8em; margin: 0; font-size: 0.9em; }
+.model dt { color: #666; }
+.model dd { margin: 0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="models">
+{{- range .Entries}}
+<div class="model">
+{{- if .Thumbnail}}
+<img src="{{thumbnail .Thumbnail}}" alt="{{.Name}}">
+{{- end}}
+<h2>{{.Name}}</h2>
+<dl>
+{{- if .Author}}
+<dt>Author</dt><dd>{{.Author}}</dd>
+{{- end}}
+<dt>Scale</dt><dd>{{printf "%g" .Scale}}</dd>
+<dt>Pages</dt><dd>{{.Pages}}</dd>
+<dt>Difficulty</dt><dd>{{.DifficultyLine}}</dd>
+</dl>
+</div>
+{{- end}}
+</div>
+</body>
+</html>
+`))
+
+// WriteCatalogHTML writes a catalog of models as one HTML page with the
+// previews embedded.
+func WriteCatalogHTML(w io.Writer, title string, entries []CatalogEntry) error {
+	type entry struct {
+		CatalogEntry
+		DifficultyLine string
+	}
+	data := struct {
+		Title   string
+		Entries []entry
+	}{Title: title}
+	for _, e := range entries {
+		data.Entries = append(data.Entries, entry{e, e.difficultyLine()})
+	}
+	return catalogHTML.Execute(w, data)
+}
+
+// Catalog PDF layout in mm and points, cells of a grid on A4 pages.
+const (
+	catalogWidth   = 210
+	catalogHeight  = 297
+	catalogMargin  = 15
+	catalogColumns = 2
+	catalogRows    = 3
+	catalogPreview = 50  // Height of the preview area of a cell
+	catalogLine    = 4.5 // Distance between text lines
+	catalogText    = 9   // Font size of the text lines
+	catalogTitle   = 16  // Font size of the catalog title
+	catalogHeader  = 10  // Room for the title on the first page
+)
+
+// WriteCatalogPDF writes a catalog of models as a PDF, six to an A4 page,
+// drawn with opts.PDFBackend.
+func WriteCatalogPDF(w io.Writer, title string, entries []CatalogEntry, opts Options) error {
+	pdf := opts.pdfBackend().NewDocument(catalogWidth, catalogHeight)
+	fonts := newPDFFonts(pdf, false)
+	if title != "" {
+		pdf.SetTitle(title)
+	}
+	cellW := float64(catalogWidth-2*catalogMargin) / catalogColumns
+	cellH := float64(catalogHeight-2*catalogMargin-catalogHeader) / catalogRows
+	perPage := catalogColumns * catalogRows
+
+	pdf.AddPage()
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Text(catalogMargin, catalogMargin+catalogTitle*ptToMM, fonts.use("Arial", catalogTitle, title))
+	for i, e := range entries {
+		if i > 0 && i%perPage == 0 {
+			pdf.AddPage()
+		}
+		col, row := i%catalogColumns, i%perPage/catalogColumns
+		x := catalogMargin + float64(col)*cellW
+		y := catalogMargin + catalogHeader + float64(row)*cellH
+
+		pdf.SetDrawColor(204, 204, 204)
+		pdf.SetLineWidth(0.2)
+		pdf.SetDashPattern(nil, 0)
+		pdf.Rect(x+1, y+1, cellW-2, cellH-2, "D")
+		drawCatalogPreview(pdf, fmt.Sprintf("preview%d", i), e.Thumbnail, x+4, y+4, cellW-8, catalogPreview)
+
+		pdf.SetTextColor(0, 0, 0)
+		ty := y + 4 + catalogPreview + 6
+		pdf.Text(x+4, ty, fonts.use("Arial", catalogText+2, e.Name))
+		lines := []string{
+			fmt.Sprintf("Scale %g, %d pages", e.Scale, e.Pages),
+			"Difficulty " + e.difficultyLine(),
+		}
+		if e.Author != "" {
+			lines = append([]string{"By " + e.Author}, lines...)
+		}
+		for _, line := range lines {
+			ty += catalogLine
+			pdf.Text(x+4, ty, fonts.use("Arial", catalogText, line))
+		}
+	}
+	return pdf.Output(w)
+}
+
+// drawCatalogPreview draws a PNG preview as large as fits in a box,
+// centered. Previews that don't decode are left out.
+func drawCatalogPreview(pdf PDFDocument, name string, data []byte, x, y, w, h float64) {
+	if data == nil {
+		return
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return
+	}
+	scale := min(w/float64(cfg.Width), h/float64(cfg.Height))
+	iw, ih := float64(cfg.Width)*scale, float64(cfg.Height)*scale
+	pdf.RegisterImage(name, "PNG", data)
+	pdf.DrawImage(name, PDFMatrix{A: iw, D: ih, E: x + (w-iw)/2, F: y + (h-ih)/2})
+}
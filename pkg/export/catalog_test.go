@@ -0,0 +1,46 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestCatalog(t *testing.T) {
+	cone, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cube := pdotest.Cube(pdotest.Options{Pages: 2})
+	cube.Thumbnail = nil
+	entries := []CatalogEntry{NewCatalogEntry("cone", cone, 64), NewCatalogEntry("<cube>", cube, 64)}
+	if e := entries[1]; e.Pages != 2 || e.Thumbnail == nil || e.Difficulty.Parts != 2 {
+		t.Errorf("cube entry: %d pages, %d parts, thumbnail %t", e.Pages, e.Difficulty.Parts, e.Thumbnail != nil)
+	}
+
+	var html bytes.Buffer
+	if err := WriteCatalogHTML(&html, "Models", entries); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<h2>cone</h2>", "<h2>&lt;cube&gt;</h2>", `src="data:image/png;base64,`} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("HTML catalog lacks %s", want)
+		}
+	}
+
+	// Seven entries fill a page and start another.
+	for len(entries) < 7 {
+		entries = append(entries, entries[0])
+	}
+	counter := &countingBackend{}
+	var pdf bytes.Buffer
+	if err := WriteCatalogPDF(&pdf, "Models", entries, Options{PDFBackend: counter}); err != nil {
+		t.Fatal(err)
+	}
+	if counter.pages != 2 || counter.images != 7 {
+		t.Errorf("PDF catalog has %d pages and %d images, want 2 and 7", counter.pages, counter.images)
+	}
+}
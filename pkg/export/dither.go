@@ -0,0 +1,140 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// DitherMode selects how textures are reduced to a few levels per channel.
+type DitherMode int
+
+const (
+	// DitherOff keeps textures as they are.
+	DitherOff DitherMode = iota
+	// DitherOrdered thresholds against an 8x8 Bayer matrix, a regular
+	// pattern that survives toner spread well.
+	DitherOrdered
+	// DitherFloydSteinberg diffuses the quantization error to neighbouring
+	// pixels, which keeps more detail.
+	DitherFloydSteinberg
+)
+
+// ParseDitherMode converts a dither mode name ("none", "ordered",
+// "floyd-steinberg") into a DitherMode.
+func ParseDitherMode(s string) (DitherMode, error) {
+	switch s {
+	case "none", "":
+		return DitherOff, nil
+	case "ordered", "bayer":
+		return DitherOrdered, nil
+	case "floyd-steinberg", "fs":
+		return DitherFloydSteinberg, nil
+	}
+	return DitherOff, fmt.Errorf("unknown dither mode %q", s)
+}
+
+// Dither prepares textures for printers with poor screening, e.g. laser
+// printers with monochrome output.
+type Dither struct {
+	Mode DitherMode
+	// Levels is the number of levels per channel, 2 (ink or no ink) when 0.
+	Levels int
+	// Gray converts textures to grayscale first.
+	Gray bool
+}
+
+// prepare converts and dithers a texture image. It returns the image
+// unchanged when there is nothing to do.
+func (d Dither) prepare(img image.Image) image.Image {
+	return d.reduce(d.gray(img))
+}
+
+// reduce dithers an image without changing its color model.
+func (d Dither) reduce(img image.Image) image.Image {
+	if d.Mode == DitherOff {
+		return img
+	}
+	switch img := img.(type) {
+	case *image.Gray:
+		d.pixels(img.Pix, img.Stride, img.Rect.Dx(), img.Rect.Dy(), 1, 1)
+	case *image.CMYK:
+		d.pixels(img.Pix, img.Stride, img.Rect.Dx(), img.Rect.Dy(), 4, 4)
+	default:
+		rgba := image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		d.pixels(rgba.Pix, rgba.Stride, rgba.Rect.Dx(), rgba.Rect.Dy(), 4, 3) // Alpha stays
+		return rgba
+	}
+	return img
+}
+
+// gray converts an image to grayscale if d.Gray is set.
+func (d Dither) gray(img image.Image) image.Image {
+	if !d.Gray {
+		return img
+	}
+	if _, ok := img.(*image.Gray); ok {
+		return img
+	}
+	gray := image.NewGray(img.Bounds())
+	draw.Draw(gray, gray.Bounds(), img, img.Bounds().Min, draw.Src)
+	return gray
+}
+
+// pixels dithers the first channels of each step bytes wide pixel in place.
+func (d Dither) pixels(pix []uint8, stride, w, h, step, channels int) {
+	levels := max(d.Levels, 2)
+	scale := float64(levels-1) / 255
+	quantize := func(v float64) (uint8, float64) {
+		q := min(max(int(v*scale+0.5), 0), levels-1)
+		out := float64(q) / scale
+		return uint8(out + 0.5), v - out
+	}
+
+	if d.Mode == DitherOrdered {
+		for y := range h {
+			for x := range w {
+				// Shift by a threshold in (-0.5, 0.5) of a level step.
+				t := (float64(bayer8[y%8][x%8])+0.5)/64 - 0.5
+				for c := range channels {
+					i := y*stride + x*step + c
+					pix[i], _ = quantize(float64(pix[i]) + t/scale)
+				}
+			}
+		}
+		return
+	}
+
+	// Floyd-Steinberg, with the error of the current and next row.
+	cur := make([]float64, (w+2)*channels)
+	next := make([]float64, (w+2)*channels)
+	for y := range h {
+		for x := range w {
+			for c := range channels {
+				i := y*stride + x*step + c
+				e := (x + 1) * channels
+				v, err := quantize(float64(pix[i]) + cur[e+c])
+				pix[i] = v
+				cur[e+channels+c] += err * 7 / 16
+				next[e-channels+c] += err * 3 / 16
+				next[e+c] += err * 5 / 16
+				next[e+channels+c] += err * 1 / 16
+			}
+		}
+		cur, next = next, cur
+		clear(next)
+	}
+}
+
+// bayer8 is the 8x8 ordered dither index matrix.
+var bayer8 = [8][8]uint8{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDither(t *testing.T) {
+	for _, mode := range []DitherMode{DitherOrdered, DitherFloydSteinberg} {
+		// A flat 25% gray comes out black and white with about a quarter white.
+		img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+		for i := range img.Pix {
+			img.Pix[i] = 64
+			if i%4 == 3 {
+				img.Pix[i] = 255
+			}
+		}
+		out, ok := Dither{Mode: mode, Gray: true}.prepare(img).(*image.Gray)
+		if !ok {
+			t.Fatalf("mode %d: not gray", mode)
+		}
+		white := 0
+		for _, v := range out.Pix {
+			switch v {
+			case 255:
+				white++
+			case 0:
+			default:
+				t.Fatalf("mode %d: level %d in monochrome output", mode, v)
+			}
+		}
+		if frac := float64(white) / float64(len(out.Pix)); frac < 0.2 || frac > 0.3 {
+			t.Errorf("mode %d: %.2f white, want about 0.25", mode, frac)
+		}
+	}
+
+	// Three levels per channel, alpha untouched.
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range 16 {
+		img.Set(i%4, i/4, color.RGBA{R: uint8(i * 16), G: 200, B: 30, A: 77})
+	}
+	out := Dither{Mode: DitherFloydSteinberg, Levels: 3}.prepare(img).(*image.RGBA)
+	for i, v := range out.Pix {
+		if i%4 == 3 {
+			if v != 77 {
+				t.Fatalf("alpha changed to %d", v)
+			}
+		} else if v != 0 && v != 128 && v != 255 {
+			t.Fatalf("level %d with 3 levels", v)
+		}
+	}
+}
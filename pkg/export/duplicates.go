@@ -0,0 +1,117 @@
+package export
+
+import (
+	"fmt"
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Duplicates selects how SVG output handles parts of the same shape, see
+// pdo.DuplicateParts.
+type Duplicates int
+
+const (
+	// DuplicatesKeep draws every part in full.
+	DuplicatesKeep Duplicates = iota
+	// DuplicatesUse draws the lines of the first part of a shape once and
+	// places the others as <use> instances of them.
+	DuplicatesUse
+	// DuplicatesCount leaves the copies out and labels the first part with
+	// the number of pieces to cut.
+	DuplicatesCount
+)
+
+// ParseDuplicates converts a duplicates mode name ("keep", "use", "count")
+// into Duplicates.
+func ParseDuplicates(s string) (Duplicates, error) {
+	switch s {
+	case "keep", "":
+		return DuplicatesKeep, nil
+	case "use":
+		return DuplicatesUse, nil
+	case "count":
+		return DuplicatesCount, nil
+	}
+	return DuplicatesKeep, fmt.Errorf("unknown duplicates mode %q", s)
+}
+
+// svgDuplicates tells the SVG writer what to do with each part.
+type svgDuplicates struct {
+	mode Duplicates
+	// copies holds the number of copies of every original part.
+	copies map[int]int
+	// of holds what every copy copies.
+	of map[int]svgCopy
+}
+
+type svgCopy struct {
+	original int
+	place    pdo.PartCopy
+}
+
+func newSVGDuplicates(p *pdo.PDO, mode Duplicates) *svgDuplicates {
+	d := &svgDuplicates{mode: mode, copies: map[int]int{}, of: map[int]svgCopy{}}
+	for _, g := range p.DuplicateParts() {
+		d.copies[g.Original] = len(g.Copies)
+		for _, c := range g.Copies {
+			d.of[c.Part] = svgCopy{g.Original, c}
+		}
+	}
+	return d
+}
+
+// skipped reports whether part i is left out of the output.
+func (d *svgDuplicates) skipped(i int) bool {
+	if d == nil || d.mode != DuplicatesCount {
+		return false
+	}
+	_, ok := d.of[i]
+	return ok
+}
+
+// writePart writes part i, the lines of originals in a group copies refer
+// to and copies as instances of it. It reports false for parts written as
+// usual.
+func (d *svgDuplicates) writePart(s *SVGWriter, p *pdo.PDO, part *pdo.Part, i int) bool {
+	if d == nil {
+		return false
+	}
+	c, isCopy := d.of[i]
+	n, isOriginal := d.copies[i]
+	switch {
+	case isCopy && d.mode == DuplicatesCount:
+		return true
+	case isCopy && d.mode == DuplicatesUse:
+		// Map the original's drawing onto this part.
+		ox, oy := s.partOrigin(p, &p.Parts[c.original])
+		cx, cy := s.partOrigin(p, part)
+		sin, cos := math.Sincos(c.place.Angle)
+		e := c.place.DX + cx - (ox*cos - oy*sin)
+		f := c.place.DY + cy - (ox*sin + oy*cos)
+		if s.partColoring == PartColorsFill {
+			s.writePartFill(p, part)
+		}
+		// The turn keeps full precision, rounding it moves far corners.
+		fmt.Fprintf(s.w, `<use xlink:href="#part-%d" transform="matrix(%.9g %.9g %.9g %.9g %s %s)" />`+"\n",
+			c.original, cos, sin, 0-sin, cos, s.num(e), s.num(f))
+		s.writeEdgeIDs(p, part)
+		return true
+	case isOriginal && d.mode == DuplicatesUse:
+		if s.partColoring == PartColorsFill {
+			s.writePartFill(p, part)
+		}
+		fmt.Fprintf(s.w, `<g id="part-%d">`+"\n", i)
+		s.writeLines(p, part, func(partLine) bool { return true })
+		fmt.Fprintln(s.w, `</g>`)
+		s.writeEdgeIDs(p, part)
+		return true
+	case isOriginal && d.mode == DuplicatesCount:
+		s.WritePart(p, part)
+		x, y := s.partOrigin(p, part)
+		fmt.Fprintf(s.w, `<text x="%s" y="%s" style="font-size:4px; font-family:sans-serif">Cut %d of this piece</text>`+"\n",
+			s.num(x), s.num(y-1), n+1)
+		return true
+	}
+	return false
+}
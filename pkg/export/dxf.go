@@ -0,0 +1,206 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// CutterSetting is the machine setting of one kind of line in cutter
+// formats.
+type CutterSetting struct {
+	// Tool is the tool or pen number, written as the DXF layer color
+	// (ACI 1-255) that cutter software maps to a tool. 0 keeps the default
+	// color of the line type.
+	Tool int
+	// Pressure is the blade force or depth in machine units, 0 for the
+	// machine's default. DXF has no place for it that cutter software
+	// reads, it is only noted in a comment on the layer for setting up
+	// the machine by hand.
+	Pressure int
+	// Passes is how often the line is cut, 1 when 0.
+	Passes int
+}
+
+func (c CutterSetting) passes() int {
+	return max(c.Passes, 1)
+}
+
+// Scoring sets up the cut and score passes of cutter formats, so one file
+// drives both. Score settings apply to mountain and valley folds.
+type Scoring struct {
+	Cut   CutterSetting
+	Score CutterSetting
+}
+
+// ParseCutterSetting reads a setting like "tool=2,pressure=10,passes=2"
+// into c, keeping the fields that aren't given.
+func ParseCutterSetting(s string, c *CutterSetting) error {
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		v, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || err != nil || v < 0 {
+			return fmt.Errorf("invalid cutter setting %q", field)
+		}
+		switch strings.TrimSpace(key) {
+		case "tool":
+			if v > 255 {
+				return fmt.Errorf("tool %d out of range 1-255", v)
+			}
+			c.Tool = v
+		case "pressure":
+			c.Pressure = v
+		case "passes":
+			c.Passes = v
+		default:
+			return fmt.Errorf("unknown cutter setting %q", key)
+		}
+	}
+	return nil
+}
+
+// dxfLayer is a DXF layer holding one line type.
+type dxfLayer struct {
+	name    string
+	color   int // Default ACI color
+	setting func(Scoring) CutterSetting
+}
+
+var dxfLayers = [...]dxfLayer{
+	lineCut:      {"CUT", 7, func(s Scoring) CutterSetting { return s.Cut }},
+	lineMountain: {"SCORE-MOUNTAIN", 5, func(s Scoring) CutterSetting { return s.Score }},
+	lineValley:   {"SCORE-VALLEY", 1, func(s Scoring) CutterSetting { return s.Score }},
+}
+
+// dxfMeasurement is the $MEASUREMENT code of a unit, 0 for imperial and 1
+// for metric drawings. R12 has no variable naming the unit itself, so
+// points, which are neither, get none.
+func dxfMeasurement(u Units) (int, bool) {
+	switch u {
+	case UnitsInch:
+		return 0, true
+	case UnitsPoint:
+		return 0, false
+	}
+	return 1, true
+}
+
+// ExportDXF writes the unfolded layout as an AutoCAD R12 DXF drawing for
+// cutting machines. Cut lines and the two kinds of folds are on layers of
+// their own, colored by the tool of opts.Scoring. Lines are repeated for
+// every pass. The pressure is noted in a comment on each layer, for people
+// setting up the machine: cutter software doesn't read it. The Y
+// axis points up as usual in DXF, down like in the PDO with opts.FlipY.
+func ExportDXF(p *pdo.PDO, w io.Writer, opts Options) error {
+	if err := checkUnfold(p); err != nil {
+		return err
+	}
+	p = opts.lines(p)
+	if len(p.Parts) == 0 {
+		opts.logger().Warn("no unfolded parts to export")
+	}
+	warnLayout(p, opts, false)
+	scale := opts.Units.perMM()
+	bottom := 0.0
+	for _, part := range p.Parts {
+		bottom = max(bottom, part.BoundingBox.Top+part.BoundingBox.Height)
+	}
+	y := func(v float64) float64 { return bottom - v }
+	if opts.FlipY {
+		y = func(v float64) float64 { return v }
+	}
+
+	bw := bufio.NewWriter(w)
+	pair := func(code int, value string) {
+		fmt.Fprintf(bw, "%d\n%s\n", code, value)
+	}
+	num := func(v float64) string {
+		return strconv.FormatFloat(math.Round(v*scale*1e4)/1e4, 'f', -1, 64)
+	}
+
+	pair(999, "pdo-tools")
+	pair(999, "Units: "+opts.Units.String())
+	for _, l := range opts.metadata(p).metadataLines() {
+		pair(999, l)
+	}
+	pair(0, "SECTION")
+	pair(2, "HEADER")
+	pair(9, "$ACADVER")
+	pair(1, "AC1009")
+	if m, ok := dxfMeasurement(opts.Units); ok {
+		pair(9, "$MEASUREMENT")
+		pair(70, strconv.Itoa(m))
+	}
+	pair(0, "ENDSEC")
+
+	pair(0, "SECTION")
+	pair(2, "TABLES")
+	pair(0, "TABLE")
+	pair(2, "LAYER")
+	pair(70, strconv.Itoa(len(dxfLayers)))
+	for _, l := range dxfLayers {
+		s := l.setting(opts.Scoring)
+		color := l.color
+		if s.Tool > 0 {
+			color = s.Tool
+		}
+		pair(999, fmt.Sprintf("%s: tool %d, pressure %d, passes %d", l.name, color, s.Pressure, s.passes()))
+		pair(0, "LAYER")
+		pair(2, l.name)
+		pair(70, "0")
+		pair(62, strconv.Itoa(color))
+		pair(6, "CONTINUOUS")
+	}
+	pair(0, "ENDTAB")
+	pair(0, "ENDSEC")
+
+	pair(0, "SECTION")
+	pair(2, "ENTITIES")
+	offset := opts.outlineOffset(p.Settings)
+	lookups := p.Lookups()
+	for part := range p.PartObjects() {
+		for line := range templateLines(p, lookups.Part(part), part, opts.FlapStyle, offset, opts.Perforation) {
+			t := drawnLineType(line.Type)
+			if t == lineInvisible {
+				continue
+			}
+			l := dxfLayers[t]
+			for range l.setting(opts.Scoring).passes() {
+				pair(0, "LINE")
+				pair(8, l.name)
+				pair(10, num(line.X1+part.BoundingBox.Left))
+				pair(20, num(y(line.Y1+part.BoundingBox.Top)))
+				pair(11, num(line.X2+part.BoundingBox.Left))
+				pair(21, num(y(line.Y2+part.BoundingBox.Top)))
+			}
+		}
+	}
+	pair(0, "ENDSEC")
+	pair(0, "EOF")
+	return bw.Flush()
+}
+
+type dxfExporter struct{}
+
+func (dxfExporter) Name() string         { return "dxf" }
+func (dxfExporter) Extensions() []string { return []string{".dxf"} }
+func (dxfExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ExportDXF(p, t.W, opts)
+}
+
+func init() {
+	Register(dxfExporter{})
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestExportDXF(t *testing.T) {
+	p := texturedCone(t)
+	count := func(opts Options) (cuts, scores int, out string) {
+		var buf bytes.Buffer
+		if err := ExportDXF(p, &buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		out = buf.String()
+		return strings.Count(out, "\n8\nCUT\n"), strings.Count(out, "\n8\nSCORE-"), out
+	}
+
+	cuts, scores, out := count(Options{})
+	if cuts == 0 || scores == 0 {
+		t.Fatalf("%d cut and %d score lines", cuts, scores)
+	}
+	if !strings.HasSuffix(out, "0\nEOF\n") || !strings.Contains(out, "\n2\nCUT\n70\n0\n62\n7\n") {
+		t.Errorf("unexpected DXF structure:\n%s", out[:min(len(out), 600)])
+	}
+
+	var opts Options
+	if err := ParseCutterSetting("tool=3, pressure=12, passes=2", &opts.Scoring.Score); err != nil {
+		t.Fatal(err)
+	}
+	cuts2, scores2, out := count(opts)
+	if cuts2 != cuts || scores2 != 2*scores {
+		t.Errorf("two score passes: %d cut and %d score lines, want %d and %d", cuts2, scores2, cuts, 2*scores)
+	}
+	if !strings.Contains(out, "\n2\nSCORE-VALLEY\n70\n0\n62\n3\n") || !strings.Contains(out, "SCORE-VALLEY: tool 3, pressure 12, passes 2") {
+		t.Error("score layer doesn't carry the tool and pressure")
+	}
+
+	// R12 has no $INSUNITS, only metric or imperial.
+	for units, want := range map[Units]string{UnitsDefault: "\n9\n$MEASUREMENT\n70\n1\n", UnitsInch: "\n9\n$MEASUREMENT\n70\n0\n", UnitsPoint: ""} {
+		_, _, out := count(Options{Units: units})
+		header := out[:strings.Index(out, "\n0\nENDSEC\n")+1]
+		if strings.Contains(header, "$INSUNITS") || !strings.Contains(header, want) || want == "" && strings.Contains(header, "$MEASUREMENT") {
+			t.Errorf("%s header:\n%s", units, header)
+		}
+	}
+
+	for _, bad := range []string{"tool=300", "speed=2", "passes=-1", "tool"} {
+		if err := ParseCutterSetting(bad, &CutterSetting{}); err == nil {
+			t.Errorf("ParseCutterSetting(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestExportDXF_FlipY(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{})
+	ys := func(opts Options) []float64 {
+		var buf bytes.Buffer
+		if err := ExportDXF(p, &buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		var out []float64
+		pairs := strings.Split(buf.String(), "\n")
+		for i := 0; i+1 < len(pairs); i += 2 {
+			if pairs[i] == "20" || pairs[i] == "21" {
+				v, err := strconv.ParseFloat(pairs[i+1], 64)
+				if err != nil {
+					t.Fatal(err)
+				}
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	up, down := ys(Options{}), ys(Options{FlipY: true})
+	if len(up) == 0 || len(up) != len(down) {
+		t.Fatalf("%d and %d Y coordinates", len(up), len(down))
+	}
+	bottom := p.Parts[0].BoundingBox.Top + p.Parts[0].BoundingBox.Height
+	for i := range up {
+		if math.Abs(up[i]+down[i]-bottom) > 1e-3 {
+			t.Fatalf("Y %g flips to %g, want %g", up[i], down[i], bottom-up[i])
+		}
+	}
+}
+
+func TestExportDXF_UnknownLineTypes(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{})
+	count := func() (cuts, scores int) {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := ExportDXF(p, &buf, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(buf.String(), "\n8\nCUT\n"), strings.Count(buf.String(), "\n8\nSCORE-")
+	}
+	cuts, scores := count()
+
+	// A fold of a type below cut is cut, one past invisible left out.
+	var folds []int
+	for i, l := range p.Parts[0].Lines {
+		if l.Type == lineMountain {
+			folds = append(folds, i)
+		}
+	}
+	p.Parts[0].Lines[folds[0]].Type = -1
+	p.Parts[0].Lines[folds[1]].Type = 9
+	if c, s := count(); c != cuts+1 || s != scores-2 {
+		t.Errorf("%d cut and %d score lines, want %d and %d", c, s, cuts+1, scores-2)
+	}
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Edge ID placement values of Settings.EdgeIDPlacement.
+const (
+	edgeIDOnFlap     = 0 // Outside the cut edge, centred on the flap when there is one
+	edgeIDInsideFace = 1 // Inside the face, along the cut edge
+)
+
+// defaultEdgeIDSize is the edge ID font size in mm when the settings have none.
+const defaultEdgeIDSize = 3.0
+
+// edgeLabel is an edge ID placed in part coordinates (relative to the part's
+// bounding box, like its vertices). X, Y is the label centre.
+type edgeLabel struct {
+	ID   int
+	X, Y float64
+}
+
+// edgeIDSize returns the edge ID font size in mm.
+func edgeIDSize(s pdo.Settings) float64 {
+	if s.EdgeIDFontSize > 0 {
+		return float64(s.EdgeIDFontSize) * ptToMM
+	}
+	return defaultEdgeIDSize
+}
+
+// segment is a drawn line of a part, in part coordinates.
+type segment struct {
+	x1, y1, x2, y2 float64
+}
+
+// placeEdgeLabels positions the edge IDs of a part's cut lines. Labels start at
+// the edge midpoint, offset into the face or onto the flap as the settings ask.
+// Candidates that overlap a placed label or sit too close to another line are
+// nudged along and away from the edge, then tried on the other side of it. If
+// no candidate is clear, the one with the most room wins.
+// lk is the lookup of the part's object, nil when it has none.
+func placeEdgeLabels(p *pdo.PDO, lk *pdo.Lookup, part *pdo.Part) []edgeLabel {
+	if p.Settings.ShowEdgeID != 1 || lk == nil {
+		return nil
+	}
+	obj := *lk.Object()
+	size := edgeIDSize(p.Settings)
+
+	// Collect the drawn lines first, labels must keep clear of all of them.
+	var segs []segment
+	type cutEdge struct {
+		seg  segment
+		line *pdo.Line
+		v1   *pdo.Face2DVertex
+		id   int
+	}
+	var cuts []cutEdge
+	for line := range part.VisibleLines() {
+		if line.Type >= 3 {
+			continue
+		}
+		v1, v2, _ := lk.ResolveLine(line)
+		if v1 == nil {
+			continue
+		}
+		seg := segment{v1.X, v1.Y, v2.X, v2.Y}
+		segs = append(segs, seg)
+		if line.Type == 0 {
+			if id := findEdgeID(lk, v1.IDVertex, v2.IDVertex); id > 0 {
+				cuts = append(cuts, cutEdge{seg, line, v1, id})
+			}
+		}
+	}
+
+	var labels []edgeLabel
+	for _, c := range cuts {
+		dx, dy := c.seg.x2-c.seg.x1, c.seg.y2-c.seg.y1
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		// Unit normal pointing into the face the line belongs to.
+		nx, ny := -dy/length, dx/length
+		cx, cy := faceCentroid(obj, c.line.FaceIndex)
+		if (cx-c.seg.x1)*nx+(cy-c.seg.y1)*ny < 0 {
+			nx, ny = -nx, -ny
+		}
+
+		offset := size * 0.8
+		sides := []float64{1, -1} // Preferred side first, the other side as a last resort
+		if p.Settings.EdgeIDPlacement == edgeIDOnFlap {
+			sides = []float64{-1, 1}
+			if c.v1.Flap != 0 && c.v1.FlapHeight > 0 {
+				offset = c.v1.FlapHeight / 2
+			}
+		}
+
+		best, bestRoom := edgeLabel{}, math.Inf(-1)
+	candidates:
+		for _, side := range sides {
+			for _, t := range []float64{0.5, 0.35, 0.65, 0.2, 0.8} {
+				for _, k := range []float64{1, 1.5, 0.6, 2} {
+					x := c.seg.x1 + dx*t + nx*side*offset*k
+					y := c.seg.y1 + dy*t + ny*side*offset*k
+					room := labelRoom(x, y, size, segs, labels)
+					if room > bestRoom {
+						best, bestRoom = edgeLabel{c.id, x, y}, room
+					}
+					if room >= size/2 {
+						break candidates
+					}
+				}
+			}
+		}
+		labels = append(labels, best)
+	}
+	return labels
+}
+
+// labelRoom returns the free space around a label centre: the distance to the
+// nearest line, or a negative value when it overlaps a placed label.
+func labelRoom(x, y, size float64, segs []segment, placed []edgeLabel) float64 {
+	for _, l := range placed {
+		if math.Abs(l.X-x) < size*1.2 && math.Abs(l.Y-y) < size {
+			return -1
+		}
+	}
+	room := math.Inf(1)
+	for _, s := range segs {
+		room = math.Min(room, pointSegmentDist(x, y, s))
+	}
+	return room
+}
+
+func pointSegmentDist(x, y float64, s segment) float64 {
+	dx, dy := s.x2-s.x1, s.y2-s.y1
+	t := 0.0
+	if l2 := dx*dx + dy*dy; l2 > 0 {
+		t = math.Max(0, math.Min(1, ((x-s.x1)*dx+(y-s.y1)*dy)/l2))
+	}
+	return math.Hypot(x-(s.x1+t*dx), y-(s.y1+t*dy))
+}
+
+// faceCentroid returns the average of a face's 2D vertices.
+func faceCentroid(obj pdo.Object, faceIdx int32) (float64, float64) {
+	if faceIdx < 0 || int(faceIdx) >= len(obj.Faces) || len(obj.Faces[faceIdx].Vertices) == 0 {
+		return 0, 0
+	}
+	var x, y float64
+	verts := obj.Faces[faceIdx].Vertices
+	for _, v := range verts {
+		x += v.X
+		y += v.Y
+	}
+	return x / float64(len(verts)), y / float64(len(verts))
+}
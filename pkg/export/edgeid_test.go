@@ -0,0 +1,43 @@
+package export
+
+import (
+	"math"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestPlaceEdgeLabels(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cylinder.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Settings.ShowEdgeID = 1
+	lookups := p.Lookups()
+
+	for _, placement := range []uint8{edgeIDOnFlap, edgeIDInsideFace} {
+		p.Settings.EdgeIDPlacement = placement
+		size := edgeIDSize(p.Settings)
+
+		total := 0
+		for i := range p.Parts {
+			labels := placeEdgeLabels(p, lookups.Part(&p.Parts[i]), &p.Parts[i])
+			total += len(labels)
+			for a := range labels {
+				for b := a + 1; b < len(labels); b++ {
+					if math.Abs(labels[a].X-labels[b].X) < size*1.2 && math.Abs(labels[a].Y-labels[b].Y) < size {
+						t.Errorf("placement %d, part %d: labels %d and %d overlap", placement, i, labels[a].ID, labels[b].ID)
+					}
+				}
+			}
+		}
+		if total == 0 {
+			t.Errorf("placement %d: no edge IDs placed", placement)
+		}
+	}
+
+	p.Settings.ShowEdgeID = 0
+	if labels := placeEdgeLabels(p, lookups.Part(&p.Parts[0]), &p.Parts[0]); labels != nil {
+		t.Errorf("got %d labels with edge IDs hidden", len(labels))
+	}
+}
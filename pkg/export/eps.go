@@ -0,0 +1,218 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// mmToPt converts millimetres to PostScript points.
+const mmToPt = 72 / 25.4
+
+// ExportEPS exports the template as Encapsulated PostScript, one file per page.
+// The first page is written to w. Further pages are written next to epsPath
+// as "<name>_p2.eps", "<name>_p3.eps", ...
+func ExportEPS(p *pdo.PDO, w io.Writer, epsPath string, opts Options) error {
+	if err := checkUnfold(p); err != nil {
+		return err
+	}
+	p = opts.lines(p)
+	dims := p.PageDims()
+	pages := layoutPages(p, dims)
+	if len(pages) == 0 {
+		opts.logger().Warn("no unfolded parts to export")
+		return nil
+	}
+	warnLayout(p, opts, true)
+	eps := newEPSWriter(p, dims, opts)
+
+	if err := eps.writePage(w, pages[0]); err != nil {
+		return err
+	}
+	if len(pages) > 1 && epsPath == "" {
+		opts.logger().Warn("no output path for additional EPS pages, only the first page was written", "pages", len(pages))
+		return nil
+	}
+
+	base := strings.TrimSuffix(epsPath, filepath.Ext(epsPath))
+	for i, page := range pages[1:] {
+		name := fmt.Sprintf("%s_p%d.eps", base, i+2)
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		err = eps.writePage(f, page)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// WriteEPSPage writes the page at grid position (px, py) as an EPS file.
+// The bounding box is fitted to the lines and text drawn on the page.
+func WriteEPSPage(p *pdo.PDO, w io.Writer, px, py int, opts Options) error {
+	dims := p.PageDims()
+	page := layoutPage{px: px, py: py, parts: getPartsOnPage(p, p.PartPages(dims), px, py), texts: getTextsOnPage(p, px, py, dims)}
+	return newEPSWriter(p, dims, opts).writePage(w, page)
+}
+
+// epsWriter holds what every page of an EPS export shares, worked out once.
+type epsWriter struct {
+	p       *pdo.PDO
+	dims    pdo.PageDims
+	lookups pdo.Lookups
+	opts    Options
+}
+
+func newEPSWriter(p *pdo.PDO, dims pdo.PageDims, opts Options) *epsWriter {
+	return &epsWriter{p: p, dims: dims, lookups: p.Lookups(), opts: opts.metadata(p)}
+}
+
+// epsFonts are the PostScript fonts standing in for the PDF core fonts,
+// re-encoded as Latin-1 under the name with epsFontSuffix.
+var epsFonts = map[string]string{"Helvetica": "Helvetica", "Times": "Times-Roman", "Courier": "Courier"}
+
+const epsFontSuffix = "-Latin1"
+
+func (e *epsWriter) writePage(w io.Writer, page layoutPage) error {
+	p, dims, opts := e.p, e.dims, e.opts
+	offX := float64(page.px)*dims.ClippedWidth - dims.MarginLeft
+	offY := float64(page.py)*dims.ClippedHeight - dims.MarginTop
+
+	var body strings.Builder
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	extend := func(x, y float64) {
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	// PostScript has its origin at the bottom left, in points.
+	toPS := func(x, y float64) (float64, float64) {
+		return (x - offX) * mmToPt, (dims.Height - (y - offY)) * mmToPt
+	}
+
+	for _, part := range page.parts {
+		for line := range templateLines(p, e.lookups.Part(part), part, opts.FlapStyle, opts.outlineOffset(p.Settings), opts.Perforation) {
+			if line.Type >= lineInvisible {
+				continue
+			}
+			x1, y1 := toPS(line.X1+part.BoundingBox.Left, line.Y1+part.BoundingBox.Top)
+			x2, y2 := toPS(line.X2+part.BoundingBox.Left, line.Y2+part.BoundingBox.Top)
+			extend(x1, y1)
+			extend(x2, y2)
+
+			style := "cut"
+			if line.Type == lineMountain {
+				style = "mountain"
+			} else if line.Type == lineValley {
+				style = "valley"
+			}
+			fmt.Fprintf(&body, "%s %.3f %.3f %.3f %.3f L\n", style, x1, y1, x2, y2)
+		}
+	}
+
+	fonts := map[string]bool{}
+	for _, tb := range page.texts {
+		font := epsFonts[pdfCoreFont(fontFamily(tb.FontName))] + epsFontSuffix
+		fonts[font] = true
+		r, g, b := textRGB(tb.Color)
+		fmt.Fprintf(&body, "/%s %.3f selectfont %.3f %.3f %.3f setrgbcolor\n",
+			font, float64(tb.FontSize), float64(r)/255, float64(g)/255, float64(b)/255)
+		// The stored box may be shorter than the lines, the bounding box
+		// takes in both.
+		extend(toPS(tb.BoundingBox.Left, tb.BoundingBox.Top))
+		extend(toPS(tb.BoundingBox.Left+tb.BoundingBox.Width, tb.BoundingBox.Top+tb.BoundingBox.Height))
+		for i, y := range textBaselines(tb) {
+			extend(toPS(tb.BoundingBox.Left+tb.BoundingBox.Width, y+textFontSize(tb)/4))
+			x, y := toPS(tb.BoundingBox.Left, y)
+			fmt.Fprintf(&body, "%.3f %.3f moveto (%s) show\n", x, y, e.psString(tb.Lines[i]))
+		}
+	}
+
+	if math.IsInf(minX, 1) {
+		// Empty page, use the paper size.
+		minX, minY = 0, 0
+		maxX, maxY = dims.Width*mmToPt, dims.Height*mmToPt
+	}
+
+	fmt.Fprintln(w, "%!PS-Adobe-3.0 EPSF-3.0")
+	fmt.Fprintln(w, "%%Creator: pdo-tools")
+	fmt.Fprintf(w, "%%%%Title: page %d,%d\n", page.px+1, page.py+1)
+	fmt.Fprintf(w, "%%%%BoundingBox: %d %d %d %d\n",
+		int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+	fmt.Fprintf(w, "%%%%HiResBoundingBox: %.3f %.3f %.3f %.3f\n", minX, minY, maxX, maxY)
+	fmt.Fprintln(w, "%%Pages: 1")
+	io.WriteString(w, "%%EndComments\n")
+	writeMetadataComments(w, "% ", opts)
+	fmt.Fprintln(w, "%%BeginProlog")
+	fmt.Fprintf(w, "/lw %.4f def\n", 0.1*mmToPt)
+	fmt.Fprintln(w, "/L { moveto lineto stroke } bind def")
+	fmt.Fprintln(w, "/cut { 0 0 0 setrgbcolor [] 0 setdash lw setlinewidth } bind def")
+	fmt.Fprintf(w, "/mountain { 0 0 1 setrgbcolor [%.3f %.3f] 0 setdash lw setlinewidth } bind def\n", mmToPt, mmToPt)
+	fmt.Fprintf(w, "/valley { 1 0 0 setrgbcolor [%.3f %.3f] 0 setdash lw setlinewidth } bind def\n", mmToPt, mmToPt)
+	if len(fonts) > 0 {
+		fmt.Fprintln(w, "/latin1 { findfont dup length dict begin { 1 index /FID ne { def } { pop pop } ifelse } forall /Encoding ISOLatin1Encoding def currentdict end definefont pop } bind def")
+		for _, font := range slices.Sorted(maps.Keys(fonts)) {
+			fmt.Fprintf(w, "/%s /%s latin1\n", font, strings.TrimSuffix(font, epsFontSuffix))
+		}
+	}
+	io.WriteString(w, "%%EndProlog\n")
+	fmt.Fprintln(w, "%%Page: 1 1")
+	fmt.Fprintln(w, "1 setlinecap 1 setlinejoin")
+	fmt.Fprint(w, body.String())
+	fmt.Fprintln(w, "showpage")
+	_, err := io.WriteString(w, "%%EOF\n")
+	return err
+}
+
+// psString escapes a line of text for a PostScript string in Latin-1.
+// Characters outside Latin-1 become question marks, with a warning.
+func (e *epsWriter) psString(s string) string {
+	var b strings.Builder
+	var missing []rune
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			missing = append(missing, r)
+			b.WriteByte('?')
+		}
+	}
+	if len(missing) > 0 {
+		e.opts.logger().Warn("font has no glyphs for text", "text", s, "missing", string(missing))
+	}
+	return b.String()
+}
+
+type epsExporter struct{}
+
+func (epsExporter) Name() string         { return "eps" }
+func (epsExporter) Extensions() []string { return []string{".eps", ".ps"} }
+func (epsExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ExportEPS(p, t.W, t.Path, opts)
+}
+
+func init() {
+	Register(epsExporter{})
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestExportEPS(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{Pages: 2})
+	p.TextBlocks[0].Lines = append(p.TextBlocks[0].Lines, `(a) \ é`)
+	path := filepath.Join(t.TempDir(), "cube.eps")
+	var first bytes.Buffer
+	if err := ExportEPS(p, &first, path, Options{Logger: slog.New(slog.DiscardHandler)}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(filepath.Join(filepath.Dir(path), "cube_p2.eps"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check reads a page, checks its structure and that its bounding box
+	// holds everything drawn, and returns the number of lines by style.
+	check := func(name, eps string) map[string]int {
+		t.Helper()
+		if !strings.HasPrefix(eps, "%!PS-Adobe-3.0 EPSF-3.0\n") || !strings.HasSuffix(eps, "showpage\n%%EOF\n") {
+			t.Errorf("%s: not a complete EPS file", name)
+		}
+		var x0, y0, x1, y1 float64
+		i := strings.Index(eps, "%%HiResBoundingBox:")
+		if _, err := fmt.Sscanf(eps[i:], "%%%%HiResBoundingBox: %g %g %g %g", &x0, &y0, &x1, &y1); err != nil {
+			t.Fatalf("%s: bounding box: %v", name, err)
+		}
+		inside := func(x, y float64) bool { return x >= x0 && x <= x1 && y >= y0 && y <= y1 }
+		styles := map[string]int{}
+		for _, l := range strings.Split(eps, "\n") {
+			var style string
+			var ax, ay, bx, by float64
+			if n, _ := fmt.Sscanf(l, "%s %g %g %g %g L", &style, &ax, &ay, &bx, &by); n == 5 && !strings.HasPrefix(style, "%") {
+				styles[style]++
+				if !inside(ax, ay) || !inside(bx, by) {
+					t.Errorf("%s: line %q outside the bounding box", name, l)
+				}
+			}
+			if n, _ := fmt.Sscanf(l, "%g %g moveto", &ax, &ay); n == 2 && !inside(ax, ay) {
+				t.Errorf("%s: text %q outside the bounding box", name, l)
+			}
+		}
+		return styles
+	}
+
+	page1, page2 := check("page 1", first.String()), check("page 2", string(second))
+	if page1["cut"] == 0 || page1["mountain"] == 0 {
+		t.Errorf("page 1 lines %v, want cut and mountain lines", page1)
+	}
+	if page2["cut"] != page1["cut"] || page2["mountain"] != page1["mountain"] {
+		t.Errorf("page 2 lines %v, want the same cube as page 1 %v", page2, page1)
+	}
+	for _, want := range []string{
+		"/Helvetica-Latin1 /Helvetica latin1\n",
+		"/Helvetica-Latin1 12.000 selectfont 0.000 0.000 0.000 setrgbcolor\n",
+		"moveto (Glue the flaps inside.) show\n",
+		`moveto (\(a\) \\ \351) show` + "\n",
+	} {
+		if !strings.Contains(first.String(), want) {
+			t.Errorf("page 1 lacks %q", want)
+		}
+	}
+	if strings.Contains(string(second), "show\n") {
+		t.Error("the text block is repeated on page 2")
+	}
+
+	// Text the fonts can't encode is replaced, with a warning.
+	var w Warnings
+	first.Reset()
+	jp := pdotest.Cube(pdotest.Options{MultiByte: true})
+	if err := ExportEPS(jp, &first, "", Options{Logger: slog.New(w.Handler(slog.DiscardHandler))}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(first.String(), "(???????????) show") || !slices.Contains(w.Summary(), WarningCount{"font has no glyphs for text", 1}) {
+		t.Errorf("Japanese text written with warnings %v", w.Summary())
+	}
+}
@@ -0,0 +1,176 @@
+package export
+
+import (
+	"math"
+	"slices"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// DefaultExplodeDistance is the default distance in mm parts are moved
+// apart by ExplodeParts.
+const DefaultExplodeDistance = 20.0
+
+// ExplodedPart is a single papercraft part of a model, the 3D mesh of
+// ExplodeParts or the template of SplitParts.
+type ExplodedPart struct {
+	// Index is the index of the part in the source model.
+	Index int
+	// Name is the part name, empty for unnamed parts.
+	Name string
+	// PDO holds one object with the faces of the part and no unfolding
+	// from ExplodeParts, the model with the part alone from SplitParts.
+	PDO *pdo.PDO
+}
+
+// ExplodeParts splits the model into one mesh per part, each moved
+// distance mm along the average normal of its faces. Parts without a
+// clear direction, like the wrapped side of a cylinder, move away from the
+// model center instead. Faces not on any part are left out.
+func ExplodeParts(p *pdo.PDO, distance float64) []ExplodedPart {
+	scale := p.Unfold.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	center := modelCenter(p)
+
+	var out []ExplodedPart
+	for partIdx, part := range p.Parts {
+		if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+			continue
+		}
+		src := p.Objects[part.ObjectIndex]
+		obj := pdo.Object{Name: src.Name, Visible: 1}
+		remap := map[int32]int32{}
+		var normal, centroid pdo.Vertex3D
+		var area float64
+		for _, face := range src.Faces {
+			if int(face.PartIndex) != partIdx {
+				continue
+			}
+			f := face
+			f.Vertices = append([]pdo.Face2DVertex(nil), face.Vertices...)
+			loop := make([]int32, len(f.Vertices))
+			for i := range f.Vertices {
+				id := f.Vertices[i].IDVertex
+				if id < 0 || int(id) >= len(src.Vertices) {
+					continue
+				}
+				n, ok := remap[id]
+				if !ok {
+					n = int32(len(obj.Vertices))
+					remap[id] = n
+					obj.Vertices = append(obj.Vertices, src.Vertices[id])
+				}
+				f.Vertices[i].IDVertex = n
+				loop[i] = id
+			}
+			fn := newellNormal(src, loop)
+			a := math.Sqrt(fn.X*fn.X+fn.Y*fn.Y+fn.Z*fn.Z) / 2
+			normal = pdo.Vertex3D{X: normal.X + fn.X, Y: normal.Y + fn.Y, Z: normal.Z + fn.Z}
+			c := loopCentroid(src, loop)
+			centroid = pdo.Vertex3D{X: centroid.X + c.X*a, Y: centroid.Y + c.Y*a, Z: centroid.Z + c.Z*a}
+			area += a
+			obj.Faces = append(obj.Faces, f)
+		}
+		if len(obj.Faces) == 0 {
+			continue
+		}
+
+		// The Newell normals sum to twice the projected area, a part curving
+		// around itself projects to a small fraction of its surface.
+		dir := normal
+		if length(dir) < 0.2*area {
+			dir = pdo.Vertex3D{X: centroid.X/area - center.X, Y: centroid.Y/area - center.Y, Z: centroid.Z/area - center.Z}
+		}
+		if l := length(dir); l > 0 && distance != 0 {
+			d := distance / scale / l
+			for i := range obj.Vertices {
+				obj.Vertices[i].X += dir.X * d
+				obj.Vertices[i].Y += dir.Y * d
+				obj.Vertices[i].Z += dir.Z * d
+			}
+		}
+		obj.BuildEdges()
+
+		q := &pdo.PDO{
+			Header:    p.Header,
+			Objects:   []pdo.Object{obj},
+			Materials: p.Materials,
+			Unfold:    pdo.Unfold{Scale: p.Unfold.Scale},
+			Settings:  p.Settings,
+		}
+		q.UpdateBounds()
+		out = append(out, ExplodedPart{Index: partIdx, Name: part.Name, PDO: q})
+	}
+	return out
+}
+
+// SplitParts returns one model per part for the 2D formats, holding that
+// part alone, moved to the top left corner of the first page. The objects
+// keep all their faces and edges, so edge IDs and flaps stay as in the
+// whole template; the faces of other parts are left unfolded. Text blocks
+// and images are left out.
+func SplitParts(p *pdo.PDO) []ExplodedPart {
+	var out []ExplodedPart
+	for partIdx, part := range p.Parts {
+		if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+			continue
+		}
+		q := *p
+		q.Objects = slices.Clone(p.Objects)
+		for i := range q.Objects {
+			obj := &q.Objects[i]
+			obj.Faces = slices.Clone(obj.Faces)
+			for j := range obj.Faces {
+				if i == int(part.ObjectIndex) && int(obj.Faces[j].PartIndex) == partIdx {
+					obj.Faces[j].PartIndex = 0
+				} else {
+					obj.Faces[j].PartIndex = -1
+				}
+			}
+		}
+		part.BoundingBox.Left, part.BoundingBox.Top = 0, 0
+		q.Parts = []pdo.Part{part}
+		q.TextBlocks, q.Images = nil, nil
+		out = append(out, ExplodedPart{Index: partIdx, Name: part.Name, PDO: &q})
+	}
+	return out
+}
+
+// modelCenter returns the center of the bounding box of all vertices.
+func modelCenter(p *pdo.PDO) pdo.Vertex3D {
+	lo := pdo.Vertex3D{X: math.Inf(1), Y: math.Inf(1), Z: math.Inf(1)}
+	hi := pdo.Vertex3D{X: math.Inf(-1), Y: math.Inf(-1), Z: math.Inf(-1)}
+	for _, obj := range p.Objects {
+		for _, v := range obj.Vertices {
+			lo = pdo.Vertex3D{X: min(lo.X, v.X), Y: min(lo.Y, v.Y), Z: min(lo.Z, v.Z)}
+			hi = pdo.Vertex3D{X: max(hi.X, v.X), Y: max(hi.Y, v.Y), Z: max(hi.Z, v.Z)}
+		}
+	}
+	if lo.X > hi.X {
+		return pdo.Vertex3D{}
+	}
+	return pdo.Vertex3D{X: (lo.X + hi.X) / 2, Y: (lo.Y + hi.Y) / 2, Z: (lo.Z + hi.Z) / 2}
+}
+
+func loopCentroid(obj pdo.Object, loop []int32) pdo.Vertex3D {
+	var c pdo.Vertex3D
+	n := 0
+	for _, id := range loop {
+		if id < 0 || int(id) >= len(obj.Vertices) {
+			continue
+		}
+		v := obj.Vertices[id]
+		c = pdo.Vertex3D{X: c.X + v.X, Y: c.Y + v.Y, Z: c.Z + v.Z}
+		n++
+	}
+	if n == 0 {
+		return c
+	}
+	return pdo.Vertex3D{X: c.X / float64(n), Y: c.Y / float64(n), Z: c.Z / float64(n)}
+}
+
+func length(v pdo.Vertex3D) float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
+}
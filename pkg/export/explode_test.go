@@ -0,0 +1,110 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestExplodeParts(t *testing.T) {
+	for _, name := range []string{"cylinder", "pyramid"} {
+		p, err := pdo.ParseFile("../../sample_basic_shapes/" + name + ".pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		onParts := 0
+		for _, obj := range p.Objects {
+			for _, f := range obj.Faces {
+				if f.PartIndex >= 0 {
+					onParts++
+				}
+			}
+		}
+		center := modelCenter(p)
+
+		still := ExplodeParts(p, 0)
+		moved := ExplodeParts(p, 50)
+		if len(still) == 0 || len(still) != len(moved) {
+			t.Fatalf("%s: got %d and %d parts", name, len(still), len(moved))
+		}
+		faces := 0
+		for i, part := range moved {
+			obj := part.PDO.Objects[0]
+			faces += len(obj.Faces)
+			if len(part.PDO.Parts) != 0 || len(obj.Edges) == 0 {
+				t.Errorf("%s part %d: %d parts, %d edges", name, part.Index, len(part.PDO.Parts), len(obj.Edges))
+			}
+			// Every vertex moves by the same 50 mm, away from the center.
+			base := still[i].PDO.Objects[0].Vertices
+			var d pdo.Vertex3D
+			for j, v := range obj.Vertices {
+				dj := pdo.Vertex3D{X: v.X - base[j].X, Y: v.Y - base[j].Y, Z: v.Z - base[j].Z}
+				if j > 0 && length(pdo.Vertex3D{X: dj.X - d.X, Y: dj.Y - d.Y, Z: dj.Z - d.Z}) > 1e-9 {
+					t.Fatalf("%s part %d: vertices moved unevenly", name, part.Index)
+				}
+				d = dj
+			}
+			if got := length(d) * p.Unfold.Scale; math.Abs(got-50) > 1e-6 {
+				t.Errorf("%s part %d: moved %g mm, want 50", name, part.Index, got)
+			}
+			c := loopCentroid(still[i].PDO.Objects[0], allVertices(base))
+			out := pdo.Vertex3D{X: c.X - center.X, Y: c.Y - center.Y, Z: c.Z - center.Z}
+			if out.X*d.X+out.Y*d.Y+out.Z*d.Z < 0 {
+				t.Errorf("%s part %d: moved toward the center", name, part.Index)
+			}
+		}
+		if faces != onParts {
+			t.Errorf("%s: %d faces exploded, want %d", name, faces, onParts)
+		}
+	}
+}
+
+func allVertices(v []pdo.Vertex3D) []int32 {
+	ids := make([]int32, len(v))
+	for i := range ids {
+		ids[i] = int32(i)
+	}
+	return ids
+}
+
+func TestSplitParts(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	split := SplitParts(p)
+	if len(split) != len(p.Parts) || len(split) < 2 {
+		t.Fatalf("got %d parts, want %d", len(split), len(p.Parts))
+	}
+	lookups := p.Lookups()
+	for i, s := range split {
+		q := s.PDO
+		if s.Index != i || len(q.Parts) != 1 || q.Parts[0].BoundingBox.Left != 0 || q.Parts[0].BoundingBox.Top != 0 {
+			t.Fatalf("part %d: index %d, %d parts at %+v", i, s.Index, len(q.Parts), q.Parts[0].BoundingBox)
+		}
+		// The template lines, flaps and edge IDs are those of the part in
+		// the whole model.
+		var want, got []partLine
+		for l := range templateLines(p, lookups.Part(&p.Parts[i]), &p.Parts[i], FlapAuto, 0, Perforation{}) {
+			want = append(want, l)
+		}
+		for l := range templateLines(q, q.Lookups().Part(&q.Parts[0]), &q.Parts[0], FlapAuto, 0, Perforation{}) {
+			got = append(got, l)
+		}
+		if len(got) == 0 || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("part %d lines\n%v\nwant\n%v", i, got, want)
+		}
+		for oi, obj := range q.Objects {
+			for fi, f := range obj.Faces {
+				if orig := p.Objects[oi].Faces[fi].PartIndex; (orig == int32(i)) != (f.PartIndex == 0) || (f.PartIndex != 0 && f.PartIndex != -1) {
+					t.Fatalf("part %d: face %d/%d moved from part %d to %d", i, oi, fi, orig, f.PartIndex)
+				}
+			}
+		}
+	}
+	if p.Parts[1].BoundingBox.Left == 0 && p.Parts[1].BoundingBox.Top == 0 {
+		t.Error("splitting moved the parts of the model")
+	}
+}
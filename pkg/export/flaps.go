@@ -0,0 +1,213 @@
+package export
+
+import (
+	"fmt"
+	"iter"
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// FlapStyle selects how glue flaps are drawn on cut edges.
+type FlapStyle int
+
+const (
+	// FlapAuto draws the flaps stored in the file, with their height and side
+	// angles. Flaps whose sides meet below the top edge become triangles.
+	FlapAuto FlapStyle = iota
+	// FlapTriangle draws every flap as an isosceles triangle of the stored height.
+	FlapTriangle
+	// FlapTrapezoid draws every flap as a trapezoid, lowering flaps whose sides
+	// would meet so a third of the edge length remains as the top edge.
+	FlapTrapezoid
+	// FlapNone strips all flaps, e.g. for laser-cut assembly without tabs.
+	FlapNone
+)
+
+// ParseFlapStyle converts a flap style name ("auto", "triangle", "trapezoid",
+// "none") into a FlapStyle.
+func ParseFlapStyle(s string) (FlapStyle, error) {
+	switch s {
+	case "auto", "":
+		return FlapAuto, nil
+	case "triangle":
+		return FlapTriangle, nil
+	case "trapezoid":
+		return FlapTrapezoid, nil
+	case "none":
+		return FlapNone, nil
+	}
+	return FlapAuto, fmt.Errorf("unknown flap style %q", s)
+}
+
+// Line types of part lines, see pdo.Line.Type.
+const (
+	lineCut       = 0
+	lineMountain  = 1
+	lineValley    = 2
+	lineInvisible = 3
+)
+
+// drawnLineType returns the type a line of type t is drawn as. Types come
+// from the file: unknown ones below lineCut are drawn as cuts, those above
+// lineInvisible are invisible.
+func drawnLineType(t int32) int32 {
+	return min(max(t, lineCut), lineInvisible)
+}
+
+// partLine is a line segment to draw, in part coordinates.
+type partLine struct {
+	X1, Y1, X2, Y2 float64
+	Type           int32
+	// Material is the material index of the face the line belongs to,
+	// -1 if unknown. Flap lines carry the material of their edge.
+	Material int32
+	// Outline is set on the lines around the part: cut edges without a
+	// flap and the flap outlines.
+	Outline bool
+	// Color is the line color the file sets on the edge, nil for the
+	// color of the line type.
+	Color *[3]uint8
+}
+
+// partLines yields the lines of a part, including hidden ones, followed by
+// the outline of each glue flap. A cut edge carrying a flap turns into a
+// mountain fold, the flap is folded behind the neighbouring face.
+// lk is the lookup of the part's object, nil when it has none.
+func partLines(p *pdo.PDO, lk *pdo.Lookup, part *pdo.Part, style FlapStyle) iter.Seq[partLine] {
+	return func(yield func(partLine) bool) {
+		if lk == nil {
+			return
+		}
+		obj := *lk.Object()
+		showFlaps := style != FlapNone && (style != FlapAuto || p.Settings.ShowFlaps == 1)
+
+		for line := range part.VisibleLines() {
+			v1, v2, _ := lk.ResolveLine(line)
+			if v1 == nil {
+				continue
+			}
+			material := int32(-1)
+			if line.FaceIndex >= 0 && int(line.FaceIndex) < len(obj.Faces) {
+				material = obj.Faces[line.FaceIndex].MaterialIndex
+			}
+			base := partLine{X1: v1.X, Y1: v1.Y, X2: v2.X, Y2: v2.Y, Type: line.Type, Material: material}
+
+			var flap []partLine
+			if showFlaps && line.Type == lineCut && hasFlap(lk, line, v1, v2) {
+				flap = flapOutline(obj, line.FaceIndex, v1, v2, style)
+			}
+			if flap != nil {
+				base.Type = lineMountain
+			} else {
+				base.Outline = !line.IsConnectingFaces
+			}
+			if base.Type == lineMountain || base.Type == lineValley {
+				base.Color = foldLineColor(v1, v2)
+			}
+			if !yield(base) {
+				return
+			}
+			for _, l := range flap {
+				l.Material = material
+				l.Outline = true
+				l.Color = lineColor(v1.FlapLineColor)
+				if !yield(l) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// foldLineColor returns the custom color of the fold line between two
+// vertices, kept by the vertex starting the edge on either face.
+func foldLineColor(v1, v2 *pdo.Face2DVertex) *[3]uint8 {
+	if c := lineColor(v1.FoldLineColor); c != nil {
+		return c
+	}
+	return lineColor(v2.FoldLineColor)
+}
+
+// lineColor converts a custom line color of a file, nil when unset.
+func lineColor(c [3]float32) *[3]uint8 {
+	if c == [3]float32{} {
+		return nil
+	}
+	var rgb [3]uint8
+	for i, v := range c {
+		rgb[i] = uint8(math.Round(float64(min(max(v, 0), 1)) * 255))
+	}
+	return &rgb
+}
+
+// hasFlap reports whether a cut line gets a flap, see pdo.Object.FlapFace.
+func hasFlap(lk *pdo.Lookup, line *pdo.Line, v1, v2 *pdo.Face2DVertex) bool {
+	if line.IsConnectingFaces || v1.Flap == 0 || v1.FlapHeight <= 0 {
+		return false
+	}
+	id := findEdgeID(lk, v1.IDVertex, v2.IDVertex)
+	return id > 0 && lk.Object().FlapFace(id-1) == line.FaceIndex
+}
+
+// flapOutline returns the cut lines of the flap on the edge v1-v2 of a face.
+// The flap sits on the side away from the face. Its shape is stored on v1,
+// FlapAAngle is the side angle at v1 and FlapBAngle the one at v2.
+func flapOutline(obj pdo.Object, faceIdx int32, v1, v2 *pdo.Face2DVertex, style FlapStyle) []partLine {
+	dx, dy := v2.X-v1.X, v2.Y-v1.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return nil
+	}
+	ux, uy := dx/length, dy/length
+	nx, ny := -uy, ux // Unit normal, pointed away from the face below
+	cx, cy := faceCentroid(obj, faceIdx)
+	if (cx-v1.X)*nx+(cy-v1.Y)*ny > 0 {
+		nx, ny = -nx, -ny
+	}
+
+	h := v1.FlapHeight
+	at := func(along, up float64) (float64, float64) {
+		return v1.X + ux*along + nx*up, v1.Y + uy*along + ny*up
+	}
+	triangle := func(apexAlong, apexUp float64) []partLine {
+		ax, ay := at(apexAlong, apexUp)
+		return []partLine{
+			{X1: v1.X, Y1: v1.Y, X2: ax, Y2: ay, Type: lineCut},
+			{X1: ax, Y1: ay, X2: v2.X, Y2: v2.Y, Type: lineCut},
+		}
+	}
+
+	if style == FlapTriangle {
+		return triangle(length/2, h)
+	}
+
+	// Horizontal run of each side up to the flap height. Angles at or beyond
+	// 90 degrees are clamped so the flap never grows wider than its edge.
+	cotA, cotB := flapCot(v1.FlapAAngle), flapCot(v1.FlapBAngle)
+	if cotA+cotB > 0 && h*(cotA+cotB) >= length {
+		if style == FlapTrapezoid {
+			h = length * 2 / 3 / (cotA + cotB)
+		} else {
+			// The sides meet before reaching the height, use their intersection.
+			apexAlong := length * cotA / (cotA + cotB)
+			return triangle(apexAlong, length/(cotA+cotB))
+		}
+	}
+
+	ax, ay := at(h*cotA, h)
+	bx, by := at(length-h*cotB, h)
+	return []partLine{
+		{X1: v1.X, Y1: v1.Y, X2: ax, Y2: ay, Type: lineCut},
+		{X1: ax, Y1: ay, X2: bx, Y2: by, Type: lineCut},
+		{X1: bx, Y1: by, X2: v2.X, Y2: v2.Y, Type: lineCut},
+	}
+}
+
+// flapCot returns the cotangent of a flap side angle, 0 for right or obtuse angles.
+func flapCot(angle float64) float64 {
+	if angle <= 0 || angle >= math.Pi/2 {
+		return 0
+	}
+	return 1 / math.Tan(angle)
+}
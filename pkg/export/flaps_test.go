@@ -0,0 +1,130 @@
+package export
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestPartLines_Flaps(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookups := p.Lookups()
+	count := func(style FlapStyle) map[int32]int {
+		types := map[int32]int{}
+		for i := range p.Parts {
+			for line := range partLines(p, lookups.Part(&p.Parts[i]), &p.Parts[i], style) {
+				types[line.Type]++
+			}
+		}
+		return types
+	}
+
+	none := count(FlapNone)
+	auto := count(FlapAuto)
+	tri := count(FlapTriangle)
+
+	// Each flap turns a cut edge into a fold and adds two or three cut lines.
+	flaps := auto[lineMountain] - none[lineMountain]
+	if flaps == 0 {
+		t.Fatal("no flaps drawn")
+	}
+	if got := tri[lineMountain] - none[lineMountain]; got != flaps {
+		t.Errorf("triangle style: %d flaps, want %d", got, flaps)
+	}
+	if got := tri[lineCut] - (none[lineCut] - flaps); got != 2*flaps {
+		t.Errorf("triangle style: %d flap lines, want %d", got, 2*flaps)
+	}
+
+	// Only one side of each split edge gets a flap.
+	if cutEdges := none[lineCut]; flaps*2 > cutEdges {
+		t.Errorf("%d flaps for %d cut lines", flaps, cutEdges)
+	}
+
+	p.Settings.ShowFlaps = 0
+	if got := count(FlapAuto); got[lineMountain] != none[lineMountain] {
+		t.Error("flaps drawn with ShowFlaps off")
+	}
+}
+
+func TestFlapOutline_PointsAwayFromFace(t *testing.T) {
+	// Right triangle face, flap on the edge (0,0)-(10,0) must be drawn at y < 0.
+	obj := pdo.Object{Faces: []pdo.Face{{Vertices: []pdo.Face2DVertex{
+		{X: 0, Y: 0, Flap: 1, FlapHeight: 3, FlapAAngle: math.Pi / 4, FlapBAngle: math.Pi / 4},
+		{X: 10, Y: 0},
+		{X: 0, Y: 10},
+	}}}}
+	v := obj.Faces[0].Vertices
+
+	for _, style := range []FlapStyle{FlapAuto, FlapTriangle, FlapTrapezoid} {
+		lines := flapOutline(obj, 0, &v[0], &v[1], style)
+		if len(lines) == 0 {
+			t.Fatalf("style %d: no flap", style)
+		}
+		for _, l := range lines {
+			if l.Y1 > 0 || l.Y2 > 0 {
+				t.Errorf("style %d: flap line %+v inside the face", style, l)
+			}
+		}
+	}
+
+	// 45 degree sides on a 4mm edge meet at 2mm, below the 3mm height.
+	v[1].X = 4
+	if lines := flapOutline(obj, 0, &v[0], &v[1], FlapAuto); len(lines) != 2 || math.Abs(lines[0].Y2+2) > 1e-9 {
+		t.Errorf("short edge: got %+v, want a triangle with its apex 2mm out", lines)
+	}
+}
+
+func TestPartLines_LineColors(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range p.Objects[0].Faces {
+		for j := range p.Objects[0].Faces[i].Vertices {
+			v := &p.Objects[0].Faces[i].Vertices[j]
+			v.FlapLineColor = [3]float32{1, 0, 0}
+			v.FoldLineColor = [3]float32{0, 1, 0}
+		}
+	}
+
+	lookups := p.Lookups()
+	var red, green, folds, plain int
+	for i := range p.Parts {
+		for line := range partLines(p, lookups.Part(&p.Parts[i]), &p.Parts[i], FlapAuto) {
+			switch {
+			case line.Color == nil:
+				plain++
+			case *line.Color == [3]uint8{255, 0, 0} && line.Type == lineCut:
+				red++
+			case *line.Color == [3]uint8{0, 255, 0} && line.Type == lineMountain:
+				green++
+			default:
+				t.Errorf("line of type %d in %v", line.Type, *line.Color)
+			}
+			if line.Type == lineMountain {
+				folds++
+			}
+		}
+	}
+	if red == 0 || green != folds || plain == 0 {
+		t.Errorf("%d flap lines in red, %d of %d folds in green, %d lines in the default colors", red, green, folds, plain)
+	}
+
+	var buf bytes.Buffer
+	for _, compact := range []bool{false, true} {
+		buf.Reset()
+		if err := ExportSVG(p, &buf, Options{CompactPaths: compact}); err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(buf.String(), "stroke:#ff0000"); n != red {
+			t.Errorf("compact paths %v: %d red SVG lines, want %d", compact, n, red)
+		}
+	}
+}
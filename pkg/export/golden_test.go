@@ -0,0 +1,109 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenCases are the synthetic models exported by TestGolden.
+var goldenCases = []struct {
+	name string
+	opts pdotest.Options
+}{
+	{"v4", pdotest.Options{Version: pdo.PDO_V4}},
+	{"v5", pdotest.Options{Version: pdo.PDO_V5}},
+	{"v6", pdotest.Options{Version: pdo.PDO_V6}},
+	{"multibyte", pdotest.Options{MultiByte: true}},
+	{"textured", pdotest.Options{Texture: true}},
+	{"pages", pdotest.Options{Pages: 3, Size: 30}},
+}
+
+// TestGolden writes each synthetic model as PDO, parses it back and
+// compares the file and its SVG, PDF and OBJ exports with the golden files.
+// Run with -update after intended output changes.
+func TestGolden(t *testing.T) {
+	reproduciblePDF(t)
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var file bytes.Buffer
+			if err := pdo.Write(&file, pdotest.Cube(tc.opts)); err != nil {
+				t.Fatal(err)
+			}
+			golden(t, tc.name+".pdo", file.Bytes())
+			parser := pdo.NewParser(bytes.NewReader(file.Bytes()))
+			if err := parser.Load(); err != nil {
+				t.Fatal(err)
+			}
+			p := parser.PDO
+
+			var svg, pdf, obj bytes.Buffer
+			if err := ExportSVG(p, &svg, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			golden(t, tc.name+".svg", svg.Bytes())
+			if err := ExportPDF(p, &pdf, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			golden(t, tc.name+".pdf", pdf.Bytes())
+
+			dir := t.TempDir()
+			if err := ExportOBJ(p, &obj, filepath.Join(dir, tc.name+".obj"), Options{}); err != nil {
+				t.Fatal(err)
+			}
+			golden(t, tc.name+".obj", obj.Bytes())
+			mtl, err := os.ReadFile(filepath.Join(dir, tc.name+".mtl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, tc.name+".mtl", mtl)
+		})
+	}
+}
+
+// reproduciblePDF makes PDF output independent of the clock and of map
+// order until the test ends.
+func reproduciblePDF(t *testing.T) {
+	stamp := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fpdf.SetDefaultCreationDate(stamp)
+	fpdf.SetDefaultModificationDate(stamp)
+	fpdf.SetDefaultCatalogSort(true)
+	t.Cleanup(func() {
+		fpdf.SetDefaultCreationDate(time.Time{})
+		fpdf.SetDefaultModificationDate(time.Time{})
+		fpdf.SetDefaultCatalogSort(false)
+	})
+}
+
+// golden compares got with testdata/golden/name, or rewrites the file with
+// -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file, run go test -update if the change is intended", name)
+	}
+}
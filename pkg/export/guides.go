@@ -0,0 +1,69 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Guides draws layout aids beneath the template on every page of 2D
+// formats, to check the layout against the printable area of a printer.
+type Guides struct {
+	// Margins outlines the printable area inside the page margins.
+	Margins bool
+	// Grid is the spacing in mm of a light grid over the printable area,
+	// 0 draws none.
+	Grid float64
+	// Background is the paper color, nil leaves the page white.
+	Background *[3]uint8
+}
+
+func (g Guides) enabled() bool {
+	return g.Margins || g.Grid > 0 || g.Background != nil
+}
+
+// Guide colors, light enough not to be mistaken for template lines.
+var (
+	marginGuideColor = [3]uint8{0xcc, 0xcc, 0xcc}
+	gridGuideColor   = [3]uint8{0xe0, 0xe0, 0xe0}
+)
+
+// Guide line widths in mm.
+const (
+	marginGuideWidth = 0.1
+	gridGuideWidth   = 0.05
+)
+
+// ParseColor converts a "#rrggbb" or "rrggbb" hex color.
+func ParseColor(s string) ([3]uint8, error) {
+	hex := strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return [3]uint8{}, fmt.Errorf("invalid color %q, want #rrggbb", s)
+	}
+	return [3]uint8{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// gridLines returns the grid lines over the printable area of a page, in
+// mm from the page's top left corner, as x1, y1, x2, y2.
+func (g Guides) gridLines(dims pdo.PageDims) [][4]float64 {
+	if g.Grid <= 0 {
+		return nil
+	}
+	left, top := dims.MarginLeft, dims.MarginTop
+	right, bottom := left+dims.ClippedWidth, top+dims.ClippedHeight
+	var lines [][4]float64
+	for x := left + g.Grid; x < right; x += g.Grid {
+		lines = append(lines, [4]float64{x, top, x, bottom})
+	}
+	for y := top + g.Grid; y < bottom; y += g.Grid {
+		lines = append(lines, [4]float64{left, y, right, y})
+	}
+	return lines
+}
+
+func hexColor(c [3]uint8) string {
+	return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+}
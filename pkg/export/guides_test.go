@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestGuides(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bg, err := ParseColor("#f5f0e6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guides := Guides{Margins: true, Grid: 10, Background: &bg}
+
+	dims := p.PageDims()
+	lines := guides.gridLines(dims)
+	want := int((dims.ClippedWidth-1e-9)/10) + int((dims.ClippedHeight-1e-9)/10)
+	if len(lines) != want {
+		t.Errorf("%d grid lines, want %d", len(lines), want)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportSVG(p, &buf, Options{Guides: guides}); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	for _, s := range []string{`id="guides"`, `class="margin"`, `fill:#f5f0e6`, `stroke:#e0e0e0`} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("SVG lacks %s", s)
+		}
+	}
+	if strings.Index(svg, `id="guides"`) > strings.Index(svg, `id="parts"`) {
+		t.Error("guides drawn above the parts")
+	}
+
+	buf.Reset()
+	if err := ExportPDF(p, &buf, Options{Guides: guides}); err != nil {
+		t.Fatal(err)
+	}
+
+	pages := RenderPages(p, 30, Options{Guides: guides})
+	if got := pages[0].RGBAAt(0, 0); got != (color.RGBA{0xf5, 0xf0, 0xe6, 255}) {
+		t.Errorf("paper color %v", got)
+	}
+
+	for _, bad := range []string{"red", "#12345", "#gggggg"} {
+		if _, err := ParseColor(bad); err == nil {
+			t.Errorf("ParseColor(%q) accepted", bad)
+		}
+	}
+}
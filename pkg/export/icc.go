@@ -0,0 +1,127 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// iccProfile is the part of an ICC profile header needed to embed it.
+type iccProfile struct {
+	data       []byte
+	class      string // Device class, e.g. "mntr" or "prtr"
+	colorSpace string // Data color space, e.g. "RGB " or "CMYK"
+}
+
+// components returns the number of color components of the profile's color space.
+func (p iccProfile) components() int {
+	switch p.colorSpace {
+	case "GRAY":
+		return 1
+	case "CMYK":
+		return 4
+	}
+	return 3
+}
+
+// parseICC checks the header of an ICC profile.
+func parseICC(data []byte) (iccProfile, error) {
+	if len(data) < 128 || string(data[36:40]) != "acsp" {
+		return iccProfile{}, fmt.Errorf("not an ICC profile")
+	}
+	if size := binary.BigEndian.Uint32(data); int(size) > len(data) {
+		return iccProfile{}, fmt.Errorf("ICC profile truncated: %d of %d bytes", len(data), size)
+	}
+	p := iccProfile{data: data, class: string(data[12:16]), colorSpace: string(data[16:20])}
+	switch p.colorSpace {
+	case "GRAY", "RGB ", "CMYK":
+		return p, nil
+	}
+	return iccProfile{}, fmt.Errorf("unsupported ICC color space %q", p.colorSpace)
+}
+
+// srgbProfile returns a compact sRGB profile: D50-adapted primaries and the
+// sRGB tone curve sampled at 256 points.
+var srgbProfile = sync.OnceValue(func() iccProfile {
+	xyz := func(x, y, z float64) []byte {
+		b := append([]byte("XYZ "), 0, 0, 0, 0)
+		for _, v := range []float64{x, y, z} {
+			b = binary.BigEndian.AppendUint32(b, uint32(int32(math.Round(v*65536))))
+		}
+		return b
+	}
+	desc := func(s string) []byte {
+		b := append([]byte("desc"), 0, 0, 0, 0)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)+1))
+		b = append(append(b, s...), 0)
+		b = append(b, make([]byte, 4+4+2+1+67)...) // Empty Unicode and ScriptCode descriptions
+		return b
+	}
+	curve := append([]byte("curv"), 0, 0, 0, 0)
+	curve = binary.BigEndian.AppendUint32(curve, 256)
+	for i := range 256 {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		curve = binary.BigEndian.AppendUint16(curve, uint16(math.Round(v*65535)))
+	}
+
+	type tag struct {
+		sig  string
+		data []byte
+	}
+	tags := []tag{
+		{"desc", desc("sRGB IEC61966-2.1")},
+		{"cprt", append([]byte("text\x00\x00\x00\x00"), "No copyright, use freely\x00"...)},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve},
+		{"gTRC", curve},
+		{"bTRC", curve},
+	}
+
+	// Tag data follows the header and tag table, 4-byte aligned. The three
+	// tone curves share their data.
+	var body bytes.Buffer
+	offsets := map[*byte]uint32{}
+	tableEnd := uint32(128 + 4 + 12*len(tags))
+	table := binary.BigEndian.AppendUint32(nil, uint32(len(tags)))
+	for _, t := range tags {
+		off, ok := offsets[&t.data[0]]
+		if !ok {
+			off = tableEnd + uint32(body.Len())
+			offsets[&t.data[0]] = off
+			body.Write(t.data)
+			for body.Len()%4 != 0 {
+				body.WriteByte(0)
+			}
+		}
+		table = append(table, t.sig...)
+		table = binary.BigEndian.AppendUint32(table, off)
+		table = binary.BigEndian.AppendUint32(table, uint32(len(t.data)))
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], tableEnd+uint32(body.Len()))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // Version 2.1
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	for i, v := range []uint16{2024, 1, 1} { // Creation date, time stays zero
+		binary.BigEndian.PutUint16(header[24+2*i:], v)
+	}
+	copy(header[36:], "acsp")
+	for i, v := range []float64{0.9642, 1.0, 0.8249} { // D50 illuminant
+		binary.BigEndian.PutUint32(header[68+4*i:], uint32(int32(math.Round(v*65536))))
+	}
+
+	data := append(append(header, table...), body.Bytes()...)
+	return iccProfile{data: data, class: "mntr", colorSpace: "RGB "}
+})
@@ -0,0 +1,265 @@
+package export
+
+import (
+	"bufio"
+	"image"
+	"io"
+	"math"
+)
+
+// encodeCMYKJPEG writes a baseline JPEG with four unsampled components, the
+// Adobe CMYK layout PDF readers understand. Image/jpeg can only write RGB
+// and gray. Values are stored inverted, as Adobe applications do.
+func encodeCMYKJPEG(w io.Writer, img *image.CMYK, quality int) error {
+	bw := bufio.NewWriter(w)
+	b := img.Bounds()
+
+	var quant [64]byte
+	scale := 5000 / quality
+	if quality >= 50 {
+		scale = 200 - 2*quality
+	}
+	for i, q := range jpegLumaQuant {
+		quant[i] = byte(min(max((int(q)*scale+50)/100, 1), 255))
+	}
+
+	bw.Write([]byte{0xff, 0xd8}) // SOI
+	// APP14 Adobe marker, transform 0: CMYK without color conversion.
+	bw.Write([]byte{0xff, 0xee, 0, 14, 'A', 'd', 'o', 'b', 'e', 0, 100, 0, 0, 0, 0, 0})
+	// DQT, one table for all components, in zigzag order.
+	bw.Write([]byte{0xff, 0xdb, 0, 67, 0})
+	for _, n := range jpegZigzag {
+		bw.WriteByte(quant[n])
+	}
+	// SOF0
+	bw.Write([]byte{0xff, 0xc0, 0, 8 + 3*4, 8, byte(b.Dy() >> 8), byte(b.Dy()), byte(b.Dx() >> 8), byte(b.Dx()), 4})
+	for c := range 4 {
+		bw.Write([]byte{byte(c + 1), 0x11, 0})
+	}
+	// DHT, the standard luminance tables.
+	writeHuffmanTable(bw, 0x00, jpegDCCounts, jpegDCValues)
+	writeHuffmanTable(bw, 0x10, jpegACCounts, jpegACValues)
+	// SOS
+	bw.Write([]byte{0xff, 0xda, 0, 6 + 2*4, 4})
+	for c := range 4 {
+		bw.Write([]byte{byte(c + 1), 0x00})
+	}
+	bw.Write([]byte{0, 63, 0})
+
+	dc := newHuffmanCodes(jpegDCCounts, jpegDCValues)
+	ac := newHuffmanCodes(jpegACCounts, jpegACValues)
+	bits := &jpegBits{w: bw}
+	var prev [4]int
+	var block [64]float64
+	for by := b.Min.Y; by < b.Max.Y; by += 8 {
+		for bx := b.Min.X; bx < b.Max.X; bx += 8 {
+			for c := range 4 {
+				for y := range 8 {
+					for x := range 8 {
+						// Edge blocks repeat the last row and column.
+						px := min(bx+x, b.Max.X-1)
+						py := min(by+y, b.Max.Y-1)
+						v := img.Pix[img.PixOffset(px, py)+c]
+						block[y*8+x] = float64(255-v) - 128
+					}
+				}
+				coef := fdct(&block)
+				var q [64]int
+				for i, n := range jpegZigzag {
+					q[i] = int(math.Round(coef[n] / float64(quant[n])))
+				}
+				prev[c] = encodeBlock(bits, &q, prev[c], dc, ac)
+			}
+		}
+	}
+	bits.flush()
+	bw.Write([]byte{0xff, 0xd9}) // EOI
+	return bw.Flush()
+}
+
+// fdct is a direct 8x8 forward DCT, separable into rows and columns.
+func fdct(in *[64]float64) [64]float64 {
+	var tmp, out [64]float64
+	for y := range 8 {
+		for u := range 8 {
+			var s float64
+			for x := range 8 {
+				s += in[y*8+x] * dctCos[x][u]
+			}
+			tmp[y*8+u] = s
+		}
+	}
+	for u := range 8 {
+		for v := range 8 {
+			var s float64
+			for y := range 8 {
+				s += tmp[y*8+u] * dctCos[y][v]
+			}
+			out[v*8+u] = s / 4
+		}
+	}
+	return out
+}
+
+// dctCos[x][u] is C(u) cos((2x+1)uπ/16).
+var dctCos = func() (t [8][8]float64) {
+	for x := range 8 {
+		for u := range 8 {
+			c := 1.0
+			if u == 0 {
+				c = 1 / math.Sqrt2
+			}
+			t[x][u] = c * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16)
+		}
+	}
+	return t
+}()
+
+// encodeBlock writes one quantized block in zigzag order and returns its DC value.
+func encodeBlock(bits *jpegBits, q *[64]int, prevDC int, dc, ac *huffmanCodes) int {
+	diff := q[0] - prevDC
+	n := bitLength(diff)
+	bits.emit(dc.code[n], dc.size[n])
+	bits.emitValue(diff, n)
+
+	run := 0
+	for i := 1; i < 64; i++ {
+		if q[i] == 0 {
+			run++
+			continue
+		}
+		for run > 15 {
+			bits.emit(ac.code[0xf0], ac.size[0xf0]) // ZRL
+			run -= 16
+		}
+		n := bitLength(q[i])
+		sym := run<<4 | n
+		bits.emit(ac.code[sym], ac.size[sym])
+		bits.emitValue(q[i], n)
+		run = 0
+	}
+	if run > 0 {
+		bits.emit(ac.code[0], ac.size[0]) // EOB
+	}
+	return q[0]
+}
+
+func bitLength(v int) int {
+	if v < 0 {
+		v = -v
+	}
+	n := 0
+	for v > 0 {
+		n++
+		v >>= 1
+	}
+	return n
+}
+
+// jpegBits packs entropy-coded bits, stuffing a zero after 0xff bytes.
+type jpegBits struct {
+	w    *bufio.Writer
+	acc  uint32
+	nacc uint
+}
+
+func (b *jpegBits) emit(code uint32, size uint) {
+	b.acc = b.acc<<size | code
+	b.nacc += size
+	for b.nacc >= 8 {
+		c := byte(b.acc >> (b.nacc - 8))
+		b.w.WriteByte(c)
+		if c == 0xff {
+			b.w.WriteByte(0)
+		}
+		b.nacc -= 8
+	}
+	b.acc &= 1<<b.nacc - 1
+}
+
+// emitValue writes the n-bit representation of a coefficient, negative
+// values as their one's complement.
+func (b *jpegBits) emitValue(v, n int) {
+	if n == 0 {
+		return
+	}
+	if v < 0 {
+		v += 1<<n - 1
+	}
+	b.emit(uint32(v), uint(n))
+}
+
+// flush pads the last byte with one bits.
+func (b *jpegBits) flush() {
+	if b.nacc > 0 {
+		b.emit(1<<(8-b.nacc)-1, 8-b.nacc)
+	}
+}
+
+type huffmanCodes struct {
+	code [256]uint32
+	size [256]uint
+}
+
+// newHuffmanCodes derives the codes of a table given as code counts per
+// length and the symbols in code order.
+func newHuffmanCodes(counts [16]byte, values []byte) *huffmanCodes {
+	h := &huffmanCodes{}
+	code, k := uint32(0), 0
+	for l, n := range counts {
+		for range n {
+			h.code[values[k]] = code
+			h.size[values[k]] = uint(l + 1)
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return h
+}
+
+func writeHuffmanTable(w *bufio.Writer, class byte, counts [16]byte, values []byte) {
+	n := 2 + 1 + 16 + len(values)
+	w.Write([]byte{0xff, 0xc4, byte(n >> 8), byte(n), class})
+	w.Write(counts[:])
+	w.Write(values)
+}
+
+var jpegZigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10, 17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34, 27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36, 29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46, 53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// jpegLumaQuant is the example luminance table of the JPEG standard (Annex K), in natural order.
+var jpegLumaQuant = [64]byte{
+	16, 11, 10, 16, 24, 40, 51, 61,
+	12, 12, 14, 19, 26, 58, 60, 55,
+	14, 13, 16, 24, 40, 57, 69, 56,
+	14, 17, 22, 29, 51, 87, 80, 62,
+	18, 22, 37, 56, 68, 109, 103, 77,
+	24, 35, 55, 64, 81, 104, 113, 92,
+	49, 64, 78, 87, 103, 121, 120, 101,
+	72, 92, 95, 98, 112, 100, 103, 99,
+}
+
+// Standard luminance Huffman tables (Annex K.3).
+var (
+	jpegDCCounts = [16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0}
+	jpegDCValues = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	jpegACCounts = [16]byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 0x7d}
+	jpegACValues = []byte{
+		0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12, 0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+		0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08, 0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+		0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+		0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+		0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+		0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79, 0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+		0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+		0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+		0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+		0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea, 0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+		0xf9, 0xfa,
+	}
+)
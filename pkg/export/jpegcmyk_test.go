@@ -0,0 +1,42 @@
+package export
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestEncodeCMYKJPEG(t *testing.T) {
+	// A gradient with a size that isn't a multiple of the block size.
+	img := image.NewCMYK(image.Rect(0, 0, 21, 13))
+	for y := range 13 {
+		for x := range 21 {
+			img.SetCMYK(x, y, color.CMYK{C: uint8(x * 12), M: uint8(y * 19), Y: 128, K: uint8(x * y)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := encodeCMYKJPEG(&buf, img, 95); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := decoded.(*image.CMYK)
+	if !ok {
+		t.Fatalf("decoded as %T, want *image.CMYK", decoded)
+	}
+	for y := range 13 {
+		for x := range 21 {
+			a, b := img.CMYKAt(x, y), got.CMYKAt(x, y)
+			for i, d := range []int{int(a.C) - int(b.C), int(a.M) - int(b.M), int(a.Y) - int(b.Y), int(a.K) - int(b.K)} {
+				if d < -8 || d > 8 {
+					t.Fatalf("pixel (%d, %d) channel %d: got %v, want %v", x, y, i, b, a)
+				}
+			}
+		}
+	}
+}
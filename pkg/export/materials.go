@@ -0,0 +1,74 @@
+package export
+
+import (
+	"fmt"
+	"slices"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// FaceFill selects what textured output paints faces with.
+type FaceFill int
+
+const (
+	// FaceFillAuto follows the face material setting of the file.
+	FaceFillAuto FaceFill = iota
+	// FaceFillTexture paints faces with their material's texture.
+	FaceFillTexture
+	// FaceFillColor paints faces with the flat 2D color of their material.
+	FaceFillColor
+)
+
+// ParseFaceFill converts a face fill name ("auto", "texture", "color")
+// into a FaceFill.
+func ParseFaceFill(s string) (FaceFill, error) {
+	switch s {
+	case "auto", "":
+		return FaceFillAuto, nil
+	case "texture":
+		return FaceFillTexture, nil
+	case "color":
+		return FaceFillColor, nil
+	}
+	return FaceFillAuto, fmt.Errorf("unknown face fill %q", s)
+}
+
+// resolve returns the fill to use for a file, Settings.FaceMaterial set
+// prints textures and clear prints material colors.
+func (f FaceFill) resolve(s pdo.Settings) FaceFill {
+	if f != FaceFillAuto {
+		return f
+	}
+	if s.FaceMaterial != 0 {
+		return FaceFillTexture
+	}
+	return FaceFillColor
+}
+
+// usedMaterials returns the material indices of all drawn part lines in
+// ascending order. -1 stands for lines of faces without a material.
+func usedMaterials(p *pdo.PDO, style FlapStyle) []int32 {
+	var used []int32
+	lookups := p.Lookups()
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		for line := range partLines(p, lookups.Part(part), part, style) {
+			if !slices.Contains(used, line.Material) {
+				used = append(used, line.Material)
+			}
+		}
+	}
+	slices.Sort(used)
+	return used
+}
+
+// materialName returns the display name of a material index.
+func materialName(p *pdo.PDO, m int32) string {
+	if m < 0 || int(m) >= len(p.Materials) {
+		return "No material"
+	}
+	if name := p.Materials[m].Name; name != "" {
+		return name
+	}
+	return fmt.Sprintf("Material_%d", m)
+}
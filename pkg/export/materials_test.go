@@ -0,0 +1,43 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestExportSVG_MaterialLayers(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Give half the faces a named material.
+	p.Materials = append(p.Materials, pdo.Material{Name: "Red & White"})
+	faces := p.Objects[0].Faces
+	for i := range faces {
+		faces[i].MaterialIndex = -1
+		if i%2 == 0 {
+			faces[i].MaterialIndex = int32(len(p.Materials) - 1)
+		}
+	}
+
+	var plain, layered bytes.Buffer
+	if err := ExportSVG(p, &plain, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportSVG(p, &layered, Options{MaterialLayers: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := layered.String()
+	for _, want := range []string{`inkscape:label="No material"`, `inkscape:label="Red &amp; White"`, `inkscape:label="Edge IDs"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing layer %s", want)
+		}
+	}
+	if got, want := strings.Count(out, "<line "), strings.Count(plain.String(), "<line "); got != want {
+		t.Errorf("layered output has %d lines, want %d", got, want)
+	}
+}
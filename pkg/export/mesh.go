@@ -0,0 +1,92 @@
+package export
+
+import (
+	"slices"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// closedMesh returns the faces of an object as vertex index loops,
+// counter-clockwise seen from outside, followed by a cap for every hole in
+// the surface so solid modelers get a watertight mesh.
+func closedMesh(obj pdo.Object) (faces [][]int32, holes int) {
+	for i := range obj.Faces {
+		if loop := outwardLoop(obj, &obj.Faces[i]); len(loop) >= 3 {
+			faces = append(faces, loop)
+		}
+	}
+
+	// Edges used once in their direction and never in the other border a hole.
+	type edge struct{ a, b int32 }
+	used := map[edge]int{}
+	for _, loop := range faces {
+		for i, a := range loop {
+			used[edge{a, loop[(i+1)%len(loop)]}]++
+		}
+	}
+	next := map[int32][]int32{}
+	var starts []int32
+	for _, loop := range faces {
+		for i, a := range loop {
+			b := loop[(i+1)%len(loop)]
+			if used[edge{b, a}] == 0 {
+				next[a] = append(next[a], b)
+				starts = append(starts, a)
+			}
+		}
+	}
+
+	// Follow the border edges around each hole. The cap runs the other way
+	// to face outwards like its neighbours.
+	for _, start := range starts {
+		if len(next[start]) == 0 {
+			continue
+		}
+		var hole []int32
+		for v := start; len(next[v]) > 0; {
+			hole = append(hole, v)
+			to := next[v][0]
+			next[v] = next[v][1:]
+			if v = to; v == start {
+				break
+			}
+		}
+		if len(hole) >= 3 {
+			slices.Reverse(hole)
+			faces = append(faces, hole)
+			holes++
+		}
+	}
+	return faces, holes
+}
+
+// outwardLoop returns the vertex indices of a face counter-clockwise seen
+// from outside. The winding comes from the stored face normal, PDO doesn't
+// fix the corner order.
+func outwardLoop(obj pdo.Object, face *pdo.Face) []int32 {
+	loop := make([]int32, len(face.Vertices))
+	for i, fv := range face.Vertices {
+		loop[i] = fv.IDVertex
+	}
+	if dot(newellNormal(obj, loop), pdo.Vertex3D{X: face.Nx, Y: face.Ny, Z: face.Nz}) < 0 {
+		slices.Reverse(loop)
+	}
+	return loop
+}
+
+// newellNormal returns the unnormalized normal of a vertex loop by Newell's
+// method, pointing to where the loop runs counter-clockwise.
+func newellNormal(obj pdo.Object, loop []int32) pdo.Vertex3D {
+	var n pdo.Vertex3D
+	for i, a := range loop {
+		b := loop[(i+1)%len(loop)]
+		if a < 0 || b < 0 || int(a) >= len(obj.Vertices) || int(b) >= len(obj.Vertices) {
+			continue
+		}
+		va, vb := obj.Vertices[a], obj.Vertices[b]
+		n.X += (va.Y - vb.Y) * (va.Z + vb.Z)
+		n.Y += (va.Z - vb.Z) * (va.X + vb.X)
+		n.Z += (va.X - vb.X) * (va.Y + vb.Y)
+	}
+	return n
+}
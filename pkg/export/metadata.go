@@ -0,0 +1,116 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// metadata returns o with Author and Comment taken from the print settings
+// of the models where they are empty, with Windows line breaks in comments
+// made plain, and the author filled into the attribution. Models with
+// different authors or comments get all of them.
+func (o Options) metadata(models ...*pdo.PDO) Options {
+	var authors, comments []string
+	for _, p := range models {
+		if s := strings.TrimSpace(p.Settings.AuthorName); s != "" && !slices.Contains(authors, s) {
+			authors = append(authors, s)
+		}
+		if s := strings.TrimSpace(strings.ReplaceAll(p.Settings.Comment, "\r\n", "\n")); s != "" && !slices.Contains(comments, s) {
+			comments = append(comments, s)
+		}
+	}
+	if o.Author == "" {
+		o.Author = strings.Join(authors, ", ")
+	}
+	if o.Comment == "" {
+		o.Comment = strings.Join(comments, "\n")
+	}
+	if strings.Contains(o.Stamp.Attribution, "{author}") {
+		if o.Author == "" {
+			o.Stamp.Attribution = ""
+		} else {
+			o.Stamp.Attribution = strings.ReplaceAll(o.Stamp.Attribution, "{author}", singleLine(o.Author))
+		}
+	}
+	return o
+}
+
+// metadataLines returns the author, attribution and comment as lines for
+// the comments of text formats, continued comment lines indented.
+func (o Options) metadataLines() []string {
+	var lines []string
+	if o.Author != "" {
+		lines = append(lines, "Author: "+singleLine(o.Author))
+	}
+	if o.Stamp.Attribution != "" {
+		lines = append(lines, "Attribution: "+singleLine(o.Stamp.Attribution))
+	}
+	if o.Comment != "" {
+		for i, l := range strings.Split(o.Comment, "\n") {
+			if i == 0 {
+				lines = append(lines, "Comment: "+l)
+			} else {
+				lines = append(lines, "  "+l)
+			}
+		}
+	}
+	return lines
+}
+
+// writeMetadataComments writes the metadata lines as comments starting
+// with prefix.
+func writeMetadataComments(w io.Writer, prefix string, opts Options) {
+	for _, l := range opts.metadataLines() {
+		fmt.Fprintln(w, strings.TrimRight(prefix+l, " \t"))
+	}
+}
+
+// singleLine joins the lines of s with spaces.
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// writeXMLMetadata writes the author, attribution and comment as "author",
+// "rights" and "description" with format, which takes the name and the
+// escaped value.
+func writeXMLMetadata(w io.Writer, format string, opts Options) {
+	if opts.Author != "" {
+		fmt.Fprintf(w, format, "author", xmlEscape(opts.Author))
+	}
+	if opts.Stamp.Attribution != "" {
+		fmt.Fprintf(w, format, "rights", xmlEscape(opts.Stamp.Attribution))
+	}
+	if opts.Comment != "" {
+		fmt.Fprintf(w, format, "description", xmlEscape(opts.Comment))
+	}
+}
+
+// writeSVGMetadata writes the title, author, attribution and comment as
+// Dublin Core in the RDF form Inkscape reads, nothing when all are empty.
+func writeSVGMetadata(w io.Writer, opts Options) {
+	if opts.Title == "" && opts.Author == "" && opts.Comment == "" && opts.Stamp.Attribution == "" {
+		return
+	}
+	fmt.Fprintln(w, `<metadata>`)
+	fmt.Fprintln(w, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:cc="http://creativecommons.org/ns#">`)
+	fmt.Fprintln(w, `<cc:Work rdf:about="">`)
+	if opts.Title != "" {
+		fmt.Fprintf(w, "<dc:title>%s</dc:title>\n", xmlEscape(opts.Title))
+	}
+	if opts.Author != "" {
+		fmt.Fprintf(w, "<dc:creator><cc:Agent><dc:title>%s</dc:title></cc:Agent></dc:creator>\n", xmlEscape(opts.Author))
+	}
+	if opts.Stamp.Attribution != "" {
+		fmt.Fprintf(w, "<dc:rights><cc:Agent><dc:title>%s</dc:title></cc:Agent></dc:rights>\n", xmlEscape(opts.Stamp.Attribution))
+	}
+	if opts.Comment != "" {
+		fmt.Fprintf(w, "<dc:description>%s</dc:description>\n", xmlEscape(opts.Comment))
+	}
+	fmt.Fprintln(w, `</cc:Work>`)
+	fmt.Fprintln(w, `</rdf:RDF>`)
+	fmt.Fprintln(w, `</metadata>`)
+}
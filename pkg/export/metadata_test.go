@@ -0,0 +1,138 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestExportMetadata(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Settings.AuthorName = "Ana & Bo"
+	p.Settings.Comment = "Print on 160gsm\r\nGlue the base last"
+	dir := t.TempDir()
+
+	exports := map[string]struct {
+		export func(w *bytes.Buffer) error
+		want   []string
+	}{
+		"svg": {
+			func(w *bytes.Buffer) error { return ExportSVG(p, w, Options{Title: "Cone"}) },
+			[]string{"<dc:title>Cone</dc:title>", "<cc:Agent><dc:title>Ana &amp; Bo</dc:title></cc:Agent>", "<dc:description>Print on 160gsm&#xA;Glue the base last</dc:description>"},
+		},
+		"pdf": {
+			func(w *bytes.Buffer) error { return ExportPDF(p, w, Options{}) },
+			[]string{"/Author (\xfe\xff\x00A\x00n\x00a", "/Subject (\xfe\xff\x00P\x00r"},
+		},
+		"pdfa": {
+			func(w *bytes.Buffer) error { return ExportPDF(p, w, Options{PDFConformance: PDFA2B}) },
+			[]string{"/Author " + pdfString("Ana & Bo"), "<dc:creator><rdf:Seq><rdf:li>Ana &amp; Bo</rdf:li>", "<dc:description><rdf:Alt>"},
+		},
+		"eps": {
+			func(w *bytes.Buffer) error { return WriteEPSPage(p, w, 0, 0, Options{}) },
+			[]string{"%%EndComments\n% Author: Ana & Bo\n% Comment: Print on 160gsm\n%   Glue the base last\n"},
+		},
+		"dxf": {
+			func(w *bytes.Buffer) error { return ExportDXF(p, w, Options{}) },
+			[]string{"999\nAuthor: Ana & Bo\n999\nComment: Print on 160gsm\n999\n  Glue the base last\n0\nSECTION\n"},
+		},
+		"obj": {
+			func(w *bytes.Buffer) error { return ExportOBJ(p, w, filepath.Join(dir, "cone.obj"), Options{}) },
+			[]string{"# Author: Ana & Bo\n# Comment: Print on 160gsm\n#   Glue the base last\nmtllib "},
+		},
+		"scad": {
+			func(w *bytes.Buffer) error { return ExportSCAD(p, w, Options{}) },
+			[]string{"// Author: Ana & Bo\n"},
+		},
+		"vrml": {
+			func(w *bytes.Buffer) error { return ExportVRML(p, w, filepath.Join(dir, "cone.wrl"), Options{}) },
+			[]string{"# Author: Ana & Bo\n"},
+		},
+		"x3d": {
+			func(w *bytes.Buffer) error { return ExportX3D(p, w, filepath.Join(dir, "cone.x3d"), Options{}) },
+			[]string{`<meta name="author" content="Ana &amp; Bo"/>`, `<meta name="description" content="Print on 160gsm`},
+		},
+		"amf": {
+			func(w *bytes.Buffer) error { return ExportAMF(p, w, Options{}) },
+			[]string{`<metadata type="author">Ana &amp; Bo</metadata>`, `<metadata type="description">Print on 160gsm`},
+		},
+	}
+	for name, e := range exports {
+		var buf bytes.Buffer
+		if err := e.export(&buf); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, want := range e.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s output lacks %q", name, want)
+			}
+		}
+		if name == "svg" || name == "x3d" || name == "amf" {
+			if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+				t.Errorf("%s output is not well-formed: %v", name, err)
+			}
+		}
+	}
+
+	// Set options replace the model's.
+	var buf bytes.Buffer
+	if err := ExportSCAD(p, &buf, Options{Author: "Cy"}); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "// Author: Cy\n") || strings.Contains(s, "Ana") {
+		t.Errorf("author option not used:\n%s", s[:strings.Index(s, "union")])
+	}
+}
+
+func TestExportAttribution(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Stamp: Stamp{Attribution: "CC BY {author}"}}
+
+	// Without an author there is nothing to attribute.
+	var buf bytes.Buffer
+	if err := ExportSVG(p, &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "CC BY") {
+		t.Error("attribution stamped without an author")
+	}
+
+	p.Settings.AuthorName = "Ana"
+	buf.Reset()
+	if err := ExportSVG(p, &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	pages := len(layoutPages(p, p.PageDims()))
+	if n := strings.Count(buf.String(), `text-anchor:middle">CC BY Ana</text>`); n != pages {
+		t.Errorf("attribution stamped on %d pages, want %d", n, pages)
+	}
+	if !strings.Contains(buf.String(), "<dc:rights><cc:Agent><dc:title>CC BY Ana</dc:title>") {
+		t.Error("SVG metadata lacks the rights")
+	}
+
+	buf.Reset()
+	if err := ExportPDF(p, &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<dc:rights><rdf:Alt><rdf:li xml:lang="x-default">CC BY Ana</rdf:li>`) {
+		t.Error("PDF metadata lacks the rights")
+	}
+
+	buf.Reset()
+	if err := ExportOBJ(p, &buf, filepath.Join(t.TempDir(), "cone.obj"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "# Attribution: CC BY Ana\n") {
+		t.Error("OBJ header lacks the attribution")
+	}
+}
@@ -0,0 +1,160 @@
+package export
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// nameMapper turns model names into ASCII-safe identifiers for formats like
+// OBJ/MTL. Names are transliterated where possible (accents stripped,
+// full-width forms folded, kana romanized). Names that still lose characters
+// get a suffix derived from a hash of the original, so distinct Japanese names
+// don't all collapse into "____". The same original always maps to the same
+// safe name within one export.
+type nameMapper struct {
+	byOriginal map[string]string
+	taken      map[string]string
+	order      []string
+}
+
+func newNameMapper() *nameMapper {
+	return &nameMapper{
+		byOriginal: map[string]string{},
+		taken:      map[string]string{},
+	}
+}
+
+// safe returns the ASCII-safe name for orig.
+func (m *nameMapper) safe(orig string) string {
+	if s, ok := m.byOriginal[orig]; ok {
+		return s
+	}
+
+	translit := transliterate(orig)
+	s := sanitizeName(translit)
+	if s != translit || strings.Trim(s, "_") == "" {
+		// Characters were lost, disambiguate with a stable hash of the original.
+		h := fnv.New32a()
+		h.Write([]byte(orig))
+		s = fmt.Sprintf("%s_%08x", strings.Trim(s, "_"), h.Sum32())
+		s = strings.TrimPrefix(s, "_")
+	}
+	for i := 2; ; i++ {
+		other, clash := m.taken[s]
+		if !clash || other == orig {
+			break
+		}
+		s = fmt.Sprintf("%s_%d", strings.TrimSuffix(s, fmt.Sprintf("_%d", i-1)), i)
+	}
+
+	m.byOriginal[orig] = s
+	m.taken[s] = orig
+	m.order = append(m.order, orig)
+	return s
+}
+
+// renamed returns the original names whose safe name differs, in first-use order.
+func (m *nameMapper) renamed() []string {
+	var names []string
+	for _, orig := range m.order {
+		if m.byOriginal[orig] != orig {
+			names = append(names, orig)
+		}
+	}
+	return names
+}
+
+// transliterate approximates a name in ASCII: compatibility composition folds
+// full-width letters, accents are stripped and kana is romanized.
+// Characters without a transliteration (e.g. kanji) are kept as is.
+func transliterate(s string) string {
+	var b strings.Builder
+	runes := []rune(norm.NFKC.String(s))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i] - kanaOffset(runes[i])
+
+		switch {
+		case r == 'っ':
+			// Small tsu doubles the following consonant.
+			if i+1 < len(runes) {
+				if rom, ok := kanaRomaji[runes[i+1]-kanaOffset(runes[i+1])]; ok {
+					b.WriteByte(rom[0])
+				}
+			}
+		case r == 'ー':
+			// Long vowel mark, repeat the previous vowel.
+			out := b.String()
+			if len(out) > 0 {
+				b.WriteByte(out[len(out)-1])
+			}
+		default:
+			rom, ok := kanaRomaji[r]
+			if !ok {
+				// Strip accents: decompose and drop combining marks.
+				for _, d := range norm.NFD.String(string(runes[i])) {
+					if !unicode.Is(unicode.Mn, d) {
+						b.WriteRune(d)
+					}
+				}
+				continue
+			}
+			// Contracted sounds: き + ゃ -> kya, し + ゃ -> sha, ジ + ェ -> je
+			if i+1 < len(runes) && len(rom) > 1 {
+				next := runes[i+1] - kanaOffset(runes[i+1])
+				base := rom[:len(rom)-1]
+				if y, ok := smallY[next]; ok && strings.HasSuffix(rom, "i") {
+					if base == "sh" || base == "ch" || base == "j" {
+						y = y[1:]
+					}
+					b.WriteString(base + y)
+					i++
+					continue
+				}
+				if v, ok := smallVowel[next]; ok {
+					b.WriteString(base + v)
+					i++
+					continue
+				}
+			}
+			b.WriteString(rom)
+		}
+	}
+	return b.String()
+}
+
+// kanaOffset returns the distance of r from its hiragana form.
+func kanaOffset(r rune) rune {
+	if r >= 0x30A1 && r <= 0x30F6 { // Katakana
+		return 0x60
+	}
+	return 0
+}
+
+var smallY = map[rune]string{'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo"}
+
+var smallVowel = map[rune]string{'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o"}
+
+var kanaRomaji = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "wi", 'ゑ': "we", 'を': "wo", 'ん': "n",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo", 'ゎ': "wa", 'ゔ': "vu",
+	'・': "_",
+}
@@ -0,0 +1,32 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNameMapper(t *testing.T) {
+	m := newNameMapper()
+
+	tests := map[string]string{
+		"オブジェクト":   "obujekuto",
+		"しゃしん":     "shashin",
+		"きって":      "kitte",
+		"Café":     "Cafe",
+		"ＡＢＣ":      "ABC",
+		"plain_01": "plain_01",
+	}
+	for in, want := range tests {
+		if got := m.safe(in); got != want {
+			t.Errorf("safe(%q) got %q, want %q", in, got, want)
+		}
+	}
+
+	a, b := m.safe("材質"), m.safe("素材")
+	if a == b || strings.Trim(a, "_") == "" {
+		t.Errorf("kanji names should stay distinguishable, got %q and %q", a, b)
+	}
+	if m.safe("材質") != a {
+		t.Errorf("mapping is not stable for repeated names")
+	}
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// smoothNormals computes a normal for every face corner by averaging the
+// normals of the faces sharing that corner's 3D vertex, as long as the angle
+// between the face normals is at most maxAngle degrees. Faces meeting at
+// sharper angles keep a hard edge.
+func smoothNormals(obj pdo.Object, maxAngle float64) [][]pdo.Vertex3D {
+	faceNormals := make([]pdo.Vertex3D, len(obj.Faces))
+	vertexFaces := make(map[int32][]int)
+	for fi, face := range obj.Faces {
+		faceNormals[fi] = normalize(pdo.Vertex3D{X: face.Nx, Y: face.Ny, Z: face.Nz})
+		for _, fv := range face.Vertices {
+			vertexFaces[fv.IDVertex] = append(vertexFaces[fv.IDVertex], fi)
+		}
+	}
+
+	cosLimit := math.Cos(maxAngle * math.Pi / 180)
+	normals := make([][]pdo.Vertex3D, len(obj.Faces))
+	for fi, face := range obj.Faces {
+		n := faceNormals[fi]
+		normals[fi] = make([]pdo.Vertex3D, len(face.Vertices))
+		for ci, fv := range face.Vertices {
+			var sum pdo.Vertex3D
+			for _, other := range vertexFaces[fv.IDVertex] {
+				on := faceNormals[other]
+				if dot(n, on) < cosLimit {
+					continue
+				}
+				// Weight by face area so small slivers don't skew the result.
+				area := obj.FaceArea3D(other)
+				sum.X += on.X * area
+				sum.Y += on.Y * area
+				sum.Z += on.Z * area
+			}
+			if sum == (pdo.Vertex3D{}) {
+				sum = n
+			}
+			normals[fi][ci] = normalize(sum)
+		}
+	}
+	return normals
+}
+
+func dot(a, b pdo.Vertex3D) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func normalize(v pdo.Vertex3D) pdo.Vertex3D {
+	l := math.Sqrt(dot(v, v))
+	if l == 0 {
+		return v
+	}
+	return pdo.Vertex3D{X: v.X / l, Y: v.Y / l, Z: v.Z / l}
+}
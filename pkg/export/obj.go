@@ -1,9 +1,11 @@
 package export
 
 import (
+	"context"
 	"fmt"
 	"image/png"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,25 +16,32 @@ import (
 // ExportOBJ exports the PDO model to Wavefront OBJ format.
 // It writes the OBJ data to w, and creates an MTL file (and textures)
 // using objPath as the base path.
-func ExportOBJ(p *pdo.PDO, w io.Writer, objPath string) error {
+func ExportOBJ(p *pdo.PDO, w io.Writer, objPath string, opts Options) error {
 	baseName := filepath.Base(objPath)
 	mtlFileName := strings.TrimSuffix(baseName, filepath.Ext(baseName)) + ".mtl"
 	mtlPath := filepath.Join(filepath.Dir(objPath), mtlFileName)
 
 	// Write Header
 	fmt.Fprintln(w, "# Exported by pdo-tools")
+	writeMetadataComments(w, "# ", opts.metadata(p))
 	fmt.Fprintf(w, "mtllib %s\n", mtlFileName)
 
+	names := newNameMapper()
+	place := opts.placement(p, true)
+
 	// Global indices for OBJ (1-based)
 	vOffset := 1
 	vtOffset := 1
 	vnOffset := 1
 
 	for objIdx, obj := range p.Objects {
-		fmt.Fprintf(w, "\no %s_%d\n", sanitizeName(obj.Name), objIdx)
+		objName := names.safe(obj.Name)
+		fmt.Fprintln(w)
+		writeNameComment(w, obj.Name, objName)
+		fmt.Fprintf(w, "o %s_%d\n", objName, objIdx)
 
 		// 1. Write Vertices
-		for _, v := range obj.Vertices {
+		for _, v := range place.vertices(obj) {
 			// PDO Z is typically up, or Y is up?
 			// Looking at types.go: X, Y, Z float64
 			// Let's dump as is.
@@ -62,14 +71,55 @@ func ExportOBJ(p *pdo.PDO, w io.Writer, objPath string) error {
 		objVTs := 0
 		objVNs := 0
 
-		// Buffer faces to write them after attributes
-		var faceBuffer strings.Builder
+		// Buffer faces to write them after attributes.
+		// Faces are bucketed per part when grouping by part.
+		var groupOrder []int32
+		groupBuffers := map[int32]*strings.Builder{}
+
+		// With smoothing, corners get shared vertex normals, written once per object.
+		var cornerNormals [][]pdo.Vertex3D
+		normalIndex := map[pdo.Vertex3D]int{}
+		if opts.SmoothAngle > 0 {
+			cornerNormals = smoothNormals(obj, opts.SmoothAngle)
+		}
 
-		for _, face := range obj.Faces {
-			// Write Normal
-			fmt.Fprintf(w, "vn %f %f %f\n", face.Nx, face.Ny, face.Nz)
-			currentVN := vnOffset + objVNs
-			objVNs++
+		for faceIdx, face := range obj.Faces {
+			groupKey := int32(-1)
+			if opts.Grouping == GroupByPart {
+				groupKey = face.PartIndex
+			}
+			faceBuffer, ok := groupBuffers[groupKey]
+			if !ok {
+				faceBuffer = &strings.Builder{}
+				groupBuffers[groupKey] = faceBuffer
+				groupOrder = append(groupOrder, groupKey)
+				if opts.SmoothAngle > 0 {
+					fmt.Fprintln(faceBuffer, "s 1")
+				}
+			}
+
+			// Write Normals
+			currentVNs := make([]int, len(face.Vertices))
+			if cornerNormals != nil {
+				for i, n := range cornerNormals[faceIdx] {
+					idx, ok := normalIndex[n]
+					if !ok {
+						rn := place.normal(n)
+						fmt.Fprintf(w, "vn %f %f %f\n", rn.X, rn.Y, rn.Z)
+						idx = vnOffset + objVNs
+						objVNs++
+						normalIndex[n] = idx
+					}
+					currentVNs[i] = idx
+				}
+			} else {
+				n := place.normal(pdo.Vertex3D{X: face.Nx, Y: face.Ny, Z: face.Nz})
+				fmt.Fprintf(w, "vn %f %f %f\n", n.X, n.Y, n.Z)
+				for i := range currentVNs {
+					currentVNs[i] = vnOffset + objVNs // Flat shading, all verts in face share normal
+				}
+				objVNs++
+			}
 
 			// Write UVs
 			// Face has Vertices which are Face2DVertex, containing U, V
@@ -89,11 +139,11 @@ func ExportOBJ(p *pdo.PDO, w io.Writer, objPath string) error {
 				if matName == "" {
 					matName = fmt.Sprintf("Material_%d", face.MaterialIndex)
 				}
-				fmt.Fprintf(&faceBuffer, "usemtl %s\n", sanitizeName(matName))
+				fmt.Fprintf(faceBuffer, "usemtl %s\n", names.safe(matName))
 			}
 
 			// Face definition
-			fmt.Fprintf(&faceBuffer, "f")
+			fmt.Fprintf(faceBuffer, "f")
 			for i, fv := range face.Vertices {
 				// f v/vt/vn
 				// v index: fv.IDVertex is the 0-based index in obj.Vertices
@@ -101,15 +151,22 @@ func ExportOBJ(p *pdo.PDO, w io.Writer, objPath string) error {
 				// global index = vOffset + fv.IDVertex
 				vIdx := vOffset + int(fv.IDVertex)
 				vtIdx := currentFaceVTIndices[i]
-				vnIdx := currentVN // Flat shading, all verts in face share normal
+				vnIdx := currentVNs[i]
 
-				fmt.Fprintf(&faceBuffer, " %d/%d/%d", vIdx, vtIdx, vnIdx)
+				fmt.Fprintf(faceBuffer, " %d/%d/%d", vIdx, vtIdx, vnIdx)
 			}
-			fmt.Fprintf(&faceBuffer, "\n")
+			fmt.Fprintf(faceBuffer, "\n")
 		}
 
 		// Flush faces
-		fmt.Fprint(w, faceBuffer.String())
+		for _, key := range groupOrder {
+			if opts.Grouping == GroupByPart {
+				groupName := partGroupName(p, key)
+				writeNameComment(w, groupName, names.safe(groupName))
+				fmt.Fprintf(w, "g %s\n", names.safe(groupName))
+			}
+			fmt.Fprint(w, groupBuffers[key].String())
+		}
 
 		// Update global offsets
 		vOffset += len(obj.Vertices)
@@ -118,14 +175,22 @@ func ExportOBJ(p *pdo.PDO, w io.Writer, objPath string) error {
 	}
 
 	// Generate MTL
-	if err := generateMTL(p, mtlPath); err != nil {
+	if err := generateMTL(p, mtlPath, names, !opts.texturesLocked(p), opts.logger()); err != nil {
 		return fmt.Errorf("failed to generate material library: %w", err)
 	}
 
+	if opts.NameMap != nil {
+		for _, orig := range names.renamed() {
+			fmt.Fprintf(opts.NameMap, "%s\t%s\n", orig, names.safe(orig))
+		}
+	}
+
 	return nil
 }
 
-func generateMTL(p *pdo.PDO, mtlPath string) error {
+// generateMTL writes the material library, with the textures as PNG files
+// next to it when textures is set.
+func generateMTL(p *pdo.PDO, mtlPath string, names *nameMapper, textures bool, log *slog.Logger) error {
 	f, err := os.Create(mtlPath)
 	if err != nil {
 		return err
@@ -139,75 +204,62 @@ func generateMTL(p *pdo.PDO, mtlPath string) error {
 		if matName == "" {
 			matName = fmt.Sprintf("Material_%d", i)
 		}
-		fmt.Fprintf(f, "\nnewmtl %s\n", sanitizeName(matName))
-
-		// Diffuse color from 3D Color (RGBA)
-		// Color3D is [16]float32, 4x4 matrix? No, spec says:
-		// 4*4B : 3D material color RGBA - float 4B
-		// Wait, types.go says: Color3D [16]float32
-		// Spec says:
-		// 166:   4*4B : material color RGBA
-		// 167:   4*4B : 3D material color RGBA
-		// 168:   4*4B : light color RGBA
-		// 169:   4*4B : diffuse color RGBA
-		// 170:   4*4B : 2D material color ARGB
-
-		// types.go has:
-		// Color3D     [16]float32 // 4*4 float32 ??
-		// That seems to map to multiple color fields in the spec?
-		// Let's assume indices 0-3 are one color, 4-7 another, etc.
-		// Spec:
-		// 1. material color (Ambient?)
-		// 2. 3D material color (Diffuse?)
-		// 3. light color (Specular?)
-		// 4. diffuse color (?)
-
-		// If types.go treats them as one array [16], then:
-		// 0..3: Material Color
-		// 4..7: 3D Material Color
-		// 8..11: Light Color
-		// 12..15: Diffuse Color
-
-		// Let's pick 4..7 (3D Material Color) for Kd (Diffuse)
-		r := mat.Color3D[4]
-		g := mat.Color3D[5]
-		b := mat.Color3D[6]
-		// a := mat.Color3D[7]
-
-		fmt.Fprintf(f, "Kd %f %f %f\n", r, g, b)
-		// Ka (Ambient) - let's use 0..3
-		fmt.Fprintf(f, "Ka %f %f %f\n", mat.Color3D[0], mat.Color3D[1], mat.Color3D[2])
-		// Ks (Specular) - let's use 8..11
-		fmt.Fprintf(f, "Ks %f %f %f\n", mat.Color3D[8], mat.Color3D[9], mat.Color3D[10])
+		fmt.Fprintln(f)
+		writeNameComment(f, matName, names.safe(matName))
+		fmt.Fprintf(f, "newmtl %s\n", names.safe(matName))
 
-		// Texture map
-		if mat.HasTexture {
-			// Extract texture to file
-			img, err := mat.Texture.GetImage()
-			if err != nil {
-				// Warn but continue?
-				fmt.Printf("Warning: failed to decode texture for material %s: %v\n", matName, err)
-			} else {
-				texFileName := fmt.Sprintf("%s_tex%d.png", strings.TrimSuffix(filepath.Base(mtlPath), ".mtl"), i)
-				texPath := filepath.Join(filepath.Dir(mtlPath), texFileName)
-
-				texFile, err := os.Create(texPath)
-				if err != nil {
-					fmt.Printf("Warning: failed to create texture file %s: %v\n", texPath, err)
-				} else {
-					if err := png.Encode(texFile, img); err != nil {
-						fmt.Printf("Warning: failed to encode texture %s: %v\n", texFileName, err)
-					}
-					texFile.Close()
+		c := mat.Color3D
+		fmt.Fprintf(f, "Kd %f %f %f\n", c.Model.R, c.Model.G, c.Model.B)
+		fmt.Fprintf(f, "Ka %f %f %f\n", c.Material.R, c.Material.G, c.Material.B)
+		fmt.Fprintf(f, "Ks %f %f %f\n", c.Light.R, c.Light.G, c.Light.B)
 
-					fmt.Fprintf(f, "map_Kd %s\n", texFileName)
-				}
+		// Texture map
+		if mat.HasTexture && textures {
+			texFileName := fmt.Sprintf("%s_tex%d.png", strings.TrimSuffix(filepath.Base(mtlPath), ".mtl"), i)
+			if writeTexturePNG(&mat, matName, filepath.Join(filepath.Dir(mtlPath), texFileName), log) {
+				fmt.Fprintf(f, "map_Kd %s\n", texFileName)
 			}
 		}
 	}
 	return nil
 }
 
+// writeTexturePNG saves the texture of a material as a PNG file, logging
+// failures as warnings. It reports whether the file was written.
+func writeTexturePNG(mat *pdo.Material, name, path string, log *slog.Logger) bool {
+	img, err := mat.Texture.GetImage()
+	if err != nil {
+		log.Warn("failed to decode texture", "material", name, "err", err)
+		return false
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		log.Warn("failed to create texture file", "path", path, "err", err)
+		return false
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		log.Warn("failed to encode texture", "path", path, "err", err)
+		return false
+	}
+	return true
+}
+
+// partGroupName returns the name used for the OBJ group of a part.
+func partGroupName(p *pdo.PDO, partIdx int32) string {
+	if partIdx >= 0 && int(partIdx) < len(p.Parts) && p.Parts[partIdx].Name != "" {
+		return fmt.Sprintf("%s_%d", p.Parts[partIdx].Name, partIdx)
+	}
+	return fmt.Sprintf("part_%d", partIdx)
+}
+
+// writeNameComment records the original name when it had to be changed.
+func writeNameComment(w io.Writer, original, safe string) {
+	if original != safe {
+		fmt.Fprintf(w, "# name: %s\n", original)
+	}
+}
+
 func sanitizeName(s string) string {
 	return strings.Map(func(r rune) rune {
 		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
@@ -216,3 +268,22 @@ func sanitizeName(s string) string {
 		return '_'
 	}, s)
 }
+
+type objExporter struct{}
+
+func (objExporter) Name() string         { return "obj" }
+func (objExporter) Extensions() []string { return []string{".obj"} }
+func (objExporter) Mesh() bool           { return true }
+func (objExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.Path == "" {
+		return fmt.Errorf("obj export needs an output path for the material library")
+	}
+	return ExportOBJ(p, t.W, t.Path, opts)
+}
+
+func init() {
+	Register(objExporter{})
+}
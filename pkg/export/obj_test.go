@@ -0,0 +1,148 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestExportOBJ_Logger(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{Texture: true})
+	p.Materials[0].Texture.RawData = []byte("not zlib")
+	dir := t.TempDir()
+	var w Warnings
+	var buf bytes.Buffer
+	if err := ExportOBJ(p, &buf, filepath.Join(dir, "cube.obj"), Options{Logger: slog.New(w.Handler(slog.DiscardHandler))}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The undecodable texture is reported to the logger and left out.
+	list := w.List()
+	if len(list) != 1 || list[0].Message != "failed to decode texture" || list[0].Attrs["material"] != "paper" {
+		t.Fatalf("warnings %v, want one for the texture of paper", list)
+	}
+	mtl, err := os.ReadFile(filepath.Join(dir, "cube.mtl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(mtl), "map_Kd") {
+		t.Error("material library references the undecodable texture")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.png")); len(files) != 0 {
+		t.Errorf("texture files %v written", files)
+	}
+	if !slices.Contains(strings.Split(buf.String(), "\n"), "usemtl paper") {
+		t.Error("faces lost their material")
+	}
+}
+
+func TestExportOBJ_GroupByPart(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{Pages: 2})
+	p.Objects[0].Name, p.Objects[1].Name = "a", "b"
+	p.Parts[0].Name, p.Parts[1].Name = "left", "right"
+	// Half of the first cube is unfolded with the second one.
+	for i := 3; i < len(p.Objects[0].Faces); i++ {
+		p.Objects[0].Faces[i].PartIndex = 1
+	}
+
+	// groups returns the groups of each object with their face counts, in
+	// the order written.
+	groups := func(opts Options) []string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := ExportOBJ(p, &buf, filepath.Join(t.TempDir(), "cube.obj"), opts); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		faces := 0
+		flush := func() {
+			if len(got) > 0 {
+				got[len(got)-1] += fmt.Sprintf(":%d", faces)
+			}
+			faces = 0
+		}
+		for _, l := range strings.Split(buf.String(), "\n") {
+			switch {
+			case strings.HasPrefix(l, "o "), strings.HasPrefix(l, "g "):
+				flush()
+				got = append(got, l)
+			case strings.HasPrefix(l, "f "):
+				faces++
+			}
+		}
+		flush()
+		return got
+	}
+
+	if got, want := groups(Options{}), []string{"o a_0:6", "o b_1:6"}; !slices.Equal(got, want) {
+		t.Errorf("grouped by object %q, want %q", got, want)
+	}
+	want := []string{"o a_0:0", "g left_0:3", "g right_1:3", "o b_1:0", "g right_1:6"}
+	if got := groups(Options{Grouping: GroupByPart}); !slices.Equal(got, want) {
+		t.Errorf("grouped by part %q, want %q", got, want)
+	}
+
+	for _, tt := range []struct {
+		name string
+		want Grouping
+	}{{"", GroupByObject}, {"object", GroupByObject}, {"part", GroupByPart}} {
+		if got, err := ParseGrouping(tt.name); err != nil || got != tt.want {
+			t.Errorf("ParseGrouping(%q) = %v, %v", tt.name, got, err)
+		}
+	}
+	if _, err := ParseGrouping("material"); err == nil {
+		t.Error("ParseGrouping accepted an unknown grouping")
+	}
+}
+
+func TestExportOBJ_SmoothAngle(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{})
+
+	// normals returns the vertex normals and whether smoothing is on.
+	normals := func(angle float64) ([][3]float64, bool) {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := ExportOBJ(p, &buf, filepath.Join(t.TempDir(), "cube.obj"), Options{SmoothAngle: angle}); err != nil {
+			t.Fatal(err)
+		}
+		var vn [][3]float64
+		for _, l := range strings.Split(buf.String(), "\n") {
+			var n [3]float64
+			if c, _ := fmt.Sscanf(l, "vn %g %g %g", &n[0], &n[1], &n[2]); c == 3 {
+				vn = append(vn, n)
+			}
+		}
+		return vn, strings.Contains(buf.String(), "\ns 1\n")
+	}
+
+	// Flat shading writes the face normals, as do angles below the 90
+	// degrees between cube faces.
+	for _, angle := range []float64{0, 30} {
+		vn, smooth := normals(angle)
+		if len(vn) != 6 || smooth != (angle > 0) {
+			t.Errorf("angle %g: %d normals, smoothing group %v", angle, len(vn), smooth)
+		}
+	}
+	// Wider angles share one normal per corner, pointing away from the
+	// three faces equally.
+	vn, smooth := normals(100)
+	if len(vn) != 8 || !smooth {
+		t.Fatalf("angle 100: %d normals, smoothing group %v, want one per corner", len(vn), smooth)
+	}
+	for _, n := range vn {
+		for _, c := range n {
+			if math.Abs(math.Abs(c)-1/math.Sqrt(3)) > 1e-6 {
+				t.Errorf("corner normal %v, want a unit diagonal", n)
+				break
+			}
+		}
+	}
+}
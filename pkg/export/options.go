@@ -0,0 +1,181 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Grouping selects how 3D exporters split the mesh into groups.
+type Grouping int
+
+const (
+	// GroupByObject emits one group per PDO object.
+	GroupByObject Grouping = iota
+	// GroupByPart emits one group per unfolded part, using Face.PartIndex.
+	GroupByPart
+)
+
+// ParseGrouping converts a grouping name ("object", "part") into a Grouping.
+func ParseGrouping(s string) (Grouping, error) {
+	switch s {
+	case "object", "":
+		return GroupByObject, nil
+	case "part":
+		return GroupByPart, nil
+	}
+	return GroupByObject, fmt.Errorf("unknown grouping %q", s)
+}
+
+// DefaultPrecision is the default number of decimal places of SVG
+// coordinates, a micrometre.
+const DefaultPrecision = 3
+
+// Options holds settings shared by all exporters.
+type Options struct {
+	// Logger receives non-fatal warnings. slog.Default() is used when nil.
+	Logger *slog.Logger
+	// Grouping controls how 3D formats group faces.
+	Grouping Grouping
+	// SmoothAngle enables smooth shading in 3D formats: faces meeting at an
+	// angle of at most SmoothAngle degrees share vertex normals. 0 keeps flat normals.
+	SmoothAngle float64
+	// NameMap, when set, receives a tab-separated "original<TAB>exported" line
+	// for every name that had to be transliterated or made unique.
+	NameMap io.Writer
+	// Imposition arranges template pages on PDF sheets.
+	Imposition Imposition
+	// FlapStyle selects the glue flap shape in 2D formats.
+	FlapStyle FlapStyle
+	// TextToPath draws text blocks as outlines from an embedded font instead
+	// of SVG text, so they look the same without the font installed and can
+	// be cut or engraved. Lines with characters the font lacks stay text.
+	TextToPath bool
+	// MaterialLayers places the lines of each material on a layer of its own
+	// in SVG, to cut multi-color builds one cardstock color at a time.
+	MaterialLayers bool
+	// PartColoring tints each part with a palette color and adds a legend
+	// in 2D formats, to tell parts apart when test-building.
+	PartColoring PartColoring
+	// PageFrames lays out SVG content on a grid of printed pages, with the
+	// page outlines and margins drawn on a layer beneath.
+	PageFrames bool
+	// Precision is the number of decimal places of SVG coordinates, 0 uses
+	// DefaultPrecision.
+	Precision int
+	// CompactPaths joins the lines of each part into one SVG path per line
+	// type with relative commands, which shrinks dense models considerably.
+	CompactPaths bool
+	// PDFConformance selects PDF/A or PDF/X output.
+	PDFConformance PDFConformance
+	// OutputProfile is an ICC profile for the PDF output intent. PDF/A uses
+	// a built-in sRGB profile when it is nil, PDF/X needs a printer profile.
+	// Plain PDFs get an output intent only when it is set.
+	OutputProfile []byte
+	// PDFBackend creates PDF documents, FPDFBackend when nil.
+	PDFBackend PDFBackend
+	// Textures draws the texture of textured faces beneath the lines in PDF
+	// and SVG.
+	Textures bool
+	// FaceFill chooses between textures and flat material colors for
+	// textured output, by default as set in the file.
+	FaceFill FaceFill
+	// CMYK embeds PDF textures as CMYK images, so the print service doesn't
+	// separate them with settings of its own. The conversion is a plain one,
+	// OutputProfile tells the printer which CMYK space the values are meant for.
+	CMYK bool
+	// Dither reduces PDF textures to a few levels per channel.
+	Dither Dither
+	// Title is the document title recorded in PDF and SVG metadata.
+	Title string
+	// Author and Comment are recorded in the metadata or header comments
+	// of every format. Empty ones take the author name and comment of the
+	// model's print settings.
+	Author  string
+	Comment string
+	// Stamp adds footer text and a QR code to each page of 2D formats.
+	Stamp Stamp
+	// Watermark lays text or an image across each page of PDF and SVG.
+	Watermark Watermark
+	// Units is the length unit of SVG sizes and 3D coordinates. PDF and
+	// EPS pages have their physical size whatever the unit.
+	Units Units
+	// Guides draws the paper color, margins and a grid on 2D formats.
+	Guides Guides
+	// OutlineOffset draws the outline of each part this many mm outside
+	// it, for weeding borders and kiss-cut stickers. Files with the
+	// AddOutlinePadding setting get DefaultOutlinePadding when it is 0.
+	OutlineOffset float64
+	// Perforation cuts fold lines as dashes or ticks in 2D formats.
+	Perforation Perforation
+	// EdgeLines draws 2D formats with lines derived from the edges of the
+	// model instead of the stored line lists, to recover files whose lists
+	// are damaged or missing. See pdo.PDO.EdgeLines.
+	EdgeLines bool
+	// FlipY mirrors the layout vertically in SVG and DXF, for files and
+	// programs that expect the other Y convention. Without it SVG keeps
+	// the Y axis of PDO files, which points down, and DXF points it up.
+	FlipY bool
+	// Duplicates draws parts of the same shape as instances of one, or
+	// once with a count, in SVG without MaterialLayers.
+	Duplicates Duplicates
+	// Scoring sets the tool, pressure and passes of cut and fold lines in
+	// DXF.
+	Scoring Scoring
+	// Poster splits parts larger than a page across several pages in PDF
+	// and PNG output.
+	Poster Poster
+	// Placement moves the model in 3D formats.
+	Placement Placement
+	// IgnoreTextureLock writes the textures of files whose texture lock
+	// forbids taking them out next to OBJ, X3D and VRML models. Without it
+	// they are left out with a warning, see pdo.PDO.CheckTextureLock.
+	IgnoreTextureLock bool
+	// Workers is the number of goroutines preparing PDF textures and part
+	// drawings before the pages are written in order, runtime.NumCPU()
+	// when 0.
+	Workers int
+}
+
+func (o Options) pdfBackend() PDFBackend {
+	if o.PDFBackend != nil {
+		return o.PDFBackend
+	}
+	return FPDFBackend{}
+}
+
+// lines returns the PDO 2D formats draw: p, or with EdgeLines a copy whose
+// parts have lines rebuilt from the edges.
+func (o Options) lines(p *pdo.PDO) *pdo.PDO {
+	if !o.EdgeLines {
+		return p
+	}
+	c := *p
+	c.Parts = slices.Clone(p.Parts)
+	c.RebuildLines()
+	return &c
+}
+
+// texturesLocked reports whether the texture lock of p keeps its textures
+// out of 3D formats, warning when it leaves any out.
+func (o Options) texturesLocked(p *pdo.PDO) bool {
+	err := p.CheckTextureLock()
+	if err == nil || o.IgnoreTextureLock {
+		return false
+	}
+	for range p.TexturedMaterials() {
+		o.logger().Warn("leaving the textures out", "err", err)
+		break
+	}
+	return true
+}
+
+func (o Options) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,269 @@
+package export
+
+import (
+	"iter"
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// DefaultOutlinePadding is the outline offset in mm used for files with the
+// AddOutlinePadding setting when no offset is given.
+const DefaultOutlinePadding = 1.0
+
+// outlineArcStep is the largest angle between the points of the rounded
+// corners of an offset outline, in radians.
+const outlineArcStep = math.Pi / 12
+
+// outlineOffset returns the distance in mm to draw part outlines around
+// the parts, 0 for none.
+func (o Options) outlineOffset(s pdo.Settings) float64 {
+	if o.OutlineOffset > 0 {
+		return o.OutlineOffset
+	}
+	if s.AddOutlinePadding != 0 {
+		return DefaultOutlinePadding
+	}
+	return 0
+}
+
+// templateLines yields the lines 2D formats draw for a part: its lines
+// with fold lines perforated as set, followed by its outline offset by d
+// mm, see partOutline.
+func templateLines(p *pdo.PDO, lk *pdo.Lookup, part *pdo.Part, style FlapStyle, d float64, perf Perforation) iter.Seq[partLine] {
+	return func(yield func(partLine) bool) {
+		for l := range partLines(p, lk, part, style) {
+			if perf.Mode != PerforateOff && (l.Type == lineMountain || l.Type == lineValley) {
+				for _, cut := range perf.cuts(l) {
+					if !yield(cut) {
+						return
+					}
+				}
+				continue
+			}
+			if !yield(l) {
+				return
+			}
+		}
+		for _, l := range partOutline(p, lk, part, style, d) {
+			if !yield(l) {
+				return
+			}
+		}
+	}
+}
+
+// partOutline returns the outline of a part, with its flaps, offset away
+// from the paper by d mm, as cut lines in part coordinates. Outer
+// outlines grow with rounded corners and holes shrink. Cut lines that
+// don't form closed loops, like slits, are left out.
+func partOutline(p *pdo.PDO, lk *pdo.Lookup, part *pdo.Part, style FlapStyle, d float64) []partLine {
+	if d <= 0 {
+		return nil
+	}
+	var segs [][2][2]float64
+	for l := range partLines(p, lk, part, style) {
+		if l.Outline {
+			segs = append(segs, [2][2]float64{{l.X1, l.Y1}, {l.X2, l.Y2}})
+		}
+	}
+	loops := closedLoops(segs)
+
+	var lines []partLine
+	for i, loop := range loops {
+		// Loops inside an odd number of others are holes.
+		hole := false
+		for j, other := range loops {
+			if j != i && insidePolygon(other, loop[0]) {
+				hole = !hole
+			}
+		}
+		// Outer loops run counterclockwise, holes clockwise, so the paper
+		// is on the left of every edge.
+		if (polygonArea(loop) > 0) == hole {
+			for a, b := 0, len(loop)-1; a < b; a, b = a+1, b-1 {
+				loop[a], loop[b] = loop[b], loop[a]
+			}
+		}
+		off := untangle(offsetPolygon(loop, d))
+		for k, a := range off {
+			b := off[(k+1)%len(off)]
+			lines = append(lines, partLine{X1: a[0], Y1: a[1], X2: b[0], Y2: b[1], Type: lineCut, Material: -1})
+		}
+	}
+	return lines
+}
+
+// closedLoops joins segments sharing end points into closed polygons.
+// Segments left over from open chains are dropped.
+func closedLoops(segs [][2][2]float64) [][][2]float64 {
+	type key [2]int64
+	at := func(v [2]float64) key {
+		return key{int64(math.Round(v[0] * 1e4)), int64(math.Round(v[1] * 1e4))}
+	}
+	ends := map[key][]int{}
+	for i, s := range segs {
+		if at(s[0]) == at(s[1]) {
+			continue
+		}
+		ends[at(s[0])] = append(ends[at(s[0])], i)
+		ends[at(s[1])] = append(ends[at(s[1])], i)
+	}
+	used := make([]bool, len(segs))
+	var loops [][][2]float64
+	for i, s := range segs {
+		if used[i] || at(s[0]) == at(s[1]) {
+			continue
+		}
+		used[i] = true
+		start, cur := at(s[0]), s[1]
+		loop := [][2]float64{s[0]}
+		for at(cur) != start {
+			next := -1
+			for _, j := range ends[at(cur)] {
+				if !used[j] {
+					next = j
+					break
+				}
+			}
+			if next < 0 {
+				loop = nil // Open chain
+				break
+			}
+			used[next] = true
+			loop = append(loop, cur)
+			if n := segs[next]; at(n[0]) == at(cur) {
+				cur = n[1]
+			} else {
+				cur = n[0]
+			}
+		}
+		if len(loop) >= 3 {
+			loops = append(loops, loop)
+		}
+	}
+	return loops
+}
+
+// polygonArea returns the signed area of a polygon, positive when it runs
+// counterclockwise with the y axis pointing up.
+func polygonArea(poly [][2]float64) float64 {
+	area := 0.0
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	return area / 2
+}
+
+// insidePolygon reports whether a point lies inside a polygon.
+func insidePolygon(poly [][2]float64, v [2]float64) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a[1] > v[1]) != (b[1] > v[1]) && v[0] < (b[0]-a[0])*(v[1]-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// untangle removes the loops a polygon forms where it crosses itself, as
+// offsetting does at concave corners with short edges. Of the two pieces a
+// crossing splits the polygon into, the larger one turning the same way as
+// the whole polygon is kept.
+func untangle(poly [][2]float64) [][2]float64 {
+	from := 0
+	for {
+		i, j, x, ok := firstCrossing(poly, from)
+		if !ok {
+			return poly
+		}
+		inner := append([][2]float64{x}, poly[i+1:j+1]...)
+		outer := append(append(append([][2]float64{}, poly[:i+1]...), x), poly[j+1:]...)
+		sign := math.Signbit(polygonArea(poly))
+		innerArea, outerArea := polygonArea(inner), polygonArea(outer)
+		keepInner := math.Signbit(innerArea) == sign &&
+			(math.Signbit(outerArea) != sign || math.Abs(innerArea) > math.Abs(outerArea))
+		if keepInner {
+			poly, from = inner, 0
+		} else {
+			poly, from = outer, i
+		}
+	}
+}
+
+// firstCrossing finds the first edges i and j > i of a polygon, from edge
+// from on, that cross at a point x. Neighbouring edges don't count.
+func firstCrossing(poly [][2]float64, from int) (i, j int, x [2]float64, ok bool) {
+	n := len(poly)
+	for i = from; i < n; i++ {
+		a, b := poly[i], poly[(i+1)%n]
+		for j = i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue
+			}
+			if x, ok = crossing(a, b, poly[j], poly[(j+1)%n]); ok {
+				return i, j, x, true
+			}
+		}
+	}
+	return 0, 0, x, false
+}
+
+// crossing returns the point where segments a-b and c-d cross, if they do.
+func crossing(a, b, c, d [2]float64) ([2]float64, bool) {
+	rx, ry := b[0]-a[0], b[1]-a[1]
+	sx, sy := d[0]-c[0], d[1]-c[1]
+	den := rx*sy - ry*sx
+	if den == 0 {
+		return [2]float64{}, false
+	}
+	t := ((c[0]-a[0])*sy - (c[1]-a[1])*sx) / den
+	u := ((c[0]-a[0])*ry - (c[1]-a[1])*rx) / den
+	if t <= 0 || t >= 1 || u <= 0 || u >= 1 {
+		return [2]float64{}, false
+	}
+	return [2]float64{a[0] + t*rx, a[1] + t*ry}, true
+}
+
+// offsetPolygon moves the edges of a counterclockwise polygon d to their
+// right. Corners turning left are rounded, the edges of corners turning
+// right are cut off where they meet.
+func offsetPolygon(poly [][2]float64, d float64) [][2]float64 {
+	normal := func(a, b [2]float64) [2]float64 {
+		dx, dy := b[0]-a[0], b[1]-a[1]
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			return [2]float64{}
+		}
+		return [2]float64{dy / length, -dx / length}
+	}
+	n := len(poly)
+	var out [][2]float64
+	for i, b := range poly {
+		a, c := poly[(i+n-1)%n], poly[(i+1)%n]
+		n1, n2 := normal(a, b), normal(b, c)
+		cross := (b[0]-a[0])*(c[1]-b[1]) - (b[1]-a[1])*(c[0]-b[0])
+		dot := n1[0]*n2[0] + n1[1]*n2[1]
+		switch {
+		case cross > 0:
+			// The offset edges leave a gap, bridge it with an arc around the corner.
+			from := math.Atan2(n1[1], n1[0])
+			turn := math.Atan2(n1[0]*n2[1]-n1[1]*n2[0], dot)
+			steps := max(1, int(math.Ceil(turn/outlineArcStep)))
+			for k := 0; k <= steps; k++ {
+				angle := from + turn*float64(k)/float64(steps)
+				out = append(out, [2]float64{b[0] + d*math.Cos(angle), b[1] + d*math.Sin(angle)})
+			}
+		case 1+dot > 1e-6:
+			// The offset edges cross on the corner's bisector.
+			s := d / (1 + dot)
+			out = append(out, [2]float64{b[0] + (n1[0]+n2[0])*s, b[1] + (n1[1]+n2[1])*s})
+		default:
+			// The edge doubles back, keep both ends.
+			out = append(out, [2]float64{b[0] + n1[0]*d, b[1] + n1[1]*d}, [2]float64{b[0] + n2[0]*d, b[1] + n2[1]*d})
+		}
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestOffsetPolygon(t *testing.T) {
+	square := func(x, y, size float64) [][2][2]float64 {
+		c := [][2]float64{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}}
+		var segs [][2][2]float64
+		for i := range c {
+			segs = append(segs, [2][2]float64{c[i], c[(i+1)%len(c)]})
+		}
+		return segs
+	}
+	// A 10 mm square with a 4 mm hole, and a slit that is no loop.
+	segs := append(square(0, 0, 10), square(3, 3, 4)...)
+	segs = append(segs, [2][2]float64{{20, 0}, {25, 0}})
+	loops := closedLoops(segs)
+	if len(loops) != 2 {
+		t.Fatalf("got %d loops, want 2", len(loops))
+	}
+
+	lines := partOutline(nil, nil, nil, FlapAuto, 0)
+	if lines != nil {
+		t.Errorf("outline without offset: %v", lines)
+	}
+	box := func(poly [][2]float64) (lo, hi [2]float64) {
+		lo, hi = [2]float64{math.Inf(1), math.Inf(1)}, [2]float64{math.Inf(-1), math.Inf(-1)}
+		for _, v := range poly {
+			lo = [2]float64{min(lo[0], v[0]), min(lo[1], v[1])}
+			hi = [2]float64{max(hi[0], v[0]), max(hi[1], v[1])}
+		}
+		return lo, hi
+	}
+	outer := loops[0]
+	if polygonArea(outer) < 0 {
+		for a, b := 0, len(outer)-1; a < b; a, b = a+1, b-1 {
+			outer[a], outer[b] = outer[b], outer[a]
+		}
+	}
+	lo, hi := box(offsetPolygon(outer, 2))
+	if lo != [2]float64{-2, -2} || math.Abs(hi[0]-12) > 1e-9 || math.Abs(hi[1]-12) > 1e-9 {
+		t.Errorf("grown square spans %v to %v", lo, hi)
+	}
+	hole := loops[1]
+	if polygonArea(hole) > 0 {
+		for a, b := 0, len(hole)-1; a < b; a, b = a+1, b-1 {
+			hole[a], hole[b] = hole[b], hole[a]
+		}
+	}
+	if lo, hi := box(offsetPolygon(hole, 1)); math.Abs(lo[0]-4) > 1e-9 || math.Abs(hi[0]-6) > 1e-9 {
+		t.Errorf("shrunk hole spans %v to %v", lo, hi)
+	}
+}
+
+func TestPartOutline(t *testing.T) {
+	p := texturedCone(t)
+	const d = 3.0
+	lookups := p.Lookups()
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		lk := lookups.Part(part)
+		lines := partOutline(p, lk, part, FlapAuto, d)
+		if len(lines) == 0 {
+			t.Fatalf("part %d has no outline", i)
+		}
+		// Every outline point keeps at least d from the part's cut lines.
+		for _, l := range lines {
+			for c := range partLines(p, lk, part, FlapAuto) {
+				if c.Type != lineCut {
+					continue
+				}
+				if dist := segmentDistance(l.X1, l.Y1, c); dist < d-1e-6 {
+					t.Fatalf("part %d: outline point %g,%g is %g mm from the part", i, l.X1, l.Y1, dist)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ExportSVG(p, &buf, Options{OutlineOffset: d}); err != nil {
+		t.Fatal(err)
+	}
+	var plain bytes.Buffer
+	ExportSVG(p, &plain, Options{})
+	if strings.Count(buf.String(), `class="cut"`) <= strings.Count(plain.String(), `class="cut"`) {
+		t.Error("SVG has no outline")
+	}
+	p.Settings.AddOutlinePadding = 1
+	if got := (Options{}).outlineOffset(p.Settings); got != DefaultOutlinePadding {
+		t.Errorf("outline padding setting gives %g mm", got)
+	}
+}
+
+// segmentDistance returns the distance of a point from a line segment.
+func segmentDistance(x, y float64, l partLine) float64 {
+	dx, dy := l.X2-l.X1, l.Y2-l.Y1
+	t := 0.0
+	if n := dx*dx + dy*dy; n > 0 {
+		t = max(0, min(1, ((x-l.X1)*dx+(y-l.Y1)*dy)/n))
+	}
+	return math.Hypot(x-(l.X1+t*dx), y-(l.Y1+t*dy))
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"fmt"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// PartColoring selects how parts are told apart by color.
+type PartColoring int
+
+const (
+	// PartColorsOff draws parts with the usual line colors.
+	PartColorsOff PartColoring = iota
+	// PartColorsOutline draws the cut lines of each part in its own color.
+	PartColorsOutline
+	// PartColorsFill fills the faces of each part with a light tint of its color.
+	PartColorsFill
+)
+
+// ParsePartColoring converts a part coloring name ("none", "outline",
+// "fill") into a PartColoring.
+func ParsePartColoring(s string) (PartColoring, error) {
+	switch s {
+	case "none", "":
+		return PartColorsOff, nil
+	case "outline":
+		return PartColorsOutline, nil
+	case "fill":
+		return PartColorsFill, nil
+	}
+	return PartColorsOff, fmt.Errorf("unknown part coloring %q", s)
+}
+
+// partFillOpacity is the opacity of part fills, light enough to keep fold
+// lines and printed textures readable.
+const partFillOpacity = 0.35
+
+// partPalette holds distinct colors for neighbouring part indices. Colors
+// repeat after the last one.
+var partPalette = [][3]uint8{
+	{230, 25, 75},   // Red
+	{60, 180, 75},   // Green
+	{0, 130, 200},   // Blue
+	{245, 130, 48},  // Orange
+	{145, 30, 180},  // Purple
+	{70, 200, 200},  // Cyan
+	{240, 50, 230},  // Magenta
+	{170, 160, 0},   // Olive
+	{0, 128, 128},   // Teal
+	{170, 110, 40},  // Brown
+	{128, 0, 0},     // Maroon
+	{0, 0, 128},     // Navy
+	{128, 128, 128}, // Grey
+	{210, 150, 190}, // Pink
+}
+
+// partColor returns the palette color of a part index.
+func partColor(i int) (r, g, b uint8) {
+	c := partPalette[i%len(partPalette)]
+	return c[0], c[1], c[2]
+}
+
+// partIndex returns the index of part in p.Parts, or -1.
+func partIndex(p *pdo.PDO, part *pdo.Part) int {
+	for i := range p.Parts {
+		if &p.Parts[i] == part {
+			return i
+		}
+	}
+	return -1
+}
+
+// partLabel returns the legend name of a part.
+func partLabel(p *pdo.PDO, i int) string {
+	if name := p.Parts[i].Name; name != "" {
+		return fmt.Sprintf("%d: %s", i+1, name)
+	}
+	return fmt.Sprintf("Part %d", i+1)
+}
+
+// partPolygons returns the 2D outline of every face of a part, in part coordinates.
+func partPolygons(p *pdo.PDO, part *pdo.Part, index int) [][]pdo.Face2DVertex {
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+		return nil
+	}
+	var polys [][]pdo.Face2DVertex
+	for _, face := range p.Objects[part.ObjectIndex].PartFaces(index) {
+		if len(face.Vertices) >= 3 {
+			polys = append(polys, face.Vertices)
+		}
+	}
+	return polys
+}
+
+// Legend layout in mm: one row per part with a swatch and its label.
+const (
+	legendRow    = 5
+	legendSwatch = 3.5
+	legendFont   = 3 // Only used by PDF, the SVG legend class has the same size
+)
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestExportSVG_PartColors(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Parts) < 2 {
+		t.Fatalf("sample has %d parts, want several", len(p.Parts))
+	}
+
+	var buf bytes.Buffer
+	if err := ExportSVG(p, &buf, Options{PartColoring: PartColorsOutline}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for i := range p.Parts {
+		r, g, b := partColor(i)
+		color := fmt.Sprintf("#%02x%02x%02x", r, g, b)
+		if !strings.Contains(out, `style="stroke:`+color+`"`) {
+			t.Errorf("part %d: no cut lines in %s", i, color)
+		}
+	}
+	if got := strings.Count(out, `class="legend"`); got != len(p.Parts) {
+		t.Errorf("legend has %d entries, want %d", got, len(p.Parts))
+	}
+
+	buf.Reset()
+	if err := ExportSVG(p, &buf, Options{PartColoring: PartColorsFill}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<polygon ") {
+		t.Error("fill mode drew no faces")
+	}
+}
@@ -1,156 +1,541 @@
 package export
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"log/slog"
 	"math"
+	"strconv"
+	"time"
 
 	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/qr"
 
-	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
 )
 
-// ExportPDF exports the PDO data to a PDF file.
-// It uses "github.com/go-pdf/fpdf".
-func ExportPDF(p *pdo.PDO, w io.Writer) error {
-	// Initialize PDF
-	// Default A4 portrait
-	// If PDO has custom size, we might need to adjust.
+// ExportPDF exports the PDO data to a PDF file, drawn with
+// Options.PDFBackend.
+func ExportPDF(p *pdo.PDO, w io.Writer, opts Options) error {
+	if err := checkUnfold(p); err != nil {
+		return err
+	}
+	p = opts.lines(p)
+	opts = opts.metadata(p)
 	// PDO uses mm. FPDF uses mm by default.
+	// The sheet size follows the page settings (incl. orientation);
+	// with 2-up imposition two template pages sit side by side on one sheet.
+	dims := p.PageDims()
+	slots := opts.Imposition.slots()
 
-	orientation := "P"
-	// Check Settings
-	width := p.Settings.CustomWidth
-	height := p.Settings.CustomHeight
-	format := "A4"
-
-	if p.Settings.PageType == 0 { // A4
-		// width=210, height=297
-	} else if p.Settings.PageType == 1 { // A3?
-		// ...
-	}
-	// For simplicity, we stick to A4 or custom.
-	// If custom size is set and non-zero
-	var size fpdf.SizeType
-	if width > 0 && height > 0 {
-		format = "Custom"
-		size = fpdf.SizeType{Wd: width, Ht: height}
-	}
-
-	// Calculate Page Grid
-	dims := getPageDims(p)
-	maxPX, maxPY := calculatePageGrid(p, dims)
-
-	pdf := fpdf.NewCustom(&fpdf.InitType{
-		OrientationStr: orientation,
-		UnitStr:        "mm",
-		SizeStr:        format,
-		Size:           size,
-	})
+	pdf := opts.pdfBackend().NewDocument(dims.Width*float64(slots), dims.Height)
+
+	fonts := newPDFFonts(pdf, opts.PDFConformance != PDFStandard)
+	setPDFMetadata(pdf, opts)
+	if opts.PDFConformance == PDFX4 {
+		pdf.SetTrimBox(0, 0, dims.Width*float64(slots), dims.Height)
+	}
+	if err := writeModelPDF(pdf, fonts, p, "", pdf.AddPage, opts); err != nil {
+		return err
+	}
+	return outputPDF(pdf, w, opts)
+}
 
-	pdf.SetFont("Arial", "", 10)
+// writeModelPDF draws the pages of a model, starting each sheet with
+// addPage. Its texture images are named with prefix, so several models can
+// share a document.
+func writeModelPDF(pdf PDFDocument, fonts *pdfFonts, p *pdo.PDO, prefix string, addPage func(), opts Options) error {
+	dims := p.PageDims()
+	imp := opts.Imposition
+	if len(p.Parts) == 0 {
+		opts.logger().Warn("no unfolded parts to export")
+	}
+	warnLayout(p, opts, true)
 
-	// Loop Pages
-	for py := 0; py <= maxPY; py++ {
-		for px := 0; px <= maxPX; px++ {
-			// Check if page has content
-			partsOnPage := getPartsOnPage(p, px, py, dims)
-			if len(partsOnPage) == 0 {
+	var textures *pdfTextures
+	if opts.Textures {
+		textures = newPDFTextures(pdf, p, opts)
+		textures.prefix = prefix
+	}
+	drawings := preparePDFParts(p, opts)
+
+	pages := opts.posterPages(p, dims)
+	stamp, err := newPDFStamp(pdf, fonts, opts.Stamp, dims, len(pages))
+	if err != nil {
+		return err
+	}
+	watermark, err := newPDFWatermark(pdf, fonts, opts.Watermark, dims, prefix)
+	if err != nil {
+		return err
+	}
+	for side, sheet := range imp.arrange(pages) {
+		addPage()
+
+		for slot, page := range sheet {
+			if page == nil { // Blank booklet page
 				continue
 			}
 
-			pdf.AddPage()
-
 			// Calculate Offset
 			// Logic: Global (px*CW, py*CH) -> Local (MarginL, MarginT)
 			// DrawX = GlobalX - OffsetX
-			// LocalX = GlobalX - OffsetX
 			// We want GlobalX=px*CW to map to MarginL.
 			// MarginL = px*CW - OffsetX => OffsetX = px*CW - MarginL
+			// The slot position and gutter shift move the page on the sheet.
+			shiftX := float64(slot)*dims.Width + imp.gutterShift(side, slot)
+			x, y := page.origin(dims)
+			offX := x - dims.MarginLeft - shiftX
+			offY := y - dims.MarginTop
 
-			offX := float64(px)*dims.ClippedWidth - dims.MarginLeft
-			offY := float64(py)*dims.ClippedHeight - dims.MarginTop
+			drawGuidesPDF(pdf, opts.Guides, dims, shiftX)
+			if page.tile != nil {
+				drawGlueStripsPDF(pdf, page.tile, dims, shiftX)
+				pdf.ClipRect(dims.MarginLeft+shiftX, dims.MarginTop, dims.ClippedWidth, dims.ClippedHeight)
+			}
+			for _, part := range page.parts {
+				if textures != nil {
+					textures.draw(part, offX, offY)
+				}
+				writePartPDF(pdf, fonts, p, part, drawings[partIndex(p, part)], offX, offY, opts)
+			}
+			if page.tile != nil {
+				pdf.ClipEnd()
+				drawAlignMarksPDF(pdf, fonts, p, page.tile, dims, shiftX)
+			}
 
-			for _, part := range partsOnPage {
-				writePartPDF(pdf, p, part, offX, offY)
+			for _, tb := range page.texts {
+				writeTextBlockPDF(pdf, fonts, tb, offX, offY, opts.logger())
 			}
 
-			// Text? (Skipping per-page text filtering for brevity, just dumping all? No, should filter)
-			// For now, skip text filtering or implement it similarly.
+			stamp.draw(page.index, shiftX)
+			watermark.draw(shiftX)
 		}
 	}
 
-	return pdf.Output(w)
+	if opts.PartColoring != PartColorsOff && len(p.Parts) > 0 {
+		writeLegendPDF(pdf, fonts, p, dims, addPage)
+	}
+	return nil
 }
 
-func getPartsOnPage(p *pdo.PDO, px, py int, dims PageDims) []*pdo.Part {
-	var parts []*pdo.Part
-	for i := range p.Parts {
-		part := &p.Parts[i]
-		// Determine part page
-		// Note: Parts can span? pdo2opf assigns owner page based on anchor?
-		ppx := int(math.Floor(part.BoundingBox.Left / dims.ClippedWidth))
-		ppy := int(math.Floor(part.BoundingBox.Top / dims.ClippedHeight))
+// setPDFMetadata records the title, author and comment in the document
+// info.
+func setPDFMetadata(pdf PDFDocument, opts Options) {
+	if opts.Title != "" {
+		pdf.SetTitle(opts.Title)
+	}
+	if opts.Author != "" {
+		pdf.SetAuthor(opts.Author)
+	}
+	if opts.Comment != "" {
+		pdf.SetSubject(opts.Comment)
+	}
+}
 
-		if ppx == px && ppy == py {
-			parts = append(parts, part)
+// outputPDF writes the finished document, made to conform to
+// opts.PDFConformance. Plain PDFs carry the attribution in XMP metadata,
+// the document info has no entry for it.
+func outputPDF(pdf PDFDocument, w io.Writer, opts Options) error {
+	if opts.PDFConformance == PDFStandard && opts.OutputProfile == nil {
+		if opts.Stamp.Attribution != "" {
+			pdf.SetXMPMetadata(xmpMetadata(opts, time.Now().UTC()))
+		}
+		return pdf.Output(w)
+	}
+	return writeConforming(pdf, w, opts)
+}
+
+// getPartsOnPage returns the parts PDO.PartPages puts on a page.
+func getPartsOnPage(p *pdo.PDO, pages [][2]int, px, py int) []*pdo.Part {
+	var parts []*pdo.Part
+	for i, page := range pages {
+		if page == [2]int{px, py} {
+			parts = append(parts, &p.Parts[i])
 		}
 	}
 	return parts
 }
 
-func writePartPDF(pdf *fpdf.Fpdf, p *pdo.PDO, part *pdo.Part, offX, offY float64) {
-	obj := p.Objects[part.ObjectIndex]
+func getTextsOnPage(p *pdo.PDO, px, py int, dims pdo.PageDims) []*pdo.TextBlock {
+	var texts []*pdo.TextBlock
+	for i := range p.TextBlocks {
+		tb := &p.TextBlocks[i]
+		tpx := int(math.Floor(tb.BoundingBox.Left / dims.ClippedWidth))
+		tpy := int(math.Floor(tb.BoundingBox.Top / dims.ClippedHeight))
+		if tpx == px && tpy == py {
+			texts = append(texts, tb)
+		}
+	}
+	return texts
+}
+
+// pdfUnicodeFont is the embedded font used for text the core fonts can't encode.
+const pdfUnicodeFont = "GoRegular"
+
+// pdfFonts selects fonts for PDF text. Core fonts cover Windows-1252 text
+// and aren't embedded, other text and documents that must embed all fonts
+// use the embedded Go font.
+type pdfFonts struct {
+	pdf     PDFDocument
+	embed   bool
+	encoder *encoding.Encoder
+}
+
+func newPDFFonts(pdf PDFDocument, embed bool) *pdfFonts {
+	return &pdfFonts{pdf: pdf, embed: embed, encoder: charmap.Windows1252.NewEncoder()}
+}
 
-	for _, line := range part.Lines {
-		if line.Hidden {
-			continue
+// use selects the core font family, or the embedded font when s needs it,
+// and returns s encoded for the selected font.
+func (f *pdfFonts) use(family string, size float64, s string) string {
+	if !f.embed {
+		if encoded, err := f.encoder.String(s); err == nil {
+			f.pdf.SetFont(family, size)
+			return encoded
 		}
+	}
+	if !f.pdf.HasFont(pdfUnicodeFont) {
+		f.pdf.AddFont(pdfUnicodeFont, goregular.TTF)
+	}
+	f.pdf.SetFont(pdfUnicodeFont, size)
+	return s
+}
+
+// writeTextBlockPDF draws a text block with its color and size. The font is
+// matched to a core font, text outside Windows-1252 uses the embedded Go font.
+func writeTextBlockPDF(pdf PDFDocument, fonts *pdfFonts, tb *pdo.TextBlock, offX, offY float64, log *slog.Logger) {
+	pdf.SetTextColor(textRGB(tb.Color))
+	size := float64(tb.FontSize)
+	core := pdfCoreFont(fontFamily(tb.FontName))
 
-		v1 := get2DVertex(obj, line.FaceIndex, line.VertexIndex)
-		if v1 == nil {
-			continue
+	for i, y := range textBaselines(tb) {
+		line := tb.Lines[i]
+		text := fonts.use(core, size, line)
+		if text == line {
+			if missing := missingGlyphs(line); len(missing) > 0 {
+				log.Warn("font has no glyphs for text", "text", line, "missing", string(missing))
+			}
 		}
+		pdf.Text(tb.BoundingBox.Left-offX, y-offY, text)
+	}
+}
 
-		var v2 *pdo.Face2DVertex
-		if line.IsConnectingFaces {
-			v2 = get2DVertex(obj, line.Face2Index, line.Vertex2Index)
-		} else {
-			v2 = getNext2DVertex(obj, line.FaceIndex, line.VertexIndex)
+// pdfPartDrawing is what writePartPDF draws of a part, worked out ahead of
+// writing so parts can be prepared concurrently.
+type pdfPartDrawing struct {
+	polygons [][]pdo.Face2DVertex
+	lines    []partLine
+	labels   []edgeLabel
+}
+
+// preparePDFParts works out the drawing of every part on opts.Workers
+// goroutines.
+func preparePDFParts(p *pdo.PDO, opts Options) []pdfPartDrawing {
+	drawings := make([]pdfPartDrawing, len(p.Parts))
+	lookups := p.Lookups()
+	parallel(len(p.Parts), opts.Workers, func(i int) {
+		part, d, lk := &p.Parts[i], &drawings[i], lookups.Part(&p.Parts[i])
+		if opts.PartColoring == PartColorsFill {
+			d.polygons = partPolygons(p, part, i)
+		}
+		for line := range templateLines(p, lk, part, opts.FlapStyle, opts.outlineOffset(p.Settings), opts.Perforation) {
+			if line.Type < lineInvisible {
+				d.lines = append(d.lines, line)
+			}
 		}
+		d.labels = placeEdgeLabels(p, lk, part)
+	})
+	return drawings
+}
 
-		if v2 == nil {
-			continue
+func writePartPDF(pdf PDFDocument, fonts *pdfFonts, p *pdo.PDO, part *pdo.Part, d pdfPartDrawing, offX, offY float64, opts Options) {
+	tintR, tintG, tintB := partColor(partIndex(p, part))
+	if opts.PartColoring == PartColorsFill {
+		pdf.SetAlpha(partFillOpacity)
+		pdf.SetFillColor(int(tintR), int(tintG), int(tintB))
+		for _, poly := range d.polygons {
+			points := make([]PDFPoint, len(poly))
+			for i, v := range poly {
+				points[i] = PDFPoint{X: v.X + part.BoundingBox.Left - offX, Y: v.Y + part.BoundingBox.Top - offY}
+			}
+			pdf.Polygon(points, "F")
 		}
+		pdf.SetAlpha(1)
+	}
 
+	for _, line := range d.lines {
 		// Apply Offset
 		// Vertex coordinates are Local. Add Part BoundingBox to get Global.
 		// Then subtract Page Offset.
-		x1 := (v1.X + part.BoundingBox.Left) - offX
-		y1 := (v1.Y + part.BoundingBox.Top) - offY
-		x2 := (v2.X + part.BoundingBox.Left) - offX
-		y2 := (v2.Y + part.BoundingBox.Top) - offY
+		x1 := (line.X1 + part.BoundingBox.Left) - offX
+		y1 := (line.Y1 + part.BoundingBox.Top) - offY
+		x2 := (line.X2 + part.BoundingBox.Left) - offX
+		y2 := (line.Y2 + part.BoundingBox.Top) - offY
 
 		// Set Style
 		pdf.SetLineWidth(0.1)
-		if line.Type == 1 { // Mountain
+		if c := line.Color; c != nil && (line.Type != lineCut || opts.PartColoring != PartColorsOutline) {
+			// The file's own color, dashed like the lines of its type.
+			pdf.SetDrawColor(int(c[0]), int(c[1]), int(c[2]))
+			if line.Type == lineCut {
+				pdf.SetDashPattern([]float64{}, 0)
+			} else {
+				pdf.SetDashPattern([]float64{1, 1}, 0)
+			}
+		} else if line.Type == lineMountain {
 			pdf.SetDrawColor(0, 0, 255) // Blue
 			pdf.SetDashPattern([]float64{1, 1}, 0)
-		} else if line.Type == 2 { // Valley
+		} else if line.Type == lineValley {
 			pdf.SetDrawColor(255, 0, 0) // Red
 			pdf.SetDashPattern([]float64{1, 1}, 0)
 		} else { // Cut
-			pdf.SetDrawColor(0, 0, 0) // Black
+			if opts.PartColoring == PartColorsOutline {
+				pdf.SetDrawColor(int(tintR), int(tintG), int(tintB))
+			} else {
+				pdf.SetDrawColor(0, 0, 0) // Black
+			}
 			pdf.SetDashPattern([]float64{}, 0)
 		}
 
 		pdf.Line(x1, y1, x2, y2)
 	}
+
+	if len(d.labels) == 0 {
+		return
+	}
+	size := edgeIDSize(p.Settings)
+	pdf.SetTextColor(0, 128, 0) // Green
+	for _, l := range d.labels {
+		id := fonts.use("Helvetica", size/ptToMM, strconv.Itoa(l.ID))
+		x := l.X + part.BoundingBox.Left - offX - pdf.StringWidth(id)/2
+		y := l.Y + part.BoundingBox.Top - offY + size*0.35 // Baseline for a vertically centred label
+		pdf.Text(x, y, id)
+	}
+}
+
+// drawGuidesPDF draws the paper color, printable area and grid of a page
+// shifted right by shiftX on the sheet.
+func drawGuidesPDF(pdf PDFDocument, g Guides, dims pdo.PageDims, shiftX float64) {
+	if g.Background != nil {
+		c := *g.Background
+		pdf.SetFillColor(int(c[0]), int(c[1]), int(c[2]))
+		pdf.Rect(shiftX, 0, dims.Width, dims.Height, "F")
+	}
+	if lines := g.gridLines(dims); len(lines) > 0 {
+		c := gridGuideColor
+		pdf.SetDrawColor(int(c[0]), int(c[1]), int(c[2]))
+		pdf.SetLineWidth(gridGuideWidth)
+		pdf.SetDashPattern([]float64{}, 0)
+		for _, l := range lines {
+			pdf.Line(l[0]+shiftX, l[1], l[2]+shiftX, l[3])
+		}
+	}
+	if g.Margins {
+		c := marginGuideColor
+		pdf.SetDrawColor(int(c[0]), int(c[1]), int(c[2]))
+		pdf.SetLineWidth(marginGuideWidth)
+		pdf.SetDashPattern([]float64{2, 1}, 0)
+		pdf.Rect(dims.MarginLeft+shiftX, dims.MarginTop, dims.ClippedWidth, dims.ClippedHeight, "D")
+		pdf.SetDashPattern([]float64{}, 0)
+	}
+}
+
+// drawGlueStripsPDF shades the strips of a poster tile covered by its neighbours.
+func drawGlueStripsPDF(pdf PDFDocument, t *posterTile, dims pdo.PageDims, shiftX float64) {
+	c := posterGlueColor
+	pdf.SetFillColor(int(c[0]), int(c[1]), int(c[2]))
+	for _, r := range t.glueStrips(dims) {
+		pdf.Rect(r[0]+shiftX, r[1], r[2], r[3], "F")
+	}
+}
+
+// drawAlignMarksPDF draws the alignment marks and caption of a poster tile.
+func drawAlignMarksPDF(pdf PDFDocument, fonts *pdfFonts, p *pdo.PDO, t *posterTile, dims pdo.PageDims, shiftX float64) {
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(posterMarkWidth)
+	pdf.SetDashPattern([]float64{}, 0)
+	for _, l := range t.alignMarks(dims) {
+		pdf.Line(l[0]+shiftX, l[1], l[2]+shiftX, l[3])
+	}
+	// The caption goes in the top margin, or inside the printable area
+	// when the margin is too narrow.
+	y := dims.MarginTop - 1.5
+	if dims.MarginTop < 4 {
+		y = dims.MarginTop + 3
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Text(dims.MarginLeft+shiftX, y, fonts.use("Arial", 7, t.label(p)))
+}
+
+// writeLegendPDF adds pages listing the color of every part.
+func writeLegendPDF(pdf PDFDocument, fonts *pdfFonts, p *pdo.PDO, dims pdo.PageDims, addPage func()) {
+	rows := max(1, int((dims.Height-2*dims.MarginTop)/legendRow))
+	for i := range p.Parts {
+		if i%rows == 0 {
+			addPage()
+			pdf.SetTextColor(0, 0, 0)
+		}
+		r, g, b := partColor(i)
+		y := dims.MarginTop + float64(i%rows)*legendRow
+		pdf.SetFillColor(int(r), int(g), int(b))
+		pdf.Rect(dims.MarginLeft, y, legendSwatch, legendSwatch, "F")
+		label := fonts.use("Arial", legendFont/ptToMM, partLabel(p, i))
+		pdf.Text(dims.MarginLeft+legendSwatch+1.5, y+legendSwatch-0.5, label)
+	}
+}
+
+// pdfStamp draws the page stamp, it does nothing when no stamp is set.
+type pdfStamp struct {
+	pdf    PDFDocument
+	fonts  *pdfFonts
+	stamp  Stamp
+	layout stampLayout
+	code   *qr.Code
+	pages  int
+}
+
+func newPDFStamp(pdf PDFDocument, fonts *pdfFonts, stamp Stamp, dims pdo.PageDims, pages int) (*pdfStamp, error) {
+	if !stamp.enabled() {
+		return nil, nil
+	}
+	code, err := stamp.code()
+	if err != nil {
+		return nil, err
+	}
+	return &pdfStamp{
+		pdf:    pdf,
+		fonts:  fonts,
+		stamp:  stamp,
+		layout: stamp.layout(dims.Width, dims.Height, dims.MarginLeft, dims.MarginTop, code),
+		code:   code,
+		pages:  pages,
+	}, nil
+}
+
+// draw stamps template page index, shifted right by shiftX on the sheet.
+func (s *pdfStamp) draw(index int, shiftX float64) {
+	if s == nil {
+		return
+	}
+	l := s.layout
+	if s.stamp.Text != "" {
+		s.pdf.SetTextColor(0, 0, 0)
+		s.pdf.Text(l.textX+shiftX, l.textY, s.fonts.use("Arial", 8, s.stamp.footer(index+1, s.pages)))
+	}
+	if s.stamp.Attribution != "" {
+		text := s.fonts.use("Arial", attributionSize/ptToMM, s.stamp.Attribution)
+		s.pdf.SetTextColor(0, 0, 0)
+		s.pdf.Text(l.attrX+shiftX-s.pdf.StringWidth(text)/2, l.attrY, text)
+	}
+	if s.code != nil {
+		s.pdf.SetFillColor(0, 0, 0)
+		qrRuns(s.code, func(x, y, n int) {
+			s.pdf.Rect(l.qrX+shiftX+float64(x)*l.module, l.qrY+float64(y)*l.module,
+				float64(n)*l.module, l.module, "F")
+		})
+	}
 }
 
-// Reuse get2DVertex from svg.go?
-// I'll copy it for now to keep packages independent or move to common.
-// Given they are in the same package 'export', I can access it if I remove the receiver?
-// No, svg.go func uses 's *SVGWriter'.
-// I'll make a helper function in a new file `common.go` or just duplicate it here lightly.
+type pdfExporter struct{}
 
-// get2DVertex is shared with svg.go (same package)
+func (pdfExporter) Name() string         { return "pdf" }
+func (pdfExporter) Extensions() []string { return []string{".pdf"} }
+func (pdfExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ExportPDF(p, t.W, opts)
+}
+
+func init() {
+	Register(pdfExporter{})
+}
+
+// Imposition arranges template pages on printed sheets.
+type Imposition struct {
+	// NUp is the number of template pages per sheet side: 1, or 2 side by side
+	// (e.g. two A4 templates on one A3 sheet).
+	NUp int
+	// Booklet orders pages for saddle stitching: sheets are printed 2-up on
+	// both sides, folded in the middle and nested. Implies NUp 2.
+	Booklet bool
+	// Duplex mirrors the gutter on the back side of each sheet, so the inner
+	// margins line up when printed double-sided.
+	Duplex bool
+	// Gutter is extra space in mm added to the inner (binding) margin.
+	Gutter float64
+}
+
+// ParseNUp validates an n-up value.
+func ParseNUp(n int) (int, error) {
+	if n != 1 && n != 2 {
+		return 0, fmt.Errorf("unsupported n-up value %d, must be 1 or 2", n)
+	}
+	return n, nil
+}
+
+func (imp Imposition) slots() int {
+	if imp.Booklet || imp.NUp == 2 {
+		return 2
+	}
+	return 1
+}
+
+// arrange groups pages into sheet sides. Nil entries are blank pages.
+func (imp Imposition) arrange(pages []layoutPage) [][]*layoutPage {
+	ptr := make([]*layoutPage, len(pages))
+	for i := range pages {
+		ptr[i] = &pages[i]
+	}
+
+	var sides [][]*layoutPage
+	switch {
+	case imp.Booklet:
+		// Pad to a multiple of four pages, one sheet carries four.
+		for len(ptr)%4 != 0 {
+			ptr = append(ptr, nil)
+		}
+		n := len(ptr)
+		for i := 0; i < n/4; i++ {
+			sides = append(sides,
+				[]*layoutPage{ptr[n-1-2*i], ptr[2*i]},   // Front
+				[]*layoutPage{ptr[2*i+1], ptr[n-2-2*i]}, // Back
+			)
+		}
+	case imp.NUp == 2:
+		for i := 0; i < len(ptr); i += 2 {
+			side := []*layoutPage{ptr[i], nil}
+			if i+1 < len(ptr) {
+				side[1] = ptr[i+1]
+			}
+			sides = append(sides, side)
+		}
+	default:
+		for _, page := range ptr {
+			sides = append(sides, []*layoutPage{page})
+		}
+	}
+	return sides
+}
+
+// gutterShift returns the horizontal offset in mm applied to a slot on a sheet side.
+// Side-by-side pages move away from the centre fold. Single pages move right,
+// or alternate right and left for duplex printing.
+func (imp Imposition) gutterShift(side, slot int) float64 {
+	if imp.Gutter == 0 {
+		return 0
+	}
+	if imp.slots() == 2 {
+		if slot == 0 {
+			return -imp.Gutter
+		}
+		return imp.Gutter
+	}
+	if imp.Duplex && side%2 == 1 {
+		return -imp.Gutter
+	}
+	return imp.Gutter
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+// countingBackend is a PDF backend counting the pages and images added to
+// fpdf documents, and recording their bookmarks.
+type countingBackend struct {
+	pages, images int
+	bookmarks     []string
+}
+
+func (c *countingBackend) NewDocument(width, height float64) PDFDocument {
+	return countedDocument{FPDFBackend{}.NewDocument(width, height), c}
+}
+
+type countedDocument struct {
+	PDFDocument
+	counter *countingBackend
+}
+
+func (d countedDocument) AddPage() {
+	d.counter.pages++
+	d.PDFDocument.AddPage()
+}
+
+func (d countedDocument) AddPageSize(width, height float64) {
+	d.counter.pages++
+	d.PDFDocument.AddPageSize(width, height)
+}
+
+func (d countedDocument) Bookmark(title string, level int) {
+	d.counter.bookmarks = append(d.counter.bookmarks, title)
+	d.PDFDocument.Bookmark(title, level)
+}
+
+func (d countedDocument) RegisterImage(name, imageType string, data []byte) {
+	d.counter.images++
+	d.PDFDocument.RegisterImage(name, imageType, data)
+}
+
+func TestExportPDF_Backend(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{Pages: 3})
+	counter := &countingBackend{}
+	var buf bytes.Buffer
+	if err := ExportPDF(p, &buf, Options{PDFBackend: counter}); err != nil {
+		t.Fatal(err)
+	}
+	if counter.pages != 3 {
+		t.Errorf("backend got %d pages, want 3", counter.pages)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Error("output isn't the backend's PDF")
+	}
+}
+
+func TestExportPDFPack(t *testing.T) {
+	pack := PDFPack{
+		Models: []PDFModel{
+			{Title: "small", PDO: pdotest.Cube(pdotest.Options{})},
+			{Title: "large", PDO: pdotest.Cube(pdotest.Options{Pages: 3})},
+		},
+		TitlePages: true,
+	}
+	counter := &countingBackend{}
+	var buf bytes.Buffer
+	if err := ExportPDFPack(pack, &buf, Options{PDFBackend: counter}); err != nil {
+		t.Fatal(err)
+	}
+	if counter.pages != 1+1+1+3 {
+		t.Errorf("backend got %d pages, want 6", counter.pages)
+	}
+	if !slices.Equal(counter.bookmarks, []string{"small", "large"}) {
+		t.Errorf("bookmarks %q", counter.bookmarks)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/Outlines")) {
+		t.Error("PDF has no outline")
+	}
+
+	pack.Models[1].PDO.Parts = nil
+	if err := ExportPDFPack(pack, io.Discard, Options{}); !errors.Is(err, ErrNoUnfold) {
+		t.Errorf("pack with a model without unfolding: %v", err)
+	}
+	if err := ExportPDFPack(PDFPack{}, io.Discard, Options{}); err == nil {
+		t.Error("empty pack exported")
+	}
+}
+
+func TestImposition_BookletOrder(t *testing.T) {
+	pages := make([]layoutPage, 6)
+	for i := range pages {
+		pages[i].px = i + 1 // page numbers 1..6
+	}
+
+	sides := Imposition{Booklet: true}.arrange(pages)
+
+	// 6 pages pad to 8: sheet 1 front 8|1, back 2|7, sheet 2 front 6|3, back 4|5
+	want := [][2]int{{0, 1}, {2, 0}, {6, 3}, {4, 5}}
+	if len(sides) != len(want) {
+		t.Fatalf("got %d sides, want %d", len(sides), len(want))
+	}
+	for i, side := range sides {
+		for slot, page := range side {
+			got := 0
+			if page != nil {
+				got = page.px
+			}
+			if got != want[i][slot] {
+				t.Errorf("side %d slot %d: got page %d, want %d", i, slot, got, want[i][slot])
+			}
+		}
+	}
+}
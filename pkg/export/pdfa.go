@@ -0,0 +1,311 @@
+package export
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf16"
+)
+
+// PDFConformance selects a PDF standard the output follows.
+type PDFConformance int
+
+const (
+	// PDFStandard writes a plain PDF.
+	PDFStandard PDFConformance = iota
+	// PDFA2B writes PDF/A-2b for archiving: all fonts embedded, an sRGB
+	// output intent (or Options.OutputProfile) and XMP metadata.
+	PDFA2B
+	// PDFX4 writes PDF/X-4 for print services. It needs Options.OutputProfile,
+	// the ICC profile of the printing condition.
+	PDFX4
+)
+
+// ParsePDFConformance converts a conformance name ("none", "pdfa-2b",
+// "pdfx-4") into a PDFConformance.
+func ParsePDFConformance(s string) (PDFConformance, error) {
+	switch s {
+	case "none", "":
+		return PDFStandard, nil
+	case "pdfa-2b", "pdfa":
+		return PDFA2B, nil
+	case "pdfx-4", "pdfx":
+		return PDFX4, nil
+	}
+	return PDFStandard, fmt.Errorf("unknown PDF conformance %q", s)
+}
+
+// pdfProducer is the producer and creator tool recorded in conforming PDFs.
+const pdfProducer = "pdo-tools"
+
+var (
+	trailerRoot = regexp.MustCompile(`/Root (\d+) 0 R`)
+	trailerInfo = regexp.MustCompile(`/Info (\d+) 0 R`)
+)
+
+// writeConforming writes the document with the additions fpdf can't make:
+// an output intent, XMP metadata linked from the catalog, a binary header
+// comment and a file ID. They are appended as an incremental update, which
+// both PDF/A and PDF/X allow. Plain PDFs with an output profile take the
+// same route for the output intent.
+func writeConforming(pdf PDFDocument, w io.Writer, opts Options) error {
+	profile := srgbProfile()
+	if opts.OutputProfile != nil {
+		var err error
+		if profile, err = parseICC(opts.OutputProfile); err != nil {
+			return fmt.Errorf("output profile: %w", err)
+		}
+	}
+	if opts.PDFConformance == PDFX4 && (opts.OutputProfile == nil || profile.class != "prtr") {
+		return fmt.Errorf("PDF/X needs the ICC profile of a printing condition as output profile")
+	}
+	if opts.Textures && opts.CMYK && opts.PDFConformance != PDFStandard && profile.components() != 4 {
+		return fmt.Errorf("CMYK textures need a CMYK output profile")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return err
+	}
+	doc, err := addBinaryComment(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	trailerAt := bytes.LastIndex(doc, []byte("\ntrailer\n"))
+	startAt := bytes.LastIndex(doc, []byte("\nstartxref\n"))
+	if trailerAt < 0 || startAt < trailerAt {
+		return fmt.Errorf("unexpected PDF structure: no trailer")
+	}
+	root, err1 := trailerRef(trailerRoot, doc[trailerAt:])
+	info, err2 := trailerRef(trailerInfo, doc[trailerAt:])
+	prevXref, err3 := strconv.Atoi(string(bytes.TrimSpace(bytes.SplitN(doc[startAt+len("\nstartxref\n"):], []byte("\n"), 2)[0])))
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return fmt.Errorf("unexpected PDF structure: %w", err)
+	}
+	catalog, err := objectBody(doc, root)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	sum := md5.Sum(append([]byte(now.String()), doc[:min(len(doc), 4096)]...))
+	id := fmt.Sprintf("<%x>", sum)
+	fileID := fmt.Sprintf("/ID [%s %s]", id, id)
+
+	// The first trailer gets the file ID too, it doesn't move any offsets.
+	trailerDict := trailerAt + len("\ntrailer\n<<")
+	doc = append(doc[:trailerDict:trailerDict], append([]byte("\n"+fileID), doc[trailerDict:]...)...)
+
+	// Incremental update: new objects after the last one, then the rewritten
+	// info and catalog.
+	size := max(root, info) + 1
+	iccObj, intentObj, xmpObj := size, size+1, size+2
+	out := bytes.NewBuffer(doc)
+	offsets := map[int]int{}
+	object := func(n int, body string, stream []byte) {
+		offsets[n] = out.Len()
+		fmt.Fprintf(out, "%d 0 obj\n%s\n", n, body)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+	}
+
+	intentType := "/GTS_PDFA1"
+	condition := "sRGB IEC61966-2.1"
+	if opts.PDFConformance != PDFA2B {
+		// Plain PDFs with a profile use the print intent as well.
+		intentType = "/GTS_PDFX"
+		condition = "Custom"
+	}
+	object(iccObj, fmt.Sprintf("<< /N %d /Length %d >>", profile.components(), len(profile.data)), profile.data)
+	object(intentObj, fmt.Sprintf("<< /Type /OutputIntent /S %s /OutputConditionIdentifier %s /Info %s /DestOutputProfile %d 0 R >>",
+		intentType, pdfString(condition), pdfString(condition), iccObj), nil)
+	xmp := xmpMetadata(opts, now)
+	object(xmpObj, fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>", len(xmp)), xmp)
+
+	infoDict := fmt.Sprintf("<<\n/Producer %s\n/Creator %s\n/CreationDate %s\n/ModDate %s",
+		pdfString(pdfProducer), pdfString(pdfProducer), pdfDate(now), pdfDate(now))
+	if opts.Title != "" {
+		infoDict += "\n/Title " + pdfString(opts.Title)
+	}
+	if opts.Author != "" {
+		infoDict += "\n/Author " + pdfString(opts.Author)
+	}
+	if opts.Comment != "" {
+		infoDict += "\n/Subject " + pdfString(opts.Comment)
+	}
+	if opts.PDFConformance == PDFX4 {
+		infoDict += "\n/Trapped /False\n/GTS_PDFXVersion (PDF/X-4)"
+	}
+	object(info, infoDict+"\n>>", nil)
+	object(root, fmt.Sprintf("<<%s\n/Metadata %d 0 R\n/OutputIntents [%d 0 R]\n>>", catalog, xmpObj, intentObj), nil)
+
+	xref := out.Len()
+	out.WriteString("xref\n")
+	for _, n := range []int{info, root} {
+		fmt.Fprintf(out, "%d 1\n%010d 00000 n \n", n, offsets[n])
+	}
+	fmt.Fprintf(out, "%d 3\n", iccObj)
+	for _, n := range []int{iccObj, intentObj, xmpObj} {
+		fmt.Fprintf(out, "%010d 00000 n \n", offsets[n])
+	}
+	fmt.Fprintf(out, "trailer\n<<\n/Size %d\n/Root %d 0 R\n/Info %d 0 R\n/Prev %d\n%s\n>>\nstartxref\n%d\n%%%%EOF\n",
+		xmpObj+1, root, info, prevXref, fileID, xref)
+
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// addBinaryComment inserts the comment with high-bit bytes after the header
+// line that marks the file as binary, and moves the cross-reference offsets.
+func addBinaryComment(doc []byte) ([]byte, error) {
+	comment := []byte("%\xe2\xe3\xcf\xd3\n")
+	header := bytes.IndexByte(doc, '\n') + 1
+	xref := bytes.LastIndex(doc, []byte("\nxref\n"))
+	start := bytes.LastIndex(doc, []byte("\nstartxref\n"))
+	if header == 0 || xref < 0 || start < xref {
+		return nil, fmt.Errorf("unexpected PDF structure: no cross-reference table")
+	}
+
+	shift := len(comment)
+	out := make([]byte, 0, len(doc)+shift+8)
+	out = append(out, doc[:header]...)
+	out = append(out, comment...)
+
+	// Entries are "nnnnnnnnnn ggggg n \n", 20 bytes each after the subsection line.
+	body := doc[header : start+len("\nstartxref\n")]
+	entries := xref + len("\nxref\n") - header
+	entries += bytes.IndexByte(body[entries:], '\n') + 1
+	fixed := append([]byte(nil), body...)
+	for i := entries; i+20 <= len(fixed) && (fixed[i+17] == 'n' || fixed[i+17] == 'f'); i += 20 {
+		if fixed[i+17] == 'f' {
+			continue
+		}
+		off, err := strconv.Atoi(string(fixed[i : i+10]))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected PDF structure: %w", err)
+		}
+		copy(fixed[i:], fmt.Sprintf("%010d", off+shift))
+	}
+	out = append(out, fixed...)
+
+	rest := doc[start+len("\nstartxref\n"):]
+	end := bytes.IndexByte(rest, '\n')
+	if end < 0 {
+		return nil, fmt.Errorf("unexpected PDF structure: no startxref")
+	}
+	off, err := strconv.Atoi(string(rest[:end]))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected PDF structure: %w", err)
+	}
+	out = append(out, strconv.Itoa(off+shift)...)
+	return append(out, rest[end:]...), nil
+}
+
+func trailerRef(re *regexp.Regexp, trailer []byte) (int, error) {
+	m := re.FindSubmatch(trailer)
+	if m == nil {
+		return 0, fmt.Errorf("trailer lacks %s", re)
+	}
+	return strconv.Atoi(string(m[1]))
+}
+
+// objectBody returns the dictionary entries of object n, without the
+// enclosing << >>.
+func objectBody(doc []byte, n int) (string, error) {
+	head := []byte(fmt.Sprintf("\n%d 0 obj\n<<", n))
+	start := bytes.LastIndex(doc, head)
+	if start < 0 {
+		return "", fmt.Errorf("unexpected PDF structure: object %d not found", n)
+	}
+	start += len(head)
+	end := bytes.Index(doc[start:], []byte(">>\nendobj"))
+	if end < 0 {
+		return "", fmt.Errorf("unexpected PDF structure: object %d not terminated", n)
+	}
+	return string(bytes.TrimRight(doc[start:start+end], "\n")), nil
+}
+
+// pdfString encodes a text string, as UTF-16 when it isn't ASCII.
+func pdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		var b bytes.Buffer
+		b.WriteByte('(')
+		for _, c := range []byte(s) {
+			if c == '(' || c == ')' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		}
+		b.WriteByte(')')
+		return b.String()
+	}
+	var b bytes.Buffer
+	b.WriteString("<FEFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", c)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+func pdfDate(t time.Time) string {
+	return "(D:" + t.Format("20060102150405") + "Z)"
+}
+
+// xmpMetadata returns the XMP packet matching the document info of a
+// conforming PDF.
+func xmpMetadata(opts Options, now time.Time) []byte {
+	date := now.Format("2006-01-02T15:04:05Z")
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n")
+	b.WriteString(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+	switch opts.PDFConformance {
+	case PDFA2B:
+		b.WriteString(`<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/" pdfaid:part="2" pdfaid:conformance="B"/>` + "\n")
+	case PDFX4:
+		b.WriteString(`<rdf:Description rdf:about="" xmlns:pdfxid="http://www.npes.org/pdfx/ns/id/" pdfxid:GTS_PDFXVersion="PDF/X-4"/>` + "\n")
+	}
+	fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:CreateDate="%s" xmp:ModifyDate="%s" xmp:MetadataDate="%s" xmp:CreatorTool="%s"/>`+"\n",
+		date, date, date, pdfProducer)
+	trapped := ""
+	if opts.PDFConformance == PDFX4 {
+		trapped = ` pdf:Trapped="False"`
+	}
+	fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/" pdf:Producer="%s"%s/>`+"\n", pdfProducer, trapped)
+	if opts.Title != "" {
+		fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:title></rdf:Description>`+"\n",
+			xmlEscape(opts.Title))
+	}
+	if opts.Author != "" {
+		fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator></rdf:Description>`+"\n",
+			xmlEscape(opts.Author))
+	}
+	if opts.Stamp.Attribution != "" {
+		fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:rights><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:rights></rdf:Description>`+"\n",
+			xmlEscape(opts.Stamp.Attribution))
+	}
+	if opts.Comment != "" {
+		fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:description><rdf:Alt><rdf:li xml:lang="x-default">%s</rdf:li></rdf:Alt></dc:description></rdf:Description>`+"\n",
+			xmlEscape(opts.Comment))
+	}
+	b.WriteString("</rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
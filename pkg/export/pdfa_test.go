@@ -0,0 +1,80 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestExportPDF_PDFA(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	opts := Options{PDFConformance: PDFA2B, Title: "Cône", Stamp: Stamp{Text: "page {page}"}}
+	if err := ExportPDF(p, &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.Bytes()
+
+	for _, want := range []string{"/OutputIntents [", "/S /GTS_PDFA1", `pdfaid:part="2"`, "/Metadata ", "/ID [<", "/Title <FEFF0043"} {
+		if !bytes.Contains(doc, []byte(want)) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if !bytes.HasPrefix(doc[bytes.IndexByte(doc, '\n')+1:], []byte("%\xe2")) {
+		t.Error("no binary comment after the header")
+	}
+	// Text must use the embedded font only.
+	if bytes.Contains(doc, []byte("/BaseFont /Helvetica")) || bytes.Contains(doc, []byte("/BaseFont /Arial")) {
+		t.Error("core font referenced")
+	}
+
+	// Every cross-reference entry, original and appended, points at its object.
+	sections := regexp.MustCompile(`(?m)^(\d+) (\d+)\n((?:\d{10} \d{5} [nf] \n)+)`)
+	checked := 0
+	for _, m := range sections.FindAllSubmatch(doc, -1) {
+		first, _ := strconv.Atoi(string(m[1]))
+		for i := 0; i*20 < len(m[3]); i++ {
+			entry := m[3][i*20 : i*20+20]
+			if entry[17] != 'n' {
+				continue
+			}
+			off, _ := strconv.Atoi(string(entry[:10]))
+			want := fmt.Sprintf("%d 0 obj", first+i)
+			if !bytes.HasPrefix(doc[off:], []byte(want)) {
+				t.Errorf("xref entry for object %d points at %q", first+i, doc[off:min(off+12, len(doc))])
+			}
+			checked++
+		}
+	}
+	if checked == 0 {
+		t.Fatal("no cross-reference entries found")
+	}
+}
+
+func TestExportPDF_PDFXNeedsProfile(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ExportPDF(p, &buf, Options{PDFConformance: PDFX4}); err == nil {
+		t.Error("PDF/X without a printer profile succeeded")
+	}
+}
+
+func TestSRGBProfile(t *testing.T) {
+	profile, err := parseICC(srgbProfile().data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.components() != 3 || profile.class != "mntr" {
+		t.Errorf("got %d components, class %q", profile.components(), profile.class)
+	}
+}
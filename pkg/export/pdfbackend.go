@@ -0,0 +1,210 @@
+package export
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// PDFBackend creates the documents the PDF exporter draws on, so PDF
+// output can move to a library with features fpdf lacks, such as layers or
+// link annotations. FPDFBackend is the default.
+type PDFBackend interface {
+	// NewDocument starts a document with pages of the given size in mm.
+	NewDocument(width, height float64) PDFDocument
+}
+
+// PDFPoint is a point on a page in mm from the top left corner.
+type PDFPoint struct {
+	X, Y float64
+}
+
+// PDFMatrix is an affine transform of page points in mm:
+// x' = A*x + C*y + E, y' = B*x + D*y + F.
+type PDFMatrix struct {
+	A, B, C, D, E, F float64
+}
+
+// PDFDocument is a PDF being drawn. Positions are in mm from the top left
+// corner of the page, colors have components from 0 to 255 and drawing
+// goes to the page added last.
+type PDFDocument interface {
+	AddPage()
+	// AddPageSize adds a page of another size in mm than the document's.
+	AddPageSize(width, height float64)
+	// Bookmark adds an outline entry at the top of the current page,
+	// nested level deep. The title is encoded for the current font like
+	// the text of Text.
+	Bookmark(title string, level int)
+	SetTitle(title string)
+	SetAuthor(author string)
+	// SetSubject sets the subject of the document info, the model
+	// comment.
+	SetSubject(subject string)
+	// SetXMPMetadata links an XMP packet from the document catalog.
+	SetXMPMetadata(xmp []byte)
+	// SetTrimBox records the size of the finished page for print
+	// services.
+	SetTrimBox(x, y, w, h float64)
+
+	SetLineWidth(width float64)
+	// SetDashPattern sets the dash and gap lengths of lines, solid when
+	// empty.
+	SetDashPattern(dashes []float64, phase float64)
+	SetDrawColor(r, g, b int)
+	SetFillColor(r, g, b int)
+	// SetAlpha sets the opacity of what is drawn next, 1 is opaque.
+	SetAlpha(alpha float64)
+	Line(x1, y1, x2, y2 float64)
+	// Rect and Polygon draw the outline with style "D", fill with "F" and
+	// do both with "FD".
+	Rect(x, y, w, h float64, style string)
+	Polygon(points []PDFPoint, style string)
+
+	// ClipRect and ClipPolygon limit drawing to an area until the matching
+	// ClipEnd.
+	ClipRect(x, y, w, h float64)
+	ClipPolygon(points []PDFPoint)
+	ClipEnd()
+
+	// RegisterImage adds an image encoded as imageType, "PNG" or "JPG",
+	// under a name.
+	RegisterImage(name, imageType string, data []byte)
+	// DrawImage draws a registered image filling the unit square mapped
+	// through m, its first row at the top.
+	DrawImage(name string, m PDFMatrix)
+	// TransformBegin maps what is drawn through m until the matching
+	// TransformEnd.
+	TransformBegin(m PDFMatrix)
+	TransformEnd()
+
+	// AddFont embeds a TrueType font under a family name.
+	AddFont(family string, ttf []byte)
+	// HasFont reports whether AddFont added a family.
+	HasFont(family string) bool
+	// SetFont selects a family added with AddFont, which draws UTF-8
+	// text, or a standard PDF font, which draws Windows-1252 text. The
+	// size is in points.
+	SetFont(family string, size float64)
+	SetTextColor(r, g, b int)
+	// Text draws s with its baseline starting at x, y.
+	Text(x, y float64, s string)
+	// StringWidth returns the width of s in the current font in mm.
+	StringWidth(s string) float64
+
+	// Output writes the finished document.
+	Output(w io.Writer) error
+}
+
+// FPDFBackend writes PDFs with github.com/go-pdf/fpdf.
+type FPDFBackend struct{}
+
+// NewDocument starts an fpdf document.
+func (FPDFBackend) NewDocument(width, height float64) PDFDocument {
+	return &fpdfDocument{
+		pdf: fpdf.NewCustom(&fpdf.InitType{
+			OrientationStr: "P",
+			UnitStr:        "mm",
+			Size:           fpdf.SizeType{Wd: width, Ht: height},
+		}),
+		images: map[string]fpdf.ImageOptions{},
+	}
+}
+
+type fpdfDocument struct {
+	pdf    *fpdf.Fpdf
+	images map[string]fpdf.ImageOptions
+}
+
+func (d *fpdfDocument) AddPage()                          { d.pdf.AddPage() }
+func (d *fpdfDocument) SetTitle(title string)             { d.pdf.SetTitle(title, true) }
+func (d *fpdfDocument) SetAuthor(author string)           { d.pdf.SetAuthor(author, true) }
+func (d *fpdfDocument) SetSubject(subject string)         { d.pdf.SetSubject(subject, true) }
+func (d *fpdfDocument) SetXMPMetadata(xmp []byte)         { d.pdf.SetXmpMetadata(xmp) }
+func (d *fpdfDocument) SetTrimBox(x, y, w, h float64)     { d.pdf.SetPageBox("trim", x, y, w, h) }
+func (d *fpdfDocument) SetLineWidth(width float64)        { d.pdf.SetLineWidth(width) }
+func (d *fpdfDocument) SetDrawColor(r, g, b int)          { d.pdf.SetDrawColor(r, g, b) }
+func (d *fpdfDocument) SetFillColor(r, g, b int)          { d.pdf.SetFillColor(r, g, b) }
+func (d *fpdfDocument) SetTextColor(r, g, b int)          { d.pdf.SetTextColor(r, g, b) }
+func (d *fpdfDocument) SetAlpha(alpha float64)            { d.pdf.SetAlpha(alpha, "Normal") }
+func (d *fpdfDocument) Line(x1, y1, x2, y2 float64)       { d.pdf.Line(x1, y1, x2, y2) }
+func (d *fpdfDocument) Rect(x, y, w, h float64, s string) { d.pdf.Rect(x, y, w, h, s) }
+func (d *fpdfDocument) ClipRect(x, y, w, h float64)       { d.pdf.ClipRect(x, y, w, h, false) }
+func (d *fpdfDocument) ClipEnd()                          { d.pdf.ClipEnd() }
+func (d *fpdfDocument) Text(x, y float64, s string)       { d.pdf.Text(x, y, s) }
+func (d *fpdfDocument) StringWidth(s string) float64      { return d.pdf.GetStringWidth(s) }
+func (d *fpdfDocument) Output(w io.Writer) error          { return d.pdf.Output(w) }
+
+func (d *fpdfDocument) AddPageSize(width, height float64) {
+	d.pdf.AddPageFormat("P", fpdf.SizeType{Wd: width, Ht: height})
+}
+
+func (d *fpdfDocument) Bookmark(title string, level int) {
+	d.pdf.Bookmark(title, level, 0)
+}
+
+func (d *fpdfDocument) SetDashPattern(dashes []float64, phase float64) {
+	d.pdf.SetDashPattern(dashes, phase)
+}
+
+func (d *fpdfDocument) Polygon(points []PDFPoint, style string) {
+	d.pdf.Polygon(fpdfPoints(points), style)
+}
+
+func (d *fpdfDocument) ClipPolygon(points []PDFPoint) {
+	d.pdf.ClipPolygon(fpdfPoints(points), false)
+}
+
+func fpdfPoints(points []PDFPoint) []fpdf.PointType {
+	out := make([]fpdf.PointType, len(points))
+	for i, p := range points {
+		out[i] = fpdf.PointType{X: p.X, Y: p.Y}
+	}
+	return out
+}
+
+func (d *fpdfDocument) RegisterImage(name, imageType string, data []byte) {
+	opts := fpdf.ImageOptions{ImageType: imageType}
+	d.pdf.RegisterImageOptionsReader(name, opts, bytes.NewReader(data))
+	d.images[name] = opts
+}
+
+func (d *fpdfDocument) DrawImage(name string, m PDFMatrix) {
+	_, pageH := d.pdf.GetPageSize()
+	d.pdf.TransformBegin()
+	d.pdf.Transform(userSpace(m, pageH, d.pdf.GetConversionRatio()))
+	d.pdf.ImageOptions(name, 0, 0, 1, 1, false, d.images[name], 0, "")
+	d.pdf.TransformEnd()
+}
+
+func (d *fpdfDocument) TransformBegin(m PDFMatrix) {
+	_, pageH := d.pdf.GetPageSize()
+	d.pdf.TransformBegin()
+	d.pdf.Transform(userSpace(m, pageH, d.pdf.GetConversionRatio()))
+}
+
+func (d *fpdfDocument) TransformEnd() { d.pdf.TransformEnd() }
+
+// userSpace converts a transform of page mm to PDF user space, for a page
+// pageH mm high and k user space units per mm.
+func userSpace(m PDFMatrix, pageH, k float64) fpdf.TransformMatrix {
+	// Conjugate with the mm to user space mapping (x, y) -> (k*x, k*(pageH-y)).
+	return fpdf.TransformMatrix{
+		A: m.A, B: -m.B, C: -m.C, D: m.D,
+		E: k * (m.C*pageH + m.E),
+		F: k * (pageH - m.D*pageH - m.F),
+	}
+}
+
+func (d *fpdfDocument) AddFont(family string, ttf []byte) {
+	d.pdf.AddUTF8FontFromBytes(family, "", ttf)
+}
+
+func (d *fpdfDocument) HasFont(family string) bool {
+	return d.pdf.GetFontDesc(family, "").Ascent != 0
+}
+
+func (d *fpdfDocument) SetFont(family string, size float64) {
+	d.pdf.SetFont(family, "", size)
+}
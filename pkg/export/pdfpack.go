@@ -0,0 +1,106 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// PDFModel is one model of a PDF pack.
+type PDFModel struct {
+	// Title names the model in the bookmarks, on its title page and in
+	// the {name} of stamps.
+	Title string
+	PDO   *pdo.PDO
+}
+
+// PDFPack lists the models ExportPDFPack merges into one PDF.
+type PDFPack struct {
+	Models []PDFModel
+	// TitlePages starts every model with a page showing its title,
+	// author, comment and number of parts and pages.
+	TitlePages bool
+}
+
+// Title page layout in mm and points.
+const (
+	titlePageTop   = 40 // Baseline of the title below the top margin
+	titlePageSize  = 24 // Title font size in points
+	titlePageText  = 11 // Font size of the lines below in points
+	titlePageSpace = 7  // Distance between the lines below
+)
+
+// ExportPDFPack writes several models into one PDF, each bookmarked under
+// its title. Every model keeps its own page size, layout and page
+// numbering; opts apply to all of them.
+func ExportPDFPack(pack PDFPack, w io.Writer, opts Options) error {
+	if len(pack.Models) == 0 {
+		return errors.New("export: no models to merge")
+	}
+	models := make([]*pdo.PDO, len(pack.Models))
+	for i, m := range pack.Models {
+		if err := checkUnfold(m.PDO); err != nil {
+			return fmt.Errorf("%s: %w", m.Title, err)
+		}
+		models[i] = opts.lines(m.PDO)
+	}
+
+	slots := float64(opts.Imposition.slots())
+	first := models[0].PageDims()
+	pdf := opts.pdfBackend().NewDocument(first.Width*slots, first.Height)
+	fonts := newPDFFonts(pdf, opts.PDFConformance != PDFStandard)
+	opts = opts.metadata(models...)
+	setPDFMetadata(pdf, opts)
+
+	for i, p := range models {
+		title := pack.Models[i].Title
+		dims := p.PageDims()
+		marked := false
+		addPage := func() {
+			pdf.AddPageSize(dims.Width*slots, dims.Height)
+			if opts.PDFConformance == PDFX4 {
+				pdf.SetTrimBox(0, 0, dims.Width*slots, dims.Height)
+			}
+			if !marked {
+				pdf.Bookmark(fonts.use("Arial", titlePageText, title), 0)
+				marked = true
+			}
+		}
+
+		modelOpts := opts
+		if title != "" {
+			modelOpts.Stamp.Name = title
+		}
+		if pack.TitlePages {
+			addPage()
+			writeTitlePagePDF(pdf, fonts, p, title, dims, len(opts.posterPages(p, dims)))
+		}
+		if err := writeModelPDF(pdf, fonts, p, fmt.Sprintf("m%d_", i), addPage, modelOpts); err != nil {
+			return fmt.Errorf("%s: %w", title, err)
+		}
+	}
+	return outputPDF(pdf, w, opts)
+}
+
+// writeTitlePagePDF draws the title page of a model in a pack.
+func writeTitlePagePDF(pdf PDFDocument, fonts *pdfFonts, p *pdo.PDO, title string, dims pdo.PageDims, pages int) {
+	pdf.SetTextColor(0, 0, 0)
+	x, y := dims.MarginLeft, dims.MarginTop+titlePageTop
+	pdf.Text(x, y, fonts.use("Arial", titlePageSize, title))
+
+	lines := []string{fmt.Sprintf("%d parts on %d pages", len(p.Parts), pages)}
+	if p.Settings.AuthorName != "" {
+		lines = append([]string{p.Settings.AuthorName}, lines...)
+	}
+	if p.Settings.Comment != "" {
+		lines = append(lines, strings.Split(strings.ReplaceAll(p.Settings.Comment, "\r\n", "\n"), "\n")...)
+	}
+	y += titlePageSize * ptToMM
+	for _, line := range lines {
+		y += titlePageSpace
+		pdf.Text(x, y, fonts.use("Arial", titlePageText, line))
+	}
+}
@@ -0,0 +1,241 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// cmykJPEGQuality is the JPEG quality of CMYK textures.
+const cmykJPEGQuality = 90
+
+// pdfTextures draws the textures of textured faces, or the 2D color of
+// every face's material. Each texture is embedded once as an image
+// referenced from every face using it, also when several materials share
+// the texture.
+type pdfTextures struct {
+	pdf  PDFDocument
+	p    *pdo.PDO
+	fill FaceFill
+	// encoded holds the image of each material ready to embed, nil for
+	// materials without a usable texture. Materials with the same texture
+	// share the image.
+	encoded []*encodedTexture
+	// registered records the images in the document by name.
+	registered map[string]bool
+	// prefix sets the image names of one model apart from those of others
+	// in the same document.
+	prefix string
+}
+
+// encodedTexture is a texture converted for embedding in the PDF.
+type encodedTexture struct {
+	name      string // Named after the first material using it
+	imageType string // PNG or JPG
+	data      []byte
+}
+
+// newPDFTextures converts the textures of the materials on parts, on
+// opts.Workers goroutines since decoding and encoding large textures takes
+// most of the time of a textured export.
+func newPDFTextures(pdf PDFDocument, p *pdo.PDO, opts Options) *pdfTextures {
+	t := &pdfTextures{
+		pdf:        pdf,
+		p:          p,
+		fill:       opts.FaceFill.resolve(p.Settings),
+		encoded:    make([]*encodedTexture, len(p.Materials)),
+		registered: map[string]bool{},
+	}
+	if t.fill == FaceFillColor {
+		return t
+	}
+	used := make([]bool, len(p.Materials))
+	for _, face := range p.AllFaces() {
+		if m := face.MaterialIndex; face.PartIndex >= 0 && m >= 0 && int(m) < len(used) {
+			used[m] = true
+		}
+	}
+	first := textureOwners(p)
+	for i, j := range first {
+		if used[i] {
+			used[j] = true
+		}
+	}
+	parallel(len(p.Materials), opts.Workers, func(i int) {
+		if used[i] && first[i] == i && p.Materials[i].HasTexture {
+			t.encoded[i] = encodeTexture(&p.Materials[i], textureImageName(int32(i)), opts)
+		}
+	})
+	for i, j := range first {
+		t.encoded[i] = t.encoded[j]
+	}
+	return t
+}
+
+// encodeTexture decodes the texture of a material and encodes it for the
+// PDF, as CMYK and dithered if asked to. It returns nil when the texture
+// can't be decoded.
+func encodeTexture(mat *pdo.Material, name string, opts Options) *encodedTexture {
+	log := opts.logger()
+	img, err := mat.Texture.GetImage()
+	if err != nil {
+		log.Warn("failed to decode texture", "material", mat.Name, "err", err)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	tex := &encodedTexture{name: name, imageType: "PNG"}
+	if opts.CMYK {
+		// Dither the inks rather than the screen colors.
+		img = opts.Dither.reduce(toCMYK(opts.Dither.gray(img)))
+	} else {
+		img = opts.Dither.prepare(img)
+	}
+	if cmyk, ok := img.(*image.CMYK); ok {
+		tex.imageType = "JPG"
+		quality := cmykJPEGQuality
+		if opts.Dither.Mode != DitherOff {
+			quality = 100 // Keep the dither pattern crisp
+		}
+		err = encodeCMYKJPEG(&buf, cmyk, quality)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		log.Warn("failed to encode texture", "material", mat.Name, "err", err)
+		return nil
+	}
+	tex.data = buf.Bytes()
+	return tex
+}
+
+// image registers the texture of material m on first use and returns its
+// image name, false if the material has no texture.
+func (t *pdfTextures) image(m int32) (string, bool) {
+	if m < 0 || int(m) >= len(t.encoded) || t.encoded[m] == nil {
+		return "", false
+	}
+	tex := t.encoded[m]
+	name := t.prefix + tex.name
+	if !t.registered[name] {
+		t.pdf.RegisterImage(name, tex.imageType, tex.data)
+		t.registered[name] = true
+	}
+	return name, true
+}
+
+// textureOwners returns for every material the first material with the
+// same texture, Pepakura stores a copy of the texture with every material
+// using it. Materials without a texture own themselves.
+func textureOwners(p *pdo.PDO) []int {
+	first := make([]int, len(p.Materials))
+	for i := range p.Materials {
+		first[i] = i
+		if !p.Materials[i].HasTexture {
+			continue
+		}
+		for j := range i {
+			if p.Materials[j].HasTexture && p.Materials[j].Texture.Equal(&p.Materials[i].Texture) {
+				first[i] = j
+				break
+			}
+		}
+	}
+	return first
+}
+
+func textureImageName(m int32) string {
+	return fmt.Sprintf("texture%d", m)
+}
+
+// toCMYK separates an image with the naive conversion of image/color. The
+// output intent profile tells the printer how to interpret the values.
+func toCMYK(img image.Image) *image.CMYK {
+	out := image.NewCMYK(img.Bounds())
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+	return out
+}
+
+// draw paints the faces of a part beneath its lines, with their material
+// color or texture. Textured faces are split into a triangle fan, every triangle clipped and mapped from texture
+// space with its own affine transform.
+func (t *pdfTextures) draw(part *pdo.Part, offX, offY float64) {
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(t.p.Objects) {
+		return
+	}
+	for _, face := range t.p.Objects[part.ObjectIndex].PartFaces(partIndex(t.p, part)) {
+		if len(face.Vertices) < 3 {
+			continue
+		}
+		at := func(v pdo.Face2DVertex) PDFPoint {
+			return PDFPoint{X: v.X + part.BoundingBox.Left - offX, Y: v.Y + part.BoundingBox.Top - offY}
+		}
+		if t.fill == FaceFillColor {
+			t.drawColor(face, at)
+			continue
+		}
+		name, ok := t.image(face.MaterialIndex)
+		if !ok {
+			continue
+		}
+		v0 := face.Vertices[0]
+		for i := 1; i+1 < len(face.Vertices); i++ {
+			v1, v2 := face.Vertices[i], face.Vertices[i+1]
+			m, ok := textureMatrix(v0, v1, v2, at(v0), at(v1), at(v2))
+			if !ok {
+				continue
+			}
+			t.pdf.ClipPolygon([]PDFPoint{at(v0), at(v1), at(v2)})
+			// The image fills the unit square, texture coordinates are
+			// fractions of its size with V running down from the first row.
+			t.pdf.DrawImage(name, m)
+			t.pdf.ClipEnd()
+		}
+	}
+}
+
+// drawColor fills a face with the 2D color of its material.
+func (t *pdfTextures) drawColor(face *pdo.Face, at func(pdo.Face2DVertex) PDFPoint) {
+	if face.MaterialIndex < 0 || int(face.MaterialIndex) >= len(t.p.Materials) {
+		return
+	}
+	c := t.p.Materials[face.MaterialIndex].Color2DRGBA
+	points := make([]PDFPoint, len(face.Vertices))
+	for i, v := range face.Vertices {
+		points[i] = at(v)
+	}
+	t.pdf.SetFillColor(int(unitByte(c[0])), int(unitByte(c[1])), int(unitByte(c[2])))
+	if c[3] < 1 {
+		t.pdf.SetAlpha(float64(max(c[3], 0)))
+		defer t.pdf.SetAlpha(1)
+	}
+	t.pdf.Polygon(points, "F")
+}
+
+// textureMatrix returns the transform taking the unit square, where an
+// image drawn with DrawImage fills texture space, to the page so the
+// texture coordinates of three vertices land on their points p0-p2.
+// It reports false for degenerate texture coordinates.
+func textureMatrix(v0, v1, v2 pdo.Face2DVertex, p0, p1, p2 PDFPoint) (PDFMatrix, bool) {
+	du1, dv1 := v1.U-v0.U, v1.V-v0.V
+	du2, dv2 := v2.U-v0.U, v2.V-v0.V
+	det := du1*dv2 - du2*dv1
+	if math.Abs(det) < 1e-12 {
+		return PDFMatrix{}, false
+	}
+	// Affine map in page mm: x = a*u + c*v + e, y = b*u + d*v + f.
+	dx1, dy1 := p1.X-p0.X, p1.Y-p0.Y
+	dx2, dy2 := p2.X-p0.X, p2.Y-p0.Y
+	a := (dx1*dv2 - dx2*dv1) / det
+	c := (du1*dx2 - du2*dx1) / det
+	b := (dy1*dv2 - dy2*dv1) / det
+	d := (du1*dy2 - du2*dy1) / det
+	e := p0.X - a*v0.U - c*v0.V
+	f := p0.Y - b*v0.U - d*v0.V
+	return PDFMatrix{A: a, B: b, C: c, D: d, E: e, F: f}, true
+}
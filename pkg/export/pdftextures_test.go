@@ -0,0 +1,143 @@
+package export
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestTextureMatrix(t *testing.T) {
+	const pageH, k = 297.0, 72 / 25.4
+	v := []pdo.Face2DVertex{{U: 0.1, V: 0.2}, {U: 0.9, V: 0.3}, {U: 0.4, V: 0.8}}
+	p := []PDFPoint{{X: 20, Y: 40}, {X: 60, Y: 35}, {X: 30, Y: 90}}
+	mm, ok := textureMatrix(v[0], v[1], v[2], p[0], p[1], p[2])
+	if !ok {
+		t.Fatal("matrix not found")
+	}
+	m := userSpace(mm, pageH, k)
+	for i := range v {
+		// Where the image at (0, 0) with size 1 draws texture point (U, V),
+		// in user space, after the transform.
+		x, y := k*v[i].U, k*(pageH-v[i].V)
+		gx, gy := m.A*x+m.C*y+m.E, m.B*x+m.D*y+m.F
+		wx, wy := k*p[i].X, k*(pageH-p[i].Y)
+		if math.Abs(gx-wx) > 1e-9 || math.Abs(gy-wy) > 1e-9 {
+			t.Errorf("vertex %d: got (%g, %g), want (%g, %g)", i, gx, gy, wx, wy)
+		}
+	}
+
+	if _, ok := textureMatrix(v[0], v[0], v[2], p[0], p[1], p[2]); ok {
+		t.Error("degenerate texture coordinates accepted")
+	}
+}
+
+func TestExportPDF_CMYKTextures(t *testing.T) {
+	p := texturedCone(t)
+
+	var buf bytes.Buffer
+	if err := ExportPDF(p, &buf, Options{Textures: true, CMYK: true}); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.Bytes()
+	for _, want := range []string{"/ColorSpace /DeviceCMYK", "/Decode [1 0 1 0 1 0 1 0]"} {
+		if !bytes.Contains(doc, []byte(want)) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if n := bytes.Count(doc, []byte("/Subtype /Image")); n != 1 {
+		t.Errorf("texture embedded %d times, want once", n)
+	}
+
+	// PDF/A has an sRGB output intent, which doesn't cover CMYK images.
+	if err := ExportPDF(p, &bytes.Buffer{}, Options{Textures: true, CMYK: true, PDFConformance: PDFA2B}); err == nil {
+		t.Error("CMYK textures accepted with an sRGB output intent")
+	}
+}
+
+func TestExportPDF_Workers(t *testing.T) {
+	reproduciblePDF(t)
+	p := texturedCone(t)
+	var want bytes.Buffer
+	if err := ExportPDF(p, &want, Options{Textures: true, Workers: 1}); err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 4} {
+		var got bytes.Buffer
+		if err := ExportPDF(p, &got, Options{Textures: true, Workers: workers}); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("%d workers: output differs from a single worker", workers)
+		}
+	}
+}
+
+func TestExportPDF_SharedTextures(t *testing.T) {
+	// Every cube has a material of its own with the same texture.
+	p := pdotest.Cube(pdotest.Options{Texture: true, Pages: 3})
+	for i := range p.Objects {
+		mat := p.Materials[0]
+		mat.Name = fmt.Sprintf("paper%d", i)
+		p.Materials = append(p.Materials, mat)
+		for j := range p.Objects[i].Faces {
+			p.Objects[i].Faces[j].MaterialIndex = int32(len(p.Materials) - 1)
+		}
+	}
+	counter := &countingBackend{}
+	if err := ExportPDF(p, io.Discard, Options{Textures: true, PDFBackend: counter}); err != nil {
+		t.Fatal(err)
+	}
+	if counter.images != 1 {
+		t.Errorf("texture registered %d times, want once", counter.images)
+	}
+}
+
+// texturedCone returns the cone sample with a 2x2 red, green, blue and
+// white texture on every face, mapped 1:1 to 100mm.
+func texturedCone(t *testing.T) *pdo.PDO {
+	t.Helper()
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw bytes.Buffer
+	zw, _ := flate.NewWriter(&raw, flate.DefaultCompression)
+	zw.Write([]byte{255, 0, 0, 0, 255, 0, 0, 0, 255, 255, 255, 255})
+	zw.Close()
+	p.Materials = []pdo.Material{{Name: "paper", HasTexture: true, Texture: pdo.Texture{Width: 2, Height: 2, RawData: raw.Bytes()}}}
+	for i := range p.Objects {
+		for j := range p.Objects[i].Faces {
+			face := &p.Objects[i].Faces[j]
+			face.MaterialIndex = 0
+			for n := range face.Vertices {
+				face.Vertices[n].U, face.Vertices[n].V = face.Vertices[n].X/100, face.Vertices[n].Y/100
+			}
+		}
+	}
+	return p
+}
+
+func TestExportPDF_OutputProfile(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ExportPDF(p, &buf, Options{OutputProfile: srgbProfile().data}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"/OutputIntents [", "/S /GTS_PDFX"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if bytes.Contains(buf.Bytes(), []byte("pdfaid:")) || bytes.Contains(buf.Bytes(), []byte("GTS_PDFXVersion")) {
+		t.Error("plain PDF claims conformance")
+	}
+}
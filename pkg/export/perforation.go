@@ -0,0 +1,102 @@
+package export
+
+import (
+	"fmt"
+	"math"
+)
+
+// PerforationMode selects how fold lines are turned into cuts.
+type PerforationMode int
+
+const (
+	// PerforateOff draws fold lines as folds.
+	PerforateOff PerforationMode = iota
+	// PerforateDash cuts fold lines as a row of dashes, so a cutting machine
+	// without a scoring tool prepares the folds.
+	PerforateDash
+	// PerforateTicks cuts a short tick at both ends of each fold line, to
+	// show where to fold without weakening the paper.
+	PerforateTicks
+)
+
+// ParsePerforationMode converts a perforation name ("off", "dash", "ticks")
+// into a PerforationMode.
+func ParsePerforationMode(s string) (PerforationMode, error) {
+	switch s {
+	case "off", "none", "":
+		return PerforateOff, nil
+	case "dash":
+		return PerforateDash, nil
+	case "ticks":
+		return PerforateTicks, nil
+	}
+	return PerforateOff, fmt.Errorf("unknown perforation %q", s)
+}
+
+// Default perforation lengths in mm.
+const (
+	DefaultPerforationDash = 1.0
+	DefaultPerforationGap  = 1.0
+)
+
+// Perforation converts mountain and valley folds into cut lines in 2D
+// formats.
+type Perforation struct {
+	Mode PerforationMode
+	// Dash is the length in mm of each cut, the tick length with
+	// PerforateTicks. DefaultPerforationDash when 0.
+	Dash float64
+	// Gap is the uncut length in mm between dashes, DefaultPerforationGap
+	// when 0.
+	Gap float64
+}
+
+func (pf Perforation) lengths() (dash, gap float64) {
+	dash, gap = pf.Dash, pf.Gap
+	if dash <= 0 {
+		dash = DefaultPerforationDash
+	}
+	if gap <= 0 {
+		gap = DefaultPerforationGap
+	}
+	return dash, gap
+}
+
+// cuts returns the cut lines replacing a fold line. Dashes are centered on
+// the line so both ends look alike.
+func (pf Perforation) cuts(l partLine) []partLine {
+	length := math.Hypot(l.X2-l.X1, l.Y2-l.Y1)
+	if length == 0 {
+		return nil
+	}
+	dash, gap := pf.lengths()
+	piece := func(from, to float64) partLine {
+		cut := l
+		cut.Type = lineCut
+		cut.Color = nil
+		cut.X1, cut.Y1 = lerpLine(l, from/length)
+		cut.X2, cut.Y2 = lerpLine(l, to/length)
+		return cut
+	}
+	if pf.Mode == PerforateTicks {
+		tick := min(dash, length/3)
+		return []partLine{piece(0, tick), piece(length-tick, length)}
+	}
+	n := int((length + gap) / (dash + gap))
+	if n == 0 {
+		// Shorter than a dash, cut its middle.
+		return []partLine{piece(length/2-length/4, length/2+length/4)}
+	}
+	start := (length - float64(n)*dash - float64(n-1)*gap) / 2
+	cuts := make([]partLine, n)
+	for i := range cuts {
+		from := start + float64(i)*(dash+gap)
+		cuts[i] = piece(from, from+dash)
+	}
+	return cuts
+}
+
+// lerpLine returns the point a fraction t along a line.
+func lerpLine(l partLine, t float64) (float64, float64) {
+	return l.X1 + (l.X2-l.X1)*t, l.Y1 + (l.Y2-l.Y1)*t
+}
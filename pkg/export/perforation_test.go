@@ -0,0 +1,48 @@
+package export
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPerforationCuts(t *testing.T) {
+	fold := partLine{X1: 0, Y1: 0, X2: 10, Y2: 0, Type: lineValley, Material: 2}
+
+	cuts := Perforation{Mode: PerforateDash, Dash: 2, Gap: 1}.cuts(fold)
+	// Three dashes and two gaps fill 8 mm, centered on the 10 mm line.
+	if len(cuts) != 3 {
+		t.Fatalf("got %d dashes, want 3", len(cuts))
+	}
+	if cuts[0].X1 != 1 || cuts[2].X2 != 9 {
+		t.Errorf("dashes run from %g to %g, want 1 to 9", cuts[0].X1, cuts[2].X2)
+	}
+	for _, c := range cuts {
+		if c.Type != lineCut || c.Material != 2 || math.Abs(c.X2-c.X1-2) > 1e-9 {
+			t.Errorf("dash %+v", c)
+		}
+	}
+
+	ticks := Perforation{Mode: PerforateTicks}.cuts(fold)
+	if len(ticks) != 2 || ticks[0].X1 != 0 || ticks[0].X2 != DefaultPerforationDash || ticks[1].X2 != 10 {
+		t.Errorf("ticks %+v", ticks)
+	}
+
+	short := partLine{X2: 0.5, Type: lineMountain}
+	if got := (Perforation{Mode: PerforateDash}).cuts(short); len(got) != 1 {
+		t.Errorf("%d cuts on a short fold, want 1", len(got))
+	}
+
+	p := texturedCone(t)
+	folds := 0
+	for l := range templateLines(p, p.Lookups().Part(&p.Parts[0]), &p.Parts[0], FlapAuto, 0, Perforation{Mode: PerforateDash}) {
+		if l.Type == lineMountain || l.Type == lineValley {
+			folds++
+		}
+	}
+	if folds != 0 {
+		t.Errorf("%d fold lines left after perforating", folds)
+	}
+	if _, err := ParsePerforationMode("zigzag"); err == nil {
+		t.Error("ParsePerforationMode accepted an unknown mode")
+	}
+}
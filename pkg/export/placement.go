@@ -0,0 +1,163 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Placement moves the model in 3D exports. The steps apply in field order.
+type Placement struct {
+	// Origin moves Header.OriginOffset, where Pepakura Designer keeps the
+	// model center, to the origin.
+	Origin bool
+	// Center moves the center of the bounding box to the origin.
+	Center bool
+	// Scale, Rotate and Translate transform the model once it is in the
+	// output units: a uniform scale (0 keeps the size), rotations in
+	// degrees around X, Y and Z, then a translation.
+	Scale     float64
+	Rotate    [3]float64
+	Translate [3]float64
+	// Ground finally moves the model along Z so its lowest point sits on
+	// Z=0, replacing the Z translation.
+	Ground bool
+}
+
+// ParseTransform reads a comma-separated list of "s=2", "rx=90", "ry",
+// "rz", "tx=10", "ty" and "tz" settings into the placement.
+func ParseTransform(s string, pl *Placement) error {
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("invalid transform %q", field)
+		}
+		switch strings.TrimSpace(key) {
+		case "s":
+			if v <= 0 {
+				return fmt.Errorf("transform scale %g must be positive", v)
+			}
+			pl.Scale = v
+		case "rx":
+			pl.Rotate[0] = v
+		case "ry":
+			pl.Rotate[1] = v
+		case "rz":
+			pl.Rotate[2] = v
+		case "tx":
+			pl.Translate[0] = v
+		case "ty":
+			pl.Translate[1] = v
+		case "tz":
+			pl.Translate[2] = v
+		default:
+			return fmt.Errorf("unknown transform %q", key)
+		}
+	}
+	return nil
+}
+
+// meshPlacement maps model vertices into the coordinates of a 3D export.
+type meshPlacement struct {
+	shift pdo.Vertex3D // Subtracted in model units
+	scale float64
+	rot   [9]float64 // Row-major rotation
+	move  pdo.Vertex3D
+}
+
+// placement returns the vertex mapping of a 3D format. modelUnits formats
+// keep the model's units by default, the others are in mm.
+func (o Options) placement(p *pdo.PDO, modelUnits bool) meshPlacement {
+	pl := o.Placement
+	m := meshPlacement{scale: realScale(p) * o.Units.perMM()}
+	if o.Units == UnitsDefault && modelUnits {
+		m.scale = 1
+	}
+	if pl.Scale > 0 {
+		m.scale *= pl.Scale
+	}
+	if pl.Origin {
+		m.shift = pdo.Vertex3D{X: p.Header.OriginOffset[0], Y: p.Header.OriginOffset[1], Z: p.Header.OriginOffset[2]}
+	}
+	if pl.Center {
+		m.shift = modelCenter(p)
+	}
+
+	m.rot = [9]float64{1, 0, 0, 0, 1, 0, 0, 0, 1}
+	for axis, deg := range pl.Rotate {
+		if deg == 0 {
+			continue
+		}
+		s, c := math.Sincos(deg * math.Pi / 180)
+		var r [9]float64
+		switch axis {
+		case 0:
+			r = [9]float64{1, 0, 0, 0, c, -s, 0, s, c}
+		case 1:
+			r = [9]float64{c, 0, s, 0, 1, 0, -s, 0, c}
+		default:
+			r = [9]float64{c, -s, 0, s, c, 0, 0, 0, 1}
+		}
+		m.rot = mul3(r, m.rot)
+	}
+	m.move = pdo.Vertex3D{X: pl.Translate[0], Y: pl.Translate[1], Z: pl.Translate[2]}
+
+	if pl.Ground {
+		lowest := math.Inf(1)
+		m.move.Z = 0
+		for _, obj := range p.Objects {
+			for _, v := range obj.Vertices {
+				lowest = min(lowest, m.point(v).Z)
+			}
+		}
+		if !math.IsInf(lowest, 1) {
+			m.move.Z = -lowest
+		}
+	}
+	return m
+}
+
+func mul3(a, b [9]float64) [9]float64 {
+	var r [9]float64
+	for i := range 3 {
+		for j := range 3 {
+			r[i*3+j] = a[i*3]*b[j] + a[i*3+1]*b[3+j] + a[i*3+2]*b[6+j]
+		}
+	}
+	return r
+}
+
+func (m meshPlacement) rotate(v pdo.Vertex3D) pdo.Vertex3D {
+	r := m.rot
+	return pdo.Vertex3D{
+		X: r[0]*v.X + r[1]*v.Y + r[2]*v.Z,
+		Y: r[3]*v.X + r[4]*v.Y + r[5]*v.Z,
+		Z: r[6]*v.X + r[7]*v.Y + r[8]*v.Z,
+	}
+}
+
+func (m meshPlacement) point(v pdo.Vertex3D) pdo.Vertex3D {
+	v = m.rotate(pdo.Vertex3D{X: (v.X - m.shift.X) * m.scale, Y: (v.Y - m.shift.Y) * m.scale, Z: (v.Z - m.shift.Z) * m.scale})
+	return pdo.Vertex3D{X: v.X + m.move.X, Y: v.Y + m.move.Y, Z: v.Z + m.move.Z}
+}
+
+// normal turns a normal with the model.
+func (m meshPlacement) normal(n pdo.Vertex3D) pdo.Vertex3D {
+	return m.rotate(n)
+}
+
+func (m meshPlacement) vertices(obj pdo.Object) []pdo.Vertex3D {
+	vs := make([]pdo.Vertex3D, len(obj.Vertices))
+	for i, v := range obj.Vertices {
+		vs[i] = m.point(v)
+	}
+	return vs
+}
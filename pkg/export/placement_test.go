@@ -0,0 +1,58 @@
+package export
+
+import (
+	"math"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestPlacement(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := func(pl Placement) (lo, hi pdo.Vertex3D) {
+		m := (Options{Placement: pl}).placement(p, true)
+		lo = pdo.Vertex3D{X: math.Inf(1), Y: math.Inf(1), Z: math.Inf(1)}
+		hi = pdo.Vertex3D{X: math.Inf(-1), Y: math.Inf(-1), Z: math.Inf(-1)}
+		for _, obj := range p.Objects {
+			for _, v := range m.vertices(obj) {
+				lo = pdo.Vertex3D{X: min(lo.X, v.X), Y: min(lo.Y, v.Y), Z: min(lo.Z, v.Z)}
+				hi = pdo.Vertex3D{X: max(hi.X, v.X), Y: max(hi.Y, v.Y), Z: max(hi.Z, v.Z)}
+			}
+		}
+		return lo, hi
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+	lo, hi := bounds(Placement{Center: true})
+	if !near(lo.X+hi.X, 0) || !near(lo.Y+hi.Y, 0) || !near(lo.Z+hi.Z, 0) {
+		t.Errorf("centered bounds %+v - %+v", lo, hi)
+	}
+	height := hi.Y - lo.Y
+
+	// Standing the Y-up model on Z: its height goes along Z, from 0.
+	pl := Placement{Center: true, Ground: true}
+	if err := ParseTransform("s=2, rx=90, tx=5", &pl); err != nil {
+		t.Fatal(err)
+	}
+	lo, hi = bounds(pl)
+	if !near(lo.Z, 0) || !near(hi.Z, 2*height) {
+		t.Errorf("grounded Z %g - %g, want 0 - %g", lo.Z, hi.Z, 2*height)
+	}
+	if !near(lo.X+hi.X, 10) {
+		t.Errorf("translated X center %g, want 5", (lo.X+hi.X)/2)
+	}
+
+	m := (Options{Placement: pl}).placement(p, true)
+	if n := m.normal(pdo.Vertex3D{Y: 1}); !near(n.Z, 1) {
+		t.Errorf("rotated normal %+v, want +Z", n)
+	}
+
+	for _, bad := range []string{"s=0", "rw=1", "tx", "tx=abc"} {
+		if err := ParseTransform(bad, &Placement{}); err == nil {
+			t.Errorf("ParseTransform(%q) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package export
+
+import (
+	"fmt"
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// DefaultPosterOverlap is the default width in mm of the glue strip shared
+// by neighbouring poster tiles.
+const DefaultPosterOverlap = 10.0
+
+// Poster splits parts larger than the printable area across several pages
+// of PDF and PNG output, for builds at a scale no printer takes in one
+// sheet. Neighbouring tiles share a strip of the part: it is shaded on the
+// tile that goes beneath, as the glue strip, and both tiles carry alignment
+// marks in its middle.
+type Poster struct {
+	// Enabled tiles oversized parts, which are otherwise printed on one
+	// page and cut off at its edge.
+	Enabled bool
+	// Overlap is the width of the shared strip in mm, DefaultPosterOverlap
+	// when 0. It is limited to a quarter of the printable area.
+	Overlap float64
+}
+
+// Poster marks: the glue strip shade, and the size and width of the
+// alignment crosses in mm.
+var posterGlueColor = [3]uint8{0xee, 0xee, 0xee}
+
+const (
+	posterMarkSize  = 5.0
+	posterMarkWidth = 0.2
+)
+
+func (ps Poster) overlap(dims pdo.PageDims) float64 {
+	ov := ps.Overlap
+	if ov <= 0 {
+		ov = DefaultPosterOverlap
+	}
+	return min(ov, dims.ClippedWidth/4, dims.ClippedHeight/4)
+}
+
+// posterTile is a page showing a window of a part too large for one page.
+type posterTile struct {
+	part       int // Index in PDO.Parts
+	col, row   int
+	cols, rows int
+	overlap    float64
+	left, top  float64 // Layout position at the printable area's top left
+}
+
+// posterPages returns the pages of the layout with oversized parts
+// replaced by tile pages in poster mode, following the page the part was
+// on. Tiles showing nothing of their part and pages left empty are dropped.
+func (o Options) posterPages(p *pdo.PDO, dims pdo.PageDims) []layoutPage {
+	pages := layoutPages(p, dims)
+	if !o.Poster.Enabled {
+		return pages
+	}
+	ov := o.Poster.overlap(dims)
+	lookups := p.Lookups()
+	var out []layoutPage
+	for _, page := range pages {
+		var fits []*pdo.Part
+		var tiles []layoutPage
+		for _, part := range page.parts {
+			bb := part.BoundingBox
+			if bb.Width <= dims.ClippedWidth && bb.Height <= dims.ClippedHeight {
+				fits = append(fits, part)
+				continue
+			}
+			cols, rows := tileCount(bb.Width, dims.ClippedWidth, ov), tileCount(bb.Height, dims.ClippedHeight, ov)
+			for row := range rows {
+				for col := range cols {
+					t := &posterTile{
+						part: partIndex(p, part), col: col, row: row, cols: cols, rows: rows, overlap: ov,
+						left: bb.Left + float64(col)*(dims.ClippedWidth-ov),
+						top:  bb.Top + float64(row)*(dims.ClippedHeight-ov),
+					}
+					if t.shows(p, lookups.Part(part), part, dims, o.FlapStyle) {
+						tiles = append(tiles, layoutPage{px: page.px, py: page.py, parts: []*pdo.Part{part}, tile: t})
+					}
+				}
+			}
+		}
+		if len(fits) > 0 || len(page.texts) > 0 {
+			page.parts = fits
+			out = append(out, page)
+		}
+		out = append(out, tiles...)
+	}
+	for i := range out {
+		out[i].index = i
+	}
+	return out
+}
+
+// shows reports whether any line of the part crosses the tile, or the
+// tile lies within one of its faces.
+func (t *posterTile) shows(p *pdo.PDO, lk *pdo.Lookup, part *pdo.Part, dims pdo.PageDims, style FlapStyle) bool {
+	// The window in part coordinates.
+	left, top := t.left-part.BoundingBox.Left, t.top-part.BoundingBox.Top
+	right, bottom := left+dims.ClippedWidth, top+dims.ClippedHeight
+	for l := range partLines(p, lk, part, style) {
+		if l.Type < lineInvisible && segmentInRect(l.X1, l.Y1, l.X2, l.Y2, left, top, right, bottom) {
+			return true
+		}
+	}
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+		return false
+	}
+	cx, cy := (left+right)/2, (top+bottom)/2
+	for _, face := range p.Objects[part.ObjectIndex].PartFaces(t.part) {
+		if pointInFace(face, cx, cy) {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentInRect reports whether any of the segment lies in the rectangle,
+// by Liang-Barsky clipping.
+func segmentInRect(x1, y1, x2, y2, left, top, right, bottom float64) bool {
+	dx, dy := x2-x1, y2-y1
+	t0, t1 := 0.0, 1.0
+	for _, c := range [4][2]float64{{-dx, x1 - left}, {dx, right - x1}, {-dy, y1 - top}, {dy, bottom - y1}} {
+		p, q := c[0], c[1]
+		if p == 0 {
+			if q < 0 {
+				return false
+			}
+			continue
+		}
+		if u := q / p; p < 0 {
+			t0 = max(t0, u)
+		} else {
+			t1 = min(t1, u)
+		}
+		if t0 > t1 {
+			return false
+		}
+	}
+	return true
+}
+
+// pointInFace reports whether a point lies inside the 2D outline of a face.
+func pointInFace(face *pdo.Face, x, y float64) bool {
+	in := false
+	vs := face.Vertices
+	for i, j := 0, len(vs)-1; i < len(vs); j, i = i, i+1 {
+		a, b := vs[i], vs[j]
+		if (a.Y > y) != (b.Y > y) && x < (b.X-a.X)*(y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+	}
+	return in
+}
+
+// tileCount returns how many tiles of the given size, overlapping by ov,
+// cover a length.
+func tileCount(length, size, ov float64) int {
+	return max(1, int(math.Ceil((length-ov)/(size-ov))))
+}
+
+// glueStrips returns the strips of a tile covered by its left and upper
+// neighbours, as x, y, width, height in mm from the page's top left.
+func (t *posterTile) glueStrips(dims pdo.PageDims) [][4]float64 {
+	var strips [][4]float64
+	if t.col > 0 {
+		strips = append(strips, [4]float64{dims.MarginLeft, dims.MarginTop, t.overlap, dims.ClippedHeight})
+	}
+	if t.row > 0 {
+		strips = append(strips, [4]float64{dims.MarginLeft, dims.MarginTop, dims.ClippedWidth, t.overlap})
+	}
+	return strips
+}
+
+// alignMarks returns the alignment crosses in the middle of the strips a
+// tile shares with its neighbours, as lines x1, y1, x2, y2 in mm from the
+// page's top left. Neighbours get their crosses at the same layout points.
+func (t *posterTile) alignMarks(dims pdo.PageDims) [][4]float64 {
+	var lines [][4]float64
+	cross := func(x, y float64) {
+		const r = posterMarkSize / 2
+		lines = append(lines, [4]float64{x - r, y, x + r, y}, [4]float64{x, y - r, x, y + r})
+	}
+	left, top := dims.MarginLeft, dims.MarginTop
+	right, bottom := left+dims.ClippedWidth, top+dims.ClippedHeight
+	for _, f := range []float64{0.25, 0.5, 0.75} {
+		y := top + dims.ClippedHeight*f
+		if t.col > 0 {
+			cross(left+t.overlap/2, y)
+		}
+		if t.col < t.cols-1 {
+			cross(right-t.overlap/2, y)
+		}
+		x := left + dims.ClippedWidth*f
+		if t.row > 0 {
+			cross(x, top+t.overlap/2)
+		}
+		if t.row < t.rows-1 {
+			cross(x, bottom-t.overlap/2)
+		}
+	}
+	return lines
+}
+
+// label names the part and the tile's place in its grid.
+func (t *posterTile) label(p *pdo.PDO) string {
+	return fmt.Sprintf("%s - row %d of %d, column %d of %d", partLabel(p, t.part), t.row+1, t.rows, t.col+1, t.cols)
+}
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"io"
+	"testing"
+)
+
+func TestPosterTiles(t *testing.T) {
+	p := texturedCone(t)
+	// The cone (148 x 250 mm) on 100 x 150 mm paper with 10 mm margins.
+	for _, kv := range [][2]string{{"pageType", "other"}, {"customWidth", "100"}, {"customHeight", "150"}, {"marginSide", "10"}, {"marginTop", "10"}} {
+		if err := p.Settings.Set(kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dims := p.PageDims()
+	poster := Poster{Enabled: true}
+	pages := Options{Poster: poster}.posterPages(p, dims)
+
+	// 80 x 130 mm printable with 10 mm overlap: 2 columns and 2 rows.
+	if len(pages) != 4 {
+		t.Fatalf("got %d pages, want 4", len(pages))
+	}
+	for i, page := range pages {
+		if page.tile == nil || page.index != i {
+			t.Fatalf("page %d: tile %v, index %d", i, page.tile, page.index)
+		}
+	}
+	last := pages[len(pages)-1].tile
+	bb := p.Parts[0].BoundingBox
+	if right := last.left + dims.ClippedWidth; right < bb.Left+bb.Width {
+		t.Errorf("tiles end at %g, the part at %g", right, bb.Left+bb.Width)
+	}
+	if n := len(pages[0].tile.glueStrips(dims)); n != 0 {
+		t.Errorf("first tile has %d glue strips", n)
+	}
+	if n := len(pages[3].tile.glueStrips(dims)); n != 2 {
+		t.Errorf("last tile has %d glue strips, want 2", n)
+	}
+
+	// Neighbours have marks at the same layout points.
+	layoutMarks := func(page layoutPage) map[[2]float64]bool {
+		x, y := page.origin(dims)
+		marks := map[[2]float64]bool{}
+		for _, l := range page.tile.alignMarks(dims) {
+			if l[1] == l[3] { // Horizontal stroke, its middle is the cross
+				cx := (l[0]+l[2])/2 - dims.MarginLeft + x
+				cy := l[1] - dims.MarginTop + y
+				marks[[2]float64{round(cx), round(cy)}] = true
+			}
+		}
+		return marks
+	}
+	a, b := layoutMarks(pages[0]), layoutMarks(pages[1])
+	shared := 0
+	for m := range a {
+		if b[m] {
+			shared++
+		}
+	}
+	if shared != 3 {
+		t.Errorf("neighbouring tiles share %d marks, want 3", shared)
+	}
+
+	if got := RenderPages(p, 20, Options{Poster: poster}); len(got) != 4 {
+		t.Errorf("rendered %d pages, want 4", len(got))
+	}
+	if err := ExportPDF(p, io.Discard, Options{Poster: poster, Textures: true}); err != nil {
+		t.Errorf("PDF: %v", err)
+	}
+	if got := (Options{}).posterPages(p, dims); len(got) != 1 {
+		t.Errorf("%d pages without poster mode", len(got))
+	}
+}
+
+func round(f float64) float64 {
+	return float64(int(f*1000+0.5)) / 1000
+}
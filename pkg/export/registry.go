@@ -0,0 +1,105 @@
+package export
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Target is the destination of an export.
+type Target struct {
+	// W receives the main output file.
+	W io.Writer
+	// Path is the path of the main output file. Exporters that write side
+	// files (materials, textures) place them next to it.
+	Path string
+}
+
+// Exporter converts a parsed PDO into an output format.
+type Exporter interface {
+	// Name is the format name used on the command line, e.g. "svg".
+	Name() string
+	// Extensions lists the file extensions of the format, including the dot.
+	// The first one is used for default output file names.
+	Extensions() []string
+	Export(ctx context.Context, p *pdo.PDO, target Target, opts Options) error
+}
+
+// meshExporter is implemented by exporters of the assembled 3D model, which
+// don't use the unfolding.
+type meshExporter interface {
+	Mesh() bool
+}
+
+// IsMesh reports whether e writes the 3D model rather than the printable
+// template.
+func IsMesh(e Exporter) bool {
+	m, ok := e.(meshExporter)
+	return ok && m.Mesh()
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Exporter{}
+)
+
+// Register makes an exporter available by name and extension.
+// It panics if an exporter with the same name is already registered.
+func Register(e Exporter) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := strings.ToLower(e.Name())
+	if _, dup := registry[name]; dup {
+		panic("export: Register called twice for exporter " + name)
+	}
+	registry[name] = e
+}
+
+// Lookup returns the exporter registered under name.
+func Lookup(name string) (Exporter, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	e, ok := registry[strings.ToLower(name)]
+	return e, ok
+}
+
+// ForExtension returns the exporter handling the given file extension.
+func ForExtension(ext string) (Exporter, bool) {
+	ext = strings.ToLower(ext)
+	for _, e := range Exporters() {
+		for _, x := range e.Extensions() {
+			if strings.ToLower(x) == ext {
+				return e, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Exporters returns all registered exporters sorted by name.
+func Exporters() []Exporter {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	list := make([]Exporter, 0, len(registry))
+	for _, e := range registry {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// Names returns the names of all registered exporters.
+func Names() []string {
+	var names []string
+	for _, e := range Exporters() {
+		names = append(names, e.Name())
+	}
+	return names
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestRegistry_BuiltinFormats(t *testing.T) {
+	for _, name := range []string{"svg", "pdf", "obj"} {
+		e, ok := Lookup(name)
+		if !ok {
+			t.Fatalf("exporter %q not registered", name)
+		}
+		got, ok := ForExtension(e.Extensions()[0])
+		if !ok || got.Name() != name {
+			t.Errorf("ForExtension(%q) got %v, want %q", e.Extensions()[0], got, name)
+		}
+	}
+
+	if _, ok := ForExtension(".PDF"); !ok {
+		t.Errorf("extension lookup should be case-insensitive")
+	}
+}
+
+func TestCheckUnfold(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{})
+	p.Parts = nil
+	for _, e := range Exporters() {
+		err := CheckUnfold(e, p)
+		if IsMesh(e) != (err == nil) {
+			t.Errorf("%s: CheckUnfold() = %v", e.Name(), err)
+		}
+	}
+	if err := ExportSVG(p, io.Discard, Options{}); !errors.Is(err, ErrNoUnfold) {
+		t.Errorf("ExportSVG() = %v, want ErrNoUnfold", err)
+	}
+	if err := ExportOBJ(p, io.Discard, "", Options{}); err != nil {
+		t.Errorf("ExportOBJ() = %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/vector"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// DefaultRenderDPI is the default resolution of rendered pages, enough for
+// a thumbnail.
+const DefaultRenderDPI = 30
+
+// Rendered line styles: widths in mm, never thinner than a pixel, and the
+// dash pattern of fold lines, as in PDF.
+const (
+	renderLineWidth = 0.2
+	renderDash      = 1.0
+)
+
+var renderLineColors = [...]color.RGBA{
+	lineCut:      {0, 0, 0, 255},
+	lineMountain: {0, 0, 255, 255},
+	lineValley:   {255, 0, 0, 255},
+}
+
+// RenderPages draws every printed page into an image at the given
+// resolution in dots per inch. Faces show their texture or material color
+// (see Options.FaceFill) when opts.Textures is set, lines are drawn on top.
+// Text blocks are left out, they aren't readable at thumbnail sizes.
+func RenderPages(p *pdo.PDO, dpi float64, opts Options) []*image.RGBA {
+	p = opts.lines(p)
+	dims := p.PageDims()
+	r := &pageRenderer{p: p, lookups: p.Lookups(), scale: dpi / 25.4, opts: opts, textures: map[int32]*image.RGBA{}, fill: opts.FaceFill.resolve(p.Settings)}
+	var images []*image.RGBA
+	for _, page := range opts.posterPages(p, dims) {
+		img := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(dims.Width*r.scale)), int(math.Ceil(dims.Height*r.scale))))
+		draw.Draw(img, img.Bounds(), r.paper(), image.Point{}, draw.Src)
+		r.drawGuides(img, dims)
+		x, y := page.origin(dims)
+		offX, offY := x-dims.MarginLeft, y-dims.MarginTop
+		dst := img
+		if page.tile != nil {
+			r.drawGlueStrips(img, page.tile, dims)
+			// Tiles show only their window of the part.
+			area := image.Rect(int(math.Floor(dims.MarginLeft*r.scale)), int(math.Floor(dims.MarginTop*r.scale)),
+				int(math.Ceil((dims.MarginLeft+dims.ClippedWidth)*r.scale)), int(math.Ceil((dims.MarginTop+dims.ClippedHeight)*r.scale)))
+			dst = img.SubImage(area).(*image.RGBA)
+		}
+		for _, part := range page.parts {
+			r.drawPart(dst, part, offX, offY)
+		}
+		if page.tile != nil {
+			r.drawAlignMarks(img, page.tile, dims)
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// DefaultThumbnailSize is the default length in pixels of the longer side
+// of thumbnails.
+const DefaultThumbnailSize = 256
+
+// Thumbnail renders the first page with its longer side size pixels long,
+// as a preview of the template. It returns nil when there are no pages.
+func Thumbnail(p *pdo.PDO, size int, opts Options) *image.RGBA {
+	dims := p.PageDims()
+	longer := max(dims.Width, dims.Height)
+	if longer <= 0 || size <= 0 {
+		return nil
+	}
+	// Round down, so rounding pixels up doesn't exceed size.
+	dpi := math.Floor(float64(size)*25.4/longer*1e3) / 1e3
+	pages := RenderPages(p, dpi, opts)
+	if len(pages) == 0 {
+		return nil
+	}
+	return pages[0]
+}
+
+// ContactSheet arranges page images in a grid of the given number of
+// columns, separated by gap pixels on a gray background.
+func ContactSheet(pages []*image.RGBA, columns, gap int) *image.RGBA {
+	if len(pages) == 0 {
+		return image.NewRGBA(image.Rectangle{})
+	}
+	columns = max(1, min(columns, len(pages)))
+	rows := (len(pages) + columns - 1) / columns
+	cw, ch := 0, 0
+	for _, pg := range pages {
+		cw, ch = max(cw, pg.Bounds().Dx()), max(ch, pg.Bounds().Dy())
+	}
+	sheet := image.NewRGBA(image.Rect(0, 0, columns*(cw+gap)+gap, rows*(ch+gap)+gap))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.RGBA{128, 128, 128, 255}), image.Point{}, draw.Src)
+	for i, pg := range pages {
+		at := image.Pt(gap+i%columns*(cw+gap), gap+i/columns*(ch+gap))
+		draw.Draw(sheet, pg.Bounds().Sub(pg.Bounds().Min).Add(at), pg, pg.Bounds().Min, draw.Src)
+	}
+	return sheet
+}
+
+type pageRenderer struct {
+	p       *pdo.PDO
+	lookups pdo.Lookups
+	scale   float64 // Pixels per mm
+	opts    Options
+	// textures caches the prepared texture of each material, nil for
+	// materials without a usable texture.
+	textures map[int32]*image.RGBA
+	fill     FaceFill
+}
+
+// point is a position in pixels.
+type point struct{ X, Y float64 }
+
+func (r *pageRenderer) drawPart(dst *image.RGBA, part *pdo.Part, offX, offY float64) {
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(r.p.Objects) {
+		return
+	}
+	at := func(x, y float64) point {
+		return point{(x + part.BoundingBox.Left - offX) * r.scale, (y + part.BoundingBox.Top - offY) * r.scale}
+	}
+
+	if r.opts.Textures {
+		for _, face := range r.p.Objects[part.ObjectIndex].PartFaces(partIndex(r.p, part)) {
+			r.drawFace(dst, face, at)
+		}
+	}
+
+	width := max(renderLineWidth*r.scale, 1)
+	for line := range templateLines(r.p, r.lookups.Part(part), part, r.opts.FlapStyle, r.opts.outlineOffset(r.p.Settings), r.opts.Perforation) {
+		t := drawnLineType(line.Type)
+		if t == lineInvisible {
+			continue
+		}
+		a, b := at(line.X1, line.Y1), at(line.X2, line.Y2)
+		c := image.NewUniform(renderLineColors[t])
+		if t == lineCut {
+			strokeLine(dst, a, b, width, c)
+			continue
+		}
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		dash := renderDash * r.scale
+		for s := 0.0; s < length; s += 2 * dash {
+			e := min(s+dash, length)
+			strokeLine(dst, lerp(a, b, s/length), lerp(a, b, e/length), width, c)
+		}
+	}
+}
+
+// paper returns the page background.
+func (r *pageRenderer) paper() image.Image {
+	if c := r.opts.Guides.Background; c != nil {
+		return image.NewUniform(color.RGBA{c[0], c[1], c[2], 255})
+	}
+	return image.White
+}
+
+// drawGuides draws the grid and printable area of a page.
+func (r *pageRenderer) drawGuides(dst *image.RGBA, dims pdo.PageDims) {
+	g := r.opts.Guides
+	at := func(x, y float64) point { return point{x * r.scale, y * r.scale} }
+	width := max(gridGuideWidth*r.scale, 1)
+	grid := image.NewUniform(color.RGBA{gridGuideColor[0], gridGuideColor[1], gridGuideColor[2], 255})
+	for _, l := range g.gridLines(dims) {
+		strokeLine(dst, at(l[0], l[1]), at(l[2], l[3]), width, grid)
+	}
+	if !g.Margins {
+		return
+	}
+	width = max(marginGuideWidth*r.scale, 1)
+	margin := image.NewUniform(color.RGBA{marginGuideColor[0], marginGuideColor[1], marginGuideColor[2], 255})
+	left, top := dims.MarginLeft, dims.MarginTop
+	right, bottom := left+dims.ClippedWidth, top+dims.ClippedHeight
+	corners := []point{at(left, top), at(right, top), at(right, bottom), at(left, bottom)}
+	for i, a := range corners {
+		strokeLine(dst, a, corners[(i+1)%len(corners)], width, margin)
+	}
+}
+
+// drawGlueStrips shades the strips of a poster tile covered by its neighbours.
+func (r *pageRenderer) drawGlueStrips(dst *image.RGBA, t *posterTile, dims pdo.PageDims) {
+	c := posterGlueColor
+	glue := image.NewUniform(color.RGBA{c[0], c[1], c[2], 255})
+	for _, s := range t.glueStrips(dims) {
+		rect := image.Rect(int(s[0]*r.scale), int(s[1]*r.scale), int(math.Ceil((s[0]+s[2])*r.scale)), int(math.Ceil((s[1]+s[3])*r.scale)))
+		draw.Draw(dst, rect, glue, image.Point{}, draw.Src)
+	}
+}
+
+// drawAlignMarks draws the alignment marks of a poster tile.
+func (r *pageRenderer) drawAlignMarks(dst *image.RGBA, t *posterTile, dims pdo.PageDims) {
+	width := max(posterMarkWidth*r.scale, 1)
+	for _, l := range t.alignMarks(dims) {
+		strokeLine(dst, point{l[0] * r.scale, l[1] * r.scale}, point{l[2] * r.scale, l[3] * r.scale}, width, image.Black)
+	}
+}
+
+// drawFace fills a face with its texture, or the 2D color of its material
+// when it has none or material colors are printed.
+func (r *pageRenderer) drawFace(dst *image.RGBA, face *pdo.Face, at func(x, y float64) point) {
+	if len(face.Vertices) < 3 || face.MaterialIndex < 0 || int(face.MaterialIndex) >= len(r.p.Materials) {
+		return
+	}
+	pts := make([]point, len(face.Vertices))
+	for i, v := range face.Vertices {
+		pts[i] = at(v.X, v.Y)
+	}
+	var tex *image.RGBA
+	if r.fill == FaceFillTexture {
+		tex = r.texture(face.MaterialIndex)
+	}
+	if tex == nil {
+		c := r.p.Materials[face.MaterialIndex].Color2DRGBA
+		fill := color.NRGBA{unitByte(c[0]), unitByte(c[1]), unitByte(c[2]), unitByte(c[3])}
+		if mask, bounds := coverage(pts, dst.Bounds()); mask != nil {
+			draw.DrawMask(dst, bounds, image.NewUniform(fill), image.Point{}, mask, image.Point{}, draw.Over)
+		}
+		return
+	}
+
+	// Triangle fan, each triangle with its own affine texture mapping.
+	tw, th := tex.Bounds().Dx(), tex.Bounds().Dy()
+	v0 := face.Vertices[0]
+	for i := 1; i+1 < len(face.Vertices); i++ {
+		v1, v2 := face.Vertices[i], face.Vertices[i+1]
+		p0, p1, p2 := pts[0], pts[i], pts[i+1]
+		// Pixel to texture coordinates: solve p = p0 + s*(p1-p0) + t*(p2-p0).
+		ax, ay, bx, by := p1.X-p0.X, p1.Y-p0.Y, p2.X-p0.X, p2.Y-p0.Y
+		det := ax*by - bx*ay
+		if math.Abs(det) < 1e-9 {
+			continue
+		}
+		mask, bounds := coverage([]point{p0, p1, p2}, dst.Bounds())
+		if mask == nil {
+			continue
+		}
+		for y := range bounds.Dy() {
+			for x := range bounds.Dx() {
+				cov := mask.Pix[y*mask.Stride+x]
+				if cov == 0 {
+					continue
+				}
+				px, py := float64(bounds.Min.X+x)+0.5-p0.X, float64(bounds.Min.Y+y)+0.5-p0.Y
+				s, t := (px*by-bx*py)/det, (ax*py-px*ay)/det
+				u := v0.U + s*(v1.U-v0.U) + t*(v2.U-v0.U)
+				v := v0.V + s*(v1.V-v0.V) + t*(v2.V-v0.V)
+				c := tex.RGBAAt(wrap(int(math.Floor(u*float64(tw))), tw), wrap(int(math.Floor(v*float64(th))), th))
+				blend(dst, bounds.Min.X+x, bounds.Min.Y+y, c, cov)
+			}
+		}
+	}
+}
+
+// texture prepares the texture of material m on first use.
+func (r *pageRenderer) texture(m int32) *image.RGBA {
+	if tex, ok := r.textures[m]; ok {
+		return tex
+	}
+	r.textures[m] = nil
+	mat := &r.p.Materials[m]
+	if !mat.HasTexture {
+		return nil
+	}
+	img, err := mat.Texture.GetImage()
+	if err != nil {
+		r.opts.logger().Warn("failed to decode texture", "material", mat.Name, "err", err)
+		return nil
+	}
+	img = r.opts.Dither.prepare(img)
+	tex, ok := img.(*image.RGBA)
+	if !ok {
+		tex = image.NewRGBA(img.Bounds())
+		draw.Draw(tex, tex.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+	r.textures[m] = tex
+	return tex
+}
+
+// coverage rasterizes a polygon and returns its coverage within bounds,
+// the part of clip it touches. The mask is nil when there is none.
+func coverage(pts []point, clip image.Rectangle) (*image.Alpha, image.Rectangle) {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, pt := range pts {
+		minX, minY = min(minX, pt.X), min(minY, pt.Y)
+		maxX, maxY = max(maxX, pt.X), max(maxY, pt.Y)
+	}
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(clip)
+	if bounds.Empty() {
+		return nil, bounds
+	}
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	z.MoveTo(float32(pts[0].X-ox), float32(pts[0].Y-oy))
+	for _, pt := range pts[1:] {
+		z.LineTo(float32(pt.X-ox), float32(pt.Y-oy))
+	}
+	z.ClosePath()
+	mask := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	return mask, bounds
+}
+
+// strokeLine draws a line of the given width in pixels with butt ends.
+func strokeLine(dst *image.RGBA, a, b point, width float64, src image.Image) {
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if length == 0 {
+		return
+	}
+	nx, ny := -(b.Y-a.Y)/length*width/2, (b.X-a.X)/length*width/2
+	quad := []point{{a.X + nx, a.Y + ny}, {b.X + nx, b.Y + ny}, {b.X - nx, b.Y - ny}, {a.X - nx, a.Y - ny}}
+	if mask, bounds := coverage(quad, dst.Bounds()); mask != nil {
+		draw.DrawMask(dst, bounds, src, image.Point{}, mask, image.Point{}, draw.Over)
+	}
+}
+
+// blend composites an opaque color over dst with the given coverage.
+func blend(dst *image.RGBA, x, y int, c color.RGBA, cov uint8) {
+	i := dst.PixOffset(x, y)
+	a := uint32(cov)
+	for n, v := range [3]uint8{c.R, c.G, c.B} {
+		dst.Pix[i+n] = uint8((uint32(v)*a + uint32(dst.Pix[i+n])*(255-a) + 127) / 255)
+	}
+}
+
+func lerp(a, b point, t float64) point {
+	return point{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t}
+}
+
+// wrap maps a texel index into [0, n), textures repeat.
+func wrap(i, n int) int {
+	return (i%n + n) % n
+}
+
+// unitByte converts a color component in [0, 1] to a byte.
+func unitByte(f float32) uint8 {
+	return uint8(math.Round(float64(min(max(f, 0), 1)) * 255))
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"image"
+	"io"
+	"testing"
+
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestRenderPages(t *testing.T) {
+	p := texturedCone(t)
+	pages := RenderPages(p, 50, Options{Textures: true})
+	if want := len(layoutPages(p, p.PageDims())); len(pages) != want || want == 0 {
+		t.Fatalf("got %d pages, want %d", len(pages), want)
+	}
+	if got := pages[0].Bounds().Size(); got != image.Pt(414, 585) {
+		t.Errorf("A4 page at 50 dpi is %v", got)
+	}
+
+	// Texture colors show on the faces, lines are black.
+	var red, black int
+	for i := 0; i < len(pages[0].Pix); i += 4 {
+		switch px := pages[0].Pix[i : i+3]; {
+		case px[0] == 255 && px[1] == 0 && px[2] == 0:
+			red++
+		case px[0] == 0 && px[1] == 0 && px[2] == 0:
+			black++
+		}
+	}
+	if red == 0 || black == 0 {
+		t.Errorf("got %d red and %d black pixels", red, black)
+	}
+
+	sheet := ContactSheet([]*image.RGBA{pages[0], pages[0], pages[0]}, 2, 4)
+	if got, want := sheet.Bounds().Size(), image.Pt(2*414+3*4, 2*585+3*4); got != want {
+		t.Errorf("contact sheet is %v, want %v", got, want)
+	}
+
+	if got := Thumbnail(p, 128, Options{}).Bounds().Size(); got.Y != 128 || got.X > 128 {
+		t.Errorf("thumbnail is %v, want 128 pixels high", got)
+	}
+}
+
+func TestRenderPages_FaceFill(t *testing.T) {
+	p := texturedCone(t)
+	p.Materials[0].Color2DRGBA = [4]float32{0, 1, 1, 1}
+	count := func(opts Options, want [3]uint8) int {
+		n := 0
+		pix := RenderPages(p, 50, opts)[0].Pix
+		for i := 0; i < len(pix); i += 4 {
+			if [3]uint8(pix[i:i+3]) == want {
+				n++
+			}
+		}
+		return n
+	}
+	cyan := [3]uint8{0, 255, 255}
+
+	// The file asks for textures, the override and a cleared setting for colors.
+	if n := count(Options{Textures: true}, cyan); n != 0 {
+		t.Errorf("%d material color pixels with textures", n)
+	}
+	if n := count(Options{Textures: true, FaceFill: FaceFillColor}, cyan); n == 0 {
+		t.Error("no material color with FaceFillColor")
+	}
+	p.Settings.FaceMaterial = 0
+	if n := count(Options{Textures: true}, cyan); n == 0 {
+		t.Error("no material color with the face material setting cleared")
+	}
+	if n := count(Options{Textures: true, FaceFill: FaceFillTexture}, [3]uint8{255, 0, 0}); n == 0 {
+		t.Error("no texture with FaceFillTexture")
+	}
+	if err := ExportPDF(p, io.Discard, Options{Textures: true}); err != nil {
+		t.Errorf("PDF with material colors: %v", err)
+	}
+	if _, err := ParseFaceFill("wood"); err == nil {
+		t.Error("ParseFaceFill accepted an unknown fill")
+	}
+}
+
+func TestRenderPages_UnknownLineTypes(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{})
+	// count returns the number of gray and blue pixels of antialiased cuts
+	// and mountain folds on white paper.
+	count := func() (black, blue int) {
+		t.Helper()
+		pix := RenderPages(p, 100, Options{})[0].Pix
+		for i := 0; i < len(pix); i += 4 {
+			switch r, g, b := pix[i], pix[i+1], pix[i+2]; {
+			case r == g && g == b && r < 255:
+				black++
+			case r == g && b > r:
+				blue++
+			}
+		}
+		return black, blue
+	}
+	black, blue := count()
+
+	// Folds of a type below cut are drawn as cuts, past invisible not at
+	// all. The folds at the flaps stay.
+	for i, l := range p.Parts[0].Lines {
+		if l.Type == lineMountain {
+			p.Parts[0].Lines[i].Type = -1
+		}
+	}
+	if b, bl := count(); b <= black || bl >= blue {
+		t.Errorf("%d black and %d blue pixels, want more than %d black and fewer than %d blue", b, bl, black, blue)
+	}
+	for i := range p.Parts[0].Lines {
+		p.Parts[0].Lines[i].Type = 9
+	}
+	if b, bl := count(); b != 0 || bl != 0 {
+		t.Errorf("invisible lines drawn with %d black and %d blue pixels", b, bl)
+	}
+	Thumbnail(p, 64, Options{})
+}
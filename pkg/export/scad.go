@@ -0,0 +1,80 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// ExportSCAD writes the assembled model as OpenSCAD polyhedra in mm (or
+// opts.Units), one per object inside a union. Holes in the paper surface are capped so the
+// result is a solid that can be printed or combined with other shapes.
+func ExportSCAD(p *pdo.PDO, w io.Writer, opts Options) error {
+	num := func(v float64) string {
+		return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64) // Micrometre precision in mm
+	}
+
+	fmt.Fprintln(w, "// Exported by pdo-tools")
+	writeMetadataComments(w, "// ", opts.metadata(p))
+	fmt.Fprintln(w, "union() {")
+	place := opts.placement(p, false)
+	for i, obj := range p.Objects {
+		faces, holes := closedMesh(obj)
+		if len(faces) == 0 {
+			continue
+		}
+		if holes > 0 {
+			opts.logger().Info("capped holes in the surface", "object", obj.Name, "holes", holes)
+		}
+
+		fmt.Fprintf(w, "  // Object %d: %s\n", i, obj.Name)
+		fmt.Fprintln(w, "  polyhedron(points = [")
+		for n, v := range place.vertices(obj) {
+			sep := ","
+			if n == len(obj.Vertices)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(w, "    [%s, %s, %s]%s\n", num(v.X), num(v.Y), num(v.Z), sep)
+		}
+		fmt.Fprintln(w, "  ], faces = [")
+		for n, loop := range faces {
+			// OpenSCAD wants faces clockwise seen from outside.
+			fmt.Fprint(w, "    [")
+			for k, v := range slices.Backward(loop) {
+				if k != len(loop)-1 {
+					fmt.Fprint(w, ", ")
+				}
+				fmt.Fprint(w, v)
+			}
+			sep := "],"
+			if n == len(faces)-1 {
+				sep = "]"
+			}
+			fmt.Fprintln(w, sep)
+		}
+		fmt.Fprintln(w, "  ], convexity = 10);")
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+type scadExporter struct{}
+
+func (scadExporter) Name() string         { return "scad" }
+func (scadExporter) Extensions() []string { return []string{".scad"} }
+func (scadExporter) Mesh() bool           { return true }
+func (scadExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ExportSCAD(p, t.W, opts)
+}
+
+func init() {
+	Register(scadExporter{})
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestClosedMesh(t *testing.T) {
+	for _, name := range []string{"cone", "cylinder", "pyramid", "sphere", "torus"} {
+		p, err := pdo.ParseFile("../../sample_basic_shapes/" + name + ".pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range p.Objects {
+			// Open the surface by dropping a face, a cap must close it again.
+			open := obj
+			open.Faces = obj.Faces[1:]
+			faces, holes := closedMesh(open)
+			if holes != 1 {
+				t.Errorf("%s: %d holes capped, want 1", name, holes)
+			}
+			// Watertight and consistently wound: every edge is used once in
+			// each direction.
+			type edge struct{ a, b int32 }
+			used := map[edge]int{}
+			for _, loop := range faces {
+				for i, a := range loop {
+					used[edge{a, loop[(i+1)%len(loop)]}]++
+				}
+			}
+			// Faces wound counter-clockwise from outside enclose a positive volume.
+			var volume float64
+			for _, loop := range faces {
+				a := obj.Vertices[loop[0]]
+				for i := 1; i+1 < len(loop); i++ {
+					b, c := obj.Vertices[loop[i]], obj.Vertices[loop[i+1]]
+					volume += a.X*(b.Y*c.Z-b.Z*c.Y) - a.Y*(b.X*c.Z-b.Z*c.X) + a.Z*(b.X*c.Y-b.Y*c.X)
+				}
+			}
+			if volume <= 0 {
+				t.Errorf("%s: volume %g, faces wound inwards", name, volume/6)
+			}
+			for e, n := range used {
+				if n != 1 || used[edge{e.b, e.a}] != 1 {
+					t.Errorf("%s: edge %d-%d used %d times, %d reversed", name, e.a, e.b, n, used[edge{e.b, e.a}])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestExportSCAD(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/pyramid.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ExportSCAD(p, &buf, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Count(out, "polyhedron(") != len(p.Objects) || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
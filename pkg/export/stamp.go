@@ -0,0 +1,113 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pdo-tools/pkg/qr"
+)
+
+// DefaultQRSize is the QR code edge length in mm used when Stamp.QRSize is 0.
+const DefaultQRSize = 12.0
+
+// Stamp adds a footer line, an attribution and a QR code to the bottom
+// margin of every page.
+type Stamp struct {
+	// Text is the footer line. The placeholders {name}, {url}, {page} and
+	// {pages} are replaced with the model name, URL and page numbers.
+	Text string
+	// Name is the model name used for {name}.
+	Name string
+	// URL is encoded as a QR code in the bottom right corner when set.
+	URL string
+	// QRSize is the QR code edge length in mm, DefaultQRSize when 0.
+	QRSize float64
+	// Attribution is a credit or license line printed small and centred
+	// along the bottom edge of every page, and recorded as the rights in
+	// the metadata of all formats. {author} is replaced with
+	// Options.Author or the author of the model; the line is left out when
+	// there is none.
+	Attribution string
+}
+
+// attributionSize is the font size of the attribution in mm.
+const attributionSize = 2.0
+
+func (s Stamp) enabled() bool {
+	return s.Text != "" || s.URL != "" || s.Attribution != ""
+}
+
+// footer returns the footer text for page number page (1-based) of pages.
+func (s Stamp) footer(page, pages int) string {
+	return strings.NewReplacer(
+		"{name}", s.Name,
+		"{url}", s.URL,
+		"{page}", strconv.Itoa(page),
+		"{pages}", strconv.Itoa(pages),
+	).Replace(s.Text)
+}
+
+func (s Stamp) qrSize() float64 {
+	if s.QRSize > 0 {
+		return s.QRSize
+	}
+	return DefaultQRSize
+}
+
+// code encodes the URL, or returns nil when there is none.
+func (s Stamp) code() (*qr.Code, error) {
+	if s.URL == "" {
+		return nil, nil
+	}
+	code, err := qr.Encode([]byte(s.URL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode stamp URL: %w", err)
+	}
+	return code, nil
+}
+
+// qrRuns calls fn for every horizontal run of dark modules, in module units.
+func qrRuns(code *qr.Code, fn func(x, y, n int)) {
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; {
+			if !code.Dark(x, y) {
+				x++
+				continue
+			}
+			start := x
+			for x < code.Size && code.Dark(x, y) {
+				x++
+			}
+			fn(start, y, x-start)
+		}
+	}
+}
+
+// stampLayout positions the stamp on a page of the given paper size, in mm
+// from the page's top left corner. The footer is vertically centred in the
+// bottom margin, the attribution is centred stampInset above the paper edge
+// and the QR code sits in the bottom right corner, inset by stampInset.
+type stampLayout struct {
+	textX, textY float64
+	attrX, attrY float64
+	qrX, qrY     float64
+	module       float64
+}
+
+// stampInset keeps the QR code clear of the unprintable paper edge.
+const stampInset = 3.0
+
+func (s Stamp) layout(width, height, marginLeft, marginBottom float64, code *qr.Code) stampLayout {
+	l := stampLayout{textX: marginLeft, textY: height - marginBottom/2, attrX: width / 2, attrY: height - stampInset}
+	if marginBottom <= 0 {
+		l.textY = height - stampInset
+	}
+	if code != nil {
+		size := s.qrSize()
+		l.module = size / float64(code.Size)
+		l.qrX = width - stampInset - size
+		l.qrY = height - stampInset - size
+	}
+	return l
+}
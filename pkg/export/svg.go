@@ -1,10 +1,18 @@
 package export
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/xml"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
 
 	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/qr"
 )
 
 // SVGWriter exports to SVG
@@ -13,6 +21,42 @@ type SVGWriter struct {
 	width  float64
 	height float64
 	scale  float64
+
+	edgeIDSize float64
+	flapStyle  FlapStyle
+	// outline is the offset in mm of the outline drawn around parts, 0 for none.
+	outline float64
+	// perforation turns fold lines into cuts.
+	perforation Perforation
+	// materialLayers puts the lines of each material on their own layer.
+	materialLayers bool
+	partColoring   PartColoring
+	// precision is the number of decimal places of coordinates.
+	precision int
+	// compactPaths joins part lines into paths with relative commands.
+	compactPaths bool
+	// frames places content on a grid of printed pages, see origin, with
+	// parts on the pages of pages.
+	frames *pdo.PageDims
+	pages  [][2]int
+	// units is the unit of the document width and height, user units stay mm.
+	units Units
+	// guides draws the paper color, margins and a grid beneath the parts.
+	guides Guides
+	// flipY mirrors parts and text blocks vertically within the document.
+	flipY bool
+	// duplicates draws parts of the same shape once when set.
+	duplicates *svgDuplicates
+	// textures paints faces beneath the lines when set.
+	textures *svgTextures
+
+	// outliner converts text blocks to paths when set.
+	outliner *textOutliner
+	log      *slog.Logger
+
+	// lookups index the objects of indexed, see lookup.
+	indexed *pdo.PDO
+	lookups pdo.Lookups
 }
 
 func NewSVGWriter(w io.Writer, width, height float64) *SVGWriter {
@@ -21,6 +65,10 @@ func NewSVGWriter(w io.Writer, width, height float64) *SVGWriter {
 		width:  width,
 		height: height,
 		scale:  1.0, // Default scale
+
+		edgeIDSize: defaultEdgeIDSize,
+		precision:  DefaultPrecision,
+		log:        slog.Default(),
 	}
 }
 
@@ -31,17 +79,30 @@ func (s *SVGWriter) WriteHeader() {
 	// viewBox="0 0 210 297"
 
 	fmt.Fprintf(s.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
-	<svg xmlns="http://www.w3.org/2000/svg" version="1.1"
-	width="%.2fmm" height="%.2fmm" viewBox="0 0 %.2f %.2f">
+	<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.1"
+	width="%s" height="%s" viewBox="0 0 %.2f %.2f">
 	<style>
 		.cut { fill:none; stroke:black; stroke-width:0.1; }
 		.mountain { fill:none; stroke:blue; stroke-width:0.1; stroke-dasharray:1,1; }
 		.valley { fill:none; stroke:red; stroke-width:0.1; stroke-dasharray:1,1; }
 		.invisible { stroke:none; display:none; }
-		.text { font-size: 5px; font-family: sans-serif; fill: black; }
-		.edge-id { font-size: 3px; font-family: sans-serif; fill: green; text-anchor: middle; dominant-baseline: middle; }
+		.text-path { stroke: none; }
+		.edge-id { font-size: %spx; font-family: sans-serif; fill: green; text-anchor: middle; dominant-baseline: middle; }
+		.stamp { font-size: 3px; font-family: sans-serif; fill: black; }
+		.stamp-qr { fill: black; stroke: none; }
+		.page { fill: white; stroke: #999999; stroke-width: 0.2; }
+		.margin { fill: none; stroke: #cccccc; stroke-width: 0.1; stroke-dasharray: 2,1; }
+		.legend { font-size: 3px; font-family: sans-serif; fill: black; }
 	</style>
-`, s.width, s.height, s.width, s.height)
+`, s.size(s.width), s.size(s.height), s.width, s.height, fontSize(s.edgeIDSize))
+}
+
+// size formats a document dimension given in mm in the document unit.
+func (s *SVGWriter) size(mm float64) string {
+	if s.units == UnitsDefault || s.units == UnitsMM {
+		return fmt.Sprintf("%.2fmm", mm)
+	}
+	return strconv.FormatFloat(math.Round(mm*s.units.perMM()*1e4)/1e4, 'f', -1, 64) + s.units.String()
 }
 
 func (s *SVGWriter) WriteFooter() {
@@ -49,116 +110,366 @@ func (s *SVGWriter) WriteFooter() {
 }
 
 func (s *SVGWriter) WritePDO(p *pdo.PDO) {
-	// Group for parts
-	fmt.Fprintln(s.w, `<g id="parts">`)
-	for _, part := range p.Parts {
-		s.WritePart(p, &part)
+	if s.flipY {
+		fmt.Fprintf(s.w, `<g id="flip-y" transform="matrix(1 0 0 -1 0 %s)">`+"\n", s.num(s.height))
+	}
+	if s.textures != nil {
+		s.textures.writeDefs(s)
+		fmt.Fprintln(s.w, `<g id="faces">`)
+		for part := range p.PartObjects() {
+			if !s.duplicates.skipped(partIndex(p, part)) {
+				s.textures.writePart(s, part)
+			}
+		}
+		fmt.Fprintln(s.w, `</g>`)
+	}
+	if s.materialLayers {
+		s.writeMaterialLayers(p)
+	} else {
+		// Group for parts
+		fmt.Fprintln(s.w, `<g id="parts">`)
+		for part := range p.PartObjects() {
+			if !s.duplicates.writePart(s, p, part, partIndex(p, part)) {
+				s.WritePart(p, part)
+			}
+		}
+		fmt.Fprintln(s.w, `</g>`)
+	}
+	if s.flipY {
+		fmt.Fprintln(s.w, `</g>`)
 	}
-	fmt.Fprintln(s.w, `</g>`)
 
 	// Text blocks
 	fmt.Fprintln(s.w, `<g id="text">`)
-	for _, tb := range p.TextBlocks {
-		// Just dump text at position
-		x := tb.BoundingBox.Left
-		y := tb.BoundingBox.Top // SVG coords are usually top-down, PDO is mm, might be consistent
-		// Wait, PDO Y grows down?
-		// Ref: `pdo2opf` -> `part2d.page_h`.
-		// Usually coordinates are in mm relative to margins.
-		// We can just plot them.
-
-		for _, line := range tb.Lines {
-			fmt.Fprintf(s.w, `<text x="%.3f" y="%.3f" class="text">%s</text>`+"\n",
-				x, y+float64(tb.FontSize), line)
-			y += tb.LineSpacing
+	for i := range p.TextBlocks {
+		s.WriteTextBlock(&p.TextBlocks[i])
+	}
+	fmt.Fprintln(s.w, `</g>`)
+}
+
+// WriteTextBlock writes a text block as a group carrying its color and font.
+// The font falls back to the closest generic family when it isn't installed.
+func (s *SVGWriter) WriteTextBlock(tb *pdo.TextBlock) {
+	r, g, b := textRGB(tb.Color)
+	size := textFontSize(tb)
+	family := fontFamily(tb.FontName)
+	if tb.FontName != "" {
+		family = "'" + xmlEscape(strings.ReplaceAll(tb.FontName, "'", "")) + "', " + family
+	}
+	fmt.Fprintf(s.w, `<g class="text" style="font-size:%spx; font-family:%s; fill:#%02x%02x%02x">`+"\n",
+		fontSize(size), family, r, g, b)
+
+	x, top := s.origin(tb.BoundingBox)
+	if s.flipY {
+		// Mirror the box, not the glyphs.
+		top = s.height - top - tb.BoundingBox.Height
+	}
+	for i, y := range textBaselines(tb) {
+		y += top - tb.BoundingBox.Top
+		line := tb.Lines[i]
+		if s.outliner != nil {
+			d, missing := s.outliner.path(line, x, y, size)
+			if len(missing) == 0 {
+				fmt.Fprintf(s.w, `<path d="%s" class="text-path" />`+"\n", d)
+				continue
+			}
+			// Keep the line as text so it still shows with a font that has the glyphs.
+			s.log.Warn("font has no outlines for text, keeping it as text", "text", line, "missing", string(missing))
 		}
+		fmt.Fprintf(s.w, `<text x="%s" y="%s">%s</text>`+"\n", s.num(x), s.num(y), xmlEscape(line))
 	}
 	fmt.Fprintln(s.w, `</g>`)
 }
 
+// xmlEscape escapes s for use in SVG text and attribute values.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
 func (s *SVGWriter) WritePart(p *pdo.PDO, part *pdo.Part) {
-	// We need to resolve lines to vertices
-	// part.Lines refers to face/vertex indices
+	if s.partColoring == PartColorsFill {
+		s.writePartFill(p, part)
+	}
+	s.writeLines(p, part, func(partLine) bool { return true })
+	s.writeEdgeIDs(p, part)
+}
 
-	obj := p.Objects[part.ObjectIndex]
+// writeMaterialLayers writes one layer per material holding the lines of its
+// faces, so each color of cardstock can be cut on its own. Edge IDs go on a
+// layer of their own.
+func (s *SVGWriter) writeMaterialLayers(p *pdo.PDO) {
+	for _, m := range usedMaterials(p, s.flapStyle) {
+		fmt.Fprintf(s.w, `<g id="material-%d" inkscape:groupmode="layer" inkscape:label="%s">`+"\n",
+			m, xmlEscape(materialName(p, m)))
+		for part := range p.PartObjects() {
+			s.writeLines(p, part, func(l partLine) bool { return l.Material == m })
+		}
+		fmt.Fprintln(s.w, `</g>`)
+	}
+	if s.outline > 0 {
+		fmt.Fprintln(s.w, `<g id="outline" inkscape:groupmode="layer" inkscape:label="Outline">`)
+		for part := range p.PartObjects() {
+			s.writeLines(p, part, func(l partLine) bool { return l.Material < 0 })
+		}
+		fmt.Fprintln(s.w, `</g>`)
+	}
 
-	for _, line := range part.Lines {
-		if line.Hidden {
+	fmt.Fprintln(s.w, `<g id="edge-ids" inkscape:groupmode="layer" inkscape:label="Edge IDs">`)
+	for part := range p.PartObjects() {
+		s.writeEdgeIDs(p, part)
+	}
+	fmt.Fprintln(s.w, `</g>`)
+}
+
+// writeLines writes the lines of a part that match the filter.
+func (s *SVGWriter) writeLines(p *pdo.PDO, part *pdo.Part, match func(partLine) bool) {
+	tint := ""
+	if s.partColoring == PartColorsOutline {
+		r, g, b := partColor(partIndex(p, part))
+		tint = fmt.Sprintf(` style="stroke:#%02x%02x%02x"`, r, g, b)
+	}
+
+	// With compact paths the lines of each class are joined into one path.
+	var paths [lineInvisible + 1]svgPath
+
+	// Lines are resolved from face/vertex indices, flaps are added on cut edges.
+	left, top := s.partOrigin(p, part)
+	for line := range templateLines(p, s.lookup(p, part), part, s.flapStyle, s.outline, s.perforation) {
+		if !match(line) {
 			continue
 		}
-
-		// line.FaceIndex, line.VertexIndex
-		// Find start vertex
-		v1 := get2DVertex(obj, line.FaceIndex, line.VertexIndex)
-		if v1 == nil {
+		// Apply Part Offset (Vertices are local to Part)
+		x1 := line.X1 + left
+		y1 := line.Y1 + top
+		x2 := line.X2 + left
+		y2 := line.Y2 + top
+
+		t := drawnLineType(line.Type)
+		// Lines in the file's own colors are left out of the joined paths.
+		custom := line.Color != nil && t < lineInvisible && (t != lineCut || tint == "")
+		if s.compactPaths && !custom {
+			paths[t].line(s, x1, y1, x2, y2)
 			continue
 		}
-
-		var v2 *pdo.Face2DVertex
-		if line.IsConnectingFaces {
-			// Connects to another face
-			v2 = get2DVertex(obj, line.Face2Index, line.Vertex2Index)
-		} else {
-			// Boundary line: connects to next vertex in the face
-			v2 = getNext2DVertex(obj, line.FaceIndex, line.VertexIndex)
+		style := ""
+		if custom {
+			style = fmt.Sprintf(` style="stroke:%s"`, hexColor(*line.Color))
+		} else if t == lineCut {
+			style = tint
 		}
+		fmt.Fprintf(s.w, `<line x1="%s" y1="%s" x2="%s" y2="%s" class="%s"%s />`+"\n",
+			s.num(x1), s.num(y1), s.num(x2), s.num(y2), lineClass[t], style)
+	}
 
-		if v2 == nil {
+	for t, path := range paths {
+		if path.d.Len() == 0 {
 			continue
 		}
-
-		// Apply Part Offset (Vertices are local to Part)
-		x1 := v1.X + part.BoundingBox.Left
-		y1 := v1.Y + part.BoundingBox.Top
-		x2 := v2.X + part.BoundingBox.Left
-		y2 := v2.Y + part.BoundingBox.Top
-
-		class := "cut"
-		if line.Type == 1 {
-			class = "mountain"
-		} else if line.Type == 2 {
-			class = "valley"
-		} else if line.Type >= 3 {
-			class = "invisible"
+		style := ""
+		if t == lineCut {
+			style = tint
 		}
+		fmt.Fprintf(s.w, `<path d="%s" class="%s"%s />`+"\n", path.d.String(), lineClass[t], style)
+	}
+}
+
+// lineClass is the CSS class of each line type, see drawnLineType.
+var lineClass = [...]string{
+	lineCut:       "cut",
+	lineMountain:  "mountain",
+	lineValley:    "valley",
+	lineInvisible: "invisible",
+}
+
+// svgPath builds path data from line segments with relative commands. A
+// segment starting where the last one ended continues the current subpath.
+type svgPath struct {
+	d      strings.Builder
+	cx, cy float64 // Current point, rounded to the output precision
+}
 
-		fmt.Fprintf(s.w, `<line x1="%.3f" y1="%.3f" x2="%.3f" y2="%.3f" class="%s" />`+"\n",
-			x1, y1, x2, y2, class)
-
-		// Edge Numbers
-		// Only show on cut lines (Type 0) or generally? usually cut lines have numbers.
-		// Mountain/Valley usually don't need numbers as they are connected.
-		// Disconnected edges (cut lines) need numbers to match.
-		// The spec says "ShowEdgeID" in settings.
-		// If line.Type == 0 (Cut), we assume it's an open edge?
-		// Note: A cut line might be an outer boundary.
-		if class == "cut" && p.Settings.ShowEdgeID == 1 {
-			edgeID := findEdgeID(obj, v1.IDVertex, v2.IDVertex)
-			if edgeID > 0 {
-				// Midpoint
-				mx := (x1 + x2) / 2
-				my := (y1 + y2) / 2
-				// Offset text slightly? Or just center. Center is fine.
-				fmt.Fprintf(s.w, `<text x="%.3f" y="%.3f" class="edge-id">%d</text>`+"\n", mx, my, edgeID)
+func (path *svgPath) line(s *SVGWriter, x1, y1, x2, y2 float64) {
+	x1, y1, x2, y2 = s.round(x1), s.round(y1), s.round(x2), s.round(y2)
+	switch {
+	case path.d.Len() == 0:
+		fmt.Fprintf(&path.d, "M%s %s", s.num(x1), s.num(y1))
+	case x1 != path.cx || y1 != path.cy:
+		fmt.Fprintf(&path.d, "m%s %s", s.num(x1-path.cx), s.num(y1-path.cy))
+	}
+	fmt.Fprintf(&path.d, "l%s %s", s.num(x2-x1), s.num(y2-y1))
+	path.cx, path.cy = x2, y2
+}
+
+// fontSize formats a font size to a micrometre without trailing zeros, 3
+// rather than 3.000. Font sizes don't follow the coordinate precision.
+func fontSize(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e3)/1e3, 'f', -1, 64)
+}
+
+// round rounds a coordinate to the output precision.
+func (s *SVGWriter) round(v float64) float64 {
+	scale := math.Pow10(s.precision)
+	return math.Round(v*scale) / scale
+}
+
+// num formats a coordinate with the output precision.
+func (s *SVGWriter) num(v float64) string {
+	str := strconv.FormatFloat(v, 'f', s.precision, 64)
+	if strings.TrimLeft(str, "-0.") == "" {
+		return str[strings.IndexByte(str, '0'):] // Drop the sign of rounded negative zeros
+	}
+	return str
+}
+
+// writePartFill fills the faces of a part with its palette color.
+func (s *SVGWriter) writePartFill(p *pdo.PDO, part *pdo.Part) {
+	idx := partIndex(p, part)
+	r, g, b := partColor(idx)
+	left, top := s.partOrigin(p, part)
+	for _, poly := range partPolygons(p, part, idx) {
+		var points strings.Builder
+		for i, v := range poly {
+			if i > 0 {
+				points.WriteByte(' ')
 			}
+			fmt.Fprintf(&points, "%s,%s", s.num(v.X+left), s.num(v.Y+top))
 		}
+		fmt.Fprintf(s.w, `<polygon points="%s" style="fill:#%02x%02x%02x; fill-opacity:%g; stroke:none" />`+"\n",
+			points.String(), r, g, b, partFillOpacity)
+	}
+}
+
+// WriteLegend lists the color of every part, starting at (x, y).
+func (s *SVGWriter) WriteLegend(p *pdo.PDO, x, y float64) {
+	fmt.Fprintln(s.w, `<g id="legend">`)
+	for i := range p.Parts {
+		r, g, b := partColor(i)
+		rowY := y + float64(i)*legendRow
+		fmt.Fprintf(s.w, `<rect x="%.3f" y="%.3f" width="%g" height="%g" style="fill:#%02x%02x%02x" />`+"\n",
+			x, rowY, legendSwatch, legendSwatch, r, g, b)
+		fmt.Fprintf(s.w, `<text x="%.3f" y="%.3f" class="legend">%s</text>`+"\n",
+			x+legendSwatch+1.5, rowY+legendSwatch-0.5, xmlEscape(partLabel(p, i)))
+	}
+	fmt.Fprintln(s.w, `</g>`)
+}
+
+func (s *SVGWriter) writeEdgeIDs(p *pdo.PDO, part *pdo.Part) {
+	// Edge Numbers
+	// Cut lines are split edges, their IDs show which edges get glued together.
+	left, top := s.partOrigin(p, part)
+	for _, l := range placeEdgeLabels(p, s.lookup(p, part), part) {
+		fmt.Fprintf(s.w, `<text x="%s" y="%s" class="edge-id">%d</text>`+"\n",
+			s.num(l.X+left), s.num(l.Y+top), l.ID)
 	}
 }
 
-func findEdgeID(obj pdo.Object, v1, v2 int32) int {
-	for i, e := range obj.Edges {
-		if (e.Vertex1Index == v1 && e.Vertex2Index == v2) || (e.Vertex1Index == v2 && e.Vertex2Index == v1) {
-			return i + 1 // 1-based index
+// lookup returns the Lookup of the object of a part, indexing p when it
+// isn't the PDO indexed last.
+func (s *SVGWriter) lookup(p *pdo.PDO, part *pdo.Part) *pdo.Lookup {
+	if s.indexed != p {
+		s.indexed, s.lookups = p, p.Lookups()
+	}
+	return s.lookups.Part(part)
+}
+
+// origin returns where the top left corner of a layout box is drawn. With
+// page frames, content moves from layout coordinates onto the page grid.
+func (s *SVGWriter) origin(bb pdo.Rect) (x, y float64) {
+	if s.frames == nil {
+		return bb.Left, bb.Top
+	}
+	return s.onPage(bb, math.Floor(bb.Left/s.frames.ClippedWidth), math.Floor(bb.Top/s.frames.ClippedHeight))
+}
+
+// partOrigin returns where the top left corner of a part's box is drawn,
+// on the page PDO.PartPages puts the part on.
+func (s *SVGWriter) partOrigin(p *pdo.PDO, part *pdo.Part) (x, y float64) {
+	i := partIndex(p, part)
+	if s.frames == nil || i < 0 || i >= len(s.pages) {
+		return s.origin(part.BoundingBox)
+	}
+	return s.onPage(part.BoundingBox, float64(s.pages[i][0]), float64(s.pages[i][1]))
+}
+
+// onPage moves a layout box onto page px, py of the page frames.
+func (s *SVGWriter) onPage(bb pdo.Rect, px, py float64) (x, y float64) {
+	d := s.frames
+	return bb.Left + px*(d.Width-d.ClippedWidth) + d.MarginLeft,
+		bb.Top + py*(d.Height-d.ClippedHeight) + d.MarginTop
+}
+
+// WritePageFrames draws the outline and printable area of every page in the
+// grid on a layer of its own, from one symbol placed per page.
+func (s *SVGWriter) WritePageFrames(dims pdo.PageDims, pagesX, pagesY int) {
+	fmt.Fprintf(s.w, `<defs><symbol id="page-frame" viewBox="0 0 %.3f %.3f" width="%.3f" height="%.3f">`+"\n",
+		dims.Width, dims.Height, dims.Width, dims.Height)
+	pageStyle := ""
+	if s.guides.Background != nil {
+		pageStyle = fmt.Sprintf(` style="fill:%s"`, hexColor(*s.guides.Background))
+	}
+	fmt.Fprintf(s.w, `<rect x="0" y="0" width="%.3f" height="%.3f" class="page"%s />`+"\n", dims.Width, dims.Height, pageStyle)
+	fmt.Fprintf(s.w, `<rect x="%.3f" y="%.3f" width="%.3f" height="%.3f" class="margin" />`+"\n",
+		dims.MarginLeft, dims.MarginTop, dims.ClippedWidth, dims.ClippedHeight)
+	fmt.Fprintln(s.w, `</symbol></defs>`)
+
+	fmt.Fprintln(s.w, `<g id="pages" inkscape:groupmode="layer" inkscape:label="Pages">`)
+	for py := range pagesY {
+		for px := range pagesX {
+			fmt.Fprintf(s.w, `<use xlink:href="#page-frame" x="%.3f" y="%.3f" />`+"\n",
+				float64(px)*dims.Width, float64(py)*dims.Height)
 		}
 	}
-	return 0 // Not found
+	fmt.Fprintln(s.w, `</g>`)
 }
 
-// get2DVertex is in util.go
+// WriteGuides draws the paper color, the printable area and the grid of
+// every page in the grid on a layer of its own. With page frames the paper
+// color fills the frames and their margins are already drawn.
+func (s *SVGWriter) WriteGuides(dims pdo.PageDims, pagesX, pagesY int) {
+	g := s.guides
+	fmt.Fprintln(s.w, `<g id="guides" inkscape:groupmode="layer" inkscape:label="Guides">`)
+	if g.Background != nil && s.frames == nil {
+		fmt.Fprintf(s.w, `<rect x="0" y="0" width="%.3f" height="%.3f" style="fill:%s; stroke:none" />`+"\n",
+			s.width, s.height, hexColor(*g.Background))
+	}
+	for py := range pagesY {
+		for px := range pagesX {
+			left, top := s.origin(pdo.Rect{Left: float64(px) * dims.ClippedWidth, Top: float64(py) * dims.ClippedHeight})
+			if g.Margins && s.frames == nil {
+				fmt.Fprintf(s.w, `<rect x="%.3f" y="%.3f" width="%.3f" height="%.3f" class="margin" />`+"\n",
+					left, top, dims.ClippedWidth, dims.ClippedHeight)
+			}
+			lines := g.gridLines(dims)
+			if len(lines) == 0 {
+				continue
+			}
+			left, top = left-dims.MarginLeft, top-dims.MarginTop
+			var d strings.Builder
+			for _, l := range lines {
+				fmt.Fprintf(&d, "M%.3f %.3fL%.3f %.3f", left+l[0], top+l[1], left+l[2], top+l[3])
+			}
+			fmt.Fprintf(s.w, `<path d="%s" style="fill:none; stroke:%s; stroke-width:%g" />`+"\n",
+				d.String(), hexColor(gridGuideColor), gridGuideWidth)
+		}
+	}
+	fmt.Fprintln(s.w, `</g>`)
+}
 
-func ExportSVG(p *pdo.PDO, w io.Writer) error {
-	dims := getPageDims(p)
-	maxPX, maxPY := calculatePageGrid(p, dims)
+// findEdgeID returns the 1-based ID of the edge between two vertices, 0 if there is none.
+func findEdgeID(lk *pdo.Lookup, v1, v2 int32) int {
+	return lk.EdgeIndex(v1, v2) + 1
+}
+
+func ExportSVG(p *pdo.PDO, w io.Writer, opts Options) error {
+	if err := checkUnfold(p); err != nil {
+		return err
+	}
+	p = opts.lines(p)
+	opts = opts.metadata(p)
+	dims := p.PageDims()
+	maxPX, maxPY := p.PageGrid(dims)
 
 	// Total SVG size
 	// +1 because indices are 0-based
@@ -171,16 +482,16 @@ func ExportSVG(p *pdo.PDO, w io.Writer) error {
 		totalHeight = dims.Height
 	} else {
 		// If multi-page, we might want to put them side-by-side or vertical?
-		// calculatePageGrid assumes global coordinates are already spread out.
+		// PageGrid assumes global coordinates are already spread out.
 		// If they occupy (210, 0) range, that's Page 1 (index 1).
 		// So MaxPX=1 implies Width needs to be at least 2*210.
-		// But wait, getPageDims returns Width=210.
+		// But wait, PageDims returns Width=210.
 		// So totalWidth should be enough to cover MaxPX.
 		// Yes, (maxPX+1) * dims.Width is correct if pages are laid out horizontally/vertically in grid.
 		// However, margins might complicate things if we want to "view" it as a continuous sheet.
 		// But since coordinates are global, we just need a ViewBox big enough.
 
-		// Note regarding margins: calculatePageGrid divides by ClippedWidth.
+		// Note regarding margins: PageGrid divides by ClippedWidth.
 		// Global coordinate X corresponds to PageX = X / ClippedWidth.
 		// Real Page Width is 'Width'.
 		// If we set SVG viewBox to (MaxPX+1)*Width, we cover the area.
@@ -203,7 +514,7 @@ func ExportSVG(p *pdo.PDO, w io.Writer) error {
 		// If parts are at 500mm, we need viewBox to 500mm.
 		// Logic:
 		// Find Max X/Y of actual parts?
-		// calculatePageGrid finds Max Page Index.
+		// PageGrid finds Max Page Index.
 		// Let's just find the max/min bounding box of all parts and use that?
 		// That's safer.
 	}
@@ -234,8 +545,10 @@ func ExportSVG(p *pdo.PDO, w io.Writer) error {
 
 	if !foundParts {
 		// Empty
+		opts.logger().Warn("no unfolded parts to export")
 		return nil
 	}
+	warnLayout(p, opts, true)
 
 	// Add some padding? Or just use Page Size multiples?
 	// Using Page Size multiples looks cleaner if printing is expected.
@@ -244,19 +557,155 @@ func ExportSVG(p *pdo.PDO, w io.Writer) error {
 	// User complained about "all on first page", presumably because content was cut off.
 	// Let's use max(PageSize, ContentSize).
 
-	if maxX > totalWidth {
-		totalWidth = maxX
-	}
-	if maxY > totalHeight {
-		totalHeight = maxY
+	// Page frames show the grid of printed pages, parts beyond their page are
+	// cut off like in print.
+	if !opts.PageFrames {
+		if maxX > totalWidth {
+			totalWidth = maxX
+		}
+		if maxY > totalHeight {
+			totalHeight = maxY
+		}
+	} else {
+		totalWidth = float64(maxPX+1) * dims.Width
+		totalHeight = float64(maxPY+1) * dims.Height
 	}
 
 	// Also if minX < 0, we might need adjustments?
 	// Usually papercraft starts at >0.
 
+	// The legend goes below the layout.
+	legendY := totalHeight + legendRow
+	if opts.PartColoring != PartColorsOff {
+		totalHeight = legendY + float64(len(p.Parts)+1)*legendRow
+	}
+
+	var code *qr.Code
+	if opts.Stamp.enabled() {
+		var err error
+		if code, err = opts.Stamp.code(); err != nil {
+			return err
+		}
+	}
+
 	svg := NewSVGWriter(w, totalWidth, totalHeight) // Width/Height are doubles
+	svg.log = opts.logger()
+	svg.edgeIDSize = edgeIDSize(p.Settings)
+	svg.flapStyle = opts.FlapStyle
+	svg.outline = opts.outlineOffset(p.Settings)
+	svg.perforation = opts.Perforation
+	svg.materialLayers = opts.MaterialLayers
+	svg.partColoring = opts.PartColoring
+	svg.compactPaths = opts.CompactPaths
+	svg.units = opts.Units
+	svg.guides = opts.Guides
+	svg.flipY = opts.FlipY
+	if opts.Duplicates != DuplicatesKeep {
+		svg.duplicates = newSVGDuplicates(p, opts.Duplicates)
+	}
+	if opts.Textures {
+		svg.textures = newSVGTextures(p, opts)
+	}
+	if opts.Precision > 0 {
+		svg.precision = opts.Precision
+	}
+	if opts.PageFrames {
+		svg.frames = &dims
+		svg.pages = p.PartPages(dims)
+	}
+	if opts.TextToPath {
+		var err error
+		if svg.outliner, err = newTextOutliner(); err != nil {
+			return err
+		}
+	}
 	svg.WriteHeader()
+	writeSVGMetadata(w, opts)
+	if opts.PageFrames {
+		svg.WritePageFrames(dims, maxPX+1, maxPY+1)
+	}
+	if opts.Guides.enabled() {
+		svg.WriteGuides(dims, maxPX+1, maxPY+1)
+	}
 	svg.WritePDO(p)
+	if opts.PartColoring != PartColorsOff {
+		svg.WriteLegend(p, dims.MarginLeft, legendY)
+	}
+	if opts.Stamp.enabled() {
+		svg.WriteStamp(opts.Stamp, code, dims, layoutPages(p, dims))
+	}
+	if opts.Watermark.enabled() {
+		if err := svg.WriteWatermark(opts.Watermark, dims, layoutPages(p, dims)); err != nil {
+			return err
+		}
+	}
 	svg.WriteFooter()
 	return nil
 }
+
+// WriteStamp stamps every page of the layout. Pages are placed like in the
+// printed output: page (px, py) starts one margin before its printable area.
+func (s *SVGWriter) WriteStamp(stamp Stamp, code *qr.Code, dims pdo.PageDims, pages []layoutPage) {
+	l := stamp.layout(dims.Width, dims.Height, dims.MarginLeft, dims.MarginTop, code)
+
+	fmt.Fprintln(s.w, `<g id="stamp">`)
+	for _, page := range pages {
+		originX, originY := s.origin(pdo.Rect{Left: float64(page.px) * dims.ClippedWidth, Top: float64(page.py) * dims.ClippedHeight})
+		originX -= dims.MarginLeft
+		originY -= dims.MarginTop
+
+		if stamp.Text != "" {
+			fmt.Fprintf(s.w, `<text x="%.3f" y="%.3f" class="stamp">%s</text>`+"\n",
+				originX+l.textX, originY+l.textY, xmlEscape(stamp.footer(page.index+1, len(pages))))
+		}
+		if stamp.Attribution != "" {
+			fmt.Fprintf(s.w, `<text x="%.3f" y="%.3f" class="stamp" style="font-size:%gpx; text-anchor:middle">%s</text>`+"\n",
+				originX+l.attrX, originY+l.attrY, attributionSize, xmlEscape(stamp.Attribution))
+		}
+		if code != nil {
+			var d strings.Builder
+			qrRuns(code, func(x, y, n int) {
+				fmt.Fprintf(&d, "M%.3f %.3fh%.3fv%.3fh%.3fz",
+					originX+l.qrX+float64(x)*l.module, originY+l.qrY+float64(y)*l.module,
+					float64(n)*l.module, l.module, -float64(n)*l.module)
+			})
+			fmt.Fprintf(s.w, `<path d="%s" class="stamp-qr" />`+"\n", d.String())
+		}
+	}
+	fmt.Fprintln(s.w, `</g>`)
+}
+
+type svgExporter struct{}
+
+func (svgExporter) Name() string         { return "svg" }
+func (svgExporter) Extensions() []string { return []string{".svg"} }
+func (svgExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ExportSVG(p, t.W, opts)
+}
+
+// svgzExporter writes gzip-compressed SVG.
+type svgzExporter struct{}
+
+func (svgzExporter) Name() string         { return "svgz" }
+func (svgzExporter) Extensions() []string { return []string{".svgz"} }
+func (svgzExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	gz, err := gzip.NewWriterLevel(t.W, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if err := ExportSVG(p, gz, opts); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func init() {
+	Register(svgExporter{})
+	Register(svgzExporter{})
+}
@@ -0,0 +1,219 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+func TestSVGWriter_OriginOnPageGrid(t *testing.T) {
+	dims := pdo.PageDims{Width: 210, Height: 297, MarginLeft: 10, MarginTop: 15, ClippedWidth: 190, ClippedHeight: 267}
+	s := NewSVGWriter(io.Discard, 0, 0)
+
+	bb := pdo.Rect{Left: 190 + 5, Top: 267*2 + 7}
+	if x, y := s.origin(bb); x != bb.Left || y != bb.Top {
+		t.Errorf("without frames: got (%g, %g), want layout coordinates", x, y)
+	}
+
+	// Page (1, 2) starts at (210, 594), its printable area one margin further in.
+	s.frames = &dims
+	x, y := s.origin(bb)
+	if math.Abs(x-(210+10+5)) > 1e-9 || math.Abs(y-(594+15+7)) > 1e-9 {
+		t.Errorf("with frames: got (%g, %g), want (225, 616)", x, y)
+	}
+}
+
+func TestExportSVG_PageFrames(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ExportSVG(p, &buf, Options{PageFrames: true}); err != nil {
+		t.Fatal(err)
+	}
+	maxPX, maxPY := p.PageGrid(p.PageDims())
+	if got, want := strings.Count(buf.String(), `<use xlink:href="#page-frame"`), (maxPX+1)*(maxPY+1); got != want {
+		t.Errorf("%d page frames, want %d", got, want)
+	}
+}
+
+func TestExportSVG_CompactPaths(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/sphere.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plain, compact bytes.Buffer
+	if err := ExportSVG(p, &plain, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportSVG(p, &compact, Options{Precision: 2, CompactPaths: true}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(compact.String(), "<line ") {
+		t.Error("compact output still has line elements")
+	}
+	if compact.Len() >= plain.Len()/2 {
+		t.Errorf("compact output is %d bytes, plain %d", compact.Len(), plain.Len())
+	}
+}
+
+func TestSVGPath_Relative(t *testing.T) {
+	s := NewSVGWriter(io.Discard, 0, 0)
+	s.precision = 1
+	var path svgPath
+	path.line(s, 1.04, 2, 3, 2)
+	path.line(s, 3.02, 2, 3, 5)   // Continues at the rounded end point
+	path.line(s, 10, 10, 9.96, 9) // Moves to a new subpath
+	if got, want := path.d.String(), "M1.0 2.0l2.0 0.0l0.0 3.0m7.0 5.0l0.0 -1.0"; got != want {
+		t.Errorf("path data %q, want %q", got, want)
+	}
+}
+
+func TestExportSVG_TexturePatterns(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{Texture: true, Pages: 3})
+	var buf bytes.Buffer
+	if err := ExportSVG(p, &buf, Options{Textures: true}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "data:image/png;base64,"); n != 1 {
+		t.Errorf("texture embedded %d times, want once", n)
+	}
+	// 6 faces of 2 triangles on each of 3 parts.
+	if n := strings.Count(out, "fill:url(#face-"); n != 36 {
+		t.Errorf("%d textured triangles, want 36", n)
+	}
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Errorf("invalid XML: %v", err)
+	}
+}
+
+func TestExportSVG_FlipY(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{})
+	var plain, flipped bytes.Buffer
+	if err := ExportSVG(p, &plain, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportSVG(p, &flipped, Options{FlipY: true}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(plain.String(), `id="flip-y"`) {
+		t.Error("plain output is flipped")
+	}
+	h, num := p.PageDims().Height, NewSVGWriter(io.Discard, 0, 0).num
+	if !strings.Contains(flipped.String(), fmt.Sprintf(`<g id="flip-y" transform="matrix(1 0 0 -1 0 %s)">`, num(h))) {
+		t.Error("flipped output lacks the mirroring group")
+	}
+	// The text block keeps upright glyphs, its box moves to the mirrored place.
+	tb := p.TextBlocks[0]
+	y := textBaselines(&tb)[0] + h - 2*tb.BoundingBox.Top - tb.BoundingBox.Height
+	if !strings.Contains(flipped.String(), fmt.Sprintf(`y="%s"`, num(y))) {
+		t.Errorf("no text at the mirrored baseline %g", y)
+	}
+}
+
+func TestExportSVG_Duplicates(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{Pages: 3})
+	export := func(mode Duplicates) string {
+		var buf bytes.Buffer
+		if err := ExportSVG(p, &buf, Options{Duplicates: mode}); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	keep, use, count := export(DuplicatesKeep), export(DuplicatesUse), export(DuplicatesCount)
+
+	lines := strings.Count(keep, "<line ")
+	if n := strings.Count(use, "<line "); n != lines/3 {
+		t.Errorf("instanced output has %d lines, want %d", n, lines/3)
+	}
+	w := p.PageDims().ClippedWidth
+	for i := 1; i < 3; i++ {
+		want := fmt.Sprintf(`<use xlink:href="#part-0" transform="matrix(1 0 0 1 %.3f 0.000)" />`, float64(i)*w)
+		if !strings.Contains(use, want) {
+			t.Errorf("no instance %s", want)
+		}
+	}
+	if n := strings.Count(count, "<line "); n != lines/3 || !strings.Contains(count, ">Cut 3 of this piece</text>") {
+		t.Errorf("counted output has %d lines, want %d and a count label", n, lines/3)
+	}
+}
+
+func TestExportSVG_EdgeLines(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The sample stores its folds as cuts, compare the outlines alone.
+	for i := range p.Parts {
+		p.Parts[i].Lines = slices.DeleteFunc(p.Parts[i].Lines, func(l pdo.Line) bool { return l.IsConnectingFaces })
+	}
+	var want bytes.Buffer
+	if err := ExportSVG(p, &want, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range p.Parts {
+		p.Parts[i].Lines = nil
+	}
+	var got bytes.Buffer
+	if err := ExportSVG(p, &got, Options{EdgeLines: true}); err != nil {
+		t.Fatal(err)
+	}
+	if n, w := strings.Count(got.String(), `class="cut"`), strings.Count(want.String(), `class="cut"`); n == 0 || n != w {
+		t.Errorf("%d cut lines from the edges, want %d", n, w)
+	}
+	if p.Parts[0].Lines != nil {
+		t.Error("EdgeLines changed the lines of the PDO")
+	}
+}
+
+func TestExportSVG_UnknownLineTypes(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{})
+	classes := func(opts Options) map[string]int {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := ExportSVG(p, &buf, opts); err != nil {
+			t.Fatal(err)
+		}
+		if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+			t.Errorf("SVG is not well-formed: %v", err)
+		}
+		n := map[string]int{}
+		for _, c := range []string{"cut", "mountain", "invisible"} {
+			n[c] = strings.Count(buf.String(), `class="`+c+`"`)
+		}
+		return n
+	}
+	want := classes(Options{})
+
+	// Types from the file below cut are drawn as cuts, those past
+	// invisible are invisible.
+	var folds []int
+	for i, l := range p.Parts[0].Lines {
+		if l.Type == lineMountain {
+			folds = append(folds, i)
+		}
+	}
+	p.Parts[0].Lines[folds[0]].Type = -1
+	p.Parts[0].Lines[folds[1]].Type = 9
+	want["cut"]++
+	want["mountain"] -= 2
+	want["invisible"]++
+	if got := classes(Options{}); !maps.Equal(got, want) {
+		t.Errorf("lines %v, want %v", got, want)
+	}
+	// Joined paths have one per class.
+	if got := classes(Options{CompactPaths: true}); got["cut"] != 1 || got["mountain"] != 1 || got["invisible"] != 1 {
+		t.Errorf("compact paths %v, want one per class", got)
+	}
+}
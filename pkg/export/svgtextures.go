@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// svgTextures paints the faces of parts beneath their lines in SVG, with
+// their texture or the 2D color of their material. Each texture is stored
+// once as a pattern in the defs. Every textured triangle gets a small
+// pattern of its own inheriting the image and mapping it with a transform,
+// so the image data isn't repeated.
+type svgTextures struct {
+	p    *pdo.PDO
+	fill FaceFill
+	log  *slog.Logger
+	// patterns holds the pattern ID of each material's texture, empty for
+	// materials without a usable texture.
+	patterns []string
+}
+
+func newSVGTextures(p *pdo.PDO, opts Options) *svgTextures {
+	return &svgTextures{
+		p:        p,
+		fill:     opts.FaceFill.resolve(p.Settings),
+		log:      opts.logger(),
+		patterns: make([]string, len(p.Materials)),
+	}
+}
+
+// writeDefs writes the pattern of every texture used by a part.
+func (t *svgTextures) writeDefs(s *SVGWriter) {
+	if t.fill == FaceFillColor {
+		return
+	}
+	used := make([]bool, len(t.p.Materials))
+	for _, face := range t.p.AllFaces() {
+		if m := face.MaterialIndex; face.PartIndex >= 0 && m >= 0 && int(m) < len(used) {
+			used[m] = true
+		}
+	}
+	owners := textureOwners(t.p)
+	for i, j := range owners {
+		if used[i] {
+			used[j] = true
+		}
+	}
+
+	fmt.Fprintln(s.w, `<defs id="textures">`)
+	for i, mat := range t.p.Materials {
+		if !used[i] || owners[i] != i || !mat.HasTexture {
+			continue
+		}
+		img, err := mat.Texture.GetImage()
+		if err != nil {
+			t.log.Warn("failed to decode texture", "material", mat.Name, "err", err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.log.Warn("failed to encode texture", "material", mat.Name, "err", err)
+			continue
+		}
+		// The image fills the unit square of texture space.
+		t.patterns[i] = fmt.Sprintf("texture-%d", i)
+		fmt.Fprintf(s.w, `<pattern id="%s" patternUnits="userSpaceOnUse" width="1" height="1">`+
+			`<image width="1" height="1" preserveAspectRatio="none" xlink:href="data:image/png;base64,%s" /></pattern>`+"\n",
+			t.patterns[i], base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+	fmt.Fprintln(s.w, `</defs>`)
+	for i, j := range owners {
+		t.patterns[i] = t.patterns[j]
+	}
+}
+
+// writePart paints the faces of a part.
+func (t *svgTextures) writePart(s *SVGWriter, part *pdo.Part) {
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(t.p.Objects) {
+		return
+	}
+	i := partIndex(t.p, part)
+	left, top := s.partOrigin(t.p, part)
+	at := func(v pdo.Face2DVertex) PDFPoint { return PDFPoint{X: v.X + left, Y: v.Y + top} }
+	for fi, face := range t.p.Objects[part.ObjectIndex].PartFaces(i) {
+		if len(face.Vertices) < 3 {
+			continue
+		}
+		m := face.MaterialIndex
+		if m < 0 || int(m) >= len(t.p.Materials) {
+			continue
+		}
+		if t.fill == FaceFillColor {
+			c := t.p.Materials[m].Color2DRGBA
+			points := make([]PDFPoint, len(face.Vertices))
+			for j, v := range face.Vertices {
+				points[j] = at(v)
+			}
+			fmt.Fprintf(s.w, `<path d="%s" style="fill:#%02x%02x%02x; fill-opacity:%g; stroke:none" />`+"\n",
+				s.polygon(points), unitByte(c[0]), unitByte(c[1]), unitByte(c[2]), float64(max(min(c[3], 1), 0)))
+			continue
+		}
+		texture := t.patterns[m]
+		if texture == "" {
+			continue
+		}
+		// Triangles of a fan, each mapped from texture space on its own.
+		v0 := face.Vertices[0]
+		for j := 1; j+1 < len(face.Vertices); j++ {
+			v1, v2 := face.Vertices[j], face.Vertices[j+1]
+			mat, ok := textureMatrix(v0, v1, v2, at(v0), at(v1), at(v2))
+			if !ok {
+				continue
+			}
+			id := fmt.Sprintf("face-%d-%d-%d", i, fi, j)
+			fmt.Fprintf(s.w, `<pattern id="%s" xlink:href="#%s" patternTransform="matrix(%s %s %s %s %s %s)" />`+"\n",
+				id, texture, s.num(mat.A), s.num(mat.B), s.num(mat.C), s.num(mat.D), s.num(mat.E), s.num(mat.F))
+			fmt.Fprintf(s.w, `<path d="%s" style="fill:url(#%s); stroke:none" />`+"\n",
+				s.polygon([]PDFPoint{at(v0), at(v1), at(v2)}), id)
+		}
+	}
+}
+
+// polygon returns the path data of a closed polygon.
+func (s *SVGWriter) polygon(points []PDFPoint) string {
+	var d strings.Builder
+	for i, pt := range points {
+		if i == 0 {
+			d.WriteByte('M')
+		} else {
+			d.WriteByte('L')
+		}
+		fmt.Fprintf(&d, "%s %s", s.num(pt.X), s.num(pt.Y))
+	}
+	d.WriteByte('Z')
+	return d.String()
+}
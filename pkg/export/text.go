@@ -0,0 +1,66 @@
+package export
+
+import (
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// ptToMM converts font points to millimetres.
+const ptToMM = 25.4 / 72
+
+// textRGB splits a text block color, stored as R, G, B, 0 bytes, into its channels.
+func textRGB(c int32) (r, g, b int) {
+	return int(c & 0xFF), int(c >> 8 & 0xFF), int(c >> 16 & 0xFF)
+}
+
+// textFontSize returns the font size of a text block in mm.
+func textFontSize(tb *pdo.TextBlock) float64 {
+	return float64(tb.FontSize) * ptToMM
+}
+
+// textBaselines returns the baseline y of each line of a text block, in layout
+// coordinates. LineSpacing is the gap between lines, on top of the font size.
+func textBaselines(tb *pdo.TextBlock) []float64 {
+	size := textFontSize(tb)
+	ys := make([]float64, len(tb.Lines))
+	for i := range ys {
+		ys[i] = tb.BoundingBox.Top + size + float64(i)*(size+tb.LineSpacing)
+	}
+	return ys
+}
+
+// Generic font families used when a text block's font isn't available.
+const (
+	familySans  = "sans-serif"
+	familySerif = "serif"
+	familyMono  = "monospace"
+)
+
+// fontFamily maps a Windows font name to the closest generic family.
+// Japanese Gothic fonts are sans-serif, Mincho fonts are serif.
+func fontFamily(name string) string {
+	n := strings.ToLower(name)
+	for _, mono := range []string{"courier", "mono", "consolas", "terminal"} {
+		if strings.Contains(n, mono) {
+			return familyMono
+		}
+	}
+	for _, serif := range []string{"times", "mincho", "明朝", "serif", "georgia", "garamond", "century"} {
+		if strings.Contains(n, serif) && !strings.Contains(n, "sans") {
+			return familySerif
+		}
+	}
+	return familySans
+}
+
+// pdfCoreFont returns the PDF core font standing in for a generic family.
+func pdfCoreFont(family string) string {
+	switch family {
+	case familySerif:
+		return "Times"
+	case familyMono:
+		return "Courier"
+	}
+	return "Helvetica"
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func textSample(t *testing.T) *pdo.PDO {
+	t.Helper()
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.TextBlocks = []pdo.TextBlock{{
+		BoundingBox: pdo.Rect{Left: 10, Top: 10},
+		LineSpacing: 1,
+		Color:       0x0000FF, // Red
+		FontSize:    12,
+		FontName:    "ＭＳ 明朝",
+		Lines:       []string{"Glue <A> & B", "Клей"},
+	}}
+	return p
+}
+
+func TestExportSVG_TextBlock(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportSVG(textSample(t), &buf, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"font-family:'ＭＳ 明朝', serif",
+		"fill:#ff0000",
+		"font-size:4.233px",
+		`<text x="10.000" y="14.233">Glue &lt;A&gt; &amp; B</text>`,
+		`<text x="10.000" y="19.467">Клей</text>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("SVG output lacks %q", want)
+		}
+	}
+}
+
+func TestExportPDF_TextBlock(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportPDF(textSample(t), &buf, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("Times-Roman")) {
+		t.Error("PDF output does not use the serif core font")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/BaseFont /utf8goregular")) {
+		t.Error("PDF output does not embed the Unicode font for Cyrillic text")
+	}
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// outlineFont is the embedded font used to convert text to paths. It's parsed
+// on first use.
+var outlineFont = sync.OnceValues(func() (*sfnt.Font, error) {
+	return sfnt.Parse(goregular.TTF)
+})
+
+// textOutliner converts strings into SVG path data using outlineFont.
+type textOutliner struct {
+	font *sfnt.Font
+	buf  sfnt.Buffer
+	ppem fixed.Int26_6 // Glyphs are loaded at one pixel per font unit
+	upem float64
+}
+
+func newTextOutliner() (*textOutliner, error) {
+	f, err := outlineFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outline font: %w", err)
+	}
+	upem := f.UnitsPerEm()
+	return &textOutliner{font: f, ppem: fixed.I(int(upem)), upem: float64(upem)}, nil
+}
+
+// path returns the outline of s as SVG path data, with the baseline starting
+// at (x, y) and an em size of size mm. It also returns the characters the font
+// has no glyph for, those are skipped.
+func (o *textOutliner) path(s string, x, y, size float64) (string, []rune) {
+	var d strings.Builder
+	var missing []rune
+	scale := size / o.upem / 64 // 26.6 font units to mm
+
+	pen := 0.0
+	prev := sfnt.GlyphIndex(0)
+	for _, r := range s {
+		idx, err := o.font.GlyphIndex(&o.buf, r)
+		if err != nil || idx == 0 {
+			missing = append(missing, r)
+			prev = 0
+			continue
+		}
+		if prev != 0 {
+			if kern, err := o.font.Kern(&o.buf, prev, idx, o.ppem, font.HintingNone); err == nil {
+				pen += float64(kern) * scale
+			}
+		}
+
+		segs, err := o.font.LoadGlyph(&o.buf, idx, o.ppem, nil)
+		if err != nil {
+			missing = append(missing, r)
+			prev = 0
+			continue
+		}
+		pt := func(p fixed.Point26_6) string {
+			return fmt.Sprintf("%.3f %.3f", x+pen+float64(p.X)*scale, y+float64(p.Y)*scale)
+		}
+		for i, seg := range segs {
+			switch seg.Op {
+			case sfnt.SegmentOpMoveTo:
+				if i > 0 {
+					d.WriteString("Z")
+				}
+				d.WriteString("M" + pt(seg.Args[0]))
+			case sfnt.SegmentOpLineTo:
+				d.WriteString("L" + pt(seg.Args[0]))
+			case sfnt.SegmentOpQuadTo:
+				d.WriteString("Q" + pt(seg.Args[0]) + " " + pt(seg.Args[1]))
+			case sfnt.SegmentOpCubeTo:
+				d.WriteString("C" + pt(seg.Args[0]) + " " + pt(seg.Args[1]) + " " + pt(seg.Args[2]))
+			}
+		}
+		if len(segs) > 0 {
+			d.WriteString("Z")
+		}
+
+		if adv, err := o.font.GlyphAdvance(&o.buf, idx, o.ppem, font.HintingNone); err == nil {
+			pen += float64(adv) * scale
+		}
+		prev = idx
+	}
+	return d.String(), missing
+}
+
+// missingGlyphs returns the characters of s the embedded font can't draw.
+func missingGlyphs(s string) []rune {
+	f, err := outlineFont()
+	if err != nil {
+		return []rune(s)
+	}
+	var buf sfnt.Buffer
+	var missing []rune
+	for _, r := range s {
+		if idx, err := f.GlyphIndex(&buf, r); err != nil || idx == 0 {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
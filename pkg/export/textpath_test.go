@@ -0,0 +1,26 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextOutliner(t *testing.T) {
+	o, err := newTextOutliner()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, missing := o.path("Tab A", 10, 20, 5)
+	if len(missing) != 0 {
+		t.Errorf("unexpected missing glyphs %q", string(missing))
+	}
+	if !strings.HasPrefix(d, "M") || strings.Count(d, "Z") < 4 {
+		t.Errorf("unexpected path data %q", d)
+	}
+
+	_, missing = o.path("のりしろ", 0, 0, 5)
+	if string(missing) != "のりしろ" {
+		t.Errorf("got missing %q, want all kana", string(missing))
+	}
+}
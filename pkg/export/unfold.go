@@ -0,0 +1,38 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// ErrNoUnfold is returned when exporting the template of a model that was
+// saved before it was unfolded.
+var ErrNoUnfold = errors.New("export: model has no unfolding")
+
+// CheckUnfold returns an error wrapping ErrNoUnfold when e writes the
+// printable template and p hasn't been unfolded. 3D formats export any
+// model.
+func CheckUnfold(e Exporter, p *pdo.PDO) error {
+	if IsMesh(e) {
+		return nil
+	}
+	return checkUnfold(p)
+}
+
+// checkUnfold tells how to get an unfolded model when p has none, instead
+// of exporting an empty template.
+func checkUnfold(p *pdo.PDO) error {
+	if p.HasUnfold() {
+		return nil
+	}
+	var mesh []string
+	for _, e := range Exporters() {
+		if IsMesh(e) {
+			mesh = append(mesh, e.Name())
+		}
+	}
+	return fmt.Errorf("%w: unfold it in Pepakura Designer and save it again, or export the 3D model (%s)", ErrNoUnfold, strings.Join(mesh, ", "))
+}
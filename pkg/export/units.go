@@ -0,0 +1,70 @@
+package export
+
+import (
+	"fmt"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Units selects the length unit of exported files.
+type Units int
+
+const (
+	// UnitsDefault keeps each format's own unit: mm for templates, AMF and
+	// OpenSCAD, the model's units for OBJ, X3D and VRML.
+	UnitsDefault Units = iota
+	UnitsMM
+	UnitsCM
+	UnitsInch
+	UnitsPoint
+)
+
+// ParseUnits converts a unit name ("mm", "cm", "in", "pt") into Units.
+func ParseUnits(s string) (Units, error) {
+	switch s {
+	case "":
+		return UnitsDefault, nil
+	case "mm":
+		return UnitsMM, nil
+	case "cm":
+		return UnitsCM, nil
+	case "in", "inch":
+		return UnitsInch, nil
+	case "pt":
+		return UnitsPoint, nil
+	}
+	return UnitsDefault, fmt.Errorf("unknown units %q", s)
+}
+
+func (u Units) String() string {
+	switch u {
+	case UnitsCM:
+		return "cm"
+	case UnitsInch:
+		return "in"
+	case UnitsPoint:
+		return "pt"
+	}
+	return "mm"
+}
+
+// perMM returns the length of a millimetre in the unit.
+func (u Units) perMM() float64 {
+	switch u {
+	case UnitsCM:
+		return 0.1
+	case UnitsInch:
+		return 1 / 25.4
+	case UnitsPoint:
+		return mmToPt
+	}
+	return 1
+}
+
+// realScale returns the size in mm of a model unit.
+func realScale(p *pdo.PDO) float64 {
+	if p.Unfold.Scale <= 0 {
+		return 1
+	}
+	return p.Unfold.Scale
+}
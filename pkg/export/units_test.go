@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestUnits_SVGSize(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dims := p.PageDims()
+	for _, tc := range []struct {
+		units string
+		want  string
+	}{
+		{"", "mm\""},
+		{"in", "in\""},
+		{"pt", "pt\""},
+	} {
+		u, err := ParseUnits(tc.units)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := ExportSVG(p, &buf, Options{Units: u}); err != nil {
+			t.Fatal(err)
+		}
+		svg := buf.String()
+		_, rest, _ := strings.Cut(svg, `width="`)
+		width, _, _ := strings.Cut(rest, `"`)
+		if !strings.HasSuffix(width+`"`, tc.want) {
+			t.Errorf("units %q: width %q", tc.units, width)
+		}
+		// The user units and so the drawing stay in mm.
+		if !strings.Contains(svg, `viewBox="0 0 `) {
+			t.Errorf("units %q: no viewBox", tc.units)
+		}
+		if tc.units == "in" {
+			var v float64
+			if _, err := fmt.Sscanf(width, "%gin", &v); err != nil || math.Abs(v-dims.Width/25.4) > 1e-3 {
+				t.Errorf("width %q, want %.4fin", width, dims.Width/25.4)
+			}
+		}
+	}
+	if _, err := ParseUnits("furlong"); err == nil {
+		t.Error("ParseUnits accepted an unknown unit")
+	}
+}
+
+func TestUnits_Mesh(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := p.Objects[0]
+	v := obj.Vertices[1]
+
+	// OBJ and X3D keep model units unless asked, the solid formats use mm.
+	if got := (Options{}).placement(p, true).vertices(obj)[1]; got != v {
+		t.Errorf("default model units: %+v, want %+v", got, v)
+	}
+	mm := (Options{}).placement(p, false).vertices(obj)[1]
+	if math.Abs(mm.X-v.X*p.Unfold.Scale) > 1e-9 {
+		t.Errorf("default mm: x %g, want %g", mm.X, v.X*p.Unfold.Scale)
+	}
+	cm := (Options{Units: UnitsCM}).placement(p, true).vertices(obj)[1]
+	if math.Abs(cm.X-mm.X/10) > 1e-9 {
+		t.Errorf("cm: x %g, want %g", cm.X, mm.X/10)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportAMF(p, &buf, Options{Units: UnitsInch}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<amf unit="inch"`) {
+		t.Error("AMF in inches doesn't say so")
+	}
+}
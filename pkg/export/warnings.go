@@ -0,0 +1,208 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Warning is a problem logged while parsing or exporting, at the warning
+// level or above.
+type Warning struct {
+	Message string `json:"message"`
+	// Attrs are the attributes of the log record, groups flattened into
+	// dotted keys. Errors are stored as their message.
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// String returns the message followed by the attributes as key=value,
+// sorted by key.
+func (w Warning) String() string {
+	var b strings.Builder
+	b.WriteString(w.Message)
+	for _, k := range slices.Sorted(maps.Keys(w.Attrs)) {
+		fmt.Fprintf(&b, " %s=%v", k, w.Attrs[k])
+	}
+	return b.String()
+}
+
+// WarningCount is how often one warning message was logged.
+type WarningCount struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// Warnings collects the warnings and errors logged through the handlers it
+// wraps, so callers can report them after the fact instead of losing them
+// in the log. It is safe for concurrent use.
+type Warnings struct {
+	mu   sync.Mutex
+	list []Warning
+}
+
+// Handler returns a handler that records warnings and errors and passes
+// every record on to next. They are recorded even when next drops them.
+func (w *Warnings) Handler(next slog.Handler) slog.Handler {
+	return &warningHandler{next: next, warnings: w}
+}
+
+// List returns the warnings recorded so far, in the order they were logged.
+func (w *Warnings) List() []Warning {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Warning(nil), w.list...)
+}
+
+// Len returns the number of warnings recorded so far.
+func (w *Warnings) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.list)
+}
+
+// Summary counts the warnings by message, in the order each message was
+// first logged.
+func (w *Warnings) Summary() []WarningCount {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var out []WarningCount
+	index := map[string]int{}
+	for _, warn := range w.list {
+		i, ok := index[warn.Message]
+		if !ok {
+			i = len(out)
+			index[warn.Message] = i
+			out = append(out, WarningCount{Message: warn.Message})
+		}
+		out[i].Count++
+	}
+	return out
+}
+
+func (w *Warnings) add(warn Warning) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.list = append(w.list, warn)
+}
+
+type warningHandler struct {
+	next     slog.Handler
+	warnings *Warnings
+	attrs    []slog.Attr // Attributes added with WithAttrs, keys qualified
+	group    string      // Current group prefix, "" or ending in "."
+}
+
+func (h *warningHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || h.next.Enabled(ctx, level)
+}
+
+func (h *warningHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		warn := Warning{Message: r.Message}
+		add := func(prefix string, a slog.Attr) {
+			if warn.Attrs == nil {
+				warn.Attrs = map[string]any{}
+			}
+			addAttr(warn.Attrs, prefix, a)
+		}
+		for _, a := range h.attrs {
+			add("", a)
+		}
+		r.Attrs(func(a slog.Attr) bool {
+			add(h.group, a)
+			return true
+		})
+		h.warnings.add(warn)
+	}
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *warningHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+	c.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.group + a.Key
+		c.attrs = append(c.attrs, a)
+	}
+	return &c
+}
+
+func (h *warningHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.next = h.next.WithGroup(name)
+	c.group = h.group + name + "."
+	return &c
+}
+
+// addAttr stores an attribute under its qualified key, flattening groups.
+func addAttr(m map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(m, prefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	value := v.Any()
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	m[prefix+a.Key] = value
+}
+
+// warnLayout logs the problems of the unfolding an exporter would silently
+// work around: lines whose vertices don't match their faces, which are
+// matched another way or left out, and, when pages is set, parts reaching
+// past the page margins, which are cut off.
+func warnLayout(p *pdo.PDO, opts Options, pages bool) {
+	log := opts.logger()
+	lookups := p.Lookups()
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		lk := lookups.Part(part)
+		if lk == nil {
+			log.Warn("part belongs to no object, leaving it out", "part", i, "object", part.ObjectIndex)
+			continue
+		}
+		var repairs [pdo.LineUnresolved + 1]int
+		for line := range part.VisibleLines() {
+			_, _, how := lk.ResolveLine(line)
+			repairs[how]++
+		}
+		if n := repairs[pdo.LineFromEdge] + repairs[pdo.LineFromNearest]; n > 0 {
+			log.Warn("line vertices don't match their faces, matched them by edge or position", "part", i, "lines", n,
+				"edge", repairs[pdo.LineFromEdge], "nearest", repairs[pdo.LineFromNearest])
+		}
+		if n := repairs[pdo.LineUnresolved]; n > 0 {
+			log.Warn("line vertices don't resolve, leaving the lines out", "part", i, "lines", n)
+		}
+	}
+	if !pages || opts.Poster.Enabled {
+		return
+	}
+	for _, u := range p.CheckPrintable(pdo.PrintableArea{}) {
+		log.Warn("part reaches past the page margins and is cut off",
+			"part", u.Part, "page", fmt.Sprintf("%d,%d", u.Page[0]+1, u.Page[1]+1),
+			"mm", math.Round(max(u.Top, u.Right, u.Bottom, u.Left)*10)/10)
+	}
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestWarnings(t *testing.T) {
+	var w Warnings
+	// The next handler drops warnings, they are still collected.
+	log := slog.New(w.Handler(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError})))
+	log.Info("parsed")
+	log.Warn("failed to decode texture", "material", "skin", "err", errors.New("bad zlib"))
+	log.With("file", "a.pdo").WithGroup("part").Warn("failed to decode texture", "index", 2)
+	log.Warn("no unfolded parts to export")
+	log.Error("failed to write page", "page", 1)
+
+	list := w.List()
+	if len(list) != 4 || w.Len() != 4 {
+		t.Fatalf("got %d warnings: %v", len(list), list)
+	}
+	if got := list[0].Attrs; got["material"] != "skin" || got["err"] != "bad zlib" {
+		t.Errorf("first warning attrs %v", got)
+	}
+	if got := list[1].Attrs; got["file"] != "a.pdo" || got["part.index"] != int64(2) {
+		t.Errorf("second warning attrs %v", got)
+	}
+	if got := list[0].String(); got != "failed to decode texture err=bad zlib material=skin" {
+		t.Errorf("first warning string %q", got)
+	}
+	want := []WarningCount{{"failed to decode texture", 2}, {"no unfolded parts to export", 1}, {"failed to write page", 1}}
+	if got := w.Summary(); !slices.Equal(got, want) {
+		t.Errorf("summary %v, want %v", got, want)
+	}
+}
+
+func TestWarnLayout(t *testing.T) {
+	p, err := pdo.ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w Warnings
+	opts := Options{Logger: slog.New(w.Handler(slog.DiscardHandler))}
+	if err := ExportSVG(p, io.Discard, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.Len(); n != 0 {
+		t.Fatalf("%d warnings for a clean file: %v", n, w.List())
+	}
+
+	p.Parts[0].Lines[0].VertexIndex = 9999
+	dims := p.PageDims()
+	p.Parts[1].BoundingBox.Left = dims.ClippedWidth - 1
+	var buf bytes.Buffer
+	if err := ExportSVG(p, &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, c := range w.Summary() {
+		got[c.Message] = true
+	}
+	for _, msg := range []string{"line vertices don't resolve, leaving the lines out", "part reaches past the page margins and is cut off"} {
+		if !got[msg] {
+			t.Errorf("no warning %q in %v", msg, w.Summary())
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Watermark images may be JPEG
+	"math"
+	"unicode/utf8"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// DefaultWatermarkOpacity is the opacity of watermarks when
+// Watermark.Opacity is 0.
+const DefaultWatermarkOpacity = 0.15
+
+// Watermark proportions: the text runs along watermarkLength of the page
+// diagonal, its letters at most watermarkHeight of the shorter page side
+// high, and images fit both on the diagonal.
+const (
+	watermarkLength = 0.7
+	watermarkHeight = 0.25
+	watermarkGray   = 128 // Gray level of watermark text
+	// svgCharWidth is the mean advance of sans-serif letters in em, for
+	// sizing SVG text the viewer lays out.
+	svgCharWidth = 0.6
+)
+
+// Watermark lays text or an image diagonally across every page of PDF and
+// SVG output, over the template, to mark preview copies.
+type Watermark struct {
+	// Text is written large from the bottom left to the top right corner.
+	Text string
+	// Image is a PNG or JPEG file drawn along the same diagonal, beneath
+	// the text.
+	Image []byte
+	// Opacity from 0 (invisible) to 1, DefaultWatermarkOpacity when 0.
+	Opacity float64
+}
+
+func (w Watermark) enabled() bool {
+	return w.Text != "" || len(w.Image) > 0
+}
+
+func (w Watermark) opacity() float64 {
+	if w.Opacity > 0 {
+		return min(w.Opacity, 1)
+	}
+	return DefaultWatermarkOpacity
+}
+
+// watermarkImage is a decoded watermark image header.
+type watermarkImage struct {
+	format        string // "png" or "jpeg"
+	width, height float64
+}
+
+// image reads the header of the watermark image, nil without one.
+func (w Watermark) image() (*watermarkImage, error) {
+	if len(w.Image) == 0 {
+		return nil, nil
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(w.Image))
+	if err != nil {
+		return nil, fmt.Errorf("watermark image: %w", err)
+	}
+	if (format != "png" && format != "jpeg") || cfg.Width == 0 || cfg.Height == 0 {
+		return nil, fmt.Errorf("watermark image: unsupported %s image", format)
+	}
+	return &watermarkImage{format: format, width: float64(cfg.Width), height: float64(cfg.Height)}, nil
+}
+
+// watermarkPlacement places the watermark on a page of the given size in
+// mm: centered, turned by angle radians counterclockwise.
+type watermarkPlacement struct {
+	cx, cy   float64
+	angle    float64
+	length   float64 // Length of the text along the diagonal
+	maxSize  float64 // Largest font size in mm
+	imageW   float64 // Image size in mm
+	imageH   float64
+	hasImage bool
+}
+
+func (w Watermark) place(width, height float64, img *watermarkImage) watermarkPlacement {
+	diagonal := math.Hypot(width, height)
+	l := watermarkPlacement{
+		cx:      width / 2,
+		cy:      height / 2,
+		angle:   math.Atan2(height, width),
+		length:  diagonal * watermarkLength,
+		maxSize: min(width, height) * watermarkHeight,
+	}
+	if img != nil {
+		scale := min(l.length/img.width, 2*l.maxSize/img.height)
+		l.imageW, l.imageH, l.hasImage = img.width*scale, img.height*scale, true
+	}
+	return l
+}
+
+// rotation returns the transform turning the page around the center.
+func (l watermarkPlacement) rotation() PDFMatrix {
+	// Counterclockwise on the page is a negative angle with Y pointing down.
+	sin, cos := math.Sincos(-l.angle)
+	return PDFMatrix{
+		A: cos, B: sin, C: -sin, D: cos,
+		E: l.cx - cos*l.cx + sin*l.cy,
+		F: l.cy - sin*l.cx - cos*l.cy,
+	}
+}
+
+// imageMatrix maps the unit square of the image onto the page.
+func (l watermarkPlacement) imageMatrix() PDFMatrix {
+	sin, cos := math.Sincos(-l.angle)
+	a, b, c, d := cos*l.imageW, sin*l.imageW, -sin*l.imageH, cos*l.imageH
+	return PDFMatrix{A: a, B: b, C: c, D: d, E: l.cx - (a+c)/2, F: l.cy - (b+d)/2}
+}
+
+// pdfWatermark draws the watermark, it does nothing when none is set.
+type pdfWatermark struct {
+	pdf       PDFDocument
+	fonts     *pdfFonts
+	watermark Watermark
+	layout    watermarkPlacement
+	image     string
+}
+
+func newPDFWatermark(pdf PDFDocument, fonts *pdfFonts, wm Watermark, dims pdo.PageDims, prefix string) (*pdfWatermark, error) {
+	if !wm.enabled() {
+		return nil, nil
+	}
+	img, err := wm.image()
+	if err != nil {
+		return nil, err
+	}
+	d := &pdfWatermark{pdf: pdf, fonts: fonts, watermark: wm, layout: wm.place(dims.Width, dims.Height, img)}
+	if img != nil {
+		d.image = prefix + "watermark"
+		imageType := "PNG"
+		if img.format == "jpeg" {
+			imageType = "JPG"
+		}
+		pdf.RegisterImage(d.image, imageType, wm.Image)
+	}
+	return d, nil
+}
+
+// draw marks the template page shifted right by shiftX on the sheet.
+func (d *pdfWatermark) draw(shiftX float64) {
+	if d == nil {
+		return
+	}
+	l := d.layout
+	l.cx += shiftX
+	d.pdf.SetAlpha(d.watermark.opacity())
+	if l.hasImage {
+		d.pdf.DrawImage(d.image, l.imageMatrix())
+	}
+	if d.watermark.Text != "" {
+		// Size the text at 10 points, then scale it to the diagonal.
+		text := d.fonts.use("Arial", 10, d.watermark.Text)
+		size := 10 * ptToMM
+		if w := d.pdf.StringWidth(text); w > 0 {
+			size = min(size*l.length/w, l.maxSize)
+		}
+		text = d.fonts.use("Arial", size/ptToMM, d.watermark.Text)
+		d.pdf.SetTextColor(watermarkGray, watermarkGray, watermarkGray)
+		d.pdf.TransformBegin(l.rotation())
+		d.pdf.Text(l.cx-d.pdf.StringWidth(text)/2, l.cy+size*0.35, text)
+		d.pdf.TransformEnd()
+	}
+	d.pdf.SetAlpha(1)
+}
+
+// WriteWatermark writes the watermark layer over every page, placed like
+// the stamp. The image is embedded once and used on every page.
+func (s *SVGWriter) WriteWatermark(wm Watermark, dims pdo.PageDims, pages []layoutPage) error {
+	img, err := wm.image()
+	if err != nil {
+		return err
+	}
+	l := wm.place(dims.Width, dims.Height, img)
+	size := l.maxSize
+	if n := utf8.RuneCountInString(wm.Text); n > 0 {
+		size = min(l.length/(svgCharWidth*float64(n)), l.maxSize)
+	}
+
+	fmt.Fprintf(s.w, `<g id="watermark" inkscape:groupmode="layer" inkscape:label="Watermark" opacity="%g">`+"\n", wm.opacity())
+	if img != nil {
+		fmt.Fprintf(s.w, `<defs><image id="watermark-image" x="%.3f" y="%.3f" width="%.3f" height="%.3f" preserveAspectRatio="none" xlink:href="data:image/%s;base64,%s"/></defs>`+"\n",
+			-l.imageW/2, -l.imageH/2, l.imageW, l.imageH, img.format, base64.StdEncoding.EncodeToString(wm.Image))
+	}
+	for _, page := range pages {
+		originX, originY := s.origin(pdo.Rect{Left: float64(page.px) * dims.ClippedWidth, Top: float64(page.py) * dims.ClippedHeight})
+		fmt.Fprintf(s.w, `<g transform="translate(%.3f %.3f) rotate(%.3f)">`+"\n",
+			originX-dims.MarginLeft+l.cx, originY-dims.MarginTop+l.cy, -l.angle*180/math.Pi)
+		if img != nil {
+			fmt.Fprintln(s.w, `<use xlink:href="#watermark-image"/>`)
+		}
+		if wm.Text != "" {
+			fmt.Fprintf(s.w, `<text x="0" y="0" style="font-size:%.3fpx; font-family:sans-serif; fill:#808080; text-anchor:middle; dominant-baseline:middle">%s</text>`+"\n",
+				size, xmlEscape(wm.Text))
+		}
+		fmt.Fprintln(s.w, `</g>`)
+	}
+	fmt.Fprintln(s.w, `</g>`)
+	return nil
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo/pdotest"
+)
+
+// watermarkBackend records how the watermark is drawn on fpdf documents.
+type watermarkBackend struct {
+	alphas     []float64
+	images     []PDFMatrix
+	transforms []PDFMatrix
+	texts      []string
+}
+
+func (b *watermarkBackend) NewDocument(width, height float64) PDFDocument {
+	return watermarkDocument{FPDFBackend{}.NewDocument(width, height), b}
+}
+
+type watermarkDocument struct {
+	PDFDocument
+	b *watermarkBackend
+}
+
+func (d watermarkDocument) SetAlpha(alpha float64) {
+	d.b.alphas = append(d.b.alphas, alpha)
+	d.PDFDocument.SetAlpha(alpha)
+}
+
+func (d watermarkDocument) DrawImage(name string, m PDFMatrix) {
+	if name == "watermark" {
+		d.b.images = append(d.b.images, m)
+	}
+	d.PDFDocument.DrawImage(name, m)
+}
+
+func (d watermarkDocument) TransformBegin(m PDFMatrix) {
+	d.b.transforms = append(d.b.transforms, m)
+	d.PDFDocument.TransformBegin(m)
+}
+
+func (d watermarkDocument) Text(x, y float64, s string) {
+	if len(d.b.transforms) > 0 {
+		d.b.texts = append(d.b.texts, s)
+	}
+	d.PDFDocument.Text(x, y, s)
+}
+
+func TestExportWatermark(t *testing.T) {
+	p := pdotest.Cube(pdotest.Options{Pages: 2})
+	dims := p.PageDims()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	img.Set(3, 3, color.Black)
+	var pngData bytes.Buffer
+	png.Encode(&pngData, img)
+	wm := Watermark{Text: "PREVIEW", Image: pngData.Bytes(), Opacity: 0.3}
+
+	b := &watermarkBackend{}
+	var buf bytes.Buffer
+	if err := ExportPDF(p, &buf, Options{PDFBackend: b, Watermark: wm}); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.images) != 2 || len(b.texts) != 2 || b.texts[0] != "PREVIEW" {
+		t.Fatalf("watermark drawn with %d images and texts %q, want one each on 2 pages", len(b.images), b.texts)
+	}
+	if len(b.alphas) != 4 || b.alphas[0] != 0.3 || b.alphas[1] != 1 {
+		t.Errorf("alphas %v, want 0.3 then 1 on each page", b.alphas)
+	}
+	// The image is centered on the page and runs up along the diagonal.
+	m := b.images[0]
+	cx, cy := m.A/2+m.C/2+m.E, m.B/2+m.D/2+m.F
+	if math.Abs(cx-dims.Width/2) > 1e-9 || math.Abs(cy-dims.Height/2) > 1e-9 {
+		t.Errorf("image centered at %.2f,%.2f, want the page center", cx, cy)
+	}
+	if angle := math.Atan2(-m.B, m.A); math.Abs(angle-math.Atan2(dims.Height, dims.Width)) > 1e-9 {
+		t.Errorf("image turned by %.3f rad, not along the diagonal", angle)
+	}
+	r := b.transforms[0]
+	if x, y := r.A*dims.Width/2+r.C*dims.Height/2+r.E, r.B*dims.Width/2+r.D*dims.Height/2+r.F; math.Abs(x-dims.Width/2) > 1e-9 || math.Abs(y-dims.Height/2) > 1e-9 {
+		t.Errorf("text rotation moves the page center to %.2f,%.2f", x, y)
+	}
+
+	buf.Reset()
+	if err := ExportSVG(p, &buf, Options{Watermark: wm}); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	if strings.Count(svg, `<use xlink:href="#watermark-image"/>`) != 2 || strings.Count(svg, ">PREVIEW</text>") != 2 || strings.Count(svg, "base64,") != 1 {
+		t.Errorf("SVG watermark not on both pages with the image embedded once:\n%s", svg[strings.Index(svg, `<g id="watermark"`):])
+	}
+	if !strings.Contains(svg, `opacity="0.3"`) {
+		t.Error("SVG watermark lacks the opacity")
+	}
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Errorf("SVG is not well-formed: %v", err)
+	}
+
+	bad := Options{Watermark: Watermark{Image: []byte("not an image")}}
+	if err := ExportPDF(p, &buf, bad); err == nil || !strings.Contains(err.Error(), "watermark image") {
+		t.Errorf("undecodable image: got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// x3dShape holds the faces of one object that share a material.
+type x3dShape struct {
+	material  int32
+	coordIdx  []int32 // Vertex indices, each face ended by -1
+	texCoords []float64
+}
+
+// x3dShapes splits the faces of an object into one shape per material, in
+// order of first use.
+func x3dShapes(obj pdo.Object) []*x3dShape {
+	var shapes []*x3dShape
+	byMaterial := map[int32]*x3dShape{}
+	for _, face := range obj.Faces {
+		if len(face.Vertices) < 3 {
+			continue
+		}
+		s, ok := byMaterial[face.MaterialIndex]
+		if !ok {
+			s = &x3dShape{material: face.MaterialIndex}
+			byMaterial[face.MaterialIndex] = s
+			shapes = append(shapes, s)
+		}
+		for _, fv := range face.Vertices {
+			s.coordIdx = append(s.coordIdx, fv.IDVertex)
+			// X3D texture coordinates start at the bottom row, PDO's at the top.
+			s.texCoords = append(s.texCoords, fv.U, 1-fv.V)
+		}
+		s.coordIdx = append(s.coordIdx, -1)
+	}
+	return shapes
+}
+
+// texCoordIndex numbers the corners of a shape's faces in order.
+func (s *x3dShape) texCoordIndex() []int32 {
+	idx := make([]int32, len(s.coordIdx))
+	n := int32(0)
+	for i, v := range s.coordIdx {
+		if v < 0 {
+			idx[i] = -1
+			continue
+		}
+		idx[i] = n
+		n++
+	}
+	return idx
+}
+
+// x3dScene is the encoding-independent content of an X3D or VRML file.
+type x3dScene struct {
+	p        *pdo.PDO
+	names    *nameMapper
+	textures map[int32]string // Texture file of each textured material
+}
+
+// newX3DScene writes the textures next to path, when there is one and the
+// texture lock allows it.
+func newX3DScene(p *pdo.PDO, path string, opts Options) *x3dScene {
+	s := &x3dScene{p: p, names: newNameMapper(), textures: map[int32]string{}}
+	if opts.texturesLocked(p) {
+		return s
+	}
+	for i := range p.Materials {
+		mat := &p.Materials[i]
+		if !mat.HasTexture {
+			continue
+		}
+		if path == "" {
+			opts.logger().Warn("textures need an output path, leaving them out", "material", mat.Name)
+			continue
+		}
+		name := fmt.Sprintf("%s_tex%d.png", strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), i)
+		if writeTexturePNG(mat, mat.Name, filepath.Join(filepath.Dir(path), name), opts.logger()) {
+			s.textures[int32(i)] = name
+		}
+	}
+	return s
+}
+
+// diffuse returns the 3D view color of a material, the OBJ diffuse color,
+// light gray without a material.
+func (s *x3dScene) diffuse(m int32) string {
+	if m < 0 || int(m) >= len(s.p.Materials) {
+		return "0.8 0.8 0.8"
+	}
+	c := s.p.Materials[m].Color3D.Model
+	return fmt.Sprintf("%s %s %s", x3dNum(float64(c.R)), x3dNum(float64(c.G)), x3dNum(float64(c.B)))
+}
+
+// defName returns the unique DEF name of an object. Names can't start
+// with a digit.
+func (s *x3dScene) defName(i int, obj pdo.Object) string {
+	name := fmt.Sprintf("%s_%d", s.names.safe(obj.Name), i)
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// ExportX3D writes the assembled model as X3D (XML encoding). Textures are
+// written as PNG files next to x3dPath and referenced by name.
+func ExportX3D(p *pdo.PDO, w io.Writer, x3dPath string, opts Options) error {
+	s := newX3DScene(p, x3dPath, opts)
+	place := opts.placement(p, true)
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<!DOCTYPE X3D PUBLIC "ISO//Web3D//DTD X3D 3.3//EN" "http://www.web3d.org/specifications/x3d-3.3.dtd">`)
+	fmt.Fprintln(w, `<X3D profile="Interchange" version="3.3">`)
+	fmt.Fprint(w, `<head><meta name="generator" content="pdo-tools"/>`)
+	writeXMLMetadata(w, "<meta name=\"%s\" content=\"%s\"/>", opts.metadata(p))
+	fmt.Fprintln(w, `</head>`)
+	fmt.Fprintln(w, `<Scene>`)
+	for i, obj := range p.Objects {
+		def := s.defName(i, obj)
+		fmt.Fprintf(w, "<Group DEF=\"%s\">\n", xmlEscape(def))
+		for n, shape := range x3dShapes(obj) {
+			fmt.Fprintln(w, "<Shape>")
+			fmt.Fprintf(w, "<Appearance><Material diffuseColor=\"%s\"/>", s.diffuse(shape.material))
+			if tex, ok := s.textures[shape.material]; ok {
+				fmt.Fprintf(w, "<ImageTexture url='\"%s\"'/>", xmlEscape(tex))
+			}
+			fmt.Fprintln(w, "</Appearance>")
+			_, textured := s.textures[shape.material]
+			fmt.Fprintf(w, "<IndexedFaceSet solid=\"false\" coordIndex=\"%s\"", x3dInts(shape.coordIdx))
+			if textured {
+				fmt.Fprintf(w, " texCoordIndex=\"%s\"", x3dInts(shape.texCoordIndex()))
+			}
+			fmt.Fprintln(w, ">")
+			// The first shape holds the object's vertices, the others share them.
+			if n == 0 {
+				fmt.Fprintf(w, "<Coordinate DEF=\"%s_coords\" point=\"%s\"/>\n", xmlEscape(def), x3dPoints(place.vertices(obj)))
+			} else {
+				fmt.Fprintf(w, "<Coordinate USE=\"%s_coords\"/>\n", xmlEscape(def))
+			}
+			if textured {
+				fmt.Fprintf(w, "<TextureCoordinate point=\"%s\"/>\n", x3dFloats(shape.texCoords))
+			}
+			fmt.Fprintln(w, "</IndexedFaceSet>")
+			fmt.Fprintln(w, "</Shape>")
+		}
+		fmt.Fprintln(w, "</Group>")
+	}
+	fmt.Fprintln(w, `</Scene>`)
+	_, err := fmt.Fprintln(w, `</X3D>`)
+	return err
+}
+
+// ExportVRML writes the assembled model as VRML97, the classic encoding
+// of the same scene as ExportX3D.
+func ExportVRML(p *pdo.PDO, w io.Writer, wrlPath string, opts Options) error {
+	s := newX3DScene(p, wrlPath, opts)
+	place := opts.placement(p, true)
+	fmt.Fprintln(w, "#VRML V2.0 utf8")
+	fmt.Fprintln(w, "# Exported by pdo-tools")
+	writeMetadataComments(w, "# ", opts.metadata(p))
+	for i, obj := range p.Objects {
+		def := s.defName(i, obj)
+		fmt.Fprintf(w, "DEF %s Group {\n  children [\n", def)
+		for n, shape := range x3dShapes(obj) {
+			fmt.Fprintln(w, "    Shape {")
+			fmt.Fprintf(w, "      appearance Appearance {\n        material Material { diffuseColor %s }\n", s.diffuse(shape.material))
+			tex, textured := s.textures[shape.material]
+			if textured {
+				fmt.Fprintf(w, "        texture ImageTexture { url %s }\n", strconv.Quote(tex))
+			}
+			fmt.Fprintln(w, "      }")
+			fmt.Fprintln(w, "      geometry IndexedFaceSet {")
+			fmt.Fprintln(w, "        solid FALSE")
+			if n == 0 {
+				fmt.Fprintf(w, "        coord DEF %s_coords Coordinate { point [ %s ] }\n", def, x3dPoints(place.vertices(obj)))
+			} else {
+				fmt.Fprintf(w, "        coord USE %s_coords\n", def)
+			}
+			fmt.Fprintf(w, "        coordIndex [ %s ]\n", x3dInts(shape.coordIdx))
+			if textured {
+				fmt.Fprintf(w, "        texCoord TextureCoordinate { point [ %s ] }\n", x3dFloats(shape.texCoords))
+				fmt.Fprintf(w, "        texCoordIndex [ %s ]\n", x3dInts(shape.texCoordIndex()))
+			}
+			fmt.Fprintln(w, "      }")
+			fmt.Fprintln(w, "    }")
+		}
+		fmt.Fprintln(w, "  ]\n}")
+	}
+	return nil
+}
+
+func x3dNum(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 32)
+}
+
+func x3dInts(v []int32) string {
+	var b strings.Builder
+	for i, n := range v {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Itoa(int(n)))
+	}
+	return b.String()
+}
+
+// x3dFloats writes pairs of numbers separated by commas.
+func x3dFloats(v []float64) string {
+	var b strings.Builder
+	for i, f := range v {
+		switch {
+		case i == 0:
+		case i%2 == 0:
+			b.WriteString(", ")
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(x3dNum(f))
+	}
+	return b.String()
+}
+
+func x3dPoints(vs []pdo.Vertex3D) string {
+	var b strings.Builder
+	for i, v := range vs {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s %s %s", x3dNum(v.X), x3dNum(v.Y), x3dNum(v.Z))
+	}
+	return b.String()
+}
+
+type x3dExporter struct{}
+
+func (x3dExporter) Name() string         { return "x3d" }
+func (x3dExporter) Extensions() []string { return []string{".x3d"} }
+func (x3dExporter) Mesh() bool           { return true }
+func (x3dExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ExportX3D(p, t.W, t.Path, opts)
+}
+
+type vrmlExporter struct{}
+
+func (vrmlExporter) Name() string         { return "vrml" }
+func (vrmlExporter) Extensions() []string { return []string{".wrl"} }
+func (vrmlExporter) Mesh() bool           { return true }
+func (vrmlExporter) Export(ctx context.Context, p *pdo.PDO, t Target, opts Options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ExportVRML(p, t.W, t.Path, opts)
+}
+
+func init() {
+	Register(x3dExporter{})
+	Register(vrmlExporter{})
+}
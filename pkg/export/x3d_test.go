@@ -0,0 +1,86 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestExportX3D(t *testing.T) {
+	p := texturedCone(t)
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := ExportX3D(p, &buf, filepath.Join(dir, "cone.x3d"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Well-formed, with the texture referenced and written.
+	dec := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	var shapes, textures int
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			switch el.Name.Local {
+			case "Shape":
+				shapes++
+			case "ImageTexture":
+				textures++
+			}
+		}
+	}
+	if shapes != 1 || textures != 1 {
+		t.Errorf("got %d shapes and %d textures, want 1 each", shapes, textures)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cone_tex0.png")); err != nil {
+		t.Error(err)
+	}
+
+	buf.Reset()
+	if err := ExportVRML(p, &buf, filepath.Join(dir, "cone.wrl"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "#VRML V2.0 utf8\n") || !strings.Contains(out, `texture ImageTexture { url "cone_tex0.png" }`) {
+		t.Errorf("unexpected VRML output:\n%.400s", out)
+	}
+	if strings.Count(out, "{") != strings.Count(out, "}") || strings.Count(out, "[") != strings.Count(out, "]") {
+		t.Error("unbalanced brackets")
+	}
+}
+
+func TestExportX3D_TextureLock(t *testing.T) {
+	p := texturedCone(t)
+	p.Header.TexLock = pdo.TexLockDeny
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := ExportX3D(p, &buf, filepath.Join(dir, "cone.x3d"), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "ImageTexture") {
+		t.Error("locked texture referenced")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cone_tex0.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("locked texture written: %v", err)
+	}
+
+	buf.Reset()
+	if err := ExportX3D(p, &buf, filepath.Join(dir, "cone.x3d"), Options{IgnoreTextureLock: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cone_tex0.png")); err != nil {
+		t.Errorf("texture not written with IgnoreTextureLock: %v", err)
+	}
+}
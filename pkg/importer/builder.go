@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"errors"
+	"image"
+	"log/slog"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// ErrNoGeometry is returned for models without any faces.
+var ErrNoGeometry = errors.New("no faces in model")
+
+// builder assembles a PDO from the meshes of an imported model.
+type builder struct {
+	p   *pdo.PDO
+	log *slog.Logger
+}
+
+func newBuilder(log *slog.Logger) *builder {
+	return &builder{p: pdo.New(), log: log}
+}
+
+// material adds a material with a color and an optional texture and
+// returns its index.
+func (b *builder) material(name string, rgba [4]float32, tex image.Image) int32 {
+	c := pdo.RGBA{R: rgba[0], G: rgba[1], B: rgba[2], A: rgba[3]}
+	mat := pdo.Material{
+		Name:        name,
+		Color2DRGBA: rgba,
+		Color3D:     pdo.Color3D{Material: c, Model: c, Light: pdo.RGBA{R: 1, G: 1, B: 1, A: 1}, Diffuse: c},
+	}
+	if tex != nil {
+		if err := mat.Texture.SetImage(tex); err != nil {
+			b.log.Warn("failed to store texture", "material", name, "err", err)
+		} else {
+			mat.HasTexture = true
+		}
+	}
+	b.p.Materials = append(b.p.Materials, mat)
+	return int32(len(b.p.Materials) - 1)
+}
+
+// meshBuilder adds faces to one object, merging vertices at the same
+// position so faces share edges.
+type meshBuilder struct {
+	p     *pdo.PDO
+	index int // Object index, the objects slice grows
+	weld  map[pdo.Vertex3D]int32
+}
+
+func (b *builder) object(name string) *meshBuilder {
+	b.p.Objects = append(b.p.Objects, pdo.Object{Name: name, Visible: 1})
+	return &meshBuilder{p: b.p, index: len(b.p.Objects) - 1, weld: map[pdo.Vertex3D]int32{}}
+}
+
+// face adds a polygon, counter-clockwise seen from outside, with texture
+// coordinates per corner in PDO orientation (V runs down the image).
+// Corners merged into the same vertex are dropped, faces left with less
+// than three corners are skipped.
+func (m *meshBuilder) face(pos []pdo.Vertex3D, uv [][2]float64, material int32) {
+	obj := &m.p.Objects[m.index]
+	f := pdo.Face{MaterialIndex: material, PartIndex: -1}
+	for i, p := range pos {
+		id, ok := m.weld[p]
+		if !ok {
+			id = int32(len(obj.Vertices))
+			m.weld[p] = id
+			obj.Vertices = append(obj.Vertices, p)
+		}
+		if n := len(f.Vertices); n > 0 && (f.Vertices[n-1].IDVertex == id || f.Vertices[0].IDVertex == id) {
+			continue
+		}
+		fv := pdo.Face2DVertex{IDVertex: id}
+		if i < len(uv) {
+			fv.U, fv.V = uv[i][0], uv[i][1]
+		}
+		f.Vertices = append(f.Vertices, fv)
+	}
+	if len(f.Vertices) >= 3 {
+		obj.Faces = append(obj.Faces, f)
+	}
+}
+
+// finish computes face normals, edges and the model bounds. Objects
+// without faces are dropped.
+func (b *builder) finish() (*pdo.PDO, error) {
+	objects := b.p.Objects[:0]
+	for _, obj := range b.p.Objects {
+		if len(obj.Faces) == 0 {
+			continue
+		}
+		obj.UpdateFaceNormals()
+		obj.BuildEdges()
+		objects = append(objects, obj)
+	}
+	b.p.Objects = objects
+	if len(objects) == 0 {
+		return nil, ErrNoGeometry
+	}
+	b.p.UpdateBounds()
+	return b.p, nil
+}
@@ -0,0 +1,490 @@
+package importer
+
+import (
+	"cmp"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// ErrBadCollada is returned for Collada files that can't be read.
+var ErrBadCollada = errors.New("invalid Collada")
+
+type daeDoc struct {
+	Asset struct {
+		Unit struct {
+			Meter string `xml:"meter,attr"`
+		} `xml:"unit"`
+		UpAxis string `xml:"up_axis"`
+	} `xml:"asset"`
+	Images     []daeImage     `xml:"library_images>image"`
+	Effects    []daeEffect    `xml:"library_effects>effect"`
+	Materials  []daeMaterial  `xml:"library_materials>material"`
+	Geometries []daeGeometry  `xml:"library_geometries>geometry"`
+	Scenes     []daeVisScene  `xml:"library_visual_scenes>visual_scene"`
+	Scene      daeInstanceURL `xml:"scene>instance_visual_scene"`
+}
+
+type daeInstanceURL struct {
+	URL string `xml:"url,attr"`
+}
+
+type daeImage struct {
+	ID       string `xml:"id,attr"`
+	InitFrom struct {
+		Path string `xml:",chardata"`
+		Ref  string `xml:"ref"` // Collada 1.5
+	} `xml:"init_from"`
+}
+
+type daeEffect struct {
+	ID     string `xml:"id,attr"`
+	Params []struct {
+		SID     string `xml:"sid,attr"`
+		Surface *struct {
+			InitFrom string `xml:"init_from"`
+		} `xml:"surface"`
+		Sampler *struct {
+			Source string         `xml:"source"`
+			Image  daeInstanceURL `xml:"instance_image"` // Collada 1.5
+		} `xml:"sampler2D"`
+	} `xml:"profile_COMMON>newparam"`
+	Technique struct {
+		Shaders []struct {
+			XMLName xml.Name
+			Diffuse *struct {
+				Color   string `xml:"color"`
+				Texture *struct {
+					Texture string `xml:"texture,attr"`
+				} `xml:"texture"`
+			} `xml:"diffuse"`
+		} `xml:",any"`
+	} `xml:"profile_COMMON>technique"`
+}
+
+type daeMaterial struct {
+	ID     string         `xml:"id,attr"`
+	Name   string         `xml:"name,attr"`
+	Effect daeInstanceURL `xml:"instance_effect"`
+}
+
+type daeInput struct {
+	Semantic string `xml:"semantic,attr"`
+	Source   string `xml:"source,attr"`
+	Offset   int    `xml:"offset,attr"`
+	Set      int    `xml:"set,attr"`
+}
+
+type daePrimitive struct {
+	XMLName  xml.Name
+	Material string     `xml:"material,attr"`
+	Count    int        `xml:"count,attr"`
+	Inputs   []daeInput `xml:"input"`
+	VCount   string     `xml:"vcount"`
+	P        []string   `xml:"p"`
+}
+
+type daeGeometry struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+	Mesh *struct {
+		Sources []struct {
+			ID       string `xml:"id,attr"`
+			Floats   string `xml:"float_array"`
+			Accessor struct {
+				Stride int `xml:"stride,attr"`
+			} `xml:"technique_common>accessor"`
+		} `xml:"source"`
+		Vertices struct {
+			ID     string     `xml:"id,attr"`
+			Inputs []daeInput `xml:"input"`
+		} `xml:"vertices"`
+		Primitives []daePrimitive `xml:",any"`
+	} `xml:"mesh"`
+}
+
+type daeNode struct {
+	Name      string `xml:"name,attr"`
+	ID        string `xml:"id,attr"`
+	Instances []struct {
+		URL      string `xml:"url,attr"`
+		Bindings []struct {
+			Symbol string `xml:"symbol,attr"`
+			Target string `xml:"target,attr"`
+		} `xml:"bind_material>technique_common>instance_material"`
+	} `xml:"instance_geometry"`
+	Children []daeNode `xml:"node"`
+	// Transforms in document order; other elements are ignored.
+	Transforms []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+type daeVisScene struct {
+	ID    string    `xml:"id,attr"`
+	Nodes []daeNode `xml:"node"`
+}
+
+// daeReader builds the PDO from a parsed Collada document.
+type daeReader struct {
+	doc       daeDoc
+	dir       string
+	b         *builder
+	materials map[string]int32 // Material id to PDO material
+}
+
+// ImportCollada reads a Collada (DAE) 1.4 or 1.5 model. Geometry
+// instanced in the visual scene becomes objects in mm with Y up;
+// materials keep their diffuse color or texture.
+func ImportCollada(src Source, opts Options) (*pdo.PDO, error) {
+	data, err := io.ReadAll(src.R)
+	if err != nil {
+		return nil, err
+	}
+	r := &daeReader{b: newBuilder(opts.logger()), materials: map[string]int32{}}
+	if err := xml.Unmarshal(data, &r.doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadCollada, err)
+	}
+	if src.Path != "" {
+		r.dir = filepath.Dir(src.Path)
+	}
+
+	meter := 1.0
+	if v, err := strconv.ParseFloat(strings.TrimSpace(r.doc.Asset.Unit.Meter), 64); err == nil && v > 0 {
+		meter = v
+	}
+	root := scaling(meter*1000, meter*1000, meter*1000)
+	switch strings.TrimSpace(r.doc.Asset.UpAxis) {
+	case "Z_UP":
+		root = mat4{1, 0, 0, 0, 0, 0, -1, 0, 0, 1, 0, 0, 0, 0, 0, 1}.mul(root)
+	case "X_UP":
+		root = mat4{0, 1, 0, 0, -1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}.mul(root)
+	}
+
+	var scene *daeVisScene
+	for i := range r.doc.Scenes {
+		if scene == nil || "#"+r.doc.Scenes[i].ID == r.doc.Scene.URL {
+			scene = &r.doc.Scenes[i]
+		}
+	}
+	if scene == nil {
+		// No scene, show every geometry once.
+		for i := range r.doc.Geometries {
+			if err := r.geometry(&r.doc.Geometries[i], r.doc.Geometries[i].Name, root, nil); err != nil {
+				return nil, err
+			}
+		}
+		return r.b.finish()
+	}
+	for i := range scene.Nodes {
+		if err := r.node(&scene.Nodes[i], root, 0); err != nil {
+			return nil, err
+		}
+	}
+	return r.b.finish()
+}
+
+func (r *daeReader) node(n *daeNode, parent mat4, depth int) error {
+	if depth > 64 {
+		return fmt.Errorf("%w: nodes nested too deep", ErrBadCollada)
+	}
+	m := parent
+	for _, t := range n.Transforms {
+		v := parseFloats(t.Value)
+		switch {
+		case t.XMLName.Local == "matrix" && len(v) == 16:
+			var c mat4 // Collada matrices are row-major
+			for row := range 4 {
+				for col := range 4 {
+					c[col*4+row] = v[row*4+col]
+				}
+			}
+			m = m.mul(c)
+		case t.XMLName.Local == "translate" && len(v) == 3:
+			m = m.mul(translation(v[0], v[1], v[2]))
+		case t.XMLName.Local == "rotate" && len(v) == 4:
+			m = m.mul(axisAngle(v[0], v[1], v[2], v[3]))
+		case t.XMLName.Local == "scale" && len(v) == 3:
+			m = m.mul(scaling(v[0], v[1], v[2]))
+		}
+	}
+
+	for _, inst := range n.Instances {
+		g := r.find(inst.URL)
+		if g == nil {
+			r.b.log.Warn("skipping missing geometry", "url", inst.URL)
+			continue
+		}
+		bind := map[string]string{}
+		for _, b := range inst.Bindings {
+			bind[b.Symbol] = strings.TrimPrefix(b.Target, "#")
+		}
+		name := n.Name
+		if name == "" {
+			name = cmp.Or(n.ID, g.Name)
+		}
+		if err := r.geometry(g, name, m, bind); err != nil {
+			return err
+		}
+	}
+	for i := range n.Children {
+		if err := r.node(&n.Children[i], m, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *daeReader) find(url string) *daeGeometry {
+	for i := range r.doc.Geometries {
+		if "#"+r.doc.Geometries[i].ID == url {
+			return &r.doc.Geometries[i]
+		}
+	}
+	return nil
+}
+
+// geometry adds an instance of a mesh as an object. bind maps material
+// symbols of the primitives to material ids.
+func (r *daeReader) geometry(g *daeGeometry, name string, m mat4, bind map[string]string) error {
+	if g.Mesh == nil {
+		return nil // Splines and convex meshes have no faces
+	}
+	sources := map[string][]float64{}
+	strides := map[string]int{}
+	for _, s := range g.Mesh.Sources {
+		sources[s.ID] = parseFloats(s.Floats)
+		strides[s.ID] = max(s.Accessor.Stride, 1)
+	}
+	// Positions live behind the vertices element.
+	posSource := ""
+	for _, in := range g.Mesh.Vertices.Inputs {
+		if in.Semantic == "POSITION" {
+			posSource = strings.TrimPrefix(in.Source, "#")
+		}
+	}
+	obj := r.b.object(name)
+	flip := m.mirrors()
+
+	for _, prim := range g.Mesh.Primitives {
+		kind := prim.XMLName.Local
+		if kind != "triangles" && kind != "polylist" && kind != "polygons" {
+			if kind == "lines" || kind == "linestrips" || kind == "trifans" || kind == "tristrips" {
+				r.b.log.Warn("skipping unsupported primitive", "geometry", g.ID, "type", kind)
+			}
+			continue
+		}
+		stride, vOff, tOff, tSet := 0, -1, -1, 0
+		tSource := ""
+		for _, in := range prim.Inputs {
+			stride = max(stride, in.Offset+1)
+			switch in.Semantic {
+			case "VERTEX":
+				vOff = in.Offset
+			case "TEXCOORD":
+				if tOff < 0 || in.Set < tSet {
+					tOff, tSet, tSource = in.Offset, in.Set, strings.TrimPrefix(in.Source, "#")
+				}
+			}
+		}
+		if vOff < 0 {
+			continue
+		}
+		mat := r.material(cmp.Or(bind[prim.Material], prim.Material))
+
+		// Corner counts of the polygons, one list of indices per p element.
+		var polys [][]int
+		var counts []int
+		switch kind {
+		case "triangles":
+			for range prim.Count {
+				counts = append(counts, 3)
+			}
+		case "polylist":
+			for _, c := range parseFloats(prim.VCount) {
+				counts = append(counts, int(c))
+			}
+		}
+		for _, p := range prim.P {
+			idx := parseInts(p)
+			if kind == "polygons" {
+				polys = append(polys, idx)
+				continue
+			}
+			at := 0
+			for _, c := range counts {
+				if at+c*stride > len(idx) {
+					return fmt.Errorf("%w: geometry %s has too few indices", ErrBadCollada, g.ID)
+				}
+				polys = append(polys, idx[at:at+c*stride])
+				at += c * stride
+			}
+		}
+
+		pos, ps := sources[posSource], strides[posSource]
+		uv, us := sources[tSource], strides[tSource]
+		for _, poly := range polys {
+			n := len(poly) / stride
+			corners := make([]pdo.Vertex3D, n)
+			uvs := make([][2]float64, n)
+			for k := range n {
+				c := k
+				if flip {
+					c = n - 1 - k
+				}
+				vi := poly[c*stride+vOff]
+				if vi < 0 || vi*ps+2 >= len(pos) {
+					return fmt.Errorf("%w: vertex index %d out of range in %s", ErrBadCollada, vi, g.ID)
+				}
+				corners[k] = m.apply(pos[vi*ps], pos[vi*ps+1], pos[vi*ps+2])
+				if tOff >= 0 {
+					if ti := poly[c*stride+tOff]; ti >= 0 && ti*us+1 < len(uv) {
+						uvs[k] = [2]float64{uv[ti*us], 1 - uv[ti*us+1]} // t runs up the image
+					}
+				}
+			}
+			obj.face(corners, uvs, mat)
+		}
+	}
+	return nil
+}
+
+// material returns the PDO material of a Collada material, adding it on
+// first use.
+func (r *daeReader) material(id string) int32 {
+	if id == "" {
+		return -1
+	}
+	if m, ok := r.materials[id]; ok {
+		return m
+	}
+	var mat *daeMaterial
+	for i := range r.doc.Materials {
+		if r.doc.Materials[i].ID == id {
+			mat = &r.doc.Materials[i]
+		}
+	}
+	if mat == nil {
+		r.b.log.Warn("missing material", "id", id)
+		r.materials[id] = -1
+		return -1
+	}
+
+	rgba := [4]float32{1, 1, 1, 1}
+	var tex image.Image
+	for _, eff := range r.doc.Effects {
+		if "#"+eff.ID != mat.Effect.URL {
+			continue
+		}
+		for _, sh := range eff.Technique.Shaders {
+			if sh.Diffuse == nil {
+				continue
+			}
+			for i, v := range parseFloats(sh.Diffuse.Color) {
+				if i < 4 {
+					rgba[i] = float32(v)
+				}
+			}
+			if t := sh.Diffuse.Texture; t != nil {
+				var err error
+				if tex, err = r.texture(&eff, t.Texture); err != nil {
+					r.b.log.Warn("failed to read texture", "material", id, "err", err)
+				}
+			}
+		}
+	}
+	r.materials[id] = r.b.material(cmp.Or(mat.Name, id), rgba, tex)
+	return r.materials[id]
+}
+
+// texture follows a sampler through its surface to the image file.
+func (r *daeReader) texture(eff *daeEffect, sampler string) (image.Image, error) {
+	imageID := sampler // Some exporters name the image directly
+	for _, p := range eff.Params {
+		if p.SID != sampler || p.Sampler == nil {
+			continue
+		}
+		if p.Sampler.Image.URL != "" {
+			imageID = strings.TrimPrefix(p.Sampler.Image.URL, "#")
+			break
+		}
+		for _, s := range eff.Params {
+			if s.SID == p.Sampler.Source && s.Surface != nil {
+				imageID = strings.TrimSpace(s.Surface.InitFrom)
+			}
+		}
+	}
+	for _, img := range r.doc.Images {
+		if img.ID != imageID {
+			continue
+		}
+		path := strings.TrimSpace(cmp.Or(strings.TrimSpace(img.InitFrom.Ref), img.InitFrom.Path))
+		path = strings.TrimPrefix(path, "file://")
+		if p, err := url.PathUnescape(path); err == nil {
+			path = p
+		}
+		if !filepath.IsAbs(path) {
+			if r.dir == "" {
+				return nil, fmt.Errorf("external texture %q needs the model path", path)
+			}
+			path = filepath.Join(r.dir, filepath.FromSlash(path))
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		decoded, _, err := image.Decode(f)
+		return decoded, err
+	}
+	return nil, fmt.Errorf("image %q not found", imageID)
+}
+
+func parseFloats(s string) []float64 {
+	fields := strings.Fields(s)
+	out := make([]float64, 0, len(fields))
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			v = 0
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func parseInts(s string) []int {
+	fields := strings.Fields(s)
+	out := make([]int, 0, len(fields))
+	for _, f := range fields {
+		v, _ := strconv.Atoi(f)
+		out = append(out, v)
+	}
+	return out
+}
+
+type colladaImporter struct{}
+
+func (colladaImporter) Name() string         { return "dae" }
+func (colladaImporter) Extensions() []string { return []string{".dae"} }
+func (colladaImporter) Import(ctx context.Context, src Source, opts Options) (*pdo.PDO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ImportCollada(src, opts)
+}
+
+func init() {
+	Register(colladaImporter{})
+}
@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pyramidDAE is a square pyramid in cm with Z up, its base a polylist quad.
+const pyramidDAE = `<?xml version="1.0" encoding="utf-8"?>
+<COLLADA xmlns="http://www.collada.org/2005/11/COLLADASchema" version="1.4.1">
+  <asset><unit name="centimeter" meter="0.01"/><up_axis>Z_UP</up_axis></asset>
+  <library_images><image id="paper-img"><init_from>paper%20tex.png</init_from></image></library_images>
+  <library_effects>
+    <effect id="paper-fx"><profile_COMMON>
+      <newparam sid="surf"><surface type="2D"><init_from>paper-img</init_from></surface></newparam>
+      <newparam sid="samp"><sampler2D><source>surf</source></sampler2D></newparam>
+      <technique sid="common"><lambert><diffuse><texture texture="samp" texcoord="UV"/></diffuse></lambert></technique>
+    </profile_COMMON></effect>
+    <effect id="red-fx"><profile_COMMON>
+      <technique sid="common"><phong><diffuse><color>1 0 0 1</color></diffuse></phong></technique>
+    </profile_COMMON></effect>
+  </library_effects>
+  <library_materials>
+    <material id="paper" name="Paper"><instance_effect url="#paper-fx"/></material>
+    <material id="red" name="Red"><instance_effect url="#red-fx"/></material>
+  </library_materials>
+  <library_geometries>
+    <geometry id="pyr" name="Pyramid"><mesh>
+      <source id="pyr-pos"><float_array id="pyr-pos-a" count="15">0 0 0 2 0 0 2 2 0 0 2 0 1 1 1</float_array>
+        <technique_common><accessor source="#pyr-pos-a" count="5" stride="3"/></technique_common></source>
+      <source id="pyr-uv"><float_array id="pyr-uv-a" count="4">0 0 1 1</float_array>
+        <technique_common><accessor source="#pyr-uv-a" count="2" stride="2"/></technique_common></source>
+      <vertices id="pyr-v"><input semantic="POSITION" source="#pyr-pos"/></vertices>
+      <polylist material="base" count="1">
+        <input semantic="VERTEX" source="#pyr-v" offset="0"/>
+        <input semantic="TEXCOORD" source="#pyr-uv" offset="1" set="0"/>
+        <vcount>4</vcount><p>0 0 3 1 2 1 1 0</p>
+      </polylist>
+      <triangles material="sides" count="4">
+        <input semantic="VERTEX" source="#pyr-v" offset="0"/>
+        <p>0 1 4 1 2 4 2 3 4 3 0 4</p>
+      </triangles>
+    </mesh></geometry>
+  </library_geometries>
+  <library_visual_scenes>
+    <visual_scene id="scene"><node name="Pyramid">
+      <translate>0 0 1</translate>
+      <instance_geometry url="#pyr"><bind_material><technique_common>
+        <instance_material symbol="base" target="#paper"/>
+        <instance_material symbol="sides" target="#red"/>
+      </technique_common></bind_material></instance_geometry>
+    </node></visual_scene>
+  </library_visual_scenes>
+  <scene><instance_visual_scene url="#scene"/></scene>
+</COLLADA>`
+
+func TestImportCollada(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.RGBA{0, 0, 255, 255})
+	f, err := os.Create(filepath.Join(dir, "paper tex.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, img)
+	f.Close()
+
+	p, err := ImportCollada(Source{R: strings.NewReader(pyramidDAE), Path: filepath.Join(dir, "pyramid.dae")}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := p.Objects[0]
+	if obj.Name != "Pyramid" || len(obj.Vertices) != 5 || len(obj.Faces) != 5 || len(obj.Edges) != 8 {
+		t.Fatalf("got %q with %d vertices, %d faces, %d edges; want Pyramid, 5, 5, 8",
+			obj.Name, len(obj.Vertices), len(obj.Faces), len(obj.Edges))
+	}
+	// Z up in cm becomes Y up in mm: the apex (1, 1, 1)+(0, 0, 1) is at (10, 20, -10).
+	apex := obj.Vertices[4]
+	if math.Abs(apex.X-10) > 1e-9 || math.Abs(apex.Y-20) > 1e-9 || math.Abs(apex.Z+10) > 1e-9 {
+		t.Errorf("apex = %+v, want (10, 20, -10)", apex)
+	}
+	// The base faces down, the sides up.
+	if obj.Faces[0].Ny > -0.99 {
+		t.Errorf("base normal Y = %g, want -1", obj.Faces[0].Ny)
+	}
+	for _, f := range obj.Faces[1:] {
+		if f.Ny <= 0 {
+			t.Errorf("side normal Y = %g, want up", f.Ny)
+		}
+	}
+	// V is flipped from Collada's t.
+	if v := obj.Faces[0].Vertices[2]; v.U != 1 || v.V != 0 {
+		t.Errorf("corner 2 uv = (%g, %g), want (1, 0)", v.U, v.V)
+	}
+
+	if len(p.Materials) != 2 {
+		t.Fatalf("got %d materials, want 2", len(p.Materials))
+	}
+	if m := p.Materials[obj.Faces[0].MaterialIndex]; m.Name != "Paper" || !m.HasTexture {
+		t.Errorf("base material %q, textured %v", m.Name, m.HasTexture)
+	}
+	if m := p.Materials[obj.Faces[1].MaterialIndex]; m.Name != "Red" || m.HasTexture || m.Color2DRGBA != [4]float32{1, 0, 0, 1} {
+		t.Errorf("side material %q: %v", m.Name, m.Color2DRGBA)
+	}
+}
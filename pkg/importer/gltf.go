@@ -0,0 +1,530 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Texture formats of glTF
+	_ "image/png"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// ErrBadGLTF is returned for glTF files that can't be read.
+var ErrBadGLTF = errors.New("invalid glTF")
+
+// glTF meters become mm, the unit of PDO models built from scratch.
+const gltfScale = 1000
+
+type gltfDoc struct {
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes []struct {
+		Name        string    `json:"name"`
+		Mesh        *int      `json:"mesh"`
+		Children    []int     `json:"children"`
+		Matrix      []float64 `json:"matrix"`
+		Translation []float64 `json:"translation"`
+		Rotation    []float64 `json:"rotation"`
+		Scale       []float64 `json:"scale"`
+	} `json:"nodes"`
+	Meshes []struct {
+		Name       string `json:"name"`
+		Primitives []struct {
+			Attributes map[string]int `json:"attributes"`
+			Indices    *int           `json:"indices"`
+			Material   *int           `json:"material"`
+			Mode       *int           `json:"mode"`
+		} `json:"primitives"`
+	} `json:"meshes"`
+	Accessors []struct {
+		BufferView    *int   `json:"bufferView"`
+		ByteOffset    int    `json:"byteOffset"`
+		ComponentType int    `json:"componentType"`
+		Normalized    bool   `json:"normalized"`
+		Count         int    `json:"count"`
+		Type          string `json:"type"`
+	} `json:"accessors"`
+	BufferViews []struct {
+		Buffer     int `json:"buffer"`
+		ByteOffset int `json:"byteOffset"`
+		ByteLength int `json:"byteLength"`
+		ByteStride int `json:"byteStride"`
+	} `json:"bufferViews"`
+	Buffers []struct {
+		URI        string `json:"uri"`
+		ByteLength int    `json:"byteLength"`
+	} `json:"buffers"`
+	Materials []struct {
+		Name string `json:"name"`
+		PBR  struct {
+			BaseColorFactor  []float64 `json:"baseColorFactor"`
+			BaseColorTexture *struct {
+				Index    int `json:"index"`
+				TexCoord int `json:"texCoord"`
+			} `json:"baseColorTexture"`
+		} `json:"pbrMetallicRoughness"`
+	} `json:"materials"`
+	Textures []struct {
+		Source *int `json:"source"`
+	} `json:"textures"`
+	Images []struct {
+		URI        string `json:"uri"`
+		BufferView *int   `json:"bufferView"`
+	} `json:"images"`
+}
+
+// gltfReader resolves the data of a glTF document.
+type gltfReader struct {
+	doc     gltfDoc
+	dir     string // Directory of the file, for external buffers and images
+	bin     []byte // Binary chunk of a GLB file
+	buffers map[int][]byte
+}
+
+// ImportGLTF reads a glTF 2.0 model, either JSON with embedded or external
+// buffers or binary GLB. Every mesh instance in the default scene becomes
+// an object, materials keep their base color and texture.
+func ImportGLTF(src Source, opts Options) (*pdo.PDO, error) {
+	data, err := io.ReadAll(src.R)
+	if err != nil {
+		return nil, err
+	}
+	r := &gltfReader{buffers: map[int][]byte{}}
+	if src.Path != "" {
+		r.dir = filepath.Dir(src.Path)
+	}
+	if bytes.HasPrefix(data, []byte("glTF")) {
+		if data, r.bin, err = splitGLB(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(data, &r.doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadGLTF, err)
+	}
+
+	b := newBuilder(opts.logger())
+	materials := make([]int32, len(r.doc.Materials))
+	for i, m := range r.doc.Materials {
+		rgba := [4]float32{1, 1, 1, 1}
+		for c, v := range m.PBR.BaseColorFactor {
+			if c < 4 {
+				rgba[c] = float32(v)
+			}
+		}
+		var tex image.Image
+		if t := m.PBR.BaseColorTexture; t != nil {
+			if tex, err = r.texture(t.Index); err != nil {
+				opts.logger().Warn("failed to read texture", "material", m.Name, "err", err)
+			}
+		}
+		name := m.Name
+		if name == "" {
+			name = fmt.Sprintf("material%d", i)
+		}
+		materials[i] = b.material(name, rgba, tex)
+	}
+
+	var roots []int
+	if len(r.doc.Scenes) > 0 {
+		scene := 0
+		if r.doc.Scene != nil {
+			scene = *r.doc.Scene
+		}
+		if scene < 0 || scene >= len(r.doc.Scenes) {
+			return nil, fmt.Errorf("%w: scene %d out of range", ErrBadGLTF, scene)
+		}
+		roots = r.doc.Scenes[scene].Nodes
+	} else {
+		for i := range r.doc.Nodes { // No scene, every node is a root
+			roots = append(roots, i)
+		}
+	}
+	root := scaling(gltfScale, gltfScale, gltfScale)
+	for _, n := range roots {
+		if err := r.node(b, n, root, materials, 0); err != nil {
+			return nil, err
+		}
+	}
+	return b.finish()
+}
+
+// node adds the meshes of a node and its children.
+func (r *gltfReader) node(b *builder, i int, parent mat4, materials []int32, depth int) error {
+	if i < 0 || i >= len(r.doc.Nodes) || depth > 64 {
+		return fmt.Errorf("%w: bad node %d", ErrBadGLTF, i)
+	}
+	n := r.doc.Nodes[i]
+	m := parent
+	switch {
+	case len(n.Matrix) == 16:
+		m = m.mul(mat4(n.Matrix))
+	default:
+		if len(n.Translation) == 3 {
+			m = m.mul(translation(n.Translation[0], n.Translation[1], n.Translation[2]))
+		}
+		if len(n.Rotation) == 4 {
+			m = m.mul(quaternion(n.Rotation[0], n.Rotation[1], n.Rotation[2], n.Rotation[3]))
+		}
+		if len(n.Scale) == 3 {
+			m = m.mul(scaling(n.Scale[0], n.Scale[1], n.Scale[2]))
+		}
+	}
+
+	if n.Mesh != nil {
+		if *n.Mesh < 0 || *n.Mesh >= len(r.doc.Meshes) {
+			return fmt.Errorf("%w: mesh %d out of range", ErrBadGLTF, *n.Mesh)
+		}
+		mesh := r.doc.Meshes[*n.Mesh]
+		name := n.Name
+		if name == "" {
+			name = mesh.Name
+		}
+		obj := b.object(name)
+		for _, prim := range mesh.Primitives {
+			if err := r.primitive(b, obj, prim.Attributes, prim.Indices, prim.Material, prim.Mode, m, materials); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range n.Children {
+		if err := r.node(b, c, m, materials, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Primitive modes with faces.
+const (
+	gltfTriangles     = 4
+	gltfTriangleStrip = 5
+	gltfTriangleFan   = 6
+)
+
+func (r *gltfReader) primitive(b *builder, obj *meshBuilder, attrs map[string]int, indices, material, mode *int, m mat4, materials []int32) error {
+	primMode := gltfTriangles
+	if mode != nil {
+		primMode = *mode
+	}
+	if primMode != gltfTriangles && primMode != gltfTriangleStrip && primMode != gltfTriangleFan {
+		b.log.Warn("skipping points and lines", "mode", primMode)
+		return nil
+	}
+	posAcc, ok := attrs["POSITION"]
+	if !ok {
+		return nil
+	}
+	pos, err := r.floats(posAcc, "VEC3")
+	if err != nil {
+		return err
+	}
+	var uv [][]float64
+	if a, ok := attrs["TEXCOORD_0"]; ok {
+		if uv, err = r.floats(a, "VEC2"); err != nil {
+			return err
+		}
+	}
+	var idx []int
+	if indices != nil {
+		if idx, err = r.ints(*indices); err != nil {
+			return err
+		}
+	} else {
+		for i := range pos {
+			idx = append(idx, i)
+		}
+	}
+	mat := int32(-1)
+	if material != nil && *material >= 0 && *material < len(materials) {
+		mat = materials[*material]
+	}
+
+	corner := func(i int) (pdo.Vertex3D, [2]float64, error) {
+		if i < 0 || i >= len(pos) {
+			return pdo.Vertex3D{}, [2]float64{}, fmt.Errorf("%w: vertex index %d out of range", ErrBadGLTF, i)
+		}
+		p := m.apply(pos[i][0], pos[i][1], pos[i][2])
+		var t [2]float64
+		if i < len(uv) {
+			t = [2]float64{uv[i][0], uv[i][1]} // glTF V also runs down the image
+		}
+		return p, t, nil
+	}
+	flip := m.mirrors()
+	triangle := func(a, b, c int) error {
+		if flip {
+			b, c = c, b
+		}
+		var ps [3]pdo.Vertex3D
+		var ts [3][2]float64
+		for k, i := range [3]int{idx[a], idx[b], idx[c]} {
+			var err error
+			if ps[k], ts[k], err = corner(i); err != nil {
+				return err
+			}
+		}
+		obj.face(ps[:], ts[:], mat)
+		return nil
+	}
+	switch primMode {
+	case gltfTriangles:
+		for i := 0; i+2 < len(idx); i += 3 {
+			if err := triangle(i, i+1, i+2); err != nil {
+				return err
+			}
+		}
+	case gltfTriangleStrip:
+		for i := 0; i+2 < len(idx); i++ {
+			a, c := i+1, i+2
+			if i%2 == 1 {
+				a, c = c, a
+			}
+			if err := triangle(i, a, c); err != nil {
+				return err
+			}
+		}
+	case gltfTriangleFan:
+		for i := 1; i+1 < len(idx); i++ {
+			if err := triangle(0, i, i+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// view returns the bytes of an accessor's buffer view from its offset,
+// and the distance between elements.
+func (r *gltfReader) view(a int, elemSize int) ([]byte, int, error) {
+	acc := r.doc.Accessors[a]
+	if acc.BufferView == nil || *acc.BufferView < 0 || *acc.BufferView >= len(r.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("%w: accessor %d has no buffer view", ErrBadGLTF, a)
+	}
+	bv := r.doc.BufferViews[*acc.BufferView]
+	buf, err := r.buffer(bv.Buffer)
+	if err != nil {
+		return nil, 0, err
+	}
+	stride := bv.ByteStride
+	if stride == 0 {
+		stride = elemSize
+	}
+	start := bv.ByteOffset + acc.ByteOffset
+	end := start + stride*(acc.Count-1) + elemSize
+	if acc.Count == 0 {
+		end = start
+	}
+	if start < 0 || end > bv.ByteOffset+bv.ByteLength || end > len(buf) {
+		return nil, 0, fmt.Errorf("%w: accessor %d exceeds its buffer", ErrBadGLTF, a)
+	}
+	return buf[start:end], stride, nil
+}
+
+var gltfComponents = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4}
+
+// Component types.
+const (
+	gltfUnsignedByte  = 5121
+	gltfUnsignedShort = 5123
+	gltfUnsignedInt   = 5125
+	gltfFloat         = 5126
+)
+
+// floats reads a float accessor, or a normalized integer one.
+func (r *gltfReader) floats(a int, typ string) ([][]float64, error) {
+	if a < 0 || a >= len(r.doc.Accessors) {
+		return nil, fmt.Errorf("%w: accessor %d out of range", ErrBadGLTF, a)
+	}
+	acc := r.doc.Accessors[a]
+	n := gltfComponents[typ]
+	if acc.Type != typ {
+		return nil, fmt.Errorf("%w: accessor %d is %s, want %s", ErrBadGLTF, a, acc.Type, typ)
+	}
+	size := map[int]int{gltfFloat: 4, gltfUnsignedShort: 2, gltfUnsignedByte: 1}[acc.ComponentType]
+	if size == 0 || (acc.ComponentType != gltfFloat && !acc.Normalized) {
+		return nil, fmt.Errorf("%w: accessor %d has unsupported component type %d", ErrBadGLTF, a, acc.ComponentType)
+	}
+	data, stride, err := r.view(a, n*size)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]float64, acc.Count)
+	for i := range out {
+		out[i] = make([]float64, n)
+		for c := range n {
+			at := data[i*stride+c*size:]
+			switch acc.ComponentType {
+			case gltfFloat:
+				out[i][c] = float64(math.Float32frombits(binary.LittleEndian.Uint32(at)))
+			case gltfUnsignedShort:
+				out[i][c] = float64(binary.LittleEndian.Uint16(at)) / 0xffff
+			case gltfUnsignedByte:
+				out[i][c] = float64(at[0]) / 0xff
+			}
+		}
+	}
+	return out, nil
+}
+
+// ints reads an index accessor.
+func (r *gltfReader) ints(a int) ([]int, error) {
+	if a < 0 || a >= len(r.doc.Accessors) {
+		return nil, fmt.Errorf("%w: accessor %d out of range", ErrBadGLTF, a)
+	}
+	acc := r.doc.Accessors[a]
+	size := map[int]int{gltfUnsignedInt: 4, gltfUnsignedShort: 2, gltfUnsignedByte: 1}[acc.ComponentType]
+	if size == 0 || acc.Type != "SCALAR" {
+		return nil, fmt.Errorf("%w: accessor %d isn't an index list", ErrBadGLTF, a)
+	}
+	data, stride, err := r.view(a, size)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int, acc.Count)
+	for i := range out {
+		at := data[i*stride:]
+		switch size {
+		case 4:
+			out[i] = int(binary.LittleEndian.Uint32(at))
+		case 2:
+			out[i] = int(binary.LittleEndian.Uint16(at))
+		default:
+			out[i] = int(at[0])
+		}
+	}
+	return out, nil
+}
+
+// buffer loads a buffer from the GLB chunk, a data URI or a file.
+func (r *gltfReader) buffer(i int) ([]byte, error) {
+	if buf, ok := r.buffers[i]; ok {
+		return buf, nil
+	}
+	if i < 0 || i >= len(r.doc.Buffers) {
+		return nil, fmt.Errorf("%w: buffer %d out of range", ErrBadGLTF, i)
+	}
+	var buf []byte
+	if uri := r.doc.Buffers[i].URI; uri == "" {
+		if r.bin == nil {
+			return nil, fmt.Errorf("%w: buffer %d has no data", ErrBadGLTF, i)
+		}
+		buf = r.bin
+	} else {
+		var err error
+		if buf, err = r.uri(uri); err != nil {
+			return nil, err
+		}
+	}
+	r.buffers[i] = buf
+	return buf, nil
+}
+
+// uri reads a data URI or a file relative to the model.
+func (r *gltfReader) uri(uri string) ([]byte, error) {
+	if rest, ok := strings.CutPrefix(uri, "data:"); ok {
+		_, payload, ok := strings.Cut(rest, ";base64,")
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported data URI", ErrBadGLTF)
+		}
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	if r.dir == "" {
+		return nil, fmt.Errorf("%w: external file %q needs the model path", ErrBadGLTF, uri)
+	}
+	name, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadGLTF, err)
+	}
+	return os.ReadFile(filepath.Join(r.dir, filepath.FromSlash(name)))
+}
+
+// texture decodes the image of a texture.
+func (r *gltfReader) texture(t int) (image.Image, error) {
+	if t < 0 || t >= len(r.doc.Textures) || r.doc.Textures[t].Source == nil {
+		return nil, fmt.Errorf("%w: texture %d has no image", ErrBadGLTF, t)
+	}
+	i := *r.doc.Textures[t].Source
+	if i < 0 || i >= len(r.doc.Images) {
+		return nil, fmt.Errorf("%w: image %d out of range", ErrBadGLTF, i)
+	}
+	img := r.doc.Images[i]
+	var data []byte
+	if img.BufferView != nil {
+		v := *img.BufferView
+		if v < 0 || v >= len(r.doc.BufferViews) {
+			return nil, fmt.Errorf("%w: buffer view %d out of range", ErrBadGLTF, v)
+		}
+		bv := r.doc.BufferViews[v]
+		buf, err := r.buffer(bv.Buffer)
+		if err != nil {
+			return nil, err
+		}
+		if bv.ByteOffset < 0 || bv.ByteOffset+bv.ByteLength > len(buf) {
+			return nil, fmt.Errorf("%w: buffer view %d exceeds its buffer", ErrBadGLTF, v)
+		}
+		data = buf[bv.ByteOffset : bv.ByteOffset+bv.ByteLength]
+	} else {
+		var err error
+		if data, err = r.uri(img.URI); err != nil {
+			return nil, err
+		}
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	return decoded, err
+}
+
+// splitGLB returns the JSON and binary chunks of a GLB file.
+func splitGLB(data []byte) (jsonChunk, bin []byte, err error) {
+	if len(data) < 20 || binary.LittleEndian.Uint32(data[4:]) != 2 {
+		return nil, nil, fmt.Errorf("%w: not a glTF 2.0 binary", ErrBadGLTF)
+	}
+	length := min(int(binary.LittleEndian.Uint32(data[8:])), len(data))
+	for at := 12; at+8 <= length; {
+		size := int(binary.LittleEndian.Uint32(data[at:]))
+		typ := string(data[at+4 : at+8])
+		at += 8
+		if size < 0 || at+size > length {
+			return nil, nil, fmt.Errorf("%w: truncated chunk", ErrBadGLTF)
+		}
+		switch typ {
+		case "JSON":
+			jsonChunk = data[at : at+size]
+		case "BIN\x00":
+			bin = data[at : at+size]
+		}
+		at += size
+	}
+	if jsonChunk == nil {
+		return nil, nil, fmt.Errorf("%w: no JSON chunk", ErrBadGLTF)
+	}
+	return jsonChunk, bin, nil
+}
+
+type gltfImporter struct{}
+
+func (gltfImporter) Name() string         { return "gltf" }
+func (gltfImporter) Extensions() []string { return []string{".gltf", ".glb"} }
+func (gltfImporter) Import(ctx context.Context, src Source, opts Options) (*pdo.PDO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ImportGLTF(src, opts)
+}
+
+func init() {
+	Register(gltfImporter{})
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// cubeGLTF returns a unit cube as glTF with 24 vertices (split per side as
+// exporters write them), a textured material and the binary buffer.
+func cubeGLTF(t *testing.T) (doc string, bin []byte) {
+	t.Helper()
+	var pos, uv []float32
+	var idx []uint16
+	sides := [6][4][3]float32{
+		{{0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1}},
+		{{1, 0, 0}, {0, 0, 0}, {0, 1, 0}, {1, 1, 0}},
+		{{1, 0, 1}, {1, 0, 0}, {1, 1, 0}, {1, 1, 1}},
+		{{0, 0, 0}, {0, 0, 1}, {0, 1, 1}, {0, 1, 0}},
+		{{0, 1, 1}, {1, 1, 1}, {1, 1, 0}, {0, 1, 0}},
+		{{0, 0, 0}, {1, 0, 0}, {1, 0, 1}, {0, 0, 1}},
+	}
+	for s, side := range sides {
+		for i, v := range side {
+			pos = append(pos, v[:]...)
+			uv = append(uv, float32(i%2), float32(i/2))
+		}
+		b := uint16(s * 4)
+		idx = append(idx, b, b+1, b+2, b, b+2, b+3)
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, pos)
+	binary.Write(&buf, binary.LittleEndian, uv)
+	binary.Write(&buf, binary.LittleEndian, idx)
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+	imgStart := buf.Len()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	bin = buf.Bytes()
+
+	doc = fmt.Sprintf(`{
+  "asset": {"version": "2.0"},
+  "scene": 0,
+  "scenes": [{"nodes": [0]}],
+  "nodes": [{"name": "box", "children": [1], "translation": [0, 2, 0]},
+            {"mesh": 0, "scale": [1, 1, 1]}],
+  "meshes": [{"name": "cube", "primitives": [{"attributes": {"POSITION": 0, "TEXCOORD_0": 1}, "indices": 2, "material": 0}]}],
+  "materials": [{"name": "paper", "pbrMetallicRoughness": {"baseColorFactor": [0.5, 0.25, 1, 1], "baseColorTexture": {"index": 0}}}],
+  "textures": [{"source": 0}],
+  "images": [{"bufferView": 3, "mimeType": "image/png"}],
+  "accessors": [
+    {"bufferView": 0, "componentType": 5126, "count": 24, "type": "VEC3"},
+    {"bufferView": 1, "componentType": 5126, "count": 24, "type": "VEC2"},
+    {"bufferView": 2, "componentType": 5123, "count": 36, "type": "SCALAR"}
+  ],
+  "bufferViews": [
+    {"buffer": 0, "byteOffset": 0, "byteLength": 288},
+    {"buffer": 0, "byteOffset": 288, "byteLength": 192},
+    {"buffer": 0, "byteOffset": 480, "byteLength": 72},
+    {"buffer": 0, "byteOffset": %d, "byteLength": %d}
+  ],
+  "buffers": [{"byteLength": %d, "uri": "%%s"}]
+}`, imgStart, len(bin)-imgStart, len(bin))
+	return doc, bin
+}
+
+func checkCube(t *testing.T, p *pdo.PDO) {
+	t.Helper()
+	if len(p.Objects) != 1 || p.Objects[0].Name != "cube" {
+		t.Fatalf("objects = %d, want one named cube", len(p.Objects))
+	}
+	obj := p.Objects[0]
+	if len(obj.Vertices) != 8 || len(obj.Faces) != 12 || len(obj.Edges) != 18 {
+		t.Fatalf("got %d vertices, %d faces, %d edges; want 8, 12, 18",
+			len(obj.Vertices), len(obj.Faces), len(obj.Edges))
+	}
+	for _, e := range obj.Edges {
+		if e.Face2Index < 0 {
+			t.Errorf("edge %+v is open, cube sides were not welded", e)
+		}
+	}
+	for _, v := range obj.Vertices {
+		if (v.X != 0 && v.X != 1000) || (v.Y != 2000 && v.Y != 3000) {
+			t.Errorf("vertex %+v not in mm or not translated", v)
+		}
+	}
+	// The first side faces +Z.
+	if f := obj.Faces[0]; math.Abs(f.Nz-1) > 1e-9 {
+		t.Errorf("face 0 normal = (%g, %g, %g), want +Z", f.Nx, f.Ny, f.Nz)
+	}
+	if len(p.Materials) != 1 || !p.Materials[0].HasTexture || p.Materials[0].Color3D.Model.R != 0.5 {
+		t.Fatalf("material not imported: %+v", p.Materials)
+	}
+	img, err := p.Materials[0].Texture.GetImage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, _, _ := img.At(0, 0).RGBA(); r != 0xffff || g != 0 {
+		t.Errorf("texture pixel = %v, want red", img.At(0, 0))
+	}
+
+	var buf bytes.Buffer
+	if err := pdo.Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := pdo.NewParser(bytes.NewReader(buf.Bytes())).Load(); err != nil {
+		t.Fatalf("imported model doesn't parse back: %v", err)
+	}
+}
+
+func TestImportGLTF(t *testing.T) {
+	doc, bin := cubeGLTF(t)
+	doc = fmt.Sprintf(doc, "data:application/octet-stream;base64,"+base64.StdEncoding.EncodeToString(bin))
+	imp, ok := ForExtension(".gltf")
+	if !ok {
+		t.Fatal("no importer for .gltf")
+	}
+	p, err := imp.Import(context.Background(), Source{R: strings.NewReader(doc)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCube(t, p)
+}
+
+func TestImportGLB(t *testing.T) {
+	doc, bin := cubeGLTF(t)
+	doc = strings.Replace(doc, `, "uri": "%s"`, "", 1)
+	js := []byte(doc)
+	for len(js)%4 != 0 {
+		js = append(js, ' ')
+	}
+	var glb bytes.Buffer
+	glb.WriteString("glTF")
+	binary.Write(&glb, binary.LittleEndian, []uint32{2, uint32(12 + 8 + len(js) + 8 + len(bin))})
+	binary.Write(&glb, binary.LittleEndian, uint32(len(js)))
+	glb.WriteString("JSON")
+	glb.Write(js)
+	binary.Write(&glb, binary.LittleEndian, uint32(len(bin)))
+	glb.WriteString("BIN\x00")
+	glb.Write(bin)
+
+	p, err := ImportGLTF(Source{R: &glb}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCube(t, p)
+}
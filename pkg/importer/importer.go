@@ -0,0 +1,98 @@
+// Package importer reads 3D models from other formats into PDO models that
+// can be unfolded in Pepakura Designer.
+package importer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Source is the input of an import.
+type Source struct {
+	// R reads the main input file.
+	R io.Reader
+	// Path is the path of the main input file. Importers that read side
+	// files (buffers, textures) resolve them relative to it.
+	Path string
+}
+
+// Options holds settings shared by all importers.
+type Options struct {
+	// Logger receives non-fatal warnings. slog.Default() is used when nil.
+	Logger *slog.Logger
+}
+
+func (o Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.Default()
+	}
+	return o.Logger
+}
+
+// Importer converts a model into a PDO without an unfolding.
+type Importer interface {
+	// Name is the format name used on the command line, e.g. "gltf".
+	Name() string
+	// Extensions lists the file extensions of the format, including the dot.
+	Extensions() []string
+	Import(ctx context.Context, src Source, opts Options) (*pdo.PDO, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Importer{}
+)
+
+// Register makes an importer available by name and extension.
+// It panics if an importer with the same name is already registered.
+func Register(i Importer) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := strings.ToLower(i.Name())
+	if _, dup := registry[name]; dup {
+		panic("importer: Register called twice for importer " + name)
+	}
+	registry[name] = i
+}
+
+// Lookup returns the importer registered under name.
+func Lookup(name string) (Importer, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	i, ok := registry[strings.ToLower(name)]
+	return i, ok
+}
+
+// ForExtension returns the importer handling the given file extension.
+func ForExtension(ext string) (Importer, bool) {
+	ext = strings.ToLower(ext)
+	for _, i := range Importers() {
+		for _, x := range i.Extensions() {
+			if strings.ToLower(x) == ext {
+				return i, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Importers returns all registered importers sorted by name.
+func Importers() []Importer {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	list := make([]Importer, 0, len(registry))
+	for _, i := range registry {
+		list = append(list, i)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Name() < list[b].Name() })
+	return list
+}
@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// mat4 is a 4x4 affine transform in column-major order, as glTF and
+// OpenGL store them.
+type mat4 [16]float64
+
+var identity = mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+
+// mul returns m*n, the transform applying n first.
+func (m mat4) mul(n mat4) mat4 {
+	var r mat4
+	for c := range 4 {
+		for row := range 4 {
+			var s float64
+			for k := range 4 {
+				s += m[k*4+row] * n[c*4+k]
+			}
+			r[c*4+row] = s
+		}
+	}
+	return r
+}
+
+func (m mat4) apply(x, y, z float64) pdo.Vertex3D {
+	return pdo.Vertex3D{
+		X: m[0]*x + m[4]*y + m[8]*z + m[12],
+		Y: m[1]*x + m[5]*y + m[9]*z + m[13],
+		Z: m[2]*x + m[6]*y + m[10]*z + m[14],
+	}
+}
+
+// mirrors reports whether the transform turns faces inside out.
+func (m mat4) mirrors() bool {
+	det := m[0]*(m[5]*m[10]-m[9]*m[6]) - m[4]*(m[1]*m[10]-m[9]*m[2]) + m[8]*(m[1]*m[6]-m[5]*m[2])
+	return det < 0
+}
+
+func translation(x, y, z float64) mat4 {
+	return mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, x, y, z, 1}
+}
+
+func scaling(x, y, z float64) mat4 {
+	return mat4{x, 0, 0, 0, 0, y, 0, 0, 0, 0, z, 0, 0, 0, 0, 1}
+}
+
+// quaternion returns the rotation of a unit quaternion.
+func quaternion(x, y, z, w float64) mat4 {
+	return mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+		2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+		2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// axisAngle returns the rotation by angle degrees around an axis.
+func axisAngle(x, y, z, angle float64) mat4 {
+	l := math.Sqrt(x*x + y*y + z*z)
+	if l == 0 {
+		return identity
+	}
+	s, c := math.Sincos(angle * math.Pi / 360)
+	return quaternion(x/l*s, y/l*s, z/l*s, c)
+}
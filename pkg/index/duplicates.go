@@ -0,0 +1,200 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"math"
+	"slices"
+	"strings"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Kinds of duplicate groups, from the closest copies.
+const (
+	KindIdentical   = "identical"    // The files are the same bytes
+	KindSameContent = "same-content" // Same fingerprint; only metadata or print settings differ
+	KindSameModel   = "same-model"   // Same shape, textures and size; the layout differs
+	KindRescaled    = "rescaled"     // Same shape and textures at different sizes
+	KindRetexture   = "retextured"   // Same shape with different textures
+	KindEdited      = "edited"       // Same author, comment and object names with a different shape
+)
+
+// shapeDigits is the number of decimals of the normalized vertex positions
+// compared by shapes.
+const shapeDigits = 4
+
+// sizeTolerance is the relative difference of extents under which copies
+// have the same size.
+const sizeTolerance = 1e-3
+
+// shapeHash hashes the topology of the faces and the positions of the
+// vertices around their centroid, scaled to a unit mean distance, so copies
+// re-scaled in the 3D view or by the unfold scale hash the same. The
+// second result is the mean distance in mm at the unfold scale.
+func shapeHash(p *pdo.PDO) (string, float64) {
+	var c pdo.Vertex3D
+	n := 0
+	for _, o := range p.Objects {
+		for _, v := range o.Vertices {
+			c.X, c.Y, c.Z = c.X+v.X, c.Y+v.Y, c.Z+v.Z
+			n++
+		}
+	}
+	if n == 0 {
+		return "", 0
+	}
+	c.X, c.Y, c.Z = c.X/float64(n), c.Y/float64(n), c.Z/float64(n)
+	var sum float64
+	for _, o := range p.Objects {
+		for _, v := range o.Vertices {
+			sum += (v.X-c.X)*(v.X-c.X) + (v.Y-c.Y)*(v.Y-c.Y) + (v.Z-c.Z)*(v.Z-c.Z)
+		}
+	}
+	radius := math.Sqrt(sum / float64(n))
+	if radius == 0 {
+		radius = 1
+	}
+
+	h := sha256.New()
+	put := func(v int64) { h.Write(binary.LittleEndian.AppendUint64(nil, uint64(v))) }
+	quantize := func(v float64) int64 { return int64(math.Round(v / radius * math.Pow10(shapeDigits))) }
+	for _, o := range p.Objects {
+		put(int64(len(o.Vertices)))
+		for _, v := range o.Vertices {
+			put(quantize(v.X - c.X))
+			put(quantize(v.Y - c.Y))
+			put(quantize(v.Z - c.Z))
+		}
+		put(int64(len(o.Faces)))
+		for _, f := range o.Faces {
+			put(int64(len(f.Vertices)))
+			for _, v := range f.Vertices {
+				put(int64(v.IDVertex))
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]), radius * p.Unfold.Scale
+}
+
+// textureHashes hashes the decoded pixels of the material textures, sorted
+// so reordered materials hash the same. Textures that don't decode hash
+// their stored data.
+func textureHashes(p *pdo.PDO) []string {
+	var hashes []string
+	for i := range p.Materials {
+		t := &p.Materials[i].Texture
+		if !p.Materials[i].HasTexture {
+			continue
+		}
+		h := sha256.New()
+		h.Write(binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, uint32(t.Width)), uint32(t.Height)))
+		if img, err := t.GetImage(); err == nil {
+			h.Write(img.(*image.RGBA).Pix)
+		} else {
+			h.Write(t.RawData)
+		}
+		hashes = append(hashes, hex.EncodeToString(h.Sum(nil)[:16]))
+	}
+	slices.Sort(hashes)
+	return slices.Compact(hashes)
+}
+
+// DuplicateGroup is a set of files holding copies of one model.
+type DuplicateGroup struct {
+	Kind  string      `json:"kind"`
+	Files []Duplicate `json:"files"`
+}
+
+// Duplicate is a file of a duplicate group.
+type Duplicate struct {
+	File   string  `json:"file"`
+	Author string  `json:"author,omitempty"`
+	Extent float64 `json:"extent_mm"`
+	// SameAs is the earlier file of the group with the same bytes.
+	SameAs string `json:"same_as,omitempty"`
+}
+
+// Duplicates groups the entries holding copies of the same model: with the
+// same shape, whatever their textures and size, or else with the same
+// author, comment and object names. Entries indexed without a shape, e.g.
+// of empty models, are left out. Groups are ordered by their first file
+// and their files by name.
+func Duplicates(entries []Entry) []DuplicateGroup {
+	sorted := make([]*Entry, 0, len(entries))
+	for i := range entries {
+		if entries[i].Shape != "" {
+			sorted = append(sorted, &entries[i])
+		}
+	}
+	slices.SortFunc(sorted, func(a, b *Entry) int { return strings.Compare(a.File, b.File) })
+
+	var groups []DuplicateGroup
+	grouped := map[*Entry]bool{}
+	group := func(kind string, members []*Entry) {
+		g := DuplicateGroup{Kind: kind}
+		first := map[string]string{}
+		for _, e := range members {
+			d := Duplicate{File: e.File, Author: e.Author, Extent: e.Extent}
+			if f, ok := first[e.Hash]; ok && e.Hash != "" {
+				d.SameAs = f
+			} else {
+				first[e.Hash] = e.File
+			}
+			g.Files = append(g.Files, d)
+			grouped[e] = true
+		}
+		groups = append(groups, g)
+	}
+
+	byShape := map[string][]*Entry{}
+	for _, e := range sorted {
+		byShape[e.Shape] = append(byShape[e.Shape], e)
+	}
+	for _, e := range sorted {
+		members := byShape[e.Shape]
+		if grouped[e] || len(members) < 2 {
+			continue
+		}
+		group(duplicateKind(members), members)
+	}
+
+	byMeta := map[string][]*Entry{}
+	for _, e := range sorted {
+		if !grouped[e] && (e.Author != "" || e.Comment != "") {
+			key := strings.Join(append([]string{e.Author, e.Comment}, e.Objects...), "\x00")
+			byMeta[key] = append(byMeta[key], e)
+		}
+	}
+	for _, members := range byMeta {
+		if len(members) > 1 {
+			group(KindEdited, members)
+		}
+	}
+	slices.SortFunc(groups, func(a, b DuplicateGroup) int { return strings.Compare(a.Files[0].File, b.Files[0].File) })
+	return groups
+}
+
+// duplicateKind names how files with the same shape differ.
+func duplicateKind(members []*Entry) string {
+	kind := KindIdentical
+	for _, e := range members[1:] {
+		switch {
+		case !slices.Equal(e.Textures, members[0].Textures):
+			return KindRetexture
+		case math.Abs(e.Extent-members[0].Extent) > sizeTolerance*max(e.Extent, members[0].Extent):
+			kind = KindRescaled
+		case e.Fingerprint == "" || e.Fingerprint != members[0].Fingerprint:
+			if kind == KindIdentical || kind == KindSameContent {
+				kind = KindSameModel
+			}
+		case e.Hash == "" || e.Hash != members[0].Hash:
+			if kind == KindIdentical {
+				kind = KindSameContent
+			}
+		}
+	}
+	return kind
+}
@@ -0,0 +1,76 @@
+package index
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func TestDuplicates(t *testing.T) {
+	entry := func(file, sample string, edit func(p *pdo.PDO)) Entry {
+		t.Helper()
+		p, err := pdo.ParseFile("../../sample_basic_shapes/" + sample + ".pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit != nil {
+			edit(p)
+		}
+		e := NewEntry(file, p)
+		e.Hash = sample
+		return e
+	}
+	grow := func(p *pdo.PDO) {
+		for i := range p.Objects[0].Vertices {
+			v := &p.Objects[0].Vertices[i]
+			v.X, v.Y, v.Z = v.X*2+5, v.Y*2, v.Z*2
+		}
+	}
+	entries := []Entry{
+		entry("b/cone.pdo", "cone", nil),
+		entry("a/cone.pdo", "cone", nil),
+		entry("a/cone-big.pdo", "cone", grow),
+		entry("a/pyramid.pdo", "pyramid", nil),
+		entry("a/pyramid-signed.pdo", "pyramid", func(p *pdo.PDO) { p.Settings.AuthorName = "Ana" }),
+		entry("c/sphere.pdo", "sphere", func(p *pdo.PDO) { p.Settings.AuthorName = "Ana" }),
+		entry("d/sphere.pdo", "sphere", func(p *pdo.PDO) {
+			p.Settings.AuthorName = "Ana"
+			p.Objects[0].Vertices[0].X += 1
+		}),
+		entry("torus.pdo", "torus", nil),
+		entry("a/torus.pdo", "torus", func(p *pdo.PDO) { p.Parts[1].BoundingBox.Top += 20 }),
+	}
+	entries[2].Hash = "big"
+	entries[4].Hash = "signed"
+	entries[6].Hash = "edited"
+	entries[8].Hash = "moved"
+	if entries[0].Shape == "" || entries[0].Shape != entries[2].Shape || entries[0].Shape == entries[3].Shape {
+		t.Fatalf("shapes %q, %q, %q", entries[0].Shape, entries[2].Shape, entries[3].Shape)
+	}
+	if r := entries[2].Extent / entries[1].Extent; math.Abs(r-2) > 1e-9 {
+		t.Errorf("grown extent ratio %g, want 2", r)
+	}
+
+	var got []string
+	for _, g := range Duplicates(entries) {
+		s := g.Kind + ":"
+		for _, f := range g.Files {
+			s += " " + f.File
+			if f.SameAs != "" {
+				s += "=" + f.SameAs
+			}
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"rescaled: a/cone-big.pdo a/cone.pdo b/cone.pdo=a/cone.pdo",
+		"same-content: a/pyramid-signed.pdo a/pyramid.pdo",
+		"same-model: a/torus.pdo torus.pdo",
+		"edited: c/sphere.pdo d/sphere.pdo",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("groups\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+// Package index keeps the metadata of a collection of PDO files in one JSON
+// file and searches it, to find models among thousands of files without
+// parsing them again.
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// FormatVersion is the version of the index file format written by Write.
+const FormatVersion = 1
+
+// Entry is the indexed metadata of one PDO file.
+type Entry struct {
+	File string `json:"file"`
+	// Size and ModTime tell whether the file changed since it was indexed.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Hash is the SHA-256 of the file, in hex.
+	Hash     string  `json:"hash,omitempty"`
+	Version  int32   `json:"version"`
+	Designer string  `json:"designer,omitempty"`
+	Author   string  `json:"author,omitempty"`
+	Comment  string  `json:"comment,omitempty"`
+	Scale    float64 `json:"scale"`
+	// Objects are the names of the 3D objects.
+	Objects []string `json:"objects,omitempty"`
+	// Text are the lines of the text blocks, in file order.
+	Text       []string       `json:"text,omitempty"`
+	Stats      pdo.Statistics `json:"stats"`
+	Difficulty pdo.Difficulty `json:"difficulty"`
+	// Fingerprint is the pdo.Fingerprint of the model.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Shape hashes the faces and the vertices of the model, moved and
+	// scaled to a unit size, and Extent is that size in mm: the mean
+	// distance of the vertices from their center at the unfold scale.
+	Shape  string  `json:"shape,omitempty"`
+	Extent float64 `json:"extent_mm"`
+	// Textures hash the pixels of the distinct material textures.
+	Textures []string `json:"textures,omitempty"`
+}
+
+// NewEntry indexes a parsed file.
+func NewEntry(file string, p *pdo.PDO) Entry {
+	e := Entry{
+		File:        file,
+		Version:     p.Header.Version,
+		Designer:    p.Header.DesignerID,
+		Author:      p.Settings.AuthorName,
+		Comment:     p.Settings.Comment,
+		Scale:       p.Unfold.Scale,
+		Stats:       pdo.Stats(p),
+		Difficulty:  p.Difficulty(),
+		Textures:    textureHashes(p),
+		Fingerprint: pdo.Fingerprint(p),
+	}
+	e.Shape, e.Extent = shapeHash(p)
+	for _, o := range p.Objects {
+		e.Objects = append(e.Objects, o.Name)
+	}
+	for _, tb := range p.TextBlocks {
+		for _, line := range tb.Lines {
+			if strings.TrimSpace(line) != "" {
+				e.Text = append(e.Text, line)
+			}
+		}
+	}
+	return e
+}
+
+// Pages returns the number of pages of the layout grid.
+func (e *Entry) Pages() int {
+	return e.Stats.PagesX * e.Stats.PagesY
+}
+
+// Index is the metadata of a collection of PDO files.
+type Index struct {
+	Version int       `json:"version"`
+	Updated time.Time `json:"updated"`
+	// Entries are sorted by file.
+	Entries []Entry `json:"entries"`
+}
+
+// Read reads an index written by Write.
+func Read(r io.Reader) (*Index, error) {
+	var ix Index
+	if err := json.NewDecoder(r).Decode(&ix); err != nil {
+		return nil, fmt.Errorf("index: %w", err)
+	}
+	if ix.Version > FormatVersion {
+		return nil, fmt.Errorf("index: format version %d is newer than %d, update pdo-tools", ix.Version, FormatVersion)
+	}
+	return &ix, nil
+}
+
+// Write writes the index as JSON.
+func (ix *Index) Write(w io.Writer) error {
+	ix.Version = FormatVersion
+	slices.SortFunc(ix.Entries, func(a, b Entry) int { return strings.Compare(a.File, b.File) })
+	return json.NewEncoder(w).Encode(ix)
+}
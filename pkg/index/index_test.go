@@ -0,0 +1,90 @@
+package index
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/pdo"
+)
+
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+	ix := &Index{}
+	for _, name := range []string{"torus", "cone", "pyramid"} {
+		p, err := pdo.ParseFile("../../sample_basic_shapes/" + name + ".pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "cone" {
+			p.Settings.AuthorName = "Ana Souza"
+			p.Settings.Comment = "Party hat\r\nPrint on 160 g/m² card"
+			p.TextBlocks = append(p.TextBlocks, pdo.TextBlock{Lines: []string{"Glue the tip last", " "}})
+		}
+		ix.Entries = append(ix.Entries, NewEntry("models/"+name+".pdo", p))
+	}
+	return ix
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	ix := testIndex(t)
+	var buf bytes.Buffer
+	if err := ix.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Entries) != 3 || got.Entries[0].File != "models/cone.pdo" {
+		t.Fatalf("entries %+v, want sorted by file", got.Entries)
+	}
+	cone := got.Entries[0]
+	if cone.Author != "Ana Souza" || len(cone.Text) != 1 || cone.Stats.Parts != 1 || cone.Difficulty.Level == "" {
+		t.Errorf("cone entry %+v", cone)
+	}
+
+	if _, err := Read(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Error("read an index of a newer format")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix := testIndex(t)
+	tests := []struct {
+		query []string
+		want  []string
+	}{
+		{nil, []string{"torus", "cone", "pyramid"}},
+		{[]string{"PARTY"}, []string{"cone"}},
+		{[]string{"comment:160 g"}, []string{"cone"}},
+		{[]string{"text:glue"}, []string{"cone"}},
+		{[]string{"author:souza", "parts<2"}, []string{"cone"}},
+		{[]string{"parts>=2"}, []string{"torus"}},
+		{[]string{"level:easy"}, []string{"pyramid"}},
+		{[]string{"models/", "pages=1"}, []string{"torus", "cone", "pyramid"}},
+		{[]string{"hat", "level:hard"}, nil},
+		{[]string{"note:"}, nil},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range ix.Search(q) {
+			got = append(got, strings.TrimSuffix(strings.TrimPrefix(m.Entry.File, "models/"), ".pdo"))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	q, _ := ParseQuery([]string{"hat"})
+	if m := ix.Search(q); len(m) != 1 || len(m[0].Snippets) != 1 || m[0].Snippets[0] != "comment: Party hat" {
+		t.Errorf("snippets %+v", m)
+	}
+	if _, err := ParseQuery([]string{"parts<many"}); err == nil {
+		t.Error("parsed a comparison with a word")
+	}
+}
@@ -0,0 +1,159 @@
+package index
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// textFields are the fields a field:words term searches, with the values
+// of an entry they hold.
+var textFields = map[string]func(e *Entry) []string{
+	"file":     func(e *Entry) []string { return []string{e.File} },
+	"author":   func(e *Entry) []string { return []string{e.Author} },
+	"designer": func(e *Entry) []string { return []string{e.Designer} },
+	"comment":  func(e *Entry) []string { return strings.Split(strings.ReplaceAll(e.Comment, "\r\n", "\n"), "\n") },
+	"text":     func(e *Entry) []string { return e.Text },
+	"object":   func(e *Entry) []string { return e.Objects },
+	"level":    func(e *Entry) []string { return []string{e.Difficulty.Level} },
+}
+
+// anyFields are the fields bare words search, in the order snippets are
+// reported.
+var anyFields = []string{"file", "author", "designer", "comment", "text", "object"}
+
+// numberFields are the fields compared by field<value terms.
+var numberFields = map[string]func(e *Entry) float64{
+	"parts":   func(e *Entry) float64 { return float64(e.Stats.Parts) },
+	"faces":   func(e *Entry) float64 { return float64(e.Stats.Faces) },
+	"pages":   func(e *Entry) float64 { return float64(e.Pages()) },
+	"score":   func(e *Entry) float64 { return e.Difficulty.Score },
+	"scale":   func(e *Entry) float64 { return e.Scale },
+	"version": func(e *Entry) float64 { return float64(e.Version) },
+}
+
+// term is one condition of a query.
+type term struct {
+	field string // "" for any text field
+	op    string // ":" for text, else a comparison
+	words string // lower case
+	value float64
+}
+
+// Query is a set of conditions an entry must all meet.
+type Query struct {
+	terms []term
+}
+
+// ParseQuery parses the terms of a query, each one of:
+//
+//	words         any of file, author, designer, comment, text and object contains words
+//	field:words   the text field contains words, also level
+//	field<number  a number field compares so, with <, <=, >, >= or =:
+//	              parts, faces, pages, score, scale or version
+//
+// Words match case-insensitively. A term whose prefix isn't a field name
+// is searched as words, so "note:" finds that text anywhere.
+func ParseQuery(args []string) (Query, error) {
+	var q Query
+	for _, arg := range args {
+		t, err := parseTerm(arg)
+		if err != nil {
+			return Query{}, err
+		}
+		if t.words == "" && t.op == ":" {
+			continue
+		}
+		q.terms = append(q.terms, t)
+	}
+	return q, nil
+}
+
+func parseTerm(arg string) (term, error) {
+	if i := strings.IndexAny(arg, "<>=:"); i > 0 {
+		field, rest := strings.ToLower(arg[:i]), arg[i:]
+		if _, ok := textFields[field]; ok && rest[0] == ':' {
+			return term{field: field, op: ":", words: strings.ToLower(rest[1:])}, nil
+		}
+		if _, ok := numberFields[field]; ok {
+			op := rest[:1]
+			if strings.HasPrefix(rest, "<=") || strings.HasPrefix(rest, ">=") {
+				op = rest[:2]
+			}
+			if op == ":" {
+				op = "="
+			}
+			v, err := strconv.ParseFloat(rest[len(op):], 64)
+			if err != nil {
+				return term{}, fmt.Errorf("query %q: %s needs a number", arg, field)
+			}
+			return term{field: field, op: op, value: v}, nil
+		}
+	}
+	return term{op: ":", words: strings.ToLower(arg)}, nil
+}
+
+// Match is an entry meeting a query.
+type Match struct {
+	Entry *Entry
+	// Snippets are the values that matched the text terms, as
+	// "field: value", the file name left out.
+	Snippets []string
+}
+
+// Search returns the entries meeting q in index order. An empty query
+// matches every entry.
+func (ix *Index) Search(q Query) []Match {
+	var matches []Match
+	for i := range ix.Entries {
+		if m, ok := q.match(&ix.Entries[i]); ok {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func (q Query) match(e *Entry) (Match, bool) {
+	m := Match{Entry: e}
+	for _, t := range q.terms {
+		if t.op != ":" {
+			if !compare(numberFields[t.field](e), t.op, t.value) {
+				return Match{}, false
+			}
+			continue
+		}
+		fields := anyFields
+		if t.field != "" {
+			fields = []string{t.field}
+		}
+		found := false
+		for _, field := range fields {
+			for _, v := range textFields[field](e) {
+				if strings.Contains(strings.ToLower(v), t.words) {
+					if field != "file" {
+						m.Snippets = append(m.Snippets, field+": "+strings.TrimSpace(v))
+					}
+					found = true
+				}
+			}
+		}
+		if !found {
+			return Match{}, false
+		}
+	}
+	return m, true
+}
+
+func compare(v float64, op string, ref float64) bool {
+	switch op {
+	case "<":
+		return v < ref
+	case "<=":
+		return v <= ref
+	case ">":
+		return v > ref
+	case ">=":
+		return v >= ref
+	}
+	return v == ref
+}
@@ -0,0 +1,95 @@
+package pdo
+
+import "math"
+
+// New returns an empty PDO in the newest format version with the default
+// settings of Pepakura Designer, for building models from scratch.
+func New() *PDO {
+	return &PDO{
+		Header: Header{Version: PDO_V6},
+		Unfold: Unfold{Scale: 1},
+		Settings: Settings{
+			ShowFlaps:               1,
+			FaceMaterial:            1,
+			HideAlmostFlatFoldLines: 1,
+			FoldLinesHidingAngle:    175,
+			MountainFoldLineStyle:   3,
+			ValleyFoldLineStyle:     2,
+			MarginTop:               15,
+			MarginSide:              15,
+			MountainFoldLinePattern: [6]float64{1, 1, -1, -1, -1, -1},
+			ValleyFoldLinePattern:   [6]float64{2, 1, 0.5, 1, -1, -1},
+			ScaleFactor:             1,
+		},
+	}
+}
+
+// UpdateFaceNormals recomputes the normal and plane offset of every face
+// from its 3D vertices. Faces run counter-clockwise around their normal.
+func (obj *Object) UpdateFaceNormals() {
+	for i := range obj.Faces {
+		f := &obj.Faces[i]
+		var n Vertex3D
+		for j, fv := range f.Vertices {
+			a := obj.vertex(fv.IDVertex)
+			b := obj.vertex(f.Vertices[(j+1)%len(f.Vertices)].IDVertex)
+			n.X += (a.Y - b.Y) * (a.Z + b.Z)
+			n.Y += (a.Z - b.Z) * (a.X + b.X)
+			n.Z += (a.X - b.X) * (a.Y + b.Y)
+		}
+		if l := math.Sqrt(n.X*n.X + n.Y*n.Y + n.Z*n.Z); l > 0 {
+			n = Vertex3D{X: n.X / l, Y: n.Y / l, Z: n.Z / l}
+		}
+		f.Nx, f.Ny, f.Nz = n.X, n.Y, n.Z
+		if len(f.Vertices) > 0 {
+			v := obj.vertex(f.Vertices[0].IDVertex)
+			f.Coord = -(n.X*v.X + n.Y*v.Y + n.Z*v.Z)
+		}
+	}
+}
+
+func (obj *Object) vertex(i int32) Vertex3D {
+	if i < 0 || int(i) >= len(obj.Vertices) {
+		return Vertex3D{}
+	}
+	return obj.Vertices[i]
+}
+
+// BuildEdges recreates the edge table from the faces. Each edge belongs
+// to the face using it last and runs in that face's direction, the face
+// sharing it comes second, -1 on the border of the surface. Edges aren't
+// marked as connecting faces, that is decided by unfolding.
+func (obj *Object) BuildEdges() {
+	type key struct{ a, b int32 }
+	seen := map[key]int{} // Edge index by its ends, lower vertex first
+	obj.Edges = obj.Edges[:0]
+	for fi, f := range obj.Faces {
+		for j, fv := range f.Vertices {
+			v1, v2 := fv.IDVertex, f.Vertices[(j+1)%len(f.Vertices)].IDVertex
+			k := key{min(v1, v2), max(v1, v2)}
+			if e, ok := seen[k]; ok && obj.Edges[e].Face2Index < 0 {
+				// The second face takes the edge over.
+				prev := &obj.Edges[e]
+				prev.Face2Index = prev.Face1Index
+				prev.Face1Index = int32(fi)
+				prev.Vertex1Index, prev.Vertex2Index = v1, v2
+				continue
+			}
+			seen[k] = len(obj.Edges)
+			obj.Edges = append(obj.Edges, Edge{Face1Index: int32(fi), Face2Index: -1, Vertex1Index: v1, Vertex2Index: v2})
+		}
+	}
+}
+
+// UpdateBounds sets the assembled size and origin in the header from the
+// vertices of all objects: the largest extent and the bounding box center.
+func (p *PDO) UpdateBounds() {
+	b, ok := p.Bounds()
+	if !ok {
+		p.Header.AssembledHeight, p.Header.OriginOffset = 0, [3]float64{}
+		return
+	}
+	size, c := b.Size(), b.Center()
+	p.Header.AssembledHeight = max(size.X, size.Y, size.Z)
+	p.Header.OriginOffset = [3]float64{c.X, c.Y, c.Z}
+}
@@ -0,0 +1,96 @@
+package pdo
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestBuildEdges(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/pyramid.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := p.Objects[0]
+	type edge struct{ f1, f2, v1, v2 int32 }
+	want := map[edge]bool{}
+	for _, e := range obj.Edges {
+		want[edge{e.Face1Index, e.Face2Index, e.Vertex1Index, e.Vertex2Index}] = true
+	}
+
+	built := obj
+	built.Edges = nil
+	built.BuildEdges()
+	if len(built.Edges) != len(want) {
+		t.Fatalf("got %d edges, want %d", len(built.Edges), len(want))
+	}
+	for _, e := range built.Edges {
+		if !want[edge{e.Face1Index, e.Face2Index, e.Vertex1Index, e.Vertex2Index}] {
+			t.Errorf("unexpected edge %+v", e)
+		}
+	}
+
+	// Normals and the header bounds as Pepakura Designer stored them.
+	faces := append([]Face(nil), obj.Faces...)
+	built.Faces = faces
+	built.UpdateFaceNormals()
+	for i, f := range built.Faces {
+		o := obj.Faces[i]
+		if math.Abs(f.Nx-o.Nx)+math.Abs(f.Ny-o.Ny)+math.Abs(f.Nz-o.Nz)+math.Abs(f.Coord-o.Coord) > 1e-6 {
+			t.Errorf("face %d: normal %v %v %v %v, want %v %v %v %v", i, f.Nx, f.Ny, f.Nz, f.Coord, o.Nx, o.Ny, o.Nz, o.Coord)
+		}
+	}
+	height, origin := p.Header.AssembledHeight, p.Header.OriginOffset
+	p.UpdateBounds()
+	if math.Abs(p.Header.AssembledHeight-height) > 1e-6 || math.Abs(p.Header.OriginOffset[1]-origin[1]) > 1e-6 {
+		t.Errorf("bounds %v %v, want %v %v", p.Header.AssembledHeight, p.Header.OriginOffset, height, origin)
+	}
+}
+
+func TestNew_RoundTrip(t *testing.T) {
+	p := New()
+	p.Objects = []Object{{
+		Name:     "tetra",
+		Visible:  1,
+		Vertices: []Vertex3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+		Faces: []Face{
+			{MaterialIndex: 0, PartIndex: -1, Vertices: []Face2DVertex{{IDVertex: 0}, {IDVertex: 2}, {IDVertex: 1}}},
+			{MaterialIndex: 0, PartIndex: -1, Vertices: []Face2DVertex{{IDVertex: 0}, {IDVertex: 1}, {IDVertex: 3}}},
+			{MaterialIndex: 0, PartIndex: -1, Vertices: []Face2DVertex{{IDVertex: 0}, {IDVertex: 3}, {IDVertex: 2}}},
+			{MaterialIndex: 0, PartIndex: -1, Vertices: []Face2DVertex{{IDVertex: 1}, {IDVertex: 2}, {IDVertex: 3}}},
+		},
+	}}
+	p.Objects[0].UpdateFaceNormals()
+	p.Objects[0].BuildEdges()
+	p.UpdateBounds()
+
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(2, 1, color.RGBA{R: 10, G: 20, B: 30, A: 255})
+	mat := Material{Name: "paper", HasTexture: true}
+	if err := mat.Texture.SetImage(img); err != nil {
+		t.Fatal(err)
+	}
+	p.Materials = []Material{mat}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewParser(bytes.NewReader(buf.Bytes()))
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got := parser.PDO
+	if len(got.Objects) != 1 || len(got.Objects[0].Edges) != 6 || got.Objects[0].Name != "tetra" {
+		t.Fatalf("unexpected objects: %+v", got.Objects)
+	}
+	tex, err := got.Materials[0].Texture.GetImage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := tex.At(2, 1); c != (color.RGBA{R: 10, G: 20, B: 30, A: 255}) {
+		t.Errorf("texel (2, 1) is %v", c)
+	}
+}
@@ -0,0 +1,200 @@
+package pdo
+
+import (
+	"bytes"
+	"slices"
+)
+
+// Clone returns a deep copy of the PDO. Transformations can modify the copy
+// without affecting the original.
+func (p *PDO) Clone() *PDO {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Objects = cloneEach(p.Objects, (*Object).Clone)
+	c.Materials = cloneEach(p.Materials, (*Material).Clone)
+	c.TextBlocks = cloneEach(p.TextBlocks, (*TextBlock).Clone)
+	c.Parts = cloneEach(p.Parts, (*Part).Clone)
+	c.Images = cloneEach(p.Images, (*Image).Clone)
+	c.Thumbnail = slices.Clone(p.Thumbnail)
+	c.source = p.source.clone()
+	return &c
+}
+
+// clone copies the undecoded data, so edits and version changes of a
+// cloned PDO don't change how the original is written.
+func (s *source) clone() *source {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.designerID = slices.Clone(s.designerID)
+	c.v6Lock = slices.Clone(s.v6Lock)
+	c.trailing = slices.Clone(s.trailing)
+	if s.settingsItems != nil {
+		c.settingsItems = make([][]int32, len(s.settingsItems))
+		for i, item := range s.settingsItems {
+			c.settingsItems[i] = slices.Clone(item)
+		}
+	}
+	if s.strings != nil {
+		c.strings = make([]rawString, len(s.strings))
+		for i, rs := range s.strings {
+			c.strings[i] = rawString{s: rs.s, raw: slices.Clone(rs.raw)}
+		}
+	}
+	return &c
+}
+
+// Clone returns a deep copy of the object and its faces.
+func (o *Object) Clone() Object {
+	c := *o
+	c.Vertices = slices.Clone(o.Vertices)
+	c.Faces = cloneEach(o.Faces, (*Face).Clone)
+	c.Edges = slices.Clone(o.Edges)
+	return c
+}
+
+// Clone returns a deep copy of the face.
+func (f *Face) Clone() Face {
+	c := *f
+	c.Vertices = slices.Clone(f.Vertices)
+	return c
+}
+
+// Clone returns a copy of the texture with its own data.
+func (t *Texture) Clone() Texture {
+	c := *t
+	c.RawData = slices.Clone(t.RawData)
+	return c
+}
+
+// Clone returns a deep copy of the material and its texture.
+func (m *Material) Clone() Material {
+	c := *m
+	c.Texture = m.Texture.Clone()
+	return c
+}
+
+// Clone returns a deep copy of the text block.
+func (tb *TextBlock) Clone() TextBlock {
+	c := *tb
+	c.Lines = slices.Clone(tb.Lines)
+	return c
+}
+
+// Clone returns a deep copy of the image and its texture.
+func (img *Image) Clone() Image {
+	c := *img
+	c.Texture = img.Texture.Clone()
+	return c
+}
+
+// Clone returns a deep copy of the part and its lines.
+func (part *Part) Clone() Part {
+	c := *part
+	c.Lines = slices.Clone(part.Lines)
+	return c
+}
+
+func cloneEach[T any](s []T, clone func(*T) T) []T {
+	if s == nil {
+		return nil
+	}
+	c := make([]T, len(s))
+	for i := range s {
+		c[i] = clone(&s[i])
+	}
+	return c
+}
+
+// Equal reports whether two PDOs have the same content.
+// Nil and empty slices compare equal, and texture data is compared byte by byte.
+func (p *PDO) Equal(o *PDO) bool {
+	if p == nil || o == nil {
+		return p == o
+	}
+	return p.Header == o.Header &&
+		p.Settings == o.Settings &&
+		p.Unfold == o.Unfold &&
+		equalEach(p.Objects, o.Objects, (*Object).Equal) &&
+		equalEach(p.Materials, o.Materials, (*Material).Equal) &&
+		equalEach(p.TextBlocks, o.TextBlocks, (*TextBlock).Equal) &&
+		equalEach(p.Parts, o.Parts, (*Part).Equal) &&
+		equalEach(p.Images, o.Images, (*Image).Equal) &&
+		bytes.Equal(p.Thumbnail, o.Thumbnail)
+}
+
+// Equal reports whether two objects have the same content, faces included.
+func (o *Object) Equal(x *Object) bool {
+	return o.Name == x.Name &&
+		o.Visible == x.Visible &&
+		slices.Equal(o.Vertices, x.Vertices) &&
+		equalEach(o.Faces, x.Faces, (*Face).Equal) &&
+		slices.Equal(o.Edges, x.Edges)
+}
+
+// Equal reports whether two faces have the same content.
+func (f *Face) Equal(x *Face) bool {
+	return f.MaterialIndex == x.MaterialIndex &&
+		f.PartIndex == x.PartIndex &&
+		f.Nx == x.Nx && f.Ny == x.Ny && f.Nz == x.Nz &&
+		f.Coord == x.Coord &&
+		slices.Equal(f.Vertices, x.Vertices)
+}
+
+// Equal reports whether two textures have the same size and data.
+func (t *Texture) Equal(x *Texture) bool {
+	return t.Width == x.Width &&
+		t.Height == x.Height &&
+		t.DataSize == x.DataSize &&
+		t.DataHeader == x.DataHeader &&
+		t.DataHash == x.DataHash &&
+		t.TextureID == x.TextureID &&
+		bytes.Equal(t.RawData, x.RawData)
+}
+
+// Equal reports whether two materials have the same content, texture included.
+func (m *Material) Equal(x *Material) bool {
+	return m.Name == x.Name &&
+		m.Color3D == x.Color3D &&
+		m.Color2DRGBA == x.Color2DRGBA &&
+		m.HasTexture == x.HasTexture &&
+		m.Texture.Equal(&x.Texture)
+}
+
+// Equal reports whether two text blocks have the same content.
+func (tb *TextBlock) Equal(x *TextBlock) bool {
+	return tb.BoundingBox == x.BoundingBox &&
+		tb.LineSpacing == x.LineSpacing &&
+		tb.Color == x.Color &&
+		tb.FontSize == x.FontSize &&
+		tb.FontName == x.FontName &&
+		slices.Equal(tb.Lines, x.Lines)
+}
+
+// Equal reports whether two images have the same placement and texture.
+func (img *Image) Equal(x *Image) bool {
+	return img.BoundingBox == x.BoundingBox && img.Texture.Equal(&x.Texture)
+}
+
+// Equal reports whether two parts have the same content.
+func (part *Part) Equal(x *Part) bool {
+	return part.ObjectIndex == x.ObjectIndex &&
+		part.BoundingBox == x.BoundingBox &&
+		part.Name == x.Name &&
+		slices.Equal(part.Lines, x.Lines)
+}
+
+func equalEach[T any](a, b []T, eq func(*T, *T) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !eq(&a[i], &b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package pdo
+
+import (
+	"math"
+)
+
+// Difficulty holds indicators of how hard a model is to build.
+type Difficulty struct {
+	Parts int
+	// Flaps counts the flaps to glue.
+	Flaps int
+	// Folds counts the visible folds between faces.
+	Folds int
+	// AverageFaceArea is the mean area of the unfolded faces in mm².
+	AverageFaceArea float64
+	// SmallestPart is the shorter side in mm of the smallest part's box,
+	// flaps included.
+	SmallestPart float64
+	// Score grows with the indicators: with the number of parts, flaps and
+	// folds, and with parts and faces too small to handle easily.
+	Score float64
+	// Level names the range of Score: easy, medium, hard or expert.
+	Level string
+}
+
+// difficultyLevels are the upper Score bounds of the difficulty levels.
+var difficultyLevels = []struct {
+	max  float64
+	name string
+}{
+	{6, "easy"},
+	{12, "medium"},
+	{18, "hard"},
+	{math.Inf(1), "expert"},
+}
+
+// Sizes below which parts and faces get fiddly, in mm and mm².
+const (
+	smallPart = 20
+	smallFace = 100
+)
+
+// Difficulty measures the indicators of the parts laid out in the PDO.
+func (p *PDO) Difficulty() Difficulty {
+	d := Difficulty{Parts: len(p.Parts)}
+	faces, area := 0, 0.0
+	lookups := p.Lookups()
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+			continue
+		}
+		obj, lk := &p.Objects[part.ObjectIndex], lookups[part.ObjectIndex]
+		for _, face := range obj.PartFaces(i) {
+			faces++
+			area += face.Area2D()
+		}
+		for line := range part.VisibleLines() {
+			if line.IsConnectingFaces {
+				if line.Type != 3 {
+					d.Folds++
+				}
+			} else if v1, v2 := lk.LineEnds(line); lk.lineHasFlap(line, v1, v2) {
+				d.Flaps++
+			}
+		}
+		if bb := p.PartBounds(i); !bb.Empty() {
+			side := min(bb.Width, bb.Height)
+			if d.SmallestPart == 0 || side < d.SmallestPart {
+				d.SmallestPart = side
+			}
+		}
+	}
+	if faces > 0 {
+		d.AverageFaceArea = area / float64(faces)
+	}
+
+	// Every doubling of parts, flaps or folds adds to the score, and so
+	// does every halving of the smallest part or the average face below
+	// what is easy to handle.
+	d.Score = math.Log2(1+float64(d.Parts))*2 + math.Log2(1+float64(d.Flaps)) + math.Log2(1+float64(d.Folds))*0.5
+	if d.SmallestPart > 0 && d.SmallestPart < smallPart {
+		d.Score += math.Log2(smallPart/d.SmallestPart) * 2
+	}
+	if d.AverageFaceArea > 0 && d.AverageFaceArea < smallFace {
+		d.Score += math.Log2(smallFace / d.AverageFaceArea)
+	}
+	d.Score = math.Round(d.Score*10) / 10
+	for _, l := range difficultyLevels {
+		if d.Score < l.max {
+			d.Level = l.name
+			break
+		}
+	}
+	return d
+}
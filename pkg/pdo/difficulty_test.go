@@ -0,0 +1,27 @@
+package pdo
+
+import "testing"
+
+func TestDifficulty(t *testing.T) {
+	load := func(name string) *PDO {
+		p, err := ParseFile("../../sample_basic_shapes/" + name + ".pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	pyramid, sphere := load("pyramid").Difficulty(), load("sphere").Difficulty()
+	if pyramid.Parts != 1 || pyramid.Flaps != 4 || pyramid.Folds != 5 || pyramid.Level != "easy" {
+		t.Errorf("pyramid: %+v", pyramid)
+	}
+	if sphere.Score <= pyramid.Score || sphere.AverageFaceArea >= pyramid.AverageFaceArea || sphere.Level != "hard" {
+		t.Errorf("sphere %+v isn't harder than pyramid %+v", sphere, pyramid)
+	}
+
+	// Shrinking a part makes it harder.
+	p := load("pyramid")
+	p.movePart(0, func(v vec2) vec2 { return vec2{v.X / 20, v.Y / 20} })
+	if small := p.Difficulty(); small.SmallestPart >= smallPart || small.Score <= pyramid.Score {
+		t.Errorf("small pyramid %+v isn't harder than %+v", small, pyramid)
+	}
+}
@@ -0,0 +1,164 @@
+package pdo
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// duplicateTolerance is how far in mm the lines of two parts may be apart
+// for the parts to count as identical.
+const duplicateTolerance = 0.05
+
+// DuplicateGroup is a part and the parts with the same shape.
+type DuplicateGroup struct {
+	Original int        `json:"original"`
+	Copies   []PartCopy `json:"copies"`
+}
+
+// PartCopy places a copy of a part on the original: the point (x, y) of
+// the original, in part coordinates, is at
+// (x*cos(Angle) - y*sin(Angle) + DX, x*sin(Angle) + y*cos(Angle) + DY) of
+// the copy.
+type PartCopy struct {
+	Part  int     `json:"part"`
+	Angle float64 `json:"angle"` // Radians
+	DX    float64 `json:"dx"`
+	DY    float64 `json:"dy"`
+}
+
+// segment is a line of a part, or a side of a flap, in part coordinates.
+type segment struct {
+	a, b vec2
+	kind int32 // Line type, cut for flap sides
+}
+
+func (s segment) length() float64 { return math.Hypot(s.b.X-s.a.X, s.b.Y-s.a.Y) }
+
+// DuplicateParts groups the parts that are geometrically identical: with the
+// same visible lines of the same types and the same flaps, turned and moved
+// on the page. Mirrored parts don't count as duplicates, the printed side
+// would be the wrong one. Groups are ordered by their first part.
+func (p *PDO) DuplicateParts() []DuplicateGroup {
+	segs := make([][]segment, len(p.Parts))
+	for i := range p.Parts {
+		segs[i] = p.partSegments(i)
+		// The longest segment anchors the match.
+		slices.SortStableFunc(segs[i], func(a, b segment) int { return cmp.Compare(b.length(), a.length()) })
+	}
+	var groups []DuplicateGroup
+	matched := make([]bool, len(p.Parts))
+	for i := range p.Parts {
+		if matched[i] || len(segs[i]) == 0 {
+			continue
+		}
+		g := DuplicateGroup{Original: i}
+		for j := i + 1; j < len(p.Parts); j++ {
+			if matched[j] {
+				continue
+			}
+			if c, ok := matchSegments(segs[i], segs[j]); ok {
+				c.Part = j
+				g.Copies = append(g.Copies, c)
+				matched[j] = true
+			}
+		}
+		if len(g.Copies) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// partSegments returns the visible lines of part i and the sides of its
+// flaps.
+func (p *PDO) partSegments(i int) []segment {
+	part := &p.Parts[i]
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+		return nil
+	}
+	obj := &p.Objects[part.ObjectIndex]
+	var segs []segment
+	for line := range part.VisibleLines() {
+		if line.Type == 3 {
+			continue
+		}
+		v1, v2 := obj.LineEnds(line)
+		if v1 == nil || v2 == nil {
+			continue
+		}
+		a, b := vec2{v1.X, v1.Y}, vec2{v2.X, v2.Y}
+		segs = append(segs, segment{a, b, line.Type})
+		if !obj.lineHasFlap(line, v1, v2) {
+			continue
+		}
+		out := outwardNormal(obj, line.FaceIndex, v1, v2)
+		if top1, top2, ok := flapTop(a, b, out, v1.FlapHeight, v1.FlapAAngle, v1.FlapBAngle); ok {
+			segs = append(segs, segment{a, top1, 0}, segment{top1, top2, 0}, segment{top2, b, 0})
+		}
+	}
+	return segs
+}
+
+// matchSegments finds the turn and move placing the segments of a on those
+// of b, both sorted longest first.
+func matchSegments(a, b []segment) (PartCopy, bool) {
+	if len(a) != len(b) {
+		return PartCopy{}, false
+	}
+	// Sorted lengths differ between parts of different shape.
+	for k := range a {
+		if math.Abs(a[k].length()-b[k].length()) > 2*duplicateTolerance {
+			return PartCopy{}, false
+		}
+	}
+	anchor := a[0]
+	for _, s := range b {
+		if s.kind != anchor.kind || math.Abs(s.length()-anchor.length()) > duplicateTolerance {
+			if s.length() < anchor.length()-duplicateTolerance {
+				break
+			}
+			continue
+		}
+		for _, s := range []segment{s, {s.b, s.a, s.kind}} {
+			angle := math.Atan2(s.b.Y-s.a.Y, s.b.X-s.a.X) - math.Atan2(anchor.b.Y-anchor.a.Y, anchor.b.X-anchor.a.X)
+			sin, cos := math.Sincos(angle)
+			c := PartCopy{
+				Angle: math.Remainder(angle, 2*math.Pi),
+				DX:    s.a.X - (anchor.a.X*cos - anchor.a.Y*sin),
+				DY:    s.a.Y - (anchor.a.X*sin + anchor.a.Y*cos),
+			}
+			if covers(a, b, func(v vec2) vec2 { return vec2{v.X*cos - v.Y*sin + c.DX, v.X*sin + v.Y*cos + c.DY} }) {
+				return c, true
+			}
+		}
+	}
+	return PartCopy{}, false
+}
+
+// covers reports whether every segment of a, mapped by f, lies on its own
+// segment of b.
+func covers(a, b []segment, f func(vec2) vec2) bool {
+	used := make([]bool, len(b))
+	for _, s := range a {
+		fa, fb := f(s.a), f(s.b)
+		found := false
+		for j, t := range b {
+			if used[j] || t.kind != s.kind {
+				continue
+			}
+			if (closeTo(fa, t.a) && closeTo(fb, t.b)) || (closeTo(fa, t.b) && closeTo(fb, t.a)) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func closeTo(a, b vec2) bool {
+	return math.Abs(a.X-b.X) <= duplicateTolerance && math.Abs(a.Y-b.Y) <= duplicateTolerance
+}
@@ -0,0 +1,37 @@
+package pdo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDuplicateParts(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cylinder.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Add a turned and moved copy of the part, and a mirrored one.
+	for i, f := range []func(vec2) vec2{
+		func(v vec2) vec2 { r := rotation(0.7)(v); return vec2{r.X + 5, r.Y - 3} },
+		func(v vec2) vec2 { return vec2{-v.X, v.Y} },
+	} {
+		obj := p.Objects[0].Clone()
+		for j := range obj.Faces {
+			obj.Faces[j].PartIndex = int32(i + 1)
+		}
+		part := p.Parts[0].Clone()
+		part.ObjectIndex = int32(len(p.Objects))
+		p.Objects = append(p.Objects, obj)
+		p.Parts = append(p.Parts, part)
+		p.movePart(i+1, f)
+	}
+
+	groups := p.DuplicateParts()
+	if len(groups) != 1 || groups[0].Original != 0 || len(groups[0].Copies) != 1 {
+		t.Fatalf("groups %+v, want part 1 copying part 0", groups)
+	}
+	c := groups[0].Copies[0]
+	if c.Part != 1 || math.Abs(c.Angle-0.7) > 1e-6 || math.Abs(c.DX-5) > 1e-6 || math.Abs(c.DY+3) > 1e-6 {
+		t.Errorf("copy %+v, want part 1 turned by 0.7 and moved by 5, -3", c)
+	}
+}
@@ -0,0 +1,91 @@
+package pdo
+
+import "math"
+
+// EdgeLines derives the lines of part i from the faces unfolded in it and
+// the edges between them, for files whose line lists are damaged or
+// missing. A side of a face on an edge that connects it to a face of the
+// same part becomes a fold, a mountain or valley fold by the angle between
+// the faces, or an invisible one when they are about flat. Every other side
+// is cut.
+func (p *PDO) EdgeLines(i int) []Line {
+	if i < 0 || i >= len(p.Parts) {
+		return nil
+	}
+	part := &p.Parts[i]
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+		return nil
+	}
+	return p.edgeLines(NewLookup(&p.Objects[part.ObjectIndex]), i, p.flatFoldAngle())
+}
+
+// RebuildLines replaces the lines of every part with EdgeLines and returns
+// how many lines the parts have now.
+func (p *PDO) RebuildLines() int {
+	lookups, flat := p.Lookups(), p.flatFoldAngle()
+	n := 0
+	for i := range p.Parts {
+		lk := lookups.Part(&p.Parts[i])
+		if lk == nil {
+			continue
+		}
+		p.Parts[i].Lines = p.edgeLines(lk, i, flat)
+		n += len(p.Parts[i].Lines)
+	}
+	return n
+}
+
+func (p *PDO) edgeLines(lk *Lookup, i int, flat float64) []Line {
+	obj := lk.Object()
+	inPart := func(f int32) bool {
+		return f >= 0 && int(f) < len(obj.Faces) && int(obj.Faces[f].PartIndex) == i
+	}
+	var lines []Line
+	for fi, f := range obj.Faces {
+		if int(f.PartIndex) != i {
+			continue
+		}
+		face := int32(fi)
+		for vi, v := range f.Vertices {
+			next := f.Vertices[(vi+1)%len(f.Vertices)].IDVertex
+			if next == v.IDVertex {
+				continue
+			}
+			line := Line{FaceIndex: face, VertexIndex: v.IDVertex}
+			if e := lk.EdgeIndex(v.IDVertex, next); e >= 0 {
+				edge := obj.Edges[e]
+				other := edge.Face2Index
+				if other == face {
+					other = edge.Face1Index
+				}
+				if edge.ConnectsFaces != 0 && other != face && inPart(other) {
+					// The fold shows once, from the lower face.
+					if other < face {
+						continue
+					}
+					line.IsConnectingFaces = true
+					line.Face2Index, line.Vertex2Index = other, next
+					switch angle := obj.foldAngle(edge); {
+					case math.Abs(angle) < flat:
+						line.Type = 3
+					case angle > 0:
+						line.Type = 1
+					default:
+						line.Type = 2
+					}
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// flatFoldAngle returns the fold angle in degrees below which Pepakura
+// Designer hides fold lines.
+func (p *PDO) flatFoldAngle() float64 {
+	if p.Settings.HideAlmostFlatFoldLines != 0 && p.Settings.FoldLinesHidingAngle > 0 && p.Settings.FoldLinesHidingAngle < 180 {
+		return float64(180 - p.Settings.FoldLinesHidingAngle)
+	}
+	return 1e-3
+}
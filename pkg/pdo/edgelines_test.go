@@ -0,0 +1,54 @@
+package pdo
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRebuildLines(t *testing.T) {
+	for _, name := range []string{"cone", "cylinder", "sphere", "torus"} {
+		p, err := ParseFile("../../sample_basic_shapes/" + name + ".pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The lines by edge and whether they fold.
+		lines := func() []string {
+			var out []string
+			for part, obj := range p.PartObjects() {
+				for i := range part.Lines {
+					line := &part.Lines[i]
+					v1, v2 := obj.LineEnds(line)
+					if v1 == nil {
+						t.Fatalf("%s: line doesn't resolve", name)
+					}
+					e := obj.EdgeIndex(v1.IDVertex, v2.IDVertex)
+					out = append(out, fmt.Sprintf("%d %t", e, line.IsConnectingFaces))
+					if line.IsConnectingFaces && line.Type != 0 {
+						// Rebuilt folds follow the angle between the faces.
+						angle, want := obj.foldAngle(obj.Edges[e]), int32(2)
+						switch {
+						case math.Abs(angle) < p.flatFoldAngle():
+							want = 3
+						case angle > 0:
+							want = 1
+						}
+						if line.Type != want {
+							t.Errorf("%s: fold on edge %d at %.1f° has type %d, want %d", name, e, angle, line.Type, want)
+						}
+					}
+				}
+			}
+			slices.Sort(out)
+			return out
+		}
+		want := lines()
+		if n := p.RebuildLines(); n != len(want) {
+			t.Errorf("%s: rebuilt %d lines, want %d", name, n, len(want))
+		}
+		if got := lines(); !slices.Equal(got, want) {
+			t.Errorf("%s: rebuilt lines\n%v\nwant\n%v", name, got, want)
+		}
+	}
+}
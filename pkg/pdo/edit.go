@@ -0,0 +1,265 @@
+package pdo
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// NameParts gives parts a name of the form "<object>_partNN", numbered per
+// object from 01. Only blank names are replaced unless overwrite is set.
+// It returns the number of parts named.
+func (p *PDO) NameParts(overwrite bool) int {
+	named := 0
+	counts := map[int32]int{}
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		counts[part.ObjectIndex]++
+		if part.Name != "" && !overwrite {
+			continue
+		}
+		objName := fmt.Sprintf("object%d", part.ObjectIndex)
+		if part.ObjectIndex >= 0 && int(part.ObjectIndex) < len(p.Objects) && p.Objects[part.ObjectIndex].Name != "" {
+			objName = p.Objects[part.ObjectIndex].Name
+		}
+		part.Name = fmt.Sprintf("%s_part%02d", objName, counts[part.ObjectIndex])
+		named++
+	}
+	return named
+}
+
+// EdgeIndex returns the index of the edge between two 3D vertices, or -1.
+func (obj *Object) EdgeIndex(v1, v2 int32) int {
+	for i, e := range obj.Edges {
+		if (e.Vertex1Index == v1 && e.Vertex2Index == v2) || (e.Vertex1Index == v2 && e.Vertex2Index == v1) {
+			return i
+		}
+	}
+	return -1
+}
+
+// EdgeOrder selects how RenumberEdges orders edge IDs.
+type EdgeOrder int
+
+const (
+	// EdgesByPart numbers edges part by part, so each part's cut edges get
+	// consecutive IDs.
+	EdgesByPart EdgeOrder = iota
+	// EdgesByPosition numbers edges across the whole layout, page by page
+	// and from top to bottom, left to right within a page.
+	EdgesByPosition
+)
+
+// RenumberEdges reorders the edges of every object so that edge IDs (the
+// 1-based edge index shown on templates) run sequentially over the cut edges
+// of the layout. Edges that aren't cut keep their relative order after them.
+func (p *PDO) RenumberEdges(order EdgeOrder) {
+	dims := p.PageDims()
+
+	// Where each cut edge first shows up, per object and edge index.
+	// Both sides of a split edge are cut lines, the earlier one counts.
+	type cutEdge struct {
+		seq            int
+		page, row, col float64
+	}
+	before := func(a, b cutEdge) int {
+		if order == EdgesByPosition {
+			return cmp.Or(cmp.Compare(a.page, b.page), cmp.Compare(a.row, b.row), cmp.Compare(a.col, b.col))
+		}
+		return cmp.Compare(a.seq, b.seq)
+	}
+	cuts := make([]map[int]cutEdge, len(p.Objects))
+	for i := range cuts {
+		cuts[i] = map[int]cutEdge{}
+	}
+
+	seq := 0
+	lookups := p.Lookups()
+	for part := range p.PartObjects() {
+		lk := lookups[part.ObjectIndex]
+		for line := range part.VisibleLines() {
+			if line.Type != 0 {
+				continue
+			}
+			v1, v2 := lk.LineEnds(line)
+			if v1 == nil {
+				continue
+			}
+			e := lk.EdgeIndex(v1.IDVertex, v2.IDVertex)
+			if e < 0 {
+				continue
+			}
+			x := (v1.X+v2.X)/2 + part.BoundingBox.Left
+			y := (v1.Y+v2.Y)/2 + part.BoundingBox.Top
+			page := math.Floor(y/dims.ClippedHeight)*1e6 + math.Floor(x/dims.ClippedWidth) // Row by row
+			c := cutEdge{seq: seq, page: page, row: y, col: x}
+			seq++
+
+			objCuts := cuts[part.ObjectIndex]
+			if prev, ok := objCuts[e]; !ok || before(c, prev) < 0 {
+				objCuts[e] = c
+			}
+		}
+	}
+
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		sorted := make([]int, 0, len(cuts[oi]))
+		for e := range cuts[oi] {
+			sorted = append(sorted, e)
+		}
+		slices.SortFunc(sorted, func(a, b int) int { return before(cuts[oi][a], cuts[oi][b]) })
+
+		edges := make([]Edge, 0, len(obj.Edges))
+		for _, e := range sorted {
+			edges = append(edges, obj.Edges[e])
+		}
+		for i, e := range obj.Edges {
+			if _, ok := cuts[oi][i]; !ok {
+				edges = append(edges, e)
+			}
+		}
+		obj.Edges = edges
+	}
+}
+
+// SetText replaces the lines of text block i, as when translating a
+// template. Lines are checked against the file's string encoding: files
+// without multi-byte strings store Shift-JIS, which has no characters for
+// scripts such as Korean, Arabic or Thai.
+func (p *PDO) SetText(i int, lines []string) error {
+	if i < 0 || i >= len(p.TextBlocks) {
+		return fmt.Errorf("text block %d out of range, the file has %d", i, len(p.TextBlocks))
+	}
+	multiByte := p.Header.MultiByteChars != 0
+	if p.source != nil {
+		multiByte = p.source.multiByte
+	}
+	for _, line := range lines {
+		if _, err := EncodeString(line, 0, multiByte, false); err != nil {
+			return fmt.Errorf("text block %d: %w", i, err)
+		}
+	}
+	p.TextBlocks[i].Lines = slices.Clone(lines)
+	return nil
+}
+
+// AddImage places a picture on the pages at bb, in layout mm. It is drawn
+// over the parts when over is set, beneath them otherwise; PDOs built from
+// scratch have all images over the parts. Transparent pixels are blended
+// onto white paper, as textures have no alpha channel.
+func (p *PDO) AddImage(img image.Image, bb Rect, over bool) error {
+	if bb.Width <= 0 || bb.Height <= 0 {
+		return fmt.Errorf("invalid image size %gx%g mm", bb.Width, bb.Height)
+	}
+	b := img.Bounds()
+	if b.Empty() {
+		return fmt.Errorf("%w: empty image", ErrBadTexture)
+	}
+	flat := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, b.Min, draw.Over)
+
+	pi := Image{BoundingBox: bb}
+	if err := pi.Texture.SetImage(flat); err != nil {
+		return err
+	}
+	at := len(p.Images)
+	if p.source != nil && over {
+		at = min(p.source.overImages, len(p.Images))
+		p.source.overImages = at + 1
+	}
+	p.Images = slices.Insert(p.Images, at, pi)
+	return nil
+}
+
+// SetDesignerID replaces the ID of the designer who published the file, ""
+// clears it. Version 4 files have no designer ID.
+func (p *PDO) SetDesignerID(id string) error {
+	if p.Header.Version == PDO_V4 && id != "" {
+		return fmt.Errorf("version 4 files have no designer ID")
+	}
+	if _, err := EncodeString(id, 0, p.Header.MultiByteChars != 0, false); err != nil {
+		return err
+	}
+	p.Header.DesignerID = id
+	return nil
+}
+
+// SetKey replaces the license key stored in the header, "" clears it.
+func (p *PDO) SetKey(key string) error {
+	if _, err := EncodeString(key, 0, p.Header.MultiByteChars != 0, false); err != nil {
+		return err
+	}
+	p.Header.Key = key
+	return nil
+}
+
+// MaterialIndex finds a material by name, or else by its index from 0.
+func (p *PDO) MaterialIndex(ref string) (int, error) {
+	for i, m := range p.Materials {
+		if m.Name == ref {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(ref); err == nil && i >= 0 && i < len(p.Materials) {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no material %q", ref)
+}
+
+// ObjectIndex finds an object by name, or else by its index from 0.
+func (p *PDO) ObjectIndex(ref string) (int, error) {
+	for i, o := range p.Objects {
+		if o.Name == ref {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(ref); err == nil && i >= 0 && i < len(p.Objects) {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no object %q", ref)
+}
+
+// PartIndex finds a part by name, or else by its index from 0.
+func (p *PDO) PartIndex(ref string) (int, error) {
+	for i, part := range p.Parts {
+		if part.Name != "" && part.Name == ref {
+			return i, nil
+		}
+	}
+	if i, err := strconv.Atoi(ref); err == nil && i >= 0 && i < len(p.Parts) {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no part %q", ref)
+}
+
+// ColorTarget selects the colors of a material SetColor changes.
+type ColorTarget int
+
+const (
+	// ColorBoth changes the colors of the template and the 3D view.
+	ColorBoth ColorTarget = iota
+	// Color2DOnly changes the color faces are printed with.
+	Color2DOnly
+	// Color3DOnly changes the colors of the 3D view.
+	Color3DOnly
+)
+
+// SetColor recolors a material with an RGB color, components 0-1, keeping
+// the alpha values. In 3D the light color stays as it is and the other
+// colors take the new one, as in materials Pepakura creates.
+func (m *Material) SetColor(rgb [3]float32, target ColorTarget) {
+	if target != Color3DOnly {
+		copy(m.Color2DRGBA[:3], rgb[:])
+	}
+	if target != Color2DOnly {
+		m.Color3D.Material.SetRGB(rgb)
+		m.Color3D.Model.SetRGB(rgb)
+		m.Color3D.Diffuse.SetRGB(rgb)
+	}
+}
@@ -0,0 +1,246 @@
+package pdo
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNameParts(t *testing.T) {
+	p := &PDO{
+		Objects: []Object{{Name: "body"}, {Name: ""}},
+		Parts: []Part{
+			{ObjectIndex: 0},
+			{ObjectIndex: 1},
+			{ObjectIndex: 0, Name: "lid"},
+			{ObjectIndex: 0},
+		},
+	}
+	if n := p.NameParts(false); n != 3 {
+		t.Errorf("NameParts(false) = %d, want 3", n)
+	}
+	want := []string{"body_part01", "object1_part01", "lid", "body_part03"}
+	for i, part := range p.Parts {
+		if part.Name != want[i] {
+			t.Errorf("part %d: name %q, want %q", i, part.Name, want[i])
+		}
+	}
+
+	if n := p.NameParts(true); n != 4 || p.Parts[2].Name != "body_part02" {
+		t.Errorf("NameParts(true) = %d, part 2 %q", n, p.Parts[2].Name)
+	}
+}
+
+func TestRenumberEdges(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cylinder.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, order := range []EdgeOrder{EdgesByPart, EdgesByPosition} {
+		p.RenumberEdges(order)
+
+		// Every cut line must map to an edge among the first IDs.
+		cutEdges := map[int]bool{}
+		for part, obj := range p.PartObjects() {
+			for line := range part.VisibleLines() {
+				if line.Type != 0 {
+					continue
+				}
+				v1, v2 := obj.LineEnds(line)
+				if v1 == nil {
+					continue
+				}
+				if e := obj.EdgeIndex(v1.IDVertex, v2.IDVertex); e >= 0 {
+					cutEdges[e] = true
+				}
+			}
+		}
+		if len(cutEdges) == 0 {
+			t.Fatal("no cut edges found")
+		}
+		for e := range cutEdges {
+			if e >= len(cutEdges) {
+				t.Errorf("order %d: cut edge has index %d, want below %d", order, e, len(cutEdges))
+			}
+		}
+	}
+
+	// The result still writes and parses.
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewParser(bytes.NewReader(buf.Bytes())).Load(); err != nil {
+		t.Fatalf("parse renumbered file: %v", err)
+	}
+}
+
+func TestSetText(t *testing.T) {
+	p := &PDO{TextBlocks: []TextBlock{{Lines: []string{"組み立て"}}}}
+	if err := p.SetText(0, []string{"Assembly", "説明"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.TextBlocks[0].Lines; len(got) != 2 || got[0] != "Assembly" {
+		t.Errorf("lines %q", got)
+	}
+	if err := p.SetText(0, []string{"조립 설명"}); err == nil {
+		t.Error("Hangul accepted in a Shift-JIS file")
+	}
+	p.Header.MultiByteChars = 1
+	if err := p.SetText(0, []string{"조립 설명"}); err != nil {
+		t.Error(err)
+	}
+	if err := p.SetText(1, nil); err == nil {
+		t.Error("missing text block accepted")
+	}
+}
+
+func TestAddImage(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(1, 1, color.NRGBA{255, 0, 0, 255})
+	bb := Rect{Left: 10, Top: 20, Width: 40, Height: 20}
+	if err := p.AddImage(img, bb, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AddImage(img, bb, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewParser(bytes.NewReader(buf.Bytes()))
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := parser.PDO.source.overImages; len(parser.PDO.Images) != 2 || got != 1 {
+		t.Fatalf("%d images, %d over the parts", len(parser.PDO.Images), got)
+	}
+	got, err := parser.PDO.Images[0].Texture.GetImage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Transparent pixels turn white.
+	if r, g, b, _ := got.At(0, 0).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+		t.Errorf("transparent pixel is %x %x %x", r, g, b)
+	}
+	if r, g, _, _ := got.At(1, 1).RGBA(); r != 0xffff || g != 0 {
+		t.Errorf("red pixel is %x %x", r, g)
+	}
+	if err := p.AddImage(img, Rect{}, true); err == nil {
+		t.Error("image without a size accepted")
+	}
+}
+
+func TestAddImage_Clone(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	bb := Rect{Width: 10, Height: 10}
+	if err := p.AddImage(img, bb, false); err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	if err := Write(&want, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Clone().AddImage(img, bb, true); err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if err := Write(&got, p); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("adding an image to a clone changed how the original is written")
+	}
+}
+
+func TestObjectIndex(t *testing.T) {
+	p := &PDO{Objects: []Object{{Name: "body"}, {Name: "1"}}}
+	for ref, want := range map[string]int{"body": 0, "1": 1, "0": 0} {
+		if i, err := p.ObjectIndex(ref); err != nil || i != want {
+			t.Errorf("ObjectIndex(%q) = %d, %v, want %d", ref, i, err, want)
+		}
+	}
+	if _, err := p.ObjectIndex("hat"); err == nil {
+		t.Error("missing object found")
+	}
+}
+
+func TestPartIndex(t *testing.T) {
+	p := &PDO{Parts: []Part{{Name: "wing"}, {}, {Name: "2"}}}
+	for ref, want := range map[string]int{"wing": 0, "1": 1, "2": 2} {
+		if i, err := p.PartIndex(ref); err != nil || i != want {
+			t.Errorf("PartIndex(%q) = %d, %v, want %d", ref, i, err, want)
+		}
+	}
+	if _, err := p.PartIndex(""); err == nil {
+		t.Error("part found by empty name")
+	}
+}
+
+func TestSetDesignerID(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetDesignerID("studio-42"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetKey(""); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewParser(bytes.NewReader(buf.Bytes()))
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if h := parser.PDO.Header; h.DesignerID != "studio-42" || h.Key != "" {
+		t.Errorf("read designer %q and key %q", h.DesignerID, h.Key)
+	}
+
+	p.Header.Version = PDO_V4
+	if err := p.SetDesignerID("studio-42"); err == nil {
+		t.Error("set a designer ID in a version 4 file")
+	}
+	if err := p.SetDesignerID(""); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetMaterialColor(t *testing.T) {
+	p := &PDO{Materials: []Material{{Name: "skin"}, {Name: "1"}}}
+	for ref, want := range map[string]int{"skin": 0, "1": 1, "0": 0} {
+		if i, err := p.MaterialIndex(ref); err != nil || i != want {
+			t.Errorf("MaterialIndex(%q) = %d, %v, want %d", ref, i, err, want)
+		}
+	}
+	if _, err := p.MaterialIndex("2"); err == nil {
+		t.Error("missing material found")
+	}
+
+	m := &p.Materials[0]
+	m.Color2DRGBA[3] = 1
+	m.Color3D.Light = RGBA{1, 1, 1, 1}
+	m.SetColor([3]float32{1, 0.5, 0}, Color2DOnly)
+	if m.Color2DRGBA != [4]float32{1, 0.5, 0, 1} || m.Color3D.Model.R != 0 {
+		t.Errorf("2D only: %v, 3D %v", m.Color2DRGBA, m.Color3D)
+	}
+	m.SetColor([3]float32{0, 0, 1}, ColorBoth)
+	if m.Color2DRGBA != [4]float32{0, 0, 1, 1} || m.Color3D.Model != (RGBA{0, 0, 1, 0}) || m.Color3D.Light.R != 1 {
+		t.Errorf("both: %v, 3D %v", m.Color2DRGBA, m.Color3D)
+	}
+}
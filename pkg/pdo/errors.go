@@ -0,0 +1,35 @@
+package pdo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Error categories returned by the parser, texture decoder and edit operations.
+// Returned errors wrap one of these, so callers can test them with errors.Is.
+var (
+	// ErrInvalidMagic means the input is not a PDO file.
+	ErrInvalidMagic = errors.New("pdo: invalid file magic")
+	// ErrUnsupportedVersion means the PDO sub-version is not one of 4, 5 or 6.
+	ErrUnsupportedVersion = errors.New("pdo: unsupported version")
+	// ErrLocked means an operation was refused because of the file's lock flags.
+	ErrLocked = errors.New("pdo: file is locked")
+	// ErrTruncated means the input ended before the structure being read.
+	ErrTruncated = errors.New("pdo: unexpected end of data")
+	// ErrBadTexture means texture data is missing or can't be decoded.
+	ErrBadTexture = errors.New("pdo: bad texture data")
+	// ErrNoFlap means an edge has no glue flap to operate on.
+	ErrNoFlap = errors.New("pdo: edge has no flap")
+	// ErrLimitExceeded means the file needs more than Options.Limits allow,
+	// or holds a negative count or line type.
+	ErrLimitExceeded = errors.New("pdo: limit exceeded")
+)
+
+// wrapReadError tags end-of-input errors with ErrTruncated.
+func wrapReadError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: %w", ErrTruncated, err)
+	}
+	return err
+}
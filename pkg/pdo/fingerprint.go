@@ -0,0 +1,146 @@
+package pdo
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"math"
+)
+
+// Fingerprint hashes the content of a model: the objects with their
+// vertices, faces and edges, the materials with the decoded pixels of their
+// textures, and the unfolding with its parts, lines and scale. It leaves
+// out what changes without changing the model: the header with the
+// version, designer ID, key and locale, the print settings with author and
+// comment, object visibility, stored normals, page text and images and the
+// thumbnail. Files re-saved or recompressed keep their fingerprint; it is
+// the hex SHA-256 of a stable encoding, the same across versions of
+// pdo-tools.
+func Fingerprint(p *PDO) string {
+	h := fingerprintHash{sha256.New()}
+	h.int(len(p.Objects))
+	for i := range p.Objects {
+		o := &p.Objects[i]
+		h.string(o.Name)
+		h.int(len(o.Vertices))
+		for _, v := range o.Vertices {
+			h.float(v.X)
+			h.float(v.Y)
+			h.float(v.Z)
+		}
+		h.int(len(o.Faces))
+		for j := range o.Faces {
+			f := &o.Faces[j]
+			h.int(int(f.MaterialIndex))
+			h.int(int(f.PartIndex))
+			h.int(len(f.Vertices))
+			for _, v := range f.Vertices {
+				h.int(int(v.IDVertex))
+				h.float(v.X)
+				h.float(v.Y)
+				h.float(v.U)
+				h.float(v.V)
+				h.int(int(v.Flap))
+				h.float(v.FlapHeight)
+				h.float(v.FlapAAngle)
+				h.float(v.FlapBAngle)
+				for _, c := range append(v.FlapLineColor[:], v.FoldLineColor[:]...) {
+					h.float(float64(c))
+				}
+			}
+		}
+		h.int(len(o.Edges))
+		for _, e := range o.Edges {
+			h.int(int(e.Face1Index))
+			h.int(int(e.Face2Index))
+			h.int(int(e.Vertex1Index))
+			h.int(int(e.Vertex2Index))
+			h.int(int(e.ConnectsFaces))
+		}
+	}
+
+	h.int(len(p.Materials))
+	for i := range p.Materials {
+		m := &p.Materials[i]
+		h.string(m.Name)
+		for _, c := range []RGBA{m.Color3D.Material, m.Color3D.Model, m.Color3D.Light, m.Color3D.Diffuse} {
+			h.float(float64(c.R))
+			h.float(float64(c.G))
+			h.float(float64(c.B))
+			h.float(float64(c.A))
+		}
+		for _, c := range m.Color2DRGBA {
+			h.float(float64(c))
+		}
+		if !m.HasTexture {
+			h.int(-1)
+			continue
+		}
+		t := &m.Texture
+		h.int(int(t.Width))
+		h.int(int(t.Height))
+		// Recompressing keeps the pixels; undecodable data is hashed as
+		// stored.
+		if rgb, err := t.rgb(); err == nil {
+			h.bytes(rgb)
+		} else {
+			h.bytes(t.RawData)
+		}
+	}
+
+	h.float(p.Unfold.Scale)
+	h.int(len(p.Parts))
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		h.int(int(part.ObjectIndex))
+		h.string(part.Name)
+		h.float(part.BoundingBox.Left)
+		h.float(part.BoundingBox.Top)
+		h.float(part.BoundingBox.Width)
+		h.float(part.BoundingBox.Height)
+		h.int(len(part.Lines))
+		for _, l := range part.Lines {
+			h.bool(l.Hidden)
+			h.int(int(l.Type))
+			h.int(int(l.FaceIndex))
+			h.int(int(l.VertexIndex))
+			h.bool(l.IsConnectingFaces)
+			h.int(int(l.Face2Index))
+			h.int(int(l.Vertex2Index))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprintHash writes values to a hash in a fixed little-endian
+// encoding, lengths before strings and bytes.
+type fingerprintHash struct {
+	hash.Hash
+}
+
+func (h fingerprintHash) int(v int) {
+	h.Write(binary.LittleEndian.AppendUint64(nil, uint64(int64(v))))
+}
+
+func (h fingerprintHash) float(v float64) {
+	// Adding 0 turns -0 into 0.
+	h.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v+0)))
+}
+
+func (h fingerprintHash) bool(v bool) {
+	if v {
+		h.int(1)
+	} else {
+		h.int(0)
+	}
+}
+
+func (h fingerprintHash) string(s string) {
+	h.bytes([]byte(s))
+}
+
+func (h fingerprintHash) bytes(b []byte) {
+	h.int(len(b))
+	h.Write(b)
+}
@@ -0,0 +1,73 @@
+package pdo
+
+import (
+	"bytes"
+	"compress/flate"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 2, color.RGBA{200, 10, 10, 255})
+	p.Materials = append(p.Materials, Material{Name: "paper", HasTexture: true})
+	if err := p.Materials[0].Texture.SetImage(img); err != nil {
+		t.Fatal(err)
+	}
+	want := Fingerprint(p)
+	if len(want) != 64 || Fingerprint(p) != want {
+		t.Fatalf("fingerprint %q is not a stable SHA-256", want)
+	}
+
+	// Written and parsed again, with other metadata and the texture
+	// compressed differently.
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewParser(bytes.NewReader(buf.Bytes()))
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	q := parser.PDO
+	q.Header.DesignerID = "studio-42"
+	q.Settings.AuthorName = "Ana"
+	q.Settings.Comment = "Reprint"
+	q.Settings.PageType++
+	q.Objects[0].Visible ^= 1
+	q.Thumbnail = []byte("\x89PNG")
+	raw, err := q.Materials[0].Texture.rgb()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var z bytes.Buffer
+	fw, _ := flate.NewWriter(&z, flate.NoCompression)
+	fw.Write(raw)
+	fw.Close()
+	q.Materials[0].Texture.RawData = z.Bytes()
+	if got := Fingerprint(q); got != want {
+		t.Errorf("fingerprint changed with the metadata to %s", got)
+	}
+
+	changes := map[string]func(p *PDO){
+		"vertex":  func(p *PDO) { p.Objects[0].Vertices[3].Z += 0.001 },
+		"texture": func(p *PDO) { img.Set(0, 0, color.White); p.Materials[0].Texture.SetImage(img) },
+		"color":   func(p *PDO) { p.Materials[0].Color2DRGBA[1] = 0.5 },
+		"layout":  func(p *PDO) { p.Parts[0].BoundingBox.Left += 10 },
+		"line":    func(p *PDO) { p.Parts[0].Lines[0].Hidden = !p.Parts[0].Lines[0].Hidden },
+		"scale":   func(p *PDO) { p.Unfold.Scale *= 2 },
+		"name":    func(p *PDO) { p.Objects[0].Name += "2" },
+	}
+	for name, change := range changes {
+		c := p.Clone()
+		change(c)
+		if Fingerprint(c) == want {
+			t.Errorf("fingerprint kept after changing the %s", name)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package pdo
+
+import (
+	"fmt"
+	"math"
+)
+
+// minFlapScale is how far ResizeFlaps lowers a flap, relative to the
+// requested height, before giving up on avoiding overlaps.
+const minFlapScale = 1.0 / 8
+
+// vec2 is a 2D point or direction in the unfolded layout, in mm.
+type vec2 struct {
+	X, Y float64
+}
+
+// ResizeFlaps sets the height (mm) and both side angles (radians) of every
+// flap. Flaps on cut edges are lowered step by step where they would cross
+// other lines of their part; the flap is kept at the lowest step if it still
+// overlaps. It returns the number of flaps that were lowered.
+func (p *PDO) ResizeFlaps(height, angle float64) int {
+	for _, face := range p.AllFaces() {
+		for i := range face.Vertices {
+			v := &face.Vertices[i]
+			if v.Flap != 0 {
+				v.FlapHeight = height
+				v.FlapAAngle = angle
+				v.FlapBAngle = angle
+			}
+		}
+	}
+
+	lowered := 0
+	for part, obj := range p.PartObjects() {
+		var segs [][2]vec2
+		for line := range part.VisibleLines() {
+			if line.Type >= 3 {
+				continue
+			}
+			if v1, v2 := obj.LineEnds(line); v1 != nil {
+				segs = append(segs, [2]vec2{{v1.X, v1.Y}, {v2.X, v2.Y}})
+			}
+		}
+
+		for line := range part.VisibleLines() {
+			if line.Type != 0 || line.IsConnectingFaces {
+				continue
+			}
+			v1, v2 := obj.LineEnds(line)
+			if v1 == nil || v1.Flap == 0 {
+				continue
+			}
+			out := outwardNormal(obj, line.FaceIndex, v1, v2)
+			h := height
+			for h > height*minFlapScale && flapCrosses(v1, v2, out, h, angle, segs) {
+				h /= 2
+			}
+			if h < height {
+				v1.FlapHeight = h
+				lowered++
+			}
+		}
+	}
+	return lowered
+}
+
+// outwardNormal returns the unit normal of the edge v1-v2 pointing away from the face.
+func outwardNormal(obj *Object, faceIdx int32, v1, v2 *Face2DVertex) vec2 {
+	dx, dy := v2.X-v1.X, v2.Y-v1.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return vec2{}
+	}
+	n := vec2{-dy / length, dx / length}
+
+	var cx, cy float64
+	verts := obj.Faces[faceIdx].Vertices
+	for _, v := range verts {
+		cx += v.X / float64(len(verts))
+		cy += v.Y / float64(len(verts))
+	}
+	if (cx-v1.X)*n.X+(cy-v1.Y)*n.Y > 0 {
+		n.X, n.Y = -n.X, -n.Y
+	}
+	return n
+}
+
+// flapCrosses reports whether a flap of the given height and side angle on
+// edge v1-v2 crosses any segment not touching the edge's end points.
+func flapCrosses(v1, v2 *Face2DVertex, out vec2, h, angle float64, segs [][2]vec2) bool {
+	a, b := vec2{v1.X, v1.Y}, vec2{v2.X, v2.Y}
+	top1, top2, ok := flapTop(a, b, out, h, angle, angle)
+	if !ok {
+		return false
+	}
+	outline := [][2]vec2{{a, top1}, {top1, top2}, {top2, b}}
+
+	for _, s := range segs {
+		if near(s[0], a) || near(s[0], b) || near(s[1], a) || near(s[1], b) {
+			continue
+		}
+		for _, o := range outline {
+			if segmentsIntersect(o[0], o[1], s[0], s[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flapTop returns the outer corners of a flap on edge a-b with the given
+// height and side angles at a and b. It reports false for a zero-length edge.
+func flapTop(a, b, out vec2, h, angleA, angleB float64) (vec2, vec2, bool) {
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if length == 0 {
+		return vec2{}, vec2{}, false
+	}
+	u := vec2{(b.X - a.X) / length, (b.Y - a.Y) / length}
+	run := func(angle float64) float64 {
+		r := 0.0
+		if angle > 0 && angle < math.Pi/2 {
+			r = h / math.Tan(angle)
+		}
+		return math.Min(r, length/2) // Sides meeting early make a triangle
+	}
+	runA, runB := run(angleA), run(angleB)
+	top1 := vec2{a.X + u.X*runA + out.X*h, a.Y + u.Y*runA + out.Y*h}
+	top2 := vec2{b.X - u.X*runB + out.X*h, b.Y - u.Y*runB + out.Y*h}
+	return top1, top2, true
+}
+
+// near reports whether two points coincide, allowing for rounding between faces.
+func near(a, b vec2) bool {
+	return math.Abs(a.X-b.X) < 1e-6 && math.Abs(a.Y-b.Y) < 1e-6
+}
+
+// segmentsIntersect reports whether segments p1-p2 and q1-q2 properly cross.
+func segmentsIntersect(p1, p2, q1, q2 vec2) bool {
+	cross := func(o, a, b vec2) float64 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+	d1 := cross(q1, q2, p1)
+	d2 := cross(q1, q2, p2)
+	d3 := cross(p1, p2, q1)
+	d4 := cross(p1, p2, q2)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// edgeStart returns the 2D vertex of a face at which the edge starts in the
+// face loop. Flap data for the edge is stored on that vertex.
+func (obj *Object) edgeStart(faceIdx int32, e Edge) *Face2DVertex {
+	if faceIdx < 0 || int(faceIdx) >= len(obj.Faces) {
+		return nil
+	}
+	verts := obj.Faces[faceIdx].Vertices
+	for i := range verts {
+		a, b := verts[i].IDVertex, verts[(i+1)%len(verts)].IDVertex
+		if (a == e.Vertex1Index && b == e.Vertex2Index) || (a == e.Vertex2Index && b == e.Vertex1Index) {
+			return &verts[i]
+		}
+	}
+	return nil
+}
+
+// FlapFace returns the face whose side of an edge carries the glue flap, or -1
+// when the edge has none. When both sides are flagged, as Pepakura usually
+// stores them, the edge's first face wins.
+func (obj *Object) FlapFace(edgeIdx int) int32 {
+	if edgeIdx < 0 || edgeIdx >= len(obj.Edges) {
+		return -1
+	}
+	e := obj.Edges[edgeIdx]
+	if e.Face2Index < 0 {
+		return -1 // Border of an open mesh, nothing to glue to
+	}
+	v1, v2 := obj.edgeStart(e.Face1Index, e), obj.edgeStart(e.Face2Index, e)
+	switch {
+	case v1 != nil && v1.Flap != 0:
+		return e.Face1Index
+	case v2 != nil && v2.Flap != 0:
+		return e.Face2Index
+	}
+	return -1
+}
+
+// lineHasFlap reports whether a cut line, with the ends LineEnds returns,
+// carries the glue flap of its edge. Of the two sides of an edge only the
+// one FlapFace picks does.
+func (obj *Object) lineHasFlap(line *Line, v1, v2 *Face2DVertex) bool {
+	if line.Type != 0 || line.IsConnectingFaces || v1 == nil || v2 == nil || v1.Flap == 0 || v1.FlapHeight <= 0 {
+		return false
+	}
+	return obj.FlapFace(obj.EdgeIndex(v1.IDVertex, v2.IDVertex)) == line.FaceIndex
+}
+
+// MoveFlap moves the glue flap of an edge to the other face of the edge pair.
+// The flap keeps its height, color and shape; the side angles swap because
+// the edge runs the other way around the other face.
+func (p *PDO) MoveFlap(objIdx, edgeIdx int) error {
+	if objIdx < 0 || objIdx >= len(p.Objects) {
+		return fmt.Errorf("%w: object %d", ErrNoFlap, objIdx)
+	}
+	obj := &p.Objects[objIdx]
+	face := obj.FlapFace(edgeIdx)
+	if face < 0 {
+		return fmt.Errorf("%w: object %d, edge %d", ErrNoFlap, objIdx, edgeIdx+1)
+	}
+
+	e := obj.Edges[edgeIdx]
+	other := e.Face2Index
+	if face == e.Face2Index {
+		other = e.Face1Index
+	}
+	from, to := obj.edgeStart(face, e), obj.edgeStart(other, e)
+	if to == nil {
+		return fmt.Errorf("%w: object %d, edge %d has no opposite face", ErrNoFlap, objIdx, edgeIdx+1)
+	}
+
+	to.Flap = 1
+	to.FlapHeight = from.FlapHeight
+	to.FlapAAngle, to.FlapBAngle = from.FlapBAngle, from.FlapAAngle
+	to.FlapLineColor = from.FlapLineColor
+	from.Flap = 0
+	return nil
+}
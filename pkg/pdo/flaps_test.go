@@ -0,0 +1,70 @@
+package pdo
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestResizeFlaps(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lowered := p.ResizeFlaps(1, math.Pi/4); lowered != 0 {
+		t.Errorf("1mm flaps: %d lowered, want 0", lowered)
+	}
+	for _, face := range p.AllFaces() {
+		for _, v := range face.Vertices {
+			if v.Flap != 0 && (v.FlapHeight != 1 || v.FlapAAngle != math.Pi/4 || v.FlapBAngle != math.Pi/4) {
+				t.Fatalf("flap not resized: %+v", v)
+			}
+		}
+	}
+
+	// Flaps as tall as the whole layout must cross something.
+	if lowered := p.ResizeFlaps(500, math.Pi/4); lowered == 0 {
+		t.Error("500mm flaps: none lowered")
+	}
+}
+
+func TestMoveFlap(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := &p.Objects[0]
+
+	edge := -1
+	for i := range obj.Edges {
+		if obj.FlapFace(i) >= 0 {
+			edge = i
+			break
+		}
+	}
+	if edge < 0 {
+		t.Fatal("no edge with a flap")
+	}
+	e := obj.Edges[edge]
+	if got := obj.FlapFace(edge); got != e.Face1Index {
+		t.Fatalf("flap on face %d, want first face %d", got, e.Face1Index)
+	}
+
+	if err := p.MoveFlap(0, edge); err != nil {
+		t.Fatal(err)
+	}
+	if got := obj.FlapFace(edge); got != e.Face2Index {
+		t.Errorf("after move: flap on face %d, want %d", got, e.Face2Index)
+	}
+	if err := p.MoveFlap(0, edge); err != nil {
+		t.Fatal(err)
+	}
+	if got := obj.FlapFace(edge); got != e.Face1Index {
+		t.Errorf("after moving back: flap on face %d, want %d", got, e.Face1Index)
+	}
+
+	if err := p.MoveFlap(0, len(obj.Edges)); !errors.Is(err, ErrNoFlap) {
+		t.Errorf("out of range edge: got %v, want ErrNoFlap", err)
+	}
+}
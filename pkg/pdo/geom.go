@@ -0,0 +1,121 @@
+package pdo
+
+import "math"
+
+// Right returns the x coordinate of the right edge.
+func (r Rect) Right() float64 { return r.Left + r.Width }
+
+// Bottom returns the y coordinate of the bottom edge.
+func (r Rect) Bottom() float64 { return r.Top + r.Height }
+
+// Empty reports whether the rectangle has no area.
+func (r Rect) Empty() bool { return r.Width <= 0 || r.Height <= 0 }
+
+// Contains reports whether a point lies within the rectangle, edges
+// included.
+func (r Rect) Contains(x, y float64) bool {
+	return x >= r.Left && x <= r.Right() && y >= r.Top && y <= r.Bottom()
+}
+
+// Intersect returns the overlap of two rectangles, the zero Rect when they
+// don't overlap.
+func (r Rect) Intersect(o Rect) Rect {
+	left, top := max(r.Left, o.Left), max(r.Top, o.Top)
+	right, bottom := min(r.Right(), o.Right()), min(r.Bottom(), o.Bottom())
+	if right <= left || bottom <= top {
+		return Rect{}
+	}
+	return Rect{Left: left, Top: top, Width: right - left, Height: bottom - top}
+}
+
+// Union returns the smallest rectangle containing both. Empty rectangles
+// are left out.
+func (r Rect) Union(o Rect) Rect {
+	if r.Empty() {
+		return o
+	}
+	if o.Empty() {
+		return r
+	}
+	left, top := min(r.Left, o.Left), min(r.Top, o.Top)
+	return Rect{Left: left, Top: top, Width: max(r.Right(), o.Right()) - left, Height: max(r.Bottom(), o.Bottom()) - top}
+}
+
+// Add, Sub, Scale and Dot are the vector operations on points.
+func (v Vertex3D) Add(o Vertex3D) Vertex3D  { return Vertex3D{v.X + o.X, v.Y + o.Y, v.Z + o.Z} }
+func (v Vertex3D) Sub(o Vertex3D) Vertex3D  { return Vertex3D{v.X - o.X, v.Y - o.Y, v.Z - o.Z} }
+func (v Vertex3D) Scale(f float64) Vertex3D { return Vertex3D{v.X * f, v.Y * f, v.Z * f} }
+func (v Vertex3D) Dot(o Vertex3D) float64   { return v.X*o.X + v.Y*o.Y + v.Z*o.Z }
+
+// Cross returns the cross product v × o.
+func (v Vertex3D) Cross(o Vertex3D) Vertex3D {
+	return Vertex3D{v.Y*o.Z - v.Z*o.Y, v.Z*o.X - v.X*o.Z, v.X*o.Y - v.Y*o.X}
+}
+
+// Length returns the distance from the origin.
+func (v Vertex3D) Length() float64 { return math.Sqrt(v.Dot(v)) }
+
+// Dist returns the distance between two points.
+func (v Vertex3D) Dist(o Vertex3D) float64 { return v.Sub(o).Length() }
+
+// Dist returns the distance between two vertices on the template.
+func (v *Face2DVertex) Dist(o *Face2DVertex) float64 {
+	return math.Hypot(v.X-o.X, v.Y-o.Y)
+}
+
+// Size returns the extent of the box along each axis.
+func (b Box3D) Size() Vertex3D { return b.Max.Sub(b.Min) }
+
+// Center returns the middle of the box.
+func (b Box3D) Center() Vertex3D { return b.Min.Add(b.Max).Scale(0.5) }
+
+// extend grows the box to contain v.
+func (b *Box3D) extend(v Vertex3D) {
+	b.Min = Vertex3D{min(b.Min.X, v.X), min(b.Min.Y, v.Y), min(b.Min.Z, v.Z)}
+	b.Max = Vertex3D{max(b.Max.X, v.X), max(b.Max.Y, v.Y), max(b.Max.Z, v.Z)}
+}
+
+// Bounds returns the bounding box of the object's vertices, false when it
+// has none.
+func (obj *Object) Bounds() (Box3D, bool) {
+	if len(obj.Vertices) == 0 {
+		return Box3D{}, false
+	}
+	b := Box3D{Min: obj.Vertices[0], Max: obj.Vertices[0]}
+	for _, v := range obj.Vertices[1:] {
+		b.extend(v)
+	}
+	return b, true
+}
+
+// Bounds returns the bounding box of the vertices of all objects, false
+// when there are none.
+func (p *PDO) Bounds() (Box3D, bool) {
+	var box Box3D
+	found := false
+	for i := range p.Objects {
+		b, ok := p.Objects[i].Bounds()
+		switch {
+		case !ok:
+		case !found:
+			box, found = b, true
+		default:
+			box.extend(b.Min)
+			box.extend(b.Max)
+		}
+	}
+	return box, found
+}
+
+// PartBounds measures part i from its 2D vertices and flaps, in part
+// coordinates. The stored BoundingBox size isn't always accurate; a well
+// laid out part has this box at 0, 0 with the stored width and height.
+// It returns the zero Rect for parts without faces.
+func (p *PDO) PartBounds(i int) Rect {
+	points := p.partOutline(i)
+	if len(points) == 0 {
+		return Rect{}
+	}
+	lo, hi := bounds(points, func(v vec2) vec2 { return v })
+	return Rect{Left: lo.X, Top: lo.Y, Width: hi.X - lo.X, Height: hi.Y - lo.Y}
+}
@@ -0,0 +1,60 @@
+package pdo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRect(t *testing.T) {
+	a := Rect{Left: 0, Top: 0, Width: 10, Height: 20}
+	b := Rect{Left: 5, Top: 15, Width: 10, Height: 10}
+	if a.Right() != 10 || a.Bottom() != 20 {
+		t.Errorf("right %g, bottom %g", a.Right(), a.Bottom())
+	}
+	if !a.Contains(10, 20) || a.Contains(10.1, 5) {
+		t.Error("Contains wrong at the edges")
+	}
+	if got, want := a.Intersect(b), (Rect{Left: 5, Top: 15, Width: 5, Height: 5}); got != want {
+		t.Errorf("Intersect = %v, want %v", got, want)
+	}
+	if got := a.Intersect(Rect{Left: 20, Top: 0, Width: 1, Height: 1}); !got.Empty() {
+		t.Errorf("disjoint Intersect = %v", got)
+	}
+	if got, want := a.Union(b), (Rect{Left: 0, Top: 0, Width: 15, Height: 25}); got != want {
+		t.Errorf("Union = %v, want %v", got, want)
+	}
+	if got := (Rect{}).Union(b); got != b {
+		t.Errorf("Union with empty = %v", got)
+	}
+}
+
+func TestVertex3D(t *testing.T) {
+	x, y := Vertex3D{X: 1}, Vertex3D{Y: 1}
+	if got := x.Cross(y); got != (Vertex3D{Z: 1}) {
+		t.Errorf("x × y = %v", got)
+	}
+	if got := x.Add(y).Scale(3).Length(); math.Abs(got-3*math.Sqrt2) > 1e-12 {
+		t.Errorf("length %g", got)
+	}
+	if d := x.Dist(y); math.Abs(d-math.Sqrt2) > 1e-12 || x.Dot(y) != 0 {
+		t.Errorf("dist %g, dot %g", d, x.Dot(y))
+	}
+}
+
+func TestBounds(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok := p.Bounds()
+	if size := b.Size(); !ok || size.X <= 0 || size.Y <= 0 || size.Z <= 0 {
+		t.Fatalf("model bounds %v", b)
+	}
+	for i, part := range p.Parts {
+		got := p.PartBounds(i)
+		// The stored size includes the flaps, like the measured one.
+		if math.Abs(got.Width-part.BoundingBox.Width) > 0.5 || math.Abs(got.Height-part.BoundingBox.Height) > 0.5 {
+			t.Errorf("part %d measures %v, stored %v", i, got, part.BoundingBox)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package pdo
+
+import (
+	"iter"
+)
+
+// FaceRef locates a face within a PDO.
+type FaceRef struct {
+	Object int
+	Face   int
+}
+
+// AllFaces yields every face of every object together with its location.
+func (p *PDO) AllFaces() iter.Seq2[FaceRef, *Face] {
+	return func(yield func(FaceRef, *Face) bool) {
+		for oi := range p.Objects {
+			obj := &p.Objects[oi]
+			for fi := range obj.Faces {
+				if !yield(FaceRef{Object: oi, Face: fi}, &obj.Faces[fi]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// TexturedMaterials yields the index and material of every material with a texture.
+func (p *PDO) TexturedMaterials() iter.Seq2[int, *Material] {
+	return func(yield func(int, *Material) bool) {
+		for i := range p.Materials {
+			if !p.Materials[i].HasTexture {
+				continue
+			}
+			if !yield(i, &p.Materials[i]) {
+				return
+			}
+		}
+	}
+}
+
+// PartObjects yields every part together with the object it unfolds.
+// Parts referring to a missing object are skipped.
+func (p *PDO) PartObjects() iter.Seq2[*Part, *Object] {
+	return func(yield func(*Part, *Object) bool) {
+		for i := range p.Parts {
+			part := &p.Parts[i]
+			if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+				continue
+			}
+			if !yield(part, &p.Objects[part.ObjectIndex]) {
+				return
+			}
+		}
+	}
+}
+
+// VisibleLines yields the lines of the part that are not hidden.
+func (part *Part) VisibleLines() iter.Seq[*Line] {
+	return func(yield func(*Line) bool) {
+		for i := range part.Lines {
+			if part.Lines[i].Hidden {
+				continue
+			}
+			if !yield(&part.Lines[i]) {
+				return
+			}
+		}
+	}
+}
+
+// PartFaces yields the index and face of every face of obj belonging to the part with the given index.
+func (obj *Object) PartFaces(partIndex int) iter.Seq2[int, *Face] {
+	return func(yield func(int, *Face) bool) {
+		for i := range obj.Faces {
+			if int(obj.Faces[i].PartIndex) != partIndex {
+				continue
+			}
+			if !yield(i, &obj.Faces[i]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package pdo
+
+import (
+	"testing"
+)
+
+func TestIterators(t *testing.T) {
+	p := &PDO{
+		Objects: []Object{
+			{Faces: []Face{{PartIndex: 0}, {PartIndex: 1}}},
+			{Faces: []Face{{PartIndex: 1}}},
+		},
+		Materials: []Material{{Name: "plain"}, {Name: "tex", HasTexture: true}},
+		Parts: []Part{
+			{ObjectIndex: 0, Lines: []Line{{Hidden: true}, {Type: 1}, {Type: 2}}},
+			{ObjectIndex: 5},
+		},
+	}
+
+	var refs []FaceRef
+	for ref := range p.AllFaces() {
+		refs = append(refs, ref)
+	}
+	if want := []FaceRef{{0, 0}, {0, 1}, {1, 0}}; len(refs) != len(want) || refs[2] != want[2] {
+		t.Errorf("AllFaces got %v, want %v", refs, want)
+	}
+
+	for i, mat := range p.TexturedMaterials() {
+		if i != 1 || mat.Name != "tex" {
+			t.Errorf("TexturedMaterials yielded %d %q", i, mat.Name)
+		}
+	}
+
+	count := 0
+	for part := range p.PartObjects() {
+		for line := range part.VisibleLines() {
+			if line.Hidden {
+				t.Errorf("VisibleLines yielded a hidden line")
+			}
+			count++
+		}
+	}
+	if count != 2 {
+		t.Errorf("got %d visible lines, want 2", count)
+	}
+}
@@ -0,0 +1,168 @@
+package pdo
+
+import "math"
+
+// JointKind tells how a joint appears on the template.
+type JointKind int
+
+const (
+	// JointCut edges are cut apart and glued back together.
+	JointCut JointKind = iota
+	// JointMountain edges are folded away from the printed side.
+	JointMountain
+	// JointValley edges are folded towards the printed side.
+	JointValley
+	// JointFlat edges are too flat to get a fold line.
+	JointFlat
+)
+
+func (k JointKind) String() string {
+	switch k {
+	case JointCut:
+		return "cut"
+	case JointMountain:
+		return "mountain"
+	case JointValley:
+		return "valley"
+	default:
+		return "flat"
+	}
+}
+
+// MarshalText writes the kind by name, for JSON reports.
+func (k JointKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Joint is an edge of the model where two faces meet.
+type Joint struct {
+	Object int
+	// Edge is the index of the edge in the object; the edge ID printed on
+	// the template is Edge+1.
+	Edge int
+	// PartA and PartB are the parts of the edge's two faces, -1 when a
+	// face isn't unfolded. They are the same part for folds.
+	PartA, PartB int
+	FaceA, FaceB int
+	Kind         JointKind
+	// Length is the edge length on the assembled model in mm.
+	Length float64
+	// LengthA and LengthB are the lengths of the edge on the template, in
+	// the parts of face A and face B, in mm. They should agree with Length.
+	LengthA, LengthB float64
+	// FoldAngle is the angle between the faces in degrees: 0 when they are
+	// flat, positive for a convex (mountain) and negative for a concave
+	// (valley) bend.
+	FoldAngle float64
+}
+
+// Joints lists every edge shared by two faces, object by object in edge
+// order.
+func (p *PDO) Joints() []Joint {
+	scale := p.Unfold.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+	// Folds Pepakura Designer would hide count as flat.
+	flat := p.flatFoldAngle()
+	var joints []Joint
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		lk := NewLookup(obj)
+
+		// Boundary cut lines mark cut joints, connecting lines are folds
+		// that may carry their fold direction.
+		kinds := map[int]JointKind{}
+		for _, part := range p.Parts {
+			if int(part.ObjectIndex) != oi {
+				continue
+			}
+			for i := range part.Lines {
+				line := &part.Lines[i]
+				v1, v2 := lk.LineEnds(line)
+				if v1 == nil {
+					continue
+				}
+				e := lk.EdgeIndex(v1.IDVertex, v2.IDVertex)
+				switch {
+				case e < 0:
+				case !line.IsConnectingFaces && line.Type == 0:
+					kinds[e] = JointCut
+				case line.IsConnectingFaces && (line.Type == 1 || line.Type == 2):
+					if _, ok := kinds[e]; !ok {
+						kinds[e] = JointKind(line.Type)
+					}
+				}
+			}
+		}
+
+		for ei, e := range obj.Edges {
+			if e.Face2Index < 0 || int(e.Face1Index) >= len(obj.Faces) || int(e.Face2Index) >= len(obj.Faces) || e.Face1Index < 0 {
+				continue
+			}
+			j := Joint{
+				Object: oi,
+				Edge:   ei,
+				FaceA:  int(e.Face1Index),
+				FaceB:  int(e.Face2Index),
+				PartA:  int(obj.Faces[e.Face1Index].PartIndex),
+				PartB:  int(obj.Faces[e.Face2Index].PartIndex),
+			}
+			a, b := obj.vertex(e.Vertex1Index), obj.vertex(e.Vertex2Index)
+			j.Length = math.Sqrt((a.X-b.X)*(a.X-b.X)+(a.Y-b.Y)*(a.Y-b.Y)+(a.Z-b.Z)*(a.Z-b.Z)) * scale
+			j.LengthA = obj.templateLength(e.Face1Index, e)
+			j.LengthB = obj.templateLength(e.Face2Index, e)
+			j.FoldAngle = obj.foldAngle(e)
+
+			kind, ok := kinds[ei]
+			switch {
+			case ok:
+			case j.PartA != j.PartB:
+				kind = JointCut
+			case math.Abs(j.FoldAngle) < flat:
+				kind = JointFlat
+			case j.FoldAngle > 0:
+				kind = JointMountain
+			default:
+				kind = JointValley
+			}
+			j.Kind = kind
+			joints = append(joints, j)
+		}
+	}
+	return joints
+}
+
+// templateLength returns the length of an edge in the unfolded face, 0
+// when the face doesn't have both ends.
+func (obj *Object) templateLength(faceIdx int32, e Edge) float64 {
+	v1, v2 := obj.faceVertex(faceIdx, e.Vertex1Index), obj.faceVertex(faceIdx, e.Vertex2Index)
+	if v1 == nil || v2 == nil {
+		return 0
+	}
+	return math.Hypot(v1.X-v2.X, v1.Y-v2.Y)
+}
+
+// foldAngle returns the signed angle between the two faces of an edge in
+// degrees, positive when the second face bends behind the first.
+func (obj *Object) foldAngle(e Edge) float64 {
+	f1, f2 := &obj.Faces[e.Face1Index], &obj.Faces[e.Face2Index]
+	dot := f1.Nx*f2.Nx + f1.Ny*f2.Ny + f1.Nz*f2.Nz
+	angle := math.Acos(math.Max(-1, math.Min(1, dot))) * 180 / math.Pi
+
+	// The corner of the second face furthest from the first face's plane
+	// tells the side.
+	start := obj.vertex(e.Vertex1Index)
+	var side float64
+	for _, fv := range f2.Vertices {
+		v := obj.vertex(fv.IDVertex)
+		d := f1.Nx*(v.X-start.X) + f1.Ny*(v.Y-start.Y) + f1.Nz*(v.Z-start.Z)
+		if math.Abs(d) > math.Abs(side) {
+			side = d
+		}
+	}
+	if side > 0 {
+		return -angle
+	}
+	return angle
+}
@@ -0,0 +1,50 @@
+package pdo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJoints(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/pyramid.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	joints := p.Joints()
+	if len(joints) != len(p.Objects[0].Edges) {
+		t.Fatalf("got %d joints, want one per edge (%d)", len(joints), len(p.Objects[0].Edges))
+	}
+	kinds := map[JointKind]int{}
+	for _, j := range joints {
+		kinds[j.Kind]++
+		// The template keeps the model's edge lengths on both sides.
+		if math.Abs(j.LengthA-j.Length) > 1e-6 || math.Abs(j.LengthB-j.Length) > 1e-6 {
+			t.Errorf("edge %d: length %g, template %g and %g", j.Edge+1, j.Length, j.LengthA, j.LengthB)
+		}
+		// A pyramid only has convex edges.
+		if j.FoldAngle < -1e-6 {
+			t.Errorf("edge %d: fold angle %g on a convex solid", j.Edge+1, j.FoldAngle)
+		}
+	}
+	// The base is split in two flat triangles, four sides are cut open.
+	if kinds[JointCut] != 4 || kinds[JointMountain] != 4 || kinds[JointFlat] != 1 {
+		t.Errorf("kinds = %v, want 4 cut, 4 mountain, 1 flat", kinds)
+	}
+
+	// The inside of a torus bends the other way.
+	p, err = ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var convex, concave int
+	for _, j := range p.Joints() {
+		if j.FoldAngle > 1 {
+			convex++
+		} else if j.FoldAngle < -1 {
+			concave++
+		}
+	}
+	if convex == 0 || concave == 0 {
+		t.Errorf("torus: %d convex and %d concave joints, want both", convex, concave)
+	}
+}
@@ -0,0 +1,319 @@
+package pdo
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// DefaultLayoutSpacing is the gap in mm Relayout leaves between parts.
+const DefaultLayoutSpacing = 3.0
+
+// minRotationGain is how much smaller, relative to the current one, the
+// bounding box of a freely rotated part must be for Relayout to turn it.
+const minRotationGain = 0.01
+
+// Rotation selects how Relayout may turn parts to pack them tighter.
+type Rotation int
+
+const (
+	// RotateNone keeps every part as it is.
+	RotateNone Rotation = iota
+	// RotateQuarter turns parts by 90 degrees where they fit better that way.
+	RotateQuarter
+	// RotateFree first turns each part to its smallest bounding box, then
+	// by 90 degrees like RotateQuarter.
+	RotateFree
+)
+
+// LayoutOptions controls how Relayout places parts.
+type LayoutOptions struct {
+	// Spacing is the gap between parts in mm, DefaultLayoutSpacing when 0.
+	Spacing float64
+	// Rotate allows turning parts, see Rotation.
+	Rotate Rotation
+}
+
+func (o LayoutOptions) spacing() float64 {
+	if o.Spacing > 0 {
+		return o.Spacing
+	}
+	return DefaultLayoutSpacing
+}
+
+// Relayout flows the parts onto the page grid of the current page settings,
+// for example after the paper size, orientation or margins changed. Parts
+// keep their order and are placed in rows, page after page, in a single row
+// of pages. A part larger than the printable area gets a page of its own.
+// With opts.Rotate parts may be turned, which changes their 2D vertices and
+// bounding box. Text blocks and images move with the nearest part. It
+// returns the number of pages used.
+func (p *PDO) Relayout(opts LayoutOptions) int {
+	if len(p.Parts) == 0 {
+		return 0
+	}
+	old := make([]Rect, len(p.Parts))
+	s := shelf{dims: p.PageDims(), gap: opts.spacing()}
+	for i := range p.Parts {
+		bb := &p.Parts[i].BoundingBox
+		old[i] = *bb
+		if opts.Rotate == RotateFree {
+			p.straightenPart(i)
+		}
+		if opts.Rotate != RotateNone && s.shouldTurn(bb.Width, bb.Height) {
+			p.turnPart(i)
+		}
+		bb.Left, bb.Top = s.place(bb.Width, bb.Height)
+	}
+
+	for i := range p.TextBlocks {
+		follow(&p.TextBlocks[i].BoundingBox, old, p.Parts)
+	}
+	for i := range p.Images {
+		follow(&p.Images[i].BoundingBox, old, p.Parts)
+	}
+	p.updateLayoutBounds()
+	return s.pages()
+}
+
+// shelf places boxes in rows across the pages of a layout.
+type shelf struct {
+	dims            PageDims
+	gap             float64
+	page            int
+	x, y, rowHeight float64
+	used            bool // Whether the current page has parts
+}
+
+func (s *shelf) oversized(w, h float64) bool {
+	return w > s.dims.ClippedWidth || h > s.dims.ClippedHeight
+}
+
+// fit reports whether a box of the given size starts a new row or a new
+// page when placed next.
+func (s *shelf) fit(w, h float64) (newRow, newPage bool) {
+	if !s.used {
+		return false, false
+	}
+	if s.oversized(w, h) {
+		return false, true
+	}
+	y := s.y
+	if s.x > 0 && s.x+w > s.dims.ClippedWidth {
+		newRow, y = true, s.y+s.rowHeight+s.gap
+	}
+	if y+h > s.dims.ClippedHeight {
+		return false, true
+	}
+	return newRow, false
+}
+
+// place returns the position of a box of the given size and moves past it.
+func (s *shelf) place(w, h float64) (left, top float64) {
+	newRow, newPage := s.fit(w, h)
+	if newRow {
+		s.x, s.y, s.rowHeight = 0, s.y+s.rowHeight+s.gap, 0
+	}
+	if newPage {
+		s.page++
+		s.x, s.y, s.rowHeight = 0, 0, 0
+	}
+	left, top = float64(s.page)*s.dims.ClippedWidth+s.x, s.y
+	s.used = true
+	s.x += w + s.gap
+	s.rowHeight = max(s.rowHeight, h)
+	if s.oversized(w, h) {
+		s.page++
+		s.x, s.y, s.rowHeight, s.used = 0, 0, 0, false
+	}
+	return left, top
+}
+
+// shouldTurn reports whether a box packs better turned by 90 degrees: it
+// then fits on a page, stays on the current page or in the current row,
+// or else lies flatter.
+func (s *shelf) shouldTurn(w, h float64) bool {
+	cost := func(w, h float64) int {
+		newRow, newPage := s.fit(w, h)
+		switch {
+		case s.oversized(w, h):
+			return 3
+		case newPage:
+			return 2
+		case newRow:
+			return 1
+		}
+		return 0
+	}
+	as, turned := cost(w, h), cost(h, w)
+	return turned < as || turned == as && w < h
+}
+
+func (s *shelf) pages() int {
+	if !s.used {
+		return s.page
+	}
+	return s.page + 1
+}
+
+// turnPart turns a part by 90 degrees clockwise within its bounding box,
+// which keeps its top left corner and swaps its width and height.
+func (p *PDO) turnPart(i int) {
+	part := &p.Parts[i]
+	h := part.BoundingBox.Height
+	p.movePart(i, func(v vec2) vec2 { return vec2{h - v.Y, v.X} })
+	part.BoundingBox.Width, part.BoundingBox.Height = h, part.BoundingBox.Width
+}
+
+// straightenPart turns a part to the angle with the smallest bounding box,
+// found along the edges of the convex hull of its outline. Parts that
+// would shrink by less than minRotationGain are left alone.
+func (p *PDO) straightenPart(i int) {
+	hull := convexHull(p.partOutline(i))
+	bb := &p.Parts[i].BoundingBox
+	best, bestArea := 0.0, bb.Width*bb.Height*(1-minRotationGain)
+	for j, a := range hull {
+		b := hull[(j+1)%len(hull)]
+		angle := -math.Atan2(b.Y-a.Y, b.X-a.X)
+		lo, hi := bounds(hull, rotation(angle))
+		if area := (hi.X - lo.X) * (hi.Y - lo.Y); area < bestArea {
+			best, bestArea = angle, area
+		}
+	}
+	if best == 0 {
+		return
+	}
+	rot := rotation(best)
+	lo, hi := bounds(hull, rot)
+	p.movePart(i, func(v vec2) vec2 {
+		r := rot(v)
+		return vec2{r.X - lo.X, r.Y - lo.Y}
+	})
+	bb.Width, bb.Height = hi.X-lo.X, hi.Y-lo.Y
+}
+
+// movePart maps the 2D vertices of the faces of a part.
+func (p *PDO) movePart(i int, f func(vec2) vec2) {
+	part := &p.Parts[i]
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+		return
+	}
+	for _, face := range p.Objects[part.ObjectIndex].PartFaces(i) {
+		for j := range face.Vertices {
+			v := &face.Vertices[j]
+			r := f(vec2{v.X, v.Y})
+			v.X, v.Y = r.X, r.Y
+		}
+	}
+}
+
+// partOutline returns the 2D vertices of a part and the outer corners of
+// its flaps.
+func (p *PDO) partOutline(i int) []vec2 {
+	part := &p.Parts[i]
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+		return nil
+	}
+	obj := &p.Objects[part.ObjectIndex]
+	var points []vec2
+	for _, face := range obj.PartFaces(i) {
+		for _, v := range face.Vertices {
+			points = append(points, vec2{v.X, v.Y})
+		}
+	}
+	for line := range part.VisibleLines() {
+		if line.Type != 0 || line.IsConnectingFaces {
+			continue
+		}
+		v1, v2 := obj.LineEnds(line)
+		if v1 == nil || v1.Flap == 0 || v1.FlapHeight <= 0 {
+			continue
+		}
+		out := outwardNormal(obj, line.FaceIndex, v1, v2)
+		top1, top2, ok := flapTop(vec2{v1.X, v1.Y}, vec2{v2.X, v2.Y}, out, v1.FlapHeight, v1.FlapAAngle, v1.FlapBAngle)
+		if ok {
+			points = append(points, top1, top2)
+		}
+	}
+	return points
+}
+
+// rotation returns the rotation of a point about the origin by angle radians.
+func rotation(angle float64) func(vec2) vec2 {
+	sin, cos := math.Sincos(angle)
+	return func(v vec2) vec2 {
+		return vec2{v.X*cos - v.Y*sin, v.X*sin + v.Y*cos}
+	}
+}
+
+// bounds returns the corners of the bounding box of the mapped points.
+func bounds(points []vec2, f func(vec2) vec2) (lo, hi vec2) {
+	lo = vec2{math.Inf(1), math.Inf(1)}
+	hi = vec2{math.Inf(-1), math.Inf(-1)}
+	for _, v := range points {
+		r := f(v)
+		lo = vec2{min(lo.X, r.X), min(lo.Y, r.Y)}
+		hi = vec2{max(hi.X, r.X), max(hi.Y, r.Y)}
+	}
+	return lo, hi
+}
+
+// convexHull returns the convex hull of the points in order, using the
+// monotone chain algorithm.
+func convexHull(points []vec2) []vec2 {
+	points = slices.Clone(points)
+	slices.SortFunc(points, func(a, b vec2) int {
+		return cmp.Or(cmp.Compare(a.X, b.X), cmp.Compare(a.Y, b.Y))
+	})
+	if len(points) < 3 {
+		return points
+	}
+	cross := func(o, a, b vec2) float64 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+	hull := make([]vec2, 0, 2*len(points))
+	for _, v := range points {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], v) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, v)
+	}
+	lower := len(hull) + 1
+	for i := len(points) - 2; i >= 0; i-- {
+		v := points[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], v) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, v)
+	}
+	return hull[:len(hull)-1]
+}
+
+// follow moves a box by the same amount as the part nearest to it was moved.
+func follow(bb *Rect, old []Rect, parts []Part) {
+	nearest, best := -1, math.Inf(1)
+	for i, r := range old {
+		dx := max(r.Left-bb.Left, 0, bb.Left-(r.Left+r.Width))
+		dy := max(r.Top-bb.Top, 0, bb.Top-(r.Top+r.Height))
+		if d := math.Hypot(dx, dy); d < best {
+			nearest, best = i, d
+		}
+	}
+	if nearest < 0 {
+		return
+	}
+	bb.Left += parts[nearest].BoundingBox.Left - old[nearest].Left
+	bb.Top += parts[nearest].BoundingBox.Top - old[nearest].Top
+}
+
+// updateLayoutBounds sets the unfold bounding box to enclose all parts.
+func (p *PDO) updateLayoutBounds() {
+	if len(p.Parts) == 0 {
+		return
+	}
+	var bb Rect
+	for _, part := range p.Parts {
+		bb = bb.Union(part.BoundingBox)
+	}
+	p.Unfold.BoundingBox = bb
+}
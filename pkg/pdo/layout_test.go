@@ -0,0 +1,102 @@
+package pdo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRelayout(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.TextBlocks = []TextBlock{{BoundingBox: Rect{Left: 10, Top: 140, Width: 20, Height: 5}}}
+	sizes := make([][2]float64, len(p.Parts))
+	for i, part := range p.Parts {
+		sizes[i] = [2]float64{part.BoundingBox.Width, part.BoundingBox.Height}
+	}
+
+	// Both parts fit on a portrait A4 page, a landscape one holds only one.
+	if err := p.Settings.Set("orientation", "landscape"); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.Relayout(LayoutOptions{}); n != 2 {
+		t.Errorf("%d pages, want 2", n)
+	}
+	dims := p.PageDims()
+	for i, part := range p.Parts {
+		bb := part.BoundingBox
+		if bb.Width != sizes[i][0] || bb.Height != sizes[i][1] {
+			t.Errorf("part %d resized to %gx%g", i, bb.Width, bb.Height)
+		}
+		page := math.Floor(bb.Left / dims.ClippedWidth)
+		if page != float64(i) || bb.Top != 0 {
+			t.Errorf("part %d at %g,%g, want the top left of page %d", i, bb.Left, bb.Top, i)
+		}
+		if right := bb.Left - page*dims.ClippedWidth + bb.Width; right > dims.ClippedWidth {
+			t.Errorf("part %d sticks out of its page by %g mm", i, right-dims.ClippedWidth)
+		}
+	}
+	// The text sat next to the second part and moved with it.
+	if tb := p.TextBlocks[0].BoundingBox; tb.Left < dims.ClippedWidth {
+		t.Errorf("text block left behind at %g,%g", tb.Left, tb.Top)
+	}
+	if maxX, maxY := p.PageGrid(dims); maxX != 1 || maxY != 0 {
+		t.Errorf("page grid %d x %d", maxX+1, maxY+1)
+	}
+
+	if err := p.Settings.Set("orientation", "portrait"); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.Relayout(LayoutOptions{}); n != 1 {
+		t.Errorf("%d portrait pages, want 1", n)
+	}
+	if top := p.Parts[1].BoundingBox.Top; top != sizes[0][1]+DefaultLayoutSpacing {
+		t.Errorf("second part at top %g, want below the first", top)
+	}
+}
+
+func TestRelayout_Rotate(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A narrow strip of paper fits both parts stacked, but only turned.
+	for _, kv := range [][2]string{{"pageType", "other"}, {"customWidth", "130"}, {"customHeight", "360"}, {"marginSide", "0"}, {"marginTop", "0"}} {
+		if err := p.Settings.Set(kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := p.Relayout(LayoutOptions{}); n != 2 {
+		t.Errorf("%d pages without rotation, want 2", n)
+	}
+	if n := p.Relayout(LayoutOptions{Rotate: RotateQuarter}); n != 1 {
+		t.Errorf("%d pages with rotation, want 1", n)
+	}
+	checkInBounds(t, p)
+
+	p, err = ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := p.Parts[0].BoundingBox
+	p.Relayout(LayoutOptions{Rotate: RotateFree})
+	if got := p.Parts[0].BoundingBox; got.Width*got.Height > bb.Width*bb.Height {
+		t.Errorf("free rotation grew the part from %gx%g to %gx%g", bb.Width, bb.Height, got.Width, got.Height)
+	}
+	checkInBounds(t, p)
+}
+
+// checkInBounds fails if a part's outline leaves its bounding box.
+func checkInBounds(t *testing.T, p *PDO) {
+	t.Helper()
+	const eps = 1e-6
+	for i, part := range p.Parts {
+		for _, v := range p.partOutline(i) {
+			if v.X < -eps || v.Y < -eps || v.X > part.BoundingBox.Width+eps || v.Y > part.BoundingBox.Height+eps {
+				t.Errorf("part %d: point %g,%g outside %gx%g", i, v.X, v.Y, part.BoundingBox.Width, part.BoundingBox.Height)
+				break
+			}
+		}
+	}
+}
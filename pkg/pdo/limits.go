@@ -0,0 +1,109 @@
+package pdo
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Limits caps what the parser allocates for counts and sizes read from a
+// file, so a malformed or hostile file fails with ErrLimitExceeded instead
+// of exhausting memory. Zero fields don't limit anything.
+type Limits struct {
+	// MaxTexturePixels limits the width times height of every texture and
+	// image.
+	MaxTexturePixels int64
+	// MaxVertices limits the 3D vertices of all objects together.
+	MaxVertices int64
+	// MaxFaces limits the faces of all objects together.
+	MaxFaces int64
+	// MaxAlloc limits the bytes allocated for the decoded file, counted
+	// from the sizes of the structures and of raw texture and string data.
+	MaxAlloc int64
+}
+
+// DefaultLimits are generous limits for files from untrusted sources. The
+// largest models made with Pepakura Designer stay well below them.
+var DefaultLimits = Limits{
+	MaxTexturePixels: 8192 * 8192,
+	MaxVertices:      2 << 20,
+	MaxFaces:         2 << 20,
+	MaxAlloc:         512 << 20,
+}
+
+// budget tracks what a parse has allocated against its Limits. A nil
+// budget only rejects negative counts.
+type budget struct {
+	limits    Limits
+	allocated int64
+	vertices  int64
+	faces     int64
+}
+
+// alloc accounts for n elements of size bytes of what, before they are
+// allocated.
+func (b *budget) alloc(what string, n int32, size uintptr) error {
+	return b.grow(what, int64(n), int64(size))
+}
+
+// grow is alloc for counts that don't fit an int32 or were never stored
+// as one, like the size of texture data or of the trailing data.
+func (b *budget) grow(what string, n, size int64) error {
+	if n < 0 {
+		return fmt.Errorf("%w: invalid %s count %d", ErrLimitExceeded, what, n)
+	}
+	if b == nil {
+		return nil
+	}
+	b.allocated += n * size
+	if m := b.limits.MaxAlloc; m > 0 && b.allocated > m {
+		return fmt.Errorf("%w: %d %s need more than %d bytes", ErrLimitExceeded, n, what, m)
+	}
+	return nil
+}
+
+// remaining returns how many more bytes may be allocated, -1 without a
+// limit.
+func (b *budget) remaining() int64 {
+	if b == nil || b.limits.MaxAlloc <= 0 {
+		return -1
+	}
+	return max(b.limits.MaxAlloc-b.allocated, 0)
+}
+
+// addVertices accounts for n more 3D vertices.
+func (b *budget) addVertices(n int32) error {
+	if err := b.alloc("vertices", n, unsafe.Sizeof(Vertex3D{})); err != nil || b == nil {
+		return err
+	}
+	b.vertices += int64(n)
+	if m := b.limits.MaxVertices; m > 0 && b.vertices > m {
+		return fmt.Errorf("%w: more than %d vertices", ErrLimitExceeded, m)
+	}
+	return nil
+}
+
+// addFaces accounts for n more faces.
+func (b *budget) addFaces(n int32) error {
+	if err := b.alloc("faces", n, unsafe.Sizeof(Face{})); err != nil || b == nil {
+		return err
+	}
+	b.faces += int64(n)
+	if m := b.limits.MaxFaces; m > 0 && b.faces > m {
+		return fmt.Errorf("%w: more than %d faces", ErrLimitExceeded, m)
+	}
+	return nil
+}
+
+// texture checks the dimensions of a texture.
+func (b *budget) texture(w, h int32) error {
+	if w < 0 || h < 0 {
+		return fmt.Errorf("%w: size %dx%d", ErrBadTexture, w, h)
+	}
+	if b == nil {
+		return nil
+	}
+	if m := b.limits.MaxTexturePixels; m > 0 && int64(w)*int64(h) > m {
+		return fmt.Errorf("%w: texture of %dx%d pixels, the limit is %d", ErrLimitExceeded, w, h, m)
+	}
+	return nil
+}
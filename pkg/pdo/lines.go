@@ -0,0 +1,51 @@
+package pdo
+
+// LineEnds returns the two 2D vertices a part line runs between.
+// Connecting lines end on the matching vertex of the second face, boundary
+// lines end on the next vertex of their own face. It returns nils when the
+// line's indices don't resolve.
+func (obj *Object) LineEnds(line *Line) (*Face2DVertex, *Face2DVertex) {
+	v1 := obj.faceVertex(line.FaceIndex, line.VertexIndex)
+	if v1 == nil {
+		return nil, nil
+	}
+
+	var v2 *Face2DVertex
+	if line.IsConnectingFaces {
+		v2 = obj.faceVertex(line.Face2Index, line.Vertex2Index)
+	} else {
+		v2 = obj.nextFaceVertex(line.FaceIndex, line.VertexIndex)
+	}
+	if v2 == nil {
+		return nil, nil
+	}
+	return v1, v2
+}
+
+// faceVertex returns the 2D vertex of a face for a 3D vertex index.
+func (obj *Object) faceVertex(faceIdx, vertIdx int32) *Face2DVertex {
+	if faceIdx < 0 || int(faceIdx) >= len(obj.Faces) {
+		return nil
+	}
+	face := &obj.Faces[faceIdx]
+	for i := range face.Vertices {
+		if face.Vertices[i].IDVertex == vertIdx {
+			return &face.Vertices[i]
+		}
+	}
+	return nil
+}
+
+// nextFaceVertex returns the vertex following the one for vertIdx in the face loop.
+func (obj *Object) nextFaceVertex(faceIdx, vertIdx int32) *Face2DVertex {
+	if faceIdx < 0 || int(faceIdx) >= len(obj.Faces) {
+		return nil
+	}
+	face := &obj.Faces[faceIdx]
+	for i := range face.Vertices {
+		if face.Vertices[i].IDVertex == vertIdx {
+			return &face.Vertices[(i+1)%len(face.Vertices)]
+		}
+	}
+	return nil
+}
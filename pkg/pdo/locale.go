@@ -0,0 +1,45 @@
+package pdo
+
+import (
+	"strings"
+)
+
+// letterRegions use US Letter paper rather than A4.
+var letterRegions = map[string]bool{
+	"US": true, "CA": true, "MX": true, "PH": true, "CL": true, "CO": true,
+	"VE": true, "CR": true, "GT": true, "PR": true, "DO": true, "PA": true,
+	"SV": true, "NI": true,
+}
+
+// inchRegions measure in inches rather than millimeters.
+var inchRegions = map[string]bool{"US": true, "LR": true, "MM": true}
+
+// localeRegion returns the upper case region code of a locale such as
+// "en_US", "en-US.UTF-8" or "English_United States.1252", "" when it has
+// none.
+func localeRegion(locale string) string {
+	locale, _, _ = strings.Cut(locale, ".")
+	if strings.EqualFold(locale, "English_United States") {
+		return "US"
+	}
+	i := strings.LastIndexAny(locale, "_-")
+	if i < 0 {
+		return ""
+	}
+	return strings.ToUpper(locale[i+1:])
+}
+
+// LocalePageType returns the paper used in the region of Locale: Letter
+// or A4.
+func (h *Header) LocalePageType() int32 {
+	if letterRegions[localeRegion(h.Locale)] {
+		return PageLetter
+	}
+	return PageA4
+}
+
+// LocaleInches reports whether lengths are shown in inches in the region
+// of Locale.
+func (h *Header) LocaleInches() bool {
+	return inchRegions[localeRegion(h.Locale)]
+}
@@ -0,0 +1,142 @@
+package pdo
+
+// Lookup resolves the lines of an object's parts through maps built once,
+// where LineEnds and EdgeIndex scan the face's vertices and the object's
+// edges for every line. Build one per object before passes over all lines
+// of a model; it describes the object as it was when built, so build a new
+// one after changing the faces or edges.
+//
+// On typical models the gain comes from the edge index, see
+// BenchmarkLookup_Lines. Scanning the vertices of a face beats a map lookup
+// up to about 64 of them, see BenchmarkLookup_FaceVertex, so only the
+// vertices of faces with lookupMinFace vertices or more are indexed.
+type Lookup struct {
+	obj      *Object
+	vertices map[faceVertexKey]int32 // Position of a 3D vertex in a big face's loop
+	edges    map[[2]int32]int        // First edge between two vertices, lower ID first
+}
+
+// lookupMinFace is the number of vertices from which a face's vertices are
+// indexed.
+const lookupMinFace = 64
+
+type faceVertexKey struct {
+	face, vertex int32
+}
+
+// NewLookup indexes the face vertices and edges of obj.
+func NewLookup(obj *Object) *Lookup {
+	l := &Lookup{
+		obj:      obj,
+		vertices: map[faceVertexKey]int32{},
+		edges:    make(map[[2]int32]int, len(obj.Edges)),
+	}
+	for fi, f := range obj.Faces {
+		if len(f.Vertices) < lookupMinFace {
+			continue
+		}
+		for vi, v := range f.Vertices {
+			k := faceVertexKey{int32(fi), v.IDVertex}
+			if _, dup := l.vertices[k]; !dup {
+				l.vertices[k] = int32(vi)
+			}
+		}
+	}
+	for i, e := range obj.Edges {
+		k := edgeKey(e.Vertex1Index, e.Vertex2Index)
+		if _, dup := l.edges[k]; !dup {
+			l.edges[k] = i
+		}
+	}
+	return l
+}
+
+// Lookups holds a Lookup for every object of a PDO, in object order.
+type Lookups []*Lookup
+
+// Lookups indexes every object.
+func (p *PDO) Lookups() Lookups {
+	lookups := make(Lookups, len(p.Objects))
+	for i := range p.Objects {
+		lookups[i] = NewLookup(&p.Objects[i])
+	}
+	return lookups
+}
+
+// Part returns the Lookup of the object of part, nil when the part belongs
+// to no object.
+func (l Lookups) Part(part *Part) *Lookup {
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(l) {
+		return nil
+	}
+	return l[part.ObjectIndex]
+}
+
+// Object returns the object l indexes.
+func (l *Lookup) Object() *Object { return l.obj }
+
+// LineEnds is Object.LineEnds through the index.
+func (l *Lookup) LineEnds(line *Line) (*Face2DVertex, *Face2DVertex) {
+	v1 := l.faceVertex(line.FaceIndex, line.VertexIndex, false)
+	if v1 == nil {
+		return nil, nil
+	}
+
+	var v2 *Face2DVertex
+	if line.IsConnectingFaces {
+		v2 = l.faceVertex(line.Face2Index, line.Vertex2Index, false)
+	} else {
+		v2 = l.faceVertex(line.FaceIndex, line.VertexIndex, true)
+	}
+	if v2 == nil {
+		return nil, nil
+	}
+	return v1, v2
+}
+
+// EdgeIndex is Object.EdgeIndex through the index.
+func (l *Lookup) EdgeIndex(v1, v2 int32) int {
+	if e, ok := l.edges[edgeKey(v1, v2)]; ok {
+		return e
+	}
+	return -1
+}
+
+// faceVertex returns the 2D vertex of a face for a 3D vertex index, or
+// with next the one following it in the face loop.
+func (l *Lookup) faceVertex(faceIdx, vertIdx int32, next bool) *Face2DVertex {
+	if faceIdx < 0 || int(faceIdx) >= len(l.obj.Faces) {
+		return nil
+	}
+	face := &l.obj.Faces[faceIdx]
+	if len(face.Vertices) < lookupMinFace {
+		if next {
+			return l.obj.nextFaceVertex(faceIdx, vertIdx)
+		}
+		return l.obj.faceVertex(faceIdx, vertIdx)
+	}
+	i, ok := l.vertices[faceVertexKey{faceIdx, vertIdx}]
+	if !ok {
+		return nil
+	}
+	if next {
+		i = (i + 1) % int32(len(face.Vertices))
+	}
+	return &face.Vertices[i]
+}
+
+// lineHasFlap is Object.lineHasFlap through the index.
+func (l *Lookup) lineHasFlap(line *Line, v1, v2 *Face2DVertex) bool {
+	if line.Type != 0 || line.IsConnectingFaces || v1 == nil || v2 == nil || v1.Flap == 0 || v1.FlapHeight <= 0 {
+		return false
+	}
+	return l.obj.FlapFace(l.EdgeIndex(v1.IDVertex, v2.IDVertex)) == line.FaceIndex
+}
+
+// edgeKey orders the vertices of an edge, edges are undirected.
+func edgeKey(v1, v2 int32) [2]int32 {
+	if v2 < v1 {
+		v1, v2 = v2, v1
+	}
+	return [2]int32{v1, v2}
+}
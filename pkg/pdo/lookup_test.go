@@ -0,0 +1,132 @@
+package pdo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"cone", "cylinder", "sphere", "torus"} {
+		p, err := ParseFile("../../sample_basic_shapes/" + name + ".pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lookups := p.Lookups()
+		for part, obj := range p.PartObjects() {
+			lk := lookups.Part(part)
+			if lk.Object() != obj {
+				t.Fatalf("%s: lookup of the wrong object", name)
+			}
+			for i := range part.Lines {
+				line := &part.Lines[i]
+				w1, w2 := obj.LineEnds(line)
+				v1, v2 := lk.LineEnds(line)
+				if v1 != w1 || v2 != w2 {
+					t.Fatalf("%s: line %d ends %p %p, want %p %p", name, i, v1, v2, w1, w2)
+				}
+				if v1 == nil {
+					continue
+				}
+				if e, want := lk.EdgeIndex(v2.IDVertex, v1.IDVertex), obj.EdgeIndex(v1.IDVertex, v2.IDVertex); e != want {
+					t.Errorf("%s: line %d on edge %d, want %d", name, i, e, want)
+				}
+			}
+		}
+	}
+}
+
+// polygon returns an object with one face of n vertices numbered from 100
+// and the edges around it.
+func polygon(n int) *Object {
+	obj := &Object{Faces: []Face{{}}}
+	for i := range n {
+		obj.Faces[0].Vertices = append(obj.Faces[0].Vertices, Face2DVertex{IDVertex: int32(100 + i), X: float64(i)})
+		obj.Edges = append(obj.Edges, Edge{Vertex1Index: int32(100 + i), Vertex2Index: int32(100 + (i+1)%n)})
+	}
+	return obj
+}
+
+func TestLookup_BigFace(t *testing.T) {
+	// A polygon with enough vertices to be indexed, and a line past its end.
+	const n = lookupMinFace + 8
+	obj := polygon(n)
+	lk := NewLookup(obj)
+	for i := range n {
+		line := &Line{VertexIndex: int32(100 + i)}
+		v1, v2 := lk.LineEnds(line)
+		if w1, w2 := obj.LineEnds(line); v1 != w1 || v2 != w2 {
+			t.Fatalf("line from vertex %d: got %p %p, want %p %p", i, v1, v2, w1, w2)
+		}
+		if e := lk.EdgeIndex(v1.IDVertex, v2.IDVertex); e != i {
+			t.Errorf("line from vertex %d on edge %d", i, e)
+		}
+	}
+	if v1, _ := lk.LineEnds(&Line{VertexIndex: 99}); v1 != nil {
+		t.Error("resolved a vertex the face doesn't have")
+	}
+	if e := lk.EdgeIndex(100, 102); e != -1 {
+		t.Errorf("found edge %d between unconnected vertices", e)
+	}
+}
+
+// BenchmarkLookup_FaceVertex compares scanning a face's vertices with
+// looking them up in a map, by face size, to choose lookupMinFace.
+func BenchmarkLookup_FaceVertex(b *testing.B) {
+	for _, n := range []int{3, 4, 8, 16, 32, 64, 128} {
+		obj := polygon(n)
+		index := map[faceVertexKey]int32{}
+		for i, v := range obj.Faces[0].Vertices {
+			index[faceVertexKey{0, v.IDVertex}] = int32(i)
+		}
+		b.Run(fmt.Sprintf("scan/%d", n), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				if obj.faceVertex(0, int32(100+i%n)) == nil {
+					b.Fatal("vertex not found")
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("map/%d", n), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				if _, ok := index[faceVertexKey{0, int32(100 + i%n)}]; !ok {
+					b.Fatal("vertex not found")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkLookup_Lines resolves every line of a sample and finds its edge,
+// with and without a Lookup.
+func BenchmarkLookup_Lines(b *testing.B) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		b.Fatal(err)
+	}
+	resolve := func(b *testing.B, lineEnds func(*Part, *Line) (*Face2DVertex, *Face2DVertex), edgeIndex func(*Part, int32, int32) int) {
+		for b.Loop() {
+			for i := range p.Parts {
+				part := &p.Parts[i]
+				for j := range part.Lines {
+					if v1, v2 := lineEnds(part, &part.Lines[j]); v1 != nil {
+						edgeIndex(part, v1.IDVertex, v2.IDVertex)
+					}
+				}
+			}
+		}
+	}
+	b.Run("object", func(b *testing.B) {
+		resolve(b, func(part *Part, line *Line) (*Face2DVertex, *Face2DVertex) {
+			return p.Objects[part.ObjectIndex].LineEnds(line)
+		}, func(part *Part, v1, v2 int32) int {
+			return p.Objects[part.ObjectIndex].EdgeIndex(v1, v2)
+		})
+	})
+	b.Run("lookup", func(b *testing.B) {
+		lookups := p.Lookups()
+		resolve(b, func(part *Part, line *Line) (*Face2DVertex, *Face2DVertex) {
+			return lookups.Part(part).LineEnds(line)
+		}, func(part *Part, v1, v2 int32) int {
+			return lookups.Part(part).EdgeIndex(v1, v2)
+		})
+	})
+}
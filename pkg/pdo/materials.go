@@ -0,0 +1,53 @@
+package pdo
+
+// MaterialUsage tells where a material is used.
+type MaterialUsage struct {
+	Material int    `json:"material"`
+	Name     string `json:"name"`
+	Textured bool   `json:"textured"`
+	// Faces counts the faces of all objects with the material.
+	Faces int `json:"faces"`
+	// Parts lists the parts holding faces with the material, in order.
+	Parts []int `json:"parts"`
+	// Area is the unfolded area of the faces in mm², TexturedArea the same
+	// for textured materials and 0 for plain ones.
+	Area         float64 `json:"area"`
+	TexturedArea float64 `json:"texturedArea"`
+}
+
+// Used reports whether any face has the material.
+func (u *MaterialUsage) Used() bool { return u.Faces > 0 }
+
+// MaterialUsage reports the use of every material, in material order.
+// Materials no face uses are the ones Prune removes.
+func (p *PDO) MaterialUsage() []MaterialUsage {
+	usage := make([]MaterialUsage, len(p.Materials))
+	onPart := make([]map[int32]bool, len(p.Materials))
+	for i, m := range p.Materials {
+		usage[i] = MaterialUsage{Material: i, Name: m.Name, Textured: m.HasTexture}
+		onPart[i] = map[int32]bool{}
+	}
+	for _, f := range p.AllFaces() {
+		if f.MaterialIndex < 0 || int(f.MaterialIndex) >= len(usage) {
+			continue
+		}
+		u := &usage[f.MaterialIndex]
+		u.Faces++
+		u.Area += f.Area2D()
+		if f.PartIndex >= 0 && int(f.PartIndex) < len(p.Parts) {
+			onPart[f.MaterialIndex][f.PartIndex] = true
+		}
+	}
+	for i := range usage {
+		u := &usage[i]
+		for pi := range p.Parts {
+			if onPart[i][int32(pi)] {
+				u.Parts = append(u.Parts, pi)
+			}
+		}
+		if u.Textured {
+			u.TexturedArea = u.Area
+		}
+	}
+	return usage
+}
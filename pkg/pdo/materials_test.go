@@ -0,0 +1,49 @@
+package pdo
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestMaterialUsage(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Materials = []Material{{Name: "spare"}, {Name: "skin", HasTexture: true}, {Name: "ring"}}
+	faces := p.Objects[0].Faces
+	var area float64
+	for i := range faces {
+		faces[i].MaterialIndex = 2
+		if faces[i].PartIndex == 1 {
+			faces[i].MaterialIndex = 1
+			area += faces[i].Area2D()
+		}
+	}
+	first := slices.IndexFunc(faces, func(f Face) bool { return f.PartIndex == 0 })
+	faces[first].MaterialIndex = 1
+	area += faces[first].Area2D()
+
+	usage := p.MaterialUsage()
+	if len(usage) != 3 {
+		t.Fatalf("%d materials, want 3", len(usage))
+	}
+	if u := usage[0]; u.Used() || u.Faces != 0 || len(u.Parts) != 0 {
+		t.Errorf("spare material used: %+v", u)
+	}
+	skin := usage[1]
+	if !slices.Equal(skin.Parts, []int{0, 1}) {
+		t.Errorf("skin on parts %v, want [0 1]", skin.Parts)
+	}
+	if math.Abs(skin.TexturedArea-area) > 1e-6 || skin.TexturedArea != skin.Area {
+		t.Errorf("skin textured area %.2f of %.2f, want %.2f", skin.TexturedArea, skin.Area, area)
+	}
+	ring := usage[2]
+	if ring.Faces+skin.Faces != len(faces) || ring.TexturedArea != 0 || ring.Area == 0 {
+		t.Errorf("ring %+v, skin %d faces of %d", ring, skin.Faces, len(faces))
+	}
+	if !slices.Equal(ring.Parts, []int{0}) {
+		t.Errorf("ring on parts %v, want [0]", ring.Parts)
+	}
+}
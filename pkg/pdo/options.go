@@ -0,0 +1,28 @@
+package pdo
+
+import (
+	"log/slog"
+)
+
+// Options controls how a PDO file is parsed.
+type Options struct {
+	// StringShift, when non-nil, replaces the character shift stored in the header.
+	StringShift *byte
+	// MultiByte, when non-nil, replaces the header's multi-byte strings flag.
+	MultiByte *bool
+	// RecoverStrings tries every shift and character width when the header
+	// strings decode to garbage, and keeps the most plausible combination.
+	RecoverStrings bool
+	// Limits caps the counts and sizes accepted from the file. Use
+	// DefaultLimits for files from untrusted sources.
+	Limits Limits
+	// Logger receives diagnostics and non-fatal warnings. slog.Default() is used when nil.
+	Logger *slog.Logger
+}
+
+func (o Options) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return slog.Default()
+}
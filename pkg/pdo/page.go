@@ -0,0 +1,108 @@
+package pdo
+
+import (
+	"math"
+)
+
+// PageDims describes the paper and printable area of the unfolded layout, in mm.
+type PageDims struct {
+	Width         float64
+	Height        float64
+	MarginLeft    float64
+	MarginTop     float64
+	ClippedWidth  float64
+	ClippedHeight float64
+}
+
+// PageDims returns the page size and margins from the print settings.
+func (p *PDO) PageDims() PageDims {
+	// Unknown page types print on the paper of the file's locale.
+	def := pageSizes[p.Header.LocalePageType()]
+	w, h := def.width, def.height
+	switch t := p.Settings.PageType; {
+	case t == PageOther:
+		if p.Settings.CustomWidth > 0 {
+			w = p.Settings.CustomWidth
+		}
+		if p.Settings.CustomHeight > 0 {
+			h = p.Settings.CustomHeight
+		}
+	case t >= 0 && int(t) < len(pageSizes):
+		w, h = pageSizes[t].width, pageSizes[t].height
+	}
+
+	mt := float64(p.Settings.MarginTop)
+	ms := float64(p.Settings.MarginSide)
+
+	// Orientation: 1 = Landscape?
+	// Logic from pdo2opf.pas:
+	// if _pdo.settings.page.orientation = 1 then Swap2f(width, height)
+	if p.Settings.Orientation == 1 {
+		w, h = h, w
+		// Swap margins? pdo2opf says Swap(margin_side, margin_top)
+		// but margins are usually relative to paper edges?
+		// "Swap2f(_page.margin_side, _page.margin_top)" -> Yes.
+		mt, ms = ms, mt
+	}
+
+	return PageDims{
+		Width:         w,
+		Height:        h,
+		MarginLeft:    ms,
+		MarginTop:     mt,
+		ClippedWidth:  w - 2*ms,
+		ClippedHeight: h - 2*mt,
+	}
+}
+
+// PageGrid returns the highest page column and row index used by any part.
+func (p *PDO) PageGrid(dims PageDims) (int, int) {
+	maxX, maxY := 0, 0
+	for _, page := range p.PartPages(dims) {
+		maxX, maxY = max(maxX, page[0]), max(maxY, page[1])
+	}
+	return maxX, maxY
+}
+
+// Pages holds the page column and row, from 0, of every part, in part
+// order. Like a Lookup it describes the parts as they were when computed.
+type Pages [][2]int
+
+// PageOf returns the page column and row that part i is printed on.
+func (pages Pages) PageOf(i int) (col, row int) {
+	return pages[i][0], pages[i][1]
+}
+
+// PartPages returns the page of every part, computed once for all parts
+// rather than part by part. As in Pepakura, a part belongs to the page
+// holding the top left corner of its faces: the stored bounding box is
+// moved by the box of the part's 2D vertices, so a flap or a loose stored
+// box reaching over a page edge doesn't move the part. Parts without faces
+// go by their stored box.
+func (p *PDO) PartPages(dims PageDims) Pages {
+	corners := make([]vec2, len(p.Parts))
+	found := make([]bool, len(p.Parts))
+	for oi := range p.Objects {
+		for _, f := range p.Objects[oi].Faces {
+			i := int(f.PartIndex)
+			if i < 0 || i >= len(p.Parts) || int(p.Parts[i].ObjectIndex) != oi {
+				continue
+			}
+			for _, v := range f.Vertices {
+				if !found[i] {
+					corners[i], found[i] = vec2{v.X, v.Y}, true
+				}
+				corners[i] = vec2{min(corners[i].X, v.X), min(corners[i].Y, v.Y)}
+			}
+		}
+	}
+	pages := make(Pages, len(p.Parts))
+	for i, part := range p.Parts {
+		x, y := part.BoundingBox.Left, part.BoundingBox.Top
+		if found[i] {
+			x, y = x+corners[i].X, y+corners[i].Y
+		}
+		pages[i] = [2]int{int(math.Floor(x / dims.ClippedWidth)), int(math.Floor(y / dims.ClippedHeight))}
+	}
+	return pages
+}
@@ -0,0 +1,71 @@
+package pdo
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPartPages(t *testing.T) {
+	// Pages as Pepakura Designer shows them. The pyramid's stored box
+	// starts 0.3 mm left of the printable area, its faces don't.
+	golden := map[string][][2]int{
+		"cone":     {{0, 0}},
+		"cylinder": {{0, 0}},
+		"pyramid":  {{0, 0}},
+		"sphere":   {{0, 0}},
+		"torus":    {{0, 0}, {0, 0}},
+	}
+	for name, want := range golden {
+		p, err := ParseFile("../../sample_basic_shapes/" + name + ".pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.PartPages(p.PageDims()); !slices.Equal(got, want) {
+			t.Errorf("%s: pages %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dims := p.PageDims()
+	// A stored box reaching back over the page edge, as flaps and loose
+	// boxes do, keeps the part on the page of its faces.
+	for i := range p.Objects[0].Faces {
+		f := &p.Objects[0].Faces[i]
+		if f.PartIndex != 1 {
+			continue
+		}
+		for j := range f.Vertices {
+			f.Vertices[j].X += 10
+		}
+	}
+	p.Parts[1].BoundingBox.Left = dims.ClippedWidth - 5
+	if col, row := p.PartPages(dims).PageOf(1); col != 1 || row != 0 {
+		t.Errorf("part on page %d, %d, want 1, 0", col, row)
+	}
+	if maxX, maxY := p.PageGrid(dims); maxX != 1 || maxY != 0 {
+		t.Errorf("page grid %d x %d", maxX+1, maxY+1)
+	}
+}
+
+func TestPageDims_Locale(t *testing.T) {
+	for locale, want := range map[string]float64{"": 210, "ja_JP": 210, "en_US": 215.9, "en-CA.UTF-8": 215.9, "English_United States.1252": 215.9, "de_DE": 210} {
+		p := &PDO{Header: Header{Locale: locale}, Settings: Settings{PageType: 42}}
+		if w := p.PageDims().Width; w != want {
+			t.Errorf("%q: unknown page type %g mm wide, want %g", locale, w, want)
+		}
+		p.Settings.PageType = PageA4
+		if w := p.PageDims().Width; w != 210 {
+			t.Errorf("%q: A4 %g mm wide", locale, w)
+		}
+	}
+	for locale, want := range map[string]bool{"en_US": true, "en_CA": false, "fr_FR": false} {
+		if h := (Header{Locale: locale}); h.LocaleInches() != want {
+			t.Errorf("%q: inches %v, want %v", locale, !want, want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package pdo
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// DefaultGrammage is the weight in g/m² of common craft cardstock, used when
+// neither grammage nor thickness of the paper is known.
+const DefaultGrammage = 160
+
+// cardstockDensity is the typical density of cardstock in g/cm³, which
+// relates its thickness to its grammage.
+const cardstockDensity = 0.8
+
+// Paper describes the paper a model is printed on.
+type Paper struct {
+	// Grammage is the weight of the paper in g/m². When 0 it follows from
+	// Thickness, or is DefaultGrammage.
+	Grammage float64
+	// Thickness is the thickness of the paper in mm, 0 when unknown.
+	Thickness float64
+}
+
+// grammage returns the weight of the paper in g/m².
+func (p Paper) grammage() float64 {
+	switch {
+	case p.Grammage > 0:
+		return p.Grammage
+	case p.Thickness > 0:
+		// 1 mm of paper at 1 g/cm³ weighs 1000 g/m².
+		return p.Thickness * cardstockDensity * 1000
+	}
+	return DefaultGrammage
+}
+
+// PartPaper is the paper used by a part.
+type PartPaper struct {
+	Part int
+	Name string
+	// Area is the area of the part's faces in mm².
+	Area float64
+	// FlapArea is the area of the part's flaps in mm².
+	FlapArea float64
+	// Weight is the weight of the cut out part in g.
+	Weight float64
+}
+
+// PaperEstimate is the paper used by a model.
+type PaperEstimate struct {
+	// Grammage is the paper weight estimated with, in g/m².
+	Grammage float64
+	// Area and FlapArea are the areas of all faces and flaps in mm².
+	Area     float64
+	FlapArea float64
+	// Weight is the estimated weight of the built model in g.
+	Weight float64
+	// Sheets is the number of pages holding parts, SheetWeight their
+	// weight in g before cutting.
+	Sheets      int
+	SheetWeight float64
+	// StackHeight is the height in mm of the printed sheets, 0 when the
+	// paper thickness is unknown.
+	StackHeight float64
+}
+
+// PartPaper measures the paper used by part i.
+func (p *PDO) PartPaper(i int, paper Paper) PartPaper {
+	part := &p.Parts[i]
+	pp := PartPaper{Part: i, Name: part.Name}
+	if part.ObjectIndex < 0 || int(part.ObjectIndex) >= len(p.Objects) {
+		return pp
+	}
+	obj := &p.Objects[part.ObjectIndex]
+	for _, face := range obj.PartFaces(i) {
+		pp.Area += face.Area2D()
+	}
+	for line := range part.VisibleLines() {
+		v1, v2 := obj.LineEnds(line)
+		if !obj.lineHasFlap(line, v1, v2) {
+			continue
+		}
+		a, b := vec2{v1.X, v1.Y}, vec2{v2.X, v2.Y}
+		out := outwardNormal(obj, line.FaceIndex, v1, v2)
+		if top1, top2, ok := flapTop(a, b, out, v1.FlapHeight, v1.FlapAAngle, v1.FlapBAngle); ok {
+			pp.FlapArea += polygonArea([]vec2{a, top1, top2, b})
+		}
+	}
+	pp.Weight = (pp.Area + pp.FlapArea) * 1e-6 * paper.grammage()
+	return pp
+}
+
+// EstimatePaper estimates the paper area and weight of the whole model and
+// the sheets it is printed on.
+func (p *PDO) EstimatePaper(paper Paper) PaperEstimate {
+	e := PaperEstimate{Grammage: paper.grammage()}
+	for i := range p.Parts {
+		pp := p.PartPaper(i, paper)
+		e.Area += pp.Area
+		e.FlapArea += pp.FlapArea
+		e.Weight += pp.Weight
+	}
+	dims := p.PageDims()
+	pages := p.PartPages(dims)
+	slices.SortFunc(pages, func(a, b [2]int) int { return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1])) })
+	e.Sheets = len(slices.Compact(pages))
+	e.SheetWeight = float64(e.Sheets) * dims.Width * dims.Height * 1e-6 * e.Grammage
+	e.StackHeight = float64(e.Sheets) * paper.Thickness
+	return e
+}
+
+// polygonArea returns the unsigned area of a polygon.
+func polygonArea(points []vec2) float64 {
+	var sum float64
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		sum += a.X*b.Y - b.X*a.Y
+	}
+	return math.Abs(sum) / 2
+}
@@ -0,0 +1,32 @@
+package pdo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimatePaper(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := p.EstimatePaper(Paper{})
+	if math.Abs(e.Area-Stats(p).TemplateArea) > 1e-6 || e.FlapArea <= 0 {
+		t.Errorf("area %g, flaps %g, want the template area %g and some flaps", e.Area, e.FlapArea, Stats(p).TemplateArea)
+	}
+	if want := (e.Area + e.FlapArea) * 1e-6 * DefaultGrammage; math.Abs(e.Weight-want) > 1e-9 {
+		t.Errorf("weight %g g, want %g", e.Weight, want)
+	}
+	if e.Sheets != 1 || e.StackHeight != 0 {
+		t.Errorf("%d sheets %g mm high, want 1 of unknown thickness", e.Sheets, e.StackHeight)
+	}
+
+	// 0.25 mm cardstock weighs about 200 g/m².
+	thick := p.EstimatePaper(Paper{Thickness: 0.25})
+	if math.Abs(thick.Grammage-200) > 1e-9 || math.Abs(thick.Weight/e.Weight-200.0/DefaultGrammage) > 1e-9 || thick.StackHeight != 0.25 {
+		t.Errorf("from thickness: %+v", thick)
+	}
+	if g := p.EstimatePaper(Paper{Grammage: 300, Thickness: 0.25}).Grammage; g != 300 {
+		t.Errorf("grammage %g, want the given 300", g)
+	}
+}
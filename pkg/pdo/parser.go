@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"unsafe"
 )
 
 const (
@@ -16,18 +17,39 @@ const (
 )
 
 type Parser struct {
-	reader *Reader
-	PDO    *PDO
+	reader  *Reader
+	PDO     *PDO
+	Options Options
+	// Strings describes how header strings were decoded.
+	Strings StringInfo
+
+	// parts is the number of parts read, which decides the settings layout.
+	parts int
+	// skipTextures leaves texture data unread, see Handler.SkipTextures.
+	skipTextures bool
 }
 
 func NewParser(r io.Reader) *Parser {
+	src := &source{}
+	reader := NewReader(r)
+	reader.raw = &src.strings
 	return &Parser{
-		reader: NewReader(r),
-		PDO:    &PDO{},
+		reader: reader,
+		PDO:    &PDO{source: src},
 	}
 }
 
 func ParseFile(filename string) (*PDO, error) {
+	parser, err := ParseFileWithOptions(filename, Options{})
+	if err != nil {
+		return nil, err
+	}
+	return parser.PDO, nil
+}
+
+// ParseFileWithOptions parses a file using the given options and returns the
+// parser, so callers can inspect diagnostics alongside the parsed PDO.
+func ParseFileWithOptions(filename string, opts Options) (*Parser, error) {
 	f, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -35,13 +57,15 @@ func ParseFile(filename string) (*PDO, error) {
 	defer f.Close()
 
 	parser := NewParser(f)
+	parser.Options = opts
 	if err := parser.Load(); err != nil {
 		return nil, err
 	}
-	return parser.PDO, nil
+	return parser, nil
 }
 
 func (p *Parser) Load() error {
+	p.reader.budget = &budget{limits: p.Options.Limits}
 	if err := p.ReadHeader(); err != nil {
 		return fmt.Errorf("failed to read header: %w", err)
 	}
@@ -57,6 +81,20 @@ func (p *Parser) Load() error {
 	if err := p.ReadSettings(); err != nil {
 		return fmt.Errorf("failed to read settings: %w", err)
 	}
+
+	// Keep what follows the settings, it isn't decoded yet apart from a
+	// preview written by pdo-tools.
+	rest, err := p.reader.ReadRest()
+	if err != nil {
+		return fmt.Errorf("failed to read trailing data: %w", wrapReadError(err))
+	}
+	src := p.PDO.source
+	p.PDO.Thumbnail, src.trailing = splitThumbnail(rest)
+	src.stringShift, src.multiByte, src.byteWise = p.reader.StringShift, p.reader.MultiByteC, p.reader.ByteWiseShift
+
+	p.Options.logger().Debug("parsed pdo", "objects", len(p.PDO.Objects),
+		"materials", len(p.PDO.Materials), "parts", len(p.PDO.Parts),
+		"text_blocks", len(p.PDO.TextBlocks), "images", len(p.PDO.Images))
 	return nil
 }
 
@@ -67,7 +105,7 @@ func (p *Parser) ReadHeader() error {
 		return fmt.Errorf("read magic failed: %w", err)
 	}
 	if string(magicBuf) != FileMagic {
-		return fmt.Errorf("invalid file magic: %q", string(magicBuf))
+		return fmt.Errorf("%w: %q", ErrInvalidMagic, string(magicBuf))
 	}
 
 	h := &p.PDO.Header
@@ -75,13 +113,18 @@ func (p *Parser) ReadHeader() error {
 	if err := p.reader.ReadBytes(&h.Version); err != nil {
 		return fmt.Errorf("read version failed: %w", err)
 	}
+	if h.Version < PDO_V4 || h.Version > PDO_V6 {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, h.Version)
+	}
 	if err := p.reader.ReadBytes(&h.MultiByteChars); err != nil {
 		return err
 	}
-	p.reader.MultiByteC = h.MultiByteChars == 1
+	p.reader.MultiByteC = h.MultiByteChars != 0
+	if p.Options.MultiByte != nil {
+		p.reader.MultiByteC = *p.Options.MultiByte
+	}
 
-	var unknownInt int32
-	if err := p.reader.ReadBytes(&unknownInt); err != nil { // Unknown int
+	if err := p.reader.ReadBytes(&p.PDO.source.unknownInt); err != nil { // Unknown int
 		return fmt.Errorf("read unknown int failed: %w", err)
 	}
 
@@ -90,13 +133,12 @@ func (p *Parser) ReadHeader() error {
 	// fpdo.ReadBytes(header.multi_byte_chars, 4);
 	// fpdo.ReadBytes(unknown_int, 4);
 
-	// My previous ReadUInt32 would read it. But I should store it or discard it.
-	// I used ReadBytes directly above.
-	// Let's use p.reader.ReadInt32() for better readability.
-
+	// Header strings are read raw and decoded once the whole header is known,
+	// so that string recovery can look at all of them together.
+	var designerID []byte
 	if h.Version > PDO_V4 {
 		var err error
-		h.DesignerID, err = p.reader.ReadString(0)
+		designerID, err = p.reader.ReadRawString()
 		if err != nil {
 			return err
 		}
@@ -105,14 +147,16 @@ func (p *Parser) ReadHeader() error {
 		}
 		p.reader.StringShift = byte(h.StringShift)
 	}
+	if p.Options.StringShift != nil {
+		p.reader.StringShift = *p.Options.StringShift
+	}
 
-	var err error
-	h.Locale, err = p.reader.ReadShiftedString()
+	locale, err := p.reader.ReadRawString()
 	if err != nil {
 		return err
 	}
 
-	h.Codepage, err = p.reader.ReadShiftedString()
+	codepage, err := p.reader.ReadRawString()
 	if err != nil {
 		return err
 	}
@@ -136,19 +180,33 @@ func (p *Parser) ReadHeader() error {
 	// Skip the check or implement peek if critical. It seems to be for "dodgy files".
 	// We'll trust standard files for now.
 
-	h.Key, err = p.reader.ReadShiftedString()
+	key, err := p.reader.ReadRawString()
 	if err != nil {
 		return err
 	}
 
+	p.decodeHeaderStrings(designerID, [][]byte{locale, codepage, key})
+	h.DesignerID = DecodeString(designerID, 0, p.reader.MultiByteC, p.reader.ByteWiseShift)
+	p.PDO.source.designerID = designerID
+	h.Locale = p.reader.decode(locale)
+	h.Codepage = p.reader.decode(codepage)
+	h.Key = p.reader.decode(key)
+
+	p.Options.logger().Debug("read header", "version", h.Version, "designer", h.DesignerID,
+		"locale", h.Locale, "codepage", h.Codepage, "string_shift", p.reader.StringShift,
+		"multi_byte", p.reader.MultiByteC)
+
 	if h.Version == PDO_V6 {
 		if err := p.reader.ReadBytes(&h.V6Lock); err != nil {
 			return err
 		}
+		if err := p.reader.budget.alloc("lock entries", h.V6Lock, 8); err != nil {
+			return err
+		}
 		if h.V6Lock > 0 {
-			junk := make([]byte, 8)
-			for i := 0; i < int(h.V6Lock); i++ {
-				p.reader.ReadBytes(junk)
+			p.PDO.source.v6Lock = make([]byte, 8*int(h.V6Lock))
+			if err := p.reader.ReadBytes(p.PDO.source.v6Lock); err != nil {
+				return err
 			}
 		}
 	} else {
@@ -178,6 +236,9 @@ func (p *Parser) ReadObjects() error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("objects", count, unsafe.Sizeof(Object{})); err != nil {
+		return err
+	}
 	p.PDO.Objects = make([]Object, count)
 	for i := 0; i < int(count); i++ {
 		if err := p.ReadObject(&p.PDO.Objects[i]); err != nil {
@@ -203,6 +264,9 @@ func (p *Parser) ReadObject(obj *Object) error {
 		return err
 	}
 
+	if err := p.reader.budget.addVertices(numVertices); err != nil {
+		return err
+	}
 	obj.Vertices = make([]Vertex3D, numVertices)
 	if err := p.reader.ReadBytes(obj.Vertices); err != nil {
 		return err
@@ -213,6 +277,9 @@ func (p *Parser) ReadObject(obj *Object) error {
 		return err
 	}
 
+	if err := p.reader.budget.addFaces(numFaces); err != nil {
+		return err
+	}
 	obj.Faces = make([]Face, numFaces)
 	for i := 0; i < int(numFaces); i++ {
 		if err := p.ReadFace(&obj.Faces[i]); err != nil {
@@ -225,6 +292,9 @@ func (p *Parser) ReadObject(obj *Object) error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("edges", numEdges, unsafe.Sizeof(Edge{})); err != nil {
+		return err
+	}
 	obj.Edges = make([]Edge, numEdges)
 	for i := 0; i < int(numEdges); i++ {
 		// Read 22 bytes for each edge
@@ -267,6 +337,9 @@ func (p *Parser) ReadFace(face *Face) error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("face vertices", count, unsafe.Sizeof(Face2DVertex{})); err != nil {
+		return err
+	}
 	face.Vertices = make([]Face2DVertex, count)
 	for i := 0; i < int(count); i++ {
 		if err := p.ReadFace2DVertex(&face.Vertices[i]); err != nil {
@@ -277,7 +350,7 @@ func (p *Parser) ReadFace(face *Face) error {
 }
 
 func (p *Parser) ReadFace2DVertex(v *Face2DVertex) error {
-	// 4 + 8*4 + 1 + 8*3 + 24
+	// 4 + 8*4 + 1 + 8*3 + 4*3 + 4*3
 	if err := p.reader.ReadBytes(&v.IDVertex); err != nil {
 		return err
 	}
@@ -305,7 +378,10 @@ func (p *Parser) ReadFace2DVertex(v *Face2DVertex) error {
 	if err := p.reader.ReadBytes(&v.FlapBAngle); err != nil {
 		return err
 	}
-	if err := p.reader.ReadBytes(&v.FlapFoldInfo); err != nil {
+	if err := p.reader.ReadBytes(&v.FlapLineColor); err != nil {
+		return err
+	}
+	if err := p.reader.ReadBytes(&v.FoldLineColor); err != nil {
 		return err
 	}
 	return nil
@@ -317,6 +393,9 @@ func (p *Parser) ReadMaterials() error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("materials", count, unsafe.Sizeof(Material{})); err != nil {
+		return err
+	}
 	p.PDO.Materials = make([]Material, count)
 	for i := 0; i < int(count); i++ {
 		if err := p.ReadMaterial(&p.PDO.Materials[i]); err != nil {
@@ -392,17 +471,30 @@ func (p *Parser) ReadTexture(tex *Texture) error {
 		return err
 	}
 
+	if err := p.reader.budget.texture(tex.Width, tex.Height); err != nil {
+		return err
+	}
+
 	var wrappedSize int32
 	if err := p.reader.ReadBytes(&wrappedSize); err != nil {
 		return err
 	}
 
+	if wrappedSize < TextureDataWrapperSize {
+		return fmt.Errorf("%w: wrapped size %d is smaller than the wrapper", ErrBadTexture, wrappedSize)
+	}
 	tex.DataSize = uint32(wrappedSize - TextureDataWrapperSize)
+	if p.skipTextures {
+		return p.reader.Skip(int64(wrappedSize))
+	}
 
 	if err := p.reader.ReadBytes(&tex.DataHeader); err != nil {
 		return err
 	}
 
+	if err := p.reader.budget.grow("bytes of texture data", int64(tex.DataSize), 1); err != nil {
+		return err
+	}
 	tex.RawData = make([]byte, tex.DataSize)
 	if err := p.reader.ReadBytes(tex.RawData); err != nil {
 		return err
@@ -436,8 +528,8 @@ func (p *Parser) ReadUnfoldData() error {
 		return err
 	}
 
-	var padding uint8
-	if err := p.reader.ReadBytes(&padding); err != nil {
+	p.PDO.source.hasUnfold = true
+	if err := p.reader.ReadBytes(&p.PDO.source.unfoldPadding); err != nil {
 		return err
 	}
 
@@ -464,7 +556,11 @@ func (p *Parser) ReadParts() error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("parts", count, unsafe.Sizeof(Part{})); err != nil {
+		return err
+	}
 	p.PDO.Parts = make([]Part, count)
+	p.parts = int(count)
 	for i := 0; i < int(count); i++ {
 		if err := p.ReadPart(&p.PDO.Parts[i]); err != nil {
 			return err
@@ -494,6 +590,9 @@ func (p *Parser) ReadPart(part *Part) error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("lines", count, unsafe.Sizeof(Line{})); err != nil {
+		return err
+	}
 	part.Lines = make([]Line, count)
 	for i := 0; i < int(count); i++ {
 		if err := p.ReadLine(&part.Lines[i]); err != nil {
@@ -513,6 +612,10 @@ func (p *Parser) ReadLine(l *Line) error {
 	if err := p.reader.ReadBytes(&l.Type); err != nil {
 		return err
 	}
+	// Types above 3 are invisible lines, negative ones aren't defined.
+	if l.Type < 0 {
+		return fmt.Errorf("%w: line type %d", ErrLimitExceeded, l.Type)
+	}
 
 	var unknownByte uint8
 	if err := p.reader.ReadBytes(&unknownByte); err != nil {
@@ -550,6 +653,9 @@ func (p *Parser) ReadTextBlocks() error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("text blocks", count, unsafe.Sizeof(TextBlock{})); err != nil {
+		return err
+	}
 	p.PDO.TextBlocks = make([]TextBlock, count)
 	for i := 0; i < int(count); i++ {
 		if err := p.ReadTextBlock(&p.PDO.TextBlocks[i]); err != nil {
@@ -584,6 +690,9 @@ func (p *Parser) ReadTextBlock(tb *TextBlock) error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("text lines", count, unsafe.Sizeof("")); err != nil {
+		return err
+	}
 	tb.Lines = make([]string, count)
 	for i := 0; i < int(count); i++ {
 		tb.Lines[i], err = p.reader.ReadShiftedString()
@@ -601,7 +710,11 @@ func (p *Parser) ReadImages() error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("images", count, unsafe.Sizeof(Image{})); err != nil {
+		return err
+	}
 	p.PDO.Images = make([]Image, count)
+	p.PDO.source.overImages = int(count)
 	for i := 0; i < int(count); i++ {
 		if err := p.ReadImage(&p.PDO.Images[i]); err != nil {
 			return err
@@ -614,6 +727,9 @@ func (p *Parser) ReadImages() error {
 		return err
 	}
 
+	if err := p.reader.budget.alloc("images", addCount, unsafe.Sizeof(Image{})); err != nil {
+		return err
+	}
 	if addCount > 0 {
 		oldLen := len(p.PDO.Images)
 		newLen := oldLen + int(addCount)
@@ -644,23 +760,31 @@ func (p *Parser) ReadImage(img *Image) error {
 
 func (p *Parser) ReadSettings() error {
 	// Unknown settings (v6)
-	if p.PDO.Header.Version == PDO_V6 && len(p.PDO.Parts) > 0 {
+	if p.PDO.Header.Version == PDO_V6 && p.parts > 0 {
 		var count int32
 		if err := p.reader.ReadBytes(&count); err != nil {
 			return err
 		}
 
+		if err := p.reader.budget.alloc("settings items", count, unsafe.Sizeof([]int32{})); err != nil {
+			return err
+		}
+		p.PDO.source.settingsItems = make([][]int32, count)
 		for i := 0; i < int(count); i++ {
 			var parts int32
 			if err := p.reader.ReadBytes(&parts); err != nil {
 				return err
 			}
 
-			// Skip data
-			skip := make([]byte, 4*parts)
-			if err := p.reader.ReadBytes(skip); err != nil {
+			// Keep the data for writing, its meaning is unknown
+			if err := p.reader.budget.alloc("settings values", parts, 4); err != nil {
+				return err
+			}
+			item := make([]int32, parts)
+			if err := p.reader.ReadBytes(item); err != nil {
 				return err
 			}
+			p.PDO.source.settingsItems[i] = item
 		}
 	}
 
@@ -0,0 +1,294 @@
+package pdo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFileWithOptions_RecoverStrings(t *testing.T) {
+	sample, err := os.ReadFile("../../sample_basic_shapes/pyramid.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	load := func(data []byte, opts Options) *Parser {
+		t.Helper()
+		parser := NewParser(bytes.NewReader(data))
+		parser.Options = opts
+		if err := parser.Load(); err != nil {
+			t.Fatal(err)
+		}
+		return parser
+	}
+
+	// The sample flags multi-byte strings with 3 and shifts each byte.
+	parser := load(sample, Options{})
+	if parser.Strings.HeaderMultiByte != 3 || !parser.Strings.MultiByte || !parser.Strings.ByteWiseShift || parser.Strings.Recovered {
+		t.Errorf("strings decoded with %+v", parser.Strings)
+	}
+	if got, want := parser.PDO.Header.DesignerID, "Pepakura Designer 6"; got != want {
+		t.Errorf("DesignerID got %q, want %q", got, want)
+	}
+	if got, want := parser.PDO.Header.Codepage, "932"; got != want {
+		t.Errorf("Codepage got %q, want %q", got, want)
+	}
+	if got, want := parser.PDO.Parts[0].Name, "Object-1"; got != want {
+		t.Errorf("part name got %q, want %q", got, want)
+	}
+
+	// A damaged shift garbles the strings with a warning, recovery finds
+	// the right one.
+	damaged := slices.Clone(sample)
+	at := len(FileMagic) + 12
+	at += 4 + int(binary.LittleEndian.Uint32(damaged[at:]))
+	damaged[at]++
+	var log bytes.Buffer
+	parser = load(damaged, Options{Logger: slog.New(slog.NewTextHandler(&log, nil))})
+	if parser.PDO.Parts[0].Name == "Object-1" || !strings.Contains(log.String(), "header strings look garbled") {
+		t.Errorf("damaged shift read part name %q, log:\n%s", parser.PDO.Parts[0].Name, log.String())
+	}
+	parser = load(damaged, Options{RecoverStrings: true, Logger: slog.New(slog.DiscardHandler)})
+	if !parser.Strings.Recovered || parser.PDO.Parts[0].Name != "Object-1" || parser.PDO.Header.Codepage != "932" {
+		t.Errorf("recovered part name %q, codepage %q, with %+v", parser.PDO.Parts[0].Name, parser.PDO.Header.Codepage, parser.Strings)
+	}
+}
+
+func TestParseFileWithOptions_Logger(t *testing.T) {
+	var log bytes.Buffer
+	parser, err := ParseFileWithOptions("../../sample_basic_shapes/pyramid.pdo",
+		Options{Logger: slog.New(slog.NewJSONHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug}))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := map[string]map[string]any{}
+	dec := json.NewDecoder(&log)
+	for dec.More() {
+		var r map[string]any
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records[r["msg"].(string)] = r
+	}
+	if r := records["read header"]; r == nil || r["level"] != "DEBUG" || r["version"] != float64(PDO_V6) || r["codepage"] != "932" {
+		t.Errorf("read header record %v", r)
+	}
+	if r := records["parsed pdo"]; r == nil || r["parts"] != float64(len(parser.PDO.Parts)) || r["objects"] != float64(len(parser.PDO.Objects)) {
+		t.Errorf("parsed pdo record %v", r)
+	}
+
+	// Debug records stay out of the default level.
+	log.Reset()
+	if _, err := ParseFileWithOptions("../../sample_basic_shapes/pyramid.pdo", Options{Logger: slog.New(slog.NewTextHandler(&log, nil))}); err != nil {
+		t.Fatal(err)
+	}
+	if log.Len() != 0 {
+		t.Errorf("logged at the info level:\n%s", log.String())
+	}
+}
+
+func TestLoad_ErrorCategories(t *testing.T) {
+	sample, err := os.ReadFile("../../sample_basic_shapes/pyramid.pdo")
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+
+	versionPatched := append([]byte(nil), sample...)
+	binary.LittleEndian.PutUint32(versionPatched[len(FileMagic):], 9)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"invalid magic", []byte("not a pdo file at all"), ErrInvalidMagic},
+		{"unsupported version", versionPatched, ErrUnsupportedVersion},
+		{"truncated", sample[:len(sample)/2], ErrTruncated},
+	}
+
+	for _, tt := range tests {
+		err := NewParser(bytes.NewReader(tt.data)).Load()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestLoad_Limits(t *testing.T) {
+	sample, err := os.ReadFile("../../sample_basic_shapes/pyramid.pdo")
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+
+	textured := New()
+	textured.Materials = []Material{{Name: "paper", HasTexture: true}}
+	if err := textured.Materials[0].Texture.SetImage(image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, textured); err != nil {
+		t.Fatal(err)
+	}
+
+	// A long tail after the settings counts against the allocation limit.
+	parser := NewParser(bytes.NewReader(sample))
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	parsed := parser.reader.budget.allocated
+	tail := append(slices.Clone(sample), make([]byte, 1<<20)...)
+
+	// A negative line type, and a negative lock count patched in where
+	// a file written with one lock entry differs from one without.
+	p := parser.PDO.Clone()
+	p.Parts[0].Lines[0].Type = -1
+	var lineType bytes.Buffer
+	if err := Write(&lineType, p); err != nil {
+		t.Fatal(err)
+	}
+	var unlocked, locked bytes.Buffer
+	p = parser.PDO.Clone()
+	p.Header.V6Lock = 0
+	if err := Write(&unlocked, p); err != nil {
+		t.Fatal(err)
+	}
+	p.Header.V6Lock = 1
+	if err := Write(&locked, p); err != nil {
+		t.Fatal(err)
+	}
+	lock := unlocked.Bytes()
+	i := 0
+	for lock[i] == locked.Bytes()[i] {
+		i++
+	}
+	binary.LittleEndian.PutUint32(lock[i:], 0xFFFFFFFF)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		limits Limits
+		want   error
+	}{
+		{"defaults", sample, DefaultLimits, nil},
+		{"vertices", sample, Limits{MaxVertices: 4}, ErrLimitExceeded},
+		{"faces", sample, Limits{MaxFaces: 4}, ErrLimitExceeded},
+		{"allocation", sample, Limits{MaxAlloc: 1000}, ErrLimitExceeded},
+		{"texture", buf.Bytes(), Limits{MaxTexturePixels: 15}, ErrLimitExceeded},
+		{"texture in limit", buf.Bytes(), Limits{MaxTexturePixels: 16}, nil},
+		{"trailing data", tail, Limits{MaxAlloc: parsed + 1000}, ErrLimitExceeded},
+		{"trailing data in limit", tail, Limits{MaxAlloc: parsed + 1<<20}, nil},
+		{"line type", lineType.Bytes(), DefaultLimits, ErrLimitExceeded},
+		{"lock count", lock, DefaultLimits, ErrLimitExceeded},
+		{"lock count without limits", lock, Limits{}, ErrLimitExceeded},
+	}
+	for _, tt := range tests {
+		parser := NewParser(bytes.NewReader(tt.data))
+		parser.Options.Limits = tt.limits
+		if err := parser.Load(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestClone_Equal(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	c := p.Clone()
+	if !c.Equal(p) {
+		t.Fatalf("clone is not equal to the original")
+	}
+
+	c.Objects[0].Faces[0].Vertices[0].X += 1
+	if c.Equal(p) {
+		t.Errorf("modified clone still equal to the original")
+	}
+	if p.Objects[0].Faces[0].Vertices[0].X == c.Objects[0].Faces[0].Vertices[0].X {
+		t.Errorf("modifying the clone changed the original")
+	}
+
+	var before bytes.Buffer
+	if err := Write(&before, p); err != nil {
+		t.Fatal(err)
+	}
+	for _, rs := range c.source.strings {
+		for i := range rs.raw {
+			rs.raw[i]++
+		}
+	}
+	c.source.designerID[0]++
+	c.source.trailing[0]++
+	var after bytes.Buffer
+	if err := Write(&after, p); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Errorf("modifying the clone's source data changed how the original is written")
+	}
+
+	empty := &Texture{RawData: []byte{}}
+	if !empty.Equal(&Texture{}) {
+		t.Errorf("nil and empty texture data should compare equal")
+	}
+}
+
+// The 24 bytes after the flap angles of a 2D vertex were documented as
+// fold line rendering vectors. Every sample draws fold lines between faces
+// and flaps, yet stores zeros in them for every vertex, which rules out
+// geometry Pepakura draws with and matches colors left unset.
+func TestParse_VertexLineColors(t *testing.T) {
+	files, err := filepath.Glob("../../sample_basic_shapes/*.pdo")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no samples: %v", err)
+	}
+	for _, file := range files {
+		p, err := ParseFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var folds, flaps int
+		check := func(v *Face2DVertex) {
+			t.Helper()
+			if v.FlapLineColor != [3]float32{} || v.FoldLineColor != [3]float32{} {
+				t.Errorf("%s: vertex %d has line data %v %v", file, v.IDVertex, v.FlapLineColor, v.FoldLineColor)
+			}
+		}
+		for i := range p.Parts {
+			part := &p.Parts[i]
+			for j := range part.Lines {
+				line := &part.Lines[j]
+				if !line.IsConnectingFaces {
+					continue
+				}
+				if v1, v2 := p.Objects[part.ObjectIndex].LineEnds(line); v1 != nil {
+					folds++
+					check(v1)
+					check(v2)
+				}
+			}
+		}
+		for _, obj := range p.Objects {
+			for _, f := range obj.Faces {
+				for k := range f.Vertices {
+					if f.Vertices[k].Flap != 0 {
+						flaps++
+						check(&f.Vertices[k])
+					}
+				}
+			}
+		}
+		if folds == 0 || flaps == 0 {
+			t.Errorf("%s: %d fold lines and %d flaps, the sample shows nothing", file, folds, flaps)
+		}
+	}
+}
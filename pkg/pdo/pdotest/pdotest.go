@@ -0,0 +1,215 @@
+// Package pdotest builds small synthetic PDO models for tests. The models
+// cover the features the exporters handle, file versions, multi-byte
+// strings, textures, flaps and layouts over several pages, without relying
+// on models made by others.
+package pdotest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"pdo-tools/pkg/pdo"
+)
+
+// Options selects the features of a generated model.
+type Options struct {
+	// Version is the file format version, pdo.PDO_V6 when zero.
+	Version int32
+	// MultiByte stores strings as UTF-16 and gives the model Japanese
+	// names.
+	MultiByte bool
+	// Texture gives the material a checkerboard texture.
+	Texture bool
+	// Pages is the number of cubes, each unfolded on a page of its own
+	// in a row. One when zero.
+	Pages int
+	// Size is the side of the cubes in mm, 40 when zero.
+	Size float64
+	// Name names the object and is the base of the part names, "cube"
+	// when empty.
+	Name string
+}
+
+// Margin is the distance of every part from the top left corner of its
+// page, in mm.
+const Margin = 10
+
+// cubeVertices are the corners of the unit cube.
+var cubeVertices = []pdo.Vertex3D{
+	{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 1, Y: 1, Z: 0}, {X: 0, Y: 1, Z: 0},
+	{X: 0, Y: 0, Z: 1}, {X: 1, Y: 0, Z: 1}, {X: 1, Y: 1, Z: 1}, {X: 0, Y: 1, Z: 1},
+}
+
+// cubeFace is a face of the cube net: its corners counter-clockwise around
+// the outward normal and their place on the net in units of the side.
+type cubeFace struct {
+	vertices []int32
+	net      [][2]float64
+}
+
+// cubeNet unfolds the cube into a cross, the front face in the middle,
+// top above, bottom below, left and right beside it and the back right of
+// the right face.
+var cubeNet = []cubeFace{
+	{[]int32{4, 5, 6, 7}, [][2]float64{{1, 2}, {2, 2}, {2, 1}, {1, 1}}}, // front
+	{[]int32{7, 6, 2, 3}, [][2]float64{{1, 1}, {2, 1}, {2, 0}, {1, 0}}}, // top
+	{[]int32{0, 1, 5, 4}, [][2]float64{{1, 3}, {2, 3}, {2, 2}, {1, 2}}}, // bottom
+	{[]int32{0, 4, 7, 3}, [][2]float64{{0, 2}, {1, 2}, {1, 1}, {0, 1}}}, // left
+	{[]int32{5, 1, 2, 6}, [][2]float64{{2, 2}, {3, 2}, {3, 1}, {2, 1}}}, // right
+	{[]int32{1, 0, 3, 2}, [][2]float64{{3, 2}, {4, 2}, {4, 1}, {3, 1}}}, // back
+}
+
+// cubeFolds are the faces of cubeNet joined on the net.
+var cubeFolds = [][2]int32{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {4, 5}}
+
+// Cube returns a model of Options.Pages cubes, each its own object and
+// part. The parts are fully laid out: edges are folded as mountains where
+// the net joins faces and cut elsewhere, with a flap on one side of every
+// cut edge and a text block on the first page.
+func Cube(opts Options) *pdo.PDO {
+	if opts.Version == 0 {
+		opts.Version = pdo.PDO_V6
+	}
+	opts.Pages = max(opts.Pages, 1)
+	if opts.Size == 0 {
+		opts.Size = 40
+	}
+	name, material, text := opts.Name, "paper", "Glue the flaps inside."
+	if opts.MultiByte {
+		if name == "" {
+			name = "立方体"
+		}
+		material, text = "紙", "のりしろを内側に貼る。"
+	}
+	if name == "" {
+		name = "cube"
+	}
+
+	p := pdo.New()
+	p.Header.Version = opts.Version
+	if opts.MultiByte {
+		p.Header.MultiByteChars = 1
+	}
+	mat := pdo.Material{Name: material}
+	mat.Color3D.Model = pdo.RGBA{R: 0.8, G: 0.6, B: 0.2, A: 1}
+	mat.Color3D.Diffuse = mat.Color3D.Model
+	mat.Color3D.Material = pdo.RGBA{R: 0.2, G: 0.2, B: 0.2, A: 1}
+	mat.Color3D.Light = pdo.RGBA{A: 1}
+	mat.Color2DRGBA = [4]float32{0.8, 0.6, 0.2, 1}
+	if opts.Texture {
+		mat.HasTexture = true
+		if err := mat.Texture.SetImage(checkerboard(8, 8)); err != nil {
+			panic(err) // The image is always encodable.
+		}
+	}
+	p.Materials = []pdo.Material{mat}
+
+	dims := p.PageDims()
+	for i := range opts.Pages {
+		objName := name
+		if opts.Pages > 1 {
+			objName = fmt.Sprintf("%s %d", name, i+1)
+		}
+		obj, part := cube(opts.Size, int32(i), opts.Texture)
+		obj.Name, part.Name = objName, objName
+		part.BoundingBox.Left += float64(i)*dims.ClippedWidth + Margin
+		part.BoundingBox.Top += Margin
+		p.Objects = append(p.Objects, obj)
+		p.Parts = append(p.Parts, part)
+		p.Unfold.BoundingBox = p.Unfold.BoundingBox.Union(part.BoundingBox)
+	}
+	p.UpdateBounds()
+
+	p.TextBlocks = []pdo.TextBlock{{
+		BoundingBox: pdo.Rect{Left: Margin, Top: Margin + 4*opts.Size, Width: 60, Height: 5},
+		LineSpacing: 1,
+		FontSize:    12,
+		FontName:    "Arial",
+		Lines:       []string{text},
+	}}
+	return p
+}
+
+// cube builds a cube of side s as object and part i, the part at the
+// origin.
+func cube(s float64, i int32, textured bool) (pdo.Object, pdo.Part) {
+	// Flaps on the border of the net stay inside the part box.
+	h := s / 4
+	obj := pdo.Object{Visible: 1}
+	for _, v := range cubeVertices {
+		obj.Vertices = append(obj.Vertices, v.Scale(s))
+	}
+	for _, cf := range cubeNet {
+		f := pdo.Face{PartIndex: i}
+		for j, id := range cf.vertices {
+			x, y := cf.net[j][0], cf.net[j][1]
+			fv := pdo.Face2DVertex{IDVertex: id, X: x*s + h, Y: y*s + h}
+			if textured {
+				fv.U, fv.V = x/4, y/3
+			}
+			f.Vertices = append(f.Vertices, fv)
+		}
+		obj.Faces = append(obj.Faces, f)
+	}
+	obj.UpdateFaceNormals()
+	obj.BuildEdges()
+
+	part := pdo.Part{
+		ObjectIndex: i,
+		BoundingBox: pdo.Rect{Width: 4*s + 2*h, Height: 3*s + 2*h},
+	}
+	for ei := range obj.Edges {
+		e := &obj.Edges[ei]
+		if folded(e) {
+			e.ConnectsFaces = 1
+			part.Lines = append(part.Lines, pdo.Line{
+				Type:              1,
+				FaceIndex:         e.Face1Index,
+				VertexIndex:       e.Vertex1Index,
+				IsConnectingFaces: true,
+				Face2Index:        e.Face2Index,
+				Vertex2Index:      e.Vertex2Index,
+			})
+			continue
+		}
+		// Cut edges are drawn on both faces. The second face runs the
+		// edge the other way.
+		part.Lines = append(part.Lines,
+			pdo.Line{FaceIndex: e.Face1Index, VertexIndex: e.Vertex1Index},
+			pdo.Line{FaceIndex: e.Face2Index, VertexIndex: e.Vertex2Index})
+		f := &obj.Faces[e.Face1Index]
+		for j := range f.Vertices {
+			if v := &f.Vertices[j]; v.IDVertex == e.Vertex1Index {
+				v.Flap, v.FlapHeight = 1, h
+				v.FlapAAngle, v.FlapBAngle = math.Pi/4, math.Pi/4
+			}
+		}
+	}
+	return obj, part
+}
+
+func folded(e *pdo.Edge) bool {
+	for _, f := range cubeFolds {
+		if f == [2]int32{e.Face1Index, e.Face2Index} || f == [2]int32{e.Face2Index, e.Face1Index} {
+			return true
+		}
+	}
+	return false
+}
+
+// checkerboard returns a w×h image of alternating dark and light pixels.
+func checkerboard(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			c := color.RGBA{R: 230, G: 220, B: 200, A: 255}
+			if (x+y)%2 == 1 {
+				c = color.RGBA{R: 40, G: 60, B: 120, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
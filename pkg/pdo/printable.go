@@ -0,0 +1,157 @@
+package pdo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PrintableArea is the area of a page a printer prints on, given by the
+// width in mm of the unprintable band along each paper edge.
+type PrintableArea struct {
+	Top, Right, Bottom, Left float64
+}
+
+// PrinterPresets are the printable areas of common home printers. Inkjets
+// usually need a wider band at the bottom edge, lasers about 1/6 inch all
+// around.
+var PrinterPresets = map[string]PrintableArea{
+	"borderless": {},
+	"inkjet":     {Top: 3, Right: 3.4, Bottom: 5, Left: 3.4},
+	"laser":      {Top: 4.2, Right: 4.2, Bottom: 4.2, Left: 4.2},
+}
+
+// ParsePrintableArea parses a preset name from PrinterPresets or the
+// unprintable margins in mm as "all", "top,right,bottom,left" or, like in
+// CSS, "vertical,horizontal".
+func ParsePrintableArea(s string) (PrintableArea, error) {
+	if a, ok := PrinterPresets[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return a, nil
+	}
+	fields := strings.Split(s, ",")
+	m := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil || v < 0 {
+			return PrintableArea{}, fmt.Errorf("invalid printable area %q: want a preset or margins in mm", s)
+		}
+		m[i] = v
+	}
+	switch len(m) {
+	case 1:
+		return PrintableArea{m[0], m[0], m[0], m[0]}, nil
+	case 2:
+		return PrintableArea{m[0], m[1], m[0], m[1]}, nil
+	case 4:
+		return PrintableArea{m[0], m[1], m[2], m[3]}, nil
+	}
+	return PrintableArea{}, fmt.Errorf("invalid printable area %q: want 1, 2 or 4 margins", s)
+}
+
+// Unprintable is a part reaching beyond the printable area of its page.
+type Unprintable struct {
+	Part int    `json:"part"`
+	Name string `json:"name,omitempty"`
+	// Page is the column and row of the part's page, from 0.
+	Page [2]int `json:"page"`
+	// Top, Right, Bottom and Left are how far in mm the part, flaps
+	// included, reaches past each edge of the printable area.
+	Top    float64 `json:"top"`
+	Right  float64 `json:"right"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
+	// Lines counts the visible lines of the part with an end outside the
+	// printable area.
+	Lines int `json:"lines"`
+	// Fits reports whether the part fits in the printable area when moved.
+	Fits bool `json:"fits"`
+}
+
+// printable returns the printable area of a page in page coordinates: the
+// part of the printer's area inside the page margins of the settings,
+// since exporters cut off what lies beyond those.
+func (a PrintableArea) printable(dims PageDims) Rect {
+	left := max(a.Left, dims.MarginLeft)
+	top := max(a.Top, dims.MarginTop)
+	right := min(dims.Width-a.Right, dims.MarginLeft+dims.ClippedWidth)
+	bottom := min(dims.Height-a.Bottom, dims.MarginTop+dims.ClippedHeight)
+	return Rect{Left: left, Top: top, Width: right - left, Height: bottom - top}
+}
+
+// CheckPrintable lists the parts reaching beyond the printable area of
+// their page, where a home printer would cut off flap and face edges.
+func (p *PDO) CheckPrintable(area PrintableArea) []Unprintable {
+	dims := p.PageDims()
+	r := area.printable(dims)
+	pages := p.PartPages(dims)
+	var out []Unprintable
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		points := p.partOutline(i)
+		if len(points) == 0 {
+			continue
+		}
+		// Page coordinates of part coordinates.
+		offX := part.BoundingBox.Left - float64(pages[i][0])*dims.ClippedWidth + dims.MarginLeft
+		offY := part.BoundingBox.Top - float64(pages[i][1])*dims.ClippedHeight + dims.MarginTop
+		lo, hi := bounds(points, func(v vec2) vec2 { return vec2{v.X + offX, v.Y + offY} })
+		u := Unprintable{
+			Part:   i,
+			Name:   part.Name,
+			Page:   pages[i],
+			Top:    max(r.Top-lo.Y, 0),
+			Right:  max(hi.X-r.Right(), 0),
+			Bottom: max(hi.Y-r.Bottom(), 0),
+			Left:   max(r.Left-lo.X, 0),
+			Fits:   hi.X-lo.X <= r.Width && hi.Y-lo.Y <= r.Height,
+		}
+		if u.Top+u.Right+u.Bottom+u.Left < printTolerance {
+			continue
+		}
+		inside := func(v *Face2DVertex) bool {
+			x, y := v.X+offX, v.Y+offY
+			return x >= r.Left-printTolerance && x <= r.Right()+printTolerance &&
+				y >= r.Top-printTolerance && y <= r.Bottom()+printTolerance
+		}
+		obj := &p.Objects[part.ObjectIndex]
+		for line := range part.VisibleLines() {
+			if v1, v2 := obj.LineEnds(line); v1 != nil && v2 != nil && (!inside(v1) || !inside(v2)) {
+				u.Lines++
+			}
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+// printTolerance is how far in mm a part may reach past the printable area
+// without being reported, to ignore rounding.
+const printTolerance = 1e-6
+
+// NudgePrintable moves the parts CheckPrintable reports inward on their
+// page, just far enough to lie in the printable area. Parts larger than the
+// area stay where they are. Moved parts may overlap their neighbors. It
+// returns the number of parts moved.
+func (p *PDO) NudgePrintable(area PrintableArea) int {
+	moved := 0
+	for _, u := range p.CheckPrintable(area) {
+		if !u.Fits {
+			continue
+		}
+		bb := &p.Parts[u.Part].BoundingBox
+		bb.Left += u.Left - u.Right
+		bb.Top += u.Top - u.Bottom
+		moved++
+	}
+	if moved > 0 {
+		p.updateLayoutBounds()
+	}
+	return moved
+}
+
+// String formats the margins as ParsePrintableArea reads them.
+func (a PrintableArea) String() string {
+	f := func(v float64) string { return strconv.FormatFloat(math.Round(v*1e3)/1e3, 'f', -1, 64) }
+	return f(a.Top) + "," + f(a.Right) + "," + f(a.Bottom) + "," + f(a.Left)
+}
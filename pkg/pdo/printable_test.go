@@ -0,0 +1,53 @@
+package pdo
+
+import "testing"
+
+func TestCheckPrintable(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cylinder.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	laser := PrinterPresets["laser"]
+	if got := p.CheckPrintable(laser); len(got) != 0 {
+		t.Fatalf("laid out part reported: %+v", got)
+	}
+
+	// Push the part 30 mm past the right page margin.
+	dims := p.PageDims()
+	p.Parts[0].BoundingBox.Left = dims.ClippedWidth + 30 - p.PartBounds(0).Right()
+	got := p.CheckPrintable(laser)
+	if len(got) != 1 {
+		t.Fatalf("%d parts reported, want 1", len(got))
+	}
+	if u := got[0]; u.Part != 0 || u.Page != [2]int{0, 0} || u.Right < 29.9 || u.Right > 30.1 || u.Left != 0 || !u.Fits || u.Lines == 0 {
+		t.Errorf("unexpected report %+v", u)
+	}
+
+	if n := p.NudgePrintable(laser); n != 1 {
+		t.Errorf("nudged %d parts, want 1", n)
+	}
+	if got := p.CheckPrintable(laser); len(got) != 0 {
+		t.Errorf("still outside after nudging: %+v", got)
+	}
+	if p.NudgePrintable(PrintableArea{Left: 100, Right: 100}) != 0 {
+		t.Error("nudged a part wider than the printable area")
+	}
+}
+
+func TestParsePrintableArea(t *testing.T) {
+	for s, want := range map[string]PrintableArea{
+		"Laser":   PrinterPresets["laser"],
+		"5":       {5, 5, 5, 5},
+		"3, 4":    {3, 4, 3, 4},
+		"1,2,3,4": {1, 2, 3, 4},
+	} {
+		if got, err := ParsePrintableArea(s); err != nil || got != want {
+			t.Errorf("ParsePrintableArea(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "1,2,3", "-1", "deskjet"} {
+		if _, err := ParsePrintableArea(bad); err == nil {
+			t.Errorf("ParsePrintableArea(%q) succeeded", bad)
+		}
+	}
+}
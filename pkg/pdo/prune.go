@@ -0,0 +1,244 @@
+package pdo
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RemoveObject deletes object i and its parts. Later objects and parts move
+// up, references to them follow. Materials and the layout bounding box stay
+// as they are until Prune.
+func (p *PDO) RemoveObject(i int) error {
+	if i < 0 || i >= len(p.Objects) {
+		return fmt.Errorf("no object %d", i)
+	}
+	p.Objects = slices.Delete(p.Objects, i, i+1)
+	parts := make([]int32, len(p.Parts))
+	kept := p.Parts[:0]
+	for pi, part := range p.Parts {
+		switch {
+		case int(part.ObjectIndex) == i:
+			parts[pi] = -1
+			continue
+		case int(part.ObjectIndex) > i:
+			part.ObjectIndex--
+		}
+		parts[pi] = int32(len(kept))
+		kept = append(kept, part)
+	}
+	clear(p.Parts[len(kept):])
+	p.Parts = kept
+	p.renumberParts(parts)
+	return nil
+}
+
+// RemovePart deletes part i and the faces unfolded in it from its object.
+// Edges the part shared with other parts become borders of those, edges of
+// the part alone go. Materials, unused 3D vertices and the layout bounding
+// box stay as they are until Prune.
+func (p *PDO) RemovePart(i int) error {
+	if i < 0 || i >= len(p.Parts) {
+		return fmt.Errorf("no part %d", i)
+	}
+	if oi := int(p.Parts[i].ObjectIndex); oi >= 0 && oi < len(p.Objects) {
+		obj := &p.Objects[oi]
+		faces := make([]int32, len(obj.Faces))
+		kept := obj.Faces[:0]
+		for fi, f := range obj.Faces {
+			if int(f.PartIndex) == i {
+				faces[fi] = -1
+				continue
+			}
+			faces[fi] = int32(len(kept))
+			kept = append(kept, f)
+		}
+		clear(obj.Faces[len(kept):])
+		obj.Faces = kept
+		p.renumberFaces(oi, faces)
+	}
+	p.Parts = slices.Delete(p.Parts, i, i+1)
+	parts := make([]int32, len(p.Parts)+1)
+	for pi := range parts {
+		parts[pi] = int32(pi)
+		if pi > i {
+			parts[pi]--
+		}
+	}
+	parts[i] = -1
+	p.renumberParts(parts)
+	return nil
+}
+
+// renumberParts points faces at the new indices of parts, -1 for removed
+// ones.
+func (p *PDO) renumberParts(parts []int32) {
+	for _, f := range p.AllFaces() {
+		if f.PartIndex >= 0 && int(f.PartIndex) < len(parts) {
+			f.PartIndex = parts[f.PartIndex]
+		}
+	}
+}
+
+// renumberFaces points the edges of object oi and the lines of its parts at
+// the new indices of its faces, -1 for removed ones. Edges keep the face
+// that is left, lines on removed faces go.
+func (p *PDO) renumberFaces(oi int, faces []int32) {
+	face := func(f int32) int32 {
+		if f < 0 || int(f) >= len(faces) {
+			return -1
+		}
+		return faces[f]
+	}
+	obj := &p.Objects[oi]
+	edges := obj.Edges[:0]
+	for _, e := range obj.Edges {
+		e.Face1Index, e.Face2Index = face(e.Face1Index), face(e.Face2Index)
+		switch {
+		case e.Face1Index < 0 && e.Face2Index < 0:
+			continue
+		case e.Face1Index < 0:
+			// The edge runs the other way around the face left.
+			e.Face1Index, e.Face2Index = e.Face2Index, -1
+			e.Vertex1Index, e.Vertex2Index = e.Vertex2Index, e.Vertex1Index
+		}
+		if e.Face2Index < 0 {
+			e.ConnectsFaces = 0
+		}
+		edges = append(edges, e)
+	}
+	clear(obj.Edges[len(edges):])
+	obj.Edges = edges
+
+	for pi := range p.Parts {
+		part := &p.Parts[pi]
+		if int(part.ObjectIndex) != oi {
+			continue
+		}
+		lines := part.Lines[:0]
+		for _, l := range part.Lines {
+			if l.FaceIndex = face(l.FaceIndex); l.FaceIndex < 0 {
+				continue
+			}
+			if l.IsConnectingFaces {
+				if l.Face2Index = face(l.Face2Index); l.Face2Index < 0 {
+					// The neighbor is gone, the fold is now a cut.
+					l.IsConnectingFaces, l.Type = false, 0
+				}
+			}
+			lines = append(lines, l)
+		}
+		part.Lines = lines
+	}
+}
+
+// Pruned counts what Prune removed.
+type Pruned struct {
+	Materials int
+	Vertices  int
+	Edges     int
+}
+
+// Prune removes the materials, with their textures, that no face uses, the
+// 3D vertices of every object that none of its faces use and edges without
+// faces, then fits the layout bounding box to the parts.
+func (p *PDO) Prune() Pruned {
+	var n Pruned
+
+	usage := p.MaterialUsage()
+	materials := make([]int32, len(p.Materials))
+	kept := p.Materials[:0]
+	for mi, m := range p.Materials {
+		if !usage[mi].Used() {
+			materials[mi] = -1
+			n.Materials++
+			continue
+		}
+		materials[mi] = int32(len(kept))
+		kept = append(kept, m)
+	}
+	clear(p.Materials[len(kept):])
+	p.Materials = kept
+	for _, f := range p.AllFaces() {
+		if f.MaterialIndex >= 0 && int(f.MaterialIndex) < len(materials) {
+			f.MaterialIndex = materials[f.MaterialIndex]
+		}
+	}
+
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		edges := obj.Edges[:0]
+		for _, e := range obj.Edges {
+			if (e.Face1Index < 0 || int(e.Face1Index) >= len(obj.Faces)) && (e.Face2Index < 0 || int(e.Face2Index) >= len(obj.Faces)) {
+				n.Edges++
+				continue
+			}
+			edges = append(edges, e)
+		}
+		clear(obj.Edges[len(edges):])
+		obj.Edges = edges
+		n.Vertices += p.pruneVertices(oi)
+	}
+
+	if len(p.Parts) == 0 {
+		p.Unfold.BoundingBox = Rect{}
+	}
+	p.updateLayoutBounds()
+	return n
+}
+
+// pruneVertices removes the 3D vertices of object oi no face uses and
+// returns how many.
+func (p *PDO) pruneVertices(oi int) int {
+	obj := &p.Objects[oi]
+	used := make([]bool, len(obj.Vertices))
+	for _, f := range obj.Faces {
+		for _, v := range f.Vertices {
+			if v.IDVertex >= 0 && int(v.IDVertex) < len(used) {
+				used[v.IDVertex] = true
+			}
+		}
+	}
+	ids := make([]int32, len(obj.Vertices))
+	kept := obj.Vertices[:0]
+	for vi, v := range obj.Vertices {
+		if !used[vi] {
+			ids[vi] = -1
+			continue
+		}
+		ids[vi] = int32(len(kept))
+		kept = append(kept, v)
+	}
+	removed := len(obj.Vertices) - len(kept)
+	obj.Vertices = kept
+	if removed == 0 {
+		return 0
+	}
+
+	id := func(v *int32) {
+		if *v >= 0 && int(*v) < len(ids) {
+			*v = ids[*v]
+		}
+	}
+	for fi := range obj.Faces {
+		for vi := range obj.Faces[fi].Vertices {
+			id(&obj.Faces[fi].Vertices[vi].IDVertex)
+		}
+	}
+	for ei := range obj.Edges {
+		id(&obj.Edges[ei].Vertex1Index)
+		id(&obj.Edges[ei].Vertex2Index)
+	}
+	for pi := range p.Parts {
+		if int(p.Parts[pi].ObjectIndex) != oi {
+			continue
+		}
+		for li := range p.Parts[pi].Lines {
+			l := &p.Parts[pi].Lines[li]
+			id(&l.VertexIndex)
+			if l.IsConnectingFaces {
+				id(&l.Vertex2Index)
+			}
+		}
+	}
+	return removed
+}
@@ -0,0 +1,109 @@
+package pdo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRemovePart(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Materials = append(p.Materials, Material{Name: "unused"})
+	faces := len(p.Objects[0].Faces)
+	keep := 0
+	for _, f := range p.Objects[0].Faces {
+		if f.PartIndex == 1 {
+			keep++
+		}
+	}
+	want := p.Parts[1].Lines
+
+	if err := p.RemovePart(0); err != nil {
+		t.Fatal(err)
+	}
+	obj := &p.Objects[0]
+	if len(p.Parts) != 1 || len(obj.Faces) != keep || keep == faces {
+		t.Fatalf("%d parts and %d faces left, want 1 and %d", len(p.Parts), len(obj.Faces), keep)
+	}
+	for _, f := range obj.Faces {
+		if f.PartIndex != 0 {
+			t.Fatalf("face on part %d", f.PartIndex)
+		}
+	}
+	if len(p.Parts[0].Lines) != len(want) {
+		t.Errorf("%d lines left, want %d", len(p.Parts[0].Lines), len(want))
+	}
+
+	pruned := p.Prune()
+	if pruned.Materials != 1 || pruned.Vertices == 0 {
+		t.Errorf("pruned %+v, want the unused material and the vertices of the removed part", pruned)
+	}
+	for ei, e := range obj.Edges {
+		if e.Face1Index < 0 || int(e.Face1Index) >= len(obj.Faces) || obj.edgeStart(e.Face1Index, e) == nil {
+			t.Fatalf("edge %d doesn't run along its face: %+v", ei, e)
+		}
+		if e.Face2Index < 0 && e.ConnectsFaces != 0 {
+			t.Fatalf("border edge %d connects faces", ei)
+		}
+	}
+	for li := range p.Parts[0].Lines {
+		if v1, _ := obj.LineEnds(&p.Parts[0].Lines[li]); v1 == nil {
+			t.Fatalf("line %d doesn't resolve", li)
+		}
+	}
+	if p.Unfold.BoundingBox != p.Parts[0].BoundingBox {
+		t.Errorf("layout box %+v, want the part's %+v", p.Unfold.BoundingBox, p.Parts[0].BoundingBox)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewParser(bytes.NewReader(buf.Bytes()))
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(parser.PDO.Objects[0].Faces) != keep {
+		t.Errorf("written file has %d faces, want %d", len(parser.PDO.Objects[0].Faces), keep)
+	}
+}
+
+func TestRemoveObject(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := p.Objects[0].Clone()
+	second.Name = "copy"
+	for i := range second.Faces {
+		second.Faces[i].PartIndex += 2
+	}
+	p.Objects = append(p.Objects, second)
+	for _, part := range p.Parts[:2] {
+		part := part.Clone()
+		part.ObjectIndex = 1
+		p.Parts = append(p.Parts, part)
+	}
+
+	if err := p.RemoveObject(0); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Objects) != 1 || p.Objects[0].Name != "copy" || len(p.Parts) != 2 {
+		t.Fatalf("%d objects and %d parts left", len(p.Objects), len(p.Parts))
+	}
+	for _, part := range p.Parts {
+		if part.ObjectIndex != 0 {
+			t.Errorf("part of object %d", part.ObjectIndex)
+		}
+	}
+	for _, f := range p.Objects[0].Faces {
+		if f.PartIndex != 0 && f.PartIndex != 1 {
+			t.Fatalf("face on part %d", f.PartIndex)
+		}
+	}
+	if err := p.RemoveObject(1); err == nil {
+		t.Error("removed a missing object")
+	}
+}
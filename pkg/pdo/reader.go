@@ -14,6 +14,21 @@ type Reader struct {
 	r           io.Reader
 	StringShift byte
 	MultiByteC  bool
+	// ByteWiseShift applies the shift to each byte of a multi-byte character
+	// instead of to the whole 16-bit value.
+	ByteWiseShift bool
+
+	// raw records the stored bytes of each decoded string when set, so a
+	// writer can store unchanged strings exactly as they were.
+	raw *[]rawString
+	// budget limits the string data read, see Options.Limits.
+	budget *budget
+}
+
+// rawString is a decoded string with the bytes it was stored as.
+type rawString struct {
+	s   string
+	raw []byte
 }
 
 func NewReader(r io.Reader) *Reader {
@@ -21,30 +36,30 @@ func NewReader(r io.Reader) *Reader {
 }
 
 func (r *Reader) ReadBytes(data interface{}) error {
-	return binary.Read(r.r, binary.LittleEndian, data)
+	return wrapReadError(binary.Read(r.r, binary.LittleEndian, data))
 }
 
 func (r *Reader) ReadInt32() (int32, error) {
 	var v int32
-	err := binary.Read(r.r, binary.LittleEndian, &v)
+	err := r.ReadBytes(&v)
 	return v, err
 }
 
 func (r *Reader) ReadUInt32() (uint32, error) {
 	var v uint32
-	err := binary.Read(r.r, binary.LittleEndian, &v)
+	err := r.ReadBytes(&v)
 	return v, err
 }
 
 func (r *Reader) ReadUInt8() (uint8, error) {
 	var v uint8
-	err := binary.Read(r.r, binary.LittleEndian, &v)
+	err := r.ReadBytes(&v)
 	return v, err
 }
 
 func (r *Reader) ReadFloat64() (float64, error) {
 	var v float64
-	err := binary.Read(r.r, binary.LittleEndian, &v)
+	err := r.ReadBytes(&v)
 	return v, err
 }
 
@@ -54,33 +69,80 @@ func (r *Reader) ReadFloat64() (float64, error) {
 // The string is expected to be null-terminated, so the last character is read but discarded.
 // The 'shift' is applied to each character.
 func (r *Reader) ReadString(shift byte) (string, error) {
-	var wrappedLen int32
-	if err := binary.Read(r.r, binary.LittleEndian, &wrappedLen); err != nil {
+	raw, err := r.ReadRawString()
+	if err != nil {
 		return "", err
 	}
+	s := DecodeString(raw, shift, r.MultiByteC, r.ByteWiseShift)
+	if r.raw != nil && shift == r.StringShift {
+		*r.raw = append(*r.raw, rawString{s, raw})
+	}
+	return s, nil
+}
 
-	if wrappedLen == 0 {
-		return "", nil
+// ReadRawString reads a length-prefixed string and returns its stored bytes
+// without applying any shift or character decoding.
+func (r *Reader) ReadRawString() ([]byte, error) {
+	var wrappedLen int32
+	if err := r.ReadBytes(&wrappedLen); err != nil {
+		return nil, err
 	}
 
-	if r.MultiByteC {
+	if wrappedLen <= 0 {
+		return nil, nil
+	}
+	if err := r.budget.alloc("bytes of string data", wrappedLen, 1); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, wrappedLen)
+	if err := r.ReadBytes(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Skip moves n bytes ahead, seeking when the input can.
+func (r *Reader) Skip(n int64) error {
+	if s, ok := r.r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekCurrent)
+		return err
+	}
+	_, err := io.CopyN(io.Discard, r.r, n)
+	return wrapReadError(err)
+}
+
+// SkipString moves past a length-prefixed string without reading it.
+func (r *Reader) SkipString() error {
+	var wrappedLen int32
+	if err := r.ReadBytes(&wrappedLen); err != nil {
+		return err
+	}
+	return r.Skip(int64(max(wrappedLen, 0)))
+}
+
+// DecodeString converts the stored bytes of a string into UTF-8.
+// If multiByte is true, characters are UTF-16LE and the shift is subtracted
+// from each 16-bit character, or from each byte when byteWise is set.
+// Otherwise characters are single bytes decoded as Shift-JIS.
+func DecodeString(raw []byte, shift byte, multiByte, byteWise bool) string {
+	if multiByte {
 		// Length is in bytes, convert to number of wchars
-		count := wrappedLen / 2
+		count := len(raw) / 2
 		if count <= 0 {
-			return "", nil
-		}
-
-		// Read count items. Spec implies null termination.
-		// However, reading exact buffer is safer.
-		buf := make([]uint16, count)
-		if err := binary.Read(r.r, binary.LittleEndian, buf); err != nil {
-			return "", err
+			return ""
 		}
 
 		// Apply shift and collect valid chars
 		runes := make([]uint16, 0, count)
-		for _, w := range buf {
-			val := w - uint16(shift)
+		for i := 0; i < count; i++ {
+			lo, hi := raw[2*i], raw[2*i+1]
+			var val uint16
+			if byteWise {
+				val = uint16(lo-shift) | uint16(hi-shift)<<8
+			} else {
+				val = (uint16(lo) | uint16(hi)<<8) - uint16(shift)
+			}
 			if val == 0 {
 				break // Null terminator
 			}
@@ -88,39 +150,27 @@ func (r *Reader) ReadString(shift byte) (string, error) {
 		}
 
 		// Decode UTF-16 (Little Endian)
-		return string(utf16.Decode(runes)), nil
-
-	} else {
-		// Single byte
-		count := wrappedLen
-		if count <= 0 {
-			return "", nil
-		}
-
-		buf := make([]byte, count)
-		if err := binary.Read(r.r, binary.LittleEndian, buf); err != nil {
-			return "", err
-		}
-
-		// Apply shift
-		validBytes := make([]byte, 0, count)
-		for _, b := range buf {
-			val := b - shift
-			if val == 0 {
-				break
-			}
-			validBytes = append(validBytes, val)
-		}
+		return string(utf16.Decode(runes))
+	}
 
-		// Decode Shift-JIS
-		decoder := japanese.ShiftJIS.NewDecoder()
-		utf8Bytes, _, err := transform.Bytes(decoder, validBytes)
-		if err != nil {
-			return string(validBytes), nil
+	// Single byte, apply shift
+	validBytes := make([]byte, 0, len(raw))
+	for _, b := range raw {
+		val := b - shift
+		if val == 0 {
+			break
 		}
+		validBytes = append(validBytes, val)
+	}
 
-		return string(utf8Bytes), nil
+	// Decode Shift-JIS
+	decoder := japanese.ShiftJIS.NewDecoder()
+	utf8Bytes, _, err := transform.Bytes(decoder, validBytes)
+	if err != nil {
+		return string(validBytes)
 	}
+
+	return string(utf8Bytes)
 }
 
 func (r *Reader) ReadShiftedString() (string, error) {
@@ -129,8 +179,34 @@ func (r *Reader) ReadShiftedString() (string, error) {
 
 func (r *Reader) ReadRect() (Rect, error) {
 	var rect Rect
-	if err := binary.Read(r.r, binary.LittleEndian, &rect); err != nil {
+	if err := r.ReadBytes(&rect); err != nil {
 		return rect, err
 	}
 	return rect, nil
 }
+
+func (r *Reader) decode(raw []byte) string {
+	s := DecodeString(raw, r.StringShift, r.MultiByteC, r.ByteWiseShift)
+	if r.raw != nil {
+		*r.raw = append(*r.raw, rawString{s, raw})
+	}
+	return s
+}
+
+// ReadRest reads everything up to the end of the input. It reads at most
+// one byte more than the budget has left, so a long tail fails with
+// ErrLimitExceeded without being read into memory first.
+func (r *Reader) ReadRest() ([]byte, error) {
+	src := r.r
+	if n := r.budget.remaining(); n >= 0 {
+		src = io.LimitReader(r.r, n+1)
+	}
+	rest, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.budget.grow("bytes of trailing data", int64(len(rest)), 1); err != nil {
+		return nil, err
+	}
+	return rest, nil
+}
@@ -87,3 +87,14 @@ func TestReadString_MultiByte_UTF16(t *testing.T) {
 		t.Errorf("ReadShiftedString got %q, want %q", got, want)
 	}
 }
+
+func TestDecodeString_ByteWiseShift(t *testing.T) {
+	// "Hi" in UTF-16LE: 48 00 69 00 00 00, every byte shifted by 7
+	raw := []byte{0x4F, 0x07, 0x70, 0x07, 0x07, 0x07}
+
+	got := DecodeString(raw, 7, true, true)
+	want := "Hi"
+	if got != want {
+		t.Errorf("DecodeString got %q, want %q", got, want)
+	}
+}
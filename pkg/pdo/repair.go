@@ -0,0 +1,146 @@
+package pdo
+
+// LineRepair tells how ResolveLine found the ends of a line.
+type LineRepair int
+
+const (
+	// LineResolved lines have vertex indices that match their faces.
+	LineResolved LineRepair = iota
+	// LineFromEdge is a connecting line matched to the edge between its
+	// two faces.
+	LineFromEdge
+	// LineFromNearest is a line matched to the face vertices nearest to the
+	// 3D vertices it names.
+	LineFromNearest
+	// LineUnresolved lines match nothing, their ends are nil.
+	LineUnresolved
+)
+
+// nearestTolerance is how far a face vertex may lie from the 3D vertex a
+// line names and still be taken for it, relative to the longest side of the
+// face.
+const nearestTolerance = 0.01
+
+// ResolveLine returns the ends of a line like LineEnds. When the vertex
+// indices don't match the line's faces, as in hand-edited files, it falls
+// back to the edge between the two faces of a connecting line, then to the
+// face vertices nearest to the 3D vertices the line names, and reports
+// which it used.
+func (l *Lookup) ResolveLine(line *Line) (*Face2DVertex, *Face2DVertex, LineRepair) {
+	if v1, v2 := l.LineEnds(line); v1 != nil {
+		return v1, v2, LineResolved
+	}
+	if line.IsConnectingFaces {
+		if v1, v2 := l.edgeEnds(line); v1 != nil {
+			return v1, v2, LineFromEdge
+		}
+	}
+	v1 := l.nearestVertex(line.FaceIndex, line.VertexIndex)
+	if v1 == nil {
+		return nil, nil, LineUnresolved
+	}
+	var v2 *Face2DVertex
+	if line.IsConnectingFaces {
+		v2 = l.nearestVertex(line.Face2Index, line.Vertex2Index)
+	} else {
+		v2 = l.faceVertex(line.FaceIndex, v1.IDVertex, true)
+	}
+	if v2 == nil {
+		return nil, nil, LineUnresolved
+	}
+	return v1, v2, LineFromNearest
+}
+
+// edgeEnds returns the ends of a connecting line on the edge between its
+// two faces. Like the lines Pepakura writes, the line runs from the first
+// vertex of the edge as its first face goes around it, unless one of the
+// line's indices says otherwise.
+func (l *Lookup) edgeEnds(line *Line) (*Face2DVertex, *Face2DVertex) {
+	for _, e := range l.obj.Edges {
+		a, b := e.Vertex1Index, e.Vertex2Index
+		switch {
+		case e.Face1Index == line.FaceIndex && e.Face2Index == line.Face2Index:
+		case e.Face2Index == line.FaceIndex && e.Face1Index == line.Face2Index:
+			a, b = b, a
+		default:
+			continue
+		}
+		if line.VertexIndex == b || line.Vertex2Index == a {
+			a, b = b, a
+		}
+		v1, v2 := l.faceVertex(line.FaceIndex, a, false), l.faceVertex(line.Face2Index, b, false)
+		if v1 != nil && v2 != nil {
+			return v1, v2
+		}
+	}
+	return nil, nil
+}
+
+// nearestVertex returns the vertex of a face for a 3D vertex index, or the
+// one nearest to that vertex within nearestTolerance.
+func (l *Lookup) nearestVertex(faceIdx, vertIdx int32) *Face2DVertex {
+	if v := l.faceVertex(faceIdx, vertIdx, false); v != nil {
+		return v
+	}
+	obj := l.obj
+	if faceIdx < 0 || int(faceIdx) >= len(obj.Faces) || vertIdx < 0 || int(vertIdx) >= len(obj.Vertices) {
+		return nil
+	}
+	at := func(id int32) (Vertex3D, bool) {
+		if id < 0 || int(id) >= len(obj.Vertices) {
+			return Vertex3D{}, false
+		}
+		return obj.Vertices[id], true
+	}
+	face := &obj.Faces[faceIdx]
+	target := obj.Vertices[vertIdx]
+	var nearest *Face2DVertex
+	best, longest := 0.0, 0.0
+	for i := range face.Vertices {
+		v, ok := at(face.Vertices[i].IDVertex)
+		if !ok {
+			continue
+		}
+		if next, ok := at(face.Vertices[(i+1)%len(face.Vertices)].IDVertex); ok {
+			longest = max(longest, v.Dist(next))
+		}
+		if d := v.Dist(target); nearest == nil || d < best {
+			nearest, best = &face.Vertices[i], d
+		}
+	}
+	if nearest == nil || best > nearestTolerance*longest {
+		return nil
+	}
+	return nearest
+}
+
+// RepairLines points the lines that only ResolveLine resolves at the
+// vertices it found, so other programs draw them too. It returns how many
+// lines it repaired and how many still match nothing.
+func (p *PDO) RepairLines() (repaired, unresolved int) {
+	lookups := p.Lookups()
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		lk := lookups.Part(part)
+		if lk == nil {
+			continue
+		}
+		for li := range part.Lines {
+			line := &part.Lines[li]
+			v1, v2, how := lk.ResolveLine(line)
+			switch how {
+			case LineResolved:
+				continue
+			case LineUnresolved:
+				unresolved++
+				continue
+			}
+			line.VertexIndex = v1.IDVertex
+			if line.IsConnectingFaces {
+				line.Vertex2Index = v2.IDVertex
+			}
+			repaired++
+		}
+	}
+	return repaired, unresolved
+}
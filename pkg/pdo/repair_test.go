@@ -0,0 +1,60 @@
+package pdo
+
+import "testing"
+
+func TestResolveLine(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := &p.Parts[0]
+	obj := &p.Objects[part.ObjectIndex]
+	fold, cut, broken := -1, -1, -1
+	for i, line := range part.Lines {
+		switch {
+		case line.IsConnectingFaces && fold < 0:
+			fold = i
+		case !line.IsConnectingFaces && cut < 0:
+			cut = i
+		case !line.IsConnectingFaces && broken < 0:
+			broken = i
+		}
+	}
+	if fold < 0 || broken < 0 {
+		t.Fatal("part has too few lines")
+	}
+	want := map[int][2]*Face2DVertex{}
+	for _, i := range []int{fold, cut} {
+		v1, v2 := obj.LineEnds(&part.Lines[i])
+		want[i] = [2]*Face2DVertex{v1, v2}
+	}
+	orig := part.Lines[cut].VertexIndex
+
+	// A fold naming no vertex of its faces lies on the edge between them.
+	part.Lines[fold].VertexIndex, part.Lines[fold].Vertex2Index = 9999, 9999
+	// A cut naming a copy of its vertex, as after splitting vertices.
+	obj.Vertices = append(obj.Vertices, obj.Vertices[orig])
+	part.Lines[cut].VertexIndex = int32(len(obj.Vertices) - 1)
+	part.Lines[broken].VertexIndex = 9999
+
+	lk := NewLookup(obj)
+	for i, how := range map[int]LineRepair{fold: LineFromEdge, cut: LineFromNearest, broken: LineUnresolved} {
+		v1, v2, got := lk.ResolveLine(&part.Lines[i])
+		if got != how {
+			t.Errorf("line %d resolved with %d, want %d", i, got, how)
+		}
+		if w, ok := want[i]; ok && (v1 != w[0] || v2 != w[1]) {
+			t.Errorf("line %d ends %v %v, want %v %v", i, v1, v2, w[0], w[1])
+		}
+	}
+
+	if repaired, unresolved := p.RepairLines(); repaired != 2 || unresolved != 1 {
+		t.Errorf("RepairLines() = %d, %d, want 2, 1", repaired, unresolved)
+	}
+	if got := part.Lines[cut].VertexIndex; got != orig {
+		t.Errorf("repaired cut names vertex %d, want %d", got, orig)
+	}
+	if v1, _ := obj.LineEnds(&part.Lines[fold]); v1 != want[fold][0] {
+		t.Error("repaired fold doesn't resolve")
+	}
+}
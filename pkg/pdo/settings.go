@@ -0,0 +1,231 @@
+package pdo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Page types of Settings.PageType.
+const (
+	PageA4     = 0
+	PageLetter = 9
+	PageOther  = 11
+)
+
+// pageSizes lists the paper of each page type in portrait, in mm. B sizes
+// are the Japanese (JIS) ones used by Pepakura Designer.
+var pageSizes = []struct {
+	name          string
+	width, height float64
+}{
+	{"A4", 210, 297},
+	{"A3", 297, 420},
+	{"A2", 420, 594},
+	{"A1", 594, 841},
+	{"B5", 182, 257},
+	{"B4", 257, 364},
+	{"B3", 364, 515},
+	{"B2", 515, 728},
+	{"B1", 728, 1030},
+	{"Letter", 215.9, 279.4},
+	{"Legal", 215.9, 355.6},
+	{"Other", 0, 0},
+}
+
+// PageTypeName returns the paper name of a page type, e.g. "A3".
+func PageTypeName(t int32) string {
+	if t < 0 || int(t) >= len(pageSizes) {
+		return fmt.Sprintf("unknown (%d)", t)
+	}
+	return pageSizes[t].name
+}
+
+// ParsePageType converts a paper name ("A4", "letter", "other") or page
+// type number into a page type.
+func ParsePageType(s string) (int32, error) {
+	for i, size := range pageSizes {
+		if strings.EqualFold(s, size.name) {
+			return int32(i), nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(pageSizes) {
+		return int32(n), nil
+	}
+	return 0, fmt.Errorf("unknown page type %q", s)
+}
+
+// setting describes a print setting that can be read and changed by name.
+type setting struct {
+	name string
+	get  func(s *Settings) string
+	set  func(s *Settings, v string) error
+}
+
+func flagSetting(name string, field func(s *Settings) *uint8) setting {
+	return setting{
+		name: name,
+		get:  func(s *Settings) string { return strconv.Itoa(int(*field(s))) },
+		set: func(s *Settings, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("want 0 or 1, got %q", v)
+			}
+			*field(s) = 0
+			if b {
+				*field(s) = 1
+			}
+			return nil
+		},
+	}
+}
+
+func intSetting(name string, lo, hi int32, field func(s *Settings) *int32) setting {
+	return setting{
+		name: name,
+		get:  func(s *Settings) string { return strconv.Itoa(int(*field(s))) },
+		set: func(s *Settings, v string) error {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil || int32(n) < lo || int32(n) > hi {
+				return fmt.Errorf("want a number from %d to %d, got %q", lo, hi, v)
+			}
+			*field(s) = int32(n)
+			return nil
+		},
+	}
+}
+
+func floatSetting(name string, field func(s *Settings) *float64) setting {
+	return setting{
+		name: name,
+		get:  func(s *Settings) string { return strconv.FormatFloat(*field(s), 'g', -1, 64) },
+		set: func(s *Settings, v string) error {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 {
+				return fmt.Errorf("want a positive number, got %q", v)
+			}
+			*field(s) = f
+			return nil
+		},
+	}
+}
+
+func stringSetting(name string, field func(s *Settings) *string) setting {
+	return setting{
+		name: name,
+		get:  func(s *Settings) string { return *field(s) },
+		set: func(s *Settings, v string) error {
+			*field(s) = v
+			return nil
+		},
+	}
+}
+
+// maxLineStyle is the highest fold line style: 0=solid, 1=none, 2=dot,
+// and 3 written by newer versions.
+const maxLineStyle = 3
+
+var settings = []setting{
+	flagSetting("showFlaps", func(s *Settings) *uint8 { return &s.ShowFlaps }),
+	flagSetting("showEdgeID", func(s *Settings) *uint8 { return &s.ShowEdgeID }),
+	flagSetting("edgeIDPlacement", func(s *Settings) *uint8 { return &s.EdgeIDPlacement }),
+	flagSetting("faceMaterial", func(s *Settings) *uint8 { return &s.FaceMaterial }),
+	flagSetting("hideAlmostFlatFoldLines", func(s *Settings) *uint8 { return &s.HideAlmostFlatFoldLines }),
+	intSetting("foldLinesHidingAngle", 0, 180, func(s *Settings) *int32 { return &s.FoldLinesHidingAngle }),
+	flagSetting("drawWhiteLineUnderDotLine", func(s *Settings) *uint8 { return &s.DrawWhiteLineUnderDotLine }),
+	intSetting("mountainFoldLineStyle", 0, maxLineStyle, func(s *Settings) *int32 { return &s.MountainFoldLineStyle }),
+	intSetting("valleyFoldLineStyle", 0, maxLineStyle, func(s *Settings) *int32 { return &s.ValleyFoldLineStyle }),
+	intSetting("cutLineStyle", 0, 1, func(s *Settings) *int32 { return &s.CutLineStyle }),
+	intSetting("edgeIDFontSize", 0, 72, func(s *Settings) *int32 { return &s.EdgeIDFontSize }),
+	{
+		name: "pageType",
+		get:  func(s *Settings) string { return PageTypeName(s.PageType) },
+		set: func(s *Settings, v string) error {
+			t, err := ParsePageType(v)
+			if err != nil {
+				return err
+			}
+			if t == PageOther && (s.CustomWidth <= 0 || s.CustomHeight <= 0) {
+				// Start from the current paper, customWidth and customHeight change it.
+				dims := (&PDO{Settings: *s}).PageDims()
+				s.CustomWidth, s.CustomHeight = dims.Width, dims.Height
+				if s.Orientation == 1 {
+					s.CustomWidth, s.CustomHeight = dims.Height, dims.Width
+				}
+			}
+			s.PageType = t
+			return nil
+		},
+	},
+	floatSetting("customWidth", func(s *Settings) *float64 { return &s.CustomWidth }),
+	floatSetting("customHeight", func(s *Settings) *float64 { return &s.CustomHeight }),
+	{
+		name: "orientation",
+		get: func(s *Settings) string {
+			if s.Orientation == 1 {
+				return "landscape"
+			}
+			return "portrait"
+		},
+		set: func(s *Settings, v string) error {
+			switch strings.ToLower(v) {
+			case "portrait", "0":
+				s.Orientation = 0
+			case "landscape", "1":
+				s.Orientation = 1
+			default:
+				return fmt.Errorf("want portrait or landscape, got %q", v)
+			}
+			return nil
+		},
+	},
+	intSetting("marginSide", 0, 100, func(s *Settings) *int32 { return &s.MarginSide }),
+	intSetting("marginTop", 0, 100, func(s *Settings) *int32 { return &s.MarginTop }),
+	flagSetting("addOutlinePadding", func(s *Settings) *uint8 { return &s.AddOutlinePadding }),
+	floatSetting("scaleFactor", func(s *Settings) *float64 { return &s.ScaleFactor }),
+	stringSetting("authorName", func(s *Settings) *string { return &s.AuthorName }),
+	stringSetting("comment", func(s *Settings) *string { return &s.Comment }),
+}
+
+func findSetting(name string) (setting, error) {
+	for _, st := range settings {
+		if strings.EqualFold(st.name, name) {
+			return st, nil
+		}
+	}
+	return setting{}, fmt.Errorf("unknown setting %q", name)
+}
+
+// SettingNames returns the names accepted by Settings.Get and Settings.Set,
+// in file order.
+func SettingNames() []string {
+	names := make([]string, len(settings))
+	for i, st := range settings {
+		names[i] = st.name
+	}
+	return names
+}
+
+// Get returns a print setting by its name, see SettingNames. Names are
+// case-insensitive.
+func (s *Settings) Get(name string) (string, error) {
+	st, err := findSetting(name)
+	if err != nil {
+		return "", err
+	}
+	return st.get(s), nil
+}
+
+// Set changes a print setting by its name, see SettingNames. Flags take 0
+// or 1, the page type a paper name and the orientation portrait or
+// landscape.
+func (s *Settings) Set(name, value string) error {
+	st, err := findSetting(name)
+	if err != nil {
+		return err
+	}
+	if err := st.set(s, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("setting %s: %w", st.name, err)
+	}
+	return nil
+}
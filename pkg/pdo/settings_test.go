@@ -0,0 +1,63 @@
+package pdo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSettings_Set(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range [][2]string{
+		{"showFlaps", "0"},
+		{"PAGETYPE", "a3"},
+		{"orientation", "landscape"},
+		{"marginSide", "10"},
+		{"comment", "Print on 160gsm"},
+	} {
+		if err := p.Settings.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%s, %s): %v", kv[0], kv[1], err)
+		}
+	}
+	if dims := p.PageDims(); dims.Width != 420 || dims.Height != 297 || dims.MarginLeft != 15 || dims.MarginTop != 10 {
+		t.Errorf("landscape A3 page dims %+v", dims)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewParser(&buf)
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	s := parser.PDO.Settings
+	if s.ShowFlaps != 0 || s.PageType != 1 || s.Orientation != 1 || s.MarginSide != 10 || s.Comment != "Print on 160gsm" {
+		t.Errorf("settings after writing: %+v", s)
+	}
+	if v, _ := s.Get("pageType"); v != "A3" {
+		t.Errorf("pageType %q", v)
+	}
+
+	// Switching to custom paper keeps the current size until changed.
+	if err := p.Settings.Set("pageType", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if dims := p.PageDims(); dims.Width != 420 || dims.Height != 297 {
+		t.Errorf("custom page dims %+v", dims)
+	}
+
+	for _, kv := range [][2]string{
+		{"showFlaps", "maybe"},
+		{"pageType", "A9"},
+		{"marginTop", "-1"},
+		{"scaleFactor", "0"},
+		{"nonsense", "1"},
+	} {
+		if err := p.Settings.Set(kv[0], kv[1]); err == nil {
+			t.Errorf("Set(%s, %s) accepted", kv[0], kv[1])
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package pdo
+
+import (
+	"math"
+	"slices"
+)
+
+// sizeTolerance is the relative difference at which sizes disagree.
+const sizeTolerance = 0.005
+
+// SizeCheck compares the stored size of a model with its geometry.
+type SizeCheck struct {
+	// HeaderHeight is Header.AssembledHeight, the largest extent of the
+	// model in model units as Pepakura Designer last stored it.
+	HeaderHeight float64
+	// ModelHeight is the largest extent of the vertices.
+	ModelHeight float64
+	// OriginOffset is the stored model center, Center the actual one.
+	OriginOffset, Center Vertex3D
+	// Scale is Unfold.Scale, mm per model unit.
+	Scale float64
+	// LayoutScale is the scale the parts are actually unfolded at: the
+	// median ratio of template to model edge lengths. It is 0 without parts.
+	LayoutScale float64
+}
+
+// RealHeight returns the largest extent of the assembled model in mm.
+func (c SizeCheck) RealHeight() float64 {
+	scale := c.LayoutScale
+	if scale == 0 {
+		scale = c.Scale
+	}
+	return c.ModelHeight * scale
+}
+
+// HeaderMismatch reports whether the header height or origin disagree
+// with the vertices.
+func (c SizeCheck) HeaderMismatch() bool {
+	tol := sizeTolerance * c.ModelHeight
+	d := Vertex3D{X: c.OriginOffset.X - c.Center.X, Y: c.OriginOffset.Y - c.Center.Y, Z: c.OriginOffset.Z - c.Center.Z}
+	return math.Abs(c.HeaderHeight-c.ModelHeight) > tol || math.Sqrt(d.X*d.X+d.Y*d.Y+d.Z*d.Z) > tol
+}
+
+// ScaleMismatch reports whether the parts are unfolded at a different
+// scale than Unfold.Scale says.
+func (c SizeCheck) ScaleMismatch() bool {
+	return c.LayoutScale > 0 && math.Abs(c.LayoutScale-c.Scale) > sizeTolerance*c.Scale
+}
+
+// CheckSize measures the model and layout and compares them with the
+// header and unfold scale.
+func (p *PDO) CheckSize() SizeCheck {
+	c := SizeCheck{HeaderHeight: p.Header.AssembledHeight, Scale: p.Unfold.Scale}
+	c.OriginOffset = Vertex3D{X: p.Header.OriginOffset[0], Y: p.Header.OriginOffset[1], Z: p.Header.OriginOffset[2]}
+	b, _ := p.Bounds()
+	size := b.Size()
+	c.ModelHeight = max(size.X, size.Y, size.Z)
+	c.Center = b.Center()
+
+	var ratios []float64
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		for fi := range obj.Faces {
+			f := &obj.Faces[fi]
+			if f.PartIndex < 0 {
+				continue
+			}
+			for j, a := range f.Vertices {
+				b := f.Vertices[(j+1)%len(f.Vertices)]
+				if l3 := obj.vertex(a.IDVertex).Dist(obj.vertex(b.IDVertex)); l3 > 0 {
+					ratios = append(ratios, a.Dist(&b)/l3)
+				}
+			}
+		}
+	}
+	if len(ratios) > 0 {
+		slices.Sort(ratios)
+		c.LayoutScale = ratios[len(ratios)/2]
+	}
+	return c
+}
+
+// FixSize updates the header from the vertices and Unfold.Scale to the
+// scale the parts are unfolded at. It returns the check before the fix.
+func (p *PDO) FixSize() SizeCheck {
+	c := p.CheckSize()
+	p.UpdateBounds()
+	if c.ScaleMismatch() {
+		p.Unfold.Scale = c.LayoutScale
+	}
+	return c
+}
@@ -0,0 +1,43 @@
+package pdo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckSize(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.CheckSize()
+	if c.HeaderMismatch() || c.ScaleMismatch() {
+		t.Fatalf("sample reported as mismatched: %+v", c)
+	}
+	if math.Abs(c.LayoutScale-p.Unfold.Scale) > 1e-6 {
+		t.Errorf("layout scale %g, want %g", c.LayoutScale, p.Unfold.Scale)
+	}
+
+	// Scaled up in another tool without touching the header or layout.
+	for i := range p.Objects[0].Vertices {
+		v := &p.Objects[0].Vertices[i]
+		v.X, v.Y, v.Z = v.X*2, v.Y*2, v.Z*2
+	}
+	c = p.CheckSize()
+	if !c.HeaderMismatch() || !c.ScaleMismatch() {
+		t.Fatalf("scaled model not reported: %+v", c)
+	}
+	if n := len(Validate(p)); n != 2 {
+		t.Errorf("Validate found %d issues, want 2 size warnings", n)
+	}
+
+	p.FixSize()
+	c = p.CheckSize()
+	if c.HeaderMismatch() || c.ScaleMismatch() {
+		t.Fatalf("still mismatched after FixSize: %+v", c)
+	}
+	// The printed size doesn't change, the model is just in other units.
+	if math.Abs(c.RealHeight()-60*1.4641) > 1e-3 {
+		t.Errorf("real height %g mm, want %g", c.RealHeight(), 60*1.4641)
+	}
+}
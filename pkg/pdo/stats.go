@@ -0,0 +1,134 @@
+package pdo
+
+import (
+	"math"
+)
+
+// Box3D is an axis-aligned bounding box in 3D model units.
+type Box3D struct {
+	Min, Max Vertex3D
+}
+
+// Statistics summarizes the size and content of a PDO.
+type Statistics struct {
+	Objects           int
+	Vertices          int
+	Faces             int
+	Edges             int
+	Materials         int
+	TexturedMaterials int
+	Parts             int
+	Lines             int
+	TextBlocks        int
+	Images            int
+
+	// SurfaceArea is the total area of all faces in 3D model units squared.
+	SurfaceArea float64
+	// TemplateArea is the total area of all unfolded faces in mm², flaps excluded.
+	TemplateArea float64
+
+	// ModelBounds is the bounding box of all 3D vertices.
+	ModelBounds Box3D
+	// LayoutBounds is the union of the stored part bounding boxes.
+	LayoutBounds Rect
+
+	// PagesX and PagesY are the number of page columns and rows used by the layout.
+	PagesX, PagesY int
+
+	// TextureBytes is the decoded RGB size of all material and page textures.
+	TextureBytes int64
+	// CompressedTextureBytes is the stored (deflated) size of the same textures.
+	CompressedTextureBytes int64
+}
+
+// Stats computes statistics for the PDO.
+func Stats(p *PDO) Statistics {
+	var s Statistics
+	s.Objects = len(p.Objects)
+	s.Materials = len(p.Materials)
+	s.Parts = len(p.Parts)
+	s.TextBlocks = len(p.TextBlocks)
+	s.Images = len(p.Images)
+
+	s.ModelBounds, _ = p.Bounds()
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		s.Vertices += len(obj.Vertices)
+		s.Faces += len(obj.Faces)
+		s.Edges += len(obj.Edges)
+
+		for fi := range obj.Faces {
+			s.SurfaceArea += obj.FaceArea3D(fi)
+			s.TemplateArea += obj.Faces[fi].Area2D()
+		}
+	}
+
+	for i, part := range p.Parts {
+		s.Lines += len(part.Lines)
+		bb := part.BoundingBox
+		if i == 0 {
+			s.LayoutBounds = bb
+			continue
+		}
+		right := math.Max(s.LayoutBounds.Left+s.LayoutBounds.Width, bb.Left+bb.Width)
+		bottom := math.Max(s.LayoutBounds.Top+s.LayoutBounds.Height, bb.Top+bb.Height)
+		s.LayoutBounds.Left = math.Min(s.LayoutBounds.Left, bb.Left)
+		s.LayoutBounds.Top = math.Min(s.LayoutBounds.Top, bb.Top)
+		s.LayoutBounds.Width = right - s.LayoutBounds.Left
+		s.LayoutBounds.Height = bottom - s.LayoutBounds.Top
+	}
+
+	if len(p.Parts) > 0 {
+		maxX, maxY := p.PageGrid(p.PageDims())
+		s.PagesX, s.PagesY = maxX+1, maxY+1
+	}
+
+	for _, mat := range p.TexturedMaterials() {
+		s.TexturedMaterials++
+		s.TextureBytes += mat.Texture.DecodedSize()
+		s.CompressedTextureBytes += int64(len(mat.Texture.RawData))
+	}
+	for _, img := range p.Images {
+		s.TextureBytes += img.Texture.DecodedSize()
+		s.CompressedTextureBytes += int64(len(img.Texture.RawData))
+	}
+
+	return s
+}
+
+// DecodedSize returns the size in bytes of the decompressed RGB texture.
+func (t *Texture) DecodedSize() int64 {
+	if t.Width <= 0 || t.Height <= 0 {
+		return 0
+	}
+	return int64(t.Width) * int64(t.Height) * 3
+}
+
+// FaceArea3D returns the area of a face using its 3D vertices (Newell's method).
+func (obj *Object) FaceArea3D(faceIdx int) float64 {
+	face := &obj.Faces[faceIdx]
+	var nx, ny, nz float64
+	n := len(face.Vertices)
+	for i := 0; i < n; i++ {
+		a, b := face.Vertices[i].IDVertex, face.Vertices[(i+1)%n].IDVertex
+		if a < 0 || b < 0 || int(a) >= len(obj.Vertices) || int(b) >= len(obj.Vertices) {
+			return 0
+		}
+		va, vb := obj.Vertices[a], obj.Vertices[b]
+		nx += (va.Y - vb.Y) * (va.Z + vb.Z)
+		ny += (va.Z - vb.Z) * (va.X + vb.X)
+		nz += (va.X - vb.X) * (va.Y + vb.Y)
+	}
+	return math.Sqrt(nx*nx+ny*ny+nz*nz) / 2
+}
+
+// Area2D returns the area of the unfolded face (shoelace formula).
+func (f *Face) Area2D() float64 {
+	var sum float64
+	n := len(f.Vertices)
+	for i := 0; i < n; i++ {
+		a, b := f.Vertices[i], f.Vertices[(i+1)%n]
+		sum += a.X*b.Y - b.X*a.Y
+	}
+	return math.Abs(sum) / 2
+}
@@ -0,0 +1,27 @@
+package pdo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/pyramid.pdo")
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	s := Stats(p)
+	if s.Objects != 1 || s.Vertices != 5 || s.Faces != 6 || s.Parts != 1 {
+		t.Errorf("unexpected counts: %+v", s)
+	}
+	if s.PagesX != 1 || s.PagesY != 1 {
+		t.Errorf("got %dx%d pages, want 1x1", s.PagesX, s.PagesY)
+	}
+
+	// The template is the model surface scaled by the unfold scale.
+	scaled := s.SurfaceArea * p.Unfold.Scale * p.Unfold.Scale
+	if math.Abs(scaled-s.TemplateArea) > 0.01*s.TemplateArea {
+		t.Errorf("scaled surface area %.2f does not match template area %.2f", scaled, s.TemplateArea)
+	}
+}
@@ -0,0 +1,446 @@
+package pdo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// Handler receives the pieces of a PDO as Stream reads them, in file
+// order. The pieces of callbacks left nil aren't decoded: Stream moves past
+// them, seeking when the input is an io.Seeker, so reading only the
+// settings and text blocks of a file barely touches its geometry and
+// textures. The values passed are only valid during the call, the parser
+// may reuse them; an error returned by a callback stops the stream and is
+// returned by Stream.
+type Handler struct {
+	Header func(h *Header) error
+	// ObjectStart gets the object's name and visibility, its vertices,
+	// faces and edges follow before ObjectEnd.
+	ObjectStart func(i int, obj *Object) error
+	Vertex      func(object, i int, v *Vertex3D) error
+	Face        func(object, i int, f *Face) error
+	Edge        func(object, i int, e *Edge) error
+	ObjectEnd   func(i int) error
+	// Material gets each material with its texture data.
+	Material func(i int, m *Material) error
+	// Unfold gets the layout scale and box, before the parts.
+	Unfold    func(u *Unfold) error
+	Part      func(i int, part *Part) error
+	TextBlock func(i int, tb *TextBlock) error
+	// Image gets each page image with its texture data.
+	Image    func(i int, img *Image) error
+	Settings func(s *Settings) error
+
+	// SkipTextures leaves the texture data of materials and images out:
+	// their textures keep the size but no RawData.
+	SkipTextures bool
+}
+
+// Stored sizes of fixed-size records.
+var (
+	vertexSize     = int64(binary.Size(Vertex3D{}))
+	faceVertexSize = int64(binary.Size(Face2DVertex{}))
+	edgeSize       = int64(binary.Size(Edge{}))
+	rectSize       = int64(binary.Size(Rect{}))
+	colorsSize     = int64(binary.Size(Color3D{}) + binary.Size([4]float32{}))
+)
+
+const (
+	// faceHeaderSize covers the material and part indices, normal and
+	// coordinate of a face, before its vertices.
+	faceHeaderSize = 4 + 4 + 4*8
+	// lineSize is the stored size of a line without its second face.
+	lineSize = 15
+)
+
+// Stream reads the file and hands its pieces to h instead of keeping them,
+// so memory stays bounded by the largest single piece, such as a texture
+// or a part's lines, whatever the size of the file. Only the header and the
+// settings are kept in the parser's PDO. Limits apply as with Load. What
+// follows the settings, like a thumbnail, isn't read.
+func (p *Parser) Stream(h *Handler) error {
+	p.reader.budget = &budget{limits: p.Options.Limits}
+	// Nothing is written back, don't keep the stored strings.
+	p.reader.raw = nil
+	p.skipTextures = h.SkipTextures
+	defer func() { p.skipTextures = false }()
+	if err := p.ReadHeader(); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if err := emit(h.Header, &p.PDO.Header); err != nil {
+		return err
+	}
+	if err := p.streamObjects(h); err != nil {
+		return fmt.Errorf("failed to read objects: %w", err)
+	}
+	if err := p.streamMaterials(h); err != nil {
+		return fmt.Errorf("failed to read materials: %w", err)
+	}
+	if err := p.streamUnfold(h); err != nil {
+		return fmt.Errorf("failed to read unfold data: %w", err)
+	}
+	if err := p.ReadSettings(); err != nil {
+		return fmt.Errorf("failed to read settings: %w", err)
+	}
+	return emit(h.Settings, &p.PDO.Settings)
+}
+
+// emit calls a callback of the handler if it is set.
+func emit[T any](f func(T) error, v T) error {
+	if f == nil {
+		return nil
+	}
+	return f(v)
+}
+
+// readCount reads the count of a list and charges it to the budget.
+func (p *Parser) readCount(what string, size uintptr) (int, error) {
+	var count int32
+	if err := p.reader.ReadBytes(&count); err != nil {
+		return 0, err
+	}
+	if err := p.reader.budget.alloc(what, count, size); err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (p *Parser) streamObjects(h *Handler) error {
+	count, err := p.readCount("objects", unsafe.Sizeof(Object{}))
+	if err != nil {
+		return err
+	}
+	for i := range count {
+		if err := p.streamObject(h, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipCount reads the count of a list that is skipped.
+func (p *Parser) skipCount() (int64, error) {
+	var count int32
+	if err := p.reader.ReadBytes(&count); err != nil {
+		return 0, err
+	}
+	if count < 0 {
+		return 0, fmt.Errorf("invalid count %d", count)
+	}
+	return int64(count), nil
+}
+
+// streamObject mirrors ReadObject one vertex, face and edge at a time.
+func (p *Parser) streamObject(h *Handler, oi int) error {
+	if h.ObjectStart == nil {
+		if err := p.reader.SkipString(); err != nil {
+			return err
+		}
+		if err := p.reader.Skip(1); err != nil {
+			return err
+		}
+	} else {
+		var obj Object
+		var err error
+		if obj.Name, err = p.reader.ReadShiftedString(); err != nil {
+			return err
+		}
+		if err := p.reader.ReadBytes(&obj.Visible); err != nil {
+			return err
+		}
+		if err := h.ObjectStart(oi, &obj); err != nil {
+			return err
+		}
+	}
+
+	if h.Vertex == nil {
+		n, err := p.skipCount()
+		if err != nil {
+			return err
+		}
+		if err := p.reader.Skip(n * vertexSize); err != nil {
+			return err
+		}
+	} else {
+		var numVertices int32
+		if err := p.reader.ReadBytes(&numVertices); err != nil {
+			return err
+		}
+		if err := p.reader.budget.addVertices(numVertices); err != nil {
+			return err
+		}
+		var v Vertex3D
+		for i := range int(numVertices) {
+			if err := p.reader.ReadBytes(&v); err != nil {
+				return err
+			}
+			if err := h.Vertex(oi, i, &v); err != nil {
+				return err
+			}
+		}
+	}
+
+	if h.Face == nil {
+		n, err := p.skipCount()
+		if err != nil {
+			return err
+		}
+		for range n {
+			if err := p.reader.Skip(faceHeaderSize); err != nil {
+				return err
+			}
+			vertices, err := p.skipCount()
+			if err != nil {
+				return err
+			}
+			if err := p.reader.Skip(vertices * faceVertexSize); err != nil {
+				return err
+			}
+		}
+	} else {
+		var numFaces int32
+		if err := p.reader.ReadBytes(&numFaces); err != nil {
+			return err
+		}
+		if err := p.reader.budget.addFaces(numFaces); err != nil {
+			return err
+		}
+		var f Face
+		for i := range int(numFaces) {
+			if err := p.ReadFace(&f); err != nil {
+				return err
+			}
+			if err := h.Face(oi, i, &f); err != nil {
+				return err
+			}
+		}
+	}
+
+	if h.Edge == nil {
+		n, err := p.skipCount()
+		if err != nil {
+			return err
+		}
+		if err := p.reader.Skip(n * edgeSize); err != nil {
+			return err
+		}
+	} else {
+		numEdges, err := p.readCount("edges", unsafe.Sizeof(Edge{}))
+		if err != nil {
+			return err
+		}
+		var e Edge
+		for i := range numEdges {
+			if err := p.reader.ReadBytes(&e); err != nil {
+				return err
+			}
+			if err := h.Edge(oi, i, &e); err != nil {
+				return err
+			}
+		}
+	}
+	return emit(h.ObjectEnd, oi)
+}
+
+func (p *Parser) streamMaterials(h *Handler) error {
+	count, err := p.readCount("materials", unsafe.Sizeof(Material{}))
+	if err != nil {
+		return err
+	}
+	for i := range count {
+		if h.Material == nil {
+			if err := p.skipMaterial(); err != nil {
+				return err
+			}
+			continue
+		}
+		var m Material
+		if err := p.ReadMaterial(&m); err != nil {
+			return err
+		}
+		if m.Name == "" {
+			m.Name = fmt.Sprintf("named_material%d", i)
+		}
+		if err := h.Material(i, &m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamUnfold mirrors ReadUnfoldData.
+func (p *Parser) streamUnfold(h *Handler) error {
+	var hasUnfold uint8
+	if err := p.reader.ReadBytes(&hasUnfold); err != nil {
+		return err
+	}
+	if hasUnfold == 0 {
+		return nil
+	}
+	u := &p.PDO.Unfold
+	if err := p.reader.ReadBytes(&u.Scale); err != nil {
+		return err
+	}
+	var padding uint8
+	if err := p.reader.ReadBytes(&padding); err != nil {
+		return err
+	}
+	if err := p.reader.ReadBytes(&u.BoundingBox); err != nil {
+		return err
+	}
+	if err := emit(h.Unfold, u); err != nil {
+		return err
+	}
+
+	count, err := p.readCount("parts", unsafe.Sizeof(Part{}))
+	if err != nil {
+		return err
+	}
+	p.parts = count
+	for i := range count {
+		if h.Part == nil {
+			if err := p.skipPart(); err != nil {
+				return err
+			}
+			continue
+		}
+		var part Part
+		if err := p.ReadPart(&part); err != nil {
+			return err
+		}
+		if err := h.Part(i, &part); err != nil {
+			return err
+		}
+	}
+
+	count, err = p.readCount("text blocks", unsafe.Sizeof(TextBlock{}))
+	if err != nil {
+		return err
+	}
+	for i := range count {
+		if h.TextBlock == nil {
+			if err := p.skipTextBlock(); err != nil {
+				return err
+			}
+			continue
+		}
+		var tb TextBlock
+		if err := p.ReadTextBlock(&tb); err != nil {
+			return err
+		}
+		if err := h.TextBlock(i, &tb); err != nil {
+			return err
+		}
+	}
+
+	// Images come in two lists, numbered on as one.
+	n := 0
+	for range 2 {
+		count, err := p.readCount("images", unsafe.Sizeof(Image{}))
+		if err != nil {
+			return err
+		}
+		for range count {
+			i := n
+			n++
+			if h.Image == nil {
+				if err := p.reader.Skip(rectSize); err != nil {
+					return err
+				}
+				if err := p.skipTexture(); err != nil {
+					return err
+				}
+				continue
+			}
+			var img Image
+			if err := p.ReadImage(&img); err != nil {
+				return err
+			}
+			if err := h.Image(i, &img); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Parser) skipMaterial() error {
+	if err := p.reader.SkipString(); err != nil {
+		return err
+	}
+	if err := p.reader.Skip(colorsSize); err != nil {
+		return err
+	}
+	var texFlag uint8
+	if err := p.reader.ReadBytes(&texFlag); err != nil {
+		return err
+	}
+	if texFlag == 1 {
+		return p.skipTexture()
+	}
+	return nil
+}
+
+func (p *Parser) skipTexture() error {
+	if err := p.reader.Skip(8); err != nil { // Width and height
+		return err
+	}
+	var wrappedSize int32
+	if err := p.reader.ReadBytes(&wrappedSize); err != nil {
+		return err
+	}
+	if wrappedSize < TextureDataWrapperSize {
+		return fmt.Errorf("%w: wrapped size %d is smaller than the wrapper", ErrBadTexture, wrappedSize)
+	}
+	return p.reader.Skip(int64(wrappedSize))
+}
+
+// skipPart moves past a part. Lines differ in size, each is looked at.
+func (p *Parser) skipPart() error {
+	if err := p.reader.Skip(4 + rectSize); err != nil { // Object index and box
+		return err
+	}
+	if p.PDO.Header.Version > PDO_V4 {
+		if err := p.reader.SkipString(); err != nil {
+			return err
+		}
+	}
+	n, err := p.skipCount()
+	if err != nil {
+		return err
+	}
+	var line [lineSize]byte
+	for range n {
+		if err := p.reader.ReadBytes(&line); err != nil {
+			return err
+		}
+		if line[lineSize-1] == 1 {
+			// Second face and vertex
+			if err := p.reader.Skip(8); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Parser) skipTextBlock() error {
+	// Box, line spacing, color and font size
+	if err := p.reader.Skip(rectSize + 8 + 4 + 4); err != nil {
+		return err
+	}
+	if err := p.reader.SkipString(); err != nil {
+		return err
+	}
+	n, err := p.skipCount()
+	if err != nil {
+		return err
+	}
+	for range n {
+		if err := p.reader.SkipString(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package pdo
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	const path = "../../sample_basic_shapes/torus.pdo"
+	want, err := ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var objects, vertices, faces, edges, parts, lines int
+	var settings *Settings
+	var area float64
+	parser := NewParser(f)
+	err = parser.Stream(&Handler{
+		ObjectStart: func(i int, obj *Object) error {
+			if obj.Name != want.Objects[i].Name {
+				t.Errorf("object %d named %q", i, obj.Name)
+			}
+			objects++
+			return nil
+		},
+		Vertex: func(object, i int, v *Vertex3D) error { vertices++; return nil },
+		Face: func(object, i int, f *Face) error {
+			faces++
+			area += f.Area2D()
+			return nil
+		},
+		Edge: func(object, i int, e *Edge) error { edges++; return nil },
+		Part: func(i int, part *Part) error {
+			parts++
+			lines += len(part.Lines)
+			return nil
+		},
+		Settings: func(s *Settings) error { settings = s; return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := Stats(want)
+	if objects != s.Objects || vertices != s.Vertices || faces != s.Faces || edges != s.Edges || parts != s.Parts || lines != s.Lines {
+		t.Errorf("streamed %d objects, %d vertices, %d faces, %d edges, %d parts, %d lines, want %+v",
+			objects, vertices, faces, edges, parts, lines, s)
+	}
+	if area != s.TemplateArea {
+		t.Errorf("template area %g, want %g", area, s.TemplateArea)
+	}
+	if settings == nil || *settings != want.Settings {
+		t.Errorf("settings %+v, want %+v", settings, want.Settings)
+	}
+	if len(parser.PDO.Objects) != 0 || len(parser.PDO.Parts) != 0 {
+		t.Error("stream kept objects or parts")
+	}
+
+	// A callback error stops the stream.
+	f.Seek(0, 0)
+	stop := errors.New("stop")
+	err = NewParser(f).Stream(&Handler{Face: func(object, i int, f *Face) error { return stop }})
+	if !errors.Is(err, stop) {
+		t.Errorf("stream returned %v, want the callback's error", err)
+	}
+}
+
+func TestStream_Skip(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tex Texture
+	if err := tex.SetImage(image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	p.Materials = append(p.Materials, Material{Name: "skin", HasTexture: true, Texture: tex})
+	p.TextBlocks = append(p.TextBlocks, TextBlock{FontName: "Arial", Lines: []string{"Cut", "here"}})
+	p.Images = append(p.Images, Image{BoundingBox: Rect{Width: 10, Height: 10}, Texture: tex})
+	p.Settings.Comment = "end"
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+
+	// Plain readers are skipped by reading, files by seeking.
+	for _, r := range []io.Reader{bytes.NewBuffer(buf.Bytes()), bytes.NewReader(buf.Bytes())} {
+		var blocks []TextBlock
+		var settings Settings
+		err := NewParser(r).Stream(&Handler{
+			TextBlock: func(i int, tb *TextBlock) error {
+				blocks = append(blocks, *tb)
+				return nil
+			},
+			Settings: func(s *Settings) error { settings = *s; return nil },
+		})
+		if err != nil {
+			t.Fatalf("%T: %v", r, err)
+		}
+		if len(blocks) != 1 || !blocks[0].Equal(&p.TextBlocks[0]) {
+			t.Errorf("%T: text blocks %+v", r, blocks)
+		}
+		if settings != p.Settings {
+			t.Errorf("%T: settings %+v, want %+v", r, settings, p.Settings)
+		}
+	}
+
+	// Textures can be left out while materials and images are read.
+	var materials, images int
+	err = NewParser(bytes.NewReader(buf.Bytes())).Stream(&Handler{
+		SkipTextures: true,
+		Material: func(i int, m *Material) error {
+			if m.HasTexture && (m.Texture.Width != 8 || m.Texture.RawData != nil) {
+				t.Errorf("material %d texture %dx%d with %d bytes", i, m.Texture.Width, m.Texture.Height, len(m.Texture.RawData))
+			}
+			materials++
+			return nil
+		},
+		Image: func(i int, img *Image) error {
+			if img.BoundingBox != p.Images[i].BoundingBox || img.Texture.RawData != nil {
+				t.Errorf("image %d: %+v", i, img.BoundingBox)
+			}
+			images++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if materials != len(p.Materials) || images != 1 {
+		t.Errorf("streamed %d materials and %d images", materials, images)
+	}
+}
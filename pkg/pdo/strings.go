@@ -0,0 +1,97 @@
+package pdo
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// StringInfo reports the string encoding found in the header and the one
+// actually used to decode strings.
+type StringInfo struct {
+	HeaderShift     int32
+	HeaderMultiByte int32
+
+	Shift         byte
+	MultiByte     bool
+	ByteWiseShift bool
+
+	// Recovered is set when RecoverStrings picked a different encoding than
+	// the one stored in the header.
+	Recovered bool
+}
+
+// decodeHeaderStrings settles the reader's string encoding using the raw
+// header strings. The designer ID is never shifted, the others are.
+func (p *Parser) decodeHeaderStrings(designerID []byte, shifted [][]byte) {
+	r := p.reader
+	if p.Options.RecoverStrings {
+		best := scoreStrings(designerID, shifted, r.StringShift, r.MultiByteC, r.ByteWiseShift)
+		found := false
+		for _, mb := range []bool{false, true} {
+			for _, byteWise := range []bool{false, true} {
+				if byteWise && !mb {
+					continue
+				}
+				for shift := 0; shift < 256; shift++ {
+					score := scoreStrings(designerID, shifted, byte(shift), mb, byteWise)
+					if score > best {
+						best = score
+						r.StringShift, r.MultiByteC, r.ByteWiseShift = byte(shift), mb, byteWise
+						found = true
+					}
+				}
+			}
+		}
+		p.Strings.Recovered = found
+		if found {
+			p.Options.logger().Warn("header strings decoded with a recovered encoding",
+				"header_shift", p.PDO.Header.StringShift, "shift", r.StringShift,
+				"multi_byte", r.MultiByteC, "byte_wise", r.ByteWiseShift)
+		}
+	} else if r.MultiByteC {
+		// The header doesn't tell whether the shift applies to whole
+		// characters or to each of their bytes, take the one that reads.
+		word := scoreStrings(designerID, shifted, r.StringShift, true, false)
+		r.ByteWiseShift = scoreStrings(designerID, shifted, r.StringShift, true, true) > word
+	}
+	if !p.Options.RecoverStrings && scoreStrings(designerID, shifted, r.StringShift, r.MultiByteC, r.ByteWiseShift) < 0 {
+		p.Options.logger().Warn("header strings look garbled, string recovery may decode them",
+			"shift", r.StringShift, "multi_byte", r.MultiByteC)
+	}
+
+	p.Strings.HeaderShift = p.PDO.Header.StringShift
+	p.Strings.HeaderMultiByte = p.PDO.Header.MultiByteChars
+	p.Strings.Shift = r.StringShift
+	p.Strings.MultiByte = r.MultiByteC
+	p.Strings.ByteWiseShift = r.ByteWiseShift
+}
+
+func scoreStrings(designerID []byte, shifted [][]byte, shift byte, mb, byteWise bool) int {
+	score := stringPlausibility(DecodeString(designerID, 0, mb, byteWise))
+	for _, raw := range shifted {
+		score += stringPlausibility(DecodeString(raw, shift, mb, byteWise))
+	}
+	return score
+}
+
+// stringPlausibility rewards printable ASCII and letters, and penalises
+// control characters, symbols, half-width katakana and decoding failures.
+// Wrong shifts tend to produce the latter, so longer clean strings score highest.
+func stringPlausibility(s string) int {
+	score := 0
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			score -= 4
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			score += 2
+		case r >= 0xFF61 && r <= 0xFF9F: // Half-width katakana
+			score -= 4
+		case unicode.IsLetter(r):
+			score++
+		default:
+			score -= 4
+		}
+	}
+	return score
+}
@@ -3,12 +3,33 @@ package pdo
 import (
 	"bytes"
 	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
 	"fmt"
 	"image"
 	"image/color"
 	"io"
 )
 
+// Values of Header.TexLock, telling whether a locked file's textures may
+// be changed.
+const (
+	TexLockOff   = 0 // No effect, the file isn't locked
+	TexLockDeny  = 1 // The textures may not be changed or taken out
+	TexLockAllow = 2 // The textures may be changed though the file is locked
+)
+
+// CheckTextureLock returns an error wrapping ErrLocked when the file's
+// texture lock forbids taking its textures out, as image files or with 3D
+// models. Printing them on the template is what the file is for and stays
+// allowed.
+func (p *PDO) CheckTextureLock() error {
+	if p.Header.TexLock == TexLockDeny {
+		return fmt.Errorf("%w: the designer locked the textures", ErrLocked)
+	}
+	return nil
+}
+
 // DecompressTexture decodes the texture data into an image.Image
 // The data structure seems to be:
 // - wrapped_size (4 bytes) [Read by Parser]
@@ -18,21 +39,15 @@ import (
 // So RawData contains the raw deflate stream.
 func (t *Texture) GetImage() (image.Image, error) {
 	if len(t.RawData) == 0 {
-		return nil, fmt.Errorf("no texture data")
+		return nil, fmt.Errorf("%w: no texture data", ErrBadTexture)
+	}
+	if t.Width <= 0 || t.Height <= 0 {
+		return nil, fmt.Errorf("%w: invalid size %dx%d", ErrBadTexture, t.Width, t.Height)
 	}
 
-	// Raw deflate stream
-	r := flate.NewReader(bytes.NewReader(t.RawData))
-	defer r.Close()
-
-	// Decompressed size should be Width * Height * 3 (RGB)
-	// Or maybe RGBA? Pascal code says "size := tex.width * tex.height * 3;"
-	// So it's RGB.
-	expectedSize := int(t.Width) * int(t.Height) * 3
-	out := make([]byte, expectedSize)
-
-	if _, err := io.ReadFull(r, out); err != nil {
-		return nil, fmt.Errorf("deflate read failed: %w", err)
+	out, err := t.rgb()
+	if err != nil {
+		return nil, err
 	}
 
 	// Create image
@@ -76,3 +91,99 @@ func (t *Texture) GetImage() (image.Image, error) {
 
 	return img, nil
 }
+
+// SetImage stores img as the texture, RGB rows top first, compressed the
+// way Pepakura Designer does.
+func (t *Texture) SetImage(img image.Image) error {
+	b := img.Bounds()
+	raw := make([]byte, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			raw = append(raw, c.R, c.G, c.B)
+		}
+	}
+
+	t.Width, t.Height = int32(b.Dx()), int32(b.Dy())
+	return t.store(raw)
+}
+
+// rgb inflates the RGB pixels of the texture.
+func (t *Texture) rgb() ([]byte, error) {
+	// Raw deflate stream
+	r := flate.NewReader(bytes.NewReader(t.RawData))
+	defer r.Close()
+
+	// Decompressed size should be Width * Height * 3 (RGB)
+	// Or maybe RGBA? Pascal code says "size := tex.width * tex.height * 3;"
+	// So it's RGB.
+	expectedSize := int(t.Width) * int(t.Height) * 3
+	out := make([]byte, expectedSize)
+
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil, fmt.Errorf("%w: deflate read failed: %w", ErrBadTexture, err)
+	}
+	return out, nil
+}
+
+// store compresses RGB pixels into the texture data.
+func (t *Texture) store(raw []byte) error {
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return err
+	}
+	zw.Write(raw)
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	// The zlib header and Adler-32 checksum are kept apart from the deflate
+	// stream, in file byte order.
+	z := buf.Bytes()
+	t.DataHeader = binary.LittleEndian.Uint16(z)
+	t.RawData = bytes.Clone(z[2 : len(z)-4])
+	t.DataHash = binary.LittleEndian.Uint32(z[len(z)-4:])
+	t.DataSize = uint32(len(t.RawData))
+	return nil
+}
+
+// Recompress deflates the texture again at the best compression level and
+// keeps the result if it is smaller. The pixels don't change. It returns the
+// number of bytes saved.
+func (t *Texture) Recompress() (int, error) {
+	raw, err := t.rgb()
+	if err != nil {
+		return 0, err
+	}
+	smaller := *t
+	if err := smaller.store(raw); err != nil {
+		return 0, err
+	}
+	saved := len(t.RawData) - len(smaller.RawData)
+	if saved <= 0 {
+		return 0, nil
+	}
+	*t = smaller
+	return saved, nil
+}
+
+// RecompressTextures recompresses the textures of all materials and images,
+// see Texture.Recompress. It returns the stored size of the textures before
+// and after.
+func (p *PDO) RecompressTextures() (before, after int64, err error) {
+	textures := make([]*Texture, 0, len(p.Materials)+len(p.Images))
+	for _, m := range p.TexturedMaterials() {
+		textures = append(textures, &m.Texture)
+	}
+	for i := range p.Images {
+		textures = append(textures, &p.Images[i].Texture)
+	}
+	for _, t := range textures {
+		before += int64(len(t.RawData))
+		if _, err := t.Recompress(); err != nil {
+			return 0, 0, err
+		}
+		after += int64(len(t.RawData))
+	}
+	return before, after, nil
+}
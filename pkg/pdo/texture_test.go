@@ -0,0 +1,65 @@
+package pdo
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestTexture_Recompress(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := range 64 {
+		for x := range 64 {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), uint8(x ^ y), 255})
+		}
+	}
+	var tex Texture
+	if err := tex.SetImage(img); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := tex.rgb()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Store the pixels the way a fast encoder would.
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	fw.Write(raw)
+	fw.Close()
+	tex.RawData = buf.Bytes()
+	p := &PDO{Materials: []Material{{HasTexture: true, Texture: tex}}}
+
+	before, after, err := p.RecompressTextures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != int64(buf.Len()) || after >= before || after != int64(len(p.Materials[0].Texture.RawData)) {
+		t.Errorf("recompressed %d to %d bytes, started with %d", before, after, buf.Len())
+	}
+	got, err := p.Materials[0].Texture.rgb()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Error("pixels changed")
+	}
+
+	// Already at best, nothing changes.
+	again := p.Materials[0].Texture
+	if saved, err := p.Materials[0].Texture.Recompress(); err != nil || saved != 0 || !p.Materials[0].Texture.Equal(&again) {
+		t.Errorf("second pass saved %d, %v", saved, err)
+	}
+}
+
+func TestCheckTextureLock(t *testing.T) {
+	var p PDO
+	for lock, locked := range map[int32]bool{TexLockOff: false, TexLockDeny: true, TexLockAllow: false} {
+		p.Header.TexLock = lock
+		if err := p.CheckTextureLock(); (err != nil) != locked || err != nil && !errors.Is(err, ErrLocked) {
+			t.Errorf("texture lock %d: %v", lock, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package pdo
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// ThumbnailMagic starts the preview chunk pdo-tools appends after the
+// settings, where Pepakura stops reading. The chunk is the magic, the
+// little-endian int32 length of the image and the PNG data.
+const ThumbnailMagic = "PDOTOOLS-THUMB"
+
+// splitThumbnail separates a preview chunk from the data following the
+// settings. Data that doesn't start with a complete chunk is all rest.
+func splitThumbnail(data []byte) (thumb, rest []byte) {
+	head := len(ThumbnailMagic) + 4
+	if len(data) < head || !bytes.HasPrefix(data, []byte(ThumbnailMagic)) {
+		return nil, data
+	}
+	n := int(int32(binary.LittleEndian.Uint32(data[len(ThumbnailMagic):])))
+	if n < 0 || n > len(data)-head {
+		return nil, data
+	}
+	return data[head : head+n], data[head+n:]
+}
+
+func writeThumbnail(w *Writer, thumb []byte) {
+	if len(thumb) == 0 {
+		return
+	}
+	w.WriteBytes([]byte(ThumbnailMagic))
+	w.WriteBytes(int32(len(thumb)))
+	w.WriteBytes(thumb)
+}
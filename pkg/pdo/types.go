@@ -23,14 +23,18 @@ type Header struct {
 }
 
 type Face2DVertex struct {
-	IDVertex     int32
-	X, Y         float64
-	U, V         float64
-	Flap         uint8
-	FlapHeight   float64
-	FlapAAngle   float64
-	FlapBAngle   float64
-	FlapFoldInfo [24]byte // 3*4 + 3*4
+	IDVertex   int32
+	X, Y       float64
+	U, V       float64
+	Flap       uint8
+	FlapHeight float64
+	FlapAAngle float64
+	FlapBAngle float64
+	// FlapLineColor and FoldLineColor are the custom colors of the lines
+	// of the flap on the edge starting at this vertex and of the fold line
+	// along it, components from 0 to 1. Zero when the file sets none.
+	FlapLineColor [3]float32
+	FoldLineColor [3]float32
 }
 
 type Face struct {
@@ -78,9 +82,39 @@ type Texture struct {
 	RawData    []byte
 }
 
+// RGBA is a color with components from 0 to 1.
+type RGBA struct {
+	R, G, B, A float32
+}
+
+// RGB returns the color components without alpha.
+func (c RGBA) RGB() [3]float32 {
+	return [3]float32{c.R, c.G, c.B}
+}
+
+// SetRGB changes the color components, keeping alpha.
+func (c *RGBA) SetRGB(rgb [3]float32) {
+	c.R, c.G, c.B = rgb[0], rgb[1], rgb[2]
+}
+
+// Color3D holds the colors of a material in the 3D view, in file order.
+type Color3D struct {
+	// Material is the base color of the material, exported as the ambient
+	// color.
+	Material RGBA
+	// Model is the color faces show in the 3D view, exported as the
+	// diffuse color.
+	Model RGBA
+	// Light is the color of light reflected by the material, exported as
+	// the specular color.
+	Light RGBA
+	// Diffuse is the color of diffusely scattered light, usually Model.
+	Diffuse RGBA
+}
+
 type Material struct {
 	Name        string
-	Color3D     [16]float32 // 4*4 float32
+	Color3D     Color3D
 	Color2DRGBA [4]float32
 	HasTexture  bool
 	Texture     Texture
@@ -160,4 +194,17 @@ type PDO struct {
 	Images     []Image
 	Settings   Settings
 	Unfold     Unfold
+	// Thumbnail is a PNG preview of the model kept in a chunk after the
+	// settings, see ThumbnailMagic. Nil when the file has none.
+	Thumbnail []byte
+
+	// source holds undecoded data of a parsed file for writing it back.
+	source *source
+}
+
+// HasUnfold reports whether the model has been unfolded: the file stores an
+// unfolding, even one whose parts were all removed, or parts were added.
+// Files saved before unfolding have only the 3D model.
+func (p *PDO) HasUnfold() bool {
+	return len(p.Parts) > 0 || p.source != nil && p.source.hasUnfold
 }
@@ -0,0 +1,269 @@
+package pdo
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Severity ranks validation issues.
+type Severity int
+
+const (
+	// SeverityWarning issues are suspicious but the model can still be built.
+	SeverityWarning Severity = iota
+	// SeverityError issues make the model unbuildable or break exports.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// MarshalText writes the severity by name, for JSON reports.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Issue is a problem found by Validate. Indices that don't apply are -1.
+type Issue struct {
+	Rule     string
+	Severity Severity
+	Object   int
+	Part     int
+	Face     int
+	// Edge is the edge index; the edge ID on the template is Edge+1.
+	Edge    int
+	Message string
+}
+
+// validationRule checks one kind of problem.
+type validationRule struct {
+	name  string
+	check func(p *PDO) []Issue
+}
+
+var validationRules = []validationRule{
+	{"orphan-edge", checkOrphanEdges},
+	{"non-manifold-edge", checkNonManifoldEdges},
+	{"degenerate-face", checkDegenerateFaces},
+	{"duplicate-face", checkDuplicateFaces},
+	{"physical-size", checkSize},
+}
+
+// Validate checks the model for problems that make it unbuildable or trip up
+// Pepakura Designer and exporters. Issues are grouped by rule.
+func Validate(p *PDO) []Issue {
+	var issues []Issue
+	for _, r := range validationRules {
+		for _, is := range r.check(p) {
+			is.Rule = r.name
+			issues = append(issues, is)
+		}
+	}
+	return issues
+}
+
+// checkOrphanEdges finds cut edges drawn on one side of the layout whose
+// mating edge is missing: the other face isn't unfolded or its part has no
+// line for the edge. Edges between unfolded faces missing from the layout
+// altogether are reported too.
+func checkOrphanEdges(p *PDO) []Issue {
+	if len(p.Parts) == 0 {
+		return nil
+	}
+	var issues []Issue
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		lk := NewLookup(obj)
+		folded := map[int]bool{}
+		cutSides := map[int]map[int32]bool{} // Faces with a boundary line per edge
+		for _, part := range p.Parts {
+			if int(part.ObjectIndex) != oi {
+				continue
+			}
+			for i := range part.Lines {
+				line := &part.Lines[i]
+				v1, v2 := lk.LineEnds(line)
+				if v1 == nil {
+					continue
+				}
+				e := lk.EdgeIndex(v1.IDVertex, v2.IDVertex)
+				if e < 0 {
+					continue
+				}
+				if line.IsConnectingFaces {
+					folded[e] = true
+					continue
+				}
+				if cutSides[e] == nil {
+					cutSides[e] = map[int32]bool{}
+				}
+				cutSides[e][line.FaceIndex] = true
+			}
+		}
+
+		unfolded := func(f int32) bool {
+			if f < 0 || int(f) >= len(obj.Faces) {
+				return false
+			}
+			part := obj.Faces[f].PartIndex
+			return part >= 0 && int(part) < len(p.Parts)
+		}
+		for ei, e := range obj.Edges {
+			if e.Face2Index < 0 || folded[ei] {
+				continue
+			}
+			sides := cutSides[ei]
+			for _, f := range []int32{e.Face1Index, e.Face2Index} {
+				other := e.Face1Index
+				if f == e.Face1Index {
+					other = e.Face2Index
+				}
+				if !sides[f] || sides[other] {
+					continue
+				}
+				is := Issue{Severity: SeverityError, Object: oi, Part: int(obj.Faces[f].PartIndex), Face: int(f), Edge: ei}
+				if unfolded(other) {
+					is.Message = fmt.Sprintf("edge %d is cut on part %d but missing on part %d", ei+1, obj.Faces[f].PartIndex, obj.Faces[other].PartIndex)
+				} else {
+					is.Message = fmt.Sprintf("edge %d is cut on part %d but its other face %d isn't unfolded", ei+1, obj.Faces[f].PartIndex, other)
+				}
+				issues = append(issues, is)
+			}
+			if len(sides) == 0 && unfolded(e.Face1Index) && unfolded(e.Face2Index) {
+				issues = append(issues, Issue{
+					Severity: SeverityError, Object: oi, Part: int(obj.Faces[e.Face1Index].PartIndex), Face: int(e.Face1Index), Edge: ei,
+					Message: fmt.Sprintf("edge %d between faces %d and %d is neither folded nor cut in the layout", ei+1, e.Face1Index, e.Face2Index),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// checkNonManifoldEdges finds edges shared by more than two faces, which
+// can't be unfolded into a single paper surface.
+func checkNonManifoldEdges(p *PDO) []Issue {
+	var issues []Issue
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		type key struct{ a, b int32 }
+		faces := map[key][]int{}
+		var order []key
+		for fi, f := range obj.Faces {
+			for j, fv := range f.Vertices {
+				a, b := fv.IDVertex, f.Vertices[(j+1)%len(f.Vertices)].IDVertex
+				if a == b {
+					continue
+				}
+				k := key{min(a, b), max(a, b)}
+				if faces[k] == nil {
+					order = append(order, k)
+				}
+				faces[k] = append(faces[k], fi)
+			}
+		}
+		for _, k := range order {
+			if fs := faces[k]; len(fs) > 2 {
+				issues = append(issues, Issue{
+					Severity: SeverityError, Object: oi, Part: -1, Face: fs[0], Edge: obj.EdgeIndex(k.a, k.b),
+					Message: fmt.Sprintf("edge between vertices %d and %d is shared by %d faces %v", k.a, k.b, len(fs), fs),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// checkDegenerateFaces finds faces with bad vertex indices, less than three
+// distinct corners or no area.
+func checkDegenerateFaces(p *PDO) []Issue {
+	var issues []Issue
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		for fi, f := range obj.Faces {
+			is := Issue{Severity: SeverityError, Object: oi, Part: int(f.PartIndex), Face: fi, Edge: -1}
+			distinct := map[int32]bool{}
+			var longest float64
+			bad := false
+			for j, fv := range f.Vertices {
+				next := f.Vertices[(j+1)%len(f.Vertices)].IDVertex
+				if fv.IDVertex < 0 || int(fv.IDVertex) >= len(obj.Vertices) || next < 0 || int(next) >= len(obj.Vertices) {
+					bad = true
+					break
+				}
+				distinct[fv.IDVertex] = true
+				a, b := obj.Vertices[fv.IDVertex], obj.Vertices[next]
+				longest = max(longest, math.Sqrt((a.X-b.X)*(a.X-b.X)+(a.Y-b.Y)*(a.Y-b.Y)+(a.Z-b.Z)*(a.Z-b.Z)))
+			}
+			switch {
+			case bad:
+				is.Message = fmt.Sprintf("face %d has a vertex index out of range", fi)
+			case len(distinct) < 3:
+				is.Message = fmt.Sprintf("face %d has only %d distinct corners", fi, len(distinct))
+			case obj.FaceArea3D(fi) <= 1e-9*longest*longest:
+				is.Message = fmt.Sprintf("face %d has no area", fi)
+			default:
+				continue
+			}
+			issues = append(issues, is)
+		}
+	}
+	return issues
+}
+
+// checkDuplicateFaces finds faces with the same corners as an earlier face,
+// in either winding.
+func checkDuplicateFaces(p *PDO) []Issue {
+	var issues []Issue
+	for oi := range p.Objects {
+		obj := &p.Objects[oi]
+		seen := map[string]int{}
+		for fi, f := range obj.Faces {
+			ids := make([]int32, len(f.Vertices))
+			for j, fv := range f.Vertices {
+				ids[j] = fv.IDVertex
+			}
+			slices.Sort(ids)
+			k := fmt.Sprint(ids)
+			if first, ok := seen[k]; ok {
+				issues = append(issues, Issue{
+					Severity: SeverityError, Object: oi, Part: int(f.PartIndex), Face: fi, Edge: -1,
+					Message: fmt.Sprintf("face %d duplicates face %d", fi, first),
+				})
+				continue
+			}
+			seen[k] = fi
+		}
+	}
+	return issues
+}
+
+// checkSize warns when the header size or unfold scale no longer match the
+// model, as after scaling it in another tool.
+func checkSize(p *PDO) []Issue {
+	if len(p.Objects) == 0 {
+		return nil
+	}
+	c := p.CheckSize()
+	var issues []Issue
+	if c.HeaderMismatch() {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning, Object: -1, Part: -1, Face: -1, Edge: -1,
+			Message: fmt.Sprintf("header says the model is %.4g units tall around (%.4g, %.4g, %.4g), the vertices are %.4g around (%.4g, %.4g, %.4g)",
+				c.HeaderHeight, c.OriginOffset.X, c.OriginOffset.Y, c.OriginOffset.Z, c.ModelHeight, c.Center.X, c.Center.Y, c.Center.Z),
+		})
+	}
+	if c.ScaleMismatch() {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning, Object: -1, Part: -1, Face: -1, Edge: -1,
+			Message: fmt.Sprintf("parts are unfolded at %.4g mm per unit but the unfold scale is %.4g, the model prints %.1f mm tall instead of %.1f mm",
+				c.LayoutScale, c.Scale, c.RealHeight(), c.ModelHeight*c.Scale),
+		})
+	}
+	return issues
+}
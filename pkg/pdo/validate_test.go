@@ -0,0 +1,107 @@
+package pdo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate_OrphanEdges(t *testing.T) {
+	load := func() *PDO {
+		p, err := ParseFile("../../sample_basic_shapes/pyramid.pdo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	if issues := Validate(load()); len(issues) != 0 {
+		t.Fatalf("sample has issues: %v", issues)
+	}
+
+	// The first line of the pyramid is one side of cut edge 7.
+	p := load()
+	obj := &p.Objects[0]
+	v1, v2 := obj.LineEnds(&p.Parts[0].Lines[0])
+	edge := obj.EdgeIndex(v1.IDVertex, v2.IDVertex)
+	p.Parts[0].Lines = p.Parts[0].Lines[1:]
+	issues := Validate(p)
+	if len(issues) != 1 || issues[0].Rule != "orphan-edge" || issues[0].Edge != edge || issues[0].Severity != SeverityError {
+		t.Fatalf("one side removed: got %+v, want an orphan edge %d", issues, edge)
+	}
+
+	// Without both sides the edge isn't in the layout at all.
+	var kept []Line
+	for _, l := range p.Parts[0].Lines {
+		a, b := obj.LineEnds(&l)
+		if obj.EdgeIndex(a.IDVertex, b.IDVertex) != edge {
+			kept = append(kept, l)
+		}
+	}
+	p.Parts[0].Lines = kept
+	issues = Validate(p)
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "neither folded nor cut") {
+		t.Fatalf("both sides removed: got %+v", issues)
+	}
+
+	// A face dropped from the unfolding leaves its neighbours' cuts orphaned.
+	p = load()
+	e := p.Objects[0].Edges[edge]
+	p.Objects[0].Faces[e.Face2Index].PartIndex = -1
+	kept = nil
+	for _, l := range p.Parts[0].Lines {
+		if l.FaceIndex != e.Face2Index && !(l.IsConnectingFaces && l.Face2Index == e.Face2Index) {
+			kept = append(kept, l)
+		}
+	}
+	p.Parts[0].Lines = kept
+	issues = Validate(p)
+	if len(issues) == 0 || !strings.Contains(issues[0].Message, "isn't unfolded") {
+		t.Fatalf("face not unfolded: got %+v", issues)
+	}
+}
+
+func TestValidate_Mesh(t *testing.T) {
+	face := func(ids ...int32) Face {
+		f := Face{PartIndex: -1}
+		for _, id := range ids {
+			f.Vertices = append(f.Vertices, Face2DVertex{IDVertex: id})
+		}
+		return f
+	}
+	p := New()
+	p.Objects = []Object{{
+		Name: "tetra",
+		Vertices: []Vertex3D{
+			{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 0, Y: 1, Z: 0}, {X: 0, Y: 0, Z: 1},
+			{X: 2, Y: 0, Z: 0}, {X: 1, Y: 1, Z: 1},
+		},
+		Faces: []Face{
+			face(0, 2, 1), face(0, 1, 3), face(1, 2, 3), face(0, 3, 2),
+		},
+	}}
+	p.UpdateBounds()
+	if issues := Validate(p); len(issues) != 0 {
+		t.Fatalf("closed tetrahedron has issues: %v", issues)
+	}
+
+	closed := p.Objects[0].Faces
+	for _, tc := range []struct {
+		name  string
+		extra Face
+		rules []string
+	}{
+		{"fin", face(0, 1, 5), []string{"non-manifold-edge"}},
+		{"collinear", face(0, 4, 1), []string{"non-manifold-edge", "degenerate-face"}},
+		{"reversed copy", face(1, 0, 2), []string{"non-manifold-edge", "non-manifold-edge", "non-manifold-edge", "duplicate-face"}},
+		{"two corners", face(5, 5, 4), []string{"degenerate-face"}},
+		{"missing vertex", face(4, 5, 9), []string{"degenerate-face"}},
+	} {
+		p.Objects[0].Faces = append(closed[:4:4], tc.extra)
+		var rules []string
+		for _, is := range Validate(p) {
+			rules = append(rules, is.Rule)
+		}
+		if strings.Join(rules, " ") != strings.Join(tc.rules, " ") {
+			t.Errorf("%s: got %v, want %v", tc.name, rules, tc.rules)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package pdo
+
+import (
+	"fmt"
+)
+
+// SetVersion converts the PDO to the container layout of another version,
+// so that it is written for older or newer Pepakura releases. Fields the
+// target version can't store are cleared and described in the returned
+// warnings; fields it adds keep their zero defaults.
+func (p *PDO) SetVersion(version int32) ([]string, error) {
+	if version < PDO_V4 || version > PDO_V6 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	h := &p.Header
+	if version == h.Version {
+		return nil, nil
+	}
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	if h.Version == PDO_V6 {
+		if h.V6Lock > 0 {
+			warn("dropping %d version 6 lock entries", h.V6Lock)
+		}
+		h.V6Lock = 0
+		if p.source != nil {
+			if len(p.source.settingsItems) > 0 {
+				warn("dropping %d version 6 settings items", len(p.source.settingsItems))
+			}
+			p.source.v6Lock, p.source.settingsItems = nil, nil
+		}
+	}
+
+	if version == PDO_V4 {
+		if h.DesignerID != "" {
+			warn("dropping designer ID %q", h.DesignerID)
+		}
+		named := 0
+		for i := range p.Parts {
+			if p.Parts[i].Name != "" {
+				named++
+				p.Parts[i].Name = ""
+			}
+		}
+		if named > 0 {
+			warn("dropping %d part names", named)
+		}
+		if p.Settings.AuthorName != "" || p.Settings.Comment != "" {
+			warn("dropping author name and comment")
+		}
+		if h.ShowStartupNotes != 0 || h.PasswordFlag != 0 {
+			warn("dropping startup notes and password flags")
+		}
+		h.DesignerID, h.ShowStartupNotes, h.PasswordFlag = "", 0, 0
+		p.Settings.AuthorName, p.Settings.Comment = "", ""
+		// Version 4 has no string shift, its strings are stored plain.
+		h.StringShift = 0
+		if p.source != nil {
+			p.source.designerID, p.source.strings, p.source.stringShift = nil, nil, 0
+		}
+	}
+
+	h.Version = version
+	return warnings, nil
+}
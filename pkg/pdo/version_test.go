@@ -0,0 +1,90 @@
+package pdo
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSetVersion(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Header.Version != PDO_V6 {
+		t.Fatalf("sample is version %d", p.Header.Version)
+	}
+	want := p.Clone()
+	p.Parts[0].Name = "ring"
+	p.Settings.AuthorName = "me"
+
+	roundTrip := func(version int32) *PDO {
+		t.Helper()
+		if _, err := p.SetVersion(version); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := Write(&buf, p); err != nil {
+			t.Fatal(err)
+		}
+		parser := NewParser(bytes.NewReader(buf.Bytes()))
+		if err := parser.Load(); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if parser.PDO.Header.Version != version {
+			t.Errorf("read version %d, want %d", parser.PDO.Header.Version, version)
+		}
+		return parser.PDO
+	}
+
+	v5 := roundTrip(PDO_V5)
+	if v5.Parts[0].Name != "ring" || v5.Settings.AuthorName != "me" {
+		t.Errorf("version 5 lost part name %q or author %q", v5.Parts[0].Name, v5.Settings.AuthorName)
+	}
+
+	warnings, err := p.SetVersion(PDO_V4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 3 {
+		t.Errorf("warnings %q, want designer ID, part names and author", warnings)
+	}
+	v4 := roundTrip(PDO_V4)
+	if v4.Parts[0].Name != "" || v4.Header.DesignerID != "" {
+		t.Errorf("version 4 kept part name %q or designer %q", v4.Parts[0].Name, v4.Header.DesignerID)
+	}
+
+	v6 := roundTrip(PDO_V6)
+	want.Parts[1].Name = ""
+	if len(v6.Objects) != len(want.Objects) || !v6.Objects[0].Equal(&want.Objects[0]) {
+		t.Error("objects changed on the way to version 4 and back")
+	}
+	if len(v6.Parts) != len(want.Parts) || !v6.Parts[1].Equal(&want.Parts[1]) {
+		t.Error("parts changed on the way to version 4 and back")
+	}
+
+	if _, err := p.SetVersion(7); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("version 7: %v", err)
+	}
+}
+
+func TestSetVersion_Clone(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/torus.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	if err := Write(&want, p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Clone().SetVersion(PDO_V4); err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if err := Write(&got, p); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("downgrading a clone changed the original: %d bytes written, want %d", got.Len(), want.Len())
+	}
+}
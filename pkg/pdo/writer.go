@@ -0,0 +1,371 @@
+package pdo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// source keeps the data of a parsed file that the model doesn't decode, so
+// writing the file back reproduces it.
+type source struct {
+	unknownInt    int32
+	designerID    []byte
+	v6Lock        []byte
+	hasUnfold     bool
+	unfoldPadding uint8
+	overImages    int
+	settingsItems [][]int32
+	trailing      []byte
+
+	// strings are the shifted strings in file order, with their stored bytes.
+	strings             []rawString
+	stringShift         byte
+	multiByte, byteWise bool
+}
+
+// Writer handles PDO specific binary writing, the counterpart of Reader.
+type Writer struct {
+	w             io.Writer
+	StringShift   byte
+	MultiByteC    bool
+	ByteWiseShift bool
+
+	// raw holds stored strings in file order, next is the one expected next.
+	raw  []rawString
+	next int
+	err  error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteBytes writes data in little-endian order. After the first error,
+// further writes are skipped and Err reports it.
+func (w *Writer) WriteBytes(data interface{}) error {
+	if w.err == nil {
+		w.err = binary.Write(w.w, binary.LittleEndian, data)
+	}
+	return w.err
+}
+
+// Err returns the first error encountered while writing.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// WriteRawString writes stored string bytes with their length prefix.
+func (w *Writer) WriteRawString(raw []byte) error {
+	w.WriteBytes(int32(len(raw)))
+	if len(raw) > 0 {
+		w.WriteBytes(raw)
+	}
+	return w.err
+}
+
+// WriteShiftedString writes a string with the writer's shift and character
+// width. Strings read from the file being written back keep their stored
+// bytes: they are matched in file order, skipping strings that were removed,
+// so even strings that decoded lossily are reproduced.
+func (w *Writer) WriteShiftedString(s string) error {
+	for i := w.next; i < len(w.raw); i++ {
+		if w.raw[i].s == s {
+			w.next = i + 1
+			return w.WriteRawString(w.raw[i].raw)
+		}
+	}
+	raw, err := EncodeString(s, w.StringShift, w.MultiByteC, w.ByteWiseShift)
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		return w.err
+	}
+	return w.WriteRawString(raw)
+}
+
+// EncodeString converts a string into stored bytes, the inverse of
+// DecodeString. The result includes the null terminator. An empty string is
+// stored without any bytes.
+func EncodeString(s string, shift byte, multiByte, byteWise bool) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if multiByte {
+		chars := append(utf16.Encode([]rune(s)), 0)
+		raw := make([]byte, 0, 2*len(chars))
+		for _, c := range chars {
+			if byteWise {
+				raw = append(raw, byte(c)+shift, byte(c>>8)+shift)
+			} else {
+				c += uint16(shift)
+				raw = append(raw, byte(c), byte(c>>8))
+			}
+		}
+		return raw, nil
+	}
+
+	sjis, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		return nil, fmt.Errorf("string %q can't be stored as Shift-JIS: %w", s, err)
+	}
+	raw := append(sjis, 0)
+	for i := range raw {
+		raw[i] += shift
+	}
+	return raw, nil
+}
+
+// WriteFile writes the PDO to a file.
+func WriteFile(filename string, p *PDO) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0o644)
+}
+
+// Write encodes the PDO in the format of Header.Version. Data of a parsed
+// file that isn't decoded (unknown header and settings values, trailing
+// bytes) is written back as it was read; PDOs built from scratch get zeros.
+// A Thumbnail is written between the settings and the trailing bytes.
+func Write(w io.Writer, p *PDO) error {
+	src := p.source
+	if src == nil {
+		src = &source{
+			stringShift: byte(p.Header.StringShift),
+			multiByte:   p.Header.MultiByteChars != 0,
+			hasUnfold:   len(p.Parts) > 0,
+			overImages:  len(p.Images),
+		}
+	}
+
+	pw := NewWriter(w)
+	pw.StringShift, pw.MultiByteC, pw.ByteWiseShift = src.stringShift, src.multiByte, src.byteWise
+	pw.raw = src.strings
+
+	if p.Header.Version < PDO_V4 || p.Header.Version > PDO_V6 {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, p.Header.Version)
+	}
+	writeHeader(pw, p, src)
+	pw.WriteBytes(int32(len(p.Objects)))
+	for i := range p.Objects {
+		writeObject(pw, &p.Objects[i])
+	}
+	writeMaterials(pw, p)
+	writeUnfold(pw, p, src)
+	writeSettings(pw, p, src)
+	writeThumbnail(pw, p.Thumbnail)
+	pw.WriteBytes(src.trailing)
+	return pw.Err()
+}
+
+func writeHeader(w *Writer, p *PDO, src *source) {
+	h := &p.Header
+	w.WriteBytes([]byte(FileMagic))
+	w.WriteBytes(h.Version)
+	w.WriteBytes(h.MultiByteChars)
+	w.WriteBytes(src.unknownInt)
+
+	if h.Version > PDO_V4 {
+		designerID := src.designerID
+		if designerID == nil || DecodeString(designerID, 0, src.multiByte, src.byteWise) != h.DesignerID {
+			var err error
+			if designerID, err = EncodeString(h.DesignerID, 0, src.multiByte, src.byteWise); err != nil && w.err == nil {
+				w.err = err
+			}
+		}
+		w.WriteRawString(designerID)
+		w.WriteBytes(h.StringShift)
+	}
+	w.WriteShiftedString(h.Locale)
+	w.WriteShiftedString(h.Codepage)
+	w.WriteBytes(h.TexLock)
+	if h.Version == PDO_V6 {
+		w.WriteBytes(h.ShowStartupNotes)
+		w.WriteBytes(h.PasswordFlag)
+	}
+	w.WriteShiftedString(h.Key)
+
+	if h.Version == PDO_V6 {
+		// A negative count means no lock entries.
+		n := max(h.V6Lock, 0)
+		lock := src.v6Lock
+		if len(lock) != 8*int(n) {
+			lock = make([]byte, 8*int(n))
+		}
+		w.WriteBytes(n)
+		w.WriteBytes(lock)
+	} else if h.Version > PDO_V4 {
+		w.WriteBytes(h.ShowStartupNotes)
+		w.WriteBytes(h.PasswordFlag)
+	}
+
+	w.WriteBytes(h.AssembledHeight)
+	w.WriteBytes(h.OriginOffset)
+}
+
+func writeObject(w *Writer, obj *Object) {
+	w.WriteShiftedString(obj.Name)
+	w.WriteBytes(obj.Visible)
+	w.WriteBytes(int32(len(obj.Vertices)))
+	w.WriteBytes(obj.Vertices)
+
+	w.WriteBytes(int32(len(obj.Faces)))
+	for i := range obj.Faces {
+		f := &obj.Faces[i]
+		w.WriteBytes(f.MaterialIndex)
+		w.WriteBytes(f.PartIndex)
+		w.WriteBytes([4]float64{f.Nx, f.Ny, f.Nz, f.Coord})
+		w.WriteBytes(int32(len(f.Vertices)))
+		for j := range f.Vertices {
+			writeFace2DVertex(w, &f.Vertices[j])
+		}
+	}
+
+	w.WriteBytes(int32(len(obj.Edges)))
+	w.WriteBytes(obj.Edges)
+}
+
+func writeFace2DVertex(w *Writer, v *Face2DVertex) {
+	w.WriteBytes(v.IDVertex)
+	w.WriteBytes([4]float64{v.X, v.Y, v.U, v.V})
+	w.WriteBytes(v.Flap)
+	w.WriteBytes([3]float64{v.FlapHeight, v.FlapAAngle, v.FlapBAngle})
+	w.WriteBytes(v.FlapLineColor)
+	w.WriteBytes(v.FoldLineColor)
+}
+
+func writeMaterials(w *Writer, p *PDO) {
+	w.WriteBytes(int32(len(p.Materials)))
+	for i := range p.Materials {
+		mat := &p.Materials[i]
+		name := mat.Name
+		if name == fmt.Sprintf("named_material%d", i) {
+			name = "" // Placeholder given by the parser to unnamed materials
+		}
+		w.WriteShiftedString(name)
+		w.WriteBytes(mat.Color3D)
+		c := mat.Color2DRGBA
+		w.WriteBytes([4]float32{c[3], c[0], c[1], c[2]}) // Stored as ARGB
+
+		if mat.HasTexture {
+			w.WriteBytes(uint8(1))
+			writeTexture(w, &mat.Texture)
+		} else {
+			w.WriteBytes(uint8(0))
+		}
+	}
+}
+
+func writeTexture(w *Writer, tex *Texture) {
+	w.WriteBytes(tex.Width)
+	w.WriteBytes(tex.Height)
+	w.WriteBytes(int32(len(tex.RawData) + TextureDataWrapperSize))
+	w.WriteBytes(tex.DataHeader)
+	w.WriteBytes(tex.RawData)
+	w.WriteBytes(tex.DataHash)
+}
+
+func writeUnfold(w *Writer, p *PDO, src *source) {
+	if !src.hasUnfold && len(p.Parts) == 0 {
+		w.WriteBytes(uint8(0))
+		return
+	}
+	w.WriteBytes(uint8(1))
+	w.WriteBytes(p.Unfold.Scale)
+	w.WriteBytes(src.unfoldPadding)
+	w.WriteBytes(p.Unfold.BoundingBox)
+
+	w.WriteBytes(int32(len(p.Parts)))
+	for i := range p.Parts {
+		part := &p.Parts[i]
+		w.WriteBytes(part.ObjectIndex)
+		w.WriteBytes(part.BoundingBox)
+		if p.Header.Version > PDO_V4 {
+			w.WriteShiftedString(part.Name)
+		}
+		w.WriteBytes(int32(len(part.Lines)))
+		for j := range part.Lines {
+			writeLine(w, &part.Lines[j])
+		}
+	}
+
+	w.WriteBytes(int32(len(p.TextBlocks)))
+	for i := range p.TextBlocks {
+		tb := &p.TextBlocks[i]
+		w.WriteBytes(tb.BoundingBox)
+		w.WriteBytes(tb.LineSpacing)
+		w.WriteBytes(tb.Color)
+		w.WriteBytes(tb.FontSize)
+		w.WriteShiftedString(tb.FontName)
+		w.WriteBytes(int32(len(tb.Lines)))
+		for _, line := range tb.Lines {
+			w.WriteShiftedString(line)
+		}
+	}
+
+	over := min(src.overImages, len(p.Images))
+	for _, images := range [][]Image{p.Images[:over], p.Images[over:]} {
+		w.WriteBytes(int32(len(images)))
+		for i := range images {
+			w.WriteBytes(images[i].BoundingBox)
+			writeTexture(w, &images[i].Texture)
+		}
+	}
+}
+
+func writeLine(w *Writer, l *Line) {
+	w.WriteBytes(boolByte(l.Hidden))
+	w.WriteBytes(l.Type)
+	w.WriteBytes(uint8(1)) // Unknown, always 1
+	w.WriteBytes(l.FaceIndex)
+	w.WriteBytes(l.VertexIndex)
+	w.WriteBytes(boolByte(l.IsConnectingFaces))
+	if l.IsConnectingFaces {
+		w.WriteBytes(l.Face2Index)
+		w.WriteBytes(l.Vertex2Index)
+	}
+}
+
+func writeSettings(w *Writer, p *PDO, src *source) {
+	if p.Header.Version == PDO_V6 && len(p.Parts) > 0 {
+		w.WriteBytes(int32(len(src.settingsItems)))
+		for _, item := range src.settingsItems {
+			w.WriteBytes(int32(len(item)))
+			w.WriteBytes(item)
+		}
+	}
+
+	s := &p.Settings
+	w.WriteBytes([]uint8{s.ShowFlaps, s.ShowEdgeID, s.EdgeIDPlacement, s.FaceMaterial, s.HideAlmostFlatFoldLines})
+	w.WriteBytes(s.FoldLinesHidingAngle)
+	w.WriteBytes(s.DrawWhiteLineUnderDotLine)
+	w.WriteBytes([]int32{s.MountainFoldLineStyle, s.ValleyFoldLineStyle, s.CutLineStyle, s.EdgeIDFontSize})
+	w.WriteBytes(s.PageType)
+	if s.PageType == 11 {
+		w.WriteBytes([2]float64{s.CustomWidth, s.CustomHeight})
+	}
+	w.WriteBytes([]int32{s.Orientation, s.MarginSide, s.MarginTop})
+	w.WriteBytes(s.MountainFoldLinePattern)
+	w.WriteBytes(s.ValleyFoldLinePattern)
+	w.WriteBytes(s.AddOutlinePadding)
+	w.WriteBytes(s.ScaleFactor)
+	if p.Header.Version > PDO_V4 {
+		w.WriteShiftedString(s.AuthorName)
+		w.WriteShiftedString(s.Comment)
+	}
+}
+
+func boolByte(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+package pdo
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestWrite_RoundTrip(t *testing.T) {
+	for _, name := range []string{"cone", "cylinder", "pyramid", "sphere", "torus"} {
+		path := "../../sample_basic_shapes/" + name + ".pdo"
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := ParseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := Write(&buf, p); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s: written file differs from the original (%d vs %d bytes)", name, buf.Len(), len(want))
+		}
+	}
+}
+
+func TestWrite_Thumbnail(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Thumbnail != nil {
+		t.Fatalf("sample has a thumbnail of %d bytes", len(p.Thumbnail))
+	}
+	p.Thumbnail = []byte("\x89PNG preview")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewParser(bytes.NewReader(buf.Bytes()))
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if !parser.PDO.Equal(p) {
+		t.Errorf("thumbnail %q didn't survive writing", parser.PDO.Thumbnail)
+	}
+}
+
+func TestWrite_HasUnfold(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparse := func(p *PDO) *PDO {
+		var buf bytes.Buffer
+		if err := Write(&buf, p); err != nil {
+			t.Fatal(err)
+		}
+		parser := NewParser(bytes.NewReader(buf.Bytes()))
+		if err := parser.Load(); err != nil {
+			t.Fatal(err)
+		}
+		return parser.PDO
+	}
+	// An unfolding whose parts were all removed is still an unfolding.
+	p.Parts = nil
+	if !p.HasUnfold() || !reparse(p).HasUnfold() {
+		t.Error("removing the parts dropped the unfolding")
+	}
+
+	built := New()
+	built.Objects = p.Objects
+	if built.HasUnfold() || reparse(built).HasUnfold() {
+		t.Error("model built without parts has an unfolding")
+	}
+}
+
+func TestWrite_LineColors(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &p.Objects[0].Faces[2].Vertices[1]
+	if v.FlapLineColor != [3]float32{} || v.FoldLineColor != [3]float32{} {
+		t.Fatalf("sample sets line colors %v, %v", v.FlapLineColor, v.FoldLineColor)
+	}
+	v.FlapLineColor = [3]float32{1, 0, 0}
+	v.FoldLineColor = [3]float32{0, 0.5, 1}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewParser(bytes.NewReader(buf.Bytes()))
+	if err := parser.Load(); err != nil {
+		t.Fatal(err)
+	}
+	got := parser.PDO.Objects[0].Faces[2].Vertices[1]
+	if got.FlapLineColor != v.FlapLineColor || got.FoldLineColor != v.FoldLineColor {
+		t.Errorf("read line colors %v, %v", got.FlapLineColor, got.FoldLineColor)
+	}
+}
+
+func TestWrite_V6Lock(t *testing.T) {
+	p, err := ParseFile("../../sample_basic_shapes/pyramid.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ lock, want int32 }{{2, 2}, {0, 0}, {-1, 0}} {
+		p.Header.V6Lock = tt.lock
+		var buf bytes.Buffer
+		if err := Write(&buf, p); err != nil {
+			t.Fatalf("lock %d: %v", tt.lock, err)
+		}
+		parser := NewParser(bytes.NewReader(buf.Bytes()))
+		if err := parser.Load(); err != nil {
+			t.Fatalf("lock %d: %v", tt.lock, err)
+		}
+		if got := parser.PDO.Header.V6Lock; got != tt.want || len(parser.PDO.source.v6Lock) != 8*int(tt.want) {
+			t.Errorf("lock %d read back as %d with %d bytes, want %d", tt.lock, got, len(parser.PDO.source.v6Lock), tt.want)
+		}
+		if tt.lock >= 0 && !parser.PDO.Equal(p) {
+			t.Errorf("lock %d: the written file reads back differently", tt.lock)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package pdotools is the high-level API of pdo-tools: it parses a PDO file
+// and exports it in one call, collecting the output files and warnings.
+// Embedders that don't need the details of the pdo and export packages
+// should use it, its types are kept stable.
+package pdotools
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"pdo-tools/pkg/export"
+	"pdo-tools/pkg/pdo"
+)
+
+// ErrUnknownFormat is returned for formats without an exporter.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Format names an output format, e.g. "pdf" or "obj".
+type Format string
+
+// Formats returns the available output formats.
+func Formats() []Format {
+	var list []Format
+	for _, name := range export.Names() {
+		list = append(list, Format(name))
+	}
+	return list
+}
+
+// FormatOf returns the output format for a file name by its extension.
+func FormatOf(path string) (Format, bool) {
+	e, ok := export.ForExtension(filepath.Ext(path))
+	if !ok {
+		return "", false
+	}
+	return Format(e.Name()), true
+}
+
+// Options controls a conversion.
+type Options struct {
+	// Name is the input file name. The artifacts are named after it,
+	// "model" is used when it is empty.
+	Name string
+	// Parse holds the string decoding options of the input.
+	Parse pdo.Options
+	// Export holds the options of the exporter.
+	Export export.Options
+	// Logger receives all diagnostics, warnings are also returned in the
+	// result. slog.Default() is used when nil.
+	Logger *slog.Logger
+}
+
+// Artifact is a file written by a conversion.
+type Artifact struct {
+	// Name is the file name, without directories.
+	Name        string
+	ContentType string
+	Data        []byte
+	// Main is set on the file in the requested format, the others are side
+	// files such as material libraries, textures or further pages.
+	Main bool
+}
+
+// Warning is a problem logged during a conversion, at the warning level
+// or above.
+type Warning = export.Warning
+
+// Result is the outcome of a conversion.
+type Result struct {
+	// PDO is the parsed model.
+	PDO *pdo.PDO
+	// Artifacts lists the files written, the main one first.
+	Artifacts []Artifact
+	Warnings  []Warning
+}
+
+// Main returns the file in the requested format.
+func (r *Result) Main() Artifact {
+	for _, a := range r.Artifacts {
+		if a.Main {
+			return a
+		}
+	}
+	return Artifact{}
+}
+
+// WriteDir saves the artifacts into dir.
+func (r *Result) WriteDir(dir string) error {
+	for _, a := range r.Artifacts {
+		if err := os.WriteFile(filepath.Join(dir, a.Name), a.Data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteZip writes the artifacts as a zip archive.
+func (r *Result) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, a := range r.Artifacts {
+		f, err := zw.Create(a.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(a.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// Convert parses a PDO file from input and exports it to target. Side files
+// are written to a scratch directory and returned with the main file.
+func Convert(ctx context.Context, input io.Reader, target Format, opts Options) (res Result, err error) {
+	exp, ok := export.Lookup(string(target))
+	if !ok {
+		return Result{}, fmt.Errorf("%w %q", ErrUnknownFormat, target)
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	warnings := &export.Warnings{}
+	defer func() { res.Warnings = warnings.List() }()
+	logger = slog.New(warnings.Handler(logger.Handler()))
+
+	parser := pdo.NewParser(input)
+	parser.Options = opts.Parse
+	parser.Options.Logger = logger
+	if err := parser.Load(); err != nil {
+		return res, err
+	}
+	res.PDO = parser.PDO
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+
+	dir, err := os.MkdirTemp("", "pdo-tools-")
+	if err != nil {
+		return res, err
+	}
+	defer os.RemoveAll(dir)
+
+	base := strings.TrimSuffix(filepath.Base(opts.Name), filepath.Ext(opts.Name))
+	if base == "" || base == "." || base == string(filepath.Separator) {
+		base = "model"
+	}
+	mainName := base + exp.Extensions()[0]
+	path := filepath.Join(dir, mainName)
+	f, err := os.Create(path)
+	if err != nil {
+		return res, err
+	}
+	exportOpts := opts.Export
+	exportOpts.Logger = logger
+	err = exp.Export(ctx, res.PDO, export.Target{W: f, Path: path}, exportOpts)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return res, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return res, err
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return res, err
+		}
+		res.Artifacts = append(res.Artifacts, Artifact{
+			Name:        e.Name(),
+			ContentType: contentType(filepath.Ext(e.Name())),
+			Data:        data,
+			Main:        e.Name() == mainName,
+		})
+	}
+	sort.SliceStable(res.Artifacts, func(i, j int) bool { return res.Artifacts[i].Main && !res.Artifacts[j].Main })
+	return res, nil
+}
+
+func contentType(ext string) string {
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
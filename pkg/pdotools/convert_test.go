@@ -0,0 +1,60 @@
+package pdotools
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"pdo-tools/pkg/export"
+)
+
+func TestConvert(t *testing.T) {
+	f, err := os.Open("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// AMF has no centimeters and warns when falling back to mm.
+	res, err := Convert(context.Background(), f, "amf", Options{
+		Name:   "models/cone.pdo",
+		Export: export.Options{Units: export.UnitsCM},
+		Logger: slog.New(slog.DiscardHandler),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PDO == nil || len(res.PDO.Objects) == 0 {
+		t.Fatal("no parsed model in the result")
+	}
+	main := res.Main()
+	if main.Name != "cone.amf" || !strings.Contains(string(main.Data), "<amf") {
+		t.Errorf("main artifact %q, %d bytes", main.Name, len(main.Data))
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0].String(), "units=cm") {
+		t.Errorf("warnings %v", res.Warnings)
+	}
+
+	f.Seek(0, 0)
+	res, err = Convert(context.Background(), f, "obj", Options{Logger: slog.New(slog.DiscardHandler)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, a := range res.Artifacts {
+		names = append(names, a.Name)
+	}
+	if got := strings.Join(names, ","); got != "model.obj,model.mtl" {
+		t.Errorf("obj artifacts %s", got)
+	}
+
+	if _, err := Convert(context.Background(), f, "dwg", Options{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("unknown format: %v", err)
+	}
+	if format, ok := FormatOf("x.SVGZ"); !ok || format != "svgz" {
+		t.Errorf("FormatOf: %q %v", format, ok)
+	}
+}
@@ -0,0 +1,168 @@
+// Package qr encodes short texts (URLs, names) as QR codes.
+//
+// Only byte mode with error correction level M and versions 1 to 10 are
+// supported, which holds up to 213 bytes and covers page stamping needs.
+package qr
+
+import (
+	"errors"
+)
+
+// ErrTooLong is returned when the data does not fit into a version 10 symbol.
+var ErrTooLong = errors.New("qr: data too long")
+
+// Code is an encoded QR symbol. Modules are indexed [row][column], true is dark.
+// The quiet zone is not included.
+type Code struct {
+	Size    int
+	Modules [][]bool
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.Modules[y][x]
+}
+
+// versionInfo holds the level M block structure of a version.
+type versionInfo struct {
+	totalCodewords int
+	ecPerBlock     int
+	blocks         int
+	alignment      []int
+}
+
+var versions = []versionInfo{
+	1:  {26, 10, 1, nil},
+	2:  {44, 16, 1, []int{6, 18}},
+	3:  {70, 26, 1, []int{6, 22}},
+	4:  {100, 18, 2, []int{6, 26}},
+	5:  {134, 24, 2, []int{6, 30}},
+	6:  {172, 16, 4, []int{6, 34}},
+	7:  {196, 18, 4, []int{6, 22, 38}},
+	8:  {242, 22, 4, []int{6, 24, 42}},
+	9:  {292, 22, 5, []int{6, 26, 46}},
+	10: {346, 26, 5, []int{6, 28, 50}},
+}
+
+func (v versionInfo) dataCodewords() int {
+	return v.totalCodewords - v.ecPerBlock*v.blocks
+}
+
+// Encode encodes data in the smallest version that fits.
+func Encode(data []byte) (*Code, error) {
+	for ver := 1; ver < len(versions); ver++ {
+		countBits := 8
+		if ver >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*versions[ver].dataCodewords() {
+			return encode(data, ver, countBits), nil
+		}
+	}
+	return nil, ErrTooLong
+}
+
+func encode(data []byte, ver, countBits int) *Code {
+	info := versions[ver]
+
+	// Byte mode segment, terminator and padding.
+	var bb bitBuffer
+	bb.append(0x4, 4)
+	bb.append(len(data), countBits)
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	capacity := info.dataCodewords() * 8
+	for i := 0; i < 4 && bb.len() < capacity; i++ {
+		bb.append(0, 1)
+	}
+	for bb.len()%8 != 0 {
+		bb.append(0, 1)
+	}
+	for pad := 0xEC; bb.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	codewords := interleave(bb.bytes(), info)
+
+	c := newCanvas(ver)
+	c.drawFunctionPatterns(info.alignment, ver)
+	c.drawCodewords(codewords)
+
+	// Pick the mask with the lowest penalty.
+	best, bestPenalty := -1, 0
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		penalty := c.penalty()
+		if best < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask) // XOR again to undo
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+
+	return &Code{Size: c.size, Modules: c.modules}
+}
+
+// interleave splits the data into blocks, appends Reed-Solomon error
+// correction to each and interleaves the result.
+func interleave(data []byte, info versionInfo) []byte {
+	shortLen := info.totalCodewords / info.blocks
+	numShort := info.blocks - info.totalCodewords%info.blocks
+	shortData := shortLen - info.ecPerBlock
+
+	gen := rsGenerator(info.ecPerBlock)
+	var dataBlocks, ecBlocks [][]byte
+	k := 0
+	for i := 0; i < info.blocks; i++ {
+		n := shortData
+		if i >= numShort {
+			n++
+		}
+		block := data[k : k+n]
+		k += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, rsRemainder(block, gen))
+	}
+
+	var out []byte
+	for i := 0; i <= shortData; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < info.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(val, n int) {
+	for i := n - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (val>>i)&1 == 1)
+	}
+}
+
+func (b *bitBuffer) len() int {
+	return len(b.bits)
+}
+
+func (b *bitBuffer) bytes() []byte {
+	out := make([]byte, len(b.bits)/8)
+	for i, bit := range b.bits {
+		if bit {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
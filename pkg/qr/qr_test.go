@@ -0,0 +1,84 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncode_Version(t *testing.T) {
+	tests := []struct {
+		n    int
+		size int
+	}{
+		{1, 21},   // Version 1
+		{14, 21},  // Version 1 holds 14 bytes at level M
+		{15, 25},  // Version 2
+		{213, 57}, // Version 10
+	}
+	for _, tt := range tests {
+		code, err := Encode([]byte(strings.Repeat("a", tt.n)))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", tt.n, err)
+		}
+		if code.Size != tt.size {
+			t.Errorf("Encode(%d bytes): size %d, want %d", tt.n, code.Size, tt.size)
+		}
+	}
+
+	if _, err := Encode(make([]byte, 214)); err != ErrTooLong {
+		t.Errorf("Encode(214 bytes): got %v, want ErrTooLong", err)
+	}
+}
+
+func TestEncode_FormatBits(t *testing.T) {
+	code, err := Encode([]byte("https://example.com/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both copies of the format information must agree and decode to level M.
+	var first, second int
+	for i := 0; i <= 5; i++ {
+		first |= bit(code.Dark(8, i)) << i
+	}
+	first |= bit(code.Dark(8, 7))<<6 | bit(code.Dark(8, 8))<<7 | bit(code.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(code.Dark(14-i, 8)) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(code.Dark(code.Size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(code.Dark(8, code.Size-15+i)) << i
+	}
+
+	if first != second {
+		t.Fatalf("format copies differ: %015b vs %015b", first, second)
+	}
+	if level := (first ^ 0x5412) >> 13; level != 0 {
+		t.Errorf("error correction level bits %02b, want 00 (M)", level)
+	}
+	if !code.Dark(8, code.Size-8) {
+		t.Error("dark module not set")
+	}
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" in alphanumeric mode as version 1-M.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := rsRemainder(data, rsGenerator(10))
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pdo-tools/pkg/export"
+)
+
+// Handler returns the HTTP API of the server:
+//
+//	POST   /jobs?format=pdf&name=model.pdo  submit a PDO file as the body
+//	GET    /jobs/{id}                       job status
+//	GET    /jobs/{id}/result                download the result
+//	DELETE /jobs/{id}                       cancel and forget a job
+//	GET    /formats                         available output formats
+//	GET    /healthz                         queue state
+//
+// Export options are query parameters of the submission: flaps, units,
+// textures and part-colors, as on the command line.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs/{id}", s.handleStatus)
+	mux.HandleFunc("GET /jobs/{id}/result", s.handleResult)
+	mux.HandleFunc("DELETE /jobs/{id}", s.handleCancel)
+	mux.HandleFunc("GET /formats", handleFormats)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	return mux
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts, err := queryOptions(q.Get)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = "svg"
+	}
+	name := q.Get("name")
+	if name == "" {
+		name = "model.pdo"
+	}
+
+	input, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxUpload))
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("upload larger than %d bytes", s.cfg.MaxUpload))
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	case len(input) == 0:
+		writeError(w, http.StatusBadRequest, errors.New("empty upload"))
+		return
+	}
+
+	info, err := s.Submit(name, format, input, opts)
+	switch {
+	case errors.Is(err, ErrUnknownFormat):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrClosed):
+		w.Header().Set("Retry-After", "30")
+		writeError(w, http.StatusServiceUnavailable, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		w.Header().Set("Location", "/jobs/"+info.ID)
+		writeJSON(w, http.StatusAccepted, info)
+	}
+}
+
+// queryOptions reads export options from submission parameters.
+func queryOptions(get func(string) string) (export.Options, error) {
+	var opts export.Options
+	var err error
+	if opts.FlapStyle, err = export.ParseFlapStyle(get("flaps")); err != nil {
+		return opts, err
+	}
+	if opts.Units, err = export.ParseUnits(get("units")); err != nil {
+		return opts, err
+	}
+	if v := get("part-colors"); v != "" {
+		if opts.PartColoring, err = export.ParsePartColoring(v); err != nil {
+			return opts, err
+		}
+	}
+	if v := get("textures"); v != "" {
+		if opts.Textures, err = strconv.ParseBool(v); err != nil {
+			return opts, fmt.Errorf("invalid textures value %q", v)
+		}
+	}
+	return opts, nil
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("no such job"))
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleResult(w http.ResponseWriter, r *http.Request) {
+	info, res, ok := s.Result(r.PathValue("id"))
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, errors.New("no such job"))
+	case info.Status != StatusDone:
+		writeJSON(w, http.StatusConflict, info)
+	default:
+		w.Header().Set("Content-Type", res.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", res.FileName))
+		w.Header().Set("Content-Length", strconv.Itoa(len(res.Data)))
+		w.Write(res.Data)
+	}
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if !s.Cancel(r.PathValue("id")) {
+		writeError(w, http.StatusNotFound, errors.New("no such job"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleFormats(w http.ResponseWriter, r *http.Request) {
+	type format struct {
+		Name       string   `json:"name"`
+		Extensions []string `json:"extensions"`
+		Mesh       bool     `json:"mesh"`
+	}
+	var list []format
+	for _, e := range export.Exporters() {
+		list = append(list, format{Name: e.Name(), Extensions: e.Extensions(), Mesh: export.IsMesh(e)})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	queued, running := s.Jobs()
+	writeJSON(w, http.StatusOK, map[string]int{"queued": queued, "running": running, "workers": s.cfg.Workers})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
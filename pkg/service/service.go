@@ -0,0 +1,433 @@
+// Package service runs PDO conversions as asynchronous jobs behind an HTTP
+// API, to share pdo-tools as a backend between many users.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"pdo-tools/pkg/export"
+	"pdo-tools/pkg/pdo"
+	"pdo-tools/pkg/pdotools"
+)
+
+// Defaults of the Config fields left zero.
+const (
+	DefaultQueueSize      = 64
+	DefaultTimeout        = 2 * time.Minute
+	DefaultMaxUpload      = 64 << 20
+	DefaultRetention      = time.Hour
+	DefaultMaxResults     = 1000
+	DefaultMaxResultBytes = 1 << 30
+)
+
+var (
+	// ErrQueueFull is returned when no more jobs can wait for a worker.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrClosed is returned when submitting to a closed server.
+	ErrClosed = errors.New("service is shut down")
+	// ErrUnknownFormat is returned for formats without an exporter.
+	ErrUnknownFormat = errors.New("unknown format")
+)
+
+// Config holds the limits of a Server.
+type Config struct {
+	// Workers is the number of conversions run at once, runtime.NumCPU()
+	// when 0.
+	Workers int
+	// QueueSize is the number of jobs that can wait for a worker.
+	QueueSize int
+	// Timeout limits the run time of a single job.
+	Timeout time.Duration
+	// MaxUpload limits the size of uploaded files in bytes.
+	MaxUpload int64
+	// Retention is how long finished jobs and their results are kept.
+	Retention time.Duration
+	// MaxResults and MaxResultBytes limit the number of finished jobs kept
+	// and the total size of their results. The oldest are forgotten first,
+	// before their retention time is over.
+	MaxResults     int
+	MaxResultBytes int64
+	// Parse holds the string decoding options of uploaded files. Without
+	// limits, uploads are parsed with pdo.DefaultLimits.
+	Parse pdo.Options
+	// Logger receives job events. slog.Default() is used when nil.
+	Logger *slog.Logger
+}
+
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = runtime.NumCPU()
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.MaxUpload <= 0 {
+		c.MaxUpload = DefaultMaxUpload
+	}
+	if c.Retention <= 0 {
+		c.Retention = DefaultRetention
+	}
+	if c.MaxResults <= 0 {
+		c.MaxResults = DefaultMaxResults
+	}
+	if c.MaxResultBytes <= 0 {
+		c.MaxResultBytes = DefaultMaxResultBytes
+	}
+	if c.Parse.Limits == (pdo.Limits{}) {
+		c.Parse.Limits = pdo.DefaultLimits
+	}
+	if c.Logger == nil {
+		c.Logger = slog.Default()
+	}
+	return c
+}
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusRunning  Status = "running"
+	StatusDone     Status = "done"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+)
+
+// Finished reports whether the job won't change anymore.
+func (s Status) Finished() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCanceled
+}
+
+// JobInfo describes a job as reported by the API.
+type JobInfo struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Format   string     `json:"format"`
+	Status   Status     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	// Size is the size of the result in bytes, once done.
+	Size int `json:"size,omitempty"`
+}
+
+// Result is the output of a finished job.
+type Result struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+type job struct {
+	info   JobInfo
+	input  []byte
+	parse  pdo.Options
+	opts   export.Options
+	result Result
+	cancel context.CancelFunc
+}
+
+// Server queues conversion jobs and runs them on a fixed number of workers.
+type Server struct {
+	cfg Config
+	// convert runs a conversion, replaced in tests.
+	convert func(ctx context.Context, name, format string, input []byte, parse pdo.Options, opts export.Options) (Result, error)
+	ctx     context.Context
+	stop    context.CancelFunc
+	queue   chan *job
+	wg      sync.WaitGroup
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	closed bool
+}
+
+// New starts a server with the given limits. Close stops it.
+func New(cfg Config) *Server {
+	cfg = cfg.withDefaults()
+	s := &Server{
+		cfg:     cfg,
+		convert: convert,
+		queue:   make(chan *job, cfg.QueueSize),
+		jobs:    map[string]*job{},
+	}
+	s.ctx, s.stop = context.WithCancel(context.Background())
+	for range cfg.Workers {
+		s.wg.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+// Close cancels queued and running jobs and waits for the workers to stop.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	s.stop()
+	s.wg.Wait()
+}
+
+// Submit queues the conversion of a PDO file to format. name is the input
+// file name, used to name the result.
+func (s *Server) Submit(name, format string, input []byte, opts export.Options) (JobInfo, error) {
+	if _, ok := export.Lookup(format); !ok {
+		return JobInfo{}, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+	id, err := newID()
+	if err != nil {
+		return JobInfo{}, err
+	}
+	parse := s.cfg.Parse
+	opts.Logger = s.cfg.Logger.With("job", id)
+	parse.Logger = opts.Logger
+	j := &job{
+		info:  JobInfo{ID: id, Name: name, Format: strings.ToLower(format), Status: StatusQueued, Created: time.Now()},
+		input: input,
+		parse: parse,
+		opts:  opts,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return JobInfo{}, ErrClosed
+	}
+	s.expire(j.info.Created)
+	select {
+	case s.queue <- j:
+	default:
+		return JobInfo{}, ErrQueueFull
+	}
+	s.jobs[id] = j
+	s.cfg.Logger.Info("job queued", "job", id, "name", name, "format", format, "size", len(input))
+	return j.info, nil
+}
+
+// Job returns the state of a job.
+func (s *Server) Job(id string) (JobInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+	j, ok := s.jobs[id]
+	if !ok {
+		return JobInfo{}, false
+	}
+	return j.info, true
+}
+
+// Result returns the job state and, once it is done, its output.
+func (s *Server) Result(id string) (JobInfo, Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+	j, ok := s.jobs[id]
+	if !ok {
+		return JobInfo{}, Result{}, false
+	}
+	return j.info, j.result, true
+}
+
+// Cancel stops a job if it hasn't finished and forgets it.
+func (s *Server) Cancel(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return false
+	}
+	if !j.info.Status.Finished() {
+		s.finish(j, StatusCanceled, nil, Result{})
+	}
+	delete(s.jobs, id)
+	return true
+}
+
+// Jobs returns the number of jobs waiting and running.
+func (s *Server) Jobs() (queued, running int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		switch j.info.Status {
+		case StatusQueued:
+			queued++
+		case StatusRunning:
+			running++
+		}
+	}
+	return queued, running
+}
+
+// expire forgets finished jobs older than the retention time, then the
+// oldest finished jobs while more or larger results are kept than the
+// limits allow. s.mu is held.
+func (s *Server) expire(now time.Time) {
+	var finished []*job
+	var size int64
+	for id, j := range s.jobs {
+		switch {
+		case j.info.Finished == nil:
+		case now.Sub(*j.info.Finished) > s.cfg.Retention:
+			delete(s.jobs, id)
+		default:
+			finished = append(finished, j)
+			size += int64(len(j.result.Data))
+		}
+	}
+	if len(finished) <= s.cfg.MaxResults && size <= s.cfg.MaxResultBytes {
+		return
+	}
+	slices.SortFunc(finished, func(a, b *job) int { return a.info.Finished.Compare(*b.info.Finished) })
+	n := len(finished)
+	for _, j := range finished {
+		if n <= s.cfg.MaxResults && size <= s.cfg.MaxResultBytes {
+			break
+		}
+		delete(s.jobs, j.info.ID)
+		n--
+		size -= int64(len(j.result.Data))
+		s.cfg.Logger.Info("job forgotten", "job", j.info.ID, "reason", "result limits")
+	}
+}
+
+// finish records the outcome of a job. s.mu is held.
+func (s *Server) finish(j *job, status Status, err error, res Result) {
+	now := time.Now()
+	j.info.Status, j.info.Finished = status, &now
+	j.info.Size = len(res.Data)
+	j.result, j.input = res, nil
+	if err != nil {
+		j.info.Error = err.Error()
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+	s.cfg.Logger.Info("job finished", "job", j.info.ID, "status", status, "err", err)
+	s.expire(now)
+}
+
+func (s *Server) work() {
+	defer s.wg.Done()
+	for j := range s.queue {
+		s.run(j)
+	}
+}
+
+func (s *Server) run(j *job) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.cfg.Timeout)
+	defer cancel()
+
+	s.mu.Lock()
+	if j.info.Status != StatusQueued {
+		s.mu.Unlock()
+		return // Canceled while waiting
+	}
+	if s.ctx.Err() != nil {
+		s.finish(j, StatusCanceled, nil, Result{})
+		s.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	j.info.Status, j.info.Started, j.cancel = StatusRunning, &now, cancel
+	input, parse, opts := j.input, j.parse, j.opts
+	s.mu.Unlock()
+
+	// Exporters check the context only between steps, so a conversion that
+	// runs over its time is reported at once but can't be interrupted. The
+	// worker waits for it to return before taking the next job, so
+	// overrunning conversions never run beyond the Workers limit.
+	type outcome struct {
+		res Result
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		// A panic on a malformed upload fails the job, not the server.
+		defer func() {
+			if r := recover(); r != nil {
+				s.cfg.Logger.Error("conversion panicked", "job", j.info.ID, "panic", r, "stack", string(debug.Stack()))
+				done <- outcome{err: fmt.Errorf("internal error: %v", r)}
+			}
+		}()
+		res, err := s.convert(ctx, j.info.Name, j.info.Format, input, parse, opts)
+		done <- outcome{res, err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+		out.err = ctx.Err()
+		defer func() { <-done }()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case j.info.Status.Finished():
+		// Canceled while running.
+	case out.err == nil:
+		s.finish(j, StatusDone, nil, out.res)
+	case errors.Is(out.err, context.DeadlineExceeded):
+		s.finish(j, StatusFailed, fmt.Errorf("timed out after %s", s.cfg.Timeout), Result{})
+	case errors.Is(out.err, context.Canceled):
+		s.finish(j, StatusCanceled, nil, Result{})
+	default:
+		s.finish(j, StatusFailed, out.err, Result{})
+	}
+}
+
+// convert exports a PDO file. A single output file is returned as is,
+// formats writing side files (materials, textures, more pages) are
+// returned as a zip archive.
+func convert(ctx context.Context, name, format string, input []byte, parse pdo.Options, opts export.Options) (Result, error) {
+	res, err := pdotools.Convert(ctx, bytes.NewReader(input), pdotools.Format(format), pdotools.Options{
+		Name:   name,
+		Parse:  parse,
+		Export: opts,
+		Logger: opts.Logger,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	if len(res.Artifacts) == 1 {
+		a := res.Artifacts[0]
+		return Result{FileName: a.Name, ContentType: a.ContentType, Data: a.Data}, nil
+	}
+	var buf bytes.Buffer
+	if err := res.WriteZip(&buf); err != nil {
+		return Result{}, err
+	}
+	base := strings.TrimSuffix(res.Main().Name, filepath.Ext(res.Main().Name))
+	return Result{FileName: base + ".zip", ContentType: "application/zip", Data: buf.Bytes()}, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pdo-tools/pkg/export"
+	"pdo-tools/pkg/pdo"
+)
+
+func TestServer_Jobs(t *testing.T) {
+	input, err := os.ReadFile("../../sample_basic_shapes/cone.pdo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := New(Config{Workers: 2, Logger: slog.New(slog.DiscardHandler)})
+	defer srv.Close()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	submit := func(query string) (*http.Response, JobInfo) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/jobs?"+query, "application/octet-stream", bytes.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var info JobInfo
+		json.NewDecoder(resp.Body).Decode(&info)
+		return resp, info
+	}
+	wait := func(id string) JobInfo {
+		t.Helper()
+		for range 500 {
+			info, ok := srv.Job(id)
+			if !ok {
+				t.Fatalf("job %s vanished", id)
+			}
+			if info.Status.Finished() {
+				return info
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("job %s didn't finish", id)
+		return JobInfo{}
+	}
+	download := func(id string) (*http.Response, []byte) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/jobs/" + id + "/result")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	resp, info := submit("format=svg&name=cone.pdo&flaps=none")
+	if resp.StatusCode != http.StatusAccepted || resp.Header.Get("Location") != "/jobs/"+info.ID {
+		t.Fatalf("submit: %s, location %q", resp.Status, resp.Header.Get("Location"))
+	}
+	if got := wait(info.ID); got.Status != StatusDone {
+		t.Fatalf("svg job %s: %s", got.Status, got.Error)
+	}
+	resp, body := download(info.ID)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "<svg") {
+		t.Errorf("svg result: %s, %d bytes", resp.Status, len(body))
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.Contains(cd, "cone.svg") {
+		t.Errorf("Content-Disposition %q", cd)
+	}
+
+	// OBJ writes a material library next to the model, returned as a zip.
+	_, info = submit("format=obj&name=cone.pdo")
+	if got := wait(info.ID); got.Status != StatusDone {
+		t.Fatalf("obj job %s: %s", got.Status, got.Error)
+	}
+	_, body = download(info.ID)
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); !strings.Contains(got, "cone.obj") || !strings.Contains(got, "cone.mtl") {
+		t.Errorf("obj archive files %s", got)
+	}
+
+	for _, query := range []string{"format=dxf2", "format=svg&units=furlong"} {
+		if resp, _ := submit(query); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: %s, want 400", query, resp.Status)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/jobs/"+info.ID, nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete: %v %v", resp, err)
+	}
+	if resp, _ := download(info.ID); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deleted job result: %s", resp.Status)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	srv := New(Config{Workers: 1, QueueSize: 1, MaxUpload: 16, Logger: slog.New(slog.DiscardHandler)})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/jobs", "application/octet-stream", strings.NewReader(strings.Repeat("x", 64)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("large upload: %s", resp.Status)
+	}
+
+	// Garbage fails to parse; the job fails rather than the server.
+	info, err := srv.Submit("bad.pdo", "svg", []byte("garbage"), queryDefaults(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 500 {
+		if info, _ = srv.Job(info.ID); info.Status.Finished() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info.Status != StatusFailed || info.Error == "" {
+		t.Errorf("garbage job: %s %q", info.Status, info.Error)
+	}
+
+	srv.Close()
+	if _, err := srv.Submit("x.pdo", "svg", []byte("x"), queryDefaults(t)); err != ErrClosed {
+		t.Errorf("submit after close: %v", err)
+	}
+}
+
+func TestServer_Timeout(t *testing.T) {
+	srv := New(Config{Workers: 1, Timeout: 20 * time.Millisecond, Logger: slog.New(slog.DiscardHandler)})
+	defer srv.Close()
+	release := make(chan struct{})
+	var running atomic.Int32
+	srv.convert = func(ctx context.Context, name, format string, input []byte, parse pdo.Options, opts export.Options) (Result, error) {
+		running.Add(1)
+		defer running.Add(-1)
+		if name == "slow.pdo" {
+			<-release
+		}
+		return Result{Data: input}, nil
+	}
+	wait := func(id string) JobInfo {
+		t.Helper()
+		for range 500 {
+			if info, _ := srv.Job(id); info.Status.Finished() {
+				return info
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("job %s didn't finish", id)
+		return JobInfo{}
+	}
+
+	slow, err := srv.Submit("slow.pdo", "svg", []byte("x"), export.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := wait(slow.ID); info.Status != StatusFailed || !strings.Contains(info.Error, "timed out") {
+		t.Fatalf("slow job: %s %q", info.Status, info.Error)
+	}
+
+	// The timed-out conversion still holds the only worker.
+	fast, err := srv.Submit("fast.pdo", "svg", []byte("x"), export.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if info, _ := srv.Job(fast.ID); info.Status != StatusQueued || running.Load() != 1 {
+		t.Fatalf("next job %s with %d conversions running, want queued behind the timed-out one", info.Status, running.Load())
+	}
+	close(release)
+	if info := wait(fast.ID); info.Status != StatusDone {
+		t.Errorf("next job: %s %q", info.Status, info.Error)
+	}
+}
+
+func TestServer_Panic(t *testing.T) {
+	var log bytes.Buffer
+	srv := New(Config{Workers: 1, Logger: slog.New(slog.NewTextHandler(&log, nil))})
+	defer srv.Close()
+	srv.convert = func(ctx context.Context, name, format string, input []byte, parse pdo.Options, opts export.Options) (Result, error) {
+		if name == "bad.pdo" {
+			var lines []int
+			_ = lines[len(input)]
+		}
+		return Result{Data: input}, nil
+	}
+	wait := func(id string) JobInfo {
+		t.Helper()
+		for range 500 {
+			if info, _ := srv.Job(id); info.Status.Finished() {
+				return info
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("job %s didn't finish", id)
+		return JobInfo{}
+	}
+
+	bad, err := srv.Submit("bad.pdo", "svg", []byte("x"), export.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := wait(bad.ID); info.Status != StatusFailed || !strings.Contains(info.Error, "internal error: runtime error: index out of range") {
+		t.Errorf("panicking job: %s %q", info.Status, info.Error)
+	}
+	if !strings.Contains(log.String(), "conversion panicked") {
+		t.Errorf("panic not logged:\n%s", log.String())
+	}
+
+	// The worker goes on with the next job.
+	good, err := srv.Submit("good.pdo", "svg", []byte("x"), export.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := wait(good.ID); info.Status != StatusDone {
+		t.Errorf("next job: %s %q", info.Status, info.Error)
+	}
+}
+
+func TestServer_ResultLimits(t *testing.T) {
+	srv := New(Config{Workers: 1, MaxResults: 3, MaxResultBytes: 25, Logger: slog.New(slog.DiscardHandler)})
+	defer srv.Close()
+	srv.convert = func(ctx context.Context, name, format string, input []byte, parse pdo.Options, opts export.Options) (Result, error) {
+		return Result{Data: input}, nil
+	}
+	var ids []string
+	for _, size := range []int{10, 10, 10, 1, 1} {
+		info, err := srv.Submit("x.pdo", "svg", make([]byte, size), export.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, info.ID)
+		for range 500 {
+			if info, ok := srv.Job(info.ID); !ok || info.Status.Finished() {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// The third result goes over the size, the fifth over the count.
+	for i, id := range ids {
+		if _, _, ok := srv.Result(id); ok != (i >= 2) {
+			t.Errorf("job %d kept: %v", i, ok)
+		}
+	}
+}
+
+func queryDefaults(t *testing.T) export.Options {
+	t.Helper()
+	opts, err := queryOptions(func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}